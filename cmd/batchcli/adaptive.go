@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
+)
+
+// Adaptive budgets (-adaptive-timeout): the per-pair timeout and the preflight attempt
+// timeout/count follow the observed RPC latency (p90 of the last calls, see rpcmetrics)
+// instead of the fixed -pair-timeout-ms / -preflight-attempt-timeout-ms, within bounds.
+// Until enough calls are measured the fixed values are used.
+type adaptiveBounds struct {
+	pairMin, pairMax         time.Duration
+	attemptsMin, attemptsMax int
+}
+
+const adaptiveMinSamples = 8
+
+var (
+	gAdaptive       bool
+	gAdaptiveBounds adaptiveBounds
+	gAdaptivePairs  atomic.Int64  // pairs finished this run, for calls-per-pair (read by -workers)
+	gAdaptiveShown  time.Duration // last pair timeout printed
+)
+
+func setAdaptive(on bool, b adaptiveBounds) {
+	if b.attemptsMin < 1 {
+		b.attemptsMin = 1
+	}
+	if b.attemptsMax < b.attemptsMin {
+		b.attemptsMax = b.attemptsMin
+	}
+	if b.pairMax < b.pairMin {
+		b.pairMax = b.pairMin
+	}
+	gAdaptive, gAdaptiveBounds = on, b
+}
+
+// resetAdaptive starts a new run (rpcmetrics.Default.Reset drops the latency window).
+func resetAdaptive() { gAdaptivePairs.Store(0); gAdaptiveShown = 0 }
+
+// adaptiveP90 is the latency the budgets are based on; ok=false while too few samples.
+func adaptiveP90() (time.Duration, bool) {
+	if !gAdaptive {
+		return 0, false
+	}
+	p90, n := rpcmetrics.Latency(0.9)
+	return p90, n >= adaptiveMinSamples
+}
+
+// adaptivePairTimeout: twice the time the average pair's calls take at p90 latency
+// (plus the rate-limit spacing per call), clamped to [pairMin, pairMax].
+func adaptivePairTimeout() (time.Duration, bool) {
+	p90, ok := adaptiveP90()
+	if !ok {
+		return 0, false
+	}
+	callsPerPair := 12.0 // typical full check before any pair finished
+	if pairs := gAdaptivePairs.Load(); pairs >= 3 {
+		callsPerPair = math.Max(4, float64(rpcmetrics.ProviderCalls())/float64(pairs))
+	}
+	d := time.Duration(2 * callsPerPair * float64(p90+gLimiter.perCall()))
+	return clampDuration(d, gAdaptiveBounds.pairMin, gAdaptiveBounds.pairMax), true
+}
+
+// adaptivePreflight keeps the configured preflight budget (attempts x attempt timeout):
+// an attempt gets 4x p90 (1s..3x configured), and the attempt count is what fits the budget,
+// clamped to [attemptsMin, attemptsMax]. Fast RPC: short attempts, more retries; slow: fewer, longer.
+func adaptivePreflight() (int, time.Duration, bool) {
+	p90, ok := adaptiveP90()
+	if !ok {
+		return 0, 0, false
+	}
+	baseN, baseT := gPreflightAttempts, gPreflightAttemptTimeout
+	if baseN < 1 {
+		baseN = 1
+	}
+	if baseT <= 0 {
+		baseT = 4 * time.Second
+	}
+	t := clampDuration(4*p90, time.Second, 3*baseT)
+	n := int(math.Round(float64(baseN) * float64(baseT) / float64(t)))
+	n = max(gAdaptiveBounds.attemptsMin, min(n, gAdaptiveBounds.attemptsMax))
+	return n, t, true
+}
+
+// adaptiveNotePair is called after every pair (under processInput's lock); prints the budgets when they moved by >25%.
+func adaptiveNotePair() {
+	if !gAdaptive {
+		return
+	}
+	gAdaptivePairs.Add(1)
+	d, ok := adaptivePairTimeout()
+	if !ok || (gAdaptiveShown > 0 && math.Abs(float64(d-gAdaptiveShown)) < 0.25*float64(gAdaptiveShown)) {
+		return
+	}
+	gAdaptiveShown = d
+	p90, n := rpcmetrics.Latency(0.9)
+	attempts, attemptT, _ := adaptivePreflight()
+	fmt.Printf("[adaptive] rpc p90=%s (%d calls) → pair timeout=%s, preflight %d x %s\n",
+		p90.Round(time.Millisecond), n, d.Round(time.Millisecond), attempts, attemptT.Round(time.Millisecond))
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	if d < lo {
+		return lo
+	}
+	if hi > 0 && d > hi {
+		return hi
+	}
+	return d
+}
+
+// getenvInt reads a non-negative int env var, def when unset or invalid.
+func getenvInt(key string, def int) int {
+	if v, err := strconv.Atoi(strings.TrimSpace(getenv(key, ""))); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// getenvDuration reads a non-negative duration env var ("24h"), def when unset or invalid.
+func getenvDuration(key string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(getenv(key, "")); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
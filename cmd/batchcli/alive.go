@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Dead-token pre-check: one eth_getCode (+ totalSupply) before the full meta/preflight path,
+// so typos and self-destructed tokens fail in one call instead of five.
+var (
+	gAliveMu sync.Mutex
+	gAlive   = map[common.Address]string{} // token -> dead reason ("" = alive); reset per run
+)
+
+func resetAliveCache() {
+	gAliveMu.Lock()
+	gAlive = map[common.Address]string{}
+	gAliveMu.Unlock()
+}
+
+// deadTokenReason returns a BAD reason when token has no code or a zero totalSupply.
+// RPC errors are not a verdict: the regular checks run and report them.
+func deadTokenReason(ctx context.Context, ec *ethclient.Client, token common.Address) string {
+	gAliveMu.Lock()
+	r, ok := gAlive[token]
+	gAliveMu.Unlock()
+	if ok {
+		return r
+	}
+
+	throttle()
+	rpcConcurrencyGate <- struct{}{}
+	code, err := ec.CodeAt(ctx, token, nil)
+	<-rpcConcurrencyGate
+	if err != nil {
+		return ""
+	}
+	reason := ""
+	if len(code) == 0 {
+		reason = "dead token: no contract code (self-destructed or wrong address)"
+	} else {
+		throttle()
+		out, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: common.FromHex("0x18160ddd")}) // totalSupply()
+		if err == nil && len(out) >= 32 && new(big.Int).SetBytes(out[:32]).Sign() == 0 {
+			reason = "dead token: totalSupply() is 0"
+		}
+	}
+	gAliveMu.Lock()
+	gAlive[token] = reason
+	gAliveMu.Unlock()
+	return reason
+}
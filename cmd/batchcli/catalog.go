@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/tokencatalog"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
+)
+
+// Token catalog (-catalog): every scanned pair adds what we learned about its token to a
+// cumulative JSON file shared across runs; -catalog-export renders it as CSV/JSON.
+var (
+	gCatalog      *tokencatalog.Catalog // nil when -catalog is ""
+	gCatalogChain string                // chain ID of the current run (also used for explorer links)
+)
+
+// catalogNote records one pair's verdict (ok | bad | spam) for its token.
+func catalogNote(r pairRow, verdict, reason string) {
+	if gCatalog == nil || r.tokenAddress == (common.Address{}) || r.fromAddress == (common.Address{}) {
+		return // bad address/key: nothing learned about the token
+	}
+	o := tokencatalog.Observation{
+		ChainID: gCatalogChain, Address: r.tokenAddress.Hex(),
+		Symbol: r.tokenSymbol, Verdict: verdict, Reason: reason,
+	}
+	// tokenDecimals stays 0 when the pair stopped before decimals() (dead token etc.).
+	if (r.tokenDecimals != 0 || r.tokenSymbol != "") && !r.warns.Has(warnings.DecimalsFallback) {
+		d := r.tokenDecimals
+		o.Decimals = &d
+	}
+	if gSpam != nil {
+		if v, ok := gSpam.verdict(r.tokenAddress); ok {
+			score := v.score
+			o.RiskScore, o.RiskReasons, o.Verified = &score, v.signals, v.verified
+		}
+	}
+	gCatalog.Observe(o)
+}
+
+// exportCatalog writes the catalog at path to out (.json => JSON, otherwise CSV).
+func exportCatalog(path, out string) error {
+	c, err := tokencatalog.Load(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.Export(f, tokencatalog.FormatOf(out)); err != nil {
+		return err
+	}
+	n, _ := c.Len()
+	fmt.Printf("Exported %d token(s) from %s => %s\n", n, path, out)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// Multi-chain classification: the sell-route preflight (transfer into the token/WETH V2 pair)
+// and the spam filter's liquidity signal use the WETH and V2 factory of the RPC's chain.
+// -chain-id pins the expected chain (the RPC must report it); an optional third input column
+// "chain" (ID or name) lets one CSV hold several networks: rows of other chains are skipped
+// and can be checked by a run against an RPC of their chain.
+
+// gChain is the chain of this run (resolved from the RPC in run()).
+var gChain = core.Mainnet
+
+// resolveChain checks the RPC's chain against -chain-id and selects its constants.
+func resolveChain(want string, rpcChain *big.Int) error {
+	if strings.TrimSpace(want) != "" {
+		c, _, err := core.ParseChain(want)
+		if err != nil {
+			return fmt.Errorf("-chain-id: %w", err)
+		}
+		if new(big.Int).SetUint64(c.ID).Cmp(rpcChain) != 0 {
+			return fmt.Errorf("-chain-id %s: the RPC is on chain %s", want, rpcChain)
+		}
+	}
+	c, ok := core.ChainByID(rpcChain)
+	if !ok {
+		gChain = core.Chain{ID: rpcChain.Uint64()}
+		fmt.Printf("[chain] %s: no WETH/V2 factory known: pairs are judged on the direct transfer only (known: %s)\n", rpcChain, strings.Join(core.KnownChainNames(), ", "))
+		return nil
+	}
+	gChain = c
+	fmt.Printf("[chain] %d %s: sell route via %s factory %s, WETH %s\n", c.ID, c.Name, c.V2Name, c.V2Factory.Hex(), c.WETH.Hex())
+	return nil
+}
+
+// rowChain reads the optional chain column of an input row: ok=false when the row belongs
+// to another chain; err when the column is not a chain ID or known name. The chain follows
+// the smart-account column when the row has one (see rowAccount).
+func rowChain(row []string) (id uint64, ok bool, err error) {
+	col := 2
+	if rowAccount(row) != "" {
+		col = 3
+	}
+	if len(row) <= col || strings.TrimSpace(row[col]) == "" {
+		return gChain.ID, true, nil
+	}
+	c, _, err := core.ParseChain(row[col])
+	if err != nil {
+		return 0, false, err
+	}
+	return c.ID, c.ID == gChain.ID, nil
+}
+
+// otherChainRows counts the rows skipped for another chain, per chain ID.
+type otherChainRows map[uint64]int
+
+func (o otherChainRows) String() string {
+	parts := make([]string, 0, len(o))
+	total := 0
+	for id, n := range o {
+		parts = append(parts, fmt.Sprintf("chain %d: %d", id, n))
+		total += n
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("%d row(s) of other chains skipped (%s); run them with an RPC of that chain", total, strings.Join(parts, ", "))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Check stages (-checks): what a pair goes through after its balance is read. A full scan
+// runs all of them; turning some off trades certainty for speed, e.g. a quick "who holds
+// what" pass (-checks balance) before a deep scan of the wallets worth it. OK rows of a
+// reduced scan carry the checks_skipped warning, so they are not mistaken for verified pairs.
+const (
+	checkRestrictions   = "restrictions"    // paused / blacklist / whitelist getters
+	checkGuards         = "guards"          // dead-token pre-check, transfer hooks, fee-on-transfer, permit probe
+	checkPreflight      = "preflight"       // 7702 transfer simulation (off: a non-zero balance is OK)
+	checkOptionalReturn = "optional-return" // SafeERC20-style retry of tokens without a bool return
+	checkSell           = "sell"            // sell-route preflight (transfer into the V2 pair) when the direct transfer fails
+)
+
+var checkStages = []string{checkRestrictions, checkGuards, checkPreflight, checkOptionalReturn, checkSell}
+
+// checkSet is the enabled stages.
+type checkSet map[string]bool
+
+// gChecks is -checks (every stage by default).
+var gChecks = parseChecksOrAll("")
+
+func parseChecksOrAll(spec string) checkSet {
+	c, _ := parseChecks(spec)
+	return c
+}
+
+// parseChecks reads -checks: all (default), balance (none), a list of the stages to run
+// ("restrictions,preflight"), or of the ones to leave out ("-guards,-sell").
+func parseChecks(spec string) (checkSet, error) {
+	all := checkSet{}
+	for _, s := range checkStages {
+		all[s] = true
+	}
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	switch spec {
+	case "", "all":
+		return all, nil
+	case "balance", "none":
+		return checkSet{}, nil
+	}
+	var c checkSet
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, off := strings.CutPrefix(item, "-")
+		if c == nil { // the first item decides: "-x" starts from all, "x" from none
+			c = checkSet{}
+			for s := range all {
+				c[s] = off
+			}
+		}
+		if !all[name] {
+			return nil, fmt.Errorf("-checks: unknown stage %q (stages: %s; or all, balance)", name, strings.Join(checkStages, ", "))
+		}
+		c[name] = !off
+	}
+	if c == nil {
+		return all, nil
+	}
+	return c, nil
+}
+
+// skipped lists the stages that are off, in pipeline order.
+func (c checkSet) skipped() []string {
+	var out []string
+	for _, s := range checkStages {
+		if !c[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func checkOn(stage string) bool { return gChecks[stage] }
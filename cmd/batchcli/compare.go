@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+)
+
+// Run comparison (-compare previous_ok.csv): after the scan, this run's OK/BAD/SPAM outputs
+// are diffed against an earlier OK file, to watch a list of compromised wallets over time.
+// Pairs are matched by (from, token) like the scheduled-mode diff.
+
+// lostPair is a pair that was OK before and is BAD or SPAM now.
+type lostPair struct {
+	okPair
+	Verdict string // bad | spam
+	Reason  string
+	Code    reasons.Code // BAD reason code ("" for spam)
+}
+
+// balanceChange is a pair OK in both runs whose balance moved.
+type balanceChange struct {
+	okPair          // current run
+	Before   string // previous balanceTokens
+	DeltaWei *big.Int
+}
+
+type compareReport struct {
+	Added   []okPair   // newly transferable
+	Blocked []lostPair // OK before, BAD/SPAM now
+	Missing []okPair   // OK before, not in this run's input (or filtered/merged)
+	Changed []balanceChange
+	Same    int
+}
+
+// compareRuns diffs the previous OK set with this run's verdicts.
+func compareRuns(prev, cur map[string]okPair, lost map[string]lostPair) compareReport {
+	var r compareReport
+	for k, p := range cur {
+		old, ok := prev[k]
+		if !ok {
+			r.Added = append(r.Added, p)
+			continue
+		}
+		if d, moved := balanceDelta(old, p); moved {
+			r.Changed = append(r.Changed, balanceChange{okPair: p, Before: old.Balance, DeltaWei: d})
+		} else {
+			r.Same++
+		}
+	}
+	for k, p := range prev {
+		if _, ok := cur[k]; ok {
+			continue
+		}
+		if l, ok := lost[k]; ok {
+			r.Blocked = append(r.Blocked, l)
+		} else {
+			r.Missing = append(r.Missing, p)
+		}
+	}
+	sortPairs(r.Added)
+	sortPairs(r.Missing)
+	sort.Slice(r.Blocked, func(i, j int) bool { return r.Blocked[i].key() < r.Blocked[j].key() })
+	sort.Slice(r.Changed, func(i, j int) bool { return r.Changed[i].key() < r.Changed[j].key() })
+	return r
+}
+
+// balanceDelta compares raw wei when both runs have it, else the formatted amounts
+// (older files without the balanceWei column); the delta is nil then.
+func balanceDelta(old, cur okPair) (*big.Int, bool) {
+	a, okA := new(big.Int).SetString(old.Wei, 10)
+	b, okB := new(big.Int).SetString(cur.Wei, 10)
+	if okA && okB {
+		d := new(big.Int).Sub(b, a)
+		return d, d.Sign() != 0
+	}
+	return nil, strings.TrimSpace(old.Balance) != strings.TrimSpace(cur.Balance)
+}
+
+func sortPairs(ps []okPair) {
+	sort.Slice(ps, func(i, j int) bool { return ps[i].key() < ps[j].key() })
+}
+
+// readLostSet loads the pairs of a BAD or SPAM output (CSV or NDJSON) with their reason;
+// reasonCol is the CSV column of the reason, codeCol that of the reason code (-1 = none).
+// Malformed rows (no from) are skipped.
+func readLostSet(path, verdict string, reasonCol, codeCol int) (map[string]lostPair, error) {
+	out := map[string]lostPair{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return nil, err
+	}
+	add := func(token, from, reason string, code reasons.Code) {
+		if strings.TrimSpace(from) == "" {
+			return
+		}
+		if code == "" && verdict == "bad" {
+			code = reasons.Classify(reason) // written before the reasonCode column
+		}
+		l := lostPair{okPair: okPair{Token: token, From: from}, Verdict: verdict, Reason: reason, Code: code}
+		out[l.key()] = l
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var rec pairRecord
+			if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			reason := rec.Reason
+			if len(rec.SpamReasons) > 0 {
+				reason = strings.Join(rec.SpamReasons, "; ")
+			}
+			add(rec.Token, rec.From, reason, reasons.Parse(rec.ReasonCode))
+		}
+		return out, nil
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	for lineNo := 1; ; lineNo++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if (lineNo == 1 && skipRow(row, lineNo)) || len(row) <= reasonCol {
+			continue
+		}
+		var code reasons.Code
+		if codeCol >= 0 && codeCol < len(row) {
+			code = reasons.Parse(row[codeCol])
+		}
+		add(row[0], row[2], row[reasonCol], code)
+	}
+	return out, nil
+}
+
+// runCompare diffs this run's outputs against prev and prints the report
+// (and writes it as CSV to outPath, if set).
+func runCompare(cfg appConfig, prev map[string]okPair) error {
+	cur, err := readOKSet(cfg.outOKPath)
+	if err != nil {
+		return fmt.Errorf("compare: read %s: %w", cfg.outOKPath, err)
+	}
+	lost, err := readLostSet(cfg.outBadPath, "bad", 3, 6)
+	if err != nil {
+		return fmt.Errorf("compare: read %s: %w", cfg.outBadPath, err)
+	}
+	if cfg.spamFilter {
+		spam, err := readLostSet(cfg.outSpamPath, "spam", 5, -1)
+		if err != nil {
+			return fmt.Errorf("compare: read %s: %w", cfg.outSpamPath, err)
+		}
+		for k, l := range spam {
+			lost[k] = l
+		}
+	}
+	r := compareRuns(prev, cur, lost)
+	printCompare(cfg.comparePath, r)
+	if cfg.compareOut == "" {
+		return nil
+	}
+	if err := writeCompareCSV(cfg.compareOut, r); err != nil {
+		return fmt.Errorf("compare: %w", err)
+	}
+	fmt.Printf("[compare] report => %s\n", cfg.compareOut)
+	return nil
+}
+
+func printCompare(prevPath string, r compareReport) {
+	fmt.Printf("[compare] vs %s: new=%d blocked=%d missing=%d balance-changed=%d unchanged=%d\n",
+		prevPath, len(r.Added), len(r.Blocked), len(r.Missing), len(r.Changed), r.Same)
+	if t := blockedTally(r.Blocked); len(t) > 0 {
+		fmt.Printf("[compare] blocked by reason code: %s\n", t)
+	}
+	for _, p := range r.Added {
+		fmt.Printf("[compare] NEW      from=%s token=%s %s %s\n", p.From, p.Token, privacy.Amount(p.Balance), p.Symbol)
+	}
+	for _, l := range r.Blocked {
+		fmt.Printf("[compare] BLOCKED  from=%s token=%s (%s: %s)\n", l.From, l.Token, l.Verdict, l.Reason)
+	}
+	for _, p := range r.Missing {
+		fmt.Printf("[compare] MISSING  from=%s token=%s (not scanned this run)\n", p.From, p.Token)
+	}
+	for _, c := range r.Changed {
+		fmt.Printf("[compare] BALANCE  from=%s token=%s %s → %s %s%s\n", c.From, c.Token,
+			privacy.Amount(c.Before), privacy.Amount(c.Balance), c.Symbol, deltaText(c))
+	}
+}
+
+// deltaText is " (+1.5)" for console output; empty without raw wei or in privacy mode.
+func deltaText(c balanceChange) string {
+	if c.DeltaWei == nil || privacy.Enabled() {
+		return ""
+	}
+	s := units.Format(c.DeltaWei, c.Decimals, gPrecision)
+	if c.DeltaWei.Sign() > 0 {
+		s = "+" + s
+	}
+	return " (" + s + ")"
+}
+
+// blockedTally counts the newly BAD pairs by reason code.
+func blockedTally(ls []lostPair) reasons.Tally {
+	t := reasons.Tally{}
+	for _, l := range ls {
+		t.Add(l.Code)
+	}
+	return t
+}
+
+func writeCompareCSV(path string, r compareReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"change", "from", "token", "symbol", "balanceBefore", "balanceAfter", "deltaWei", "reason", "reasonCode"})
+	for _, p := range r.Added {
+		_ = w.Write([]string{"new", p.From, p.Token, p.Symbol, "", p.Balance, "", "", ""})
+	}
+	for _, l := range r.Blocked {
+		_ = w.Write([]string{"blocked", l.From, l.Token, "", "", "", "", l.Verdict + ": " + l.Reason, string(l.Code)})
+	}
+	for _, p := range r.Missing {
+		_ = w.Write([]string{"missing", p.From, p.Token, p.Symbol, p.Balance, "", "", "", ""})
+	}
+	for _, c := range r.Changed {
+		_ = w.Write([]string{"balance", c.From, c.Token, c.Symbol, c.Before, c.Balance, units.WeiString(c.DeltaWei), "", ""})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
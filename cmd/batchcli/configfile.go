@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configKey is one -config file setting: the section it lives in and the env var it stands
+// in for ("" = flag only).
+type configKey struct{ section, env string }
+
+// configKeys lists what a -config file may set, by flag name. Keys (SAFE_PRIVATE_KEY,
+// KEYREF_SECRET) are deliberately missing: the file is meant to be version-controlled.
+var configKeys = map[string]configKey{
+	"rpc":      {"rpc", "RPC_URL"},
+	"at-block": {"rpc", "BATCH_AT_BLOCK"},
+	"chain-id": {"rpc", "BATCH_CHAIN_ID"},
+	"rpc2":     {"rpc", "BATCH_RPC2"},
+
+	"rpc-delay-ms": {"throttle", "BATCH_RPC_DELAY_MS"},
+	"rate-per-sec": {"throttle", "BATCH_RATE_PER_SEC"},
+	"rate-burst":   {"throttle", "BATCH_RATE_BURST"},
+	"row-delay-ms": {"throttle", "BATCH_ROW_DELAY_MS"},
+	"workers":      {"throttle", "BATCH_WORKERS"},
+	"cache-ttl-ms": {"throttle", "BATCH_CACHE_TTL_MS"},
+
+	"pair-timeout-ms":              {"preflight", "BATCH_PAIR_TIMEOUT_MS"},
+	"preflight-attempts":           {"preflight", "BATCH_PREFLIGHT_ATTEMPTS"},
+	"preflight-attempt-timeout-ms": {"preflight", "BATCH_PREFLIGHT_ATTEMPT_TIMEOUT_MS"},
+	"adaptive-timeout":             {"preflight", "BATCH_ADAPTIVE_TIMEOUT"},
+	"pair-timeout-min-ms":          {"preflight", "BATCH_PAIR_TIMEOUT_MIN_MS"},
+	"pair-timeout-max-ms":          {"preflight", "BATCH_PAIR_TIMEOUT_MAX_MS"},
+	"preflight-attempts-min":       {"preflight", "BATCH_PREFLIGHT_ATTEMPTS_MIN"},
+	"preflight-attempts-max":       {"preflight", "BATCH_PREFLIGHT_ATTEMPTS_MAX"},
+
+	"out-ok":        {"output", "BATCH_OUT_OK"},
+	"out-bad":       {"output", "BATCH_OUT_BAD"},
+	"out-spam":      {"output", "BATCH_OUT_SPAM"},
+	"format":        {"output", "BATCH_FORMAT"},
+	"precision":     {"output", "BATCH_PRECISION"},
+	"privacy":       {"output", "PRIVACY_DISPLAY"},
+	"usd":           {"output", "BATCH_USD"},
+	"gas-estimate":  {"output", "BATCH_GAS_ESTIMATE"},
+	"gas-tip-gwei":  {"output", "BATCH_GAS_TIP_GWEI"},
+	"queue-out":     {"output", "BATCH_QUEUE_OUT"},
+	"pair-log-json": {"output", "BATCH_PAIR_LOG_JSON"},
+	"sort":          {"output", "BATCH_SORT"},
+	"top":           {"output", "BATCH_TOP"},
+	"compare":       {"output", "BATCH_COMPARE"},
+	"compare-out":   {"output", "BATCH_COMPARE_OUT"},
+	"db":            {"output", "BATCH_DB"},
+	"catalog":       {"output", "BATCH_CATALOG"},
+	"dedupe-report": {"output", "BATCH_DEDUPE_REPORT"},
+	"pair-logs":     {"output", ""},
+	"no-prompt":     {"output", "BATCH_NO_PROMPT"},
+	"progress":      {"output", "BATCH_PROGRESS"},
+
+	"input":                 {"scan", "BATCH_INPUT"},
+	"token-allowlist":       {"scan", "BATCH_TOKEN_ALLOWLIST"},
+	"token-denylist":        {"scan", "BATCH_TOKEN_DENYLIST"},
+	"spam-filter":           {"scan", "BATCH_SPAM_FILTER"},
+	"duplicates":            {"scan", "BATCH_DUPLICATES"},
+	"db-skip-unchanged":     {"scan", "BATCH_DB_SKIP_UNCHANGED"},
+	"triage":                {"scan", "TRIAGE_FILE"},
+	"schedule":              {"scan", "BATCH_SCHEDULE"},
+	"alert-webhook":         {"scan", "BATCH_ALERT_WEBHOOK"},
+	"cache":                 {"scan", "BATCH_CACHE"},
+	"recheck-balances-only": {"scan", "BATCH_RECHECK_BALANCES_ONLY"},
+	"nft-scan":              {"scan", "BATCH_NFT_SCAN"},
+	"nft-lookback":          {"scan", "BATCH_NFT_LOOKBACK"},
+	"checks":                {"scan", "BATCH_CHECKS"},
+	"sample":                {"scan", "BATCH_SAMPLE"},
+}
+
+var (
+	gFileEnv   = map[string]string{} // -config values of env-backed settings; getenv falls back to them
+	gFileFlags = map[string]string{} // -config values of flag-only settings, applied after flag.Parse
+)
+
+// configPathFromArgs finds -config before flag.Parse (the file feeds the flag defaults),
+// falling back to BATCH_CONFIG.
+func configPathFromArgs(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		name := strings.TrimLeft(a, "-")
+		if name == a {
+			continue
+		}
+		if v, ok := strings.CutPrefix(name, "config="); ok {
+			return v
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return strings.TrimSpace(os.Getenv("BATCH_CONFIG"))
+}
+
+// loadConfigFile reads a TOML (or YAML by extension) config with [rpc], [throttle],
+// [preflight], [output] and [scan] sections into gFileEnv / gFileFlags. Precedence is
+// flags > env > file: the file only fills what the environment leaves empty.
+func loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("-config: %w", err)
+	}
+	var doc map[string]map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		_, err = toml.Decode(string(data), &doc)
+	}
+	if err != nil {
+		return fmt.Errorf("-config %s: %w (settings go under [rpc], [throttle], [preflight], [output] or [scan])", path, err)
+	}
+	for section, kv := range doc {
+		for name, v := range kv {
+			k, ok := configKeys[name]
+			if !ok {
+				return fmt.Errorf("-config %s: [%s] %s: unknown setting (use flag names, e.g. rpc-delay-ms)", path, section, name)
+			}
+			if k.section != section {
+				return fmt.Errorf("-config %s: %s belongs in [%s], not [%s]", path, name, k.section, section)
+			}
+			if k.env == "" {
+				gFileFlags[name] = fmt.Sprint(v)
+				continue
+			}
+			s := fmt.Sprint(v)
+			if b, isBool := v.(bool); isBool {
+				s = "0"
+				if b {
+					s = "1"
+				}
+			}
+			gFileEnv[k.env] = s
+		}
+	}
+	return nil
+}
+
+// applyFileFlags sets the flag-only -config values that were not given on the command line.
+func applyFileFlags() error {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	names := make([]string, 0, len(gFileFlags))
+	for n := range gFileFlags {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if set[n] {
+			continue
+		}
+		if err := flag.Set(n, gFileFlags[n]); err != nil {
+			return fmt.Errorf("-config: %s: %w", n, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Duplicate rows (-duplicates): the same (from, token) pair listed twice would be checked
+// twice and its balance counted twice in OK totals/queues. By default only the first row is
+// checked; -dedupe-report lists the dropped rows for the data owner.
+const (
+	dupDrop = "drop"
+	dupKeep = "keep"
+)
+
+var (
+	gDuplicates   = dupDrop
+	gDedupeReport string
+)
+
+// dupRow is one dropped duplicate: its line, the line it repeats and the "from|token" key.
+type dupRow struct {
+	line, first int
+	key         string
+}
+
+// writeDedupeReport writes the dropped rows as CSV (no private keys).
+func writeDedupeReport(path string, dups []dupRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"line", "firstLine", "from", "token"})
+	for _, d := range dups {
+		from, token, _ := strings.Cut(d.key, "|")
+		_ = w.Write([]string{strconv.Itoa(d.line), strconv.Itoa(d.first), common.HexToAddress(from).Hex(), common.HexToAddress(token).Hex()})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
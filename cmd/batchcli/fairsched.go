@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// Parallel scan (-workers N). Input files are usually grouped by token (one airdrop hits
+// many wallets), so handing rows to workers in file order would put every worker on the
+// same token contract and hammer the provider with identical calls. Instead pairs are
+// queued per token and dispatched round-robin across tokens, with at most one pair of a
+// token in flight: different tokens run in parallel, the same token is serialized (and
+// its second pair then hits the per-token caches warmed by the first).
+
+// pairJob is an input row that passed parsing, filters and dedupe.
+type pairJob struct {
+	lineNo     int
+	tokenHex   string
+	privateHex string
+	accountHex string  // smart-account column ("" = the key's own address)
+	inputPos   float64 // share of a streamed input file read up to this row (-1 = unknown)
+}
+
+// fairQueue hands out pairJobs fairly across tokens; safe for concurrent workers. Jobs are
+// pushed (all at once for an in-memory input, as they are read for a streamed one) until close.
+type fairQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	tokens []string             // ring in order of first appearance
+	queues map[string][]pairJob // pending jobs per token
+	busy   map[string]bool      // token has a pair in flight
+	next   int                  // ring position to resume from
+	left   int                  // jobs not yet handed out
+	limit  int                  // push waits while this many are queued (0 = unbounded)
+	closed bool                 // no more pushes
+}
+
+func newFairQueue() *fairQueue {
+	q := &fairQueue{queues: map[string][]pairJob{}, busy: map[string]bool{}}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues j behind the pending jobs of its token; with a limit it waits for room.
+func (q *fairQueue) push(j pairJob) {
+	q.mu.Lock()
+	for q.limit > 0 && q.left >= q.limit {
+		q.cond.Wait()
+	}
+	k := tokenKey(j.tokenHex)
+	if _, ok := q.queues[k]; !ok {
+		q.tokens = append(q.tokens, k)
+	}
+	q.queues[k] = append(q.queues[k], j)
+	q.left++
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// close marks the input complete: take returns ok=false once the queue drains.
+func (q *fairQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// tokenKey groups rows of one token regardless of address case; invalid addresses are
+// grouped as written (they fail fast without RPC anyway).
+func tokenKey(tokenHex string) string { return strings.ToLower(strings.TrimSpace(tokenHex)) }
+
+// take blocks until a job of an idle token is available; ok=false once the queue is closed
+// and all jobs are out.
+func (q *fairQueue) take() (pairJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.left == 0 && q.closed {
+			return pairJob{}, false
+		}
+		for n := 0; n < len(q.tokens); n++ {
+			i := (q.next + n) % len(q.tokens)
+			k := q.tokens[i]
+			if q.busy[k] || len(q.queues[k]) == 0 {
+				continue
+			}
+			j := q.queues[k][0]
+			q.queues[k] = q.queues[k][1:]
+			q.busy[k] = true
+			q.left--
+			q.next = i + 1
+			if q.limit > 0 {
+				q.cond.Broadcast() // room for a waiting push
+			}
+			return j, true
+		}
+		q.cond.Wait() // every pending token is in flight, or the input has not arrived yet
+	}
+}
+
+// done frees the job's token for the next pair.
+func (q *fairQueue) done(j pairJob) {
+	q.mu.Lock()
+	q.busy[tokenKey(j.tokenHex)] = false
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// run starts workers goroutines processing the queue; the returned func waits for them
+// (call it after close).
+func (q *fairQueue) run(workers int, process func(pairJob)) (wait func()) {
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, ok := q.take()
+				if !ok {
+					return
+				}
+				process(j)
+				q.done(j)
+			}
+		}()
+	}
+	return wg.Wait
+}
+
+// runFair processes jobs on workers goroutines via fairQueue and waits for all of them.
+func runFair(jobs []pairJob, workers int, process func(pairJob)) {
+	q := newFairQueue()
+	for _, j := range jobs {
+		q.push(j)
+	}
+	q.close()
+	q.run(workers, process)()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// Rescue gas estimate (-gas-estimate): OK pairs get the gas of the route their preflight
+// passed and what the sponsor (SAFE) would pay for it at the current base fee plus
+// -gas-tip-gwei, next to the balance's value in the native coin. Pairs whose gas costs
+// more than the tokens are worth can then be filtered out before any rescue is signed.
+//
+//	transfer  eth_estimateGas of transfer(SAFE, balance) from FROM, without its 21000 intrinsic
+//	sell      the same transfer plus v2SwapGas (the pair swap, WETH unwrap and ETH payout)
+//	userop    the same transfer plus userOpOverheadGas (smart account: paid by the account or
+//	          a SAFE deposit, see smartaccount.go)
+//
+// transfer and sell add rescueOverheadGas: the sponsor's type-4 tx with one authorization.
+const (
+	routeTransfer = "transfer"
+	routeSell     = "sell"
+
+	rescueOverheadGas = 21_000 + 25_000 + 4_000 // intrinsic + 7702 authorization (new account) + calldata/delegate dispatch
+	v2SwapGas         = 110_000
+	baseFeeMaxAge     = 12 * time.Second // one block
+)
+
+var (
+	gGasEstimate bool
+	gGasTipWei   = new(big.Int)
+
+	gBaseFeeMu sync.Mutex
+	gBaseFee   *big.Int
+	gBaseFeeAt time.Time
+
+	gGasMu                       sync.Mutex
+	gGasEstimated, gGasOverValue int
+)
+
+// gasEstimate is the -gas-estimate result of one OK pair.
+type gasEstimate struct {
+	gas      uint64
+	costWei  *big.Int
+	valueWei *big.Int // balance quoted in the native coin (nil = no quote)
+}
+
+// overValue is "yes" when the gas costs more than the balance is worth, "" without a quote.
+func (g *gasEstimate) overValue() string {
+	if g == nil || g.valueWei == nil {
+		return ""
+	}
+	if g.costWei.Cmp(g.valueWei) > 0 {
+		return "yes"
+	}
+	return "no"
+}
+
+// estimateRescueGas estimates the sponsor gas of r's route; nil when the transfer cannot
+// be estimated.
+func estimateRescueGas(ec *ethclient.Client, r pairRow, safe common.Address) *gasEstimate {
+	if r.balanceWei == nil || r.balanceWei.Sign() == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), getPairTimeout())
+	defer cancel()
+	data := append(common.FromHex("0xa9059cbb"), common.LeftPadBytes(safe.Bytes(), 32)...) // transfer(to, amount)
+	data = append(data, common.LeftPadBytes(r.balanceWei.Bytes(), 32)...)
+	throttle()
+	transferGas, err := ec.EstimateGas(ctx, ethereum.CallMsg{From: r.fromAddress, To: &r.tokenAddress, Data: data})
+	if err != nil {
+		return nil
+	}
+	if transferGas > 21_000 {
+		transferGas -= 21_000
+	}
+	g := &gasEstimate{gas: rescueOverheadGas + transferGas}
+	switch r.route {
+	case routeSell:
+		g.gas += v2SwapGas
+	case routeUserOp:
+		g.gas = userOpOverheadGas + transferGas
+	}
+	base, err := currentBaseFee(ctx, ec)
+	if err != nil {
+		return nil
+	}
+	price := new(big.Int).Add(base, gGasTipWei)
+	g.costWei = new(big.Int).Mul(price, new(big.Int).SetUint64(g.gas))
+	throttle()
+	if v, err := core.QuoteTokenToETHOn(ctx, gChain, ec, r.tokenAddress, r.balanceWei); err == nil {
+		g.valueWei = v
+	}
+
+	gGasMu.Lock()
+	gGasEstimated++
+	if g.overValue() == "yes" {
+		gGasOverValue++
+	}
+	gGasMu.Unlock()
+	return g
+}
+
+// currentBaseFee is the latest block's base fee, read at most once per block time.
+func currentBaseFee(ctx context.Context, ec *ethclient.Client) (*big.Int, error) {
+	gBaseFeeMu.Lock()
+	defer gBaseFeeMu.Unlock()
+	if gBaseFee != nil && time.Since(gBaseFeeAt) < baseFeeMaxAge {
+		return gBaseFee, nil
+	}
+	throttle()
+	h, err := ec.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.BaseFee == nil {
+		return nil, fmt.Errorf("no base fee (pre-London chain)")
+	}
+	gBaseFee, gBaseFeeAt = h.BaseFee, time.Now()
+	return gBaseFee, nil
+}
+
+// gasReport is the end-of-run line of -gas-estimate ("" when off).
+func gasReport() string {
+	if !gGasEstimate {
+		return ""
+	}
+	gGasMu.Lock()
+	defer gGasMu.Unlock()
+	return fmt.Sprintf("[gas] %d OK pair(s) estimated, %d cost more gas than their balance is worth (gasOverValue=yes)", gGasEstimated, gGasOverValue)
+}
+
+// gasColumns are the CSV cells route,gasEstimate,gasCostWei,valueWei,gasOverValue.
+func gasColumns(r pairRow) []string {
+	g := r.gas
+	if g == nil {
+		return []string{r.route, "", "", "", ""}
+	}
+	value := ""
+	if g.valueWei != nil {
+		value = g.valueWei.String()
+	}
+	return []string{r.route, fmt.Sprintf("%d", g.gas), g.costWei.String(), value, g.overValue()}
+}
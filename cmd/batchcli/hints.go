@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ligun0805/bundle-rescue/internal/errhelp"
+)
+
+// printHints explains the BAD reasons of the run that the error catalog knows, most
+// frequent first, each once.
+func printHints(hints map[string]int) {
+	codes := make([]string, 0, len(hints))
+	for c := range hints {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if hints[codes[i]] != hints[codes[j]] {
+			return hints[codes[i]] > hints[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	for _, c := range codes {
+		e, _ := errhelp.ByCode(c)
+		fmt.Printf("[%s] %d pair(s)\n", c, hints[c])
+		for _, l := range e.Lines() {
+			fmt.Println("  " + l)
+		}
+	}
+}
@@ -11,11 +11,11 @@ import (
 	"fmt"
 	"io"
 	"math/big"
-	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -25,22 +25,46 @@ import (
   "github.com/ethereum/go-ethereum/rpc"
 
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/chaos"
+	"github.com/ligun0805/bundle-rescue/internal/features"
+	"github.com/ligun0805/bundle-rescue/internal/config"
+	"github.com/ligun0805/bundle-rescue/internal/errhelp"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/metacache"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/profile"
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+	"github.com/ligun0805/bundle-rescue/internal/rpcpin"
+	"github.com/ligun0805/bundle-rescue/internal/rpcpool"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+	"github.com/ligun0805/bundle-rescue/internal/tokencatalog"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
 )
 
 // RPC client used for eth_call stateOverrides in 7702 preflight.
 var gStateOverrideRPC *rpc.Client
-// newEthClientWithTimeout dials RPC with keep-alives and sane timeouts.
-func newEthClientWithTimeout(rpcURL string) (*ethclient.Client, error) {
+// newRPCPool builds the -rpc endpoint pool (one or more URLs) with keep-alives.
+func newRPCPool(rpcList string) (*rpcpool.Pool, error) {
 	transport := &http.Transport{
 		MaxIdleConns:       100,
 		IdleConnTimeout:    90 * time.Second,
 		DisableCompression: false,
 	}
+	return rpcpool.New(rpcpool.Split(rpcList), transport)
+}
+
+// newEthClientWithTimeout dials RPC through the endpoint pool with sane timeouts.
+func newEthClientWithTimeout(pool *rpcpool.Pool) (*ethclient.Client, error) {
 	httpClient := &http.Client{
 		Timeout:   30 * time.Second,
-		Transport: transport,
+		Transport: rpcpin.Transport(rpcmetrics.Transport(rateLimitTransport{pool}), gAtBlock),
 	}
-	rpcClient, err := rpc.DialHTTPWithClient(rpcURL, httpClient)
+	rpcClient, err := rpc.DialHTTPWithClient(pool.URL(), httpClient)
 	if err != nil {
 		return nil, err
 	}
@@ -55,28 +79,141 @@ type appConfig struct {
 	outOKPath      string
 	outBadPath     string
 	rpcDelay       time.Duration
+	ratePerSec     float64 // RPC token bucket refill (0 = derived from rpcDelay)
+	rateBurst      int     // RPC token bucket size (0 = workers)
 	rowDelay       time.Duration
 	pairTimeout    time.Duration
 	preflightAttempts int
 	preflightAttemptTimeout time.Duration
+	cacheTTL       time.Duration
+	adaptive       bool           // scale pair/preflight timeouts with observed RPC latency
+	adaptiveBounds adaptiveBounds // limits for -adaptive-timeout
   showPairLogs   bool
+	redactOut      string // if set: only rewrite -input with key fingerprints and exit
+	keyrefSecret   string
+	schedule       string // if set: re-run the scan per schedule and alert on new OK pairs
+	alertWebhook   string
+	noPrompt       bool // never wait for Enter on exit (CI / automation)
+	tokenAllowlist string
+	tokenDenylist  string
+	spamFilter     bool // demote likely airdrop/spam tokens to outSpamPath
+	outSpamPath    string
+	chaos          string // dev builds: fault injection spec (internal/chaos)
+	privacy        bool   // mask balances in console output (CSV keeps full values)
+	precision      string // fractional digits of balanceTokens columns; "full" = all
+	format         string // csv | json (NDJSON records)
+	catalogPath    string // cumulative token catalog updated by every scan ("" = off)
+	catalogExport  string // if set: export the catalog (CSV, or JSON by extension) and exit
+	workers        int    // pairs checked in parallel, fair across tokens (1 = sequential)
+	comparePath    string // previous run's OK output to diff this run against ("" = off)
+	compareOut     string // optional CSV with the -compare changes
+	dbPath         string // SQLite results database ("" = off; needs a -tags sqlite build)
+	dbSkipUnchanged time.Duration // reuse stored verdicts of pairs checked within this window whose balance is unchanged
+	usd            string // price source for the usdValue column: coingecko | uniswap ("" = off)
+	sortBy         string // order OK output: balance | usd ("" = scan order)
+	top            int    // write only the N most valuable OK pairs (0 = all)
+	triagePath     string // triage sidecar CSV edited by -mark / -triage-list
+	mark           string // if set: mark the positional pairs with this status and exit
+	markNote       string
+	triageList     bool // print the triage sidecar and exit
+	atBlock        uint64 // pin every read to this block (0 = chain tip)
+	duplicates     string // drop | keep rows repeating a (from, token) pair
+	dedupeReport   string // CSV listing the dropped duplicate rows ("" = off)
+	configPath     string // TOML/YAML settings file (flags > env > file)
+	metaCachePath  string // token metadata cache across runs ("" = off)
+	recheckOnly    bool   // cached tokens: re-read balances/preflight only
+	resume         bool   // continue an interrupted run from its checkpoint, appending to the outputs
+	progress       string // in-place progress line: auto | on | off
+	chainID        string // expected chain (ID or name); "" = whatever the RPC reports
+	gasEstimate    bool   // add route/gas/cost/value columns to OK pairs
+	gasTipGwei     string // priority fee added to the base fee in the gas cost
+	nftScan        string // also scan the input's wallets for ERC-721/1155 holdings into this CSV ("" = off)
+	nftLookback    uint64 // blocks of transfer logs searched for NFT candidates
+	queueOut       string // merge the OK pairs into this bundlegui session file ("" = off)
+	pairLogJSON    string // structured per-pair log, one JSON object per pair ("" = off)
+	checks         string // -checks: stages after the balance read (all | balance | list)
+	retryBad       string // if set: re-check this BAD output's RPC-failed rows on rpc2 and merge the verdicts
+	rpc2           string // second provider for -retry-bad
+	inputData      []byte // rows to scan instead of reading inputPath (-retry-bad, -sample)
+	sample         int    // check this many random rows and extrapolate instead of the full scan (0 = off)
 }
 
 func getenv(key, def string) string {
 	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
 		return v
 	}
+	if v, ok := gFileEnv[key]; ok {
+		return v
+	}
 	return def
 }
 
 func mustLoadConfig() appConfig {
 	var cfg appConfig
-	flag.StringVar(&cfg.inputPath, "input", getenv("BATCH_INPUT", ""), "Path to CSV with pairs: token,privateKey")
+	// -config is read before the flags are defined: its values are the flag defaults below env.
+	cfg.configPath = configPathFromArgs(os.Args[1:])
+	if cfg.configPath == "" {
+		cfg.configPath = gProfile.File("batchcli.toml")
+	}
+	if cfg.configPath != "" {
+		if err := loadConfigFile(cfg.configPath); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Config)
+		}
+	}
+	flag.StringVar(&cfg.configPath, "config", cfg.configPath, "TOML (or .yaml) file with [rpc], [throttle], [preflight], [output], [scan] settings; flags and env override it")
+	flag.StringVar(&cfg.inputPath, "input", getenv("BATCH_INPUT", ""), "Path to CSV with pairs: token,privateKey (\"-\" = stdin)")
 	flag.StringVar(&cfg.outOKPath, "out-ok", getenv("BATCH_OUT_OK", "ok_pairs.csv"), "Output CSV for promising pairs")
 	flag.StringVar(&cfg.outBadPath, "out-bad", getenv("BATCH_OUT_BAD", "bad_pairs.csv"), "Output CSV for rejected pairs")
-	flag.StringVar(&cfg.rpcURL, "rpc", getenv("RPC_URL", ""), "RPC endpoint URL")
-	flag.StringVar(&cfg.safePrivateHex, "safe-pk", getenv("SAFE_PRIVATE_KEY", ""), "SAFE private key (hex) to receive tokens")
+	flag.StringVar(&cfg.rpcURL, "rpc", getenv("RPC_URL", ""), "RPC endpoint URL; several comma-separated URLs fail over on 429/5xx/timeouts; or one ws:// / wss:// URL")
+	flag.StringVar(&cfg.safePrivateHex, "safe-pk", getenv("SAFE_PRIVATE_KEY", ""), "SAFE private key (hex, or env:NAME / file:/path) to receive tokens")
   flag.BoolVar(&cfg.showPairLogs, "pair-logs", false, "Print per-pair diagnostic logs to stdout")
+	flag.StringVar(&cfg.redactOut, "redact-out", getenv("BATCH_REDACT_OUT", ""), "Rewrite -input to this CSV with private keys replaced by address + HMAC fingerprint, then exit")
+	flag.StringVar(&cfg.schedule, "schedule", getenv("BATCH_SCHEDULE", ""), "Re-run the scan on a schedule: duration (6h), @hourly, @daily or \"M H * * *\"")
+	flag.StringVar(&cfg.alertWebhook, "alert-webhook", getenv("BATCH_ALERT_WEBHOOK", ""), "Scheduled mode: POST newly transferable pairs (JSON, no keys) to this URL")
+	flag.String("profile", gProfile.Label(), "Named profile: profiles/<name>/.env (+ batchcli.toml as -config); also PROFILE")
+	flag.BoolVar(&cfg.noPrompt, "no-prompt", getenv("BATCH_NO_PROMPT", "") == "1", "Exit without waiting for Enter (CI/automation); see exit codes in README")
+	flag.StringVar(&cfg.tokenAllowlist, "token-allowlist", getenv("BATCH_TOKEN_ALLOWLIST", ""), "File with token addresses (one per line): scan only these tokens")
+	flag.StringVar(&cfg.tokenDenylist, "token-denylist", getenv("BATCH_TOKEN_DENYLIST", ""), "File with token addresses (one per line) to skip, e.g. known spam")
+	flag.BoolVar(&cfg.spamFilter, "spam-filter", getenv("BATCH_SPAM_FILTER", "") == "1", "Move likely spam/airdrop tokens from OK to -out-spam (liquidity, name, holders, verification)")
+	flag.StringVar(&cfg.outSpamPath, "out-spam", getenv("BATCH_OUT_SPAM", "spam_pairs.csv"), "Output CSV for pairs demoted by -spam-filter")
+	flag.StringVar(&cfg.chaos, "chaos", getenv("BATCH_CHAOS", ""), "Dev builds (-tags chaos): inject RPC timeouts/429/relay 5xx/nonce races, e.g. \"timeout=0.05,429=0.1\" or \"1\"")
+	flag.BoolVar(&cfg.privacy, "privacy", getenv("PRIVACY_DISPLAY", "") == "1", "Show balances/amounts in console logs as magnitude buckets (shared screens); CSVs keep full values")
+	flag.StringVar(&cfg.precision, "precision", getenv("BATCH_PRECISION", getenv("OUTPUT_PRECISION", "6")), "Fractional digits of formatted token amounts in CSVs/logs (truncated); \"full\" = all decimals. Raw wei columns are always exact")
+	flag.StringVar(&cfg.format, "format", getenv("BATCH_FORMAT", formatCSV), "Output format: csv, or json = NDJSON per-pair records (verdict, reasonCode, warnings, timings); .csv output paths become .ndjson")
+	flag.IntVar(&cfg.workers, "workers", getenvInt("BATCH_WORKERS", 1), "Pairs checked in parallel; rows are spread round-robin across tokens, one pair per token at a time (1 = sequential, file order)")
+	flag.StringVar(&cfg.comparePath, "compare", getenv("BATCH_COMPARE", ""), "Previous run's OK output (CSV or NDJSON): report newly transferable, newly blocked and balance changes at the end")
+	flag.StringVar(&cfg.compareOut, "compare-out", getenv("BATCH_COMPARE_OUT", ""), "With -compare: also write the changes to this CSV")
+	flag.StringVar(&cfg.dbPath, "db", getenv("BATCH_DB", ""), "SQLite results database: store every verdict with reasons, report changed verdicts (builds with -tags sqlite)")
+	flag.DurationVar(&cfg.dbSkipUnchanged, "db-skip-unchanged", getenvDuration("BATCH_DB_SKIP_UNCHANGED", 0), "With -db: pairs checked within this window (e.g. 24h) whose balance did not change keep their stored verdict")
+	flag.StringVar(&cfg.usd, "usd", getenv("BATCH_USD", ""), "Add an estimated usdValue column to OK pairs and list the most valuable wallets: coingecko or uniswap (on-chain, mainnet)")
+	flag.StringVar(&cfg.sortBy, "sort", getenv("BATCH_SORT", ""), "Write OK pairs most valuable first, after the scan: balance (whole tokens) or usd (needs -usd)")
+	flag.IntVar(&cfg.top, "top", getenvInt("BATCH_TOP", 0), "Write only the N most valuable OK pairs (implies -sort usd with -usd, else balance); 0 = all")
+	flag.StringVar(&cfg.chainID, "chain-id", getenv("BATCH_CHAIN_ID", ""), "Expected chain, ID or name (base, arbitrum, bsc...): the RPC must be on it; selects the WETH/V2 factory of the sell-route preflight. Rows with another value in the optional third column \"chain\" are skipped")
+	flag.BoolVar(&cfg.gasEstimate, "gas-estimate", getenv("BATCH_GAS_ESTIMATE", "") == "1", "Estimate the sponsor gas of each OK pair's route and its cost at the current base fee; adds gasEstimate, gasCostWei, valueWei and gasOverValue columns")
+	flag.StringVar(&cfg.gasTipGwei, "gas-tip-gwei", getenv("BATCH_GAS_TIP_GWEI", "2"), "With -gas-estimate: priority fee (gwei) added to the base fee")
+	flag.StringVar(&cfg.pairLogJSON, "pair-log-json", getenv("BATCH_PAIR_LOG_JSON", ""), "Also write a structured per-pair log here: one JSON object per pair with every check step, verdict, reason code/error class and timings (no keys); \"\" = off")
+	flag.StringVar(&cfg.queueOut, "queue-out", getenv("BATCH_QUEUE_OUT", ""), "Also merge the OK pairs (decimals, balances, to = SAFE) into this bundlegui queue file, e.g. its pairs_session.json; \"\" = off")
+	flag.StringVar(&cfg.checks, "checks", getenv("BATCH_CHECKS", "all"), "Check stages after the balance read: all, balance (none), the stages to run (restrictions,guards,preflight,optional-return,sell) or the ones to skip (-guards,-sell)")
+	flag.IntVar(&cfg.sample, "sample", getenvInt("BATCH_SAMPLE", 0), "Check N random input rows and extrapolate the full scan: expected OK rate, recoverable value, RPC calls and time (0 = off; outputs go to *.sample.csv)")
+	flag.StringVar(&cfg.retryBad, "retry-bad", getenv("BATCH_RETRY_BAD", ""), "Re-check the pairs of this BAD output rejected for rpc_timeout/rpc_rate_limited on -rpc2, then merge: new OK pairs are appended to -out-ok, -out-bad is rewritten corrected")
+	flag.StringVar(&cfg.rpc2, "rpc2", getenv("BATCH_RPC2", ""), "Second RPC endpoint for -retry-bad (same forms as -rpc)")
+	flag.StringVar(&cfg.nftScan, "nft-scan", getenv("BATCH_NFT_SCAN", ""), "Also list the ERC-721/ERC-1155 holdings of every input wallet in this CSV (token,privateKey,from,standard,tokenId,amount), e.g. nft_pairs.csv; \"\" = off")
+	flag.Uint64Var(&cfg.nftLookback, "nft-lookback", uint64(getenvInt("BATCH_NFT_LOOKBACK", 200_000)), "With -nft-scan: blocks of transfer logs searched for NFTs sent to the wallets")
+	flag.Uint64Var(&cfg.atBlock, "at-block", uint64(getenvInt("BATCH_AT_BLOCK", 0)), "Run every read (balances, restrictions, preflights) at this historical block instead of the chain tip: reproducible snapshots, post-incident analysis (archive RPC for old blocks)")
+	flag.StringVar(&cfg.duplicates, "duplicates", getenv("BATCH_DUPLICATES", dupDrop), "Rows repeating a (from, token) pair: drop = check the first row only (no double-counted balances), keep = check every row")
+	flag.StringVar(&cfg.dedupeReport, "dedupe-report", getenv("BATCH_DEDUPE_REPORT", ""), "Write the dropped duplicate rows (line, first line, from, token; no keys) to this CSV")
+	flag.StringVar(&cfg.metaCachePath, "cache", getenv("BATCH_CACHE", ""), "Token metadata cache (decimals, symbol, proxy, permit) kept across runs, e.g. metadata.db; \"\" = off")
+	flag.BoolVar(&cfg.resume, "resume", false, "Continue a run stopped by Ctrl+C/SIGTERM: skip the rows listed in its checkpoint (<out-ok>.checkpoint.json) and append to the outputs")
+	flag.BoolVar(&cfg.recheckOnly, "recheck-balances-only", getenv("BATCH_RECHECK_BALANCES_ONLY", "") == "1", "With -cache: tokens already in the cache skip the static metadata reads; only balances and preflights are re-read")
+	flag.StringVar(&cfg.progress, "progress", getenv("BATCH_PROGRESS", progressAuto), "One-line progress on stderr (processed/total, ok/bad, rows/s, ETA): auto = only on a terminal without -pair-logs, on, off")
+	flag.StringVar(&cfg.catalogPath, "catalog", getenv("BATCH_CATALOG", "token_catalog.json"), "Cumulative token catalog (symbol, decimals, risk, verified, first-seen) updated by every scan; \"\" = off")
+	flag.StringVar(&cfg.catalogExport, "catalog-export", getenv("BATCH_CATALOG_EXPORT", ""), "Export -catalog to this file (.json = JSON, otherwise CSV) and exit")
+	flag.StringVar(&cfg.triagePath, "triage", getenv("TRIAGE_FILE", "triage.csv"), "Triage sidecar CSV (from,token,status,note,updated) used by -mark and -triage-list; executors read it via TRIAGE_FILE")
+	flag.StringVar(&cfg.mark, "mark", "", "Mark the pairs given as arguments (FROM:TOKEN, FROM = all its tokens, or an OK output file) approved, review, declined or clear in -triage, then exit")
+	flag.StringVar(&cfg.markNote, "mark-note", "", "With -mark: note stored with the marks (e.g. who/why)")
+	flag.BoolVar(&cfg.triageList, "triage-list", false, "Print the -triage marks and exit")
+	flag.StringVar(&cfg.keyrefSecret, "keyref-secret", getenv("KEYREF_SECRET", ""), "Secret for key fingerprints (-redact-out); keep it private")
 
 	// Delay between RPC calls (helps avoid 429 / -32005). Default: 200 ms.
 	delayEnv := getenv("BATCH_RPC_DELAY_MS", "200")
@@ -84,7 +221,13 @@ func mustLoadConfig() appConfig {
 	if v, err := strconv.Atoi(strings.TrimSpace(delayEnv)); err == nil && v >= 0 {
 		delayMS = v
 	}
-	flag.IntVar(&delayMS, "rpc-delay-ms", delayMS, "Delay between RPC calls in milliseconds")
+	flag.IntVar(&delayMS, "rpc-delay-ms", delayMS, "Delay between RPC calls in milliseconds; without -rate-per-sec sets the rate limit to workers calls per delay (0 = no limit)")
+	rateEnv := getenv("BATCH_RATE_PER_SEC", "0")
+	if v, err := strconv.ParseFloat(strings.TrimSpace(rateEnv), 64); err == nil && v >= 0 {
+		cfg.ratePerSec = v
+	}
+	flag.Float64Var(&cfg.ratePerSec, "rate-per-sec", cfg.ratePerSec, "RPC rate limit: token refill per second, shared by all workers; halves on 429/-32005 and recovers when healthy (0 = from -rpc-delay-ms)")
+	flag.IntVar(&cfg.rateBurst, "rate-burst", getenvInt("BATCH_RATE_BURST", 0), "RPC rate limit bucket size: calls allowed back to back (0 = -workers)")
 
 	// Explicit per-pair delay to avoid provider bursts across different wallets.
 	rowDelayEnv := getenv("BATCH_ROW_DELAY_MS", "300")
@@ -116,31 +259,218 @@ func mustLoadConfig() appConfig {
 	}
 	flag.IntVar(&pfAttemptTOMS, "preflight-attempt-timeout-ms", pfAttemptTOMS, "Timeout per preflight attempt (ms)")
 
+	// Cache restrictions/preflight results for identical (token, from, to, amount). Default: off.
+	cacheTTLEnv := getenv("BATCH_CACHE_TTL_MS", "0")
+	cacheTTLMS := 0
+	if v, err := strconv.Atoi(strings.TrimSpace(cacheTTLEnv)); err == nil && v >= 0 {
+		cacheTTLMS = v
+	}
+	flag.IntVar(&cacheTTLMS, "cache-ttl-ms", cacheTTLMS, "Cache restrictions/preflight results for this long (ms, 0 = off)")
+
+	// Adaptive timeouts: scale -pair-timeout-ms / preflight attempts with measured RPC latency.
+	flag.BoolVar(&cfg.adaptive, "adaptive-timeout", getenv("BATCH_ADAPTIVE_TIMEOUT", "") == "1", "Scale pair timeout and preflight attempts with observed RPC latency (within the min/max bounds)")
+	pairMinMS := flag.Int("pair-timeout-min-ms", getenvInt("BATCH_PAIR_TIMEOUT_MIN_MS", 3000), "Adaptive mode: lower bound for the pair timeout (ms)")
+	pairMaxMS := flag.Int("pair-timeout-max-ms", getenvInt("BATCH_PAIR_TIMEOUT_MAX_MS", 60000), "Adaptive mode: upper bound for the pair timeout (ms)")
+	flag.IntVar(&cfg.adaptiveBounds.attemptsMin, "preflight-attempts-min", getenvInt("BATCH_PREFLIGHT_ATTEMPTS_MIN", 1), "Adaptive mode: fewest preflight attempts")
+	flag.IntVar(&cfg.adaptiveBounds.attemptsMax, "preflight-attempts-max", getenvInt("BATCH_PREFLIGHT_ATTEMPTS_MAX", 6), "Adaptive mode: most preflight attempts")
+
 
 	flag.Parse()
+	if err := applyFileFlags(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		askExitAndQuit(exitcode.Config)
+	}
+	gNoPrompt = cfg.noPrompt
+	if gProfileErr != nil {
+		fmt.Fprintln(os.Stderr, gProfileErr.Error())
+		askExitAndQuit(exitcode.Config)
+	}
+	privacy.Enable(cfg.privacy)
+	gPrecision = units.ParsePrecision(cfg.precision, 6)
+	if err := explorer.LoadEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		askExitAndQuit(exitcode.Config)
+	}
+	if err := core.LoadSelectorEnv(gProfile.Path(core.DefaultSelectorFile)); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		askExitAndQuit(exitcode.Config)
+	}
 
+	// Secret references (env:NAME, file:/run/secrets/...) keep keys out of argv and .env files.
+	for _, f := range []*string{&cfg.safePrivateHex, &cfg.keyrefSecret, &cfg.rpcURL, &cfg.rpc2} {
+		v, err := config.ResolveSecret(*f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Config)
+		}
+		*f = v
+	}
+	cfg.format = strings.ToLower(strings.TrimSpace(cfg.format))
+	if cfg.format == "ndjson" {
+		cfg.format = formatJSON
+	}
+	if cfg.format != formatCSV && cfg.format != formatJSON {
+		fmt.Fprintf(os.Stderr, "-format %q: expected csv or json\n", cfg.format)
+		askExitAndQuit(exitcode.Config)
+	}
+	cfg.usd = strings.ToLower(strings.TrimSpace(cfg.usd))
+	if cfg.usd != "" && cfg.usd != usdCoinGecko && cfg.usd != usdUniswap {
+		fmt.Fprintf(os.Stderr, "-usd %q: expected coingecko or uniswap\n", cfg.usd)
+		askExitAndQuit(exitcode.Config)
+	}
+	cfg.sortBy = strings.ToLower(strings.TrimSpace(cfg.sortBy))
+	if cfg.top < 0 {
+		fmt.Fprintf(os.Stderr, "-top %d: expected 0 (all) or more\n", cfg.top)
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.top > 0 && cfg.sortBy == "" {
+		cfg.sortBy = sortBalance
+		if cfg.usd != "" {
+			cfg.sortBy = sortUSD
+		}
+	}
+	switch {
+	case cfg.sortBy != "" && cfg.sortBy != sortBalance && cfg.sortBy != sortUSD:
+		fmt.Fprintf(os.Stderr, "-sort %q: expected balance or usd\n", cfg.sortBy)
+		askExitAndQuit(exitcode.Config)
+	case cfg.sortBy == sortUSD && cfg.usd == "":
+		fmt.Fprintln(os.Stderr, "-sort usd needs -usd coingecko|uniswap")
+		askExitAndQuit(exitcode.Config)
+	}
+	cfg.duplicates = strings.ToLower(strings.TrimSpace(cfg.duplicates))
+	if cfg.duplicates != dupDrop && cfg.duplicates != dupKeep {
+		fmt.Fprintf(os.Stderr, "-duplicates %q: expected drop or keep\n", cfg.duplicates)
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.dedupeReport != "" && cfg.duplicates == dupKeep {
+		fmt.Fprintln(os.Stderr, "-dedupe-report needs -duplicates drop")
+		askExitAndQuit(exitcode.Config)
+	}
+	gDuplicates, gDedupeReport = cfg.duplicates, cfg.dedupeReport
+	if cfg.workers < 1 || cfg.workers > 64 {
+		fmt.Fprintf(os.Stderr, "-workers %d: expected 1..64\n", cfg.workers)
+		askExitAndQuit(exitcode.Config)
+	}
+	cfg.outOKPath, cfg.outBadPath, cfg.outSpamPath = outputPath(cfg.outOKPath, cfg.format), outputPath(cfg.outBadPath, cfg.format), outputPath(cfg.outSpamPath, cfg.format)
+	if cfg.dbSkipUnchanged > 0 && cfg.dbPath == "" {
+		fmt.Fprintln(os.Stderr, "-db-skip-unchanged needs -db")
+		askExitAndQuit(exitcode.Config)
+	}
+	gDBSkipUnchanged = cfg.dbSkipUnchanged
+	if cfg.recheckOnly && cfg.metaCachePath == "" {
+		fmt.Fprintln(os.Stderr, "-recheck-balances-only needs -cache")
+		askExitAndQuit(exitcode.Config)
+	}
+	gRecheckOnly = cfg.recheckOnly
+	cfg.progress = strings.ToLower(strings.TrimSpace(cfg.progress))
+	if cfg.progress != progressAuto && cfg.progress != progressOn && cfg.progress != progressOff {
+		fmt.Fprintf(os.Stderr, "-progress %q: expected auto, on or off\n", cfg.progress)
+		askExitAndQuit(exitcode.Config)
+	}
+	gProgress = cfg.progress
+	if cfg.atBlock > 0 && cfg.schedule != "" {
+		fmt.Fprintln(os.Stderr, "-at-block cannot be combined with -schedule: every pass would read the same block")
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.atBlock > 0 && cfg.dbSkipUnchanged > 0 {
+		fmt.Fprintln(os.Stderr, "-at-block cannot be combined with -db-skip-unchanged: stored verdicts come from other blocks")
+		askExitAndQuit(exitcode.Config)
+	}
+	gAtBlock = cfg.atBlock
+	gGasEstimate = cfg.gasEstimate
+	if tip, err := units.Parse(cfg.gasTipGwei, 9); err != nil || tip.Sign() < 0 {
+		fmt.Fprintf(os.Stderr, "-gas-tip-gwei %q: expected a non-negative amount of gwei\n", cfg.gasTipGwei)
+		askExitAndQuit(exitcode.Config)
+	} else {
+		gGasTipWei = tip
+	}
+	if cfg.nftScan != "" && cfg.nftLookback == 0 {
+		fmt.Fprintln(os.Stderr, "-nft-lookback must be > 0")
+		askExitAndQuit(exitcode.Config)
+	}
+	gNFTScan, gNFTLookback = cfg.nftScan, cfg.nftLookback
+	if c, err := parseChecks(cfg.checks); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		askExitAndQuit(exitcode.Config)
+	} else {
+		gChecks = c
+	}
+	if cfg.comparePath != "" && cfg.schedule != "" {
+		fmt.Fprintln(os.Stderr, "-compare cannot be combined with -schedule: scheduled mode already diffs every pass against the previous one")
+		askExitAndQuit(exitcode.Config)
+	}
+	if strings.TrimSpace(cfg.inputPath) == "-" && cfg.schedule != "" {
+		fmt.Fprintln(os.Stderr, "-input - (stdin) cannot be combined with -schedule: stdin can only be read once")
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.resume && (strings.TrimSpace(cfg.inputPath) == "-" || cfg.schedule != "") {
+		fmt.Fprintln(os.Stderr, "-resume needs a file -input and no -schedule")
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.sample < 0 {
+		fmt.Fprintln(os.Stderr, "-sample must be >= 0")
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.sample > 0 && (cfg.schedule != "" || cfg.resume || cfg.retryBad != "" || cfg.comparePath != "") {
+		fmt.Fprintln(os.Stderr, "-sample cannot be combined with -schedule, -resume, -retry-bad or -compare")
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.retryBad != "" {
+		switch {
+		case cfg.rpc2 == "":
+			fmt.Fprintln(os.Stderr, "-retry-bad needs -rpc2 (or BATCH_RPC2): the second provider to re-check on")
+			askExitAndQuit(exitcode.Config)
+		case cfg.format != formatCSV:
+			fmt.Fprintln(os.Stderr, "-retry-bad works on CSV outputs (-format csv)")
+			askExitAndQuit(exitcode.Config)
+		case cfg.schedule != "" || cfg.resume || cfg.comparePath != "":
+			fmt.Fprintln(os.Stderr, "-retry-bad cannot be combined with -schedule, -resume or -compare")
+			askExitAndQuit(exitcode.Config)
+		}
+		cfg.inputPath = cfg.retryBad
+		cfg.rpcURL = cfg.rpc2
+	}
+
+	if cfg.catalogExport != "" || cfg.mark != "" || cfg.triageList {
+		return cfg // offline: no input/RPC needed
+	}
 	if cfg.inputPath == "" {
 		fmt.Fprintln(os.Stderr, "missing -input (or BATCH_INPUT) file with rows: token,privateKey")
-		askExitAndQuit(2)
+		askExitAndQuit(exitcode.Config)
+	}
+	if cfg.redactOut != "" {
+		// Redaction is offline: no RPC/SAFE needed.
+		if strings.TrimSpace(cfg.keyrefSecret) == "" {
+			fmt.Fprintln(os.Stderr, "missing fingerprint secret: set -keyref-secret or KEYREF_SECRET")
+			askExitAndQuit(exitcode.Config)
+		}
+		return cfg
 	}
 	if cfg.rpcURL == "" {
 		fmt.Fprintln(os.Stderr, "missing RPC: set -rpc or RPC_URL")
-		askExitAndQuit(2)
+		askExitAndQuit(exitcode.Config)
 	}
 	if strings.TrimSpace(cfg.safePrivateHex) == "" {
 		fmt.Fprintln(os.Stderr, "missing SAFE private key: set -safe-pk or SAFE_PRIVATE_KEY")
-		askExitAndQuit(2)
+		askExitAndQuit(exitcode.Config)
 	}
 	cfg.rpcDelay = time.Duration(delayMS) * time.Millisecond
 	cfg.rowDelay = time.Duration(rowDelayMS) * time.Millisecond
 	cfg.pairTimeout = time.Duration(pairTimeoutMS) * time.Millisecond
 	cfg.preflightAttempts = pfAttempts
 	cfg.preflightAttemptTimeout = time.Duration(pfAttemptTOMS) * time.Millisecond
+	cfg.cacheTTL = time.Duration(cacheTTLMS) * time.Millisecond
+	cfg.adaptiveBounds.pairMin = time.Duration(*pairMinMS) * time.Millisecond
+	cfg.adaptiveBounds.pairMax = time.Duration(*pairMaxMS) * time.Millisecond
 	return cfg
 }
 
 type pairRow struct {
-	warn          string
+	lineNo        int
+	malformed     bool          // not enough columns: only the raw line is reported
+	reused        bool          // verdict taken from the results database (-db-skip-unchanged)
+	timings       pairTimings
+	warns         warnings.List // soft problems, reported in their own CSV columns
 	tokenHex      string
 	privateHex    string
 	fromAddress   common.Address
@@ -148,89 +478,653 @@ type pairRow struct {
 	tokenSymbol   string
 	tokenDecimals int
 	balanceWei    *big.Int
+	usdValue      string // estimated USD value of the balance (-usd); "" = no price
+	transferTax   string // simulated fee-on-transfer, percent ("" = not measured)
+	permit        string // EIP-2612 permit support: yes | no ("" = not probed)
+	route         string // rescue route the preflight passed: transfer | sell | userop ("" = unknown)
+	gas           *gasEstimate // -gas-estimate: sponsor gas and cost of the route (nil = not estimated)
 	reason        string
+	code          reasons.Code // canonical code of reason; "" = derived from the text (see reasonCode)
 }
 
 func main() {
+	// --profile NAME loads profiles/NAME/.env before anything reads the environment.
+	profileName, args := profile.Extract(os.Args[1:])
+	os.Args = append(os.Args[:1], args...)
+	if profileName != "" {
+		if gProfile, gProfileErr = profile.Load(profileName); gProfileErr == nil {
+			fmt.Println(gProfile.Banner())
+		}
+	}
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		os.Exit(features.Command("batchcli", os.Args[2:]))
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "seal" || os.Args[1] == "unseal") {
+		if gProfileErr != nil {
+			fmt.Fprintln(os.Stderr, gProfileErr.Error())
+			os.Exit(exitcode.Config)
+		}
+		os.Exit(runSealCmd(os.Args[1], os.Args[2:]))
+	}
 	cfg := mustLoadConfig()
-	setRPCDelay(cfg.rpcDelay)
+	if cfg.mark != "" || cfg.triageList {
+		var err error
+		if cfg.mark != "" {
+			err = markTriage(cfg.triagePath, cfg.mark, cfg.markNote, flag.Args())
+		} else {
+			err = listTriage(cfg.triagePath)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Config)
+		}
+		return
+	}
+	if cfg.catalogExport != "" {
+		if err := exportCatalog(cfg.catalogPath, cfg.catalogExport); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Config)
+		}
+		return
+	}
+	if cfg.redactOut != "" {
+		n, err := redactCSV(cfg.inputPath, cfg.redactOut, []byte(cfg.keyrefSecret))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Config)
+		}
+		fmt.Printf("Redacted %d row(s) => %s\n", n, cfg.redactOut)
+		return
+	}
+	setRateLimit(cfg.ratePerSec, cfg.rateBurst, cfg.rpcDelay, cfg.workers)
 	setPairTimeout(cfg.pairTimeout)
 	setPreflightRetryConfig(cfg.preflightAttempts, cfg.preflightAttemptTimeout)
-	if err := run(cfg); err != nil {
+	setResultCacheTTL(cfg.cacheTTL)
+	setAdaptive(cfg.adaptive, cfg.adaptiveBounds)
+	if cfg.chaos != "" {
+		c, err := chaos.Enable(cfg.chaos)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Config)
+		}
+		fmt.Println("[chaos] ENABLED:", c)
+	}
+	if err := setTokenLists(cfg.tokenAllowlist, cfg.tokenDenylist); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		askExitAndQuit(exitcode.Config)
+	}
+	if off := gChecks.skipped(); len(off) > 0 {
+		fmt.Printf("[checks] not checked: %s — OK pairs are flagged checks_skipped\n", strings.Join(off, ", "))
+	}
+	watchSignals()
+	if cfg.schedule != "" {
+		runScheduled(cfg)
+		return
+	}
+	// Read the previous run before this one overwrites outputs (it may be the same file).
+	var prevOK map[string]okPair
+	if cfg.comparePath != "" {
+		if _, err := os.Stat(cfg.comparePath); err != nil {
+			fmt.Fprintln(os.Stderr, "-compare:", err)
+			askExitAndQuit(exitcode.Config)
+		}
+		p, err := readOKSet(cfg.comparePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-compare %s: %v\n", cfg.comparePath, err)
+			askExitAndQuit(exitcode.Config)
+		}
+		prevOK = p
+	}
+	var bad int
+	var err error
+	switch {
+	case cfg.sample > 0:
+		if err := runSample(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			askExitAndQuit(exitcode.Of(err))
+		}
+		return
+	case cfg.retryBad != "":
+		bad, err = runRetryBad(cfg)
+	default:
+		bad, err = run(cfg)
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
-		askExitAndQuit(1)
+		for _, l := range errhelp.Explain(err.Error()) {
+			fmt.Fprintln(os.Stderr, "  "+l)
+		}
+		askExitAndQuit(exitcode.Of(err))
+	}
+	if prevOK != nil {
+		if err := runCompare(cfg, prevOK); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
 	}
 	fmt.Println("Done. OK =>", cfg.outOKPath, " BAD =>", cfg.outBadPath)
+	if bad > 0 {
+		os.Exit(exitcode.Partial)
+	}
 }
 
+// gProfile is the --profile in use (nil: environment and -config only). A profile that
+// fails to load is reported once -no-prompt is known (gProfileErr).
+var (
+	gProfile    *profile.Profile
+	gProfileErr error
+)
+
+// gNoPrompt is set by -no-prompt: askExitAndQuit exits right away.
+var gNoPrompt bool
+
 // askExitAndQuit prints a prompt and waits for Enter before exiting.
 // This avoids instant window close on double-click runs (Windows).
 func askExitAndQuit(code int) {
-	fmt.Fprint(os.Stderr, "Exit now? Press Enter to close...")
-	_, _ = bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if !gNoPrompt {
+		fmt.Fprint(os.Stderr, "Exit now? Press Enter to close...")
+		_, _ = bufio.NewReader(os.Stdin).ReadBytes('\n')
+	}
 	os.Exit(code)
 }
 
-func run(cfg appConfig) error {
-	ec, err := newEthClientWithTimeout(cfg.rpcURL)
+// run scans cfg.inputPath once and returns how many pairs were written to the BAD CSV.
+func run(cfg appConfig) (bad int, err error) {
+	ec, pool, err := dialRPC(cfg.rpcURL)
 	if err != nil {
-		return fmt.Errorf("dial rpc: %w", err)
+		return 0, err
 	}
 	defer ec.Close()
+	rpcmetrics.Default.Reset() // scheduled mode: report per pass
+	stagetime.Reset()
+	resetAliveCache()
+	resetStaticMeta()
+	resetAdaptive()
+	resetNFTWallets()
+	defer func() {
+		for _, l := range rpcmetrics.Report(rpcpool.Split(cfg.rpcURL)[0]) {
+			fmt.Println(l)
+		}
+		for _, l := range pool.Report() {
+			fmt.Println(l)
+		}
+		if l := chaos.Summary(); l != "" {
+			fmt.Println(l)
+		}
+		if l := staticMetaReport(); l != "" {
+			fmt.Println(l)
+		}
+		if l := rateLimitReport(); l != "" {
+			fmt.Println(l)
+		}
+		if l := gasReport(); l != "" {
+			fmt.Println(l)
+		}
+		for _, l := range stagetime.Report() {
+			fmt.Println(l)
+		}
+	}()
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), 10*time.Second)
+	chainID, err := ec.ChainID(pingCtx)
+	cancelPing()
+	if err != nil {
+		return 0, exitcode.Wrap(exitcode.RPC, fmt.Errorf("rpc unreachable: %w", err))
+	}
+	if err := checkPoolChain(pool, chainID); err != nil {
+		return 0, exitcode.Wrap(exitcode.Config, err)
+	}
+	if err := gProfile.CheckChain(chainID); err != nil {
+		return 0, exitcode.Wrap(exitcode.Config, err)
+	}
+	if err := resolveChain(cfg.chainID, chainID); err != nil {
+		return 0, exitcode.Wrap(exitcode.Config, err)
+	}
+	if err := checkPinnedBlock(ec); err != nil {
+		return 0, err
+	}
 
 	// Best-effort RPC client for stateOverrides (7702 preflight).
-	if rc, e := rpc.DialContext(context.Background(), cfg.rpcURL); e == nil {
+	if pool == nil {
+		gStateOverrideRPC = ec.Client() // WebSocket: same connection
+	} else if rc, e := rpc.DialOptions(context.Background(), pool.URL(), rpc.WithHTTPClient(&http.Client{Transport: rpcpin.Transport(rpcmetrics.Transport(pool), gAtBlock)})); e == nil {
 		gStateOverrideRPC = rc
 	}
 
 	safePriv, err := hexToECDSA(cfg.safePrivateHex)
 	if err != nil {
-		return fmt.Errorf("SAFE key: %w", err)
+		return 0, exitcode.Wrap(exitcode.Config, fmt.Errorf("SAFE key: %w", err))
 	}
 	safeAddress := gethcrypto.PubkeyToAddress(safePriv.PublicKey)
 
-	data, err := os.ReadFile(cfg.inputPath)
-	if err != nil {
-		return fmt.Errorf("open input: %w", err)
+	// -input is streamed (see streaminput.go): rows are checked as they are read, with bounded
+	// memory; only in-memory inputs (-sample, -retry-bad) and sealed files are read whole.
+	stdin := strings.TrimSpace(cfg.inputPath) == "-"
+	stream := cfg.inputData == nil
+	var data []byte
+	var in io.Reader
+	var src *inputStream
+	if stream {
+		if src, err = openInputStream(cfg.inputPath); err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, fmt.Errorf("open input: %w", err))
+		}
+		defer src.Close()
+		in = src
+		stream = !src.sealed() // a sealed container is decrypted whole, it cannot be streamed
+	}
+	if !stream {
+		if in != nil {
+			data, err = io.ReadAll(in)
+			if err == nil {
+				data, err = unsealInput(data)
+			}
+		} else {
+			data = cfg.inputData
+		}
+		if err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, fmt.Errorf("open input: %w", err))
+		}
+		in = bytes.NewReader(data)
+	}
+	inputHash := func() (string, error) {
+		if !stream {
+			return runmanifest.HashBytes(data), nil
+		}
+		return src.hash()
+	}
+
+	// Ctrl+C / SIGTERM: this defer runs after the outputs below are flushed and closed.
+	gDoneLines, gResumeDone = nil, nil
+	cpPath := checkpointPath(cfg.outOKPath)
+	if cfg.resume {
+		h, herr := inputHash()
+		if herr != nil {
+			return 0, exitcode.Wrap(exitcode.Config, fmt.Errorf("open input: %w", herr))
+		}
+		if gResumeDone, err = loadCheckpoint(cpPath, h); err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, err)
+		}
+		fmt.Printf("[resume] %d row(s) already done in %s are skipped\n", len(gResumeDone), cpPath)
+	}
+	defer func() {
+		switch {
+		case err != nil:
+		case stopRequested() && cfg.schedule != "":
+			err = exitcode.Wrap(exitcode.Interrupted, errors.New("interrupted: outputs flushed"))
+		case stopRequested() && stdin:
+			err = exitcode.Wrap(exitcode.Interrupted, errors.New("interrupted: outputs flushed; stdin input has no checkpoint, feed the remaining rows again"))
+		case stopRequested():
+			h, werr := inputHash()
+			n := 0
+			if werr == nil {
+				n, werr = writeCheckpoint(cpPath, cfg.inputPath, h)
+			}
+			if werr != nil {
+				err = exitcode.Wrap(exitcode.Interrupted, fmt.Errorf("interrupted: outputs flushed, but checkpoint %s: %w", cpPath, werr))
+				return
+			}
+			err = exitcode.Wrap(exitcode.Interrupted, fmt.Errorf("interrupted: outputs flushed, %d row(s) done => %s; continue with -resume", n, cpPath))
+		case cfg.resume:
+			_ = os.Remove(cpPath)
+		}
+	}()
+
+	gCatalog, gCatalogChain = nil, chainID.String()
+	if cfg.catalogPath != "" {
+		if gCatalog, err = tokencatalog.Load(cfg.catalogPath); err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, err)
+		}
+		defer func() {
+			if err := gCatalog.Save(); err != nil {
+				fmt.Fprintln(os.Stderr, "catalog:", err)
+				return
+			}
+			n, added := gCatalog.Len()
+			fmt.Printf("[catalog] %d token(s), %d new => %s\n", n, added, cfg.catalogPath)
+		}()
+	}
+
+	gMetaCache = nil
+	if cfg.metaCachePath != "" {
+		if gMetaCache, err = metacache.Load(cfg.metaCachePath); err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, err)
+		}
+		defer func() {
+			if err := gMetaCache.Save(); err != nil {
+				fmt.Fprintln(os.Stderr, "cache:", err)
+				return
+			}
+			n, hits, stored := gMetaCache.Stats()
+			fmt.Printf("[cache] %d token(s), %d cached lookup(s), %d stored => %s\n", n, hits, stored, cfg.metaCachePath)
+		}()
+	}
+
+	// Run manifest next to the OK CSV: what config/input/chain/blocks produced these results.
+	man := runmanifest.New("batchcli", time.Now().Format("20060102_150405"), manifestConfig(cfg, safeAddress))
+	if !stream {
+		man.SetInput(cfg.inputPath, data)
+	}
+	man.SetChainID(chainID)
+	man.MarkBlock(context.Background(), ec)
+	man.Outputs = []string{cfg.outOKPath, cfg.outBadPath}
+	if cfg.spamFilter {
+		man.Outputs = append(man.Outputs, cfg.outSpamPath)
+	}
+	if cfg.nftScan != "" {
+		man.Outputs = append(man.Outputs, cfg.nftScan)
+	}
+	if cfg.queueOut != "" {
+		man.Outputs = append(man.Outputs, cfg.queueOut)
+	}
+	if cfg.pairLogJSON != "" {
+		man.Outputs = append(man.Outputs, cfg.pairLogJSON)
+	}
+	defer func() {
+		if stream {
+			if h, herr := inputHash(); herr == nil {
+				man.SetInputHash(cfg.inputPath, h)
+			}
+		}
+		man.MarkBlock(context.Background(), ec)
+		man.Result = fmt.Sprintf("bad=%d", bad)
+		if stopRequested() {
+			man.Result += " interrupted"
+		}
+		if err != nil {
+			man.Result = "error: " + err.Error()
+		}
+		path := runmanifest.PathFor(cfg.outOKPath)
+		if werr := man.Write(path); werr != nil {
+			fmt.Fprintln(os.Stderr, "manifest:", werr)
+			return
+		}
+		fmt.Printf("[manifest] %s (config %s, input %s)\n", path, man.ConfigHash, man.InputHash)
+	}()
+
+	gSpam = nil
+	if cfg.spamFilter {
+		if gSpam, err = newSpamFilter(); err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, err)
+		}
+		defer func() { fmt.Printf("[spam] %d pair(s) demoted => %s\n", gSpam.count, cfg.outSpamPath) }()
+	}
+	gUSD = nil
+	if cfg.usd != "" {
+		if gUSD, err = newUSDPricer(cfg.usd, chainID.String()); err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, err)
+		}
+		defer gUSD.report()
 	}
 
-	okW, badW, err := openOutputs(cfg.outOKPath, cfg.outBadPath)
+	sink, closeOut, err := openSink(cfg)
 	if err != nil {
-		return fmt.Errorf("open outputs: %w", err)
+		return 0, exitcode.Wrap(exitcode.Config, fmt.Errorf("open outputs: %w", err))
+	}
+	defer closeOut() // scheduled mode calls run repeatedly; don't leak handles
+	if cfg.pairLogJSON != "" {
+		pl, err := openPairJSONLog(cfg.pairLogJSON, cfg.resume)
+		if err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, fmt.Errorf("-pair-log-json: %w", err))
+		}
+		gPairJSON = pl
+		defer func() {
+			_ = gPairJSON.close()
+			gPairJSON = nil
+		}()
+	}
+	if cfg.queueOut != "" {
+		q := newQueueSink(sink, safeAddress, cfg.inputPath)
+		defer func() {
+			if err == nil {
+				if qerr := q.write(cfg.queueOut); qerr != nil {
+					fmt.Fprintln(os.Stderr, "-queue-out:", qerr)
+				}
+			}
+		}()
+		sink = q
 	}
-	defer okW.Flush()
-	defer badW.Flush()
+	if cfg.sortBy != "" {
+		ord := newOrderedSink(sink, cfg.sortBy, cfg.top)
+		defer ord.flush() // before closeOut: the files are still open
+		sink = ord
+	}
+	if cfg.dbPath != "" {
+		dbs, err := openResultsDB(cfg.dbPath, sink, man.InputHash, man.ConfigHash)
+		if err != nil {
+			return 0, exitcode.Wrap(exitcode.Config, err)
+		}
+		defer func() { dbs.finish(cfg.dbPath, bad, err) }()
+		sink = dbs
+	}
+
+	rs := newReasonSink(sink)
+	bad, err = processInput(ec, safeAddress, in, stream, rs, cfg.rowDelay, cfg.showPairLogs, cfg.workers)
+	rs.print()
+	man.ReasonCodes = rs.tally.Map()
+	if err == nil && gNFTScan != "" && !stopRequested() {
+		if nerr := runNFTScan(ec); nerr != nil {
+			fmt.Fprintln(os.Stderr, "-nft-scan:", nerr)
+		}
+	}
+	return bad, err
+}
 
-	// headers
-	_ = okW.Write([]string{"token", "privateKey", "from", "symbol", "decimals", "balanceTokens"})
-	_ = badW.Write([]string{"token", "privateKey", "from", "reason"})
+// manifestConfig is the settings part of the run manifest. Keys are reduced to addresses,
+// the RPC URL to its host (provider URLs embed API keys).
+func manifestConfig(cfg appConfig, safe common.Address) map[string]string {
+	m := map[string]string{
+		"rpc":                     manifestEndpoints(cfg.rpcURL),
+		"safe":                    safe.Hex(),
+		"rpcDelay":                cfg.rpcDelay.String(),
+		"ratePerSec":              strconv.FormatFloat(cfg.ratePerSec, 'f', -1, 64),
+		"rateBurst":               strconv.Itoa(cfg.rateBurst),
+		"rowDelay":                cfg.rowDelay.String(),
+		"pairTimeout":             cfg.pairTimeout.String(),
+		"preflightAttempts":       strconv.Itoa(cfg.preflightAttempts),
+		"preflightAttemptTimeout": cfg.preflightAttemptTimeout.String(),
+		"cacheTTL":                cfg.cacheTTL.String(),
+		"adaptiveTimeout":         fmt.Sprintf("%v pair=%s..%s attempts=%d..%d", cfg.adaptive, cfg.adaptiveBounds.pairMin, cfg.adaptiveBounds.pairMax, cfg.adaptiveBounds.attemptsMin, cfg.adaptiveBounds.attemptsMax),
+		"tokenAllowlist":          fileHashOrEmpty(cfg.tokenAllowlist),
+		"tokenDenylist":           fileHashOrEmpty(cfg.tokenDenylist),
+		"spamFilter":              strconv.FormatBool(cfg.spamFilter),
+		"chaos":                   cfg.chaos,
+		"precision":               strconv.Itoa(gPrecision),
+		"format":                  cfg.format,
+		"workers":                 strconv.Itoa(cfg.workers),
+		"usd":                     cfg.usd,
+		"sort":                    cfg.sortBy,
+		"top":                     strconv.Itoa(cfg.top),
+		"atBlock":                 strconv.FormatUint(cfg.atBlock, 10),
+		"chainId":                 cfg.chainID,
+		"gasEstimate":             fmt.Sprintf("%v tip=%s gwei", cfg.gasEstimate, cfg.gasTipGwei),
+		"nftScan":                 fmt.Sprintf("%s lookback=%d", cfg.nftScan, cfg.nftLookback),
+		"queueOut":                cfg.queueOut,
+		"pairLogJSON":             cfg.pairLogJSON,
+		"checks":                  cfg.checks,
+		"retryBad":                fileHashOrEmpty(cfg.retryBad),
+		"sample":                  fmt.Sprint(cfg.sample),
+		"rpc2":                    manifestEndpoints(cfg.rpc2),
+		"config":                  fileHashOrEmpty(cfg.configPath),
+		"duplicates":              cfg.duplicates,
+		"recheckBalancesOnly":     strconv.FormatBool(cfg.recheckOnly),
+	}
+	if gProfile != nil {
+		m["profile"] = gProfile.Name
+	}
+	return m
+}
 
-	return processBytes(ec, safeAddress, data, okW, badW, cfg.rowDelay, cfg.showPairLogs)
+// fileHashOrEmpty hashes a list file so an edited allow/denylist changes the config hash.
+func fileHashOrEmpty(path string) string {
+	if strings.TrimSpace(path) == "" {
+		return ""
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return path + " (unreadable)"
+	}
+	return path + " " + runmanifest.HashBytes(b)
 }
 
-func processBytes(ec *ethclient.Client, safeAddr common.Address, data []byte, okW, badW *csv.Writer, rowDelay time.Duration, showPairLogs bool) error {
+// processInput scans CSV rows from in and returns the number of BAD rows.
+// With workers > 1 pairs run in parallel (see fairsched.go) and are written in completion order.
+// stream (a file or -input -): rows are checked as they are read and every verdict is flushed
+// to the outputs right away, so batchcli can sit in a pipeline and memory stays bounded;
+// otherwise (in-memory inputs) the input is parsed (and deduplicated) completely first.
+func processInput(ec *ethclient.Client, safeAddr common.Address, in io.Reader, stream bool, sink pairSink, rowDelay time.Duration, showPairLogs bool, workers int) (int, error) {
 	// Delimiter auto-detect on the first non-empty line
-	delim := detectDelimiter(data)
-	reader := csv.NewReader(strings.NewReader(string(data)))
+	br := bufio.NewReader(in)
+	head, err := readHead(br)
+	if err != nil {
+		return 0, err
+	}
+	delim := detectDelimiter(head)
+	reader := csv.NewReader(io.MultiReader(bytes.NewReader(head), br))
 	reader.FieldsPerRecord = -1
 	reader.TrimLeadingSpace = true
 	reader.Comma = delim
 
 	lineNo := 0
-	for {
+	bad := 0
+	seen := map[string]int{} // (from, token) -> first line; duplicates are merged into it
+	merged := 0
+	var dups []dupRow
+	var filtered tokenFilterStats
+	otherChains := otherChainRows{}
+	var jobs []pairJob
+	var mu sync.Mutex // sink, counters and catalog are shared by the workers
+	var prog *progress // in-memory input: started once the rows are counted
+	inputPos := func() float64 { return -1 }
+	if stream {
+		prog = newProgress(-1, showPairLogs)
+		if f, ok := in.(interface{ fraction() float64 }); ok {
+			inputPos = f.fraction
+		}
+	}
+	hints := map[string]int{} // errhelp code -> BAD pairs, explained once at the end
+	process := func(j pairJob) {
+		lineNo, tokenHex := j.lineNo, j.tokenHex
+		started := time.Now()
+		result, spamReasons, reused := reuseCheck(ec, j)
+		if reused {
+			pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "balance unchanged — verdict of the last check reused (-db-skip-unchanged)")
+		} else {
+			result = processOne(ec, safeAddr, tokenHex, j.privateHex, j.accountHex, showPairLogs, lineNo)
+		}
+		result.lineNo, result.timings.Total = lineNo, time.Since(started)
+
+		if !reused && result.reason == "" && gSpam != nil {
+			sctx, cancel := context.WithTimeout(context.Background(), getPairTimeout())
+			spamStart := time.Now()
+			spamReasons = gSpam.check(sctx, ec, result.tokenAddress, result.tokenSymbol)
+			cancel()
+			result.timings.Spam = time.Since(spamStart)
+			result.timings.Total += result.timings.Spam
+		}
+		if result.reason == "" && spamReasons == nil && gUSD != nil {
+			result.usdValue = gUSD.value(ec, result)
+		}
+		if result.reason == "" && spamReasons == nil && gGasEstimate {
+			result.gas = estimateRescueGas(ec, result, safeAddr)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		prog.at(j.inputPos)
+		adaptiveNotePair()
+		noteLineDone(lineNo)
+		defer func() {
+			verdict := "ok"
+			switch {
+			case result.reason != "":
+				verdict = "bad"
+			case spamReasons != nil:
+				verdict = "spam"
+			}
+			gPairJSON.write(result, verdict, spamReasons) // after the RESULT line, which ends its steps
+		}()
+		switch {
+		case result.reason != "":
+			// Soft warnings (decimals/symbol/balance) go to their own columns, not into the reason.
+			badReason := result.reason
+			bad++
+			if e, ok := errhelp.Lookup(badReason); ok {
+				hints[e.Code]++
+			}
+			sink.Bad(result)
+			prog.note("bad")
+      pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "RESULT: BAD — %s", badReason)
+			catalogNote(result, "bad", badReason)
+		case spamReasons != nil:
+			gSpam.demote()
+			sink.Spam(result, spamReasons)
+			prog.note("spam")
+			pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "RESULT: SPAM — %s", strings.Join(spamReasons, "; "))
+			catalogNote(result, "spam", strings.Join(spamReasons, "; "))
+		default:
+			sink.OK(result)
+			prog.note("ok")
+      pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "RESULT: OK — symbol=%s decimals=%d balance=%s",
+        result.tokenSymbol, result.tokenDecimals, formatTokensFromWei(result.balanceWei, result.tokenDecimals))
+			if len(result.warns) > 0 {
+				pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "WARNINGS: %s", result.warns)
+			}
+			catalogNote(result, "ok", "")
+		}
+	}
+	paced := func(j pairJob) {
+		if stopRequested() {
+			return // shutdown: rows not started yet stay for -resume
+		}
+		process(j)
+		if stream {
+			mu.Lock()
+			syncSink(sink)
+			gPairJSON.flush()
+			mu.Unlock()
+		}
+		// per-pair delay before moving to next pair (per worker)
+		if rowDelay > 0 {
+			time.Sleep(rowDelay)
+		}
+	}
+	var queue *fairQueue // stream with workers > 1: rows are queued as they are read
+	waitQueue := func() {}
+	if stream && workers > 1 {
+		queue = newFairQueue()
+		queue.limit = streamQueueMax // bounded read-ahead: the input is never held whole
+		waitQueue = queue.run(workers, paced)
+	}
+
+	for !stopRequested() {
 		row, e := reader.Read()
 		if e != nil {
 			if errors.Is(e, io.EOF) {
 				break
 			}
-			return e
+			if queue != nil {
+				queue.close()
+				waitQueue()
+			}
+			return bad, e
 		}
 		lineNo++
 		if skipRow(row, lineNo) {
 			continue
 		}
+		done := gResumeDone[lineNo] // -resume: verdict already in the outputs (still deduped against below)
 		if len(row) < 2 {
-			_ = badW.Write([]string{strings.Join(row, string([]rune{delim})), "", "", "not enough columns, expected token,privateKey"})
+			if done {
+				continue
+			}
+			mu.Lock()
+			bad++
+			sink.Bad(pairRow{lineNo: lineNo, malformed: true, tokenHex: strings.Join(row, string([]rune{delim})), reason: "not enough columns, expected token,privateKey", code: reasons.MalformedRow})
+			noteLineDone(lineNo)
+			prog.note("bad")
+			gPairJSON.write(pairRow{lineNo: lineNo, tokenHex: strings.Join(row, string([]rune{delim})), reason: "not enough columns, expected token,privateKey", code: reasons.MalformedRow}, "bad", nil)
+			if stream {
+				syncSink(sink)
+			}
+			mu.Unlock()
 			// per-pair delay even on malformed row
 			if rowDelay > 0 {
 				time.Sleep(rowDelay)
@@ -238,43 +1132,129 @@ func processBytes(ec *ethclient.Client, safeAddr common.Address, data []byte, ok
 			continue
 		}
 
-		tokenHex, privateHex := strings.TrimSpace(row[0]), strings.TrimSpace(row[1])
-		result := processOne(ec, safeAddr, tokenHex, privateHex, showPairLogs, lineNo)
-
-		if result.reason != "" {
-			// Attach collected "soft" warnings (decimals/symbol/balance) to reason for context.
-			badReason := result.reason
-			if strings.TrimSpace(result.warn) != "" {
-				badReason = badReason + " | " + result.warn
+		tokenHex, privateHex, accountHex := strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), rowAccount(row)
+		if id, ours, cerr := rowChain(row); cerr != nil {
+			if done {
+				continue
 			}
-			_ = badW.Write([]string{tokenHex, privateHex, result.fromAddress.Hex(), badReason})
-      pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "RESULT: BAD — %s", badReason)
-
-			// per-pair delay before moving to next pair
-			if rowDelay > 0 {
-				time.Sleep(rowDelay)
+			mu.Lock()
+			bad++
+			sink.Bad(pairRow{lineNo: lineNo, malformed: true, tokenHex: strings.Join(row, string([]rune{delim})), reason: "chain column: " + cerr.Error(), code: reasons.WrongChain})
+			gPairJSON.write(pairRow{lineNo: lineNo, tokenHex: strings.Join(row, string([]rune{delim})), reason: "chain column: " + cerr.Error(), code: reasons.WrongChain}, "bad", nil)
+			noteLineDone(lineNo)
+			prog.note("bad")
+			if stream {
+				syncSink(sink)
 			}
+			mu.Unlock()
+			continue
+		} else if !ours {
+			otherChains[id]++
+			if showPairLogs {
+				fmt.Printf("[chain] line %d: chain %d row — skipped, this run is on chain %d\n", lineNo, id, gChain.ID)
+			}
+			continue
+		}
+		noteNFTWallet(privateHex)
+		if skip, why := tokenFiltered(tokenHex, &filtered); skip {
+			if showPairLogs {
+				fmt.Printf("[filter] line %d: token %s %s — skipped\n", lineNo, tokenHex, why)
+			}
+			continue
+		}
+		if key, ok := pairDedupKey(tokenHex, privateHex, accountHex); ok && gDuplicates != dupKeep {
+			if first, dup := seen[key]; dup {
+				merged++
+				dups = append(dups, dupRow{line: lineNo, first: first, key: key})
+				if showPairLogs {
+					fmt.Printf("[dedupe] line %d: same (from, token) as line %d — merged, not processed twice\n", lineNo, first)
+				}
+				continue
+			}
+			seen[key] = lineNo
+		}
+		if done {
 			continue
 		}
+		j := pairJob{lineNo: lineNo, tokenHex: tokenHex, privateHex: privateHex, accountHex: accountHex, inputPos: inputPos()}
+		switch {
+		case !stream:
+			jobs = append(jobs, j)
+		case queue != nil:
+			queue.push(j)
+		default:
+			paced(j)
+		}
+	}
 
-		_ = okW.Write([]string{
-			tokenHex,
-			privateHex,
-			result.fromAddress.Hex(),
-			result.tokenSymbol,
-			fmt.Sprintf("%d", result.tokenDecimals),
-			formatTokensFromWei(result.balanceWei, result.tokenDecimals),
-		})
-    pairLogf(showPairLogs, lineNo, tokenHex, result.fromAddress, "RESULT: OK — symbol=%s decimals=%d balance=%s",
-      result.tokenSymbol, result.tokenDecimals, formatTokensFromWei(result.balanceWei, result.tokenDecimals))
+	switch {
+	case queue != nil:
+		queue.close()
+		waitQueue()
+	case stream: // every row was checked as it was read
+	case workers > 1:
+		prog = newProgress(len(jobs), showPairLogs)
+		runFair(jobs, workers, paced)
+	default:
+		prog = newProgress(len(jobs), showPairLogs)
+		for _, j := range jobs {
+			paced(j)
+		}
+	}
+	prog.finish()
 
-		// per-pair delay before next iteration
-		if rowDelay > 0 {
-			time.Sleep(rowDelay)
+	if merged > 0 {
+		fmt.Printf("[dedupe] %d duplicate row(s) merged by (from, token)\n", merged)
+	}
+	if gDedupeReport != "" {
+		if err := writeDedupeReport(gDedupeReport, dups); err != nil {
+			fmt.Fprintln(os.Stderr, "-dedupe-report:", err)
+		} else {
+			fmt.Printf("[dedupe] report: %d row(s) => %s\n", len(dups), gDedupeReport)
 		}
 	}
+	if gTokenAllow != nil || gTokenDeny != nil {
+		fmt.Printf("[filter] %s\n", filtered)
+	}
+	if len(otherChains) > 0 {
+		fmt.Printf("[chain] %s\n", otherChains)
+	}
+	printHints(hints)
 
-	return nil
+	return bad, nil
+}
+
+// pairDedupKey returns "from|token" (lower-case) for a row, from being the smart account when
+// the row has one; ok=false when the row is malformed (those are reported by processOne as usual).
+func pairDedupKey(tokenHex, privateHex, accountHex string) (string, bool) {
+	if !common.IsHexAddress(tokenHex) {
+		return "", false
+	}
+	k, err := hexToECDSA(privateHex)
+	if err != nil {
+		return "", false
+	}
+	from := gethcrypto.PubkeyToAddress(k.PublicKey)
+	if accountHex != "" {
+		from = common.HexToAddress(accountHex)
+	}
+	return strings.ToLower(from.Hex() + "|" + common.HexToAddress(tokenHex).Hex()), true
+}
+
+// readHead reads br up to and including the first non-empty line, which detectDelimiter
+// needs; the caller replays it in front of the rest of the input.
+func readHead(br *bufio.Reader) ([]byte, error) {
+	var head []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		head = append(head, line...)
+		if len(bytes.TrimSpace(line)) > 0 || errors.Is(err, io.EOF) {
+			return head, nil
+		}
+		if err != nil {
+			return head, err
+		}
+	}
 }
 
 func detectDelimiter(data []byte) rune {
@@ -308,76 +1288,125 @@ func skipRow(row []string, lineNo int) bool {
 	return false
 }
 
-func openOutputs(okPath, badPath string) (*csv.Writer, *csv.Writer, error) {
-	okF, err := os.Create(okPath)
-	if err != nil {
-		return nil, nil, err
-	}
-	badF, err := os.Create(badPath)
-	if err != nil {
-		_ = okF.Close()
-		return nil, nil, err
-	}
-	return csv.NewWriter(okF), csv.NewWriter(badF), nil
-}
-
-func processOne(ec *ethclient.Client, safeAddr common.Address, tokenHex, privateHex string, showPairLogs bool, lineNo int) pairRow {
+func processOne(ec *ethclient.Client, safeAddr common.Address, tokenHex, privateHex, accountHex string, showPairLogs bool, lineNo int) pairRow {
 	out := pairRow{tokenHex: tokenHex, privateHex: privateHex}
 	if !common.IsHexAddress(tokenHex) {
-		out.reason = "invalid token address"
+		out.reason, out.code = "invalid token address", reasons.InvalidToken
 		return out
 	}
 	out.tokenAddress = common.HexToAddress(tokenHex)
 
 	prv, err := hexToECDSA(privateHex)
 	if err != nil {
-		out.reason = "invalid private key"
+		out.reason, out.code = "invalid private key", reasons.InvalidKey
 		return out
 	}
 	out.fromAddress = gethcrypto.PubkeyToAddress(prv.PublicKey)
   pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "START")
+	if off := gChecks.skipped(); len(off) > 0 {
+		out.warns.Add(warnings.ChecksSkipped, "not checked: "+strings.Join(off, ", "))
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), getPairTimeout())
 	defer cancel()
-	var warnParts []string
+
+	// Smart account (third column): the pair is the account's balance, moved by a UserOperation.
+	acct, why := detectSmartAccount(ctx, ec, out.fromAddress, accountHex)
+	if why != "" {
+		out.reason = why
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "%s — stop", why)
+		return out
+	}
+	viability := checkTransferViability
+	if acct != nil {
+		out.fromAddress = acct.Address
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "smart account: EntryPoint %s (%s), owner %s", acct.EntryPoint.Hex(), acct.Version, gethcrypto.PubkeyToAddress(prv.PublicKey).Hex())
+		viability = func(ctx context.Context, ec *ethclient.Client, token, _, to common.Address, amount *big.Int) (string, string) {
+			return checkUserOpViability(ctx, ec, acct, token, to, amount)
+		}
+	}
+
+	// -recheck-balances-only: a token in the -cache skips the static reads (dead-token check,
+	// decimals, symbol, proxy lookup, permit probe); only balanceOf and preflight run.
+	cached, hit := cachedMeta(out.tokenAddress)
+	if !hit && checkOn(checkGuards) {
+		if reason := deadTokenReason(ctx, ec, out.tokenAddress); reason != "" {
+			out.reason = reason
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "getCode/totalSupply: %s — stop", reason)
+			return out
+		}
+	}
+
+	// decimals/symbol/balanceOf are independent reads: fetch them concurrently
+	// (still through the RPC gate and within the pair timeout).
+	metaStart := time.Now()
+	var meta tokenMeta
+	if hit {
+		meta.decimals, meta.symbol = cached.Decimals, cached.Symbol
+		meta.balance, meta.balErr = fetchTokenBalance(ctx, ec, out.tokenAddress, out.fromAddress)
+		if cached.Proxy != "" {
+			out.warns.Add(warnings.Proxy, "proxy(impl="+cached.Proxy+")")
+		}
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "metadata: cached (-recheck-balances-only)")
+	} else {
+		meta = fetchTokenMeta(ctx, ec, out.tokenAddress, out.fromAddress)
+		if meta.shared {
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "metadata: shared with an earlier row of this token")
+		}
+	}
+	out.timings.Meta = time.Since(metaStart)
+	stagetime.Add(stagetime.Metadata, out.timings.Meta)
+
+	// EIP-1967 proxy (upgradeable token): decimals()/symbol() failed on the proxy and were
+	// read from the implementation instead of reporting a broken token (see readStaticMeta).
+	proxyImpl := meta.proxy
+	if proxyImpl != (common.Address{}) {
+		out.warns.Add(warnings.Proxy, "proxy(impl="+proxyImpl.Hex()+")")
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "proxy(impl=%s): decimals()/symbol() read from the implementation", proxyImpl.Hex())
+	}
 
 	// decimals(): on failure assume 18 (do not reject)
-	dec, derr := fetchTokenDecimals(ctx, ec, out.tokenAddress)
+	dec, derr := meta.decimals, meta.decErr
 	if derr != nil {
-		// Keep going with 18, but remember why decimals failed for a better reason text later.
-		warnParts = append(warnParts, "decimals() failed: "+classifyCallError(ctx, ec, out.tokenAddress, derr))
+		// Keep going with 18; the fallback is reported as a warning, not as a failure.
+		why := rpcerrors.ClassifyCall(ctx, ec, out.tokenAddress, derr).Describe()
+		out.warns.Add(warnings.DecimalsFallback, "decimals() failed, 18 assumed: "+why)
 		out.tokenDecimals = 18
-    pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "decimals(): FAIL — %s", classifyCallError(ctx, ec, out.tokenAddress, derr))
+    pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "decimals(): FAIL — %s", why)
 	} else {
 		out.tokenDecimals = dec
     pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "decimals(): %d", dec)
+		if !hit {
+			storeMeta(out.tokenAddress, dec, meta.symbol, proxyImpl)
+		}
 	}
 
 	// symbol(): best-effort
-	if sym, e := fetchTokenSymbol(ctx, ec, out.tokenAddress); e == nil && sym != "" {
+	if sym, e := meta.symbol, meta.symErr; e == nil && sym != "" {
 		out.tokenSymbol = sym
     pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "symbol(): %s", sym)
 	} else if e != nil {
-		warnParts = append(warnParts, "symbol() failed: "+classifyCallError(ctx, ec, out.tokenAddress, e))
-    pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "symbol(): FAIL — %s", classifyCallError(ctx, ec, out.tokenAddress, e))
+		why := rpcerrors.ClassifyCall(ctx, ec, out.tokenAddress, e).Describe()
+		out.warns.Add(warnings.SymbolMissing, "symbol() failed: "+why)
+    pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "symbol(): FAIL — %s", why)
+	} else {
+		out.warns.Add(warnings.SymbolMissing, "symbol() returned empty")
 	}
 
 	// balanceOf(): if failed — fallback to preflight(1)
-	bal, berr := fetchTokenBalance(ctx, ec, out.tokenAddress, out.fromAddress)
+	bal, berr := meta.balance, meta.balErr
 	if berr != nil {
-		warnParts = append(warnParts, "balanceOf() failed: "+classifyCallError(ctx, ec, out.tokenAddress, berr))
-    pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "balanceOf(): FAIL — %s", classifyCallError(ctx, ec, out.tokenAddress, berr))
+		why := rpcerrors.ClassifyCall(ctx, ec, out.tokenAddress, berr).Describe()
+		out.warns.Add(warnings.BalanceUnknown, "balanceOf() failed: "+why)
+    pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "balanceOf(): FAIL — %s", why)
 	}
 	out.balanceWei = bal
 
 	// If balance successfully fetched and equals zero — stop further checks and mark BAD.
 	// This avoids running restrictions/preflight for addresses that simply hold no tokens.
 	if berr == nil && (bal == nil || bal.Sign() <= 0) {
-		out.reason = "no token balance"
+		out.reason, out.code = "no token balance", reasons.NoBalance
     pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "balanceOf(): 0 — stop, no preflight")
-		if len(warnParts) > 0 {
-			out.warn = strings.Join(warnParts, "; ")
-		}
 		return out
 	}
 
@@ -385,12 +1414,12 @@ func processOne(ec *ethclient.Client, safeAddr common.Address, tokenHex, private
 	// do a tiny preflight (1 wei) to see if the route is theoretically transferable.
 	if berr != nil {
 		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "preflight(): fallback 1 wei (balance unknown)")
-    if reason := checkTransferViability(ctx, ec, out.tokenAddress, out.fromAddress, safeAddr, big.NewInt(1)); reason != "" {
+    preflightStart := time.Now()
+    reason, _ := viability(ctx, ec, out.tokenAddress, out.fromAddress, safeAddr, big.NewInt(1))
+    out.timings.Preflight = time.Since(preflightStart)
+    if reason != "" {
 			out.reason = reason
       pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "preflight(): FAIL — %s", reason)
-			if len(warnParts) > 0 {
-				out.warn = strings.Join(warnParts, "; ")
-			}
 			return out
 		}
 		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "preflight(): OK")
@@ -400,43 +1429,112 @@ func processOne(ec *ethclient.Client, safeAddr common.Address, tokenHex, private
 
 	// Non-zero balance: regular strict preflight
 	pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "preflight(): start, amountWei=%s", bal.String())
-  if reason := checkTransferViability(ctx, ec, out.tokenAddress, out.fromAddress, safeAddr, bal); reason != "" {
+  preflightStart := time.Now()
+  reason, route := viability(ctx, ec, out.tokenAddress, out.fromAddress, safeAddr, bal)
+  out.route = route
+  out.timings.Preflight = time.Since(preflightStart)
+  if reason != "" {
 		out.reason = reason
     pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "preflight(): FAIL — %s", reason)
-		if len(warnParts) > 0 {
-			out.warn = strings.Join(warnParts, "; ")
-		}
 		return out
 	}
   pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "preflight(): OK")
+	if !checkOn(checkGuards) {
+		return out
+	}
+
+	// ERC-777 / ERC-1363: the transfer runs third-party hooks (which may reenter the delegate).
+	// Flag the token, and check both balance deltas instead of the recipient's only.
+	throttle()
+	hooks, herr := core.DetectTransferHooks(ctx, ec, out.tokenAddress, out.fromAddress, safeAddr)
+	if herr != nil {
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "transfer hooks: not checked — %v", herr)
+	} else if hooks.Any() {
+		detail := hooks.Summary()
+		if gStateOverrideRPC == nil {
+			detail += " (strict simulation skipped: RPC without stateOverrides)"
+		}
+		out.warns.Add(warnings.TransferHook, detail)
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "transfer hooks: %s", hooks.Summary())
+	}
+
+	// Fee-on-transfer: recipient balance delta of the same transfer (stateOverride probe).
+	if gStateOverrideRPC != nil {
+		throttle()
+		simulate := core.SimulateTransferTax
+		if hooks.Any() {
+			simulate = core.SimulateTransferStrict
+		}
+		if tax, err := simulate(ctx, gStateOverrideRPC, out.tokenAddress, out.fromAddress, safeAddr, bal); err == nil {
+			out.transferTax = tax.Pct()
+			if tax.Bps() > 0 {
+				out.warns.Add(warnings.TransferTax, fmt.Sprintf("transfer tax %s%%: SAFE receives %s of %s", tax.Pct(),
+					formatTokensFromWei(tax.Received, out.tokenDecimals), formatTokensFromWei(tax.Sent, out.tokenDecimals)))
+			}
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "transfer tax: %s%%", tax.Pct())
+		} else if hooks.Any() {
+			out.reason, out.code = "transfer hook: "+err.Error(), reasons.TransferHook
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "strict simulation: FAIL — %v", err)
+			return out
+		} else {
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "transfer tax: not measured — %v", err)
+		}
+	}
 
-	if len(warnParts) > 0 {
-		out.warn = strings.Join(warnParts, "; ")
+	// EIP-2612: permit-capable tokens allow a cheaper route (signed approval, no FROM gas).
+	if hit && cached.Permit != "" {
+		out.permit = cached.Permit
+		return out
 	}
+	throttle()
+	if ps, err := core.DetectPermit(ctx, ec, out.tokenAddress, out.fromAddress); err == nil {
+		out.permit = "no"
+		if ps.Supported {
+			out.permit = "yes"
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "permit: yes (nonce %s)", ps.Nonce)
+		} else {
+			pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "permit: no — %s", ps.Reason)
+		}
+		storePermit(out.tokenAddress, out.permit)
+	} else {
+		pairLogf(showPairLogs, lineNo, tokenHex, out.fromAddress, "permit: not probed — %v", err)
+	}
+
 	return out
 }
 
-func checkTransferViability(ctx context.Context, ec *ethclient.Client, token, from, to common.Address, amount *big.Int) string {
-	restr, err := core.CheckRestrictions(ctx, ec, token, from, to)
-	if err == nil && restr.Blocked() {
-		return "blocked: " + restr.Summary()
+// checkTransferViability returns "" and the rescue route (transfer | sell) when the pair
+// can be rescued, else the reason.
+func checkTransferViability(ctx context.Context, ec *ethclient.Client, token, from, to common.Address, amount *big.Int) (string, string) {
+	if checkOn(checkRestrictions) {
+		restrStart := time.Now()
+		restr, err := core.CachedCheckRestrictions(ctx, gResultCache, gResultCacheTTL, ec, token, from, to)
+		stagetime.Since(stagetime.Restrictions, restrStart)
+		if err == nil && restr.Blocked() {
+			return "blocked: " + restr.Summary(), ""
+		}
+	}
+	if !checkOn(checkPreflight) {
+		return "", "" // -checks: route unknown
 	}
+	defer stagetime.Since(stagetime.Preflight, time.Now())
 	// Preflight with short attempt timeouts and limited retries against transient RPC failures.
-	if reason := preflightWithRetry7702(ctx, ec, token, from, to, amount, getPreflightAttempts(), getPreflightAttemptTimeout()); reason != "" {
+	reason, route := preflightWithRetry7702(ctx, ec, token, from, to, amount, getPreflightAttempts(), getPreflightAttemptTimeout())
+	if reason != "" {
 		// Optional-return fallback (SafeERC20 semantics):
 		// If the failure looks like ABI/empty-output/boolean-decode issue, try raw eth_call and treat empty return as success.
-		if isOptionalReturnCandidate(reason) {
+		if checkOn(checkOptionalReturn) && isOptionalReturnCandidate(reason) {
 			ok, detail := optionalReturnTransferCall(ctx, ec, token, from, to, amount)
 			if ok {
-				return ""
+				return "", routeTransfer
 			}
 			if strings.TrimSpace(detail) != "" {
-				return detail
+				return detail, ""
 			}
 		}
-		return reason
+		return reason, ""
 	}
-	return ""
+	return "", route
 }
 
 // 7702-aware preflight with retries: simulates transfer() with stateOverrides (EOA has code).
@@ -448,36 +1546,46 @@ func preflightWithRetry7702(
 	amount *big.Int,
 	attempts int,
 	attemptTimeout time.Duration,
-) string {
+) (reason, route string) {
 	if attempts < 1 {
 		attempts = 1
 	}
+	chain := gChain
+	if !checkOn(checkSell) {
+		chain.V2Factory = common.Address{} // direct transfer only
+	}
 	backoff := 300 * time.Millisecond
 	for i := 1; i <= attempts; i++ {
 		attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
-		ok, why, err := core.PreflightTransfer7702(attemptCtx, ec, gStateOverrideRPC, token, from, to, amount)
+		ok, why, err := core.CachedPreflightTransfer7702On(attemptCtx, gResultCache, gResultCacheTTL, chain, ec, gStateOverrideRPC, token, from, to, amount)
 		cancel()
 
 		if err != nil {
 			// Retry only on transient transport-level problems; contract-level results should not retry.
-			if isTransientNetworkError(err) && i < attempts {
+			if rpcerrors.IsTransient(err) && i < attempts {
 				time.Sleep(backoff)
 				if backoff < 2*time.Second {
 					backoff *= 2
 				}
 				continue
 			}
-			return fmt.Sprintf("%s: %v", classifyRPCError(err), err)
+			return fmt.Sprintf("%s: %v", rpcerrors.Classify(err).Tag(), err), ""
 		}
 		if !ok {
 			if strings.TrimSpace(why) == "" {
-				return "not transferable: preflight 7702 failed"
+				return "not transferable: preflight 7702 failed", ""
 			}
-			return why // e.g., "blocked in 7702 context" / "no v2 pair ..." / "route=router"
+			if !checkOn(checkSell) && strings.HasPrefix(why, "no v2 factory known") {
+				return "not transferable: direct transfer failed (sell route not checked: -checks)", ""
+			}
+			return why, "" // e.g., "blocked in 7702 context" / "no v2 pair ..."
+		}
+		if why == "route=router" {
+			return "", routeSell // only the transfer into the V2 pair passed
 		}
-		return "" // success
+		return "", routeTransfer // success
 	}
-	return fmt.Sprintf("rpc_timeout: preflight 7702 attempts exhausted (attempts=%d)", attempts)
+	return fmt.Sprintf("rpc_timeout: preflight 7702 attempts exhausted (attempts=%d)", attempts), ""
 }
 
 // preflightWithRetry runs preflight with multiple short attempts to survive transient RPC issues.
@@ -495,15 +1603,14 @@ func preflightWithRetry(ctx context.Context, ec *ethclient.Client, token, from,
 
 		if err != nil {
 			// Retry only on transient transport-level problems; contract-level errors should bubble up.
-			if isTransientNetworkError(err) && i < attempts {
+			if rpcerrors.IsTransient(err) && i < attempts {
 				time.Sleep(backoff)
 				if backoff < 2*time.Second {
 					backoff *= 2
 				}
 				continue
 			}
-			cls := classifyRPCError(err)
-			return fmt.Sprintf("%s: %v", cls, err)
+			return fmt.Sprintf("%s: %v", rpcerrors.Classify(err).Tag(), err)
 		}
 		if !ok {
 			if strings.TrimSpace(why) == "" {
@@ -517,18 +1624,6 @@ func preflightWithRetry(ctx context.Context, ec *ethclient.Client, token, from,
 	return fmt.Sprintf("rpc_timeout: preflight attempts exhausted (attempts=%d)", attempts)
 }
 
-// isTransientNetworkError detects short-lived provider/transport failures worth retrying.
-func isTransientNetworkError(err error) bool {
-	s := strings.ToLower(err.Error())
-	if strings.Contains(s, "context deadline exceeded") { return true }
-	if strings.Contains(s, "client.timeout exceeded") { return true }
-	if strings.Contains(s, "i/o timeout") { return true }
-	if strings.Contains(s, "tls handshake timeout") { return true }
-	if strings.Contains(s, "eof") { return true }
-	if strings.Contains(s, "connection reset") { return true }
-	if strings.Contains(s, "502") || strings.Contains(s, "503") || strings.Contains(s, "504") { return true }
-	return false
-}
 
 
 // --- local helpers (copied, minimal, no refactor) ---
@@ -548,14 +1643,7 @@ func fetchTokenDecimals(ctx context.Context, ec *ethclient.Client, token common.
 	if err != nil {
 		return 0, err
 	}
-	if len(res) == 0 {
-		return 18, nil
-	}
-	// ABI uint8 encoded as 32 bytes (big-endian)
-	if len(res) < 32 {
-		return int(new(big.Int).SetBytes(res).Int64()), nil
-	}
-	return int(new(big.Int).SetBytes(res[len(res)-32:]).Int64()), nil
+	return units.DecodeDecimals(res)
 }
 
 func fetchTokenBalance(ctx context.Context, ec *ethclient.Client, token, owner common.Address) (*big.Int, error) {
@@ -572,7 +1660,16 @@ func fetchTokenBalance(ctx context.Context, ec *ethclient.Client, token, owner c
 }
 
 func fetchTokenSymbol(ctx context.Context, ec *ethclient.Client, token common.Address) (string, error) {
-	data := common.FromHex("0x95d89b41") // symbol()
+	return fetchTokenString(ctx, ec, token, "0x95d89b41") // symbol()
+}
+
+func fetchTokenName(ctx context.Context, ec *ethclient.Client, token common.Address) (string, error) {
+	return fetchTokenString(ctx, ec, token, "0x06fdde03") // name()
+}
+
+// fetchTokenString calls a string/bytes32 getter (symbol(), name()).
+func fetchTokenString(ctx context.Context, ec *ethclient.Client, token common.Address, selector string) (string, error) {
+	data := common.FromHex(selector)
 	throttle()
 	out, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: data})
 	if err != nil || len(out) == 0 {
@@ -589,6 +1686,27 @@ func fetchTokenSymbol(ctx context.Context, ec *ethclient.Client, token common.Ad
 	return strings.TrimRight(string(out), "\x00"), nil
 }
 
+// tokenMeta holds the read-only per-pair lookups done up front by processOne.
+type tokenMeta struct {
+	staticMeta
+	shared  bool // staticMeta came from an earlier row of the same token (tokenmeta.go)
+	balance *big.Int
+	balErr  error
+}
+
+// fetchTokenMeta runs balanceOf(owner) in parallel with the token's decimals()/symbol(),
+// which are read once per run and token (tokenStatic).
+// Each call keeps its own throttle/retry and takes a slot in rpcConcurrencyGate.
+func fetchTokenMeta(ctx context.Context, ec *ethclient.Client, token, owner common.Address) tokenMeta {
+	var m tokenMeta
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); m.balance, m.balErr = fetchTokenBalance(ctx, ec, token, owner) }()
+	m.staticMeta, m.shared = tokenStatic(ctx, ec, token)
+	wg.Wait()
+	return m
+}
+
 // --- RPC concurrency gate (limits parallel eth_call to protect the RPC) ---
 var rpcConcurrencyGate chan struct{}
 
@@ -602,17 +1720,7 @@ func init() {
 	rpcConcurrencyGate = make(chan struct{}, n)
 }
 
-// --- tiny RPC throttle/retry (batch-local) ---
-var gRPCDelay time.Duration
-
-func setRPCDelay(d time.Duration) { gRPCDelay = d }
-
-// throttle sleeps between RPC calls to avoid rate limiting.
-func throttle() {
-	if gRPCDelay > 0 {
-		time.Sleep(gRPCDelay)
-	}
-}
+// --- tiny RPC retry (batch-local; throttle() is in ratelimit.go) ---
 
 // callContractWithRetry wraps eth_call with small exponential backoff.
 func callContractWithRetry(ctx context.Context, ec *ethclient.Client, msg ethereum.CallMsg) ([]byte, error) {
@@ -631,7 +1739,8 @@ func callContractWithRetry(ctx context.Context, ec *ethclient.Client, msg ethere
 		lastErr = err
 		if attempt < maxAttempts {
 			time.Sleep(backoff)
-			if strings.Contains(err.Error(), "Too Many Requests") || strings.Contains(err.Error(), "-32005") {
+			if rpcerrors.IsRateLimited(err) {
+				gLimiter.throttled()
 				backoff *= 2
 			}
 		}
@@ -639,73 +1748,24 @@ func callContractWithRetry(ctx context.Context, ec *ethclient.Client, msg ethere
 	return nil, lastErr
 }
 
-// classifyRPCError returns a coarse class for RPC transport errors.
-func classifyRPCError(err error) string {
-	if err == nil {
-		return ""
-	}
-	if errors.Is(err, context.DeadlineExceeded) || strings.Contains(strings.ToLower(err.Error()), "context deadline exceeded") {
-		return "rpc_timeout"
-	}
-	var ne net.Error
-	if errors.As(err, &ne) && ne.Timeout() {
-		return "rpc_timeout"
-	}
-	s := strings.ToLower(err.Error())
-	if strings.Contains(s, "connection reset") || strings.Contains(s, "broken pipe") || strings.Contains(s, "eof") {
-		return "rpc_unavailable"
-	}
-	if strings.Contains(s, "too many requests") || strings.Contains(s, "-32005") {
-		return "rpc_rate_limited"
-	}
-	return "rpc_error"
-}
-
-
-// classifyCallError returns a short, user-facing explanation for common eth_call failures.
-// It does NOT change control flow; used only to produce richer "reason" strings.
-func classifyCallError(ctx context.Context, ec *ethclient.Client, target common.Address, err error) string {
-	if err == nil {
-		return ""
-	}
-	s := err.Error()
-	// 1) Provider throttling
-	if strings.Contains(s, "Too Many Requests") || strings.Contains(s, "-32005") {
-		return "[RATE_LIMIT] provider throttled the request"
-	}
-	// 2) Execution reverted (try to show revert reason)
-	if strings.Contains(s, "execution reverted") {
-		if idx := strings.Index(s, ":"); idx >= 0 && idx+1 < len(s) {
-			reason := strings.TrimSpace(s[idx+1:])
-			if reason != "" {
-				return "[REVERT] " + reason
-			}
-		}
-		return "[REVERT] execution reverted"
-	}
-	// 3) Not a contract (EOA / selfdestructed)
-	if code, e := ec.CodeAt(ctx, target, nil); e == nil && len(code) == 0 {
-		return "[NOT_CONTRACT] no bytecode at address"
-	}
-	// 4) ABI / return quirks
-	if strings.Contains(s, "unsupported") || strings.Contains(s, "abi") {
-		return "[UNSUPPORTED] ABI/return type mismatch"
-	}
-	// 5) Fallback
-	if strings.Contains(strings.ToLower(s), "invalid opcode") || strings.Contains(strings.ToLower(s), "0xfe") {
-		// Explicitly label VM invalid to distinguish from "revert".
-		return "[INVALID] invalid opcode during execution"
-	}
-	return "[RPC] " + s
-}
-
 // --- global knobs similar to RPC delay (no refactor through signatures) ---
 var gPairTimeout time.Duration
 var gPreflightAttempts int
 var gPreflightAttemptTimeout time.Duration
+var gResultCache core.Cache // nil = no caching of restrictions/preflight results
+var gResultCacheTTL time.Duration
+
+// setResultCacheTTL enables the in-memory result cache (ttl <= 0 disables it).
+func setResultCacheTTL(ttl time.Duration) {
+	if ttl <= 0 { gResultCache = nil; return }
+	gResultCache, gResultCacheTTL = core.NewMemoryCache(), ttl
+}
 
 func setPairTimeout(d time.Duration) { gPairTimeout = d }
 func getPairTimeout() time.Duration {
+	if d, ok := adaptivePairTimeout(); ok {
+		return d
+	}
 	if gPairTimeout > 0 {
 		return gPairTimeout
 	}
@@ -717,35 +1777,21 @@ func setPreflightRetryConfig(attempts int, attemptTimeout time.Duration) {
 	gPreflightAttempts = attempts
 	gPreflightAttemptTimeout = attemptTimeout
 }
-func getPreflightAttempts() int { if gPreflightAttempts < 1 { return 1 }; return gPreflightAttempts }
-func getPreflightAttemptTimeout() time.Duration { if gPreflightAttemptTimeout <= 0 { return 4 * time.Second }; return gPreflightAttemptTimeout }
+func getPreflightAttempts() int {
+	if n, _, ok := adaptivePreflight(); ok { return n }
+	if gPreflightAttempts < 1 { return 1 }; return gPreflightAttempts
+}
+func getPreflightAttemptTimeout() time.Duration {
+	if _, t, ok := adaptivePreflight(); ok { return t }
+	if gPreflightAttemptTimeout <= 0 { return 4 * time.Second }; return gPreflightAttemptTimeout
+}
+
 
+// gPrecision: fractional digits of formatted amounts (-precision); units.Full = all.
+var gPrecision = 6
 
 func formatTokensFromWei(x *big.Int, decimals int) string {
-	if x == nil {
-		return "0"
-	}
-	if decimals <= 0 {
-		return x.String()
-	}
-	base := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
-	var intPart big.Int
-	intPart.Quo(x, base)
-	var frac big.Int
-	frac.Rem(x, base)
-	// scale fractional to 6 digits
-	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(6), nil)
-	fracScaled := new(big.Int).Mul(&frac, scale)
-	fracScaled.Quo(fracScaled, base)
-	fs := fracScaled.String()
-	if len(fs) < 6 {
-		fs = strings.Repeat("0", 6-len(fs)) + fs
-	}
-	fs = strings.TrimRight(fs, "0")
-	if fs == "" {
-		return intPart.String()
-	}
-	return intPart.String() + "." + fs
+	return units.Format(x, decimals, gPrecision)
 }
 
 // ---- Optional-return preflight (SafeERC20 semantics) -------------------------------------------
@@ -790,20 +1836,17 @@ func optionalReturnTransferCall(ctx context.Context, ec *ethclient.Client, token
 		Data: data,
 	}, nil)
 	if err != nil {
-		low := strings.ToLower(err.Error())
-		if strings.Contains(low, "execution reverted") {
-			reason := extractRevertReason(low)
-			if reason == "" {
-				reason = "execution reverted"
+		switch c := rpcerrors.Classify(err); c.Kind {
+		case rpcerrors.Reverted:
+			if c.Reason == "" {
+				return false, "not transferable: execution reverted"
 			}
-			return false, "not transferable: " + reason
-		}
-		if strings.Contains(low, "invalid opcode") || strings.Contains(low, "0xfe") {
+			return false, "not transferable: " + c.Reason
+		case rpcerrors.InvalidOpcode:
 			return false, "not transferable: invalid opcode: INVALID"
+		default: // transport or other RPC error
+			return false, fmt.Sprintf("%s: %v", c.Tag(), err)
 		}
-		// transport or other RPC error
-		cls := classifyRPCError(err)
-		return false, fmt.Sprintf("%s: %v", cls, err)
 	}
 	// Optional return handling
 	if len(ret) == 0 {
@@ -863,24 +1906,16 @@ func decodeOptionalBool(ret []byte) (bool, bool) {
 	return true, true
 }
 
-// extractRevertReason pulls short revert reason from a lowercase error string "execution reverted: <reason>".
-func extractRevertReason(lowerErr string) string {
-	const p = "execution reverted"
-	i := strings.Index(lowerErr, p)
-	if i < 0 {
-		return ""
-	}
-	rest := strings.TrimSpace(lowerErr[i+len(p):])
-	rest = strings.TrimPrefix(rest, ":")
-	return strings.TrimSpace(rest)
-}
-
 // pairLogf prints a single diagnostic line for a pair when enabled.
 // The format is: "[pair N] token=<addr> from=<addr> | message"
 func pairLogf(enabled bool, lineNo int, tokenHex string, from common.Address, format string, args ...any) {
-	if !enabled {
+	if !enabled && gPairJSON == nil {
 		return
 	}
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("[pair %d] token=%s from=%s | %s\n", lineNo, tokenHex, from.Hex(), msg)
+	gPairJSON.note(lineNo, msg)
+	if !enabled {
+		return
+	}
+	fmt.Printf("[pair %d] token=%s from=%s | %s\n", lineNo, tokenHex, from.Hex(), privacy.Line(msg))
 }
\ No newline at end of file
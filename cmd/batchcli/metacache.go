@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/metacache"
+)
+
+// Metadata cache (-cache metadata.db): decimals/symbol/proxy/permit of every token are stored
+// across runs; with -recheck-balances-only a cached token skips those reads (and the
+// dead-token check), so a repeated scan spends RPC calls on balanceOf and preflight only.
+var (
+	gMetaCache   *metacache.Cache // nil when -cache is ""
+	gRecheckOnly bool             // -recheck-balances-only
+)
+
+// cachedMeta returns the cached metadata of token when the run re-checks balances only.
+func cachedMeta(token common.Address) (metacache.Entry, bool) {
+	if gMetaCache == nil || !gRecheckOnly {
+		return metacache.Entry{}, false
+	}
+	return gMetaCache.Lookup(gCatalogChain, token.Hex())
+}
+
+// storeMeta records freshly read metadata of token (decimals() must have answered).
+func storeMeta(token common.Address, decimals int, symbol string, proxy common.Address) {
+	if gMetaCache == nil {
+		return
+	}
+	e := metacache.Entry{ChainID: gCatalogChain, Address: token.Hex(), Decimals: decimals, Symbol: symbol}
+	if proxy != (common.Address{}) {
+		e.Proxy = proxy.Hex()
+	}
+	gMetaCache.Put(e)
+}
+
+// storePermit records the EIP-2612 probe result of token.
+func storePermit(token common.Address, permit string) {
+	if gMetaCache != nil {
+		gMetaCache.SetPermit(gCatalogChain, token.Hex(), permit)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NFT holdings scan (-nft-scan nft_pairs.csv): after the token pairs, every wallet of the
+// input is checked for ERC-721 / ERC-1155 holdings, the input of a future NFT sweep route.
+// Candidates come from the transfers into the wallet over the last -nft-lookback blocks
+// (eth_getLogs, by topic, no indexer needed); each one is then confirmed at the head:
+//
+//	erc721   ownerOf(id) == wallet
+//	erc1155  balanceOf(wallet, id) > 0
+//
+// so tokens sent on since are not listed. Older holdings need a longer lookback (and an RPC
+// that serves logs that far back).
+const (
+	nftERC721  = "erc721"
+	nftERC1155 = "erc1155"
+
+	nftLogChunk    = 10_000 // blocks per eth_getLogs; halved while the RPC refuses the range
+	nftLogChunkMin = 250
+)
+
+var (
+	topicTransfer       = gethcrypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	topicTransferSingle = gethcrypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	topicTransferBatch  = gethcrypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+var (
+	gNFTScan     string // output CSV ("" = off)
+	gNFTLookback uint64
+
+	gNFTMu      sync.Mutex
+	gNFTWallets map[common.Address]string // wallet -> its privateKey cell, first row wins
+	gNFTOrder   []common.Address
+)
+
+// nftHolding is one NFT (or ERC-1155 balance) a wallet holds.
+type nftHolding struct {
+	contract common.Address
+	standard string
+	id       *big.Int
+	amount   *big.Int
+}
+
+// resetNFTWallets starts a pass (scheduled mode calls run repeatedly).
+func resetNFTWallets() {
+	gNFTMu.Lock()
+	gNFTWallets, gNFTOrder = map[common.Address]string{}, nil
+	gNFTMu.Unlock()
+}
+
+// noteNFTWallet queues the wallet of an input row for -nft-scan; invalid keys are ignored
+// (the pair itself is reported BAD).
+func noteNFTWallet(privateHex string) {
+	if gNFTScan == "" {
+		return
+	}
+	prv, err := hexToECDSA(privateHex)
+	if err != nil {
+		return
+	}
+	w := gethcrypto.PubkeyToAddress(prv.PublicKey)
+	gNFTMu.Lock()
+	defer gNFTMu.Unlock()
+	if _, dup := gNFTWallets[w]; !dup {
+		gNFTWallets[w] = privateHex
+		gNFTOrder = append(gNFTOrder, w)
+	}
+}
+
+// runNFTScan scans the queued wallets and writes gNFTScan.
+func runNFTScan(ec *ethclient.Client) error {
+	f, err := os.Create(gNFTScan)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"token", "privateKey", "from", "standard", "tokenId", "amount"})
+
+	ctx := context.Background()
+	head := gAtBlock
+	if head == 0 {
+		throttle()
+		if head, err = ec.BlockNumber(ctx); err != nil {
+			return fmt.Errorf("head: %w", err)
+		}
+	}
+	from := uint64(0)
+	if head > gNFTLookback {
+		from = head - gNFTLookback
+	}
+	gNFTMu.Lock()
+	wallets := append([]common.Address(nil), gNFTOrder...)
+	gNFTMu.Unlock()
+
+	found, holders := 0, 0
+	for _, wallet := range wallets {
+		if stopRequested() {
+			break
+		}
+		list, err := scanNFTs(ctx, ec, wallet, from, head)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[nft] %s: %v\n", wallet.Hex(), err)
+			continue
+		}
+		for _, h := range list {
+			_ = w.Write([]string{h.contract.Hex(), gNFTWallets[wallet], wallet.Hex(), h.standard, h.id.String(), h.amount.String()})
+		}
+		if len(list) > 0 {
+			found += len(list)
+			holders++
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	fmt.Printf("[nft] %d wallet(s) scanned over blocks %d..%d: %d holding(s) in %d wallet(s) => %s\n", len(wallets), from, head, found, holders, gNFTScan)
+	return nil
+}
+
+// scanNFTs lists what wallet holds among the NFTs transferred to it in [from, to].
+func scanNFTs(ctx context.Context, ec *ethclient.Client, wallet common.Address, from, to uint64) ([]nftHolding, error) {
+	walletTopic := common.BytesToHash(wallet.Bytes())
+	type key struct {
+		contract common.Address
+		id       string
+	}
+	cand := map[key]nftHolding{}
+	add := func(c common.Address, std string, id *big.Int) {
+		k := key{c, id.String()}
+		if _, ok := cand[k]; !ok {
+			cand[k] = nftHolding{contract: c, standard: std, id: id}
+		}
+	}
+	queries := [][][]common.Hash{
+		{{topicTransfer}, nil, {walletTopic}},                                // ERC-721 (and ERC-20: 3 topics, dropped below)
+		{{topicTransferSingle, topicTransferBatch}, nil, nil, {walletTopic}}, // ERC-1155
+	}
+	for _, topics := range queries {
+		logs, err := nftLogs(ctx, ec, topics, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			switch {
+			case l.Topics[0] == topicTransfer && len(l.Topics) == 4:
+				add(l.Address, nftERC721, l.Topics[3].Big())
+			case l.Topics[0] == topicTransferSingle && len(l.Data) >= 64:
+				add(l.Address, nftERC1155, new(big.Int).SetBytes(l.Data[:32]))
+			case l.Topics[0] == topicTransferBatch:
+				for _, id := range decodeUintArray(l.Data, 0) {
+					add(l.Address, nftERC1155, id)
+				}
+			}
+		}
+	}
+
+	var out []nftHolding
+	for _, h := range cand {
+		if amount := nftBalance(ctx, ec, h, wallet); amount != nil && amount.Sign() > 0 {
+			h.amount = amount
+			out = append(out, h)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if c := strings.Compare(out[i].contract.Hex(), out[j].contract.Hex()); c != 0 {
+			return c < 0
+		}
+		return out[i].id.Cmp(out[j].id) < 0
+	})
+	return out, nil
+}
+
+// nftLogs reads the logs matching topics in [from, to], in chunks the RPC accepts.
+func nftLogs(ctx context.Context, ec *ethclient.Client, topics [][]common.Hash, from, to uint64) ([]types.Log, error) {
+	var all []types.Log
+	chunk := uint64(nftLogChunk)
+	for start := from; start <= to; {
+		end := min(start+chunk-1, to)
+		throttle()
+		qctx, cancel := context.WithTimeout(ctx, getPairTimeout())
+		logs, err := ec.FilterLogs(qctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Topics:    topics,
+		})
+		cancel()
+		if err != nil {
+			if chunk/2 >= nftLogChunkMin {
+				chunk /= 2 // "block range too large" / "query returned more than N results"
+				continue
+			}
+			return nil, fmt.Errorf("eth_getLogs %d..%d: %w", start, end, err)
+		}
+		all = append(all, logs...)
+		start = end + 1
+	}
+	return all, nil
+}
+
+// nftBalance confirms a candidate: 1 when wallet owns the ERC-721 id, the ERC-1155 balance
+// otherwise; nil when the call fails.
+func nftBalance(ctx context.Context, ec *ethclient.Client, h nftHolding, wallet common.Address) *big.Int {
+	id := common.LeftPadBytes(h.id.Bytes(), 32)
+	var data []byte
+	if h.standard == nftERC721 {
+		data = append(common.FromHex("0x6352211e"), id...) // ownerOf(uint256)
+	} else {
+		data = append(common.FromHex("0x00fdd58e"), common.LeftPadBytes(wallet.Bytes(), 32)...) // balanceOf(address,uint256)
+		data = append(data, id...)
+	}
+	c := h.contract
+	ctx, cancel := context.WithTimeout(ctx, getPairTimeout())
+	defer cancel()
+	throttle()
+	ret, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &c, Data: data})
+	if err != nil || len(ret) < 32 {
+		return nil
+	}
+	if h.standard == nftERC721 {
+		if common.BytesToAddress(ret[:32]) != wallet {
+			return nil
+		}
+		return big.NewInt(1)
+	}
+	return new(big.Int).SetBytes(ret[:32])
+}
+
+// decodeUintArray decodes the ABI uint256[] whose offset is the word at byte pos of data.
+func decodeUintArray(data []byte, pos int) []*big.Int {
+	if len(data) < pos+32 {
+		return nil
+	}
+	off := new(big.Int).SetBytes(data[pos : pos+32])
+	if !off.IsInt64() || off.Int64()+32 > int64(len(data)) {
+		return nil
+	}
+	o := int(off.Int64())
+	n := new(big.Int).SetBytes(data[o : o+32])
+	if !n.IsInt64() || int64(o+32)+n.Int64()*32 > int64(len(data)) {
+		return nil
+	}
+	out := make([]*big.Int, 0, n.Int64())
+	for i := 0; i < int(n.Int64()); i++ {
+		s := o + 32 + i*32
+		out = append(out, new(big.Int).SetBytes(data[s:s+32]))
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Value-ordered OK output (-sort balance|usd, -top N): OK pairs are held until the scan ends,
+// then written most valuable first, optionally only the first N, so ok_pairs is already the
+// rescue queue. BAD and spam verdicts are written as they come.
+const (
+	sortBalance = "balance" // whole tokens (balance / 10^decimals); tokens are not comparable, but big holdings surface
+	sortUSD     = "usd"     // usdValue from -usd; unpriced pairs follow, by balance
+)
+
+// orderedSink buffers OK rows for next.
+type orderedSink struct {
+	next pairSink
+	by   string
+	top  int // 0 = all
+	ok   []pairRow
+}
+
+func newOrderedSink(next pairSink, by string, top int) *orderedSink {
+	return &orderedSink{next: next, by: by, top: top}
+}
+
+func (s *orderedSink) OK(r pairRow)                     { s.ok = append(s.ok, r) }
+func (s *orderedSink) Bad(r pairRow)                    { s.next.Bad(r) }
+func (s *orderedSink) sync()                           { syncSink(s.next) } // OK rows wait for flush
+func (s *orderedSink) Spam(r pairRow, reasons []string) { s.next.Spam(r, reasons) }
+
+// flush sorts the OK rows and writes them (the first top) to next.
+func (s *orderedSink) flush() {
+	sort.SliceStable(s.ok, func(i, j int) bool { return s.less(s.ok[i], s.ok[j]) })
+	keep := s.ok
+	if s.top > 0 && len(keep) > s.top {
+		keep = keep[:s.top]
+	}
+	for _, r := range keep {
+		s.next.OK(r)
+	}
+	if len(keep) < len(s.ok) {
+		fmt.Printf("[top] %d of %d OK pair(s) written (-top %d, by %s)\n", len(keep), len(s.ok), s.top, s.by)
+	}
+	s.ok = nil
+}
+
+func (s *orderedSink) less(a, b pairRow) bool {
+	if s.by == sortUSD {
+		ua, okA := parseUSD(a.usdValue)
+		ub, okB := parseUSD(b.usdValue)
+		if okA != okB {
+			return okA
+		}
+		if okA && ua != ub {
+			return ua > ub
+		}
+	}
+	if c := wholeTokens(a).Cmp(wholeTokens(b)); c != 0 {
+		return c > 0
+	}
+	return a.lineNo < b.lineNo
+}
+
+func parseUSD(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v, err == nil
+}
+
+// wholeTokens is the balance in token units (0 when unknown).
+func wholeTokens(r pairRow) *big.Float {
+	if r.balanceWei == nil {
+		return new(big.Float)
+	}
+	return tokensOf(r.balanceWei, r.tokenDecimals)
+}
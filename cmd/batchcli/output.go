@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+)
+
+// Output formats (-format). json writes NDJSON: one pairRecord per line, so tools can read
+// verdicts, reason codes and timings without parsing CSV reason strings.
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// pairTimings is where a pair's time went (filled by processOne / processInput).
+type pairTimings struct {
+	Meta, Preflight, Spam, Total time.Duration
+}
+
+// pairSink receives every verdict of a scan: the CSV files, or NDJSON records.
+type pairSink interface {
+	OK(r pairRow)
+	Bad(r pairRow)
+	Spam(r pairRow, reasons []string)
+}
+
+// openSink creates the -out-ok / -out-bad (and -out-spam with -spam-filter) files in cfg.format.
+func openSink(cfg appConfig) (pairSink, func(), error) {
+	paths := []string{cfg.outOKPath, cfg.outBadPath}
+	if cfg.spamFilter {
+		paths = append(paths, cfg.outSpamPath)
+	}
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	header := make([]bool, len(paths)) // -resume appends: files that already have rows keep their header
+	for i, p := range paths {
+		var f *os.File
+		var err error
+		if cfg.resume {
+			f, err = os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		} else {
+			f, err = os.Create(p)
+		}
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		st, err := f.Stat()
+		header[i] = err != nil || st.Size() == 0
+		files = append(files, f)
+	}
+	var spamF io.Writer
+	if cfg.spamFilter {
+		spamF = files[2]
+	}
+	if cfg.format == formatJSON {
+		s := &jsonSink{ok: json.NewEncoder(files[0]), bad: json.NewEncoder(files[1])}
+		if spamF != nil {
+			s.spam = json.NewEncoder(spamF)
+		}
+		return s, closeAll, nil
+	}
+	s := &csvSink{ok: csv.NewWriter(files[0]), bad: csv.NewWriter(files[1])}
+	if header[0] {
+		_ = s.ok.Write([]string{"token", "privateKey", "from", "symbol", "decimals", "balanceTokens", "warnings", "warningDetails", "balanceWei", "usdValue", "transferTaxPct", "permit",
+			"route", "gasEstimate", "gasCostWei", "valueWei", "gasOverValue"})
+	}
+	if header[1] {
+		_ = s.bad.Write([]string{"token", "privateKey", "from", "reason", "warnings", "warningDetails", "reasonCode"})
+	}
+	if spamF != nil {
+		s.spam = csv.NewWriter(spamF)
+		if header[2] {
+			_ = s.spam.Write([]string{"token", "privateKey", "from", "symbol", "balanceTokens", "spamReasons", "balanceWei"})
+		}
+	}
+	// scheduled mode calls run repeatedly: flush before the files are closed
+	return s, func() { s.flush(); closeAll() }, nil
+}
+
+// outputPath switches a .csv output path to .ndjson for -format json.
+func outputPath(path, format string) string {
+	if format == formatJSON && strings.EqualFold(filepath.Ext(path), ".csv") {
+		return strings.TrimSuffix(path, filepath.Ext(path)) + ".ndjson"
+	}
+	return path
+}
+
+// csvSink keeps the historical CSV layout (column positions are relied upon downstream).
+type csvSink struct{ ok, bad, spam *csv.Writer }
+
+func (s *csvSink) OK(r pairRow) {
+	_ = s.ok.Write(append([]string{
+		r.tokenHex,
+		r.privateHex,
+		r.fromAddress.Hex(),
+		r.tokenSymbol,
+		fmt.Sprintf("%d", r.tokenDecimals),
+		formatTokensFromWei(r.balanceWei, r.tokenDecimals),
+		r.warns.Codes(),
+		r.warns.Details(),
+		units.WeiString(r.balanceWei),
+		r.usdValue,
+		r.transferTax,
+		r.permit,
+	}, gasColumns(r)...))
+}
+
+func (s *csvSink) Bad(r pairRow) {
+	if r.malformed {
+		_ = s.bad.Write([]string{r.tokenHex, "", "", r.reason, "", "", string(r.reasonCode())})
+		return
+	}
+	_ = s.bad.Write([]string{r.tokenHex, r.privateHex, r.fromAddress.Hex(), r.reason, r.warns.Codes(), r.warns.Details(), string(r.reasonCode())})
+}
+
+func (s *csvSink) Spam(r pairRow, reasons []string) {
+	if s.spam == nil {
+		return
+	}
+	_ = s.spam.Write([]string{r.tokenHex, r.privateHex, r.fromAddress.Hex(), r.tokenSymbol,
+		formatTokensFromWei(r.balanceWei, r.tokenDecimals), strings.Join(reasons, "; "), units.WeiString(r.balanceWei)})
+}
+
+// syncSink pushes buffered verdicts of a streamed scan to the output files; sinks that
+// must see the whole scan first (-sort/-top) keep theirs until the end.
+func syncSink(sink pairSink) {
+	if s, ok := sink.(interface{ sync() }); ok {
+		s.sync()
+	}
+}
+
+func (s *csvSink) sync() { s.flush() }
+
+func (s *csvSink) flush() {
+	for _, w := range []*csv.Writer{s.ok, s.bad, s.spam} {
+		if w != nil {
+			w.Flush()
+		}
+	}
+}
+
+// pairRecord is one NDJSON line of -format json (schema/pair_report.schema.json).
+type pairRecord = reportschema.PairReport
+
+type jsonSink struct{ ok, bad, spam *json.Encoder }
+
+func (s *jsonSink) OK(r pairRow)  { _ = s.ok.Encode(newPairRecord(r, "ok")) }
+func (s *jsonSink) Bad(r pairRow) { _ = s.bad.Encode(newPairRecord(r, "bad")) }
+
+func (s *jsonSink) Spam(r pairRow, reasons []string) {
+	if s.spam == nil {
+		return
+	}
+	rec := newPairRecord(r, "spam")
+	rec.SpamReasons = reasons
+	_ = s.spam.Encode(rec)
+}
+
+func newPairRecord(r pairRow, verdict string) pairRecord {
+	rec := pairRecord{SchemaVersion: reportschema.Version, Line: r.lineNo, Verdict: verdict, Token: r.tokenHex, PrivateKey: r.privateHex, Reason: r.reason, ReasonCode: string(r.reasonCode())}
+	if r.fromAddress != (common.Address{}) {
+		rec.From = r.fromAddress.Hex()
+	}
+	if r.balanceWei != nil || r.tokenSymbol != "" || verdict != "bad" {
+		d := r.tokenDecimals
+		rec.Symbol, rec.Decimals = r.tokenSymbol, &d
+		rec.BalanceWei, rec.BalanceTokens = units.WeiString(r.balanceWei), formatTokensFromWei(r.balanceWei, r.tokenDecimals)
+	}
+	rec.USDValue, rec.TransferTaxPct, rec.Permit = r.usdValue, r.transferTax, r.permit
+	if verdict != "bad" {
+		c := gasColumns(r)
+		rec.Route, rec.GasCostWei, rec.ValueWei, rec.GasOverValue = c[0], c[2], c[3], c[4]
+		if r.gas != nil {
+			rec.GasEstimate = r.gas.gas
+		}
+	}
+	if len(r.warns) > 0 {
+		rec.Warnings = r.warns
+	}
+	t := map[string]int64{}
+	for k, d := range map[string]time.Duration{"meta": r.timings.Meta, "preflight": r.timings.Preflight, "spam": r.timings.Spam, "total": r.timings.Total} {
+		if d > 0 {
+			t[k] = d.Milliseconds()
+		}
+	}
+	if len(t) > 0 {
+		rec.TimingsMs = t
+	}
+	return rec
+}
+
+// reasonCode is the canonical code of a BAD reason: the one set where the reason was
+// decided, else the one its text maps to.
+func (r pairRow) reasonCode() reasons.Code {
+	if r.reason == "" {
+		return ""
+	}
+	if r.code != "" {
+		return r.code
+	}
+	return reasons.Classify(r.reason)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/errhelp"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
+)
+
+// Structured pair log (-pair-log-json pairs.log.ndjson): one JSON object per pair with every
+// step the check went through (the lines -pair-logs prints, split into step and result, with
+// the time since the pair started), the verdict, the reason code and its error class, the
+// warnings and the timings. Unlike the OK/BAD outputs it records why a pair got its verdict,
+// for post-run analytics ("which step fails most", "where does the time go"). It never holds
+// private keys.
+
+// pairLogStep is one check of a pair.
+type pairLogStep struct {
+	AtMs   int64  `json:"atMs"` // since the pair's first step
+	Step   string `json:"step"`
+	Result string `json:"result,omitempty"`
+}
+
+// pairLogRecord is one line of -pair-log-json.
+type pairLogRecord struct {
+	Time        string           `json:"time"`
+	Line        int              `json:"line"`
+	Token       string           `json:"token"`
+	From        string           `json:"from,omitempty"`
+	Verdict     string           `json:"verdict"`              // ok | bad | spam
+	Reason      string           `json:"reason,omitempty"`     // BAD reason as in -out-bad
+	ReasonCode  string           `json:"reasonCode,omitempty"` // see reasonCode
+	ErrorClass  string           `json:"errorClass,omitempty"` // input | balance | token | rpc | other
+	Hint        string           `json:"hint,omitempty"`       // errhelp catalog code
+	SpamReasons []string         `json:"spamReasons,omitempty"`
+	Route       string           `json:"route,omitempty"`
+	BalanceWei  string           `json:"balanceWei,omitempty"`
+	Reused      bool             `json:"reused,omitempty"` // -db-skip-unchanged
+	Warnings    warnings.List    `json:"warnings,omitempty"`
+	Steps       []pairLogStep    `json:"steps"`
+	TimingsMs   map[string]int64 `json:"timingsMs,omitempty"`
+}
+
+// pairJSONLog collects the steps of the pairs in flight and writes a record per verdict.
+type pairJSONLog struct {
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	starts map[int]time.Time
+	steps  map[int][]pairLogStep
+}
+
+// gPairJSON is the -pair-log-json writer (nil = off).
+var gPairJSON *pairJSONLog
+
+func openPairJSONLog(path string, appendTo bool) (*pairJSONLog, error) {
+	mode := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendTo {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, mode, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &pairJSONLog{f: f, w: bufio.NewWriter(f), starts: map[int]time.Time{}, steps: map[int][]pairLogStep{}}, nil
+}
+
+// note records one pairLogf line of the pair on lineNo ("step: result").
+func (l *pairJSONLog) note(lineNo int, msg string) {
+	if l == nil {
+		return
+	}
+	step, result, _ := strings.Cut(privacy.Line(msg), ": ")
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start, ok := l.starts[lineNo]
+	if !ok {
+		start = now
+		l.starts[lineNo] = now
+	}
+	l.steps[lineNo] = append(l.steps[lineNo], pairLogStep{AtMs: now.Sub(start).Milliseconds(), Step: step, Result: result})
+}
+
+// write emits the record of r's verdict with the steps collected for its line.
+func (l *pairJSONLog) write(r pairRow, verdict string, spamReasons []string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec := pairLogRecord{
+		Time: time.Now().Format(time.RFC3339), Line: r.lineNo, Token: r.tokenHex, Verdict: verdict,
+		Reason: r.reason, ReasonCode: string(r.reasonCode()), ErrorClass: r.reasonCode().Class(),
+		SpamReasons: spamReasons, Route: r.route, Reused: r.reused, Warnings: r.warns,
+		Steps: l.steps[r.lineNo],
+	}
+	if r.fromAddress != (common.Address{}) {
+		rec.From = r.fromAddress.Hex()
+	}
+	if r.balanceWei != nil {
+		rec.BalanceWei = units.WeiString(r.balanceWei)
+	}
+	if e, ok := errhelp.Lookup(r.reason); ok {
+		rec.Hint = e.Code
+	}
+	if rec.Steps == nil {
+		rec.Steps = []pairLogStep{}
+	}
+	t := map[string]int64{}
+	for k, d := range map[string]time.Duration{"meta": r.timings.Meta, "preflight": r.timings.Preflight, "spam": r.timings.Spam, "total": r.timings.Total} {
+		if d > 0 {
+			t[k] = d.Milliseconds()
+		}
+	}
+	if len(t) > 0 {
+		rec.TimingsMs = t
+	}
+	delete(l.starts, r.lineNo)
+	delete(l.steps, r.lineNo)
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	_, _ = l.w.Write(append(b, '\n'))
+}
+
+// flush pushes the buffered records to the file (streamed scans call it per verdict).
+func (l *pairJSONLog) flush() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	_ = l.w.Flush()
+	l.mu.Unlock()
+}
+
+func (l *pairJSONLog) close() error {
+	if l == nil {
+		return nil
+	}
+	l.flush()
+	return l.f.Close()
+}
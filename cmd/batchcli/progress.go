@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	progressAuto = "auto"
+	progressOn   = "on"
+	progressOff  = "off"
+)
+
+var gProgress = progressAuto // -progress
+
+// progressEnabled: "auto" draws the line only on an interactive stderr without -pair-logs
+// (the per-pair logs would tear it, and CI logs do not need carriage returns).
+func progressEnabled(showPairLogs bool) bool {
+	switch gProgress {
+	case progressOn:
+		return true
+	case progressOff:
+		return false
+	}
+	return !showPairLogs && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// progress is the single in-place status line of a scan on stderr:
+// processed/total, ok/bad/spam counters, rows/sec and ETA. Callers serialize note/finish.
+type progress struct {
+	total         int     // < 0: streamed input, the total is unknown
+	pos           float64 // streamed input file: share read up to the last row done (-1 = stdin)
+	done          int
+	ok, bad, spam int
+	started       time.Time
+	drawn         time.Time
+	width         int // length of the last line, blanked by the next one
+	shown         int // done as of the last draw
+}
+
+// newProgress returns nil (all methods are no-ops) when the line is disabled or there is
+// nothing to check.
+func newProgress(total int, showPairLogs bool) *progress {
+	if total == 0 || !progressEnabled(showPairLogs) {
+		return nil
+	}
+	p := &progress{total: total, pos: -1, started: time.Now()}
+	p.draw()
+	return p
+}
+
+// note counts one finished pair: verdict is "ok", "bad" or "spam".
+func (p *progress) note(verdict string) {
+	if p == nil {
+		return
+	}
+	p.done++
+	switch verdict {
+	case "ok":
+		p.ok++
+	case "bad":
+		p.bad++
+	case "spam":
+		p.spam++
+	}
+	if p.done == p.total || time.Since(p.drawn) >= 200*time.Millisecond {
+		p.draw()
+	}
+}
+
+// at moves a streamed input's percentage to pos (the inputPos of a row being finished).
+func (p *progress) at(pos float64) {
+	if p != nil && pos > p.pos {
+		p.pos = pos
+	}
+}
+
+// finish leaves the final line on screen and moves to the next one.
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	if p.shown != p.done {
+		p.draw()
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func (p *progress) draw() {
+	elapsed := time.Since(p.started)
+	line := fmt.Sprintf("[progress] %d processed  ok=%d bad=%d", p.done, p.ok, p.bad)
+	if p.total > 0 {
+		line = fmt.Sprintf("[progress] %d/%d (%d%%)  ok=%d bad=%d", p.done, p.total, p.done*100/p.total, p.ok, p.bad)
+	} else if p.pos >= 0 {
+		line = fmt.Sprintf("[progress] %d processed (%d%% of input)  ok=%d bad=%d", p.done, int(p.pos*100), p.ok, p.bad)
+	}
+	if gSpam != nil {
+		line += fmt.Sprintf(" spam=%d", p.spam)
+	}
+	if p.done > 0 && elapsed > 0 {
+		rate := float64(p.done) / elapsed.Seconds()
+		line += fmt.Sprintf("  %.1f rows/s", rate)
+		switch left := p.total - p.done; {
+		case p.total < 0 && p.pos > 0 && p.pos < 1: // streamed file: ETA from the bytes done
+			eta := time.Duration(float64(elapsed) * (1 - p.pos) / p.pos)
+			line += "  ETA " + eta.Round(time.Second).String()
+		case p.total < 0: // stdin: no ETA
+		case left > 0:
+			eta := time.Duration(float64(left) / rate * float64(time.Second))
+			line += "  ETA " + eta.Round(time.Second).String()
+		default:
+			line += "  done in " + elapsed.Round(time.Second).String()
+		}
+	}
+	pad := ""
+	if n := p.width - len(line); n > 0 {
+		pad = strings.Repeat(" ", n)
+	}
+	fmt.Fprint(os.Stderr, "\r"+line+pad)
+	p.width, p.shown, p.drawn = len(line), p.done, time.Now()
+}
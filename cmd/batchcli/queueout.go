@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/config"
+	"github.com/ligun0805/bundle-rescue/internal/operators"
+	"github.com/ligun0805/bundle-rescue/internal/queuefile"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+)
+
+// Queue handoff (-queue-out pairs_session.json): the OK pairs are also merged into a bundlegui
+// session file, with decimals, balances, amount = whole balance and To = SAFE, the way the
+// GUI's own import leaves them. Pointing -queue-out at the GUI's session file (closed GUI)
+// queues them for its next start without a CSV re-import and its second round of checks.
+// Pairs already queued keep their place and get the fresh balance; new ones are recorded as
+// imported by the OS user running the scan.
+
+// queueSink collects the OK pairs for -queue-out and forwards every verdict to next. It sits
+// under -sort/-top, so the queue holds the same pairs as the OK output.
+type queueSink struct {
+	next     pairSink
+	safe     common.Address
+	campaign string
+	pairs    []queuefile.Pair
+}
+
+func newQueueSink(next pairSink, safe common.Address, inputPath string) *queueSink {
+	name := config.ListName(filepath.Base(inputPath), "") // leak.csv.gz => leak.csv
+	campaign := strings.TrimSuffix(name, filepath.Ext(name))
+	if strings.TrimSpace(inputPath) == "-" {
+		campaign = "stdin"
+	}
+	return &queueSink{next: next, safe: safe, campaign: "batchcli:" + campaign}
+}
+
+func (s *queueSink) OK(r pairRow) {
+	s.pairs = append(s.pairs, queuefile.Pair{
+		Token:         strings.ToLower(r.tokenAddress.Hex()),
+		From:          strings.ToLower(r.fromAddress.Hex()),
+		FromPK:        r.privateHex,
+		To:            s.safe.Hex(),
+		AmountWei:     units.WeiString(r.balanceWei),
+		AmountTokens:  units.Format(r.balanceWei, r.tokenDecimals, units.Full),
+		Decimals:      r.tokenDecimals,
+		BalanceWei:    units.WeiString(r.balanceWei),
+		BalanceTokens: units.Format(r.balanceWei, r.tokenDecimals, units.Full),
+		Campaign:      s.campaign,
+		Sources:       []string{s.campaign},
+		Warnings:      r.warns,
+		ImportedBy:    operators.OSUser(), // the GUI stamps its login on imports; a scan runs as the OS user
+		ImportedAt:    time.Now().Format("2006-01-02 15:04:05"),
+	})
+	s.next.OK(r)
+}
+func (s *queueSink) Bad(r pairRow)                    { s.next.Bad(r) }
+func (s *queueSink) Spam(r pairRow, reasons []string) { s.next.Spam(r, reasons) }
+func (s *queueSink) sync()                            { syncSink(s.next) }
+
+// write merges the collected pairs into path.
+func (s *queueSink) write(path string) error {
+	f, err := queuefile.Read(path)
+	if err != nil {
+		return err
+	}
+	added, updated := f.Merge(s.pairs)
+	if err := f.Write(path); err != nil {
+		return err
+	}
+	fmt.Printf("[queue] %d pair(s) queued, %d already queued refreshed (%d in queue) => %s\n", added, updated, len(f.Pairs), path)
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Adaptive RPC rate limit: every throttle() takes a token from one bucket shared by all
+// workers (-rate-burst tokens, refilled at -rate-per-sec). A provider answer "429 Too Many
+// Requests" / -32005 halves the refill rate (at most once per second, so one burst of
+// parallel 429s counts once); after rateRecoverEvery without throttling the rate grows back
+// by a tenth of the configured one per step until it is reached again.
+// Without -rate-per-sec the ceiling comes from -rpc-delay-ms: workers calls per delay, the
+// same pace the old per-call sleep allowed. -rpc-delay-ms 0 and no -rate-per-sec = no limit.
+
+const (
+	rateMinPerSec    = 0.5
+	rateCutCooldown  = time.Second
+	rateRecoverEvery = 5 * time.Second
+)
+
+type rateLimiter struct {
+	mu       sync.Mutex
+	ceiling  float64 // configured refill rate, tokens/s (0 = unlimited)
+	rate     float64 // current refill rate
+	burst    float64
+	tokens   float64 // may go negative: callers reserve a token and sleep for it
+	last     time.Time
+	lastCut  time.Time
+	lastStep time.Time // last cut or recovery step
+	cuts     int
+	lowest   float64
+	workers  int
+}
+
+var gLimiter = &rateLimiter{}
+
+// setRateLimit configures gLimiter; perSec 0 derives the rate from rpcDelay and workers.
+func setRateLimit(perSec float64, burst int, rpcDelay time.Duration, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	if perSec <= 0 && rpcDelay > 0 {
+		perSec = float64(workers) / rpcDelay.Seconds()
+	}
+	if burst <= 0 {
+		burst = workers
+	}
+	now := time.Now()
+	l := gLimiter
+	l.mu.Lock()
+	l.ceiling, l.rate, l.lowest = perSec, perSec, perSec
+	l.burst, l.tokens = float64(burst), float64(burst)
+	l.last, l.lastStep, l.lastCut = now, now, time.Time{}
+	l.cuts, l.workers = 0, workers
+	l.mu.Unlock()
+}
+
+// throttle waits for a token of the shared RPC rate limit.
+func throttle() {
+	if d := gLimiter.reserve(); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait for it.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ceiling <= 0 {
+		return 0
+	}
+	now := time.Now()
+	if l.rate < l.ceiling && now.Sub(l.lastStep) >= rateRecoverEvery {
+		l.rate = math.Min(l.ceiling, l.rate+l.ceiling/10)
+		l.lastStep = now
+	}
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// throttled is called when the provider rejected a call for rate: the refill rate halves.
+func (l *rateLimiter) throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.ceiling <= 0 || now.Sub(l.lastCut) < rateCutCooldown {
+		return
+	}
+	l.rate = math.Max(rateMinPerSec, l.rate/2)
+	l.lastCut, l.lastStep = now, now
+	l.cuts++
+	l.lowest = math.Min(l.lowest, l.rate)
+}
+
+// perCall is the spacing one worker sees between its own calls at the current rate
+// (the adaptive pair timeout budgets for it).
+func (l *rateLimiter) perCall() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(l.workers) / l.rate * float64(time.Second))
+}
+
+// rateLimitReport is the end-of-run line ("" when the provider never throttled).
+func rateLimitReport() string {
+	l := gLimiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cuts == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[rate] provider throttled %d time(s): rate lowered to %.1f/s at worst, now %.1f/s of %.1f/s", l.cuts, l.lowest, l.rate, l.ceiling)
+}
+
+// rateLimitTransport reports HTTP 429 answers to gLimiter (-32005 arrives as a JSON-RPC
+// error and is reported by callContractWithRetry).
+type rateLimitTransport struct{ base http.RoundTripper }
+
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		gLimiter.throttled()
+	}
+	return resp, err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+)
+
+// Reason codes in the run summary: every BAD verdict is counted by its canonical code
+// (internal/reasons), printed at the end of the scan and stored in the run manifest
+// (reasonCodes), so runs and lists can be compared by why their pairs failed.
+
+// reasonSink counts the BAD verdicts by reason code and forwards every verdict to next.
+type reasonSink struct {
+	next  pairSink
+	tally reasons.Tally
+}
+
+func newReasonSink(next pairSink) *reasonSink {
+	return &reasonSink{next: next, tally: reasons.Tally{}}
+}
+
+func (s *reasonSink) OK(r pairRow)                 { s.next.OK(r) }
+func (s *reasonSink) Bad(r pairRow)                { s.tally.Add(r.reasonCode()); s.next.Bad(r) }
+func (s *reasonSink) Spam(r pairRow, why []string) { s.next.Spam(r, why) }
+func (s *reasonSink) sync()                        { syncSink(s.next) }
+
+// print writes the summary line ("[reasons] NO_BALANCE=12 RPC_TIMEOUT=3 ..."), nothing
+// when the run had no BAD verdicts.
+func (s *reasonSink) print() {
+	if len(s.tally) == 0 {
+		return
+	}
+	fmt.Printf("[reasons] BAD by code: %s\n", s.tally)
+}
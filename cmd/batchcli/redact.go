@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ligun0805/bundle-rescue/internal/keyref"
+)
+
+// redactCSV rewrites inPath (input or OK/BAD output) so it can be shared: every private
+// key becomes an HMAC fingerprint and the derived address is kept next to it.
+// bundlecli re-joins fingerprints with raw keys via --keys at execution time.
+func redactCSV(inPath, outPath string, secret []byte) (int, error) {
+	data, err := readInput(inPath)
+	if err != nil {
+		return 0, fmt.Errorf("open input: %w", err)
+	}
+	delim := detectDelimiter(data)
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comma = delim
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return 0, fmt.Errorf("create output: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Comma = delim
+	defer w.Flush()
+
+	n, lineNo := 0, 0
+	for {
+		row, e := reader.Read()
+		if e != nil {
+			if errors.Is(e, io.EOF) {
+				break
+			}
+			return n, e
+		}
+		lineNo++
+		if lineNo == 1 && skipRow(row, lineNo) && len(row) > 1 {
+			_ = w.Write(redactHeader(row))
+			continue
+		}
+		out, err := keyref.RedactRow(secret, row)
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		_ = w.Write(out)
+		n++
+	}
+	w.Flush()
+	return n, w.Error()
+}
+
+// redactHeader renames the key column and adds "from" after it when missing.
+func redactHeader(row []string) []string {
+	hasFrom := false
+	for _, c := range row {
+		if strings.EqualFold(strings.TrimSpace(c), "from") {
+			hasFrom = true
+		}
+	}
+	out := make([]string, 0, len(row)+1)
+	for _, c := range row {
+		if strings.Contains(strings.ToLower(c), "priv") {
+			out = append(out, "keyFingerprint")
+			if !hasFrom {
+				out = append(out, "from")
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/resultsdb"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+)
+
+// Results database (-db results.sqlite): every verdict is also stored per (chain, from, token)
+// with its reasons and warnings, verdicts that changed since the last run are reported, and
+// with -db-skip-unchanged a pair checked recently whose balance did not move keeps its stored
+// verdict after a single balanceOf call.
+var (
+	gResultsDB       *resultsdb.DB // nil unless -db
+	gDBSkipUnchanged time.Duration // 0 = always run the full check
+)
+
+// dbSink records every verdict in gResultsDB and forwards it to the file sink.
+type dbSink struct {
+	next     pairSink
+	recorded int
+	reused   int
+	changed  int
+}
+
+// openResultsDB opens path, starts a run in it and wraps next.
+func openResultsDB(path string, next pairSink, inputHash, configHash string) (*dbSink, error) {
+	db, err := resultsdb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.BeginRun("batchcli", gCatalogChain, inputHash, configHash); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("results database %s: %w", path, err)
+	}
+	gResultsDB = db
+	return &dbSink{next: next}, nil
+}
+
+// finish closes the run in the database and prints the summary.
+func (s *dbSink) finish(path string, bad int, runErr error) {
+	result := fmt.Sprintf("bad=%d", bad)
+	if runErr != nil {
+		result = "error: " + runErr.Error()
+	}
+	if err := gResultsDB.FinishRun(result); err != nil {
+		fmt.Fprintln(os.Stderr, "[db]", err)
+	}
+	_ = gResultsDB.Close()
+	gResultsDB = nil
+	fmt.Printf("[db] %d check(s) recorded (%d reused, balance unchanged), %d verdict change(s) => %s\n", s.recorded, s.reused, s.changed, path)
+}
+
+func (s *dbSink) sync()         { syncSink(s.next) }
+func (s *dbSink) OK(r pairRow)  { s.next.OK(r); s.record(r, "ok", nil) }
+func (s *dbSink) Bad(r pairRow) { s.next.Bad(r); s.record(r, "bad", nil) }
+
+func (s *dbSink) Spam(r pairRow, reasons []string) {
+	s.next.Spam(r, reasons)
+	s.record(r, "spam", reasons)
+}
+
+func (s *dbSink) record(r pairRow, verdict string, spam []string) {
+	if r.malformed || r.fromAddress == (common.Address{}) {
+		return // no pair identity: malformed row, bad token address or key
+	}
+	c := resultsdb.Check{
+		ChainID: gCatalogChain, From: r.fromAddress.Hex(), Token: r.tokenAddress.Hex(),
+		Line: r.lineNo, Verdict: verdict, ReasonCode: string(r.reasonCode()), Reason: r.reason,
+		Symbol: r.tokenSymbol, Decimals: r.tokenDecimals, BalanceWei: units.WeiString(r.balanceWei),
+		Warnings: r.warns, SpamReasons: spam, TotalMs: r.timings.Total.Milliseconds(), Reused: r.reused,
+	}
+	if r.balanceWei != nil {
+		c.BalanceTokens = formatTokensFromWei(r.balanceWei, r.tokenDecimals)
+	}
+	prev, err := gResultsDB.Record(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "[db]", err)
+		return
+	}
+	s.recorded++
+	if r.reused {
+		s.reused++
+	}
+	if prev != "" && prev != verdict {
+		s.changed++
+		why := ""
+		if r.reason != "" {
+			why = " (" + r.reason + ")"
+		}
+		fmt.Printf("[db] line %d: from=%s token=%s %s → %s%s\n", r.lineNo, c.From, c.Token, prev, verdict, why)
+	}
+}
+
+// reuseCheck returns the stored verdict of a pair classified within gDBSkipUnchanged whose
+// balance is still the one stored. RPC failures are never reused, and spam verdicts only
+// while -spam-filter is on. spam is non-nil for a reused SPAM verdict.
+func reuseCheck(ec *ethclient.Client, j pairJob) (r pairRow, spam []string, ok bool) {
+	if gResultsDB == nil || gDBSkipUnchanged <= 0 || !common.IsHexAddress(j.tokenHex) {
+		return r, nil, false
+	}
+	prv, err := hexToECDSA(j.privateHex)
+	if err != nil {
+		return r, nil, false
+	}
+	from, token := gethcrypto.PubkeyToAddress(prv.PublicKey), common.HexToAddress(j.tokenHex)
+	if j.accountHex != "" {
+		from = common.HexToAddress(j.accountHex)
+	}
+	prev, found, err := gResultsDB.Last(gCatalogChain, from.Hex(), token.Hex())
+	if err != nil || !found || prev.BalanceWei == "" || time.Since(prev.CheckedAt) > gDBSkipUnchanged ||
+		reasons.Parse(prev.ReasonCode).Transient() || (prev.Verdict == "spam" && gSpam == nil) {
+		return r, nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), getPairTimeout())
+	defer cancel()
+	bal, err := fetchTokenBalance(ctx, ec, token, from)
+	if err != nil || bal.String() != prev.BalanceWei {
+		return r, nil, false
+	}
+	r = pairRow{
+		lineNo: j.lineNo, tokenHex: j.tokenHex, privateHex: j.privateHex, reused: true,
+		fromAddress: from, tokenAddress: token, tokenSymbol: prev.Symbol, tokenDecimals: prev.Decimals,
+		balanceWei: bal, warns: prev.Warnings,
+	}
+	switch prev.Verdict {
+	case "bad":
+		r.reason, r.code = prev.Reason, reasons.Parse(prev.ReasonCode)
+		if r.reason == "" {
+			r.reason = string(r.code)
+		}
+	case "spam":
+		spam = prev.SpamReasons
+		if spam == nil {
+			spam = []string{"spam (stored verdict)"}
+		}
+	}
+	return r, spam, true
+}
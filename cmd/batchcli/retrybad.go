@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+)
+
+// Retry-bad mode (-retry-bad bad_pairs.csv -rpc2 URL): the pairs a scan rejected only because
+// its RPC timed out or rate-limited them (reason codes RPC_TIMEOUT, RPC_RATE_LIMITED) are
+// checked again on a second provider. The other rejections are final and kept as they are.
+// Pairs that pass now are appended to -out-ok, and -out-bad is rewritten with the kept rows
+// plus what still fails, so the outputs read as if the first scan had gone through cleanly.
+
+// retryReasonCodes are the BAD reason codes worth a second provider.
+var retryReasonCodes = map[reasons.Code]bool{reasons.RPCTimeout: true, reasons.RPCRateLimited: true}
+
+// rowReasonCode is the reason code of a BAD row: its reasonCode column, or (files written
+// before the column) the code of its reason text.
+func rowReasonCode(row []string, reasonCol, codeCol int) reasons.Code {
+	if codeCol >= 0 && codeCol < len(row) && strings.TrimSpace(row[codeCol]) != "" {
+		return reasons.Parse(row[codeCol])
+	}
+	return reasons.Classify(row[reasonCol])
+}
+
+// retryPath is where the retry scan writes before its results are merged:
+// ok_pairs.csv => ok_pairs.retry.csv (its manifest stays, as ok_pairs.retry.manifest.json).
+func retryPath(out string) string { return sidePath(out, "retry") }
+
+// sidePath is out with tag before its extension: ok_pairs.csv => ok_pairs.<tag>.csv.
+func sidePath(out, tag string) string {
+	return strings.TrimSuffix(out, filepath.Ext(out)) + "." + tag + filepath.Ext(out)
+}
+
+// csvTable is a CSV output with its header.
+type csvTable struct {
+	header []string
+	rows   [][]string
+}
+
+func readCSVTable(path string) (csvTable, error) {
+	var t csvTable
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t, err
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	all, err := r.ReadAll()
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(all) > 0 && skipRow(all[0], 1) {
+		t.header, all = all[0], all[1:]
+	}
+	for _, row := range all {
+		if !skipRow(row, 0) {
+			t.rows = append(t.rows, row)
+		}
+	}
+	return t, nil
+}
+
+// col is the index of the named column, -1 when missing.
+func (t csvTable) col(name string) int {
+	for i, h := range t.header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// reshape maps row (in t's columns) to the columns of header, by name.
+func (t csvTable) reshape(row []string, header []string) []string {
+	out := make([]string, len(header))
+	for i, h := range header {
+		if j := t.col(h); j >= 0 && j < len(row) {
+			out[i] = row[j]
+		}
+	}
+	return out
+}
+
+// key is the (from, token) of a row, lower-case.
+func (t csvTable) key(row []string) string {
+	get := func(name string) string {
+		if j := t.col(name); j >= 0 && j < len(row) {
+			return strings.ToLower(strings.TrimSpace(row[j]))
+		}
+		return ""
+	}
+	return get("from") + "|" + get("token")
+}
+
+func writeCSVTable(path string, t csvTable) error {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	_ = w.Write(t.header)
+	_ = w.WriteAll(t.rows)
+	if err := w.Error(); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// mergeInto appends the rows of from that dst does not have yet (by from/token) and returns
+// how many were added. A missing dst is created with from's rows.
+func mergeInto(dst string, from csvTable) (int, error) {
+	cur, err := readCSVTable(dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return len(from.rows), writeCSVTable(dst, from)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if cur.header == nil {
+		cur.header = from.header
+	}
+	seen := map[string]bool{}
+	for _, row := range cur.rows {
+		seen[cur.key(row)] = true
+	}
+	added := 0
+	for _, row := range from.rows {
+		if k := from.key(row); !seen[k] {
+			seen[k] = true
+			cur.rows = append(cur.rows, from.reshape(row, cur.header))
+			added++
+		}
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, writeCSVTable(dst, cur)
+}
+
+// runRetryBad re-checks the RPC-failed rows of cfg.retryBad on cfg.rpc2 and merges the
+// verdicts into -out-ok / -out-bad (and -out-spam). bad is the size of the corrected BAD list.
+func runRetryBad(cfg appConfig) (bad int, err error) {
+	src, err := readCSVTable(cfg.retryBad)
+	if err != nil {
+		return 0, fmt.Errorf("-retry-bad: %w", err)
+	}
+	tokCol, keyCol, reasonCol, codeCol := src.col("token"), src.col("privateKey"), src.col("reason"), src.col("reasonCode")
+	if tokCol < 0 || keyCol < 0 || reasonCol < 0 {
+		return 0, fmt.Errorf("-retry-bad %s: not a batchcli BAD output (needs token, privateKey and reason columns)", cfg.retryBad)
+	}
+	var retry bytes.Buffer
+	w := csv.NewWriter(&retry)
+	var keep [][]string
+	n := 0
+	for _, row := range src.rows {
+		if reasonCol < len(row) && keyCol < len(row) && retryReasonCodes[rowReasonCode(row, reasonCol, codeCol)] {
+			_ = w.Write([]string{row[tokCol], row[keyCol]})
+			n++
+			continue
+		}
+		keep = append(keep, row)
+	}
+	w.Flush()
+	if n == 0 {
+		fmt.Printf("[retry] no RPC_TIMEOUT/RPC_RATE_LIMITED rows in %s: nothing to re-check\n", cfg.retryBad)
+		return len(src.rows), nil
+	}
+	fmt.Printf("[retry] %d of %d rejected pair(s) failed on RPC timeouts/rate limits; re-checking them on %s\n", n, len(src.rows), runmanifest.Endpoint(cfg.rpc2))
+
+	rc := cfg
+	rc.rpcURL, rc.inputPath, rc.inputData = cfg.rpc2, cfg.retryBad, retry.Bytes()
+	rc.outOKPath, rc.outBadPath, rc.outSpamPath = retryPath(cfg.outOKPath), retryPath(cfg.outBadPath), retryPath(cfg.outSpamPath)
+	defer func() {
+		for _, p := range []string{rc.outOKPath, rc.outBadPath, rc.outSpamPath, checkpointPath(rc.outOKPath)} {
+			_ = os.Remove(p)
+		}
+	}()
+	if _, err := run(rc); err != nil {
+		return 0, fmt.Errorf("%w (nothing merged; %s is unchanged)", err, cfg.retryBad)
+	}
+
+	ok, err := readCSVTable(rc.outOKPath)
+	if err != nil {
+		return 0, err
+	}
+	still, err := readCSVTable(rc.outBadPath)
+	if err != nil {
+		return 0, err
+	}
+	added, err := mergeInto(cfg.outOKPath, ok)
+	if err != nil {
+		return 0, fmt.Errorf("merge into %s: %w", cfg.outOKPath, err)
+	}
+	spam := 0
+	if cfg.spamFilter {
+		st, err := readCSVTable(rc.outSpamPath)
+		if err != nil {
+			return 0, err
+		}
+		if spam, err = mergeInto(cfg.outSpamPath, st); err != nil {
+			return 0, fmt.Errorf("merge into %s: %w", cfg.outSpamPath, err)
+		}
+	}
+	corrected := csvTable{header: still.header}
+	for _, row := range keep {
+		corrected.rows = append(corrected.rows, src.reshape(row, still.header))
+	}
+	corrected.rows = append(corrected.rows, still.rows...)
+	if err := writeCSVTable(cfg.outBadPath, corrected); err != nil {
+		return 0, err
+	}
+	fmt.Printf("[retry] %d now OK (%d added => %s), %d spam, %d still rejected; %d other rejection(s) kept => %s\n",
+		len(ok.rows), added, cfg.outOKPath, spam, len(still.rows), len(keep), cfg.outBadPath)
+	return len(corrected.rows), nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+	"github.com/ligun0805/bundle-rescue/internal/rpcpool"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+)
+
+// dialRPC connects to -rpc: http(s) endpoints through the failover pool, or a single
+// ws:// / wss:// endpoint directly (pool is nil then). Failover works per HTTP request;
+// a WebSocket is one long-lived connection that geth's client redials when it drops.
+func dialRPC(rpcList string) (*ethclient.Client, *rpcpool.Pool, error) {
+	urls := rpcpool.Split(rpcList)
+	for _, u := range urls {
+		if !rpcdial.IsWS(u) {
+			continue
+		}
+		if len(urls) > 1 {
+			return nil, nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("-rpc: %s is a WebSocket endpoint; failover lists must be http(s) only", rpcpool.Host(u)))
+		}
+		if gAtBlock > 0 {
+			return nil, nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("-at-block needs an http(s) -rpc: %s is a WebSocket endpoint", rpcpool.Host(u)))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		ec, err := rpcdial.DialEth(ctx, u, nil)
+		if err != nil {
+			return nil, nil, exitcode.Wrap(exitcode.RPC, fmt.Errorf("dial rpc: %w", err))
+		}
+		return ec, nil, nil
+	}
+	pool, err := newRPCPool(rpcList)
+	if err != nil {
+		return nil, nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	ec, err := newEthClientWithTimeout(pool)
+	if err != nil {
+		return nil, nil, exitcode.Wrap(exitcode.RPC, fmt.Errorf("dial rpc: %w", err))
+	}
+	return ec, pool, nil
+}
+
+// checkPoolChain asks every -rpc endpoint for eth_chainId before the scan: an endpoint on
+// another chain is a config error (its answers would be silently wrong), an unreachable one
+// starts on cooldown. Single-endpoint pools are checked by the caller's ChainID call.
+func checkPoolChain(pool *rpcpool.Pool, want *big.Int) error {
+	if pool == nil || pool.Len() < 2 {
+		return nil
+	}
+	for _, u := range pool.Endpoints() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		got, err := chainIDAt(ctx, u)
+		cancel()
+		switch {
+		case err != nil:
+			pool.MarkDown(u)
+			fmt.Printf("[rpc-pool] %s unreachable at start (%v) — on cooldown\n", rpcpool.Host(u), err)
+		case got.Cmp(want) != 0:
+			return fmt.Errorf("-rpc %s is chain %s, expected %s (all endpoints must serve the same chain)", rpcpool.Host(u), got, want)
+		}
+	}
+	fmt.Printf("[rpc-pool] %d endpoint(s), chain %s\n", pool.Len(), want)
+	return nil
+}
+
+// chainIDAt calls eth_chainId on one endpoint directly (bypassing the pool).
+func chainIDAt(ctx context.Context, rpcURL string) (*big.Int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL,
+		bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("http %d: %w", resp.StatusCode, err)
+	}
+	if out.Error != nil {
+		return nil, fmt.Errorf("%s", out.Error.Message)
+	}
+	id, ok := new(big.Int).SetString(strings.TrimPrefix(out.Result, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("bad eth_chainId %q", out.Result)
+	}
+	return id, nil
+}
+
+// manifestEndpoints reduces every -rpc URL to scheme://host for the run manifest.
+func manifestEndpoints(list string) string {
+	var out []string
+	for _, u := range rpcpool.Split(list) {
+		out = append(out, runmanifest.Endpoint(u))
+	}
+	return strings.Join(out, ",")
+}
+
+// gAtBlock is -at-block: reads go through rpcpin at this block (0 = chain tip).
+var gAtBlock uint64
+
+// checkPinnedBlock makes sure the RPC serves state at -at-block (an explicit block number is
+// not rewritten by rpcpin): a future block or a pruned node fails here, not in every pair.
+func checkPinnedBlock(ec *ethclient.Client) error {
+	if gAtBlock == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	n := new(big.Int).SetUint64(gAtBlock)
+	h, err := ec.HeaderByNumber(ctx, n)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("-at-block %d: block not available (future block?): %w", gAtBlock, err))
+	}
+	if _, err := ec.BalanceAt(ctx, common.Address{}, n); err != nil {
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("-at-block %d: no state at this block (archive RPC needed): %w", gAtBlock, err))
+	}
+	fmt.Printf("[at-block] every read pinned to block %d (%s)\n", gAtBlock, time.Unix(int64(h.Time), 0).UTC().Format(time.RFC3339))
+	return nil
+}
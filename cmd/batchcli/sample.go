@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
+	"github.com/ligun0805/bundle-rescue/internal/rpcpool"
+)
+
+// Sample mode (-sample N): before committing to a scan of a huge CSV, N random rows are
+// checked exactly as the full scan would check them, and the result is extrapolated to the
+// whole input: expected OK rate (with its 95% interval), recoverable value (-usd, or the native
+// value with -gas-estimate), RPC calls and provider units, and wall time at the same
+// -workers. The sample's verdicts go to <out-ok>.sample.csv / <out-bad>.sample.csv; the
+// real outputs are not touched.
+
+// sampleRows picks n data rows of data at random (file order kept) and returns them as CSV
+// together with the number of data rows. The header, comments and blank rows are skipped.
+func sampleRows(data []byte, n int) ([]byte, int, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comma = detectDelimiter(data)
+	type row struct {
+		idx    int
+		fields []string
+	}
+	var picked []row
+	total, lineNo := 0, 0
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		lineNo++
+		if skipRow(rec, lineNo) {
+			continue
+		}
+		total++
+		// reservoir sampling: every row ends up in the sample with probability n/total
+		if len(picked) < n {
+			picked = append(picked, row{total, rec})
+		} else if j := rand.IntN(total); j < n {
+			picked[j] = row{total, rec}
+		}
+	}
+	sort.Slice(picked, func(i, j int) bool { return picked[i].idx < picked[j].idx })
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	for _, p := range picked {
+		_ = w.Write(p.fields)
+	}
+	w.Flush()
+	return b.Bytes(), total, w.Error()
+}
+
+// runSample checks cfg.sample random rows of the input and prints the extrapolation.
+func runSample(cfg appConfig) error {
+	data := cfg.inputData
+	if data == nil {
+		var err error
+		if data, err = readInput(cfg.inputPath); err != nil {
+			return fmt.Errorf("-sample: %w", err)
+		}
+	}
+	sample, total, err := sampleRows(data, cfg.sample)
+	if err != nil {
+		return fmt.Errorf("-sample: %w", err)
+	}
+	if total == 0 {
+		return errors.New("-sample: the input has no rows")
+	}
+	n := min(cfg.sample, total)
+	fmt.Printf("[sample] checking %d random row(s) of %d before the full scan\n", n, total)
+
+	rc := cfg
+	rc.inputData, rc.format = sample, formatCSV
+	rc.outOKPath, rc.outBadPath, rc.outSpamPath = sidePath(cfg.outOKPath, "sample"), sidePath(cfg.outBadPath, "sample"), sidePath(cfg.outSpamPath, "sample")
+	rc.queueOut, rc.dbPath, rc.nftScan, rc.pairLogJSON = "", "", "", ""
+	start := time.Now()
+	if _, err := run(rc); err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	calls := int64(0)
+	counts := rpcmetrics.Default.Snapshot()
+	for _, c := range counts {
+		calls += c
+	}
+	units, unit := rpcmetrics.Cost(rpcmetrics.Provider(rpcpool.Split(cfg.rpcURL)[0]), counts)
+
+	ok, err := readCSVTable(rc.outOKPath)
+	if err != nil {
+		return err
+	}
+	spam := 0
+	if cfg.spamFilter {
+		if st, err := readCSVTable(rc.outSpamPath); err == nil {
+			spam = len(st.rows)
+		}
+	}
+	scale := float64(total) / float64(n)
+	p := float64(len(ok.rows)) / float64(n)
+	lo, hi := wilson(p, n)
+	if n == total {
+		lo, hi = p, p
+	}
+	for _, l := range sampleReport(n, total, len(ok.rows), spam, p, lo, hi, scale, ok, elapsed, calls, units, unit) {
+		fmt.Println(l)
+	}
+	fmt.Printf("[sample] sample verdicts => %s, %s (the full scan's outputs are untouched)\n", rc.outOKPath, rc.outBadPath)
+	return nil
+}
+
+// wilson is the 95% Wilson score interval of an OK rate p seen in n rows (unlike p ± 1.96σ it
+// stays meaningful for small samples and rates near 0% or 100%).
+func wilson(p float64, n int) (lo, hi float64) {
+	const z = 1.96
+	nf := float64(n)
+	d := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / d
+	half := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / d
+	return max(0, center-half), min(1, center+half)
+}
+
+// sampleReport renders the extrapolation lines; lo..hi is the 95% interval of the OK rate.
+func sampleReport(n, total, okN, spam int, p, lo, hi, scale float64, ok csvTable, elapsed time.Duration, calls, units int64, unit string) []string {
+	lines := []string{fmt.Sprintf("[sample] %d/%d OK (%.1f%%, 95%%: %.1f..%.1f%%), %d spam => expected ~%.0f OK pair(s) of %d (%.0f..%.0f)",
+		okN, n, 100*p, 100*lo, 100*hi, spam, p*float64(total), total, lo*float64(total), hi*float64(total))}
+
+	usd, usdRows := 0.0, 0
+	if c := ok.col("usdValue"); c >= 0 {
+		for _, row := range ok.rows {
+			if c < len(row) {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(row[c]), 64); err == nil {
+					usd += v
+					usdRows++
+				}
+			}
+		}
+	}
+	if usdRows > 0 {
+		lines = append(lines, fmt.Sprintf("[sample] recoverable value: $%.2f in the sample (%d priced) => ~$%.0f in the full input", usd, usdRows, usd*scale))
+	}
+	native, nativeRows := new(big.Int), 0
+	if c := ok.col("valueWei"); c >= 0 {
+		for _, row := range ok.rows {
+			if c < len(row) {
+				if v, good := new(big.Int).SetString(strings.TrimSpace(row[c]), 10); good {
+					native.Add(native, v)
+					nativeRows++
+				}
+			}
+		}
+	}
+	if nativeRows > 0 {
+		f, _ := new(big.Float).Quo(new(big.Float).SetInt(native), big.NewFloat(1e18)).Float64()
+		lines = append(lines, fmt.Sprintf("[sample] recoverable value: %.4f native in the sample (%d quoted) => ~%.2f native in the full input", f, nativeRows, f*scale))
+	}
+	if usdRows == 0 && nativeRows == 0 && okN > 0 {
+		lines = append(lines, "[sample] recoverable value: not priced (-usd coingecko|uniswap or -gas-estimate price the OK pairs where a quote exists)")
+	}
+
+	full := time.Duration(float64(elapsed) * scale).Round(time.Second)
+	lines = append(lines, fmt.Sprintf("[sample] cost: %d RPC call(s) (%d %s) in %s => full scan ~%.0f call(s) (~%.0f %s), ~%s at the same -workers (per-token caching usually makes it cheaper)",
+		calls, units, unit, elapsed.Round(time.Millisecond), float64(calls)*scale, float64(units)*scale, unit, full))
+	return lines
+}
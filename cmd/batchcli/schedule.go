@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+)
+
+// nextRunFunc returns the next run time strictly after t.
+type nextRunFunc func(t time.Time) time.Time
+
+// parseSchedule accepts a Go duration ("6h", "90m"), "@hourly", "@daily",
+// or a 5-field cron spec where only minute and hour are set ("30 3 * * *", "0 * * * *").
+func parseSchedule(spec string) (nextRunFunc, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	}
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d < time.Minute {
+			return nil, fmt.Errorf("schedule %q: interval must be >= 1m", spec)
+		}
+		return func(t time.Time) time.Time { return t.Add(d) }, nil
+	}
+	f := strings.Fields(spec)
+	if len(f) != 5 || f[2] != "*" || f[3] != "*" || f[4] != "*" {
+		return nil, fmt.Errorf("schedule %q: use a duration, @hourly, @daily or \"M H * * *\"", spec)
+	}
+	minute, err := strconv.Atoi(f[0])
+	if err != nil || minute < 0 || minute > 59 {
+		return nil, fmt.Errorf("schedule %q: bad minute", spec)
+	}
+	hour := -1 // every hour
+	if f[1] != "*" {
+		if hour, err = strconv.Atoi(f[1]); err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("schedule %q: bad hour", spec)
+		}
+	}
+	return func(t time.Time) time.Time {
+		n := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), minute, 0, 0, t.Location())
+		if hour >= 0 {
+			n = time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location())
+		}
+		for !n.After(t) {
+			if hour >= 0 {
+				n = n.AddDate(0, 0, 1)
+			} else {
+				n = n.Add(time.Hour)
+			}
+		}
+		return n
+	}, nil
+}
+
+// runScheduled re-runs the scan of cfg.inputPath forever per cfg.schedule.
+// After each run the OK set is diffed against the previous one and newly
+// transferable pairs are alerted on stdout (and cfg.alertWebhook, if set).
+func runScheduled(cfg appConfig) {
+	next, err := parseSchedule(cfg.schedule)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		askExitAndQuit(exitcode.Config)
+	}
+	prev, _ := readOKSet(cfg.outOKPath) // previous run survives restarts via the OK CSV
+	for {
+		started := time.Now()
+		fmt.Printf("[schedule] scan started at %s\n", started.Format(time.RFC3339))
+		if _, err := run(cfg); stopRequested() {
+			fmt.Println("[schedule] stopped")
+			os.Exit(exitcode.Interrupted)
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "[schedule] scan error:", err)
+		} else if cur, err := readOKSet(cfg.outOKPath); err != nil {
+			fmt.Fprintln(os.Stderr, "[schedule] read OK set:", err)
+		} else {
+			added := diffOKSets(prev, cur)
+			fmt.Printf("[schedule] scan done: OK=%d new=%d (%s)\n", len(cur), len(added), time.Since(started).Round(time.Second))
+			if len(added) > 0 {
+				alertNewPairs(cfg.alertWebhook, added)
+			}
+			prev = cur
+		}
+		at := next(time.Now())
+		fmt.Printf("[schedule] next run at %s\n", at.Format(time.RFC3339))
+		select {
+		case <-time.After(time.Until(at)):
+		case <-gStopCh:
+			fmt.Println("[schedule] stopped")
+			os.Exit(exitcode.Interrupted)
+		}
+	}
+}
+
+// okPair is one row of the OK CSV, keyed by (from, token).
+type okPair struct {
+	Token    string `json:"token"`
+	From     string `json:"from"`
+	Symbol   string `json:"symbol"`
+	Balance  string `json:"balanceTokens"`
+	Wei      string `json:"balanceWei,omitempty"`
+	Decimals int    `json:"-"` // for -compare deltas
+
+	FromURL  string `json:"fromUrl,omitempty"` // explorer links, filled for alerts
+	TokenURL string `json:"tokenUrl,omitempty"`
+}
+
+func (p okPair) key() string { return strings.ToLower(p.From) + "|" + strings.ToLower(p.Token) }
+
+// readOKSet loads an OK CSV (token,privateKey,from,symbol,decimals,balanceTokens,...,balanceWei)
+// or its -format json NDJSON equivalent.
+// A missing file is an empty set.
+func readOKSet(path string) (map[string]okPair, error) {
+	out := map[string]okPair{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return readOKRecords(data)
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	for lineNo := 1; ; lineNo++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if lineNo == 1 && skipRow(row, lineNo) {
+			continue
+		}
+		if len(row) < 3 {
+			continue
+		}
+		p := okPair{Token: row[0], From: row[2]}
+		if len(row) >= 6 {
+			p.Symbol, p.Balance = row[3], row[5]
+			p.Decimals, _ = strconv.Atoi(row[4])
+		}
+		if len(row) >= 9 {
+			p.Wei = row[8]
+		}
+		out[p.key()] = p
+	}
+	return out, nil
+}
+
+// readOKRecords is readOKSet for -format json (NDJSON pairRecord lines).
+func readOKRecords(data []byte) (map[string]okPair, error) {
+	out := map[string]okPair{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var rec pairRecord
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		p := okPair{Token: rec.Token, From: rec.From, Symbol: rec.Symbol, Balance: rec.BalanceTokens, Wei: rec.BalanceWei}
+		if rec.Decimals != nil {
+			p.Decimals = *rec.Decimals
+		}
+		out[p.key()] = p
+	}
+	return out, nil
+}
+
+// diffOKSets returns pairs present in cur but not in prev.
+func diffOKSets(prev, cur map[string]okPair) []okPair {
+	var added []okPair
+	for k, p := range cur {
+		if _, ok := prev[k]; !ok {
+			added = append(added, p)
+		}
+	}
+	return added
+}
+
+// alertNewPairs prints newly transferable pairs and posts them to webhook (best-effort).
+// Private keys are never included.
+func alertNewPairs(webhook string, added []okPair) {
+	for i, p := range added {
+		fmt.Printf("[ALERT] newly transferable: from=%s token=%s %s %s\n", p.From, p.Token, privacy.Amount(p.Balance), p.Symbol)
+		added[i].FromURL, added[i].TokenURL = explorer.Address(gCatalogChain, p.From), explorer.Token(gCatalogChain, p.Token)
+		if added[i].FromURL != "" {
+			fmt.Printf("        %s\n", added[i].FromURL)
+		}
+	}
+	if strings.TrimSpace(webhook) == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]any{
+		"text":  fmt.Sprintf("batchcli: %d newly transferable pair(s)", len(added)),
+		"pairs": added,
+	})
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "[schedule] webhook error:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Fprintln(os.Stderr, "[schedule] webhook status:", resp.Status)
+	}
+}
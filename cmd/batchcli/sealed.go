@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/ligun0805/bundle-rescue/internal/config"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/sealed"
+)
+
+// Encrypted input: "batchcli seal" turns a CSV with private keys into a sealed container
+// (internal/sealed), and -input reads such a file directly — it is decrypted in memory,
+// the plaintext never touches the disk. The passphrase comes from BATCH_INPUT_PASSPHRASE
+// (env:NAME / file:/path work) or a terminal prompt.
+
+// gInputPassphrase is kept after the first successful prompt (scheduled mode re-reads the input).
+var gInputPassphrase string
+
+// inputPassphrase returns the passphrase for sealed input; confirm asks twice (sealing).
+func inputPassphrase(confirm bool) (string, error) {
+	if gInputPassphrase != "" {
+		return gInputPassphrase, nil
+	}
+	if v := strings.TrimSpace(os.Getenv("BATCH_INPUT_PASSPHRASE")); v != "" {
+		return config.ResolveSecret(v)
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("sealed input: stdin is not a terminal, set BATCH_INPUT_PASSPHRASE")
+	}
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	p, err := ask("Input passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := ask("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != p {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+// unsealInput decrypts data when it is a sealed container; plain CSV is returned as is.
+func unsealInput(data []byte) ([]byte, error) {
+	if !sealed.IsSealed(data) {
+		return data, nil
+	}
+	pass, err := inputPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := sealed.Open(data, pass)
+	if err != nil {
+		return nil, err
+	}
+	gInputPassphrase = pass
+	return pt, nil
+}
+
+// readInput is config.ReadInput for -input: sealed files are decrypted in memory.
+func readInput(path string) ([]byte, error) {
+	data, err := config.ReadInput(path)
+	if err != nil {
+		return nil, err
+	}
+	return unsealInput(data)
+}
+
+// runSealCmd is "batchcli seal" / "batchcli unseal"; it returns the exit code.
+//
+//	batchcli seal -in keys.csv [-out keys.csv.sealed] [-remove]
+//	batchcli unseal -in keys.csv.sealed          (plaintext to stdout only)
+func runSealCmd(cmd string, args []string) int {
+	fs := flag.NewFlagSet("batchcli "+cmd, flag.ContinueOnError)
+	in := fs.String("in", "", "Input file (\"-\" = stdin)")
+	out := fs.String("out", "", "Sealed output (default: <in>.sealed)")
+	remove := fs.Bool("remove", false, "Overwrite and delete the plaintext -in after sealing")
+	if err := fs.Parse(args); err != nil {
+		return exitcode.Config
+	}
+	if *in == "" {
+		fmt.Fprintf(os.Stderr, "batchcli %s: -in is required\n", cmd)
+		return exitcode.Config
+	}
+	data, err := config.ReadInput(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "batchcli %s: %v\n", cmd, err)
+		return exitcode.Config
+	}
+
+	if cmd == "unseal" {
+		pt, err := unsealInput(data)
+		if err == nil && !sealed.IsSealed(data) {
+			err = errors.New("not a sealed file")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "batchcli unseal: %v\n", err)
+			return exitcode.Config
+		}
+		_, _ = os.Stdout.Write(pt)
+		return exitcode.OK
+	}
+
+	if sealed.IsSealed(data) {
+		fmt.Fprintf(os.Stderr, "batchcli seal: %s is already sealed\n", *in)
+		return exitcode.Config
+	}
+	if *out == "" {
+		if *in == "-" {
+			fmt.Fprintln(os.Stderr, "batchcli seal: -out is required with -in -")
+			return exitcode.Config
+		}
+		*out = *in + ".sealed"
+	}
+	if _, err := os.Stat(*out); err == nil {
+		fmt.Fprintf(os.Stderr, "batchcli seal: %s already exists\n", *out)
+		return exitcode.Config
+	}
+	pass, err := inputPassphrase(true)
+	if err == nil {
+		data, err = sealed.Seal(data, pass)
+	}
+	if err == nil {
+		err = os.WriteFile(*out, data, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "batchcli seal: %v\n", err)
+		return exitcode.Failure
+	}
+	fmt.Printf("Sealed => %s\n", *out)
+	if *remove && *in != "-" {
+		if err := shredFile(*in); err != nil {
+			fmt.Fprintf(os.Stderr, "batchcli seal: remove %s: %v\n", *in, err)
+			return exitcode.Failure
+		}
+		fmt.Printf("Removed plaintext %s\n", *in)
+	}
+	return exitcode.OK
+}
+
+// shredFile overwrites path with zeros before deleting it. Best effort: journaling or
+// copy-on-write filesystems and SSDs may keep old blocks.
+func shredFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err == nil {
+		_, err = io.Copy(f, io.LimitReader(zeroReader{}, st.Size()))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+)
+
+// Graceful shutdown: the first SIGINT/SIGTERM stops handing out rows, pairs in flight
+// finish, the outputs are flushed and closed as on a normal end, and a checkpoint
+// (<out-ok>.checkpoint.json) lists the input lines already written. -resume skips those
+// lines and appends to the existing outputs. A second signal exits at once.
+
+var (
+	gStop     atomic.Bool
+	gStopCh   = make(chan struct{}) // closed on the first signal
+	gStopOnce sync.Once
+)
+
+// watchSignals installs the SIGINT/SIGTERM handler.
+func watchSignals() {
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-ch
+		gStop.Store(true)
+		gStopOnce.Do(func() { close(gStopCh) })
+		fmt.Fprintf(os.Stderr, "\n[shutdown] %s: no new rows, finishing the pairs in flight (again to exit now)\n", sig)
+		<-ch
+		fmt.Fprintln(os.Stderr, "[shutdown] forced exit: outputs may end mid-row")
+		os.Exit(exitcode.Interrupted)
+	}()
+}
+
+// stopRequested reports whether a shutdown signal arrived.
+func stopRequested() bool { return gStop.Load() }
+
+// checkpoint is the resume state of an interrupted run.
+type checkpoint struct {
+	Input     string    `json:"input"`
+	InputHash string    `json:"inputSha256"`
+	DoneLines []int     `json:"doneLines"` // input lines (CSV records, 1-based) with a verdict in the outputs
+	Written   time.Time `json:"written"`
+}
+
+// Lines of the current run; gResumeDone holds the lines of the checkpoint -resume started from.
+var (
+	gDoneMu     sync.Mutex
+	gDoneLines  []int
+	gResumeDone map[int]bool
+)
+
+func noteLineDone(lineNo int) {
+	gDoneMu.Lock()
+	gDoneLines = append(gDoneLines, lineNo)
+	gDoneMu.Unlock()
+}
+
+func checkpointPath(outOK string) string {
+	return strings.TrimSuffix(outOK, filepath.Ext(outOK)) + ".checkpoint.json"
+}
+
+// loadCheckpoint reads the checkpoint of -resume and checks it belongs to this input.
+func loadCheckpoint(path, inputHash string) (map[int]bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("-resume: no checkpoint %s (the previous run was not interrupted, or -out-ok differs)", path)
+		}
+		return nil, fmt.Errorf("-resume: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("-resume %s: %w", path, err)
+	}
+	if cp.InputHash != inputHash {
+		return nil, fmt.Errorf("-resume: %s was written for another input (%s); the input file changed since", path, cp.Input)
+	}
+	done := make(map[int]bool, len(cp.DoneLines))
+	for _, l := range cp.DoneLines {
+		done[l] = true
+	}
+	return done, nil
+}
+
+// writeCheckpoint saves the lines of the checkpoint we resumed from plus the lines of this run.
+func writeCheckpoint(path, input, inputHash string) (int, error) {
+	gDoneMu.Lock()
+	lines := append([]int(nil), gDoneLines...)
+	gDoneMu.Unlock()
+	for l := range gResumeDone {
+		lines = append(lines, l)
+	}
+	sort.Ints(lines)
+	b, err := json.MarshalIndent(checkpoint{Input: input, InputHash: inputHash, DoneLines: lines, Written: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return 0, err
+	}
+	return len(lines), os.Rename(tmp, path)
+}
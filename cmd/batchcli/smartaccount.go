@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/erc4337"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+)
+
+// Smart-account victims: when the leaked key owns an ERC-4337 account, the assets sit in the
+// account, not at the key's address. Such rows carry the account as a third column
+// (token,privateKey,account[,chain]); the pair is then checked from the account, and its
+// preflight is the account's execute(token, 0, transfer(SAFE, balance)) called from its
+// EntryPoint, as a bundler would run the UserOperation. OK rows get route "userop" and keep
+// the layout bundlecli reads: privateKey = the owner key, from = the account.
+
+const routeUserOp = "userop"
+
+// userOpOverheadGas is the gas a UserOperation costs on top of the transfer: EntryPoint
+// handleOps, account validation (ecrecover) and the bundler's preVerificationGas.
+const userOpOverheadGas = 21_000 + 45_000 + 50_000
+
+// rowAccount is the smart-account column of an input row: a hex address in the third
+// column ("" when the row has none; a chain ID or name is not an address).
+func rowAccount(row []string) string {
+	if len(row) < 3 {
+		return ""
+	}
+	if a := strings.TrimSpace(row[2]); common.IsHexAddress(a) {
+		return a
+	}
+	return ""
+}
+
+// detectSmartAccount resolves a row's account column: nil when the column is empty or is
+// the key's own address (a plain EOA row), else the account owned by key.
+func detectSmartAccount(ctx context.Context, ec *ethclient.Client, key common.Address, accountHex string) (*erc4337.Account, string) {
+	if accountHex == "" || common.HexToAddress(accountHex) == key {
+		return nil, ""
+	}
+	throttle()
+	acct, err := erc4337.Detect(ctx, ec, common.HexToAddress(accountHex))
+	if err != nil {
+		return nil, "smart account: " + err.Error()
+	}
+	if err := acct.CheckOwner(key); err != nil {
+		return nil, "smart account: " + err.Error()
+	}
+	return &acct, ""
+}
+
+// checkUserOpViability is checkTransferViability for a smart account: "" and routeUserOp
+// when execute(transfer) passes from the EntryPoint, else the reason.
+func checkUserOpViability(ctx context.Context, ec *ethclient.Client, acct *erc4337.Account, token, to common.Address, amount *big.Int) (string, string) {
+	if checkOn(checkRestrictions) {
+		restrStart := time.Now()
+		restr, err := core.CachedCheckRestrictions(ctx, gResultCache, gResultCacheTTL, ec, token, acct.Address, to)
+		stagetime.Since(stagetime.Restrictions, restrStart)
+		if err == nil && restr.Blocked() {
+			return "blocked: " + restr.Summary(), ""
+		}
+	}
+	if !checkOn(checkPreflight) {
+		return "", routeUserOp
+	}
+	defer stagetime.Since(stagetime.Preflight, time.Now())
+	backoff := 300 * time.Millisecond
+	for i := 1; i <= getPreflightAttempts(); i++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, getPreflightAttemptTimeout())
+		throttle()
+		ok, why, err := acct.SimulateExecute(attemptCtx, ec, erc4337.TransferCallData(token, to, amount))
+		cancel()
+		switch {
+		case err != nil && rpcerrors.IsTransient(err) && i < getPreflightAttempts():
+			time.Sleep(backoff)
+			backoff = min(2*backoff, 2*time.Second)
+			continue
+		case err != nil:
+			return fmt.Sprintf("%s: %v", rpcerrors.Classify(err).Tag(), err), ""
+		case !ok:
+			return "not transferable: " + why, ""
+		}
+		return "", routeUserOp
+	}
+	return fmt.Sprintf("rpc_timeout: userop preflight attempts exhausted (attempts=%d)", getPreflightAttempts()), ""
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/tokencatalog"
+)
+
+// Spam/airdrop filter (-spam-filter). OK pairs whose token scores >= spamScoreThreshold
+// go to -out-spam instead of ok_pairs.csv. Signals and weights:
+//
+//	name/symbol matches BATCH_SPAM_NAME_RE (URLs, "claim", "airdrop"...)  +1
+//	WETH liquidity in the V2 pool (-chain-id) < BATCH_SPAM_MIN_WETH        +1
+//	holders < BATCH_SPAM_MIN_HOLDERS (only with BATCH_HOLDERS_URL)         +1
+//	unverified source and younger than BATCH_SPAM_YOUNG_DAYS (ETHERSCAN_API_KEY) +1
+//
+// Signals that cannot be evaluated (no indexer / API key / RPC error) add nothing. The name
+// alone never reaches the threshold: a liquid, verified "Reward Token" stays OK.
+const spamScoreThreshold = 2
+
+const defaultSpamNameRE = `(?i)(https?://|www\.|\.(com|io|org|net|xyz|site|app|fi)\b|t\.me|claim|airdrop|reward|voucher|visit|bonus|gift)`
+
+type spamFilter struct {
+	nameRE     *regexp.Regexp
+	minWETH    *big.Int
+	minHolders int
+	holdersURL string // template with {token}, JSON answer with holdersCount/holders
+	youngAge   time.Duration
+	apiKey     string // Etherscan
+	chainID    string
+
+	mu    sync.Mutex
+	cache map[common.Address]spamVerdict
+	count int
+}
+
+// spamVerdict is the cached outcome per token; score/verified also feed the token catalog.
+type spamVerdict struct {
+	score    int
+	signals  []string
+	reasons  []string // signals when score >= spamScoreThreshold, else nil
+	verified string   // tokencatalog.Verified* ("" = not checked)
+}
+
+// gSpam is nil unless -spam-filter is on.
+var gSpam *spamFilter
+
+func newSpamFilter() (*spamFilter, error) {
+	re, err := regexp.Compile(getenv("BATCH_SPAM_NAME_RE", defaultSpamNameRE))
+	if err != nil {
+		return nil, fmt.Errorf("BATCH_SPAM_NAME_RE: %w", err)
+	}
+	minEth, err := strconv.ParseFloat(getenv("BATCH_SPAM_MIN_WETH", "0.5"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("BATCH_SPAM_MIN_WETH: %w", err)
+	}
+	minWei, _ := new(big.Float).Mul(big.NewFloat(minEth), big.NewFloat(1e18)).Int(nil)
+	minHolders, _ := strconv.Atoi(getenv("BATCH_SPAM_MIN_HOLDERS", "25"))
+	days, _ := strconv.Atoi(getenv("BATCH_SPAM_YOUNG_DAYS", "14"))
+	return &spamFilter{
+		nameRE:     re,
+		minWETH:    minWei,
+		minHolders: minHolders,
+		holdersURL: getenv("BATCH_HOLDERS_URL", ""),
+		youngAge:   time.Duration(days) * 24 * time.Hour,
+		apiKey:     getenv("ETHERSCAN_API_KEY", ""),
+		chainID:    strconv.FormatUint(gChain.ID, 10),
+		cache:      map[common.Address]spamVerdict{},
+	}, nil
+}
+
+// check returns the spam reasons for token (nil when it looks legitimate).
+// Results are cached per token: one airdrop usually hits many wallets.
+func (f *spamFilter) check(ctx context.Context, ec *ethclient.Client, token common.Address, symbol string) []string {
+	f.mu.Lock()
+	if v, ok := f.cache[token]; ok {
+		f.mu.Unlock()
+		return v.reasons
+	}
+	f.mu.Unlock()
+
+	score := 0
+	var signals []string
+	name, _ := fetchTokenName(ctx, ec, token)
+	if f.nameRE.MatchString(name) || f.nameRE.MatchString(symbol) {
+		score++
+		signals = append(signals, fmt.Sprintf("name pattern (%q/%q)", name, symbol))
+	}
+	if reserve, err := wethLiquidity(ctx, ec, token); err == nil && reserve.Cmp(f.minWETH) < 0 {
+		score++
+		signals = append(signals, "low liquidity: "+formatTokensFromWei(reserve, 18)+" WETH")
+	}
+	if n, ok := f.holders(ctx, token); ok && n < f.minHolders {
+		score++
+		signals = append(signals, fmt.Sprintf("holders=%d", n))
+	}
+	young, verified, ok := f.unverifiedYoung(ctx, ec, token)
+	if ok && young {
+		score++
+		signals = append(signals, "unverified + young contract")
+	}
+	v := spamVerdict{score: score, signals: signals, verified: verified}
+	if score >= spamScoreThreshold {
+		v.reasons = signals
+	}
+	f.mu.Lock()
+	f.cache[token] = v
+	f.mu.Unlock()
+	return v.reasons
+}
+
+// verdict returns the cached check of token (ok=false when check never ran for it).
+func (f *spamFilter) verdict(token common.Address) (spamVerdict, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.cache[token]
+	return v, ok
+}
+
+// demote counts a pair moved to the spam output.
+func (f *spamFilter) demote() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count++
+}
+
+// wethLiquidity returns the WETH reserve of the token/WETH V2 pool of the run's chain (0 when no pool).
+func wethLiquidity(ctx context.Context, ec *ethclient.Client, token common.Address) (*big.Int, error) {
+	factory, weth := gChain.V2Factory, gChain.WETH
+	if factory == (common.Address{}) {
+		return nil, fmt.Errorf("no V2 factory known for chain %d", gChain.ID)
+	}
+	data := append(common.FromHex("0xe6a43905"), common.LeftPadBytes(token.Bytes(), 32)...) // getPair(a,b)
+	data = append(data, common.LeftPadBytes(weth.Bytes(), 32)...)
+	throttle()
+	out, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &factory, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if len(out) < 32 {
+		return big.NewInt(0), nil
+	}
+	pair := common.BytesToAddress(out[12:32])
+	if pair == (common.Address{}) {
+		return big.NewInt(0), nil
+	}
+	throttle()
+	res, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &pair, Data: common.FromHex("0x0902f1ac")}) // getReserves()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) < 64 {
+		return big.NewInt(0), nil
+	}
+	// token0 is the lower address
+	if strings.ToLower(weth.Hex()) < strings.ToLower(token.Hex()) {
+		return new(big.Int).SetBytes(res[:32]), nil
+	}
+	return new(big.Int).SetBytes(res[32:64]), nil
+}
+
+// holders asks the optional indexer (BATCH_HOLDERS_URL, "{token}" is substituted).
+func (f *spamFilter) holders(ctx context.Context, token common.Address) (int, bool) {
+	if f.holdersURL == "" {
+		return 0, false
+	}
+	var m map[string]any
+	if !getJSON(ctx, strings.ReplaceAll(f.holdersURL, "{token}", token.Hex()), &m) {
+		return 0, false
+	}
+	for _, k := range []string{"holdersCount", "holders_count", "holders"} {
+		if v, ok := m[k].(float64); ok {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+// unverifiedYoung uses Etherscan: source not verified and created within youngAge.
+// verified is tokencatalog.VerifiedYes/No, or "" when Etherscan could not tell.
+func (f *spamFilter) unverifiedYoung(ctx context.Context, ec *ethclient.Client, token common.Address) (young bool, verified string, ok bool) {
+	if f.apiKey == "" {
+		return false, tokencatalog.VerifiedUnknown, false
+	}
+	base := "https://api.etherscan.io/v2/api?chainid=" + f.chainID + "&apikey=" + f.apiKey
+	var src struct {
+		Result []struct {
+			SourceCode string `json:"SourceCode"`
+		} `json:"result"`
+	}
+	if !getJSON(ctx, base+"&module=contract&action=getsourcecode&address="+token.Hex(), &src) || len(src.Result) == 0 {
+		return false, tokencatalog.VerifiedUnknown, false
+	}
+	if strings.TrimSpace(src.Result[0].SourceCode) != "" {
+		return false, tokencatalog.VerifiedYes, true
+	}
+	var cr struct {
+		Result []struct {
+			TxHash string `json:"txHash"`
+		} `json:"result"`
+	}
+	if !getJSON(ctx, base+"&module=contract&action=getcontractcreation&contractaddresses="+token.Hex(), &cr) || len(cr.Result) == 0 {
+		return false, tokencatalog.VerifiedNo, false
+	}
+	rcpt, err := ec.TransactionReceipt(ctx, common.HexToHash(cr.Result[0].TxHash))
+	if err != nil {
+		return false, tokencatalog.VerifiedNo, false
+	}
+	h, err := ec.HeaderByNumber(ctx, rcpt.BlockNumber)
+	if err != nil {
+		return false, tokencatalog.VerifiedNo, false
+	}
+	return time.Since(time.Unix(int64(h.Time), 0)) < f.youngAge, tokencatalog.VerifiedNo, true
+}
+
+func getJSON(ctx context.Context, url string, v any) bool {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode < 300 && json.NewDecoder(resp.Body).Decode(v) == nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ligun0805/bundle-rescue/internal/config"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+	"github.com/ligun0805/bundle-rescue/internal/sealed"
+)
+
+// Streamed input: -input (a file or "-") is read row by row while the pairs are checked,
+// never as a whole, so memory stays bounded and a dump larger than RAM can be scanned.
+// The bytes are hashed as they pass (run manifest, -resume); the progress line takes its
+// percentage from the byte offset of the rows done. A sealed file is the exception: it is
+// decrypted whole.
+
+// streamQueueMax bounds the rows read ahead of the workers (-workers > 1); the reader waits
+// while that many are queued.
+const streamQueueMax = 100_000
+
+// inputStream is an opened -input.
+type inputStream struct {
+	path   string
+	rc     io.ReadCloser
+	br     *bufio.Reader
+	hasher *runmanifest.InputHasher
+	read   atomic.Int64 // bytes taken from the file (compressed bytes for .gz/.zip)
+	size   int64        // file size; 0 = stdin
+	eof    bool
+}
+
+func openInputStream(path string) (*inputStream, error) {
+	s := &inputStream{path: path, hasher: runmanifest.NewInputHasher()}
+	rc, size, err := config.OpenInputCounted(path, &s.read)
+	if err != nil {
+		return nil, err
+	}
+	s.rc, s.size, s.br = rc, size, bufio.NewReader(rc)
+	return s, nil
+}
+
+// sealed reports whether the input is a sealed container (it must then be read whole).
+func (s *inputStream) sealed() bool {
+	peek, _ := s.br.Peek(len(sealed.Magic))
+	return sealed.IsSealed(peek)
+}
+
+func (s *inputStream) Read(b []byte) (int, error) {
+	n, err := s.br.Read(b)
+	_, _ = s.hasher.Write(b[:n])
+	if errors.Is(err, io.EOF) {
+		s.eof = true
+	}
+	return n, err
+}
+
+// fraction is how much of the input file has been read, 0..1; -1 for stdin.
+func (s *inputStream) fraction() float64 {
+	if s.size <= 0 {
+		return -1
+	}
+	return min(1, float64(s.read.Load())/float64(s.size))
+}
+
+// hash is the input hash (runmanifest.HashBytes of the decompressed content). Once the
+// stream was read to the end it is the hash of what passed; a file that was not (yet) read
+// through is hashed by a separate pass; stdin yields the hash of the bytes read so far.
+func (s *inputStream) hash() (string, error) {
+	if s.eof || strings.TrimSpace(s.path) == "-" {
+		return s.hasher.Sum(), nil
+	}
+	r, err := config.OpenInput(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := runmanifest.NewInputHasher()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return h.Sum(), nil
+}
+
+func (s *inputStream) Close() error { return s.rc.Close() }
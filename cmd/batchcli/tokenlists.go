@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Token allow/deny lists (-token-allowlist / -token-denylist). Applied before any RPC call.
+// nil allowlist = every token allowed.
+var (
+	gTokenAllow map[common.Address]bool
+	gTokenDeny  map[common.Address]bool
+)
+
+// tokenFilterStats are the match counts printed in the scan summary.
+type tokenFilterStats struct {
+	allowed, notAllowed, denied int
+}
+
+// loadTokenList reads one token address per line. Blank lines and "#" comments are ignored;
+// for CSV-like lines only the first column is used, so a previous ok_pairs.csv also works.
+func loadTokenList(path string) (map[common.Address]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := map[common.Address]bool{}
+	sc := bufio.NewScanner(f)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		cols := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ';' })
+		if len(cols) == 0 {
+			continue
+		}
+		first := strings.TrimSpace(cols[0])
+		if !common.IsHexAddress(first) {
+			if lineNo == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("%s:%d: not a token address: %q", path, lineNo, first)
+		}
+		out[common.HexToAddress(first)] = true
+	}
+	return out, sc.Err()
+}
+
+// setTokenLists loads the optional allow/deny lists ("" = not used).
+func setTokenLists(allowPath, denyPath string) error {
+	if p := strings.TrimSpace(allowPath); p != "" {
+		m, err := loadTokenList(p)
+		if err != nil {
+			return fmt.Errorf("token allowlist: %w", err)
+		}
+		gTokenAllow = m
+	}
+	if p := strings.TrimSpace(denyPath); p != "" {
+		m, err := loadTokenList(p)
+		if err != nil {
+			return fmt.Errorf("token denylist: %w", err)
+		}
+		gTokenDeny = m
+	}
+	return nil
+}
+
+// tokenFiltered reports whether tokenHex is excluded by the lists and updates st.
+// Malformed addresses are never filtered here: processOne reports them as usual.
+func tokenFiltered(tokenHex string, st *tokenFilterStats) (bool, string) {
+	if !common.IsHexAddress(tokenHex) {
+		return false, ""
+	}
+	t := common.HexToAddress(tokenHex)
+	if gTokenDeny[t] {
+		st.denied++
+		return true, "denylisted"
+	}
+	if gTokenAllow != nil {
+		if !gTokenAllow[t] {
+			st.notAllowed++
+			return true, "not in allowlist"
+		}
+		st.allowed++
+	}
+	return false, ""
+}
+
+func (st tokenFilterStats) String() string {
+	s := fmt.Sprintf("denylist skipped %d", st.denied)
+	if gTokenAllow != nil {
+		s = fmt.Sprintf("allowlist matched %d, skipped %d; ", st.allowed, st.notAllowed) + s
+	}
+	return s
+}
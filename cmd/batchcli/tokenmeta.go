@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+)
+
+// Per-run token metadata: decimals()/symbol() (and the EIP-1967 implementation fallback)
+// do not depend on the wallet, so a token that appears in hundreds of rows is read once per
+// run. Rows of the same token that run in parallel wait for the first read instead of
+// repeating it. Transient RPC failures are not kept: the next row of the token reads again.
+// (-cache keeps the same data across runs; this cache needs no flag.)
+
+// staticMeta is the wallet-independent part of tokenMeta.
+type staticMeta struct {
+	decimals int
+	decErr   error
+	symbol   string
+	symErr   error
+	proxy    common.Address // EIP-1967 implementation the values were read from (zero = token itself)
+}
+
+type staticMetaEntry struct {
+	done chan struct{} // closed when m is ready
+	m    staticMeta
+}
+
+var (
+	gStaticMu     sync.Mutex
+	gStatic       = map[common.Address]*staticMetaEntry{} // reset per run
+	gStaticShared int                                     // rows served from gStatic
+)
+
+func resetStaticMeta() {
+	gStaticMu.Lock()
+	gStatic, gStaticShared = map[common.Address]*staticMetaEntry{}, 0
+	gStaticMu.Unlock()
+}
+
+// staticMetaReport is the end-of-run line ("" when no row reused metadata).
+func staticMetaReport() string {
+	gStaticMu.Lock()
+	defer gStaticMu.Unlock()
+	if gStaticShared == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[meta] %d row(s) reused decimals/symbol of %d token(s) read earlier in this run", gStaticShared, len(gStatic))
+}
+
+// tokenStatic returns the static metadata of token; shared is true when another row
+// already read it.
+func tokenStatic(ctx context.Context, ec *ethclient.Client, token common.Address) (m staticMeta, shared bool) {
+	gStaticMu.Lock()
+	e, ok := gStatic[token]
+	if !ok {
+		e = &staticMetaEntry{done: make(chan struct{})}
+		gStatic[token] = e
+	}
+	gStaticMu.Unlock()
+
+	if ok {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return staticMeta{decErr: ctx.Err(), symErr: ctx.Err()}, false
+		}
+		if !definitiveCallError(e.m.decErr) || !definitiveCallError(e.m.symErr) {
+			// the first read hit a transient error and was dropped: read again for this row
+			return tokenStatic(ctx, ec, token)
+		}
+		gStaticMu.Lock()
+		gStaticShared++
+		gStaticMu.Unlock()
+		return e.m, true
+	}
+
+	e.m = readStaticMeta(ctx, ec, token)
+	if !definitiveCallError(e.m.decErr) || !definitiveCallError(e.m.symErr) {
+		gStaticMu.Lock()
+		if gStatic[token] == e {
+			delete(gStatic, token)
+		}
+		gStaticMu.Unlock()
+	}
+	close(e.done)
+	return e.m, false
+}
+
+// readStaticMeta reads decimals() and symbol() in parallel. If either fails on an EIP-1967
+// proxy, it is read from the implementation instead of reporting a broken token.
+func readStaticMeta(ctx context.Context, ec *ethclient.Client, token common.Address) staticMeta {
+	var m staticMeta
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); m.decimals, m.decErr = fetchTokenDecimals(ctx, ec, token) }()
+	go func() { defer wg.Done(); m.symbol, m.symErr = fetchTokenSymbol(ctx, ec, token) }()
+	wg.Wait()
+	if m.decErr == nil && m.symErr == nil {
+		return m
+	}
+	impl, err := core.ProxyImplementation(ctx, ec, token)
+	if err != nil || impl == (common.Address{}) {
+		return m
+	}
+	m.proxy = impl
+	if m.decErr != nil {
+		if d, e := fetchTokenDecimals(ctx, ec, impl); e == nil {
+			m.decimals, m.decErr = d, nil
+		}
+	}
+	if m.symErr != nil {
+		if sym, e := fetchTokenSymbol(ctx, ec, impl); e == nil {
+			m.symbol, m.symErr = sym, nil
+		}
+	}
+	return m
+}
+
+// definitiveCallError reports whether err is an answer of the token (revert, bad return
+// value) rather than a transport/provider failure that may go away on the next call.
+func definitiveCallError(err error) bool {
+	switch rpcerrors.Classify(err).Kind {
+	case rpcerrors.None, rpcerrors.Reverted, rpcerrors.InvalidOpcode, rpcerrors.Other:
+		return true
+	}
+	return false
+}
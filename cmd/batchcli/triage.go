@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/triage"
+)
+
+// Triage annotations (-mark / -triage-list): analysts mark OK pairs approved / review /
+// declined in the -triage sidecar CSV; bundlecli --pairs and the GUI run only approved pairs
+// when the same file is configured (TRIAGE_FILE). Offline: no input, RPC or keys needed.
+
+// markTriage applies -mark to every target: FROM:TOKEN, FROM (all tokens of the wallet) or
+// the path of an OK output file (CSV or NDJSON: every pair in it).
+func markTriage(path, status, note string, targets []string) error {
+	st, err := triage.ParseStatus(status)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("-mark %s: no pairs given (FROM:TOKEN, FROM or an OK output file)", status)
+	}
+	store, err := triage.Load(path)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, t := range targets {
+		t = strings.TrimSpace(t)
+		from, token, pair := strings.Cut(t, ":")
+		switch {
+		case pair && common.IsHexAddress(from):
+			if err := store.Set(common.HexToAddress(from), token, st, note); err != nil {
+				return fmt.Errorf("%s: %w", t, err)
+			}
+			n++
+		case common.IsHexAddress(t):
+			_ = store.Set(common.HexToAddress(t), triage.AnyToken, st, note)
+			n++
+		default:
+			if _, err := os.Stat(t); err != nil {
+				return fmt.Errorf("%s: expected FROM:TOKEN, FROM or an OK output file", t)
+			}
+			ok, err := readOKSet(t)
+			if err != nil {
+				return err
+			}
+			for _, p := range ok {
+				if !common.IsHexAddress(p.From) || !common.IsHexAddress(p.Token) {
+					continue
+				}
+				_ = store.Set(common.HexToAddress(p.From), p.Token, st, note)
+				n++
+			}
+		}
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	label := string(st)
+	if st == triage.Unmarked {
+		label = "cleared"
+	}
+	fmt.Printf("[triage] %d mark(s) %s => %s\n", n, label, path)
+	return nil
+}
+
+// listTriage prints the sidecar, one mark per line, and the per-status totals.
+func listTriage(path string) error {
+	store, err := triage.Load(path)
+	if err != nil {
+		return err
+	}
+	entries := store.Entries()
+	for _, e := range entries {
+		upd := ""
+		if !e.Updated.IsZero() {
+			upd = e.Updated.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-9s %s %-42s %s %s\n", e.Status, e.From.Hex(), e.Token, upd, e.Note)
+	}
+	c := store.Counts()
+	fmt.Printf("[triage] %s: %d mark(s): approved=%d review=%d declined=%d\n",
+		path, len(entries), c[triage.Approved], c[triage.Review], c[triage.Declined])
+	return nil
+}
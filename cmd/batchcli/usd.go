@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+)
+
+// USD value enrichment (-usd coingecko|uniswap): OK pairs get an estimated usdValue column so
+// operators can rescue the most valuable wallets first; the run ends with the top wallets.
+//
+//	coingecko  simple/token_price by contract (COINGECKO_API_KEY optional, COINGECKO_API_URL)
+//	uniswap    on-chain UniswapV2 quote token → WETH → USDC (mainnet); includes price impact
+//
+// Prices are looked up once per token. Tokens without a price leave the column empty.
+const (
+	usdCoinGecko = "coingecko"
+	usdUniswap   = "uniswap"
+
+	usdTopWallets = 10
+)
+
+var mainnetUSDC = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+
+// coinGeckoPlatforms maps chain IDs to CoinGecko asset platform ids.
+var coinGeckoPlatforms = map[string]string{
+	"1": "ethereum", "10": "optimistic-ethereum", "56": "binance-smart-chain",
+	"137": "polygon-pos", "8453": "base", "42161": "arbitrum-one",
+}
+
+// gUSD is nil unless -usd is set.
+var gUSD *usdPricer
+
+type usdPricer struct {
+	source   string
+	platform string // coingecko
+	apiURL   string
+	apiKey   string
+
+	mu       sync.Mutex
+	prices   map[common.Address]*big.Float // per whole token; nil = no price
+	ethUSD   *big.Float                    // uniswap: USDC per ETH, looked up once
+	wallets  map[common.Address]float64
+	priced   int
+	unpriced int
+}
+
+func newUSDPricer(source, chainID string) (*usdPricer, error) {
+	p := &usdPricer{
+		source:  source,
+		apiURL:  strings.TrimRight(getenv("COINGECKO_API_URL", "https://api.coingecko.com/api/v3"), "/"),
+		apiKey:  getenv("COINGECKO_API_KEY", ""),
+		prices:  map[common.Address]*big.Float{},
+		wallets: map[common.Address]float64{},
+	}
+	switch source {
+	case usdCoinGecko:
+		if p.platform = coinGeckoPlatforms[chainID]; p.platform == "" {
+			return nil, fmt.Errorf("-usd coingecko: chain %s is not mapped to a CoinGecko platform", chainID)
+		}
+	case usdUniswap:
+		if chainID != "1" {
+			return nil, fmt.Errorf("-usd uniswap: UniswapV2 quotes are mainnet-only (chain %s)", chainID)
+		}
+	default:
+		return nil, fmt.Errorf("-usd %q: expected coingecko or uniswap", source)
+	}
+	return p, nil
+}
+
+// value returns the USD value of an OK pair's balance with 2 decimals ("" = no price),
+// and adds it to the wallet's total.
+func (p *usdPricer) value(ec *ethclient.Client, r pairRow) string {
+	if r.balanceWei == nil || r.balanceWei.Sign() == 0 {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), getPairTimeout())
+	defer cancel()
+	price := p.price(ctx, ec, r.tokenAddress, r.tokenDecimals)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if price == nil {
+		p.unpriced++
+		return ""
+	}
+	usd, _ := new(big.Float).Mul(tokensOf(r.balanceWei, r.tokenDecimals), price).Float64()
+	p.priced++
+	p.wallets[r.fromAddress] += usd
+	return fmt.Sprintf("%.2f", usd)
+}
+
+// price is the cached USD price of one whole token.
+func (p *usdPricer) price(ctx context.Context, ec *ethclient.Client, token common.Address, decimals int) *big.Float {
+	p.mu.Lock()
+	if v, ok := p.prices[token]; ok {
+		p.mu.Unlock()
+		return v
+	}
+	p.mu.Unlock()
+
+	var v *big.Float
+	if p.source == usdCoinGecko {
+		v = p.coinGecko(ctx, token)
+	} else {
+		v = p.uniswap(ctx, ec, token, decimals)
+	}
+	p.mu.Lock()
+	p.prices[token] = v
+	p.mu.Unlock()
+	return v
+}
+
+func (p *usdPricer) coinGecko(ctx context.Context, token common.Address) *big.Float {
+	q := url.Values{"contract_addresses": {strings.ToLower(token.Hex())}, "vs_currencies": {"usd"}}
+	if p.apiKey != "" {
+		q.Set("x_cg_demo_api_key", p.apiKey)
+	}
+	var m map[string]map[string]float64
+	if !getJSON(ctx, p.apiURL+"/simple/token_price/"+p.platform+"?"+q.Encode(), &m) {
+		return nil
+	}
+	usd, ok := m[strings.ToLower(token.Hex())]["usd"]
+	if !ok || usd <= 0 {
+		return nil
+	}
+	return big.NewFloat(usd)
+}
+
+// uniswap quotes one whole token into WETH, then WETH into USDC.
+func (p *usdPricer) uniswap(ctx context.Context, ec *ethclient.Client, token common.Address, decimals int) *big.Float {
+	ethUSD := p.ethPrice(ctx, ec)
+	if ethUSD == nil {
+		return nil
+	}
+	one := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	throttle()
+	wei, err := core.QuoteTokenToETH(ctx, ec, token, one)
+	if err != nil || wei.Sign() == 0 {
+		return nil
+	}
+	return new(big.Float).Mul(tokensOf(wei, 18), ethUSD)
+}
+
+func (p *usdPricer) ethPrice(ctx context.Context, ec *ethclient.Client) *big.Float {
+	p.mu.Lock()
+	v := p.ethUSD
+	p.mu.Unlock()
+	if v != nil {
+		return v
+	}
+	throttle()
+	usdc, err := core.QuoteETHTo(ctx, ec, mainnetUSDC, big.NewInt(1e18))
+	if err != nil || usdc.Sign() == 0 {
+		return nil
+	}
+	v = tokensOf(usdc, 6)
+	p.mu.Lock()
+	p.ethUSD = v
+	p.mu.Unlock()
+	return v
+}
+
+// report prints the priced pairs and the wallets with the most value to rescue.
+func (p *usdPricer) report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	type wallet struct {
+		from common.Address
+		usd  float64
+	}
+	var ws []wallet
+	total := 0.0
+	for a, v := range p.wallets {
+		ws = append(ws, wallet{a, v})
+		total += v
+	}
+	sort.Slice(ws, func(i, j int) bool { return ws[i].usd > ws[j].usd })
+	fmt.Printf("[usd] %d OK pair(s) priced via %s (%d without a price), total ≈ $%s\n",
+		p.priced, p.source, p.unpriced, privacy.Amount(fmt.Sprintf("%.2f", total)))
+	for i, w := range ws {
+		if i == usdTopWallets {
+			break
+		}
+		fmt.Printf("[usd] #%d from=%s ≈ $%s\n", i+1, w.from.Hex(), privacy.Amount(fmt.Sprintf("%.2f", w.usd)))
+	}
+}
+
+// tokensOf converts base units to whole tokens.
+func tokensOf(wei *big.Int, decimals int) *big.Float {
+	f := new(big.Float).SetInt(wei)
+	return f.Quo(f, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// batchRunLog writes the batch-wide log (logs/<run>.log) and keeps one file per
+// pair under logs/<run>/<from>_<token>.log with the full decision trail of that pair.
+type batchRunLog struct {
+	runID string
+	dir   string
+	path  string
+	file  *os.File
+	w     *bufio.Writer
+}
+
+// pairLog is a per-row handle: every line goes to the batch log with the usual
+// "[row N]" prefix and, once FROM/TOKEN are known, into the pair's own file.
+type pairLog struct {
+	run  *batchRunLog
+	row  int
+	file *os.File
+}
+
+// openBatchRunLog creates logs/<runID>.log and the logs/<runID>/ directory for per-pair files.
+func openBatchRunLog(runID string) (*batchRunLog, error) {
+	dir := filepath.Join("logs", runID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join("logs", runID+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &batchRunLog{runID: runID, dir: dir, path: path, file: f, w: bufio.NewWriter(f)}, nil
+}
+
+// printf writes a run-level line (no row prefix) to the batch log.
+func (l *batchRunLog) printf(format string, args ...any) {
+	fmt.Fprintf(l.w, format, args...)
+}
+
+// Close flushes and closes the batch log.
+func (l *batchRunLog) Close() {
+	_ = l.w.Flush()
+	_ = l.file.Close()
+}
+
+// row returns a logger for CSV row N; the per-pair file is attached later via attach().
+func (l *batchRunLog) row(n int) *pairLog {
+	return &pairLog{run: l, row: n}
+}
+
+// attach opens (append mode) logs/<run>/<from>_<token>.log for this row.
+// Failures are reported to the batch log only: per-pair files are best-effort.
+func (p *pairLog) attach(from, token common.Address) {
+	if p.file != nil {
+		return
+	}
+	name := fmt.Sprintf("%s_%s.log", from.Hex(), token.Hex())
+	f, err := os.OpenFile(filepath.Join(p.run.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		fmt.Fprintf(p.run.w, "[row %d] per-pair log unavailable: %v\n", p.row, err)
+		return
+	}
+	p.file = f
+	fmt.Fprintf(f, "# run=%s row=%d from=%s token=%s started=%s\n",
+		p.run.runID, p.row, from.Hex(), token.Hex(), time.Now().Format(time.RFC3339))
+}
+
+// logf writes one decision line for this row.
+func (p *pairLog) logf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(p.run.w, "[row %d] %s\n", p.row, msg)
+	if p.file != nil {
+		fmt.Fprintf(p.file, "%s %s\n", time.Now().Format("15:04:05.000"), strings.TrimRight(msg, "\n"))
+	}
+}
+
+// Close finalizes the per-pair file, if any.
+func (p *pairLog) Close() {
+	if p.file == nil {
+		return
+	}
+	fmt.Fprintf(p.file, "# finished=%s\n", time.Now().Format(time.RFC3339))
+	_ = p.file.Close()
+	p.file = nil
+}
@@ -1,11 +1,14 @@
 package main
 
 // Batch processing of pairs (token,privateKey,from) via EIP-7702 only.
-// Reads CSV from PAIRS_CSV env var and executes each pair non-interactively.
-// Logs per-pair decisions and relay responses into logs/bundlecli_batch_<timestamp>.log.
+// Reads CSV from --pairs / PAIRS_CSV and executes each pair non-interactively.
+// Logs per-pair decisions and relay responses into logs/bundlecli_batch_<timestamp>.log
+// and mirrors each pair's trail into logs/bundlecli_batch_<timestamp>/<from>_<token>.log.
+// With --simulate-only every SetCodeTx is built, signed and checked via eth_callBundle
+// but never sent; verdicts go to logs/bundlecli_batch_<timestamp>/verdicts.csv.
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/csv"
@@ -15,6 +18,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,7 +29,20 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/config"
 	eip7702 "github.com/ligun0805/bundle-rescue/internal/eip7702"
+	"github.com/ligun0805/bundle-rescue/internal/erc4337"
+	"github.com/ligun0805/bundle-rescue/internal/errhelp"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/keyref"
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/relayseen"
+	"github.com/ligun0805/bundle-rescue/internal/riskgate"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+	"github.com/ligun0805/bundle-rescue/internal/triage"
 )
 
 // delegateABI keeps only the functions we actually use to avoid bloat.
@@ -42,8 +59,42 @@ const delegateABI = `[
    ],"outputs":[]}
 ]`
 
-// runBatchPairsFromCSV processes ok.csv-like files in a non-interactive way.
-// Each row: token,privateKey,from[,reason]
+// batchOptions are the command-line switches of the batch path.
+type batchOptions struct {
+	simulateOnly bool         // build+sign+eth_callBundle only, like core.Params.SimulateOnly
+	keys         keyref.Index // --keys: resolves kfp:... fingerprints from redacted CSVs
+	triage       *triage.Store // --triage: only approved pairs are sent (nil = all)
+}
+
+// batchEnv carries everything shared between rows of one batch run.
+type batchEnv struct {
+	opts         batchOptions
+	ec           *ethclient.Client
+	rc           *rpc.Client
+	cfg          EnvConfig
+	chainID      *big.Int
+	sponsorAddr  common.Address
+	delegates    *eip7702.DelegatePolicy // DELEGATE_ADDRESS + DELEGATE_BY_TOKEN + per-row override
+	caps         map[common.Address]delegateProbe // delegate -> bytecode probe (once per run)
+	risk         *riskgate.Policy        // RISK_*: pairs above RISK_UNATTENDED_MAX are skipped
+	parsedABI    abi.ABI
+	relays       []string
+	headers      eip7702.ExtraHeaders // bloXroute Authorization etc.
+	authSigner   *ecdsa.PrivateKey    // FLASHBOTS_AUTH_PK, nil when unset
+	sent         *relayseen.Ledger    // relays that already hold a raw tx, this run only
+	safePK       *ecdsa.PrivateKey
+	verdicts     *csv.Writer // simulate-only: from,token,route,verdict,reason,reasonCode
+	bundler      *erc4337.Bundler // BUNDLER_URL, dialed at the first smart-account row
+	// Local sponsor nonce counter: private relays do not advance pending nonce in the public RPC.
+	nextNonce uint64
+	// Outcome counters for the exit code: rows attempted, rows sent/simulated OK, SAFE ran dry.
+	rows, ok  int
+	budgetHit bool
+}
+
+// runBatchPairsFromCSV runs non-interactive EIP-7702 rescue for each CSV row.
+// CSV format: token,privateKey,from[,reason[,delegate]]; privateKey may be a kfp:... fingerprint (see --keys).
+// A from other than the key's address is an ERC-4337 account owned by the key (see userop.go).
 func runBatchPairsFromCSV(
 	ctx context.Context,
 	ec *ethclient.Client,
@@ -51,44 +102,43 @@ func runBatchPairsFromCSV(
 	chainID *big.Int,
 	sponsorAddr common.Address,
 	csvPath string,
+	opts batchOptions,
 ) error {
 	csvPath = strings.TrimSpace(csvPath)
 	if csvPath == "" {
-		return errors.New("empty CSV path")
+		return exitcode.Wrap(exitcode.Config, errors.New("empty CSV path"))
 	}
-	f, err := os.Open(csvPath)
+	data, err := config.ReadInput(csvPath) // "-" = stdin
 	if err != nil {
-		return fmt.Errorf("open CSV: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("open CSV: %w", err))
 	}
-	defer f.Close()
 
-	r := csv.NewReader(bufio.NewReader(f))
+	r := csv.NewReader(bytes.NewReader(data))
 	r.FieldsPerRecord = -1
 	rows, err := r.ReadAll()
 	if err != nil {
-		return fmt.Errorf("parse CSV: %w", err)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("parse CSV: %w", err))
 	}
 	if len(rows) == 0 {
-		return errors.New("CSV is empty")
+		return exitcode.Wrap(exitcode.Config, errors.New("CSV is empty"))
 	}
 
-	// Prepare logging
-	_ = os.MkdirAll("logs", 0o755)
-	logPath := filepath.Join("logs", fmt.Sprintf("bundlecli_batch_%s.log", time.Now().Format("20060102_150405")))
-	lf, err := os.Create(logPath)
+	// Logging: batch-wide file + per-pair files under logs/<run>/
+	runLog, err := openBatchRunLog(fmt.Sprintf("bundlecli_batch_%s", time.Now().Format("20060102_150405")))
 	if err != nil {
-	return fmt.Errorf("create log: %w", err)
+		return fmt.Errorf("create log: %w", err)
+	}
+	defer runLog.Close()
+	runLog.printf("# batch started at %s\n", time.Now().Format(time.RFC3339))
+	if opts.simulateOnly {
+		runLog.printf("# simulate-only: transactions are never sent\n")
 	}
-	defer lf.Close()
-	logw := bufio.NewWriter(lf)
-	defer logw.Flush()
-	fmt.Fprintf(logw, "# batch started at %s\n", time.Now().Format(time.RFC3339))
 
-	// Separate rpc client for PreflightTransfer7702
-	httpClient := &http.Client{ Timeout: 30 * time.Second, Transport: &http.Transport{ MaxIdleConns: 100, IdleConnTimeout: 90 * time.Second } }
-	rc, err := rpc.DialHTTPWithClient(cfg.RPC, httpClient)
+	// RPC for 7702 preflight
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: rpcmetrics.Transport(&http.Transport{MaxIdleConns: 100, IdleConnTimeout: 90 * time.Second})}
+	rc, err := rpcdial.Dial(ctx, cfg.RPC, httpClient)
 	if err != nil {
-		return err
+		return exitcode.Wrap(exitcode.RPC, err)
 	}
 	defer rc.Close()
 
@@ -97,10 +147,73 @@ func runBatchPairsFromCSV(
 		return fmt.Errorf("delegate ABI parse: %w", err)
 	}
 	if strings.TrimSpace(cfg.DelegateHex) == "" || !common.IsHexAddress(cfg.DelegateHex) {
-		return fmt.Errorf("bad DELEGATE_ADDRESS in .env")
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("bad DELEGATE_ADDRESS in .env"))
+	}
+	delegates, err := eip7702.NewDelegatePolicy(cfg.DelegateHex, cfg.DelegateAllow, cfg.DelegateByToken)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	risk, err := riskPolicy(cfg, chainID)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	nextNonce, err := eip7702.EstimateSponsorNonce(ctx, ec, sponsorAddr)
+	if err != nil {
+		return exitcode.Wrap(exitcode.RPC, fmt.Errorf("sponsor nonce error: %w", err))
+	}
+	env := &batchEnv{
+		opts: opts,
+		ec:   ec, rc: rc, cfg: cfg, chainID: chainID,
+		sponsorAddr:  sponsorAddr,
+		delegates:    delegates,
+		caps:         map[common.Address]delegateProbe{},
+		risk:         risk,
+		parsedABI:    parsedABI,
+		relays:       splitCSV(cfg.RelaysCSV),
+		headers:      bloxrouteHeaders(),
+		sent:         relayseen.NewLedger(),
+		nextNonce:    nextNonce,
+	}
+	defer func() {
+		if env.bundler != nil {
+			env.bundler.Close()
+		}
+	}()
+
+	// Run manifest (logs/<run>/manifest.json): version, config/input hashes, chain, blocks, relays.
+	man := runmanifest.New("bundlecli", runLog.runID, batchManifestConfig(cfg, sponsorAddr, opts))
+	man.SetInput(csvPath, data)
+	man.SetChainID(chainID)
+	man.SetRelays(env.relays)
+	man.MarkBlock(ctx, ec)
+	man.Outputs = []string{runLog.path, runLog.dir}
+	if head, err := ec.BlockNumber(ctx); err == nil && len(cfg.HeadCheckRPCs) > 0 {
+		hv := core.CheckHead(ctx, head, cfg.HeadCheckRPCs)
+		runLog.printf("# head: rpc=%d freshest=%d (%s) lag=%d\n", hv.Primary, hv.Best, hv.Source, hv.Lag())
+		if hv.Lag() > uint64(cfg.HeadLagWarn) {
+			fmt.Printf("  [!] RPC head %d is %d block(s) behind %s — stale provider; simulations use the fresher head\n", hv.Primary, hv.Lag(), hv.Source)
+		}
+	}
+
+	// Fail fast on bad key material / relay credentials instead of at the first send.
+	if err := validateBatchCredentials(ctx, env, runLog); err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	reportDelegateCaps(ctx, env, runLog)
+
+	if opts.simulateOnly {
+		vf, err := os.Create(filepath.Join(runLog.dir, "verdicts.csv"))
+		if err != nil {
+			return fmt.Errorf("create verdicts: %w", err)
+		}
+		defer vf.Close()
+		env.verdicts = csv.NewWriter(vf)
+		defer env.verdicts.Flush()
+		_ = env.verdicts.Write([]string{"from", "token", "route", "verdict", "reason", "reasonCode"})
 	}
-	delegateAddr := common.HexToAddress(cfg.DelegateHex)
-	relays := splitCSV(cfg.RelaysCSV)
 
 	// Skip header if present
 	start := 0
@@ -111,134 +224,492 @@ func runBatchPairsFromCSV(
 		}
 	}
 
+	// Same (from, token) twice would race on the same sponsor/victim nonces: keep the first.
+	seen := map[string]int{}
 	for i := start; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) < 3 { continue }
-		tokenHex := strings.TrimSpace(row[0])
-		fromPKHex := strings.TrimSpace(row[1])
-		fromHex := strings.TrimSpace(row[2])
-
-		if !common.IsHexAddress(tokenHex) || !common.IsHexAddress(fromHex) || len(fromPKHex) < 16 {
-			fmt.Fprintf(logw, "[row %d] skip: malformed values\n", i+1)
-			continue
+		pl := runLog.row(i + 1)
+		if k := batchRowKey(rows[i]); k != "" {
+			if first, dup := seen[k]; dup {
+				pl.logf("skip: duplicate of row %d (same from, token) — merged", first)
+				pl.Close()
+				continue
+			}
+			seen[k] = i + 1
 		}
-		token := common.HexToAddress(tokenHex)
-		from := common.HexToAddress(fromHex)
+		env.rows++
+		runBatchRow(ctx, env, rows[i], pl)
+		pl.Close()
+	}
+
+	runLog.printf("# batch finished at %s: %d/%d pair(s) ok\n", time.Now().Format(time.RFC3339), env.ok, env.rows)
+	man.MarkBlock(ctx, ec)
+	man.Result = fmt.Sprintf("%d/%d pair(s) ok", env.ok, env.rows)
+	if env.budgetHit {
+		man.Result += ", SAFE balance ran out"
+	}
+	if err := man.Write(filepath.Join(runLog.dir, "manifest.json")); err != nil {
+		runLog.printf("# manifest: %v\n", err)
+	} else {
+		runLog.printf("# manifest: version=%s config=%s input=%s blocks=%d..%d\n", man.Version, man.ConfigHash, man.InputHash, man.BlockFrom, man.BlockTo)
+	}
+	fmt.Printf("Batch log written to %s (per-pair logs in %s)\n", runLog.path, runLog.dir)
+	switch {
+	case env.budgetHit:
+		return exitcode.Wrap(exitcode.Budget, fmt.Errorf("SAFE balance ran out: %d/%d pair(s) ok", env.ok, env.rows))
+	case env.ok < env.rows:
+		return exitcode.Wrap(exitcode.Partial, fmt.Errorf("%d/%d pair(s) ok", env.ok, env.rows))
+	}
+	return nil
+}
 
-		// Check privkey -> from
-		fromPK, err := crypto.HexToECDSA(strings.TrimPrefix(fromPKHex, "0x"))
-		if err != nil || crypto.PubkeyToAddress(fromPK.PublicKey) != from {
-			fmt.Fprintf(logw, "[row %d] error: bad private key for %s\n", i+1, from.Hex())
-			continue
+// batchManifestConfig lists the settings that shape a batch run. Keys appear only as
+// addresses, the RPC URL only as its host.
+func batchManifestConfig(cfg EnvConfig, sponsor common.Address, opts batchOptions) map[string]string {
+	auth := ""
+	if a := strings.TrimSpace(cfg.AuthPK); a != "" {
+		if k, err := crypto.HexToECDSA(strings.TrimPrefix(a, "0x")); err == nil {
+			auth = crypto.PubkeyToAddress(k.PublicKey).Hex()
 		}
+	}
+	return map[string]string{
+		"rpc":             runmanifest.Endpoint(cfg.RPC),
+		"sponsor":         sponsor.Hex(),
+		"authSigner":      auth,
+		"blocks":          fmt.Sprint(cfg.Blocks),
+		"tipGwei":         fmt.Sprint(cfg.TipGwei),
+		"tipMul":          fmt.Sprint(cfg.TipMul),
+		"baseMul":         fmt.Sprint(cfg.BaseMul),
+		"bufferPct":       fmt.Sprint(cfg.BufferPct),
+		"delegate":        cfg.DelegateHex,
+		"delegateAllow":   cfg.DelegateAllow,
+		"delegateByToken": cfg.DelegateByToken,
+		"builders":        strings.Join(cfg.Builders, ","),
+		"competeBumpPct":  fmt.Sprint(cfg.CompeteBumpPct),
+		"competeMaxMul":   fmt.Sprint(cfg.CompeteMaxMul),
+		"accessList":      fmt.Sprint(cfg.AccessList),
+		"simulateOnly":    fmt.Sprint(opts.simulateOnly),
+		"keysFile":        fmt.Sprint(len(opts.keys) > 0),
+	}
+}
 
-		// Balance
-		bal, err := fetchTokenBalance(ctx, ec, token, from)
+// validateBatchCredentials parses SAFE/AUTH keys and runs an authenticated no-op call
+// against every relay. Any problem aborts the batch with guidance before the first row.
+func validateBatchCredentials(ctx context.Context, env *batchEnv, runLog *batchRunLog) error {
+	var problems []string
+	k, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(env.cfg.SafePK), "0x"))
+	if err != nil {
+		problems = append(problems, "SAFE_PRIVATE_KEY: cannot parse ("+err.Error()+")")
+	} else {
+		env.safePK = k
+	}
+	if a := strings.TrimSpace(env.cfg.AuthPK); a != "" {
+		k, err := crypto.HexToECDSA(strings.TrimPrefix(a, "0x"))
 		if err != nil {
-			fmt.Fprintf(logw, "[row %d] %s balanceOf error: %v\n", i+1, token.Hex(), err)
-			continue
+			problems = append(problems, "FLASHBOTS_AUTH_PK: cannot parse ("+err.Error()+"); expected 0x + 64 hex")
+		} else {
+			env.authSigner = k
 		}
-		if bal == nil || bal.Sign() == 0 {
-			fmt.Fprintf(logw, "[row %d] %s balance=0 - skip\n", i+1, token.Hex())
-			continue
+	}
+	if len(env.relays) == 0 {
+		problems = append(problems, "RELAYS is empty")
+	}
+	if len(problems) == 0 {
+		fmt.Println("  [*] Checking relay credentials…")
+		for _, rc := range eip7702.ValidateRelays(ctx, env.relays, env.headers, env.authSigner) {
+			runLog.printf("# relay check %s ok=%v http=%d problem=%s body=%s\n",
+				rc.RelayURL, rc.OK, rc.HTTPStatus, rc.Problem, rc.ResponseBody)
+			if rc.OK {
+				fmt.Printf("  [+] %s OK\n", rc.RelayURL)
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s -> %s", rc.RelayURL, rc.Problem, rc.Hint))
 		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Println("  [!]", p)
+		runLog.printf("# validation: %s\n", p)
+	}
+	return fmt.Errorf("startup validation failed (%d problem(s)); fix .env and re-run", len(problems))
+}
+
+// delegateProbe is the cached eip7702.ProbeDelegate result of one delegate.
+type delegateProbe struct {
+	caps eip7702.Capabilities
+	err  error
+}
 
-		// Decide route
-		ok, why, _ := core.PreflightTransfer7702(ctx, ec, rc, token, from, sponsorAddr, bal)
-		route := "sell-v2"
-		if ok { route = "transfer" }
-		fmt.Fprintf(logw, "[row %d] plan: %s (%s)\n", i+1, route, why)
+// delegateCaps probes a delegate once per run: it must be deployed before we authorize it,
+// and its function set decides which routes can be planned for it.
+func (e *batchEnv) delegateCaps(ctx context.Context, d common.Address) (eip7702.Capabilities, error) {
+	if p, seen := e.caps[d]; seen {
+		return p.caps, p.err
+	}
+	caps, err := eip7702.ProbeDelegate(ctx, e.ec, d)
+	e.caps[d] = delegateProbe{caps, err}
+	return caps, err
+}
 
-		var calldata []byte
-		switch route {
-		case "transfer":
-			calldata, err = parsedABI.Pack("sweepToken", token, sponsorAddr)
-		default:
-			amountOutMin := big.NewInt(0) // accept any, private tx mitigates MEV
-			deadline := big.NewInt(time.Now().Add(20*time.Minute).Unix())
-			calldata, err = parsedABI.Pack("sellToETH_V2", token, bal, amountOutMin, sponsorAddr, deadline)
+// reportDelegateCaps prints the capability matrix of every allowlisted delegate at startup.
+func reportDelegateCaps(ctx context.Context, env *batchEnv, runLog *batchRunLog) {
+	ds := []common.Address{env.delegates.Default}
+	for d := range env.delegates.Allow {
+		if d != env.delegates.Default {
+			ds = append(ds, d)
 		}
+	}
+	sort.Slice(ds[1:], func(i, j int) bool { return ds[1+i].Hex() < ds[1+j].Hex() })
+	fmt.Println("  [*] Delegate capabilities:")
+	for _, d := range ds {
+		caps, err := env.delegateCaps(ctx, d)
+		line := caps.Matrix()
 		if err != nil {
-			fmt.Fprintf(logw, "[row %d] abi pack failed: %v\n", i+1, err)
-			continue
+			line = "ERROR " + err.Error()
+		} else if caps.Impl != (common.Address{}) {
+			line += " (via implementation " + caps.Impl.Hex() + ")"
 		}
+		fmt.Printf("      %s: %s\n", d.Hex(), line)
+		runLog.printf("# delegate %s: %s\n", d.Hex(), line)
+	}
+}
 
-		// 7702 authorizations
-		authNonce, _ := ec.NonceAt(ctx, from, nil)
-		auths, err := eip7702.BuildAuthorizations(chainID, from, delegateAddr, authNonce, 1, fromPK)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] build auth failed: %v\n", i+1, err)
-			continue
+// batchRouteFunc is the delegate function each batch route calls.
+var batchRouteFunc = map[string]string{"transfer": "sweepToken", "sell-v2": "sellToETH_V2"}
+
+// planBatchRoute picks the first route the delegate can execute: transfer when its
+// preflight passed (and swap is not forced), otherwise sell-v2.
+func planBatchRoute(caps eip7702.Capabilities, transferOK, preferSwap bool) (route, note string, err error) {
+	candidates := []string{"sell-v2"}
+	if transferOK && !preferSwap {
+		candidates = []string{"transfer", "sell-v2"}
+	}
+	var missing []string
+	for _, r := range candidates {
+		if caps.Supports(batchRouteFunc[r]) {
+			if len(missing) > 0 {
+				note = "delegate lacks " + strings.Join(missing, ", ")
+			}
+			return r, note, nil
 		}
+		missing = append(missing, batchRouteFunc[r])
+	}
+	return "", "", fmt.Errorf("delegate %s cannot execute %s (no %s; has: %s)",
+		caps.Delegate.Hex(), strings.Join(candidates, "/"), strings.Join(missing, ", "), caps.Matrix())
+}
+
+// batchRowKey returns "from|token" (lower-case) for a well-formed row, "" otherwise.
+func batchRowKey(row []string) string {
+	if len(row) < 3 || !common.IsHexAddress(strings.TrimSpace(row[0])) || !common.IsHexAddress(strings.TrimSpace(row[2])) {
+		return ""
+	}
+	from, token := common.HexToAddress(strings.TrimSpace(row[2])), common.HexToAddress(strings.TrimSpace(row[0]))
+	return strings.ToLower(from.Hex() + "|" + token.Hex())
+}
 
-		// Sponsor fees & nonce
-		sponsorNonce, err := eip7702.EstimateSponsorNonce(ctx, ec, sponsorAddr)
+// runBatchRow plans, builds, signs and sends one sponsored 7702 tx for a CSV row.
+// Every decision is written through pl so the pair's trail ends up in its own file.
+func runBatchRow(ctx context.Context, env *batchEnv, row []string, pl *pairLog) {
+	ec := env.ec
+	if len(row) < 3 {
+		return
+	}
+	tokenHex := strings.TrimSpace(row[0])
+	fromPKHex := strings.TrimSpace(row[1])
+	fromHex := strings.TrimSpace(row[2])
+	if keyref.IsFingerprint(fromPKHex) {
+		k, err := env.opts.keys.Resolve(fromPKHex)
 		if err != nil {
-			fmt.Fprintf(logw, "[row %d] sponsor nonce error: %v\n", i+1, err)
-			continue
+			pl.logf("skip: %v", err)
+			return
 		}
-		var tipWei *big.Int
-		if cfg.TipGwei > 0 {
-			tipWei = new(big.Int).Mul(big.NewInt(cfg.TipGwei), big.NewInt(1_000_000_000))
+		fromPKHex = k
+	}
+
+	if !common.IsHexAddress(tokenHex) || !common.IsHexAddress(fromHex) || len(fromPKHex) < 16 {
+		pl.logf("skip: malformed values")
+		return
+	}
+	token := common.HexToAddress(tokenHex)
+	from := common.HexToAddress(fromHex)
+	pl.attach(from, token)
+	if env.opts.triage != nil && !env.opts.simulateOnly {
+		if st := env.opts.triage.Status(from, token); st != triage.Approved {
+			if st == triage.Unmarked {
+				st = "unmarked"
+			}
+			pl.logf("skip: triage=%s (only approved pairs are sent)", st)
+			return
 		}
-		tip, cap, err := eip7702.PrepareFees(ctx, ec, tipWei)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] fee prep error: %v\n", i+1, err)
-			continue
-		}
-		gasLimit := uint64(500_000) // transfer~90k, v2~220-300k => 500k 
-
-		// Build & sign setcode tx
-		unsigned, err := eip7702.BuildSetCodeTx(eip7702.BuildParams{
-			ChainID:           chainID,
-			SponsorNonce:      sponsorNonce,
-			GasLimit:          gasLimit,
-			MaxPriorityFeeWei: tip,
-			MaxFeeWei:         cap,
-			AuthorityEOA:      from,
-			DelegateContract:  delegateAddr,
-			Calldata:          calldata,
-			Authorizations:    auths,
-		})
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] build setcode tx failed: %v\n", i+1, err)
-			continue
+	}
+
+	fromPK, err := crypto.HexToECDSA(strings.TrimPrefix(fromPKHex, "0x"))
+	if err != nil {
+		pl.logf("error: bad private key for %s", from.Hex())
+		return
+	}
+	// A key that is not from's own may own from as an ERC-4337 account: swept by UserOperation.
+	if crypto.PubkeyToAddress(fromPK.PublicKey) != from {
+		runUserOpRow(ctx, env, token, from, fromPK, pl)
+		return
+	}
+
+	// Delegate: 5th column > DELEGATE_BY_TOKEN > DELEGATE_ADDRESS, always allowlisted.
+	override := ""
+	if len(row) >= 5 {
+		override = row[4]
+	}
+	delegate, src, err := env.delegates.Resolve(token, override)
+	if err != nil {
+		pl.logf("skip: %v", err)
+		return
+	}
+	caps, err := env.delegateCaps(ctx, delegate)
+	if err != nil {
+		pl.logf("skip: %v", err)
+		return
+	}
+	pl.logf("delegate: %s (%s)", delegate.Hex(), src)
+
+	// Balance
+	metaStart := time.Now()
+	bal, err := fetchTokenBalance(ctx, ec, token, from)
+	stagetime.Since(stagetime.Metadata, metaStart)
+	if err != nil {
+		pl.logf("%s balanceOf error: %v", token.Hex(), err)
+		return
+	}
+	if bal == nil || bal.Sign() == 0 {
+		pl.logf("%s balance=0 - skip", token.Hex())
+		return
+	}
+	pl.logf("balance=%s wei", bal.String())
+
+	// Confirmation gate: nobody confirms in batch mode, so pairs above RISK_UNATTENDED_MAX are skipped.
+	if !env.opts.simulateOnly && !riskGateOK(ctx, env, token, from, delegate, bal, publicFallback(env.cfg), pl) {
+		return
+	}
+
+	// Decide route by 7702 preflight (with optional force-swap)
+	preStart := time.Now()
+	ok, why, _ := core.PreflightTransfer7702(ctx, ec, env.rc, token, from, env.sponsorAddr, bal)
+	stagetime.Since(stagetime.Preflight, preStart)
+	// Force swap if:
+	//  • SWAP_ONLY=1 in environment, OR
+	//  • CSV has 4th column containing word "swap" for this row.
+	preferSwap := strings.EqualFold(strings.TrimSpace(os.Getenv("SWAP_ONLY")), "1")
+	if !preferSwap && len(row) >= 4 && strings.Contains(strings.ToLower(row[3]), "swap") {
+		preferSwap = true
+	}
+	// transfer when its preflight passed, else swap to ETH and send ETH to SAFE — only
+	// routes the delegate's bytecode actually implements are offered.
+	route, capNote, err := planBatchRoute(caps, ok, preferSwap)
+	if err != nil {
+		pl.logf("skip: %v", err)
+		return
+	}
+	if capNote != "" {
+		why += "; " + capNote
+	}
+	pl.logf("plan: %s (%s)", route, why)
+
+	// Additional preflight: when plan is sell-v2, ensure swap path [token->WETH] has liquidity.
+	if route == "sell-v2" {
+		swapStart := time.Now()
+		okSwap, reason := preflightSellV2GetAmountsOut(ctx, ec, env.chainID, token, bal)
+		stagetime.Since(stagetime.Preflight, swapStart)
+		if !okSwap {
+			pl.logf("sell-v2 preflight FAIL: %s - skip", reason)
+			return
 		}
-		safePK, err := crypto.HexToECDSA(strings.TrimPrefix(cfg.SafePK, "0x"))
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] safe key parse failed: %v\n", i+1, err)
-			continue
+	}
+
+	// Calldata
+	buildStart := time.Now()
+	var calldata []byte
+	switch route {
+	case "transfer":
+		calldata, err = env.parsedABI.Pack("sweepToken", token, env.sponsorAddr)
+	default:
+		amountOutMin := big.NewInt(0)
+		deadline := big.NewInt(time.Now().Add(20 * time.Minute).Unix())
+		calldata, err = env.parsedABI.Pack("sellToETH_V2", token, bal, amountOutMin, env.sponsorAddr, deadline)
+	}
+	if err != nil {
+		pl.logf("abi pack failed: %v", err)
+		return
+	}
+
+	// 7702 authorizations
+	authNonce, _ := ec.NonceAt(ctx, from, nil)
+	auths, err := eip7702.BuildAuthorizations(env.chainID, from, delegate, authNonce, 1, fromPK)
+	if err != nil {
+		pl.logf("build auth failed: %v", err)
+		return
+	}
+
+	var tipWei *big.Int
+	if env.cfg.TipGwei > 0 {
+		tipWei = new(big.Int).Mul(big.NewInt(env.cfg.TipGwei), big.NewInt(1_000_000_000))
+	}
+	tip, cap, err := eip7702.PrepareFees(ctx, ec, tipWei)
+	if err != nil {
+		pl.logf("fee prep error: %v", err)
+		return
+	}
+	gasLimit := uint64(500_000) // transfer~90k, v2~220-300k => 500k headroom
+	pl.logf("fees: tip=%s gwei maxFee=%s gwei gas=%d sponsorNonce=%d authNonce=%d",
+		formatGwei(tip), formatGwei(cap), gasLimit, env.nextNonce, authNonce)
+	if !env.opts.simulateOnly {
+		need := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), cap)
+		if have, err := ec.BalanceAt(ctx, env.sponsorAddr, nil); err == nil && have.Cmp(need) < 0 {
+			pl.logf("skip: SAFE balance %s wei < worst-case gas %s wei", have.String(), need.String())
+			env.budgetHit = true
+			return
 		}
-		signed, err := eip7702.SignSetCodeTx(chainID, safePK, unsigned)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] sign failed: %v\n", i+1, err)
-			continue
+	}
+
+	// Build & sign
+	unsigned, err := eip7702.BuildSetCodeTx(eip7702.BuildParams{
+		ChainID:           env.chainID,
+		SponsorNonce:      env.nextNonce,
+		GasLimit:          gasLimit,
+		MaxPriorityFeeWei: tip,
+		MaxFeeWei:         cap,
+		AuthorityEOA:      from,
+		DelegateContract:  delegate,
+		Calldata:          calldata,
+		Authorizations:    auths,
+	})
+	if err != nil {
+		pl.logf("build setcode tx failed: %v", err)
+		return
+	}
+	if !env.opts.simulateOnly {
+		// In simulate-only mode nothing lands, so every row simulates against the same on-chain nonce.
+		env.nextNonce++
+	}
+	signed, err := eip7702.SignSetCodeTx(env.chainID, env.safePK, unsigned)
+	stagetime.Since(stagetime.BuildSign, buildStart)
+	if err != nil {
+		pl.logf("sign failed: %v", err)
+		return
+	}
+
+	// Send private
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		pl.logf("rlp failed: %v", err)
+		return
+	}
+	pl.logf("tx: %s%s", signed.Hash().Hex(), explorerSuffix(env.chainID, signed.Hash()))
+	if env.opts.simulateOnly {
+		simStart := time.Now()
+		simulateBatchRow(ctx, env, "0x"+common.Bytes2Hex(raw), from, token, route, pl)
+		stagetime.Since(stagetime.Simulate, simStart)
+		return
+	}
+	sendStart := time.Now()
+	results := eip7702.SendPrivate(ctx, "0x"+common.Bytes2Hex(raw), env.relays, env.headers, env.authSigner, env.sent)
+	stagetime.Since(stagetime.RelaySend, sendStart)
+	accepted := false
+	for _, rr := range results {
+		note := ""
+		switch {
+		case rr.Skipped:
+			note = " (skipped: already sent)"
+		case rr.AlreadyKnown:
+			note = " (already known)"
 		}
+		pl.logf("relay=%s method=%s http=%d accepted=%v%s body=%s",
+			rr.RelayURL, rr.RequestMethod, rr.HTTPStatus, rr.Accepted, note, rr.ResponseBody)
+		if rr.Accepted {
+			accepted = true
+		}
+	}
+	if !accepted {
+		pl.logf("no relay accepted")
+		for _, l := range errhelp.Explain("no relay accepted") {
+			pl.logf("%s", l)
+		}
+		return
+	}
+	env.ok++
+}
 
-		// Send private
-		raw, err := signed.MarshalBinary()
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] rlp failed: %v\n", i+1, err)
-			continue
+// riskGateOK runs the transfer-hook check and the RISK_* assessment of one pair: false (logged
+// as a skip) when the pair needs a human confirmation. delegate is zero for routes without one.
+func riskGateOK(ctx context.Context, env *batchEnv, token, from, delegate common.Address, bal *big.Int, public bool, pl *pairLog) bool {
+	restrStart := time.Now()
+	defer stagetime.Since(stagetime.Restrictions, restrStart)
+	hookNote, err := transferHookNote(ctx, env.ec, env.rc, token, from, env.sponsorAddr, bal)
+	if err != nil {
+		pl.logf("skip: transfer hooks: %v", err)
+		return false
+	}
+	if hookNote != "" {
+		pl.logf("transfer hooks: %s", hookNote)
+	}
+	act := riskgate.Action{Token: token, Amount: bal, Recipient: env.sponsorAddr, Safe: env.sponsorAddr,
+		Delegate: delegate, PublicFallback: public, TransferHook: hookNote}
+	if len(env.risk.HighValue) > 0 {
+		act.Decimals, _ = fetchTokenDecimals(ctx, env.ec, token)
+		act.Symbol, _ = fetchTokenSymbol(ctx, env.ec, token)
+	}
+	if as := env.risk.Assess(act); !env.risk.UnattendedOK(as) {
+		pl.logf("skip: risk %s needs confirmation (RISK_UNATTENDED_MAX=%s); run it interactively", as.Summary(), env.risk.Unattended)
+		return false
+	}
+	return true
+}
+
+// simulateBatchRow runs eth_callBundle at head+1 on each relay and records the pair's verdict.
+// The pair passes when at least one relay simulates it without a revert.
+func simulateBatchRow(ctx context.Context, env *batchEnv, rawHex string, from, token common.Address, route string, pl *pairLog) {
+	head, err := env.ec.BlockNumber(ctx)
+	if err != nil {
+		pl.logf("sim: head block error: %v", err)
+		env.verdict(from, token, route, "ERROR", err.Error())
+		return
+	}
+	if hv := core.CheckHead(ctx, head, env.cfg.HeadCheckRPCs); hv.Lag() > 0 {
+		if hv.Lag() > uint64(env.cfg.HeadLagWarn) {
+			pl.logf("sim: RPC head %d is %d block(s) behind %s — using %d", hv.Primary, hv.Lag(), hv.Source, hv.Best)
 		}
-		var authSigner *ecdsa.PrivateKey
-		if strings.TrimSpace(cfg.AuthPK) != "" {
-			if k, e := crypto.HexToECDSA(strings.TrimPrefix(cfg.AuthPK, "0x")); e == nil {
-				authSigner = k
+		head = hv.Best
+	}
+	blockHex := fmt.Sprintf("0x%x", head+1)
+	verdict, reason := "FAIL", "no relays"
+	for _, sr := range eip7702.SimulatePrivate(ctx, rawHex, blockHex, env.relays, env.headers, env.authSigner) {
+		pl.logf("sim relay=%s block=%s http=%d ok=%v reason=%s body=%s",
+			sr.RelayURL, blockHex, sr.HTTPStatus, sr.OK, sr.Reason, sr.ResponseBody)
+		switch {
+		case sr.OK:
+			verdict, reason = "OK", ""
+		case verdict != "OK":
+			reason = sr.Reason
+			if sr.Err != nil {
+				reason = sr.Err.Error()
 			}
 		}
-		results := eip7702.SendPrivate(ctx, "0x"+common.Bytes2Hex(raw), relays, nil, authSigner)
-		accepted := false
-		for _, rr := range results {
-			fmt.Fprintf(logw, "[row %d] relay=%s status=%d accepted=%v err=%s\n", i+1, rr.RelayURL, rr.Status, rr.Accepted, rr.Error)
-			if rr.Accepted { accepted = true }
-		}
-		if !accepted {
-			fmt.Fprintf(logw, "[row %d] no relay accepted\n", i+1)
+	}
+	pl.logf("sim verdict: %s %s", verdict, reason)
+	if verdict != "OK" {
+		for _, l := range errhelp.Explain(reason) {
+			pl.logf("%s", l)
 		}
 	}
+	if verdict == "OK" {
+		env.ok++
+	}
+	env.verdict(from, token, route, verdict, reason)
+}
 
-	fmt.Fprintf(logw, "# batch finished at %s\n", time.Now().Format(time.RFC3339))
-	fmt.Printf("Batch log written to %s\n", logPath)
-	return nil
-}
\ No newline at end of file
+// verdict appends one simulate-only row to verdicts.csv.
+func (e *batchEnv) verdict(from, token common.Address, route, verdict, reason string) {
+	if e.verdicts == nil {
+		return
+	}
+	_ = e.verdicts.Write([]string{from.Hex(), token.Hex(), route, verdict, reason, string(reasons.Classify(reason))})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ligun0805/bundle-rescue/internal/bribe"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// adviseBribe prints the bribe advisor's proposal for moving amount of token: recent builder
+// payments, the recovered value (UniswapV2 quote) and the BRIBE_MAX_PCT cap.
+func adviseBribe(ctx context.Context, ec *ethclient.Client, cfg EnvConfig, token common.Address, amount *big.Int) bribe.Advice {
+	actx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var pricer bribe.Pricer = func(ctx context.Context, token common.Address, amount *big.Int) (*big.Int, error) {
+		return core.QuoteTokenToETH(ctx, ec, token, amount)
+	}
+	value, err := pricer(actx, token, amount)
+	if err != nil {
+		fmt.Println("  [bribe] value unknown (no UniswapV2 quote):", err, "— cap not enforced")
+		value = nil
+	}
+	vals, err := core.ScanCoinbaseBribes(actx, ec, cfg.BribeScanBlocks)
+	if err != nil {
+		fmt.Println("  [bribe] recent bribes unknown:", err)
+	}
+	sum := core.SummarizeBribes(vals)
+	eff, err := bribe.Summarize(cfg.BribeLog)
+	if err != nil {
+		fmt.Println("  [bribe]", err)
+	}
+	a := bribe.Advise(value, sum.P50, sum.P95, sum.Count, eff, bribe.Policy{TargetPct: cfg.BribeTargetPct, MaxPct: cfg.BribeMaxPct})
+
+	if a.ValueWei != nil {
+		fmt.Printf("  [bribe] recovered value ≈ %s ETH\n", formatEther(a.ValueWei))
+	}
+	if a.Samples > 0 {
+		fmt.Printf("  [bribe] last %d blocks: %d bribes, p50=%s p95=%s ETH\n", cfg.BribeScanBlocks, a.Samples, formatEther(a.MarketP50), formatEther(a.MarketP95))
+	} else {
+		fmt.Printf("  [bribe] last %d blocks: no coinbase bribes seen\n", cfg.BribeScanBlocks)
+	}
+	if eff.Runs > 0 {
+		fmt.Printf("  [bribe] history (%s): %d/%d bribed runs included, median included share %.2f%%\n", cfg.BribeLog, eff.Included, eff.Runs, eff.IncludedMedianPct)
+	}
+	if a.CapWei != nil {
+		fmt.Printf("  [bribe] cap BRIBE_MAX_PCT=%g%% of value = %s ETH\n", cfg.BribeMaxPct, formatEther(a.CapWei))
+	}
+	note := ""
+	if a.Capped {
+		note = " (cut to the cap)"
+	}
+	fmt.Printf("  [bribe] proposed: %s ETH%s\n", formatEther(a.ProposedWei), note)
+	return a
+}
+
+// recordBribe appends a bribed run to BRIBE_LOG; the advisor tunes later proposals from it.
+func recordBribe(cfg EnvConfig, a bribe.Advice, token, from common.Address, bribeWei *big.Int, res core.Result) {
+	if cfg.BribeLog == "" || bribeWei == nil || bribeWei.Sign() <= 0 {
+		return
+	}
+	err := bribe.Append(cfg.BribeLog, bribe.Record{
+		Time: time.Now(), Token: token, From: from, ValueWei: a.ValueWei,
+		BribeWei: bribeWei, Included: res.Included, Reason: res.Reason,
+	})
+	if err != nil {
+		fmt.Println("  [bribe] log:", err)
+	}
+}
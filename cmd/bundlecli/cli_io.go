@@ -8,6 +8,10 @@ import (
 	"syscall"
 
 	"golang.org/x/term"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/errhelp"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
 )
 
 func readLine(r *bufio.Reader, prompt string) string {
@@ -40,11 +44,50 @@ func AskStrategy(r *bufio.Reader) string {
     return "3"
 }
 
-// die prints an error and waits for Enter before exiting.
+// noPrompt is set by --no-prompt (or NO_PROMPT=1): exit without waiting for Enter.
+var noPrompt bool
+
+// die prints a config error and waits for Enter before exiting.
 // This prevents instant console close on Windows double-click runs.
-func die(message string) {
+func die(message string) { dieCode(exitcode.Config, message) }
+
+// dieCode is die with an explicit exit code (see internal/exitcode).
+func dieCode(code int, message string) {
 	fmt.Fprintln(os.Stderr, "Error:", message)
-	fmt.Fprint(os.Stderr, "Exit now? Press Enter to close...")
-	_, _ = bufio.NewReader(os.Stdin).ReadBytes('\n')
-	os.Exit(1)
-}
\ No newline at end of file
+	for _, l := range errhelp.Explain(message) {
+		fmt.Fprintln(os.Stderr, "  "+l)
+	}
+	if !noPrompt {
+		fmt.Fprint(os.Stderr, "Exit now? Press Enter to close...")
+		_, _ = bufio.NewReader(os.Stdin).ReadBytes('\n')
+	}
+	os.Exit(code)
+}
+
+// printHint prints the error-catalog explanation of a failure reason (nothing when unknown).
+func printHint(indent, reason string) {
+	for _, l := range errhelp.Explain(reason) {
+		fmt.Println(indent + l)
+	}
+}
+
+// printResultHint explains a run that rescued nothing, by its code; for an included one it
+// names the builder of the inclusion block and the relays credited with it.
+func printResultHint(indent string, res core.Result) {
+	if res.Included {
+		if res.Builder != "" {
+			via := strings.Join(res.WinRelays, ", ")
+			if via == "" {
+				via = "no relay of RELAYS known to reach it"
+			}
+			fmt.Printf("%s[winner] built by %s via %s\n", indent, res.Builder, via)
+		}
+		return
+	}
+	if res.Err() == nil {
+		return
+	}
+	for _, l := range errhelp.ExplainCode(string(res.Code), res.Reason) {
+		fmt.Println(indent + l)
+	}
+}
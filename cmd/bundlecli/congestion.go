@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+)
+
+// deferOptions are the start thresholds of a sending batch (--defer-max-basefee /
+// --defer-max-rise): with one set, the batch waits for the fee market to meet them.
+type deferOptions struct {
+	maxGwei    float64       // DEFER_MAX_BASEFEE_GWEI: start when the next base fee is at most this
+	maxRisePct float64       // DEFER_MAX_RISE_PCT: start when it is at most this % above the window median
+	maxWait    time.Duration // DEFER_MAX_WAIT: give up after this long (0 = wait as long as it takes)
+}
+
+func (o deferOptions) enabled() bool { return o.maxGwei > 0 || o.maxRisePct > 0 }
+
+// deferPoll is how often the fee market is sampled again while a start is deferred.
+const deferPoll = time.Minute
+
+// congestionGate prints the pre-run congestion advisory and, with thresholds set, holds the
+// start until the base fee meets them. An unreadable fee market never blocks a run.
+func congestionGate(ctx context.Context, ec *ethclient.Client, cfg EnvConfig, opts deferOptions) error {
+	if cfg.CongestionBlocks <= 0 && !opts.enabled() {
+		return nil
+	}
+	c, err := core.SampleCongestion(ctx, ec, cfg.CongestionBlocks)
+	if err != nil {
+		fmt.Println("  [gas] advisory unavailable:", err)
+		return nil
+	}
+	fmt.Println("  [gas]", c.Advisory())
+	fmt.Println("  [gas]", c.Details())
+	if !opts.enabled() {
+		return nil
+	}
+	ok, why := c.Meets(opts.maxGwei, opts.maxRisePct)
+	if ok {
+		return nil
+	}
+	deadline := time.Time{}
+	if opts.maxWait > 0 {
+		deadline = time.Now().Add(opts.maxWait)
+		fmt.Printf("  [gas] start deferred: %s; checking every %s until %s\n", why, deferPoll, deadline.Format("15:04"))
+	} else {
+		fmt.Printf("  [gas] start deferred: %s; checking every %s\n", why, deferPoll)
+	}
+	for {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return exitcode.Wrap(exitcode.Budget, fmt.Errorf("gas did not meet the start thresholds within %s (%s)", opts.maxWait, why))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(deferPoll):
+		}
+		c, err = core.SampleCongestion(ctx, ec, cfg.CongestionBlocks)
+		if err != nil {
+			fmt.Println("  [gas] sample failed:", err)
+			continue
+		}
+		if ok, why = c.Meets(opts.maxGwei, opts.maxRisePct); ok {
+			fmt.Println("  [gas] thresholds met, starting:", c.Details())
+			return nil
+		}
+		fmt.Printf("  [gas] %s still waiting: %s\n", time.Now().Format("15:04"), why)
+	}
+}
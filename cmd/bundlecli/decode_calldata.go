@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ligun0805/bundle-rescue/internal/calldata"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+)
+
+// runDecodeCalldata implements `bundlecli decode-calldata 0x...` ("-" or no argument = stdin):
+// prints the function and arguments per the delegate/ERC-20/router ABIs. Offline, no .env needed.
+func runDecodeCalldata(args []string) int {
+	in := ""
+	if len(args) > 0 && args[0] != "-" {
+		in = strings.Join(args, "")
+	} else {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "decode-calldata:", err)
+			return exitcode.Config
+		}
+		in = string(b)
+	}
+	data, err := calldata.ParseHex(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "decode-calldata:", err)
+		return exitcode.Config
+	}
+	c, err := calldata.Decode(data)
+	if c != nil {
+		fmt.Print(c)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "decode-calldata:", err)
+		return exitcode.Failure
+	}
+	return exitcode.OK
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+)
+
+// runDiscoverSelectors implements `bundlecli discover-selectors`: finds nonstandard blacklist
+// getters of a token by scanning its dispatcher and probing every public f(address) against
+// addresses known to be blacklisted on it, then records them in the selector config, where
+// the restriction checks of bundlecli, batchcli and the GUI pick them up.
+func runDiscoverSelectors(args []string) int {
+	fs := flag.NewFlagSet("discover-selectors", flag.ExitOnError)
+	tokenHex := fs.String("token", os.Getenv("TOKEN_ADDRESS"), "Token to scan")
+	blHex := fs.String("blacklisted", os.Getenv("DISCOVER_BLACKLISTED"), "Comma-separated addresses known to be blacklisted on the token")
+	out := fs.String("out", core.SelectorFile(activeProfile.Path(core.DefaultSelectorFile)), "Selector config to record the getters in (RESTRICTION_SELECTORS)")
+	dryRun := fs.Bool("dry-run", false, "Print what was found, do not write -out")
+	_ = fs.Parse(args)
+
+	if !common.IsHexAddress(strings.TrimSpace(*tokenHex)) {
+		fmt.Fprintln(os.Stderr, "discover-selectors: set -token or TOKEN_ADDRESS")
+		return exitcode.Config
+	}
+	token := common.HexToAddress(strings.TrimSpace(*tokenHex))
+	var blacklisted []common.Address
+	for _, a := range strings.Split(*blHex, ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		if !common.IsHexAddress(a) {
+			fmt.Fprintf(os.Stderr, "discover-selectors: -blacklisted %q is not an address\n", a)
+			return exitcode.Config
+		}
+		blacklisted = append(blacklisted, common.HexToAddress(a))
+	}
+	if len(blacklisted) == 0 {
+		fmt.Fprintln(os.Stderr, "discover-selectors: -blacklisted needs at least one address known to be blacklisted on the token")
+		return exitcode.Config
+	}
+	conf, err := core.LoadSelectorConfig(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "discover-selectors:", err)
+		return exitcode.Config
+	}
+	core.SetSelectorConfig(conf) // getters already recorded are not probed again
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ec, err := newEthClientWithTimeout(loadEnv().RPC)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "discover-selectors: dial:", err)
+		return exitcode.RPC
+	}
+	defer ec.Close()
+	if chainID, err := ec.ChainID(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "discover-selectors: chain id:", err)
+		return exitcode.RPC
+	} else if err := activeProfile.CheckChain(chainID); err != nil {
+		fmt.Fprintln(os.Stderr, "discover-selectors:", err)
+		return exitcode.Config
+	}
+
+	rep, err := core.DiscoverBlacklistGetters(ctx, ec, token, blacklisted)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "discover-selectors:", err)
+		return exitcode.RPC
+	}
+	code := token.Hex()
+	if rep.CodeFrom != token {
+		code += " (implementation " + rep.CodeFrom.Hex() + ")"
+	}
+	fmt.Printf("  code: %s\n  public functions: %d, probed: %d\n", code, rep.Functions, rep.Probed)
+	if len(rep.Getters) == 0 {
+		fmt.Println("  no blacklist getter found beyond the known ones")
+		return exitcode.OK
+	}
+	added := 0
+	for _, g := range rep.Getters {
+		hits := make([]string, len(g.Hits))
+		for i, a := range g.Hits {
+			hits[i] = a.Hex()
+		}
+		state := "new"
+		if !conf.Add(token, g.Selector, "discover-selectors") {
+			state = "already recorded"
+		} else {
+			added++
+		}
+		fmt.Printf("  getter %s(address): true for %s (%s)\n", hexutil.Encode(g.Selector[:]), strings.Join(hits, ", "), state)
+	}
+	if *dryRun || added == 0 {
+		return exitcode.OK
+	}
+	if err := conf.Save(*out); err != nil {
+		fmt.Fprintln(os.Stderr, "discover-selectors:", err)
+		return exitcode.Failure
+	}
+	fmt.Printf("  %d getter(s) recorded => %s\n", added, *out)
+	return exitcode.OK
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/riskgate"
+)
+
+// runDispose implements `bundlecli dispose`: after a rescue the tokens sit on the SAFE; this
+// plans (and with -execute runs) staged sells of one token for ETH from the SAFE through the
+// UniswapV2 router. Every tranche is re-quoted right before it is sent and skipped when its
+// price impact is above -max-impact-bps. Mainnet only (the router/WETH of the router route).
+func runDispose(args []string) int {
+	fs := flag.NewFlagSet("dispose", flag.ExitOnError)
+	tokenHex := fs.String("token", os.Getenv("TOKEN_ADDRESS"), "Token held by the SAFE to sell")
+	amountStr := fs.String("amount", getenv("DISPOSE_AMOUNT", ""), "Tokens to sell, human units (default: the whole SAFE balance)")
+	tranches := fs.Int("tranches", atoi(getenv("DISPOSE_TRANCHES", "4"), 4), "Number of sells the amount is split into")
+	interval := fs.Duration("interval", durationEnv("DISPOSE_INTERVAL", 15*time.Minute), "Time between tranches (lets arbitrage refill the pool)")
+	delay := fs.Duration("start-in", durationEnv("DISPOSE_START_IN", 0), "Wait before the first tranche")
+	slippage := fs.Int64("slippage-bps", atoi64(getenv("DISPOSE_SLIPPAGE_BPS", "100"), 100), "amountOutMin = fresh quote minus this many bps")
+	maxImpact := fs.Int64("max-impact-bps", atoi64(getenv("DISPOSE_MAX_IMPACT_BPS", "300"), 300), "Skip a tranche whose price impact is above this (0 = no limit)")
+	send := fs.String("send", getenv("DISPOSE_SEND", "private"), "How to send: private (-private-rpc, never in the public mempool) | public (RPC_URL)")
+	privateRPC := fs.String("private-rpc", getenv("DISPOSE_PRIVATE_RPC", "https://rpc.flashbots.net/fast"), "Private transaction RPC for -send private")
+	toHex := fs.String("to", os.Getenv("DISPOSE_TO"), "ETH recipient (default: SAFE address)")
+	wait := fs.Duration("wait", durationEnv("DISPOSE_WAIT", 5*time.Minute), "How long to wait for each tx to be mined")
+	planOut := fs.String("out", "", "Write the plan to this CSV")
+	execute := fs.Bool("execute", false, "Send the tranches (default: print the plan only)")
+	_ = fs.Parse(args)
+
+	cfg := loadEnv()
+	if strings.TrimSpace(cfg.SafePK) == "" {
+		fmt.Fprintln(os.Stderr, "dispose: SAFE_PRIVATE_KEY is empty in env")
+		return exitcode.Config
+	}
+	if !common.IsHexAddress(strings.TrimSpace(*tokenHex)) {
+		fmt.Fprintln(os.Stderr, "dispose: set -token or TOKEN_ADDRESS")
+		return exitcode.Config
+	}
+	if *send != "private" && *send != "public" {
+		fmt.Fprintf(os.Stderr, "dispose: -send %q: expected private or public\n", *send)
+		return exitcode.Config
+	}
+	if *tranches < 1 || *tranches > 100 {
+		fmt.Fprintf(os.Stderr, "dispose: -tranches %d: expected 1..100\n", *tranches)
+		return exitcode.Config
+	}
+	safePrv, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(cfg.SafePK), "0x"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose: bad SAFE_PRIVATE_KEY:", err)
+		return exitcode.Config
+	}
+	safeAddr := crypto.PubkeyToAddress(safePrv.PublicKey)
+	to := safeAddr
+	if v := strings.TrimSpace(*toHex); v != "" {
+		if !common.IsHexAddress(v) {
+			fmt.Fprintln(os.Stderr, "dispose: bad -to", v)
+			return exitcode.Config
+		}
+		to = common.HexToAddress(v)
+	}
+	token := common.HexToAddress(strings.TrimSpace(*tokenHex))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ec, err := newEthClientWithTimeout(cfg.RPC)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose: dial:", err)
+		return exitcode.RPC
+	}
+	chainID, err := ec.ChainID(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose: chain id:", err)
+		return exitcode.RPC
+	}
+	if err := activeProfile.CheckChain(chainID); err != nil {
+		fmt.Fprintln(os.Stderr, "dispose:", err)
+		return exitcode.Config
+	}
+	if chainID.Int64() != 1 {
+		fmt.Fprintf(os.Stderr, "dispose: chain %s: only mainnet UniswapV2 is supported\n", chainID)
+		return exitcode.Config
+	}
+
+	decimals, err := fetchTokenDecimals(ctx, ec, token)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose: decimals:", err)
+		return exitcode.RPC
+	}
+	symbol, _ := fetchTokenSymbol(ctx, ec, token)
+	bal, err := fetchTokenBalance(ctx, ec, token, safeAddr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose: balance:", err)
+		return exitcode.RPC
+	}
+	total := bal
+	if s := strings.TrimSpace(*amountStr); s != "" {
+		if total, err = toWeiFromTokens(s, decimals); err != nil {
+			fmt.Fprintln(os.Stderr, "dispose: bad -amount:", err)
+			return exitcode.Config
+		}
+		if total.Cmp(bal) > 0 {
+			fmt.Fprintf(os.Stderr, "dispose: -amount %s is above the SAFE balance %s\n", s, formatTokensFromWei(bal, decimals))
+			return exitcode.Config
+		}
+	}
+	fmt.Printf("  SAFE: %s | %s balance: %s\n", safeAddr.Hex(), symbol, privacy.Amount(formatTokensFromWei(bal, decimals)))
+
+	plan, err := core.PlanDisposal(ctx, ec, token, safeAddr, total, core.DisposalOptions{
+		Tranches: *tranches, Start: time.Now().Add(*delay), Interval: *interval, SlippageBps: *slippage,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose:", err)
+		return exitcode.Failure
+	}
+	printDisposalPlan(plan, symbol, decimals, *maxImpact)
+	if *planOut != "" {
+		if err := writeDisposalPlan(*planOut, plan, decimals); err != nil {
+			fmt.Fprintln(os.Stderr, "dispose:", err)
+			return exitcode.Failure
+		}
+		fmt.Println("  plan =>", *planOut)
+	}
+	if !*execute {
+		fmt.Println("  [plan only] re-run with -execute to send the tranches")
+		return exitcode.OK
+	}
+
+	risk, err := riskPolicy(cfg, chainID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dispose:", err)
+		return exitcode.Config
+	}
+	as := risk.Assess(riskgate.Action{
+		Token: token, Symbol: symbol, Decimals: decimals, Amount: total, Recipient: to, Safe: safeAddr, PublicFallback: *send == "public",
+	})
+	if err := risk.Confirm(bufio.NewReader(os.Stdin), os.Stdout, as); err != nil {
+		fmt.Println("  [dispose] aborted by confirmation gate:", err)
+		if errors.Is(err, riskgate.ErrDeclined) {
+			return exitcode.OK
+		}
+		return exitcode.Config
+	}
+
+	submissions := startSubmissionLog(cfg, "dispose")
+	defer printSubmissionLog(submissions)
+	defer submissions.Stop()
+	d := disposer{ec: ec, chainID: chainID, prv: safePrv, plan: plan, to: to, wait: *wait, relays: splitCSV(cfg.RelaysCSV)}
+	if a := strings.TrimSpace(cfg.AuthPK); a != "" {
+		if d.authPrv, err = crypto.HexToECDSA(strings.TrimPrefix(a, "0x")); err != nil {
+			fmt.Fprintln(os.Stderr, "dispose: bad FLASHBOTS_AUTH_PK:", err)
+			return exitcode.Config
+		}
+	}
+	if *send == "private" {
+		d.privateRPC = *privateRPC
+	}
+	sold, gotETH, skipped := new(big.Int), new(big.Int), 0
+	for _, t := range plan.Tranches {
+		if w := time.Until(t.At); w > 0 {
+			fmt.Printf("  [tranche %d/%d] waiting %s (until %s)\n", t.Index, len(plan.Tranches), w.Round(time.Second), t.At.Format("15:04:05"))
+			select {
+			case <-ctx.Done():
+			case <-time.After(w):
+			}
+		}
+		if ctx.Err() != nil {
+			fmt.Println("  [dispose] interrupted: remaining tranches not sent")
+			break
+		}
+		out, err := d.sell(ctx, t, *slippage, *maxImpact)
+		switch {
+		case errors.Is(err, errTrancheSkipped):
+			skipped++
+			fmt.Printf("  [tranche %d] %v\n", t.Index, err)
+		case err != nil:
+			fmt.Printf("  [tranche %d] FAILED: %v\n", t.Index, err)
+			fmt.Println("  [dispose] stopping: remaining tranches not sent")
+			printDisposalResult(sold, gotETH, total, skipped, symbol, decimals)
+			return exitcode.Failure
+		default:
+			sold.Add(sold, t.Amount)
+			gotETH.Add(gotETH, out)
+		}
+	}
+	printDisposalResult(sold, gotETH, total, skipped, symbol, decimals)
+	if sold.Cmp(total) < 0 {
+		return exitcode.Partial
+	}
+	return exitcode.OK
+}
+
+var errTrancheSkipped = errors.New("tranche skipped")
+
+// disposer sends the tranches of one plan.
+type disposer struct {
+	ec         *ethclient.Client
+	chainID    *big.Int
+	prv        *ecdsa.PrivateKey
+	plan       core.DisposalPlan
+	to         common.Address
+	privateRPC string // "" = public
+	wait       time.Duration
+	relays     []string          // RELAYS: eth_callBundle of approvals + swap before sending
+	authPrv    *ecdsa.PrivateKey // FLASHBOTS_AUTH_PK (nil = no simulation)
+}
+
+// sell re-quotes tranche t, approves the router when needed and sends the swap; it returns
+// the ETH the swap paid out.
+func (d disposer) sell(ctx context.Context, t core.SellTranche, slippageBps, maxImpactBps int64) (*big.Int, error) {
+	quote, impact, err := core.QuoteSell(ctx, d.ec, d.plan.Token, t.Amount, d.plan.Tax)
+	if err != nil {
+		return nil, err
+	}
+	if maxImpactBps > 0 && impact > maxImpactBps {
+		return nil, fmt.Errorf("%w: price impact %s%% > %s%%", errTrancheSkipped, bpsPct(impact), bpsPct(maxImpactBps))
+	}
+	// approve exactly what is left of the plan, never an unlimited allowance
+	left := new(big.Int).Set(t.Amount)
+	for _, n := range d.plan.Tranches[t.Index:] {
+		left.Add(left, n.Amount)
+	}
+	ap, err := core.PlanApproval(ctx, d.ec, d.plan.Token, d.plan.Owner, d.plan.Router, t.Amount, left)
+	if err != nil {
+		return nil, err
+	}
+	if ap.ZeroFirst {
+		fmt.Printf("  [tranche %d] allowance %s cannot be changed directly: approve(0) first\n", t.Index, ap.Allowance)
+	}
+	minOut := core.MinOut(quote, slippageBps)
+	deadline := time.Now().Add(time.Duration(len(ap.Calls)+1) * d.wait).Unix()
+	data := core.EncodeSwapExactTokensForETH(d.plan.Token, t.Amount, minOut, d.to, deadline)
+
+	// approvals + swap as one eth_callBundle: the swap's transferFrom runs on the new allowance
+	simErr := core.ErrNoSimRelay
+	if d.authPrv != nil {
+		simErr = core.SimulateApprovalRoute(ctx, d.ec, d.chainID, d.prv, d.relays, d.authPrv, ap, d.plan.Router, data)
+	}
+	switch err := simErr; {
+	case errors.Is(err, core.ErrNoSimRelay):
+		fmt.Printf("  [tranche %d] bundle simulation skipped: set FLASHBOTS_AUTH_PK and a classic relay in RELAYS\n", t.Index)
+	case err != nil:
+		return nil, err
+	default:
+		fmt.Printf("  [tranche %d] simulated: %d approve(s) + swap OK\n", t.Index, len(ap.Calls))
+	}
+	for i, c := range ap.Calls {
+		if _, err := d.sendAndWait(ctx, fmt.Sprintf("tranche %d approve %d/%d", t.Index, i+1, len(ap.Calls)), d.plan.Token, c); err != nil {
+			return nil, err
+		}
+	}
+	if len(ap.Calls) > 0 {
+		if err := ap.CheckAllowance(ctx, d.ec); err != nil {
+			return nil, fmt.Errorf("tranche %d: %w", t.Index, err)
+		}
+	}
+	fmt.Printf("  [tranche %d] selling, quote %s ETH, min %s ETH, impact %s%%\n", t.Index, privacy.Amount(formatEther(quote)), privacy.Amount(formatEther(minOut)), bpsPct(impact))
+	rcpt, err := d.sendAndWait(ctx, fmt.Sprintf("tranche %d swap", t.Index), d.plan.Router, data)
+	if err != nil {
+		return nil, err
+	}
+	out := core.SwapOutputETH(rcpt)
+	fmt.Printf("  [tranche %d] sold: %s ETH in block %s\n", t.Index, privacy.Amount(formatEther(out)), rcpt.BlockNumber)
+	return out, nil
+}
+
+// sendAndWait signs a SAFE call, sends it (private RPC or public) and waits for a
+// successful receipt.
+func (d disposer) sendAndWait(ctx context.Context, what string, to common.Address, data []byte) (*types.Receipt, error) {
+	tx, err := core.SignSafeCall(ctx, d.ec, d.chainID, d.prv, to, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	if d.privateRPC != "" {
+		err = core.SendRawPrivate(ctx, d.privateRPC, tx)
+	} else {
+		err = d.ec.SendTransaction(ctx, tx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: send: %w", what, err)
+	}
+	fmt.Printf("  [%s] sent %s%s\n", what, tx.Hash().Hex(), explorerSuffix(d.chainID, tx.Hash()))
+	waitCtx, cancel := context.WithTimeout(ctx, d.wait)
+	defer cancel()
+	rcpt, err := core.WaitReceipt(waitCtx, d.ec, tx.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	if rcpt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("%s: tx %s reverted", what, tx.Hash().Hex())
+	}
+	return rcpt, nil
+}
+
+func printDisposalPlan(p core.DisposalPlan, symbol string, decimals int, maxImpactBps int64) {
+	tax := "none"
+	if p.TaxErr != nil {
+		tax = "not measured (" + p.TaxErr.Error() + ")"
+	} else if p.Tax.Bps() > 0 {
+		tax = p.Tax.Pct() + "%"
+	}
+	fmt.Printf("  Disposal plan: %s %s in %d tranche(s) via UniswapV2, transfer tax %s\n", privacy.Amount(formatTokensFromWei(p.Total, decimals)), symbol, len(p.Tranches), tax)
+	for _, t := range p.Tranches {
+		warn := ""
+		if maxImpactBps > 0 && t.ImpactBps > maxImpactBps {
+			warn = "  [impact above -max-impact-bps: will be skipped unless the pool deepens]"
+		}
+		fmt.Printf("   #%-3d %s  %s %s  quote %s ETH  min %s ETH  impact %s%%%s\n",
+			t.Index, t.At.Format("15:04:05"), privacy.Amount(formatTokensFromWei(t.Amount, decimals)), symbol,
+			privacy.Amount(formatEther(t.QuoteWei)), privacy.Amount(formatEther(t.MinOutWei)), bpsPct(t.ImpactBps), warn)
+	}
+	fmt.Printf("  Expected: %s ETH (quotes at the current pool state)\n", privacy.Amount(formatEther(p.QuoteWei())))
+}
+
+func printDisposalResult(sold, gotETH, total *big.Int, skipped int, symbol string, decimals int) {
+	fmt.Printf("[RESULT] sold %s of %s %s for %s ETH", privacy.Amount(formatTokensFromWei(sold, decimals)), privacy.Amount(formatTokensFromWei(total, decimals)), symbol, privacy.Amount(formatEther(gotETH)))
+	if skipped > 0 {
+		fmt.Printf(", %d tranche(s) skipped", skipped)
+	}
+	fmt.Println()
+}
+
+// writeDisposalPlan saves the plan as CSV (index,at,amount,amountTokens,quoteWei,minOutWei,impactBps).
+func writeDisposalPlan(path string, p core.DisposalPlan, decimals int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"index", "at", "amount", "amountTokens", "quoteWei", "minOutWei", "impactBps"})
+	for _, t := range p.Tranches {
+		_ = w.Write([]string{strconv.Itoa(t.Index), t.At.UTC().Format(time.RFC3339), t.Amount.String(), formatTokensFromWei(t.Amount, decimals),
+			t.QuoteWei.String(), t.MinOutWei.String(), strconv.FormatInt(t.ImpactBps, 10)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func bpsPct(bps int64) string { return fmt.Sprintf("%d.%02d", bps/100, bps%100) }
+
+// durationEnv reads a duration env var ("15m"), def when unset or invalid.
+func durationEnv(k string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(getenv(k, "")); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
@@ -4,7 +4,15 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/config"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/profile"
 )
 
 type EnvConfig struct {
@@ -21,6 +29,8 @@ type EnvConfig struct {
 	BaseMul     int64
 	BufferPct   int64
 	DelegateHex string
+	DelegateAllow   string // DELEGATE_ALLOWLIST: extra delegates allowed for per-pair/per-token overrides
+	DelegateByToken string // DELEGATE_BY_TOKEN: token=delegate,...
 	Builders    []string
 	MinTs       int64
 	MaxTs       int64
@@ -28,20 +38,50 @@ type EnvConfig struct {
 	BeaverRefundTo string
 	NetBlocks   int
 	NetPcts     []int
+	CompeteBumpPct int64
+	CompeteMaxMul  float64 // COMPETE_MAX_MUL: cap of the compounded COMPETE_BUMP_PCT tip multiplier
+	AccessList     bool     // ACCESS_LIST: EIP-2930 access list on the transfer tx when it saves gas
+	ClassicRoute   string   // CLASSIC_ROUTE: transfer | router | auto
+	SellMinOutWei  *big.Int // SELL_MIN_OUT_WEI: amountOutMin for the router route (nil = quote minus SELL_SLIPPAGE_BPS)
+	SellSlippageBps int64   // SELL_SLIPPAGE_BPS: amountOutMin = router quote minus this many bps
+	HeadCheckRPCs  []string // HEAD_CHECK_RPCS: secondary endpoints to cross-check the head block
+	HeadLagWarn    int      // HEAD_LAG_WARN: warn when RPC_URL lags them by more blocks
+	BribeTargetPct  float64 // BRIBE_TARGET_PCT: proposal in % of value when no recent bribes are seen
+	BribeMaxPct     float64 // BRIBE_MAX_PCT: bribe cap in % of value (0 = no cap)
+	BribeScanBlocks int     // BRIBE_SCAN_BLOCKS: recent blocks scanned for builder payments
+	BribeLog        string  // BRIBE_LOG: efficacy log of bribed runs ("" = off)
+	CongestionBlocks int    // CONGESTION_BLOCKS: blocks sampled for the pre-batch gas advisory (0 = off)
+	BundlerURL       string // BUNDLER_URL: ERC-4337 bundler for smart-account pairs ("" = such pairs are skipped)
+	AAPrefund        bool   // AA_PREFUND: SAFE deposits a smart account's missing gas at its EntryPoint
+	Share            core.ShareOptions // MEVSHARE_*: bundles for "share:" relays (MEV-Share v0.1)
+	SubmissionLog    string // SUBMISSION_LOG: root of the relay submission records ("" = off)
+	MetricsAddr      string // METRICS_ADDR: listen address of the Prometheus /metrics endpoint ("" = off)
+}
+
+// activeProfile is the --profile in use (nil: plain .env / .env.local).
+var activeProfile *profile.Profile
+
+// loadProfileEnv loads the environment of the named profile (or the root .env files) and
+// prints the profile banner, so the console always says which client/chain it works for.
+func loadProfileEnv(name string) {
+	p, err := profile.Load(name)
+	if err != nil { die(err.Error()) }
+	activeProfile = p
+	if b := p.Banner(); b != "" { fmt.Println(b) }
 }
 
 // loadEnv reads config exactly as the old main.go did (logic preserved).
 func loadEnv() EnvConfig {
-	rpc := getenv("RPC_URL", "https://eth.llamarpc.com")
+	rpc := secretEnv("RPC_URL", "https://eth.llamarpc.com")
 	chainIDStr := getenv("CHAIN_ID", "")
     relays := getenv("RELAYS", "https://relay.flashbots.net")
     // Do not auto-append bloXroute unless explicitly requested in .env
     if v := getenv("BLOXROUTE_RELAY", ""); v != "" {
         if !strings.Contains(relays, v) { relays = relays + "," + v }
     }
-	authPK := getenv("FLASHBOTS_AUTH_PK", "")
-	safePK := getenv("SAFE_PRIVATE_KEY", "")
-	fromPK := getenv("FROM_PRIVATE_KEY", "")
+	authPK := secretEnv("FLASHBOTS_AUTH_PK", "")
+	safePK := secretEnv("SAFE_PRIVATE_KEY", "")
+	fromPK := secretEnv("FROM_PRIVATE_KEY", "")
 	tokenHex := getenv("TOKEN_ADDRESS", "")
 	blocks := atoi(getenv("BLOCKS", "6"), 6)
 	tipGwei := atoi64(getenv("TIP_GWEI", "3"), 3)
@@ -49,20 +89,63 @@ func loadEnv() EnvConfig {
 	baseMul := atoi64(getenv("BASEFEE_MUL", "2"), 2)
 	bufferPct := atoi64(getenv("BUFFER_PCT", "5"), 5)
 	delegateHex := getenv("DELEGATE_ADDRESS", "")
+	delegateAllow := getenv("DELEGATE_ALLOWLIST", "")
+	delegateByToken := getenv("DELEGATE_BY_TOKEN", "")
 	builders := splitCSV(getenv("BUILDERS", ""))
 	minTs := atoi64(getenv("MIN_TIMESTAMP", "0"), 0)
 	maxTs := atoi64(getenv("MAX_TIMESTAMP", "0"), 0)
 	beaverAllow := strings.ToLower(getenv("BEAVER_ALLOW_BUILDERNET_REFUNDS", "true")) == "true"
 	beaverRefundTo := strings.TrimSpace(getenv("BEAVER_REFUND_RECIPIENT", ""))
 	netBlocks := atoi(getenv("NETCHECK_BLOCKS", "100"), 100)
+	competeBump := atoi64(getenv("COMPETE_BUMP_PCT", "0"), 0)
+	competeMax := atof(getenv("COMPETE_MAX_MUL", "4"), core.DefaultCompeteMaxMul)
+	accessList := strings.TrimSpace(getenv("ACCESS_LIST", "0")) == "1"
+	classicRoute := strings.ToLower(strings.TrimSpace(getenv("CLASSIC_ROUTE", "transfer")))
+	var sellMinOut *big.Int // unset: quoted per attempt, a typo must not mean amountOutMin=0
+	if s := strings.TrimSpace(getenv("SELL_MIN_OUT_WEI", "")); s != "" {
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok || v.Sign() < 0 { die("SELL_MIN_OUT_WEI: not a wei amount (decimal integer): " + s) }
+		sellMinOut = v
+	}
+	sellSlippage, err := strconv.ParseInt(strings.TrimSpace(getenv("SELL_SLIPPAGE_BPS", "100")), 10, 64)
+	if err != nil || sellSlippage <= 0 || sellSlippage >= 10_000 { die("SELL_SLIPPAGE_BPS: expected bps between 1 and 9999") }
 	netPcts := parseCSVInts(getenv("NETCHECK_PCTS", "50,95,99"), []int{50, 95, 99})
+	headCheck := splitCSV(secretEnv("HEAD_CHECK_RPCS", ""))
+	headLagWarn := atoi(getenv("HEAD_LAG_WARN", "2"), 2)
+	bribeTarget := atof(getenv("BRIBE_TARGET_PCT", "5"), 5)
+	bribeMax := atof(getenv("BRIBE_MAX_PCT", "20"), 20)
+	bribeScan := atoi(getenv("BRIBE_SCAN_BLOCKS", "50"), 50)
+	bribeLog := getenv("BRIBE_LOG", "bribe_log.csv")
+	if strings.EqualFold(bribeLog, "off") { bribeLog = "" }
+	congestionBlocks := atoi(getenv("CONGESTION_BLOCKS", "300"), core.DefaultCongestionBlocks)
+	bundlerURL := secretEnv("BUNDLER_URL", "")
+	aaPrefund := strings.TrimSpace(getenv("AA_PREFUND", "0")) == "1"
+	shareHints, err := core.ParseShareHints(getenv("MEVSHARE_HINTS", ""))
+	if err != nil { die("MEVSHARE_HINTS: " + err.Error()) }
+	share := core.ShareOptions{
+		MaxBlocks: atoi(getenv("MEVSHARE_MAX_BLOCKS", "0"), 0), Hints: shareHints,
+		Builders: splitCSV(getenv("MEVSHARE_BUILDERS", "")), RefundTo: getenv("MEVSHARE_REFUND_RECIPIENT", ""),
+	}
+	if share.RefundTo != "" && !common.IsHexAddress(share.RefundTo) { die("MEVSHARE_REFUND_RECIPIENT: not an address: " + share.RefundTo) }
+	submissionLog := getenv("SUBMISSION_LOG", activeProfile.Path(defaultSubmissionLog))
+	if strings.EqualFold(submissionLog, "off") { submissionLog = "" }
+	metricsAddr := strings.TrimSpace(getenv("METRICS_ADDR", ""))
 	return EnvConfig{
 		RPC: rpc, ChainIDStr: chainIDStr, RelaysCSV: relays, AuthPK: authPK, SafePK: safePK, FromPK: fromPK, TokenAddrHex: tokenHex,
 		Blocks: blocks, TipGwei: tipGwei, TipMul: tipMul, BaseMul: baseMul, BufferPct: bufferPct,
-		DelegateHex: delegateHex,
+		DelegateHex: delegateHex, DelegateAllow: delegateAllow, DelegateByToken: delegateByToken,
 		Builders: builders, MinTs: minTs, MaxTs: maxTs,
 		BeaverAllow: beaverAllow, BeaverRefundTo: beaverRefundTo,
 		NetBlocks: netBlocks, NetPcts: netPcts,
+		CompeteBumpPct: competeBump, CompeteMaxMul: competeMax, AccessList: accessList,
+		ClassicRoute: classicRoute, SellMinOutWei: sellMinOut, SellSlippageBps: sellSlippage,
+		HeadCheckRPCs: headCheck, HeadLagWarn: headLagWarn,
+		BribeTargetPct: bribeTarget, BribeMaxPct: bribeMax, BribeScanBlocks: bribeScan, BribeLog: bribeLog,
+		CongestionBlocks: congestionBlocks,
+		BundlerURL: bundlerURL, AAPrefund: aaPrefund,
+		Share: share,
+		SubmissionLog: submissionLog,
+		MetricsAddr: metricsAddr,
 	}
 }
 
@@ -73,7 +156,11 @@ func printConfig(
     tokenAddr Address,
     fromAddr Address, fromTokBal *big.Int, tokSymbol string, tokDec int, fromEthBal *big.Int,
 ) {
-    fmt.Println("=== CONFIG (.env) ===")
+    if activeProfile != nil {
+        fmt.Println("=== CONFIG (profile " + activeProfile.Name + ") ===")
+    } else {
+        fmt.Println("=== CONFIG (.env) ===")
+    }
     fmt.Println("RPC_URL           :", cfg.RPC)
     fmt.Println("CHAIN_ID          :", chainID.String())
     fmt.Println("RELAYS            :", cfg.RelaysCSV)
@@ -84,7 +171,7 @@ func printConfig(
     fmt.Println()
     fmt.Println("SAFE_PRIVATE_KEY  :", maskHex(cfg.SafePK))
     fmt.Println("  -> Safe address :", safeAddr.Hex())
-    fmt.Println("  -> Safe balance :", formatEther(safeBal), "ETH")
+    fmt.Println("  -> Safe balance :", privacy.Amount(formatEther(safeBal)), "ETH")
     if (tokenAddr != Address{}) {
         fmt.Println("TOKEN_ADDRESS     :", tokenAddr.Hex())
     } else {
@@ -95,8 +182,8 @@ func printConfig(
     if fromTokBal == nil { fromTokBal = big.NewInt(0) }
     if tokDec < 0 { tokDec = 18 }
     if tokSymbol == "" { tokSymbol = "TOKEN" }
-    fmt.Printf("  -> From balance (tokens) : %s %s\n", formatTokensFromWei(fromTokBal, tokDec), tokSymbol)
-    fmt.Println("  -> From balance (ETH)    :", formatEther(fromEthBal), "ETH")
+    fmt.Printf("  -> From balance (tokens) : %s %s\n", privacy.Amount(formatTokensFromWei(fromTokBal, tokDec)), tokSymbol)
+    fmt.Println("  -> From balance (ETH)    :", privacy.Amount(formatEther(fromEthBal)), "ETH")
     fmt.Println("Blocks            :", cfg.Blocks)
     fmt.Println("Tip (gwei)        :", cfg.TipGwei)
     fmt.Println("TipMul            :", cfg.TipMul)
@@ -106,6 +193,8 @@ func printConfig(
 }
 
 func getenv(k, d string) string { v := strings.TrimSpace(os.Getenv(k)); if v=="" { return d }; return v }
+// secretEnv is getenv that also expands env:NAME / file:/path references (see config.ResolveSecret).
+func secretEnv(k, d string) string { v, err := config.ResolveSecret(getenv(k, d)); if err != nil { die(k+": "+err.Error()) }; return v }
 func atoi(s string, d int) int { var n int; _,err := fmt.Sscan(strings.TrimSpace(s), &n); if err!=nil { return d }; return n }
 func atoi64(s string, d int64) int64 { var n int64; _,err := fmt.Sscan(strings.TrimSpace(s), &n); if err!=nil { return d }; return n }
 func atof(s string, d float64) float64 { var n float64; _,err := fmt.Sscan(strings.TrimSpace(s), &n); if err!=nil { return d }; return n }
@@ -113,6 +202,8 @@ func must(err error, msg string) { if err!=nil { die(msg+": "+err.Error()) } }
 // NOTE: die(...) is defined in cli_io.go to show the error and wait for Enter before exiting.
 func mustBig(s string) *big.Int { z,newOk := new(big.Int), false; s=strings.TrimSpace(s); if strings.HasPrefix(s,"0x") { z,newOk = z.SetString(s[2:],16) } else { z,newOk = z.SetString(s,10) }; if !newOk { return big.NewInt(0) }; return z }
 func splitCSV(s string) []string { arr := strings.Split(s, ","); out := make([]string,0,len(arr)); for _,x := range arr { x=strings.TrimSpace(x); if x!="" { out=append(out,x) } }; return out }
+// explorerSuffix is " <explorer tx link>" for printing after a hash; "" for a zero hash or unknown chain.
+func explorerSuffix(chainID *big.Int, h common.Hash) string { if chainID == nil || h == (common.Hash{}) { return "" }; if u := explorer.Tx(chainID.String(), h.Hex()); u != "" { return " " + u }; return "" }
 
 // parseCSVInts parses "a,b,c" into []int with defaults if empty/bad.
 func parseCSVInts(s string, def []int) []int {
@@ -129,4 +220,24 @@ func parseCSVInts(s string, def []int) []int {
 	}
 	if len(out) == 0 { return def }
 	return out
-}
\ No newline at end of file
+}
+// bloxrouteHeaders builds per-relay headers for BLOXROUTE_RELAY (API key OR ready Authorization).
+func bloxrouteHeaders() map[string]map[string]string {
+	extraHeaders := map[string]map[string]string{}
+	if v := getenv("BLOXROUTE_RELAY", ""); v != "" {
+		h := map[string]string{}
+		if k := getenv("BLOXROUTE_API_KEY", ""); k != "" {
+			// Classic path sets both headers for Cloud API
+			h["X-API-KEY"] = k
+			h["Authorization"] = k
+		}
+		if auth := getenv("BLOXROUTE_AUTH_HEADER", ""); auth != "" {
+			// Allow overriding with ready Authorization header
+			h["Authorization"] = auth
+		}
+		if len(h) > 0 {
+			extraHeaders[v] = h
+		}
+	}
+	return extraHeaders
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// transferHookNote checks token for ERC-777 / ERC-1363 transfer hooks. Without hooks it
+// returns "". With hooks it returns the risk note (riskgate.Action.TransferHook) and runs
+// the strict simulation of transfer(to, amount) from `from`; err is a failed simulation,
+// after which the transfer must not be sent.
+func transferHookNote(ctx context.Context, ec *ethclient.Client, rc *rpc.Client, token, from, to Address, amount *big.Int) (string, error) {
+	hooks, err := core.DetectTransferHooks(ctx, ec, token, from, to)
+	if err != nil || !hooks.Any() {
+		return "", nil // detection is best-effort: an RPC error is not a hook
+	}
+	note := hooks.Summary()
+	if rc == nil || amount == nil || amount.Sign() == 0 {
+		return note + " (strict simulation skipped)", nil
+	}
+	if _, err := core.SimulateTransferStrict(ctx, rc, token, from, to, amount); err != nil {
+		return note, fmt.Errorf("%s: %w", note, err)
+	}
+	return note + " (strict simulation OK)", nil
+}
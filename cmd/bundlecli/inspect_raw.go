@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/calldata"
+	"github.com/ligun0805/bundle-rescue/internal/eip7702"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+)
+
+// runInspectRaw implements `bundlecli inspect-raw [-send] 0x02f8...` ("-" or no argument =
+// stdin): decodes a raw signed tx of any type (incl. 0x04 SetCodeTx and its authorizations),
+// recovers the signers, checks on RPC_URL whether it can still land, and with -send
+// re-broadcasts it to RELAYS. For raw hex left in logs by a run that never landed.
+func runInspectRaw(args []string) int {
+	fs := flag.NewFlagSet("inspect-raw", flag.ExitOnError)
+	send := fs.Bool("send", false, "Re-broadcast the tx to RELAYS (eth_sendPrivateTransaction & co, see SendPrivate)")
+	offline := fs.Bool("offline", false, "Decode only: no RPC_URL state check")
+	assumeYes := fs.Bool("yes", false, "With -send: do not ask for confirmation")
+	_ = fs.Parse(args)
+
+	in := ""
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		in = strings.Join(fs.Args(), "")
+	} else {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "inspect-raw:", err)
+			return exitcode.Config
+		}
+		in = string(b)
+	}
+	raw, err := calldata.ParseHex(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "inspect-raw:", err)
+		return exitcode.Config
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		fmt.Fprintln(os.Stderr, "inspect-raw: not a raw signed tx:", err)
+		return exitcode.Config
+	}
+	if err := explorer.LoadEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, "inspect-raw:", err)
+		return exitcode.Config
+	}
+	printRawTx(tx)
+
+	if *offline {
+		if *send {
+			fmt.Fprintln(os.Stderr, "inspect-raw: -send needs the RPC check, drop -offline")
+			return exitcode.Config
+		}
+		return exitcode.OK
+	}
+	cfg := loadEnv()
+	ec, err := newEthClientWithTimeout(cfg.RPC)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "inspect-raw: dial:", err)
+		return exitcode.RPC
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	landable, err := checkRawTx(ctx, ec, tx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "inspect-raw: state check:", err)
+		return exitcode.RPC
+	}
+	if !*send {
+		return exitcode.OK
+	}
+	if !landable {
+		fmt.Println("  [send] not sent: the tx cannot land any more (see above)")
+		return exitcode.Failure
+	}
+	relays := splitCSV(cfg.RelaysCSV)
+	if len(relays) == 0 {
+		fmt.Fprintln(os.Stderr, "inspect-raw: RELAYS is empty")
+		return exitcode.Config
+	}
+	var authSigner *ecdsa.PrivateKey
+	if a := strings.TrimSpace(cfg.AuthPK); a != "" {
+		if authSigner, err = crypto.HexToECDSA(strings.TrimPrefix(a, "0x")); err != nil {
+			fmt.Fprintln(os.Stderr, "inspect-raw: FLASHBOTS_AUTH_PK:", err)
+			return exitcode.Config
+		}
+	}
+	if !*assumeYes && !yes(strings.ToLower(readLine(bufio.NewReader(os.Stdin), fmt.Sprintf("Отправить tx %s в %d relay(s)? [y/N]: ", tx.Hash().Hex(), len(relays))))) {
+		fmt.Println("  [send] cancelled")
+		return exitcode.OK
+	}
+	accepted := false
+	for _, rr := range eip7702.SendPrivate(context.Background(), hexutil.Encode(raw), relays, bloxrouteHeaders(), authSigner, nil) {
+		note := ""
+		switch {
+		case rr.Skipped:
+			note = " (skipped: already sent)"
+		case rr.AlreadyKnown:
+			note = " (already known)"
+		}
+		fmt.Printf("  relay=%s method=%s http=%d accepted=%v%s body=%s\n",
+			rr.RelayURL, rr.RequestMethod, rr.HTTPStatus, rr.Accepted, note, rr.ResponseBody)
+		accepted = accepted || rr.Accepted
+	}
+	if !accepted {
+		fmt.Println("  [send] no relay accepted")
+		printHint("  ", "no relay accepted")
+		return exitcode.Failure
+	}
+	fmt.Printf("[RESULT] re-broadcast%s\n", explorerSuffix(tx.ChainId(), tx.Hash()))
+	return exitcode.OK
+}
+
+// printRawTx prints every field of tx and the recovered sender/authorities.
+func printRawTx(tx *types.Transaction) {
+	names := map[uint8]string{types.LegacyTxType: "legacy", types.AccessListTxType: "access-list (EIP-2930)",
+		types.DynamicFeeTxType: "dynamic-fee (EIP-1559)", types.BlobTxType: "blob (EIP-4844)", types.SetCodeTxType: "set-code (EIP-7702)"}
+	fmt.Printf("  hash:      %s\n", tx.Hash().Hex())
+	fmt.Printf("  type:      0x%02x %s\n", tx.Type(), names[tx.Type()])
+	fmt.Printf("  chainId:   %s\n", tx.ChainId())
+	if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+		fmt.Printf("  from:      %s (recovered)\n", from.Hex())
+	} else {
+		fmt.Printf("  from:      cannot recover: %v\n", err)
+	}
+	fmt.Printf("  nonce:     %d\n", tx.Nonce())
+	if tx.To() != nil {
+		fmt.Printf("  to:        %s\n", tx.To().Hex())
+	} else {
+		fmt.Printf("  to:        (contract creation)\n")
+	}
+	fmt.Printf("  value:     %s ETH (%s wei)\n", formatEther(tx.Value()), tx.Value())
+	fmt.Printf("  gas:       %d\n", tx.Gas())
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		fmt.Printf("  gasPrice:  %s gwei\n", formatGwei(tx.GasPrice()))
+	} else {
+		fmt.Printf("  tip/cap:   %s / %s gwei\n", formatGwei(tx.GasTipCap()), formatGwei(tx.GasFeeCap()))
+	}
+	fmt.Printf("  max cost:  %s ETH (gas × cap + value)\n", formatEther(tx.Cost()))
+	for i, t := range tx.AccessList() {
+		fmt.Printf("  access[%d]: %s (%d slot(s))\n", i, t.Address.Hex(), len(t.StorageKeys))
+	}
+	for i, a := range tx.SetCodeAuthorizations() {
+		who := "cannot recover"
+		if auth, err := a.Authority(); err == nil {
+			who = auth.Hex()
+		}
+		fmt.Printf("  auth[%d]:   authority=%s delegate=%s nonce=%d chainId=%s\n", i, who, a.Address.Hex(), a.Nonce, a.ChainID.Dec())
+	}
+	if data := tx.Data(); len(data) == 0 {
+		fmt.Println("  data:      (empty)")
+	} else if c, err := calldata.Decode(data); c != nil {
+		fmt.Printf("  data:      %d byte(s)\n", len(data))
+		for _, l := range strings.Split(strings.TrimRight(c.String(), "\n"), "\n") {
+			fmt.Println("    " + l)
+		}
+		if err != nil {
+			fmt.Println("    decode:", err)
+		}
+	} else {
+		fmt.Printf("  data:      %s\n", hexutil.Encode(data))
+	}
+}
+
+// checkRawTx reports whether tx is mined, stale (nonce used) or still able to land, and why.
+func checkRawTx(ctx context.Context, ec *ethclient.Client, tx *types.Transaction) (landable bool, err error) {
+	chainID, err := ec.ChainID(ctx)
+	if err != nil {
+		return false, err
+	}
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(chainID) != 0 {
+		fmt.Printf("  [state] tx is for chain %s, RPC_URL is chain %s — skipped\n", tx.ChainId(), chainID)
+		return false, nil
+	}
+	if rc, err := ec.TransactionReceipt(ctx, tx.Hash()); err == nil && rc != nil {
+		st := "success"
+		if rc.Status != types.ReceiptStatusSuccessful {
+			st = "REVERTED"
+		}
+		fmt.Printf("  [state] already mined in block %s: %s%s\n", rc.BlockNumber, st, explorerSuffix(chainID, tx.Hash()))
+		return false, nil
+	} else if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return false, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return false, err
+	}
+	landable = true
+	nonce, err := ec.NonceAt(ctx, from, nil)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case nonce > tx.Nonce():
+		fmt.Printf("  [state] nonce %d of %s is already used (account nonce %d): this tx can never land\n", tx.Nonce(), from.Hex(), nonce)
+		landable = false
+	case nonce < tx.Nonce():
+		fmt.Printf("  [state] nonce gap: account nonce %d, tx nonce %d — needs the earlier tx(s) first\n", nonce, tx.Nonce())
+	default:
+		fmt.Printf("  [state] nonce %d matches the account: not mined yet\n", nonce)
+	}
+	if bal, err := ec.BalanceAt(ctx, from, nil); err == nil && bal.Cmp(tx.Cost()) < 0 {
+		fmt.Printf("  [state] %s has %s ETH, the tx may cost up to %s ETH\n", from.Hex(), formatEther(bal), formatEther(tx.Cost()))
+	}
+	if h, err := ec.HeaderByNumber(ctx, nil); err == nil && h.BaseFee != nil && tx.GasFeeCap().Cmp(h.BaseFee) < 0 {
+		fmt.Printf("  [state] fee cap %s gwei is below the current base fee %s gwei: it waits for the base fee to drop\n", formatGwei(tx.GasFeeCap()), formatGwei(h.BaseFee))
+	}
+	for i, a := range tx.SetCodeAuthorizations() {
+		auth, err := a.Authority()
+		if err != nil {
+			continue
+		}
+		n, err := ec.NonceAt(ctx, auth, nil)
+		if err != nil {
+			continue
+		}
+		// The sponsor's own nonce bump comes first when the authority is the sender.
+		want := n
+		if auth == from {
+			want = n + 1
+		}
+		if a.Nonce != want {
+			fmt.Printf("  [state] auth[%d]: nonce %d, authority %s expects %d — the delegation will be skipped (tx still valid)\n", i, a.Nonce, auth.Hex(), want)
+		}
+		if a.ChainID.Sign() != 0 && a.ChainID.ToBig().Cmp(chainID) != 0 {
+			fmt.Printf("  [state] auth[%d]: chainId %s does not match chain %s\n", i, a.ChainID.Dec(), chainID)
+		}
+	}
+	return landable, nil
+}
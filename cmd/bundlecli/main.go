@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
   "flag"
 	"fmt"
 	"math/big"
@@ -10,59 +11,189 @@ import (
   "os"
   "time"
 
-	"github.com/joho/godotenv"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/common"
   "github.com/ethereum/go-ethereum/rpc"
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/chaos"
+	"github.com/ligun0805/bundle-rescue/internal/features"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/keyref"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/profile"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+	"github.com/ligun0805/bundle-rescue/internal/runlock"
+	"github.com/ligun0805/bundle-rescue/internal/triage"
 )
 
-// newEthClientWithTimeout dials RPC with keep-alives and sane timeouts.
+// newEthClientWithTimeout dials RPC with keep-alives and sane timeouts (ws:// / wss:// too).
 func newEthClientWithTimeout(rpcURL string) (*ethclient.Client, error) {
 	transport := &http.Transport{ MaxIdleConns: 100, IdleConnTimeout: 90 * time.Second, DisableCompression: false }
-	httpClient := &http.Client{ Timeout: 30 * time.Second, Transport: transport }
-	rpcClient, err := rpc.DialHTTPWithClient(rpcURL, httpClient)
+	httpClient := &http.Client{ Timeout: 30 * time.Second, Transport: rpcmetrics.Transport(transport) }
+	rpcClient, err := rpcdial.Dial(context.Background(), rpcURL, httpClient)
 	if err != nil { return nil, err }
 	return ethclient.NewClient(rpcClient), nil
 }
 
 // main keeps high-level flow; details are extracted to small helpers (see *.go in this folder).
 func main() {
+	// --profile NAME is taken out before any flag set sees it (subcommands included).
+	profileName, args := profile.Extract(os.Args[1:])
+	os.Args = append(os.Args[:1], args...)
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		os.Exit(features.Command("bundlecli", os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rehearse" {
+		loadProfileEnv(profileName)
+		os.Exit(runRehearse(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "decode-calldata" {
+		os.Exit(runDecodeCalldata(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "inspect-raw" {
+		loadProfileEnv(profileName)
+		os.Exit(runInspectRaw(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sweep-eth" {
+		loadProfileEnv(profileName)
+		os.Exit(runSweepETH(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dispose" {
+		loadProfileEnv(profileName)
+		os.Exit(runDispose(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "operators" {
+		loadProfileEnv(profileName)
+		os.Exit(runOperators(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "submissions" {
+		loadProfileEnv(profileName)
+		os.Exit(runSubmissions(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "discover-selectors" {
+		loadProfileEnv(profileName)
+		os.Exit(runDiscoverSelectors(os.Args[2:]))
+	}
 	var pairsPath string
-	flag.StringVar(&pairsPath, "pairs", "", "Path to CSV for batch EIP-7702 mode (token,privateKey,from[,reason])")
-	flag.Parse()	
+	flag.StringVar(&pairsPath, "pairs", "", "Path to CSV for batch EIP-7702 mode (token,privateKey,from[,reason]); \"-\" = stdin")
+	var batchOpts batchOptions
+	flag.BoolVar(&batchOpts.simulateOnly, "simulate-only", false, "Batch mode: build, sign and simulate (eth_callBundle) every pair, never send")
+	var keysPath string
+	flag.StringVar(&keysPath, "keys", os.Getenv("KEYS_FILE"), "Batch mode: file with raw private keys to re-join fingerprinted (kfp:...) CSV rows; secret from KEYREF_SECRET")
+	var triagePath string
+	flag.StringVar(&triagePath, "triage", os.Getenv("TRIAGE_FILE"), "Batch mode: triage sidecar CSV (batchcli -mark); only pairs marked approved are sent")
+	var allowConcurrent bool
+	flag.BoolVar(&allowConcurrent, "allow-concurrent", os.Getenv("ALLOW_CONCURRENT_SAFE") == "1", "Run even when another GUI/CLI run holds the SAFE run lock (nonces may collide)")
+	flag.String("profile", os.Getenv("PROFILE"), "Named profile: load profiles/<name>/.env instead of ./.env (also PROFILE)")
+	flag.BoolVar(&noPrompt, "no-prompt", os.Getenv("NO_PROMPT") == "1", "Exit without waiting for Enter (CI/automation); see exit codes in README")
+	var chaosSpec string
+	flag.StringVar(&chaosSpec, "chaos", os.Getenv("CHAOS"), "Dev builds (-tags chaos): inject RPC timeouts/429/relay 5xx/nonce races, e.g. \"timeout=0.05,429=0.1\" or \"1\"")
+	var deferOpts deferOptions
+	flag.Float64Var(&deferOpts.maxGwei, "defer-max-basefee", atof(os.Getenv("DEFER_MAX_BASEFEE_GWEI"), 0), "Batch mode: defer the start until the next base fee is at most this many gwei (0 = off)")
+	flag.Float64Var(&deferOpts.maxRisePct, "defer-max-rise", atof(os.Getenv("DEFER_MAX_RISE_PCT"), 0), "Batch mode: defer the start until the base fee is at most this % above its recent median (0 = off)")
+	flag.DurationVar(&deferOpts.maxWait, "defer-max-wait", durationEnv("DEFER_MAX_WAIT", 3*time.Hour), "Batch mode: give up a deferred start after this long (exit 6; 0 = wait as long as it takes)")
+	var privacyDisplay bool
+	flag.BoolVar(&privacyDisplay, "privacy", os.Getenv("PRIVACY_DISPLAY") == "1", "Show balances/amounts on the console as magnitude buckets (shared screens); logs/reports keep full values")
+	flag.Parse()
+	privacy.Enable(privacyDisplay)
+	if chaosSpec != "" {
+		c, err := chaos.Enable(chaosSpec)
+		if err != nil { die(err.Error()) }
+		fmt.Println("[chaos] ENABLED:", c)
+	}	
   
-  _ = godotenv.Load()
-	_ = godotenv.Overload(".env.local")
+	loadProfileEnv(profileName)
+	if err := explorer.LoadEnv(); err != nil { die(err.Error()) }
+	if err := core.LoadSelectorEnv(activeProfile.Path(core.DefaultSelectorFile)); err != nil { die(err.Error()) }
+	if err := core.LoadBuilderEnv(activeProfile.Path(core.DefaultBuilderFile)); err != nil { die(err.Error()) }
 
 	ctx := context.Background()
 	cfg := loadEnv()
+	submissions := startSubmissionLog(cfg, "bundlecli")
+	defer submissions.Stop()
+	startMetrics(cfg)
 
 	ec, err := newEthClientWithTimeout(cfg.RPC)
-	must(err, "dial RPC")
-	// Best-effort RPC client for eth_call stateOverrides (7702 preflight)
-	rc, _ := rpc.DialContext(ctx, cfg.RPC)
+	if err != nil { dieCode(exitcode.RPC, "dial RPC: "+err.Error()) }
+	// Best-effort RPC client for eth_call stateOverrides (7702 preflight); WebSocket shares ec's connection
+	rc := ec.Client()
+	if !rpcdial.IsWS(cfg.RPC) {
+		rc, _ = rpc.DialOptions(ctx, cfg.RPC, rpc.WithHTTPClient(&http.Client{Transport: rpcmetrics.Transport(nil)}))
+	}
 
 	var chainID *big.Int
 	if strings.TrimSpace(cfg.ChainIDStr) != "" {
 		chainID = mustBig(cfg.ChainIDStr)
 	} else {
-		chainID, err = ec.ChainID(ctx); must(err, "chain id")
+		chainID, err = ec.ChainID(ctx)
+		if err != nil { dieCode(exitcode.RPC, "chain id: "+err.Error()) }
 	}
+	if err := activeProfile.CheckChain(chainID); err != nil { die(err.Error()) }
 
 	if strings.TrimSpace(cfg.SafePK) == "" { die("SAFE_PRIVATE_KEY is empty in env") }
 	safeAddr := mustAddrFromPK(cfg.SafePK)
     safeBal, _ := ec.BalanceAt(ctx, safeAddr, nil)
 
-    // --- Batch mode (EIP-7702 only) BEFORE reading FROM_PK ---
-    // Priority: --pairs flag > PAIRS_CSV env > interactive.
+    // One sending run per SAFE: GUI and CLI runs on the same key collide on nonces.
     batchPath := strings.TrimSpace(pairsPath)
     if batchPath == "" {
         batchPath = strings.TrimSpace(os.Getenv("PAIRS_CSV"))
     }
+    var lock *runlock.Lock // stale locks of crashed/exited runs are taken over by pid
+    if !(batchPath != "" && batchOpts.simulateOnly) {
+        lock, err = runlock.Acquire(safeAddr, "bundlecli")
+        var held *runlock.HeldError
+        switch {
+        case errors.As(err, &held) && allowConcurrent:
+            fmt.Println("  [lock] WARNING:", held.Error(), "— continuing (--allow-concurrent)")
+        case errors.As(err, &held):
+            dieCode(exitcode.Locked, held.Error()+"; wait for it to finish or pass --allow-concurrent")
+        case err != nil:
+            fmt.Println("  [lock] run lock unavailable:", err)
+        }
+    }
+    defer lock.Release()
+
+    // --- Batch mode (EIP-7702 only) BEFORE reading FROM_PK ---
+    // Priority: --pairs flag > PAIRS_CSV env > interactive.
     if batchPath != "" {
-        if err := runBatchPairsFromCSV(ctx, ec, cfg, chainID, safeAddr, batchPath); err != nil {
-            fmt.Println("  [batch] error:", err)
+        if strings.TrimSpace(keysPath) != "" {
+            secret := secretEnv("KEYREF_SECRET", "")
+            if secret == "" { die("KEYREF_SECRET is empty (needed for --keys)") }
+            idx, err := keyref.LoadIndex(keysPath, []byte(secret))
+            must(err, "load --keys")
+            fmt.Printf("  [keys] %d key(s) indexed from %s\n", len(idx), keysPath)
+            batchOpts.keys = idx
+        }
+        if strings.TrimSpace(triagePath) != "" {
+            t, err := triage.Load(triagePath)
+            must(err, "load --triage")
+            c := t.Counts()
+            fmt.Printf("  [triage] %s: %d approved, %d review, %d declined — only approved pairs are sent\n", triagePath, c[triage.Approved], c[triage.Review], c[triage.Declined])
+            batchOpts.triage = t
+        }
+        if !batchOpts.simulateOnly {
+            if err := congestionGate(ctx, ec, cfg, deferOpts); err != nil {
+                lock.Release()
+                dieCode(exitcode.Of(err), "[gas] "+err.Error())
+            }
+        }
+        err := runBatchPairsFromCSV(ctx, ec, cfg, chainID, safeAddr, batchPath, batchOpts)
+        for _, l := range rpcmetrics.Report(cfg.RPC) { fmt.Println("  " + l) }
+        for _, l := range stagetime.Report() { fmt.Println("  " + l) }
+        if l := chaos.Summary(); l != "" { fmt.Println("  " + l) }
+        printSubmissionLog(submissions)
+        switch code := exitcode.Of(err); code {
+        case exitcode.OK:
+        case exitcode.Partial, exitcode.Budget:
+            fmt.Println("  [batch]", err)
+            lock.Release()
+            os.Exit(code)
+        default:
+            dieCode(code, "[batch] "+err.Error())
         }
         return
     }
@@ -150,6 +281,14 @@ func main() {
 			}
 			fmt.Println(line)
 		}
+		// ERC-777 / ERC-1363 hooks: risk note, and the strict simulation must pass too
+		if preOK {
+			if note, err := transferHookNote(ctx, ec, rc, tokenAddr, fromAddr, safeAddr, victimBal); err != nil {
+				preOK, preWhy = false, "transfer hooks: "+err.Error()
+			} else if note != "" {
+				fmt.Println("  [!] Transfer hooks:", note)
+			}
+		}
 		// Summary
 		fmt.Println("  --- Результат проверок ---")
 		if guardsOK { fmt.Println("   • Guards   : OK") } else { fmt.Println("   • Guards   : FAIL —", guardsWhy) }
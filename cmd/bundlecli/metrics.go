@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// startMetrics serves the bundlecore metrics on METRICS_ADDR for the rest of the process
+// (nil when unset or the address cannot be bound; the run goes on without them).
+func startMetrics(cfg EnvConfig) *http.Server {
+	if cfg.MetricsAddr == "" {
+		return nil
+	}
+	srv, err := core.ServeMetrics(cfg.MetricsAddr)
+	if err != nil {
+		fmt.Println("  [metrics] unavailable:", err)
+		return nil
+	}
+	fmt.Printf("  [metrics] Prometheus metrics on http://%s/metrics\n", cfg.MetricsAddr)
+	return srv
+}
@@ -15,6 +15,14 @@ func printNetworkState(ctx context.Context, ec *ethclient.Client, cfg EnvConfig,
 	var baseFee *big.Int = big.NewInt(0)
 	if h != nil && h.BaseFee != nil { baseFee = new(big.Int).Set(h.BaseFee) }
 	fmt.Printf("[net] baseFee(now): %s gwei\n", formatGwei(baseFee))
+	if h != nil && len(cfg.HeadCheckRPCs) > 0 {
+		hv := core.CheckHead(ctx, h.Number.Uint64(), cfg.HeadCheckRPCs)
+		if lag := hv.Lag(); lag > uint64(cfg.HeadLagWarn) {
+			fmt.Printf("[net] WARNING: RPC head %d is %d block(s) behind %s (%d) — stale provider, target blocks will use the fresher head\n", hv.Primary, lag, hv.Source, hv.Best)
+		} else {
+			fmt.Printf("[net] head %d (lag %d vs %d secondary endpoint(s))\n", hv.Primary, lag, len(cfg.HeadCheckRPCs))
+		}
+	}
 
 	stats, err := core.FeeHistoryStats(ctx, rpc, cfg.NetBlocks, cfg.NetPcts)
 	if err != nil {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/operators"
+)
+
+// runOperators implements `bundlecli operators add|remove|list [NAME]`: the local operator
+// accounts the GUI logs in against with OPERATOR_AUTH=password. The password is asked
+// twice on a terminal, or read as one line from stdin otherwise.
+func runOperators(args []string) int {
+	fs := flag.NewFlagSet("operators", flag.ExitOnError)
+	file := fs.String("file", getenv("OPERATORS_FILE", activeProfile.Path(operators.DefaultFile)), "Operator accounts file")
+	_ = fs.Parse(args)
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: bundlecli operators add NAME | remove NAME | list [-file operators.json]")
+		return exitcode.Config
+	}
+	store, err := operators.Load(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "operators:", err)
+		return exitcode.Config
+	}
+	cmd := rest[0]
+	if cmd == "list" {
+		for _, n := range store.Names() {
+			fmt.Println(n)
+		}
+		return exitcode.OK
+	}
+	if (cmd != "add" && cmd != "remove") || len(rest) != 2 {
+		fmt.Fprintf(os.Stderr, "operators %s: expected add NAME, remove NAME or list\n", strings.Join(rest, " "))
+		return exitcode.Config
+	}
+	name := rest[1]
+	if cmd == "remove" {
+		err = store.Remove(name)
+	} else {
+		var pass string
+		if pass, err = readNewPassword(); err == nil {
+			err = store.Add(name, pass)
+		}
+	}
+	if err == nil {
+		err = store.Save()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "operators:", err)
+		return exitcode.Config
+	}
+	fmt.Printf("operator %s: %s => %s\n", name, map[string]string{"add": "added", "remove": "removed"}[cmd], *file)
+	return exitcode.OK
+}
+
+func readNewPassword() (string, error) {
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("password: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+	p1 := readPassword("Password: ")
+	if p1 != readPassword("Repeat: ") {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return p1, nil
+}
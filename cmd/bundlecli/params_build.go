@@ -13,6 +13,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
 )
 
 // runInteractiveLoop keeps the original REPL-style flow but split into smaller steps.
@@ -29,7 +30,7 @@ func runInteractiveLoop(ctx context.Context, ec *ethclient.Client, chainID *big.
 		fromAddr := mustAddrFromPK(fromPK)
 		if !singleTokenMode {
 			fromBal, _ := ec.BalanceAt(ctx, fromAddr, nil)
-			fmt.Println("  from:", fromAddr.Hex(), " | ETH balance:", formatEther(fromBal))
+			fmt.Println("  from:", fromAddr.Hex(), " | ETH balance:", privacy.Amount(formatEther(fromBal)))
 			_ = printPendingStateForAddress(cfg.RPC, strings.ToLower(fromAddr.Hex()))
 			if nLatest, err1 := ec.NonceAt(ctx, fromAddr, nil); err1 == nil {
 				if nPending, err2 := ec.PendingNonceAt(ctx, fromAddr); err2 == nil {
@@ -65,7 +66,7 @@ func runInteractiveLoop(ctx context.Context, ec *ethclient.Client, chainID *big.
 		bal, err := fetchTokenBalance(ctx, ec, tokenAddr, fromAddr)
 		if err != nil { fmt.Println("  [!] Ошибка чтения баланса токена:", err); continue }
 		amountWei := new(big.Int).Set(bal)
-		fmt.Println("  Decimals:", dec, " | TokenBalance(from):", formatTokensFromWei(bal, dec), " -> amount=ALL")
+		fmt.Println("  Decimals:", dec, " | TokenBalance(from):", privacy.Amount(formatTokensFromWei(bal, dec)), " -> amount=ALL")
 		toAddr := safeAddr
 		fmt.Println("  To:", toAddr.Hex(), "(SAFE)")
 
@@ -79,12 +80,12 @@ func runInteractiveLoop(ctx context.Context, ec *ethclient.Client, chainID *big.
 			maxTxWei := new(big.Int).Div(new(big.Int).Mul(ts, big.NewInt(int64(txBps))), big.NewInt(10_000))
 			maxWalWei := new(big.Int).Div(new(big.Int).Mul(ts, big.NewInt(int64(walBps))), big.NewInt(10_000))
 			toBal, _ := fetchTokenBalance(ctx, ec, tokenAddr, toAddr)
-			fmt.Printf("  Limits: maxTx=%s (%d bps), maxWallet=%s (%d bps)\n", formatTokensFromWei(maxTxWei, dec), txBps, formatTokensFromWei(maxWalWei, dec), walBps)
+			fmt.Printf("  Limits: maxTx=%s (%d bps), maxWallet=%s (%d bps)\n", privacy.Amount(formatTokensFromWei(maxTxWei, dec)), txBps, privacy.Amount(formatTokensFromWei(maxWalWei, dec)), walBps)
 			if amountWei.Cmp(maxTxWei) > 0 {
-				fmt.Printf("  [WARN] amount > maxTx (%s > %s)\n", formatTokensFromWei(amountWei, dec), formatTokensFromWei(maxTxWei, dec))
+				fmt.Printf("  [WARN] amount > maxTx (%s > %s)\n", privacy.Amount(formatTokensFromWei(amountWei, dec)), privacy.Amount(formatTokensFromWei(maxTxWei, dec)))
 			}
 			if new(big.Int).Add(toBal, amountWei).Cmp(maxWalWei) > 0 {
-				fmt.Printf("  [WARN] toBalance+amount > maxWallet (%s + %s > %s)\n", formatTokensFromWei(toBal, dec), formatTokensFromWei(amountWei, dec), formatTokensFromWei(maxWalWei, dec))
+				fmt.Printf("  [WARN] toBalance+amount > maxWallet (%s + %s > %s)\n", privacy.Amount(formatTokensFromWei(toBal, dec)), privacy.Amount(formatTokensFromWei(amountWei, dec)), privacy.Amount(formatTokensFromWei(maxWalWei, dec)))
 			}
 		} else {
 			fmt.Println("  Limits: unknown (no maxTxBPS/maxWalletBPS getters)")
@@ -176,11 +177,13 @@ func runInteractiveLoop(ctx context.Context, ec *ethclient.Client, chainID *big.
 				SafePKHex: cfg.SafePK, FromPKHex: fromPK,
 				Blocks: cfg.Blocks, TipGweiBase: cfg.TipGwei, TipMul: cfg.TipMul, BaseMul: cfg.BaseMul, BufferPct: cfg.BufferPct,
 				TipMode: tipMode, TipWindow: tipWindow, TipPercentile: tipPercentile,
-				BribeWei: bribeWei, BribeGasLimit: bribeGasLimit, ExtraHeaders: extraHeaders,
+				BribeWei: bribeWei, BribeGasLimit: bribeGasLimit, ExtraHeaders: extraHeaders, CompeteBumpPct: cfg.CompeteBumpPct, CompeteMaxMul: cfg.CompeteMaxMul,
+				Route: cfg.ClassicRoute, SellMinOutWei: cfg.SellMinOutWei, SellSlippageBps: cfg.SellSlippageBps, AccessList: cfg.AccessList,
+				HeadCheckRPCs: cfg.HeadCheckRPCs, HeadLagWarn: cfg.HeadLagWarn,
 				Builders: cfg.Builders, ReplacementUUID: replUUID, MinTimestamp: cfg.MinTs, MaxTimestamp: cfg.MaxTs,
-				BeaverAllowBuilderNetRefunds: &cfg.BeaverAllow, BeaverRefundRecipientHex: cfg.BeaverRefundTo,
+				BeaverAllowBuilderNetRefunds: &cfg.BeaverAllow, BeaverRefundRecipientHex: cfg.BeaverRefundTo, Share: cfg.Share,
 				Verbose: false, SimulateOnly: false, SkipIfPaused: true,
-				Logf: func(f string, a ...any){ fmt.Printf(f+"\n", a...) },
+				Logf: func(f string, a ...any){ fmt.Println(privacy.Line(fmt.Sprintf(f, a...))) },
 				OnSimResult: func(relay, raw string, ok bool, err string){
 					state := "OK"; if !ok { state = "FAIL" }
 					if err != "" { err = friendlySimErr(err) }
@@ -189,8 +192,10 @@ func runInteractiveLoop(ctx context.Context, ec *ethclient.Client, chainID *big.
 			}
 			if res, err := core.Run(ctx, ec, params); err != nil {
 				fmt.Println("[ERROR run]", err)
+				printHint("  ", err.Error())
 			} else {
-				fmt.Println("[RESULT]", res.Reason, "| included:", res.Included)
+				fmt.Printf("[RESULT] %s | included: %v%s\n", res.Reason, res.Included, explorerSuffix(chainID, res.TxHash))
+				printResultHint("  ", res)
 			}
 		}
         again := strings.ToLower(readLine(reader, "Перейти к добавлению новой пары? [y/N]: "))
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/mocktoken"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+)
+
+// rehearsal is one mock token scenario and what the pipeline is expected to do with it.
+type rehearsal struct {
+	name      string
+	cfg       mocktoken.Config
+	rescuable bool // true: tokens must reach SAFE; false: pipeline must refuse before sending
+}
+
+// rehearsals builds the scenario set; victim is the compromised wallet of this run.
+func rehearsals(victim common.Address, feeBps uint16) []rehearsal {
+	return []rehearsal{
+		{"plain", mocktoken.Config{Name: "Rehearsal Plain", Symbol: "RPLAIN", Decimals: 18}, true},
+		{"fee", mocktoken.Config{Name: "Rehearsal Fee", Symbol: "RFEE", Decimals: 18, FeeBps: feeBps}, true},
+		{"pause", mocktoken.Config{Name: "Rehearsal Pause", Symbol: "RPAUSE", Decimals: 18, Paused: true}, false},
+		{"blacklist", mocktoken.Config{Name: "Rehearsal Blacklist", Symbol: "RBL", Decimals: 18, Blacklist: []common.Address{victim}}, false},
+		{"hidden-blacklist", mocktoken.Config{Name: "Rehearsal Hidden BL", Symbol: "RHBL", Decimals: 18, Blacklist: []common.Address{victim}, HideViews: true}, false},
+		// decimals edge cases: amounts are parsed, preflighted and moved in the token's own units
+		{"zero-decimals", mocktoken.Config{Name: "Rehearsal Zero Dec", Symbol: "RZERO", Decimals: 0}, true},
+		{"high-decimals", mocktoken.Config{Name: "Rehearsal High Dec", Symbol: "RHIGH", Decimals: 24}, true},
+	}
+}
+
+// runRehearse implements `bundlecli rehearse`: installs mock tokens on a local fork
+// (anvil --fork-url ...) and runs restrictions → preflight → core.Run against each,
+// mining bundles directly on the dev node. Fresh throwaway keys every run.
+func runRehearse(args []string) int {
+	fs := flag.NewFlagSet("rehearse", flag.ExitOnError)
+	rpcURL := fs.String("rpc", getenv("REHEARSE_RPC", "http://127.0.0.1:8545"), "Dev node RPC (anvil/hardhat; must allow anvil_* methods)")
+	only := fs.String("scenarios", "", "Comma-separated subset: plain,fee,pause,blacklist,hidden-blacklist,zero-decimals,high-decimals (empty = all)")
+	feeBps := fs.Uint("fee-bps", 500, "Fee-on-transfer for the \"fee\" token, basis points")
+	amount := fs.String("amount", "1000", "Victim token balance per mock (whole tokens, in each mock's decimals)")
+	_ = fs.Parse(args)
+
+	if *feeBps > 10000 {
+		fmt.Fprintln(os.Stderr, "rehearse: -fee-bps must be <= 10000")
+		return exitcode.Config
+	}
+	// whole tokens must fit every scenario, including the 0-decimals one
+	if v, err := toWeiFromTokens(*amount, 0); err != nil || v.Sign() <= 0 {
+		fmt.Fprintln(os.Stderr, "rehearse: bad -amount (whole tokens > 0):", *amount)
+		return exitcode.Config
+	}
+
+	ctx := context.Background()
+	rc, err := rpc.DialContext(ctx, *rpcURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rehearse: dial:", err)
+		return exitcode.RPC
+	}
+	defer rc.Close()
+	ec, err := newEthClientWithTimeout(*rpcURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rehearse: dial:", err)
+		return exitcode.RPC
+	}
+	chainID, err := ec.ChainID(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rehearse: chain id:", err)
+		return exitcode.RPC
+	}
+
+	safeKey, victimKey, authKey := mustKey(), mustKey(), mustKey()
+	safe := crypto.PubkeyToAddress(safeKey.PublicKey)
+	victim := crypto.PubkeyToAddress(victimKey.PublicKey)
+	if err := mocktoken.SetBalance(ctx, rc, safe, new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))); err != nil {
+		fmt.Fprintln(os.Stderr, "rehearse: not a dev node (anvil_setBalance failed):", err)
+		return exitcode.RPC
+	}
+	fmt.Printf("[rehearse] rpc=%s chainId=%s SAFE=%s victim=%s\n", *rpcURL, chainID, safe.Hex(), victim.Hex())
+	defer func() {
+		for _, l := range rpcmetrics.Report(*rpcURL) {
+			fmt.Println(l)
+		}
+		for _, l := range stagetime.Report() {
+			fmt.Println(l)
+		}
+	}()
+
+	want := map[string]bool{}
+	for _, s := range strings.Split(*only, ",") {
+		if s = strings.TrimSpace(strings.ToLower(s)); s != "" {
+			want[s] = true
+		}
+	}
+
+	failed := 0
+	for _, r := range rehearsals(victim, uint16(*feeBps)) {
+		if len(want) > 0 && !want[r.name] {
+			continue
+		}
+		token := mocktoken.Address(r.name)
+		dec := int(r.cfg.Decimals)
+		amountWei, _ := toWeiFromTokens(*amount, dec)
+		if err := mocktoken.Install(ctx, rc, token, r.cfg, map[common.Address]*big.Int{victim: amountWei}); err != nil {
+			fmt.Fprintln(os.Stderr, "rehearse:", r.name+":", err)
+			return exitcode.RPC
+		}
+		got, detail := rehearseOne(ctx, ec, chainID, *rpcURL, token, victim, safe, dec, amountWei, safeKey, victimKey, authKey)
+		expected := new(big.Int)
+		if r.rescuable {
+			expected.Mul(amountWei, big.NewInt(int64(10000-r.cfg.FeeBps)))
+			expected.Div(expected, big.NewInt(10000))
+		}
+		verdict := "PASS"
+		if got.Cmp(expected) != 0 {
+			verdict = "FAIL"
+			failed++
+		}
+		fmt.Printf("[rehearse] %-16s %s token=%s moved=%s expected=%s — %s\n",
+			r.name, verdict, token.Hex(), formatTokensFromWei(got, dec), formatTokensFromWei(expected, dec), detail)
+	}
+	if failed > 0 {
+		fmt.Printf("[rehearse] %d scenario(s) FAILED\n", failed)
+		return exitcode.Failure
+	}
+	fmt.Println("[rehearse] all scenarios passed")
+	return exitcode.OK
+}
+
+// rehearseOne runs the same gates as a real rescue and returns what reached SAFE.
+func rehearseOne(ctx context.Context, ec *ethclient.Client, chainID *big.Int, rpcURL string, token, victim, safe common.Address, decimals int, amountWei *big.Int, safeKey, victimKey, authKey *ecdsa.PrivateKey) (*big.Int, string) {
+	zero := big.NewInt(0)
+	if dec, err := fetchTokenDecimals(ctx, ec, token); err != nil || dec != decimals {
+		return zero, fmt.Sprintf("decimals() read back as %d (err=%v), want %d", dec, err, decimals)
+	}
+	if back, err := toWeiFromTokens(formatTokensFromWei(amountWei, decimals), decimals); err != nil || back.Cmp(amountWei) != 0 {
+		return zero, fmt.Sprintf("amount %s does not round-trip at %d decimals", amountWei, decimals)
+	}
+	if restr, err := core.CheckRestrictions(ctx, ec, token, victim, safe); err == nil && restr.Blocked() {
+		return zero, "stopped at restrictions: " + restr.Summary()
+	}
+	if ok, why, err := core.PreflightTransfer(ctx, ec, token, victim, safe, amountWei); err != nil || !ok {
+		if err != nil {
+			why = err.Error()
+		}
+		return zero, "stopped at preflight: " + why
+	}
+	before, _ := fetchTokenBalance(ctx, ec, token, safe)
+	res, err := core.Run(ctx, ec, core.Params{
+		RPC: rpcURL, ChainID: chainID, AuthPrivHex: keyHex(authKey), LocalFork: true,
+		Token: token, From: victim, To: safe, AmountWei: amountWei,
+		SafePKHex: keyHex(safeKey), FromPKHex: keyHex(victimKey),
+		Blocks: 1, TipGweiBase: 1,
+		Logf: func(f string, a ...any) { fmt.Printf("    "+f+"\n", a...) },
+	})
+	if err != nil {
+		return zero, "run error: " + err.Error()
+	}
+	after, _ := fetchTokenBalance(ctx, ec, token, safe)
+	if before == nil || after == nil {
+		return zero, "run: " + res.Reason + " (SAFE balance unreadable)"
+	}
+	return new(big.Int).Sub(after, before), "run: " + res.Reason
+}
+
+func mustKey() *ecdsa.PrivateKey {
+	k, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func keyHex(k *ecdsa.PrivateKey) string { return fmt.Sprintf("%x", crypto.FromECDSA(k)) }
@@ -3,25 +3,23 @@ package main
 import (
 	"bufio"
 	"context"
-	"crypto/ecdsa"
-	"encoding/csv"
 	"fmt"
 	"math/big"
-	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
 	eip7702 "github.com/ligun0805/bundle-rescue/internal/eip7702"
+	"github.com/ligun0805/bundle-rescue/internal/bribe"
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/riskgate"
 )
 
 // runRescue7702 collects minimal inputs and sends a single sponsored EIP-7702 sweep ERC20 tx.
@@ -48,7 +46,7 @@ func runRescue7702(ctx context.Context, ec *ethclient.Client, chainID *big.Int,
 		for _, t := range tokenAddrs {
 			dec, _ := fetchTokenDecimals(ctx, ec, t)
 			bal, _ := fetchTokenBalance(ctx, ec, t, compromisedAddr)
-			fmt.Println("  ", t.Hex(), "dec:", dec, "balance:", formatTokensFromWei(bal, dec))
+			fmt.Println("  ", t.Hex(), "dec:", dec, "balance:", privacy.Amount(formatTokensFromWei(bal, dec)))
 		}
 	}
 
@@ -68,6 +66,15 @@ func runRescue7702(ctx context.Context, ec *ethclient.Client, chainID *big.Int,
 		return fmt.Errorf("bad DELEGATE_ADDRESS in .env")
 	}
 	delegate := common.HexToAddress(cfg.DelegateHex)
+	// This flow calls sweepERC20: refuse a delegate whose bytecode does not implement it.
+	caps, err := eip7702.ProbeDelegate(ctx, ec, delegate)
+	if err != nil {
+		return err
+	}
+	fmt.Println("  [*] Delegate capabilities:", caps.Matrix())
+	if !caps.Supports("sweepERC20") {
+		return fmt.Errorf("delegate %s does not implement sweepERC20 (needed here); use --pairs (sweepToken/sellToETH_V2) or another DELEGATE_ADDRESS", delegate.Hex())
+	}
 	
     // 3.1) Token guard checks (single-token flow): bots/limits
     guardsOK, guardsWhy := true, ""
@@ -149,7 +156,35 @@ func runRescue7702(ctx context.Context, ec *ethclient.Client, chainID *big.Int,
 		return fmt.Errorf("token balance is zero")
 	}
 	// continue with EIP-7702 flow below
-	
+
+	// 3.4) Confirmation gate (RISK_*): new token / high value ask once, foreign recipient /
+	// unknown delegate / public fallback want the typed phrase.
+	risk, err := riskPolicy(cfg, chainID)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	var assessed []riskgate.Assessment
+	for _, t := range tokenAddrs {
+		dec, _ := fetchTokenDecimals(ctx, ec, t)
+		sym, _ := fetchTokenSymbol(ctx, ec, t)
+		bal, _ := fetchTokenBalance(ctx, ec, t, compromisedAddr)
+		hookNote, err := transferHookNote(ctx, ec, ec.Client(), t, compromisedAddr, recipient, bal)
+		if err != nil {
+			return fmt.Errorf("transfer hooks of %s: %w", t.Hex(), err)
+		}
+		if hookNote != "" {
+			fmt.Println("  [!] Transfer hooks:", hookNote)
+		}
+		assessed = append(assessed, risk.Assess(riskgate.Action{
+			Token: t, Symbol: sym, Decimals: dec, Amount: bal,
+			Recipient: recipient, Safe: safeAddr, Delegate: delegate, PublicFallback: publicFallback(cfg),
+			TransferHook: hookNote,
+		}))
+	}
+	if err := risk.Confirm(reader, os.Stdout, riskgate.Merge(assessed...)); err != nil {
+		return fmt.Errorf("aborted by confirmation gate: %w", err)
+	}
+
 
 
 	// 4) Auth nonce and count
@@ -210,9 +245,9 @@ func runRescue7702(ctx context.Context, ec *ethclient.Client, chainID *big.Int,
 	fmt.Println("  [*] Отправляю приватную 7702-транзакцию…")
 	out, err := eip7702.ExecuteRescue(ctx, ec, req)
 	if err != nil { return err }
-	fmt.Println("  tx:", out.TxHash.Hex())
+	fmt.Println("  tx:", out.TxHash.Hex()+explorerSuffix(chainID, out.TxHash))
 	for _, a := range out.RelayAttempts {
-		fmt.Printf("    [%s] %s -> %d accepted=%v\n", a.RelayURL, a.RequestMethod, a.HTTPStatus, a.Accepted)
+		fmt.Printf("    [%s] %s -> %d accepted=%v known=%v\n", a.RelayURL, a.RequestMethod, a.HTTPStatus, a.Accepted, a.AlreadyKnown)
 		if strings.TrimSpace(a.ResponseBody) != "" {
 			fmt.Println("      resp:", a.ResponseBody)
 		}
@@ -220,255 +255,15 @@ func runRescue7702(ctx context.Context, ec *ethclient.Client, chainID *big.Int,
 	return nil
 }
 
-// --------------------
-// Batch CSV processing (moved here to avoid creating a new file)
-// --------------------
-
-// runBatchPairsFromCSV runs non-interactive EIP-7702 rescue for each CSV row.
-// CSV format: token,privateKey,from[,reason]
-func runBatchPairsFromCSV(
-	ctx context.Context,
-	ec *ethclient.Client,
-	cfg EnvConfig,
-	chainID *big.Int,
-	sponsorAddr common.Address,
-	csvPath string,
-) error {
-	csvPath = strings.TrimSpace(csvPath)
-	if csvPath == "" {
-		return fmt.Errorf("empty CSV path")
-	}
-	f, err := os.Open(csvPath)
-	if err != nil {
-		return fmt.Errorf("open CSV: %w", err)
-	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.FieldsPerRecord = -1
-	rows, err := r.ReadAll()
-	if err != nil {
-		return fmt.Errorf("parse CSV: %w", err)
-	}
-	if len(rows) == 0 {
-		return fmt.Errorf("CSV is empty")
-	}
-
-	// Logging
-	_ = os.MkdirAll("logs", 0o755)
-	logPath := filepath.Join("logs", fmt.Sprintf("bundlecli_batch_%s.log", time.Now().Format("20060102_150405")))
-	lf, err := os.Create(logPath)
-	if err != nil {
-		return fmt.Errorf("create log: %w", err)
-	}
-	defer lf.Close()
-	logw := bufio.NewWriter(lf)
-	defer logw.Flush()
-	fmt.Fprintf(logw, "# batch started at %s\n", time.Now().Format(time.RFC3339))
-
-	// RPC for 7702 preflight
-	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{MaxIdleConns: 100, IdleConnTimeout: 90 * time.Second}}
-	rc, err := rpc.DialHTTPWithClient(cfg.RPC, httpClient)
-	if err != nil {
-		return err
-	}
-	defer rc.Close()
-
-	// Minimal ABI for delegate calls
-	const delegateABI = `[
-	  {"type":"function","stateMutability":"nonpayable","name":"sweepToken",
-	   "inputs":[{"name":"token","type":"address"},{"name":"recipient","type":"address"}],"outputs":[]},
-	  {"type":"function","stateMutability":"nonpayable","name":"sellToETH_V2",
-	   "inputs":[
-	     {"name":"tokenIn","type":"address"},
-	     {"name":"amountIn","type":"uint256"},
-	     {"name":"amountOutMinETH","type":"uint256"},
-	     {"name":"recipient","type":"address"},
-	     {"name":"deadline","type":"uint256"}
-	   ],"outputs":[]}
-	]`
-	parsedABI, err := abi.JSON(strings.NewReader(delegateABI))
-	if err != nil {
-		return fmt.Errorf("delegate ABI parse: %w", err)
-	}
-	if strings.TrimSpace(cfg.DelegateHex) == "" || !common.IsHexAddress(cfg.DelegateHex) {
-		return fmt.Errorf("bad DELEGATE_ADDRESS in .env")
-	}
-	delegateAddr := common.HexToAddress(cfg.DelegateHex)
-	relays := splitCSV(cfg.RelaysCSV)
-
-	// Skip header if present
-	start := 0
-	if len(rows) > 0 {
-		h := strings.ToLower(strings.TrimSpace(rows[0][0]))
-		if strings.Contains(h, "token") || strings.Contains(h, "address") {
-			start = 1
-		}
-	}
-
-	// Keep a local sponsor nonce counter for private relays.
-	// Private relays do not advance pending nonce in your public RPC.
-	nextNonce, err := eip7702.EstimateSponsorNonce(ctx, ec, sponsorAddr) // uint64
-	if err != nil {
-		return fmt.Errorf("sponsor nonce error: %w", err)
-	}
-
-
-	for i := start; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) < 3 {
-			continue
-		}
-		tokenHex := strings.TrimSpace(row[0])
-		fromPKHex := strings.TrimSpace(row[1])
-		fromHex := strings.TrimSpace(row[2])
-
-		if !common.IsHexAddress(tokenHex) || !common.IsHexAddress(fromHex) || len(fromPKHex) < 16 {
-			fmt.Fprintf(logw, "[row %d] skip: malformed values\n", i+1)
-			continue
-		}
-		token := common.HexToAddress(tokenHex)
-		from := common.HexToAddress(fromHex)
-
-		// PK -> from check
-		fromPK, err := crypto.HexToECDSA(strings.TrimPrefix(fromPKHex, "0x"))
-		if err != nil || crypto.PubkeyToAddress(fromPK.PublicKey) != from {
-			fmt.Fprintf(logw, "[row %d] error: bad private key for %s\n", i+1, from.Hex())
-			continue
-		}
-
-		// Balance
-		bal, err := fetchTokenBalance(ctx, ec, token, from)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] %s balanceOf error: %v\n", i+1, token.Hex(), err)
-			continue
-		}
-		if bal == nil || bal.Sign() == 0 {
-			fmt.Fprintf(logw, "[row %d] %s balance=0 - skip\n", i+1, token.Hex())
-			continue
-		}
-
-    // Decide route by 7702 preflight (with optional force-swap)
-    ok, why, _ := core.PreflightTransfer7702(ctx, ec, rc, token, from, sponsorAddr, bal)
-    route := "sell-v2" // default: swap to ETH, send ETH to SAFE
-    // Force swap if:
-    //  • SWAP_ONLY=1 in environment, OR
-    //  • CSV has 4th column containing word "swap" for this row.
-    preferSwap := strings.EqualFold(strings.TrimSpace(os.Getenv("SWAP_ONLY")), "1")
-    if !preferSwap && len(row) >= 4 && strings.Contains(strings.ToLower(row[3]), "swap") {
-        preferSwap = true
-    }
-    if !preferSwap && ok { route = "transfer" }
-		fmt.Fprintf(logw, "[row %d] plan: %s (%s)\n", i+1, route, why)
-
-		// Additional preflight: when plan is sell-v2, ensure swap path [token->WETH] has liquidity.
-		if route == "sell-v2" {
-			if okSwap, reason := preflightSellV2GetAmountsOut(ctx, ec, token, bal); !okSwap {
-				fmt.Fprintf(logw, "[row %d] sell-v2 preflight FAIL: %s - skip\n", i+1, reason)
-				continue
-			}
-		}
-
-		// Calldata
-		var calldata []byte
-		switch route {
-		case "transfer":
-			calldata, err = parsedABI.Pack("sweepToken", token, sponsorAddr)
-		default:
-			amountOutMin := big.NewInt(0)
-			deadline := big.NewInt(time.Now().Add(20 * time.Minute).Unix())
-			calldata, err = parsedABI.Pack("sellToETH_V2", token, bal, amountOutMin, sponsorAddr, deadline)
-		}
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] abi pack failed: %v\n", i+1, err)
-			continue
-		}
-
-		// 7702 authorizations
-		authNonce, _ := ec.NonceAt(ctx, from, nil)
-		auths, err := eip7702.BuildAuthorizations(chainID, from, delegateAddr, authNonce, 1, fromPK)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] build auth failed: %v\n", i+1, err)
-			continue
-		}
-
-		var tipWei *big.Int
-		if cfg.TipGwei > 0 {
-			tipWei = new(big.Int).Mul(big.NewInt(cfg.TipGwei), big.NewInt(1_000_000_000))
-		}
-		tip, cap, err := eip7702.PrepareFees(ctx, ec, tipWei)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] fee prep error: %v\n", i+1, err)
-			continue
-		}
-		// ASCII-only comment
-		gasLimit := uint64(500_000) // transfer~90k, v2~220-300k => 500k headroom
-
-		// Build & sign
-		unsigned, err := eip7702.BuildSetCodeTx(eip7702.BuildParams{
-			ChainID:           chainID,
-			SponsorNonce:      nextNonce,
-			GasLimit:          gasLimit,
-			MaxPriorityFeeWei: tip,
-			MaxFeeWei:         cap,
-			AuthorityEOA:      from,
-			DelegateContract:  delegateAddr,
-			Calldata:          calldata,
-			Authorizations:    auths,
-		})
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] build setcode tx failed: %v\n", i+1, err)
-			continue
-		}
-    nextNonce++  // uint64 increment
-		safePK, err := crypto.HexToECDSA(strings.TrimPrefix(cfg.SafePK, "0x"))
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] safe key parse failed: %v\n", i+1, err)
-			continue
-		}
-		signed, err := eip7702.SignSetCodeTx(chainID, safePK, unsigned)
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] sign failed: %v\n", i+1, err)
-			continue
-		}
-
-		// Send private
-		raw, err := signed.MarshalBinary()
-		if err != nil {
-			fmt.Fprintf(logw, "[row %d] rlp failed: %v\n", i+1, err)
-			continue
-		}
-		var authSigner *ecdsa.PrivateKey
-		if strings.TrimSpace(cfg.AuthPK) != "" {
-			if k, e := crypto.HexToECDSA(strings.TrimPrefix(cfg.AuthPK, "0x")); e == nil {
-				authSigner = k
-			}
-		}
-		results := eip7702.SendPrivate(ctx, "0x"+common.Bytes2Hex(raw), relays, nil, authSigner)
-		accepted := false
-		for _, rr := range results {
-			fmt.Fprintf(logw, "[row %d] relay=%s http=%d accepted=%v body=%s\n",
-				i+1, rr.RelayURL, rr.HTTPStatus, rr.Accepted, rr.ResponseBody)
-			if rr.Accepted {
-				accepted = true
-			}
-		}
-		if !accepted {
-			fmt.Fprintf(logw, "[row %d] no relay accepted\n", i+1)
-		}
-	}
-
-	fmt.Fprintf(logw, "# batch finished at %s\n", time.Now().Format(time.RFC3339))
-	fmt.Printf("Batch log written to %s\n", logPath)
-	return nil
-}
-
 // preflightSellV2GetAmountsOut checks if Uniswap V2 path [token -> WETH] yields non-zero out.
 // It uses router.getAmountsOut(amountIn, path) via eth_call; no approvals are required.
-func preflightSellV2GetAmountsOut(ctx context.Context, ec *ethclient.Client, token common.Address, amountIn *big.Int) (bool, string) {
-	// Mainnet constants (match delegate)
-	router := common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
-	weth   := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+// The delegate's sellToETH_V2 sells through the mainnet UniswapV2 router, so on any other
+// chain the route is refused.
+func preflightSellV2GetAmountsOut(ctx context.Context, ec *ethclient.Client, chainID *big.Int, token common.Address, amountIn *big.Int) (bool, string) {
+	if chainID == nil || !chainID.IsUint64() || chainID.Uint64() != core.Mainnet.ID {
+		return false, fmt.Sprintf("chain %s: the delegate sells via the mainnet UniswapV2 router only", chainID)
+	}
+	router, weth := core.Mainnet.V2Router, core.Mainnet.WETH
 	if amountIn == nil || amountIn.Sign() == 0 {
 		return false, "zero amount"
 	}
@@ -529,22 +324,7 @@ func runClassicBundleFromRescueMenu(
 	}
 
 	// Extra headers (bloxroute): keep parity with classic flow (API key OR ready Authorization)
-	extraHeaders := map[string]map[string]string{}
-	if v := getenv("BLOXROUTE_RELAY", ""); v != "" {
-		h := map[string]string{}
-		if k := getenv("BLOXROUTE_API_KEY", ""); k != "" {
-			// Classic path sets both headers for Cloud API
-			h["X-API-KEY"] = k
-			h["Authorization"] = k
-		}
-		if auth := getenv("BLOXROUTE_AUTH_HEADER", ""); auth != "" {
-			// Allow overriding with ready Authorization header
-			h["Authorization"] = auth
-		}
-		if len(h) > 0 {
-			extraHeaders[v] = h
-		}
-	}
+	extraHeaders := bloxrouteHeaders()
 
 	// Tip strategy
 	tipMode := "fixed"
@@ -574,11 +354,19 @@ func runClassicBundleFromRescueMenu(
 	// Optional coinbase bribe only for mode==2 (as per your menu)
 	var bribeWei *big.Int
 	var bribeGasLimit uint64
+	var advice bribe.Advice
 	if mode == "2" && yes(strings.ToLower(readLine(bufio.NewReader(os.Stdin), "Включить coinbase bribe? [y/N]: "))) {
-		if s := strings.TrimSpace(readLine(bufio.NewReader(os.Stdin), "Сумма bribe в ETH (например 0.02) [ENTER=0]: ")); s != "" {
+		advice = adviseBribe(ctx, ec, cfg, tokenAddr, bal)
+		if s := strings.TrimSpace(readLine(bufio.NewReader(os.Stdin), fmt.Sprintf("Сумма bribe в ETH [ENTER=%s, 0=без bribe]: ", formatEther(advice.ProposedWei)))); s != "" {
 			if v, ok := parseAmountETHToWei(s); ok {
 				bribeWei = v
 			}
+		} else {
+			bribeWei = advice.ProposedWei
+		}
+		if v, ok := advice.Clamp(bribeWei); !ok {
+			fmt.Printf("  [bribe] %s ETH is above the cap (BRIBE_MAX_PCT=%g%% of value) — using %s ETH\n", formatEther(bribeWei), cfg.BribeMaxPct, formatEther(v))
+			bribeWei = v
 		}
 		if s := strings.TrimSpace(readLine(bufio.NewReader(os.Stdin), "GasLimit для bribe [ENTER=50000]: ")); s != "" {
 			if v, err := strconv.ParseUint(s, 10, 64); err == nil && v > 21000 && v < 1_000_000 {
@@ -600,11 +388,12 @@ func runClassicBundleFromRescueMenu(
 		SafePKHex: cfg.SafePK, FromPKHex: fromPK,
 		Blocks: cfg.Blocks, TipGweiBase: tipBase, TipMul: cfg.TipMul, BaseMul: cfg.BaseMul, BufferPct: cfg.BufferPct,
 		TipMode: tipMode, TipWindow: tipWindow, TipPercentile: tipPercentile,
-		BribeWei: bribeWei, BribeGasLimit: bribeGasLimit, ExtraHeaders: extraHeaders,
+		BribeWei: bribeWei, BribeGasLimit: bribeGasLimit, ExtraHeaders: extraHeaders, CompeteBumpPct: cfg.CompeteBumpPct, CompeteMaxMul: cfg.CompeteMaxMul,
+		Route: cfg.ClassicRoute, SellMinOutWei: cfg.SellMinOutWei, SellSlippageBps: cfg.SellSlippageBps, AccessList: cfg.AccessList,
 		Builders: cfg.Builders, ReplacementUUID: "", MinTimestamp: cfg.MinTs, MaxTimestamp: cfg.MaxTs,
-		BeaverAllowBuilderNetRefunds: &cfg.BeaverAllow, BeaverRefundRecipientHex: cfg.BeaverRefundTo,
+		BeaverAllowBuilderNetRefunds: &cfg.BeaverAllow, BeaverRefundRecipientHex: cfg.BeaverRefundTo, Share: cfg.Share,
 		Verbose: false, SimulateOnly: false, SkipIfPaused: true,
-		Logf: func(format string, a ...any){ fmt.Printf(format+"\n", a...) },
+		Logf: func(format string, a ...any){ fmt.Println(privacy.Line(fmt.Sprintf(format, a...))) },
 		OnSimResult: func(relay, raw string, ok bool, err string){
 			state := "OK"; if !ok { state = "FAIL" }
 			if err != "" { err = friendlySimErr(err) }
@@ -616,7 +405,9 @@ func runClassicBundleFromRescueMenu(
 	if res, err := core.Run(ctx, ec, params); err != nil {
 		return fmt.Errorf("classic bundle error: %w", err)
 	} else {
-		fmt.Println("  [RESULT]", res.Reason, "| included:", res.Included)
+		fmt.Printf("  [RESULT] %s | included: %v%s\n", res.Reason, res.Included, explorerSuffix(chainID, res.TxHash))
+		printResultHint("    ", res)
+		recordBribe(cfg, advice, tokenAddr, fromAddr, bribeWei, res)
 	}
 	return nil
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ligun0805/bundle-rescue/internal/eip7702"
+	"github.com/ligun0805/bundle-rescue/internal/riskgate"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+)
+
+// riskPolicy loads the confirmation gates (RISK_*, see internal/riskgate). Without
+// RISK_KNOWN_DELEGATES the known delegates are DELEGATE_ADDRESS + DELEGATE_ALLOWLIST.
+func riskPolicy(cfg EnvConfig, chainID *big.Int) (*riskgate.Policy, error) {
+	p, err := riskgate.FromEnv(chainID.String())
+	if err != nil {
+		return nil, err
+	}
+	if d, err := eip7702.NewDelegatePolicy(cfg.DelegateHex, cfg.DelegateAllow, cfg.DelegateByToken); err == nil {
+		p.TrustDelegates(d.Allow)
+	}
+	return p, nil
+}
+
+// publicFallback reports whether RELAYS contains the RPC_URL host itself: SendPrivate ends
+// with eth_sendRawTransaction, which a public node broadcasts to the mempool.
+func publicFallback(cfg EnvConfig) bool {
+	rpcHost := rpcdial.Host(cfg.RPC)
+	for _, r := range splitCSV(cfg.RelaysCSV) {
+		if strings.EqualFold(rpcdial.Host(r), rpcHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/relayrecord"
+)
+
+// defaultSubmissionLog is the root of the relay submission records (SUBMISSION_LOG).
+const defaultSubmissionLog = "submissions"
+
+// startSubmissionLog starts recording the relay calls of this run under cfg.SubmissionLog
+// (see relayrecord); nil when it is off or cannot be opened (the run goes on without it).
+func startSubmissionLog(cfg EnvConfig, tool string) *relayrecord.Recorder {
+	if cfg.SubmissionLog == "" {
+		return nil
+	}
+	rec, err := relayrecord.Start(cfg.SubmissionLog, tool+"_"+time.Now().Format("20060102_150405"))
+	if err != nil {
+		fmt.Println("  [submissions] recording unavailable:", err)
+		return nil
+	}
+	fmt.Println("  [submissions] relay calls are recorded in", rec.Dir())
+	return rec
+}
+
+// printSubmissionLog prints how many relay calls the run recorded.
+func printSubmissionLog(rec *relayrecord.Recorder) {
+	if rec == nil {
+		return
+	}
+	fmt.Printf("  [submissions] %d relay call(s) recorded in %s (export: bundlecli submissions export %s)\n", rec.Count(), rec.Dir(), filepath.Base(rec.Dir()))
+}
+
+// runSubmissions implements `bundlecli submissions list | show RUN | export RUN [-out FILE]`:
+// the recorded relay calls of past runs, and their export as a zip for a relay or builder
+// operator (records, request/response bodies, SHA256SUMS).
+func runSubmissions(args []string) int {
+	fs := flag.NewFlagSet("submissions", flag.ExitOnError)
+	root := fs.String("dir", getenv("SUBMISSION_LOG", activeProfile.Path(defaultSubmissionLog)), "Root of the submission records (SUBMISSION_LOG)")
+	out := fs.String("out", "", "export: zip to write (default: <RUN>.zip)")
+	_ = fs.Parse(args)
+	rest := fs.Args()
+	if len(rest) > 2 { // flags after RUN
+		_ = fs.Parse(rest[2:])
+		rest = append(rest[:2], fs.Args()...)
+	}
+	usage := "usage: bundlecli submissions list | show RUN | export RUN [-out FILE.zip] [-dir submissions]"
+	if len(rest) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return exitcode.Config
+	}
+	if rest[0] == "list" {
+		runs, err := relayrecord.Runs(*root)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "submissions:", err)
+			return exitcode.Config
+		}
+		for _, r := range runs {
+			recs, _ := relayrecord.Load(filepath.Join(*root, r))
+			fmt.Printf("%s\t%d call(s)\n", r, len(recs))
+		}
+		return exitcode.OK
+	}
+	if (rest[0] != "show" && rest[0] != "export") || len(rest) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		return exitcode.Config
+	}
+	dir := filepath.Join(*root, rest[1])
+	if rest[0] == "show" {
+		recs, err := relayrecord.Load(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "submissions:", err)
+			return exitcode.Config
+		}
+		for _, r := range recs {
+			answer := fmt.Sprintf("http %d in %d ms", r.Status, r.LatencyMs)
+			if r.Error != "" {
+				answer = "error: " + r.Error
+			}
+			fmt.Printf("#%d %s %s %s block %s req %s — %s\n", r.Seq, r.SentAt, r.Relay, r.Method, r.TargetBlock, r.RequestSHA256[:12], answer)
+		}
+		for _, l := range relayrecord.Summary(recs) {
+			fmt.Println("  " + l)
+		}
+		return exitcode.OK
+	}
+	path := *out
+	if path == "" {
+		path = rest[1] + ".zip"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "submissions:", err)
+		return exitcode.Config
+	}
+	recs, err := relayrecord.Export(dir, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		fmt.Fprintln(os.Stderr, "submissions export:", err)
+		return exitcode.Config
+	}
+	fmt.Printf("%s: %d relay call(s)\n", path, len(recs))
+	for _, l := range relayrecord.Summary(recs) {
+		fmt.Println("  " + l)
+	}
+	return exitcode.OK
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/riskgate"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+)
+
+// runSweepETH implements `bundlecli sweep-eth`: drains the native ETH of FROM_PRIVATE_KEY to
+// the SAFE with a classic bundle (no 7702, FROM pays its own gas). For wallets where only ETH
+// is stranded; the SAFE key is used for its address only, nothing is prefunded.
+func runSweepETH(args []string) int {
+	fs := flag.NewFlagSet("sweep-eth", flag.ExitOnError)
+	simulateOnly := fs.Bool("simulate-only", false, "Build, sign and simulate (eth_callBundle), never send")
+	toHex := fs.String("to", os.Getenv("SWEEP_TO"), "Recipient (default: SAFE address); another address needs the typed confirmation phrase")
+	_ = fs.Parse(args)
+
+	if err := explorer.LoadEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth:", err)
+		return exitcode.Config
+	}
+	if err := core.LoadBuilderEnv(activeProfile.Path(core.DefaultBuilderFile)); err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth:", err)
+		return exitcode.Config
+	}
+	cfg := loadEnv()
+	if strings.TrimSpace(cfg.FromPK) == "" {
+		fmt.Fprintln(os.Stderr, "sweep-eth: FROM_PRIVATE_KEY is empty in env")
+		return exitcode.Config
+	}
+	if strings.TrimSpace(cfg.AuthPK) == "" {
+		fmt.Fprintln(os.Stderr, "sweep-eth: FLASHBOTS_AUTH_PK is empty in env")
+		return exitcode.Config
+	}
+	var safeAddr Address
+	if strings.TrimSpace(cfg.SafePK) != "" {
+		safeAddr = mustAddrFromPK(cfg.SafePK)
+	}
+	to := safeAddr
+	if v := strings.TrimSpace(*toHex); v != "" {
+		if !common.IsHexAddress(v) {
+			fmt.Fprintln(os.Stderr, "sweep-eth: bad -to", v)
+			return exitcode.Config
+		}
+		to = common.HexToAddress(v)
+	}
+	if to == (Address{}) {
+		fmt.Fprintln(os.Stderr, "sweep-eth: SAFE_PRIVATE_KEY is empty in env and no -to given")
+		return exitcode.Config
+	}
+	fromAddr := mustAddrFromPK(cfg.FromPK)
+	if fromAddr == to {
+		fmt.Fprintln(os.Stderr, "sweep-eth: recipient equals FROM")
+		return exitcode.Config
+	}
+
+	ctx := context.Background()
+	ec, err := newEthClientWithTimeout(cfg.RPC)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth: dial:", err)
+		return exitcode.RPC
+	}
+	chainID, err := ec.ChainID(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth: chain id:", err)
+		return exitcode.RPC
+	}
+	if strings.TrimSpace(cfg.ChainIDStr) != "" && mustBig(cfg.ChainIDStr).Cmp(chainID) != 0 {
+		fmt.Fprintf(os.Stderr, "sweep-eth: CHAIN_ID=%s but RPC reports %s\n", cfg.ChainIDStr, chainID)
+		return exitcode.Config
+	}
+	if err := activeProfile.CheckChain(chainID); err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth:", err)
+		return exitcode.Config
+	}
+	bal, err := ec.BalanceAt(ctx, fromAddr, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth: balance:", err)
+		return exitcode.RPC
+	}
+	fmt.Println("  from:", fromAddr.Hex(), " | ETH balance:", privacy.Amount(formatEther(bal)))
+	fmt.Println("  to:  ", to.Hex(), map[bool]string{true: "(SAFE)", false: ""}[to == safeAddr])
+	if bal.Sign() == 0 {
+		fmt.Println("  [sweep] nothing to sweep")
+		return exitcode.OK
+	}
+
+	if !*simulateOnly {
+		risk, err := riskPolicy(cfg, chainID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sweep-eth:", err)
+			return exitcode.Config
+		}
+		as := risk.Assess(riskgate.Action{
+			Symbol: "ETH", Decimals: 18, Amount: bal, Recipient: to, Safe: safeAddr, PublicFallback: publicFallback(cfg),
+		})
+		if err := risk.Confirm(bufio.NewReader(os.Stdin), os.Stdout, as); err != nil {
+			fmt.Println("  [sweep] aborted by confirmation gate:", err)
+			if errors.Is(err, riskgate.ErrDeclined) {
+				return exitcode.OK
+			}
+			return exitcode.Config
+		}
+	}
+
+	submissions := startSubmissionLog(cfg, "sweep-eth")
+	defer submissions.Stop()
+	startMetrics(cfg)
+	res, err := core.SweepETH(ctx, ec, core.Params{
+		RPC: cfg.RPC, ChainID: chainID, Relays: splitCSV(cfg.RelaysCSV), AuthPrivHex: cfg.AuthPK,
+		From: fromAddr, To: to, FromPKHex: cfg.FromPK,
+		Blocks: cfg.Blocks, TipGweiBase: cfg.TipGwei, TipMul: cfg.TipMul, BaseMul: cfg.BaseMul,
+		ExtraHeaders: bloxrouteHeaders(), CompeteBumpPct: cfg.CompeteBumpPct, CompeteMaxMul: cfg.CompeteMaxMul,
+		HeadCheckRPCs: cfg.HeadCheckRPCs, HeadLagWarn: cfg.HeadLagWarn,
+		Builders: cfg.Builders, ReplacementUUID: genUUIDv4(), MinTimestamp: cfg.MinTs, MaxTimestamp: cfg.MaxTs,
+		BeaverAllowBuilderNetRefunds: &cfg.BeaverAllow, BeaverRefundRecipientHex: cfg.BeaverRefundTo, Share: cfg.Share,
+		SimulateOnly: *simulateOnly,
+		Logf:         func(f string, a ...any) { fmt.Println(privacy.Line(fmt.Sprintf(f, a...))) },
+		OnSimResult: func(relay, raw string, ok bool, err string) {
+			state := "OK"
+			if !ok {
+				state = "FAIL"
+			}
+			if err != "" {
+				err = friendlySimErr(err)
+			}
+			fmt.Printf("  [sim %s] %s err=%s\n", relay, state, err)
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sweep-eth:", err)
+		return exitcode.Failure
+	}
+	moved := ""
+	if res.Moved != nil {
+		moved = " | " + privacy.Amount(formatEther(res.Moved)) + " ETH"
+	}
+	fmt.Printf("[RESULT] %s | included: %v%s%s\n", res.Reason, res.Included, moved, explorerSuffix(chainID, res.TxHash))
+	for _, l := range stagetime.Report() {
+		fmt.Println("  " + l)
+	}
+	printSubmissionLog(submissions)
+	if res.Err() == nil {
+		return exitcode.OK
+	}
+	printResultHint("  ", res)
+	return exitcode.Failure
+}
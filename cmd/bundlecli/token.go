@@ -16,6 +16,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+	"github.com/ligun0805/bundle-rescue/internal/units"
 )
 
 // --- RPC concurrency gate (limits parallel eth_call to protect the RPC) ---
@@ -57,8 +61,7 @@ func callContractWithRetry(ctx context.Context, ec *ethclient.Client, msg ethere
 		lastErr = err
 		if attempt < maxAttempts {
 			time.Sleep(backoff)
-			s := err.Error()
-			if strings.Contains(s, "Too Many Requests") || strings.Contains(s, "-32005") {
+			if rpcerrors.IsRateLimited(err) {
 				backoff *= 2
 			}
 		}
@@ -66,30 +69,15 @@ func callContractWithRetry(ctx context.Context, ec *ethclient.Client, msg ethere
 	return nil, lastErr
 }
 
-// classifyCallError returns a concise, user-facing reason for common eth_call failures.
-func classifyCallError(ctx context.Context, ec *ethclient.Client, target common.Address, err error) string {
-	if err == nil {
-		return ""
-	}
-	s := err.Error()
-	if strings.Contains(s, "Too Many Requests") || strings.Contains(s, "-32005") {
-		return "[RATE_LIMIT] provider throttled the request"
-	}
-	if strings.Contains(s, "execution reverted") {
-		if idx := strings.Index(s, ":"); idx >= 0 && idx+1 < len(s) {
-			if r := strings.TrimSpace(s[idx+1:]); r != "" {
-				return "[REVERT] " + r
-			}
-		}
-		return "[REVERT] execution reverted"
-	}
-	if code, e := ec.CodeAt(ctx, target, nil); e == nil && len(code) == 0 {
-		return "[NOT_CONTRACT] no bytecode at address"
+// proxyImplementation looks up the EIP-1967 implementation of token after a failed getter;
+// the caller retries the getter there so upgradeable tokens are not taken for broken ones.
+func proxyImplementation(ctx context.Context, ec *ethclient.Client, token Address) (Address, bool) {
+	impl, err := core.ProxyImplementation(ctx, ec, token)
+	if err != nil || impl == (Address{}) {
+		return Address{}, false
 	}
-	if strings.Contains(s, "unsupported") || strings.Contains(s, "abi") {
-		return "[UNSUPPORTED] ABI/return type mismatch"
-	}
-	return "[RPC] " + s
+	fmt.Printf("  [proxy] proxy(impl=%s): retrying on the implementation\n", impl.Hex())
+	return impl, true
 }
 
 // fetchTokenDecimals returns decimals or error (caller may default to 18)
@@ -98,20 +86,23 @@ func fetchTokenDecimals(ctx context.Context, ec *ethclient.Client, token Address
 	res, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: decimalsSelector})
 	if err != nil {
 		// Print precise reason for diagnostics; caller decides flow.
-		fmt.Println("  [!] decimals():", classifyCallError(ctx, ec, token, err))
-		return 0, err
-	}
-	if len(res) == 0 {
-		return 18, nil
+		fmt.Println("  [!] decimals():", rpcerrors.ClassifyCall(ctx, ec, token, err).Describe())
+		impl, ok := proxyImplementation(ctx, ec, token)
+		if !ok {
+			return 0, err
+		}
+		if res, err = callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &impl, Data: decimalsSelector}); err != nil {
+			return 0, err
+		}
 	}
-	return int(res[len(res)-1]), nil
+	return units.DecodeDecimals(res)
 }
 
 func fetchTokenBalance(ctx context.Context, ec *ethclient.Client, token, owner Address) (*big.Int, error) {
 	data := append(common.FromHex("0x70a08231"), common.LeftPadBytes(owner.Bytes(), 32)...)
 	res, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: data})
 	if err != nil {
-		fmt.Println("  [!] balanceOf():", classifyCallError(ctx, ec, token, err))
+		fmt.Println("  [!] balanceOf():", rpcerrors.ClassifyCall(ctx, ec, token, err).Describe())
 		return nil, err
 	}
 	if len(res) == 0 {
@@ -121,64 +112,11 @@ func fetchTokenBalance(ctx context.Context, ec *ethclient.Client, token, owner A
 }
 
 func toWeiFromTokens(amount string, decimals int) (*big.Int, error) {
-	amount = strings.TrimSpace(amount)
-	if amount == "" {
-		return nil, fmt.Errorf("empty amount")
-	}
-	if decimals < 0 {
-		decimals = 18
-	}
-	parts := strings.SplitN(amount, ".", 2)
-	intPart := parts[0]
-	fracPart := ""
-	if len(parts) == 2 {
-		fracPart = parts[1]
-	}
-	if len(fracPart) > decimals {
-		return nil, fmt.Errorf("too many fractional digits for %d decimals", decimals)
-	}
-	fracPart = fracPart + strings.Repeat("0", decimals-len(fracPart))
-	clean := strings.TrimLeft(intPart+fracPart, "0")
-	if clean == "" {
-		return big.NewInt(0), nil
-	}
-	v, ok := new(big.Int).SetString(clean, 10)
-	if !ok {
-		return nil, fmt.Errorf("bad amount")
-	}
-	return v, nil
+	return units.Parse(amount, decimals)
 }
 
 func formatTokensFromWei(v *big.Int, decimals int) string {
-	if v == nil {
-		return "0"
-	}
-	if decimals <= 0 {
-		return v.String()
-	}
-	s := new(big.Int).Abs(v).String()
-	neg := v.Sign() < 0
-	if len(s) <= decimals {
-		frac := strings.Repeat("0", decimals-len(s)) + s
-		out := "0." + strings.TrimRight(frac, "0")
-		if out == "0." {
-			out = "0"
-		}
-		if neg {
-			return "-" + out
-		}
-		return out
-	}
-	intPart := s[:len(s)-decimals]
-	frac := strings.TrimRight(s[len(s)-decimals:], "0")
-	out := intPart
-	if frac != "" {
-		out = intPart + "." + frac
-	}
-	if neg {
-		return "-" + out
-	}
-	return out
+	return units.Format(v, decimals, units.Full)
 }
 
 func tryReadBPSAndTS(ctx context.Context, ec *ethclient.Client, token Address) (ok bool, maxTxBps, maxWalletBps uint64, totalSupply *big.Int) {
@@ -228,7 +166,7 @@ func printPendingStateForAddress(rpcURL, fromHexLower string) error {
 			return rpcResp{}, err
 		}
 		if out.Error != nil {
-			return out, fmt.Errorf(out.Error.Message)
+			return out, fmt.Errorf("%s", out.Error.Message)
 		}
 		return out, nil
 	}
@@ -278,10 +216,13 @@ func printPendingStateForAddress(rpcURL, fromHexLower string) error {
 func fetchTokenSymbol(ctx context.Context, ec *ethclient.Client, token Address) (string, error) {
 	data := common.FromHex("0x95d89b41") // symbol()
 	out, err := callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: data})
-	if err != nil || len(out) == 0 {
-		if err != nil {
-			fmt.Println("  [!] symbol():", classifyCallError(ctx, ec, token, err))
+	if err != nil {
+		fmt.Println("  [!] symbol():", rpcerrors.ClassifyCall(ctx, ec, token, err).Describe())
+		if impl, ok := proxyImplementation(ctx, ec, token); ok {
+			out, err = callContractWithRetry(ctx, ec, ethereum.CallMsg{To: &impl, Data: data})
 		}
+	}
+	if err != nil || len(out) == 0 {
 		return "", err
 	}
 	if len(out) >= 64 {
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	eip7702 "github.com/ligun0805/bundle-rescue/internal/eip7702"
+	"github.com/ligun0805/bundle-rescue/internal/erc4337"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+)
+
+// Smart-account pairs: the row's key owns from as an ERC-4337 account (SimpleAccount-style,
+// EntryPoint v0.6/v0.7). Neither 7702 nor a raw transfer applies; the owner signs a
+// UserOperation execute(token, 0, transfer(SAFE, balance)) and BUNDLER_URL submits it. The
+// account pays the gas from its EntryPoint deposit and ETH balance; when both are short,
+// AA_PREFUND=1 lets SAFE deposit the missing amount first (a public depositTo tx). Bundler
+// mempools are public, so these pairs carry the public_fallback risk factor.
+
+const (
+	userOpWait     = 3 * time.Minute // bundler inclusion deadline
+	userOpPoll     = 3 * time.Second
+	prefundGas     = 60_000 // depositTo from SAFE
+	prefundHeadPct = 10     // deposit above the estimate: fees move until the op lands
+)
+
+// runUserOpRow rescues one smart-account pair: detect, preflight, estimate, (prefund,) sign,
+// send through the bundler and wait for the receipt. Simulate-only stops after the estimate.
+func runUserOpRow(ctx context.Context, env *batchEnv, token, from common.Address, owner *ecdsa.PrivateKey, pl *pairLog) {
+	ec := env.ec
+	ownerAddr := crypto.PubkeyToAddress(owner.PublicKey)
+	fail := func(format string, args ...any) {
+		reason := fmt.Sprintf(format, args...)
+		pl.logf("skip: %s", reason)
+		env.verdict(from, token, "userop", "FAIL", reason)
+	}
+	acct, err := erc4337.Detect(ctx, ec, from)
+	if err != nil {
+		fail("key %s is not from's key, and from is no smart account: %v", ownerAddr.Hex(), err)
+		return
+	}
+	if err := acct.CheckOwner(ownerAddr); err != nil {
+		fail("%v", err)
+		return
+	}
+	pl.logf("smart account: EntryPoint %s (%s), owner %s", acct.EntryPoint.Hex(), acct.Version, ownerAddr.Hex())
+	if env.cfg.BundlerURL == "" {
+		fail("smart account needs a bundler: set BUNDLER_URL")
+		return
+	}
+	if env.bundler == nil {
+		if env.bundler, err = erc4337.DialBundler(ctx, env.cfg.BundlerURL); err != nil {
+			fail("%v", err)
+			return
+		}
+	}
+	if ok, err := env.bundler.Supports(ctx, acct.EntryPoint); err != nil || !ok {
+		fail("bundler does not serve EntryPoint %s (%s) %v", acct.EntryPoint.Hex(), acct.Version, err)
+		return
+	}
+
+	metaStart := time.Now()
+	bal, err := fetchTokenBalance(ctx, ec, token, from)
+	stagetime.Since(stagetime.Metadata, metaStart)
+	if err != nil {
+		pl.logf("%s balanceOf error: %v", token.Hex(), err)
+		return
+	}
+	if bal == nil || bal.Sign() == 0 {
+		pl.logf("%s balance=0 - skip", token.Hex())
+		return
+	}
+	pl.logf("balance=%s wei", bal.String())
+	if !env.opts.simulateOnly && !riskGateOK(ctx, env, token, from, common.Address{}, bal, true, pl) {
+		return
+	}
+
+	callData := erc4337.TransferCallData(token, env.sponsorAddr, bal)
+	preStart := time.Now()
+	ok, why, err := acct.SimulateExecute(ctx, ec, callData)
+	stagetime.Since(stagetime.Preflight, preStart)
+	switch {
+	case err != nil:
+		fail("userop preflight: %v", err)
+		return
+	case !ok:
+		fail("%s", why)
+		return
+	}
+	pl.logf("plan: userop (execute(transfer) passes from the EntryPoint)")
+
+	buildStart := time.Now()
+	nonce, err := acct.Nonce(ctx, ec)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+	var tipWei *big.Int
+	if env.cfg.TipGwei > 0 {
+		tipWei = new(big.Int).Mul(big.NewInt(env.cfg.TipGwei), big.NewInt(1_000_000_000))
+	}
+	tip, maxFee, err := eip7702.PrepareFees(ctx, ec, tipWei)
+	if err != nil {
+		pl.logf("fee prep error: %v", err)
+		return
+	}
+	op := &erc4337.UserOp{Sender: from, Nonce: nonce, CallData: callData, MaxFeePerGas: maxFee, MaxPriorityFeePerGas: tip}
+	stagetime.Since(stagetime.BuildSign, buildStart)
+	simStart := time.Now()
+	err = env.bundler.Estimate(ctx, op, acct.EntryPoint, acct.Version)
+	stagetime.Since(stagetime.Simulate, simStart)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+	prefund := op.Prefund()
+	funds, err := acct.Funds(ctx, ec)
+	if err != nil {
+		fail("account funds: %v", err)
+		return
+	}
+	pl.logf("userop: nonce=%s callGas=%s verificationGas=%s preVerificationGas=%s maxFee=%s gwei tip=%s gwei prefund=%s wei funds=%s wei",
+		nonce, op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas, formatGwei(maxFee), formatGwei(tip), prefund, funds)
+	missing := new(big.Int).Sub(prefund, funds)
+	if missing.Sign() > 0 && !env.cfg.AAPrefund {
+		fail("account holds %s wei for gas, the op may charge %s wei: fund it or set AA_PREFUND=1", funds, prefund)
+		return
+	}
+	if env.opts.simulateOnly {
+		note := ""
+		if missing.Sign() > 0 {
+			note = fmt.Sprintf("SAFE deposits %s wei first (AA_PREFUND)", missing)
+		}
+		pl.logf("sim verdict: OK (bundler estimate) %s", note)
+		env.ok++
+		env.verdict(from, token, "userop", "OK", note)
+		return
+	}
+	if missing.Sign() > 0 && !prefundAccount(ctx, env, acct, missing, pl) {
+		return
+	}
+
+	buildStart = time.Now()
+	err = op.Sign(owner, acct.EntryPoint, acct.Version, env.chainID)
+	stagetime.Since(stagetime.BuildSign, buildStart)
+	if err != nil {
+		pl.logf("sign failed: %v", err)
+		return
+	}
+	sendStart := time.Now()
+	h, err := env.bundler.Send(ctx, op, acct.EntryPoint, acct.Version)
+	stagetime.Since(stagetime.RelaySend, sendStart)
+	if err != nil {
+		pl.logf("bundler rejected the userop: %v", err)
+		return
+	}
+	pl.logf("userop: %s sent", h.Hex())
+	waitStart := time.Now()
+	wctx, cancel := context.WithTimeout(ctx, userOpWait)
+	rcpt, err := env.bundler.Wait(wctx, h, userOpPoll)
+	cancel()
+	stagetime.Since(stagetime.InclusionWait, waitStart)
+	if err != nil {
+		pl.logf("userop: %v", err)
+		return
+	}
+	pl.logf("userop: included in tx %s%s block=%s success=%v gasCost=%s wei %s",
+		rcpt.Receipt.TransactionHash.Hex(), explorerSuffix(env.chainID, rcpt.Receipt.TransactionHash), rcpt.Receipt.BlockNumber, rcpt.Success, rcpt.Cost(), rcpt.Reason)
+	if rcpt.Success {
+		env.ok++
+	}
+}
+
+// prefundAccount sends EntryPoint.depositTo(account) from SAFE for missing (plus headroom)
+// and waits for it to land; false when the deposit could not be made.
+func prefundAccount(ctx context.Context, env *batchEnv, acct erc4337.Account, missing *big.Int, pl *pairLog) bool {
+	value := new(big.Int).Div(new(big.Int).Mul(missing, big.NewInt(100+prefundHeadPct)), big.NewInt(100))
+	tip, maxFee, err := eip7702.PrepareFees(ctx, env.ec, nil)
+	if err != nil {
+		pl.logf("prefund: fee prep error: %v", err)
+		return false
+	}
+	need := new(big.Int).Add(value, new(big.Int).Mul(big.NewInt(prefundGas), maxFee))
+	if have, err := env.ec.BalanceAt(ctx, env.sponsorAddr, nil); err == nil && have.Cmp(need) < 0 {
+		pl.logf("skip: SAFE balance %s wei < prefund %s wei", have.String(), need.String())
+		env.budgetHit = true
+		return false
+	}
+	tx, err := types.SignNewTx(env.safePK, types.LatestSignerForChainID(env.chainID), &types.DynamicFeeTx{
+		ChainID: env.chainID, Nonce: env.nextNonce, GasTipCap: tip, GasFeeCap: maxFee, Gas: prefundGas,
+		To: &acct.EntryPoint, Value: value, Data: acct.DepositToCalldata(),
+	})
+	if err != nil {
+		pl.logf("prefund: sign failed: %v", err)
+		return false
+	}
+	if err := env.ec.SendTransaction(ctx, tx); err != nil {
+		pl.logf("prefund: send failed: %v", err)
+		return false
+	}
+	env.nextNonce++
+	pl.logf("prefund: SAFE depositTo(%s) %s wei: tx %s%s", acct.Address.Hex(), value, tx.Hash().Hex(), explorerSuffix(env.chainID, tx.Hash()))
+	waitStart := time.Now()
+	defer stagetime.Since(stagetime.InclusionWait, waitStart)
+	wctx, cancel := context.WithTimeout(ctx, userOpWait)
+	defer cancel()
+	for {
+		if r, err := env.ec.TransactionReceipt(wctx, tx.Hash()); err == nil {
+			if r.Status != types.ReceiptStatusSuccessful {
+				pl.logf("prefund: deposit reverted")
+				return false
+			}
+			return true
+		}
+		select {
+		case <-wctx.Done():
+			pl.logf("prefund: deposit not mined within %s", userOpWait)
+			return false
+		case <-time.After(userOpPoll):
+		}
+	}
+}
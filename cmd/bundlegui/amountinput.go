@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// amountLocale is AMOUNT_LOCALE: which character is the decimal separator in typed amounts.
+// "dot" = 1,234.5, "comma" = 1.234,5; "auto" (default) accepts both and refuses what only
+// a locale could decide ("1,000" is 1000 in one and 1.0 in the other).
+func amountLocale() string {
+	switch l := strings.ToLower(strings.TrimSpace(os.Getenv("AMOUNT_LOCALE"))); l {
+	case "dot", "comma":
+		return l
+	}
+	return "auto"
+}
+
+// groupSpaces are the thousands separators that are never a decimal point.
+const groupSpaces = "   '’"
+
+// normalizeAmount turns a typed token amount ("1 234,5", "1,234.5", "1.234,5") into the plain
+// "1234.5" that toWeiFromTokens parses. Thousands groups must be 3 digits; exponents, signs
+// and ambiguous separators are errors, so an amount is never silently read 1000× too small.
+// "" and "all" pass through.
+func normalizeAmount(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "all") {
+		return strings.ToLower(s), nil
+	}
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',', strings.ContainsRune(groupSpaces, r):
+		case r == 'e' || r == 'E':
+			return "", errors.New("exponent notation is not accepted — type the digits")
+		case r == '-' || r == '+':
+			return "", errors.New("signs are not accepted")
+		default:
+			return "", fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	for _, r := range groupSpaces {
+		s = strings.ReplaceAll(s, string(r), "_")
+	}
+
+	dec, grp := ".", ","
+	switch amountLocale() {
+	case "comma":
+		dec, grp = ",", "."
+	case "auto":
+		dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+		switch {
+		case dot >= 0 && comma >= 0:
+			if comma > dot {
+				dec, grp = ",", "."
+			}
+		case dot >= 0 || comma >= 0:
+			c := "."
+			if comma >= 0 {
+				c = ","
+			}
+			if strings.Count(s, c) > 1 {
+				dec, grp = map[string]string{".": ",", ",": "."}[c], c
+				break
+			}
+			intPart, frac, _ := strings.Cut(s, c)
+			whole := strings.ReplaceAll(intPart, "_", "")
+			if len(frac) == 3 && !strings.Contains(intPart, "_") && len(whole) >= 1 && len(whole) <= 3 && strings.Trim(whole, "0") != "" {
+				return "", fmt.Errorf("%q is ambiguous (%s or %s.%s?): type %s, or %s.%s0 for the fraction, or set AMOUNT_LOCALE=dot|comma", s, whole+frac, whole, frac, whole+frac, whole, frac)
+			}
+			dec, grp = c, map[string]string{".": ",", ",": "."}[c]
+		}
+	}
+
+	if strings.Count(s, dec) > 1 {
+		return "", fmt.Errorf("more than one decimal separator %q", dec)
+	}
+	intPart, frac, _ := strings.Cut(s, dec)
+	if strings.ContainsAny(frac, grp+"_") {
+		return "", errors.New("thousands separator after the decimal separator")
+	}
+	groups := strings.FieldsFunc(intPart, func(r rune) bool { return string(r) == grp || r == '_' })
+	if len(groups) > 1 || strings.ContainsAny(intPart, grp+"_") {
+		if strings.HasPrefix(intPart, grp) || strings.HasPrefix(intPart, "_") || strings.HasSuffix(intPart, grp) || strings.HasSuffix(intPart, "_") {
+			return "", errors.New("misplaced thousands separator")
+		}
+		for i, g := range groups {
+			if len(g) != 3 && (i > 0 || len(g) > 3) {
+				return "", fmt.Errorf("thousands groups must have 3 digits (%q)", g)
+			}
+		}
+	}
+	whole := strings.Join(groups, "")
+	if whole == "" && frac == "" {
+		return "", errors.New("no digits")
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if frac == "" {
+		return whole, nil
+	}
+	return whole + "." + frac, nil
+}
+
+// amountPreview is the inline feedback under an amount entry: how the text will be read
+// (in base units once decimals are known) or why it cannot be.
+func amountPreview(s string, decimals int) string {
+	norm, err := normalizeAmount(s)
+	switch {
+	case err != nil:
+		return "✗ " + err.Error()
+	case norm == "":
+		return ""
+	case norm == "all":
+		return "= whole balance"
+	case decimals < 0:
+		return "= " + norm + " tokens"
+	}
+	wei, err := toWeiFromTokens(norm, decimals)
+	if err != nil {
+		return "✗ " + err.Error()
+	}
+	return fmt.Sprintf("= %s tokens (%s base units)", formatTokensFromWei(wei, decimals), wei)
+}
+
+// bindAmountPreview validates amountE as the user types and keeps hint showing
+// amountPreview; decimals is read on every change (it may be typed after the amount).
+func bindAmountPreview(amountE, decE *widget.Entry, hint *widget.Label, decimals func() int) {
+	amountE.Validator = func(s string) error {
+		_, err := normalizeAmount(s)
+		return err
+	}
+	update := func(string) { hint.SetText(amountPreview(amountE.Text, decimals())) }
+	amountE.OnChanged = update
+	decE.OnChanged = update
+	update("")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"io"
 	"math/big"
 	"strconv"
@@ -14,13 +13,29 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/queuefile"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
 )
 
-type pairRow struct {
-	Token, From, FromPK, To   string
-	AmountWei, AmountTokens   string
-	Decimals                  int
-	BalanceWei, BalanceTokens string
+type pairRow = queuefile.Pair // the session file layout, shared with batchcli -queue-out
+
+// warningsText is the "Warnings" block of the Check details dialog ("" when none).
+func warningsText(p pairRow) string {
+	if len(p.Warnings) == 0 { return "" }
+	var b strings.Builder
+	b.WriteString("\n\nWarnings:")
+	for _, w := range p.Warnings { b.WriteString("\n - " + string(w.Code)); if w.Detail != "" { b.WriteString(": " + w.Detail) } }
+	return b.String()
+}
+
+// explorerText lists explorer links for the details dialog ("" when the chain has none).
+func explorerText(chain string, p pairRow) string {
+	from, tok := explorer.Address(chain, p.From), explorer.Token(chain, p.Token)
+	if from == "" && tok == "" { return "" }
+	return "\n\nExplorer:\n - from: " + from + "\n - token: " + tok
 }
 
 func mustBig(s string) *big.Int {
@@ -49,8 +64,17 @@ func fetchTokenDecimals(ec *ethclient.Client, token common.Address) (int, error)
 	data := common.FromHex("0x313ce567") // decimals()
 	res, err := ec.CallContract(context.Background(), ethereum.CallMsg{To: &token, Data: data}, nil)
 	if err != nil { return 0, err }
-	if len(res)==0 { return 18, nil }
-	return int(res[len(res)-1]), nil
+	return units.DecodeDecimals(res)
+}
+// fetchTokenDecimalsProxy is fetchTokenDecimals that, when decimals() fails on an EIP-1967
+// proxy, reads it from the implementation and notes proxy(impl=…) in warns.
+func fetchTokenDecimalsProxy(ec *ethclient.Client, token common.Address, warns *warnings.List) (int, error) {
+	dec, err := fetchTokenDecimals(ec, token)
+	if err == nil { return dec, nil }
+	impl, perr := core.ProxyImplementation(context.Background(), ec, token)
+	if perr != nil || impl == (common.Address{}) { return 0, err }
+	warns.Add(warnings.Proxy, "proxy(impl="+impl.Hex()+")")
+	return fetchTokenDecimals(ec, impl)
 }
 func fetchTokenBalance(ec *ethclient.Client, token common.Address, owner common.Address) (*big.Int, error) {
 	data := append(common.FromHex("0x70a08231"), common.LeftPadBytes(owner.Bytes(),32)...)
@@ -61,39 +85,10 @@ func fetchTokenBalance(ec *ethclient.Client, token common.Address, owner common.
 }
 
 func toWeiFromTokens(amount string, decimals int) (*big.Int, error) {
-	amount = strings.TrimSpace(amount)
-	if amount == "" { return nil, fmt.Errorf("empty amount") }
-	if decimals < 0 { decimals = 18 }
-	parts := strings.SplitN(amount, ".", 2)
-	intPart := parts[0]
-	fracPart := ""
-	if len(parts) == 2 { fracPart = parts[1] }
-	if len(fracPart) > decimals { return nil, fmt.Errorf("too many fractional digits for %d decimals", decimals) }
-	fracPart = fracPart + strings.Repeat("0", decimals-len(fracPart))
-	clean := strings.TrimLeft(intPart+fracPart, "0")
-	if clean == "" { return big.NewInt(0), nil }
-	v, ok := new(big.Int).SetString(clean, 10)
-	if !ok { return nil, fmt.Errorf("bad amount") }
-	return v, nil
+	return units.Parse(amount, decimals)
 }
 func formatTokensFromWei(v *big.Int, decimals int) string {
-	if v==nil { return "0" }
-	if decimals<=0 { return v.String() }
-	s := new(big.Int).Abs(v).String()
-	neg := v.Sign() < 0
-	if len(s) <= decimals {
-		frac := strings.Repeat("0", decimals-len(s)) + s
-		out := "0." + strings.TrimRight(frac, "0")
-		if out == "0." { out = "0" }
-		if neg { return "-" + out }
-		return out
-	}
-	intPart := s[:len(s)-decimals]
-	frac := strings.TrimRight(s[len(s)-decimals:], "0")
-	out := intPart
-	if frac != "" { out = intPart + "." + frac }
-	if neg { return "-" + out }
-	return out
+	return units.Format(v, decimals, units.Full)
 }
 
 // import/export
@@ -112,6 +107,8 @@ func parseCSVAll(rd io.Reader) ([]pairRow, error) {
 			p := pairRow{
 				Token: get(row,"token"), From: get(row,"from"), FromPK:get(row,"frompk"), To:get(row,"to"),
 				AmountWei:get(row,"amountwei"), AmountTokens:get(row,"amount"), Decimals:-1,
+				BalanceWei:get(row,"balancewei"), BalanceTokens:get(row,"balancetokens"),
+				Warnings: warnings.Parse(get(row,"warnings"), get(row,"warningdetails")),
 			}
 			if d := get(row,"decimals"); d!="" { if n,err := strconv.Atoi(d); err==nil { p.Decimals = n } }
 			if p.Token=="" && p.FromPK=="" && p.To=="" { continue }
@@ -132,7 +129,7 @@ func parseJSONAll(rd io.Reader) ([]pairRow, error) {
 	if err := json.Unmarshal(b, &arr); err != nil { return nil, err }
 	var out []pairRow
 	for _, m := range arr {
-		p := pairRow{ Token:m["token"], From:m["from"], FromPK:m["fromPk"], To:m["to"], AmountWei:m["amountWei"], AmountTokens:m["amount"], Decimals:-1 }
+		p := pairRow{ Token:m["token"], From:m["from"], FromPK:m["fromPk"], To:m["to"], AmountWei:m["amountWei"], AmountTokens:m["amount"], Decimals:-1, BalanceWei:m["balanceWei"], BalanceTokens:m["balanceTokens"] }
 		if d := strings.TrimSpace(m["decimals"]); d!="" { if n,err := strconv.Atoi(d); err==nil { p.Decimals = n } }
 		if p.Token=="" && p.FromPK=="" && p.To=="" { continue }
 		out = append(out, p)
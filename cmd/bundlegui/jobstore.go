@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+)
+
+// jobStoreFile keeps one JSON line per processed pair, across all runs.
+const jobStoreFile = "jobs_history.jsonl"
+
+// JobRecord is the persisted outcome of one pair within one run.
+type JobRecord struct {
+	RunID     string          `json:"runId"`
+	Time      string          `json:"time"`
+	Campaign  string          `json:"campaign"`
+	Mode      string          `json:"mode"` // simulate | run
+	PairIndex int             `json:"pairIndex"`
+	Token     string          `json:"token"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Status    string          `json:"status"`
+	Operator   string         `json:"operator,omitempty"`   // who ran it (simulate or run)
+	ImportedBy string         `json:"importedBy,omitempty"` // who queued the pair
+	ApprovedBy string         `json:"approvedBy,omitempty"` // who confirmed the risk gate
+	Approval   string         `json:"approval,omitempty"`   // what was confirmed: risk gate and/or triage
+	Reason    string          `json:"reason,omitempty"`
+	Log       []string        `json:"log,omitempty"`
+	Relays    []TelemetryItem `json:"relays,omitempty"`
+	SentTo    []string        `json:"sentTo,omitempty"`    // relay hosts of a sending run (RELAYS)
+	Builder   string          `json:"builder,omitempty"`   // builder of the inclusion block ("" = unknown)
+	WinRelays []string        `json:"winRelays,omitempty"` // relay hosts credited with the inclusion
+}
+
+var jobMu sync.Mutex
+
+// jobAppend appends a record to the job store; errors are ignored like saveQueueToFile.
+func jobAppend(rec JobRecord) {
+	jobMu.Lock()
+	defer jobMu.Unlock()
+	f, err := os.OpenFile(jobStoreFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_ = json.NewEncoder(f).Encode(rec)
+}
+
+// loadJobs reads all records, newest first. Broken lines are skipped.
+func loadJobs() []JobRecord {
+	jobMu.Lock()
+	defer jobMu.Unlock()
+	f, err := os.Open(jobStoreFile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var out []JobRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var rec JobRecord
+		if json.Unmarshal(sc.Bytes(), &rec) == nil {
+			out = append(out, rec)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// relayWins is the record of one relay over the sending runs of the job store.
+type relayWins struct {
+	Relay      string
+	Runs, Wins int
+}
+
+// Rate is Wins / Runs.
+func (r relayWins) Rate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Runs)
+}
+
+// relayWinRates counts, per relay host, the sending runs it took part in and the inclusions
+// credited to it (winner attribution), best win rate first: the suggested RELAYS order.
+func relayWinRates(recs []JobRecord) []relayWins {
+	idx := map[string]*relayWins{}
+	for _, r := range recs {
+		if r.Mode != "run" {
+			continue
+		}
+		for _, h := range r.SentTo {
+			if idx[h] == nil {
+				idx[h] = &relayWins{Relay: h}
+			}
+			idx[h].Runs++
+		}
+		for _, h := range r.WinRelays {
+			if idx[h] == nil {
+				idx[h] = &relayWins{Relay: h, Runs: 1}
+			}
+			idx[h].Wins++
+		}
+	}
+	out := make([]relayWins, 0, len(idx))
+	for _, w := range idx {
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rate() != out[j].Rate() {
+			return out[i].Rate() > out[j].Rate()
+		}
+		if out[i].Wins != out[j].Wins {
+			return out[i].Wins > out[j].Wins
+		}
+		return out[i].Relay < out[j].Relay
+	})
+	return out
+}
@@ -8,6 +8,7 @@ import (
 	"math/big"
   "net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -15,26 +16,31 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/crypto"
-  "github.com/ethereum/go-ethereum/rpc"
 	ethereum "github.com/ethereum/go-ethereum"
-	"github.com/joho/godotenv"
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/config"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/profile"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
-// newEthClientWithTimeout dials RPC with keep-alives and sane timeouts.
+// newEthClientWithTimeout dials RPC with keep-alives and sane timeouts (ws:// / wss:// too).
 func newEthClientWithTimeout(rpcURL string) (*ethclient.Client, error) {
 	transport := &http.Transport{ MaxIdleConns: 100, IdleConnTimeout: 90 * time.Second, DisableCompression: false }
 	httpClient := &http.Client{ Timeout: 30 * time.Second, Transport: transport }
-	rpcClient, err := rpc.DialHTTPWithClient(rpcURL, httpClient)
+	rpcClient, err := rpcdial.Dial(context.Background(), rpcURL, httpClient)
 	if err != nil { return nil, err }
 	return ethclient.NewClient(rpcClient), nil
 }
@@ -46,32 +52,56 @@ var (
 	pairStatus   []string   // per-row status: "", PENDING, FAILED, COMPLETED
 	pairCheckS   []string   // short check text for row
 	pairCheckD   []string   // details text for dialog
+	undoBtn      *widget.Button
 )
 
+// refreshUndo updates the UNDO button after the trash changed.
+func refreshUndo() {
+	if undoBtn != nil { undoBtn.SetText(undoText()) }
+}
+
 func main() {
 	hideConsoleWindow()
 
-	_ = godotenv.Load()
-	_ = godotenv.Overload(".env.local")
+	profileName, _ := profile.Extract(os.Args[1:])
+	prof, profErr := profile.Load(profileName)
+	guiProfile, sessionFile = prof, prof.Path(sessionFile)
+	if err := explorer.LoadEnv(); err != nil { fmt.Fprintln(os.Stderr, err) }
+	if err := core.LoadSelectorEnv(guiProfile.Path(core.DefaultSelectorFile)); err != nil { fmt.Fprintln(os.Stderr, err) }
+	if err := core.LoadBuilderEnv(guiProfile.Path(core.DefaultBuilderFile)); err != nil { fmt.Fprintln(os.Stderr, err) }
+	// Prometheus /metrics of the runs (METRICS_ADDR, same as bundlecli)
+	if addr := strings.TrimSpace(os.Getenv("METRICS_ADDR")); addr != "" {
+		if _, err := core.ServeMetrics(addr); err != nil { fmt.Fprintln(os.Stderr, err) }
+	}
 
 	a := app.New()
 	curTheme := makeTheme("dark", false)
 	a.Settings().SetTheme(curTheme)
 
-	w := a.NewWindow("Bundle Rescue")
+	if profErr != nil {
+		// No fallback to the root .env: it may belong to another client.
+		ew := a.NewWindow("Bundle Rescue — profile error")
+		ew.SetContent(container.NewPadded(widget.NewLabel(profErr.Error())))
+		ew.Resize(fyne.NewSize(640, 140))
+		ew.ShowAndRun()
+		return
+	}
+
+	w := a.NewWindow(windowTitle("Bundle Rescue"))
 	w.SetOnClosed(func(){
 		if viewWin != nil { viewWin.Close(); viewWin = nil }
 		if logWin  != nil { logWin.Close();  logWin  = nil }
+		if walletWin != nil { walletWin.Close() }
 		closeAddPairWindows()
 	})
 	loadQueueFromFile()
 	w.Resize(fyne.NewSize(1180, 760))
 
-	rpcEntry := widget.NewEntry(); rpcEntry.SetText(os.Getenv("RPC_URL"))
+	rpcEntry := widget.NewEntry(); rpcEntry.SetText(envSecret("RPC_URL"))
 	chainEntry := widget.NewEntry(); chainEntry.SetText(defaultStr(os.Getenv("CHAIN_ID"), "1"))
 	relaysEntry := widget.NewEntry(); relaysEntry.SetText(defaultStr(os.Getenv("RELAYS"), "https://relay.flashbots.net"))
-	authPkEntry := widget.NewPasswordEntry(); authPkEntry.SetText(os.Getenv("FLASHBOTS_AUTH_PK"))
-	safePkEntry := widget.NewPasswordEntry(); safePkEntry.SetText(os.Getenv("SAFE_PRIVATE_KEY"))
+	authPkEntry := widget.NewPasswordEntry(); authPkEntry.SetText(envSecret("FLASHBOTS_AUTH_PK"))
+	safePkEntry := widget.NewPasswordEntry(); safePkEntry.SetText(envSecret("SAFE_PRIVATE_KEY"))
 
 	useEnvGlobals := widget.NewCheck("Use .env globals (lock)", func(b bool){
 		rpcEntry.Disable(); chainEntry.Disable(); relaysEntry.Disable(); authPkEntry.Disable(); safePkEntry.Disable()
@@ -95,6 +125,13 @@ func main() {
 		curTheme = makeTheme(curTheme.(*appTheme).mode, b)
 		a.Settings().SetTheme(curTheme)
 	})
+	// Privacy display: balances/amounts on screen become magnitude buckets; files keep full precision.
+	privacyCheck := widget.NewCheck("Privacy", func(b bool){
+		privacy.Enable(b); a.Preferences().SetBool("privacyDisplay", b)
+		if pairsTable != nil { pairsTable.Refresh() }
+		if table != nil { table.Refresh() }
+	})
+	privacyCheck.SetChecked(os.Getenv("PRIVACY_DISPLAY") == "1" || a.Preferences().Bool("privacyDisplay"))
 
 	// Read-only fields: Delegate & SAFE_ADDRESS (без bindReadOnly)
 	delegateEntry := widget.NewEntry()
@@ -107,15 +144,26 @@ func main() {
 		if v, err := deriveAddrFromPK(strings.TrimSpace(s)); err == nil { safeAddrEntry.SetText(v) } else { safeAddrEntry.SetText("") }
 	}
 
+	// Test buttons: per-endpoint diagnostics (see ui_probe.go)
+	testRPCBtn := widget.NewButton("Test", func(){
+		rpcURL, chain := strings.TrimSpace(rpcEntry.Text), strings.TrimSpace(chainEntry.Text)
+		runProbe(w, "RPC test", func() []probeRow { return probeRPC(rpcURL, chain) })
+	})
+	testRelaysBtn := widget.NewButton("Test", func(){
+		rpcURL, chain, relays := strings.TrimSpace(rpcEntry.Text), strings.TrimSpace(chainEntry.Text), relaysEntry.Text
+		auth, safe := authPkEntry.Text, safePkEntry.Text
+		runProbe(w, "Relays test", func() []probeRow { return probeRelays(rpcURL, chain, relays, auth, safe) })
+	})
+
 	globalsCard := widget.NewCard("Globals", "", widget.NewForm(
-		widget.NewFormItem("RPC URL", rpcEntry),
+		widget.NewFormItem("RPC URL", container.NewBorder(nil, nil, nil, testRPCBtn, rpcEntry)),
 		widget.NewFormItem("Chain ID", chainEntry),
-		widget.NewFormItem("Relays", relaysEntry),
+		widget.NewFormItem("Relays", container.NewBorder(nil, nil, nil, testRelaysBtn, relaysEntry)),
 		widget.NewFormItem("Auth PK", authPkEntry),
 		widget.NewFormItem("Delegate (7702)", delegateEntry),
 		widget.NewFormItem("Safe PK", safePkEntry),
 		widget.NewFormItem("SAFE_ADDRESS", safeAddrEntry),
-		widget.NewFormItem("", container.NewGridWithColumns(3, useEnvGlobals, themeSelect, compactCheck)),
+		widget.NewFormItem("", container.NewGridWithColumns(4, useEnvGlobals, themeSelect, compactCheck, privacyCheck)),
 	))
 
 	strategyCard := widget.NewCard("Strategy", "", widget.NewForm(
@@ -125,6 +173,7 @@ func main() {
 		widget.NewFormItem("BaseFee ×", baseMul),
 		widget.NewFormItem("Buffer %", buffer),
 	))
+	projectionCard := newProjectionCard(blocks, tip, tipMul, baseMul, buffer)
 	
 	// ---------- Imported Pairs (full-height list) ----------
 	// state arrays declared at package level (used in ui_run.go too)
@@ -200,7 +249,7 @@ func main() {
 			case 2:
 				lbl.Show(); lbl.TextStyle = fyne.TextStyle{Monospace: true}; lbl.SetText(pr.Token)
 			case 3:
-				lbl.Show(); lbl.SetText(formatTokFromWei(pr.BalanceWei, pr.Decimals))
+				lbl.Show(); lbl.SetText(privacy.Amount(formatTokFromWei(pr.BalanceWei, pr.Decimals)))
 			case 4:
 				// short + details button
 				lbl.Show()
@@ -210,13 +259,15 @@ func main() {
 					} else {
 						pairCheckS[row] = "OK"
 					}
-					pairCheckD[row] = fmt.Sprintf("From: %s\nToken: %s\nDecimals: %d\nBalance (wei): %s",
+					pairCheckD[row] = fmt.Sprintf("From: %s\nToken: %s\nDecimals: %d\nBalance: %s wei",
 						pr.From, pr.Token, pr.Decimals, pr.BalanceWei)
 				}
-				lbl.SetText(pairCheckS[row])
+				chk := pairCheckS[row]
+				if n := len(pr.Warnings); n > 0 { chk += fmt.Sprintf(" (%d warn)", n) }
+				lbl.SetText(chk)
 				btn.Show()
 				btn.OnTapped = func() {
-					dialog.ShowInformation("Check details", pairCheckD[row], w)
+					dialog.ShowInformation("Check details", privacy.Line(pairCheckD[row])+warningsText(pairs[row])+explorerText(strings.TrimSpace(chainEntry.Text), pairs[row]), w)
 				}
 			case 5:
 				// scenario selector
@@ -291,12 +342,8 @@ func main() {
 				del.OnTapped = func() {
 					i := row
 					if i < 0 || i >= len(pairs) { return }
-					pairs = append(pairs[:i], pairs[i+1:]...)
-					if i < len(pairScenario) { pairScenario = append(pairScenario[:i], pairScenario[i+1:]...) }
-					if i < len(pairStatus)   { pairStatus   = append(pairStatus[:i],   pairStatus[i+1:]...) }
-					if i < len(pairCheckS)   { pairCheckS   = append(pairCheckS[:i],   pairCheckS[i+1:]...) }
-					if i < len(pairCheckD)   { pairCheckD   = append(pairCheckD[:i],   pairCheckD[i+1:]...) }
-					saveQueueToFile()
+					removePairs("delete row", []int{i})
+					refreshUndo()
 					pairsTable.Refresh()
 				}
 			}
@@ -362,6 +409,8 @@ func main() {
 				fmt.Sprintf("[net] baseFee: %.2f gwei · tip: %.2f gwei · gas(≈40766): fixed=%.6f ETH, peak=%.6f ETH",
 					baseGwei, tipGwei, fixedEth, peakEth),
 			)
+			projBaseGwei = baseGwei
+			refreshProjection()
 		}()
 	}	
 
@@ -370,33 +419,45 @@ func main() {
 		cb := func(rc fyne.URIReadCloser, err error){
 			if err!=nil || rc==nil { return }
 			defer rc.Close()
-			ext := strings.ToLower(rc.URI().Extension())
+			// .csv.gz / .zip: decompressed while reading, the list's own name decides the format
+			in, inner, derr := config.Decompress(rc)
+			if derr!=nil { dialog.ShowInformation("Import", derr.Error(), w); return }
+			listName := config.ListName(rc.URI().Name(), inner)
+			ext := strings.ToLower(filepath.Ext(listName))
 			var ps []pairRow
 			if ext==".txt" || ext=="" {
 				// Each line: "<fromPrivKey> <tokenAddress>"
 				ec, e := newEthClientWithTimeout(rpcEntry.Text); if e!=nil { dialog.ShowInformation("Import", "RPC dial error: "+e.Error(), w); return }
-				for scanner := bufio.NewScanner(rc); scanner.Scan(); {
+				for scanner := bufio.NewScanner(in); scanner.Scan(); {
 					line := strings.TrimSpace(scanner.Text()); if line=="" || strings.HasPrefix(line,"#") { continue }
 					parts := strings.Fields(line); if len(parts) < 2 { continue }
 					fromPK := parts[0]; token := strings.ToLower(parts[1])
 					fromAddr, derr := deriveAddrFromPK(fromPK); if derr!=nil { continue }
-					dec := 18; if d, e := fetchTokenDecimals(ec, common.HexToAddress(token)); e==nil { dec = d }
+					var warns warnings.List
+					dec := 18; if d, e := fetchTokenDecimalsProxy(ec, common.HexToAddress(token), &warns); e==nil { dec = d } else { warns.Add(warnings.DecimalsFallback, "decimals() failed, 18 assumed: "+e.Error()) }
 					balWei := big.NewInt(0); if b, e := fetchTokenBalance(ec, common.HexToAddress(token), common.HexToAddress(fromAddr)); e==nil { balWei = b }
 					toAddr := ""; if v, err := deriveAddrFromPK(strings.TrimSpace(safePkEntry.Text)); err==nil { toAddr = v }
-					ps = append(ps, pairRow{ Token: token, From: strings.ToLower(fromAddr), FromPK: fromPK, To: toAddr, Decimals: dec, AmountWei: balWei.String(), BalanceWei: balWei.String() })
+					ps = append(ps, pairRow{ Token: token, From: strings.ToLower(fromAddr), FromPK: fromPK, To: toAddr, Decimals: dec, AmountWei: balWei.String(), BalanceWei: balWei.String(), Warnings: warns })
 				}
 			} else if ext==".csv" {
-				if arr, e := parseCSVAll(rc); e==nil { ps = arr }
+				if arr, e := parseCSVAll(in); e==nil { ps = arr }
 			} else if ext==".json" {
-				if arr, e := parseJSONAll(rc); e==nil { ps = arr }
+				if arr, e := parseJSONAll(in); e==nil { ps = arr }
 			} else {
-				dialog.ShowInformation("Import", `Use .txt ("<privKey> <token>") or CSV/JSON`, w); return
+				dialog.ShowInformation("Import", `Use .txt ("<privKey> <token>") or CSV/JSON (plain, .gz or .zip)`, w); return
 			}
 			if len(ps)==0 { return }
+			campaign := strings.TrimSuffix(listName, filepath.Ext(listName))
+			for k := range ps { ps[k].Campaign = campaign }
 			start := len(pairs)
-			pairs = append(pairs, ps...)
+			var merged []string
+			if keepDuplicates() { ps = appendToQueue(ps) } else { ps, merged = mergeIntoQueue(ps) }
 			statsAdded += len(ps)
 			saveQueueToFile()
+			if len(merged) > 0 {
+				for _, m := range merged { appendLogLine(a, "[dedupe] "+m) }
+				dialog.ShowInformation("Import", dedupeReport(merged), w)
+			}
 			// init Ui-side arrays for new rows
 			for i:=0; i<len(ps); i++ {
 				pairScenario = append(pairScenario, "")
@@ -408,7 +469,7 @@ func main() {
 				} else {
 					pairCheckS = append(pairCheckS, "OK")
 				}
-				pairCheckD = append(pairCheckD, fmt.Sprintf("From: %s\nToken: %s\nDecimals: %d\nBalance (wei): %s",
+				pairCheckD = append(pairCheckD, fmt.Sprintf("From: %s\nToken: %s\nDecimals: %d\nBalance: %s wei",
 					pr.From, pr.Token, pr.Decimals, pr.BalanceWei))
 			}
 			pairsTable.Refresh() // refresh list
@@ -480,40 +541,59 @@ func main() {
 		fd.Show()
 	})
 
-	buttons := container.NewGridWithColumns(2, importBtn, widget.NewButton("REMOVE NON-TRANSFERABLE", func(){
-		var keep []pairRow
-		var keepSc, keepSt, keepS, keepD []string
-		for _,pr := range pairs {
-			if strings.TrimSpace(pr.BalanceWei)!="0" && strings.TrimSpace(pr.BalanceWei)!="" {
-				keep = append(keep, pr)
-				idx := len(keep)-1
-				// переносим параллельные массивы
-				if len(pairScenario) > idx { keepSc = append(keepSc, pairScenario[idx]) } else { keepSc = append(keepSc, "") }
-				if len(pairStatus)   > idx { keepSt = append(keepSt, pairStatus[idx])   } else { keepSt = append(keepSt, "") }
-				if len(pairCheckS)   > idx { keepS  = append(keepS,  pairCheckS[idx])   } else { keepS  = append(keepS,  "") }
-				if len(pairCheckD)   > idx { keepD  = append(keepD,  pairCheckD[idx])   } else { keepD  = append(keepD,  "") }
-			}
+	// UNDO / TRASH: destructive actions go to the trash in the session file (see trash.go)
+	undoBtn = widget.NewButtonWithIcon(undoText(), theme.ContentUndoIcon(), func(){
+		if op, ok := undoLast(); ok {
+			appendLogLine(a, "[trash] undo: "+opSummary(op))
+			refreshUndo()
+			pairsTable.Refresh()
+		}
+	})
+	trashBtn := widget.NewButtonWithIcon("TRASH", theme.DeleteIcon(), func(){
+		openTrashWindow(a, func(){ refreshUndo(); pairsTable.Refresh() })
+	})
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut){ undoBtn.OnTapped() })
+	buttons := container.NewGridWithColumns(4, importBtn, widget.NewButton("REMOVE NON-TRANSFERABLE", func(){
+		var drop []int
+		for i, pr := range pairs {
+			if strings.TrimSpace(pr.BalanceWei)=="0" || strings.TrimSpace(pr.BalanceWei)=="" { drop = append(drop, i) }
 		}
-		pairs = keep
-		pairScenario, pairStatus, pairCheckS, pairCheckD = keepSc, keepSt, keepS, keepD
-		saveQueueToFile()
+		removePairs("remove non-transferable", drop)
+		refreshUndo()
 		pairsTable.Refresh() // refresh list
-	}))
-
-	resBtn := widget.NewButtonWithIcon("RESCUE",   theme.ConfirmIcon(),   func(){
-        go runAll(a, false,
-            rpcEntry.Text, chainEntry.Text, relaysEntry.Text,
-            authPkEntry.Text, safePkEntry.Text,
-            blocks.Text, tip.Text, tipMul.Text, baseMul.Text, buffer.Text,
-        )
-    })
-	runRow := container.NewGridWithColumns(2,
+	}), undoBtn, trashBtn)
+
+	startRun := func(only func(pairRow) bool) {
+		if gOperator == "" { dialog.ShowInformation("Operator", "Log in first (OPERATOR_AUTH=password)", w); return }
+		if err := checkProfileChain(chainEntry.Text); err != nil { dialog.ShowError(err, w); return }
+		only, note, err := triageFilter(only)
+		if err != nil { dialog.ShowError(fmt.Errorf("TRIAGE_FILE: %w", err), w); return }
+		if note != "" { appendLogLine(a, note) }
+		confirmRisk(w, only, rpcEntry.Text, chainEntry.Text, relaysEntry.Text, safePkEntry.Text, func(gate string){
+			go runAll(a, false, only, newRunApproval(gate),
+				rpcEntry.Text, chainEntry.Text, relaysEntry.Text,
+				authPkEntry.Text, safePkEntry.Text,
+				blocks.Text, tip.Text, tipMul.Text, baseMul.Text, buffer.Text,
+			)
+		})
+	}
+	resBtn := widget.NewButtonWithIcon("RESCUE",   theme.ConfirmIcon(),   func(){ startRun(nil) })
+	walletsBtn := widget.NewButtonWithIcon("WALLETS", theme.ListIcon(), func(){
+		openWalletsWindow(a, func() string { return rpcEntry.Text }, func(from string){
+			startRun(func(p pairRow) bool { return strings.EqualFold(strings.TrimSpace(p.From), strings.TrimSpace(from)) })
+		})
+	})
+	runRow := container.NewGridWithColumns(5,
 		widget.NewButton("UPDATE NETWORK", func(){ updateNetwork() }),
+		widget.NewButtonWithIcon("HISTORY", theme.HistoryIcon(), func(){ openHistoryWindow(a) }),
+		widget.NewButtonWithIcon("RESULTS", theme.VisibilityIcon(), func(){ openResultsWindow(a) }),
+		walletsBtn,
 		resBtn,
 	)
 
     // layout: top (globals+strategy+buttons+run) and center (pairs list) to occupy the remaining height
-    top := container.NewVBox(globalsCard, strategyCard, buttons, runRow)
+    top := container.NewVBox(globalsCard, strategyCard, projectionCard, buttons, runRow)
+    if b := profileBanner(); b != nil { top.Objects = append([]fyne.CanvasObject{b}, top.Objects...) }
     center := importedPairsCard
     bg := canvas.NewLinearGradient(color.NRGBA{12,16,24,255}, color.NRGBA{20,28,40,255}, 90)
     w.SetContent(
@@ -523,6 +603,7 @@ func main() {
         ),
     )
 	updateNetwork()
+	requireOperator(a, w)
 	w.ShowAndRun()
 }
 
@@ -711,3 +792,5 @@ func guardChecks(ctx context.Context, ec *ethclient.Client, token, from, to comm
 }
 
 func defaultStr(v, d string) string { if strings.TrimSpace(v)=="" { return d }; return v }
+// envSecret reads an env value, expanding env:NAME / file:/path references; unresolvable => "".
+func envSecret(k string) string { v, err := config.ResolveSecret(os.Getenv(k)); if err != nil { return "" }; return v }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pairKey identifies one logical pair: (from, token), case-insensitive.
+// FROM is derived from FromPK when the row does not carry it yet.
+func pairKey(p pairRow) string {
+	from := strings.TrimSpace(p.From)
+	if from == "" {
+		if v, err := deriveAddrFromPK(p.FromPK); err == nil {
+			from = v
+		}
+	}
+	return strings.ToLower(from) + "|" + strings.ToLower(strings.TrimSpace(p.Token))
+}
+
+// addSource records where a pair came from (import file name / "manual").
+func addSource(p *pairRow, src string) {
+	src = defaultStr(src, "manual")
+	for _, s := range p.Sources {
+		if s == src {
+			return
+		}
+	}
+	p.Sources = append(p.Sources, src)
+}
+
+// mergeIntoQueue appends incoming pairs to the queue, folding duplicates by (from, token)
+// into the existing row instead of queueing them twice (which would race on the same nonce).
+// The incoming balance wins when present since it was read just now. Returns the rows that
+// were really appended and one report line per merged duplicate.
+func mergeIntoQueue(incoming []pairRow) (added []pairRow, merged []string) {
+	idx := make(map[string]int, len(pairs))
+	for i := range pairs {
+		idx[pairKey(pairs[i])] = i
+	}
+	for _, in := range incoming {
+		addSource(&in, in.Campaign)
+		stampImport(&in)
+		k := pairKey(in)
+		j, dup := idx[k]
+		if !dup {
+			pairs = append(pairs, in)
+			idx[k] = len(pairs) - 1
+			added = append(added, in)
+			continue
+		}
+		cur := &pairs[j]
+		merged = append(merged, fmt.Sprintf("%s / %s: %s + %s", shortAddr(cur.From), shortAddr(cur.Token),
+			strings.Join(cur.Sources, ", "), defaultStr(in.Campaign, "manual")))
+		if strings.TrimSpace(in.BalanceWei) != "" {
+			cur.BalanceWei, cur.BalanceTokens = in.BalanceWei, in.BalanceTokens
+			if strings.TrimSpace(in.AmountWei) != "" {
+				cur.AmountWei, cur.AmountTokens = in.AmountWei, in.AmountTokens
+			}
+		}
+		if cur.From == "" {
+			cur.From = in.From
+		}
+		if cur.FromPK == "" {
+			cur.FromPK = in.FromPK
+		}
+		if in.Decimals >= 0 && cur.Decimals < 0 {
+			cur.Decimals = in.Decimals
+		}
+		for _, s := range in.Sources {
+			addSource(cur, s)
+		}
+	}
+	return added, merged
+}
+
+// keepDuplicates is IMPORT_DUPLICATES=keep: imports queue every row, even repeated pairs
+// (each copy is sent on its own; they race on FROM's nonce).
+func keepDuplicates() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("IMPORT_DUPLICATES")), "keep")
+}
+
+// appendToQueue is mergeIntoQueue without folding (IMPORT_DUPLICATES=keep).
+func appendToQueue(incoming []pairRow) []pairRow {
+	for i := range incoming {
+		addSource(&incoming[i], incoming[i].Campaign)
+		stampImport(&incoming[i])
+	}
+	pairs = append(pairs, incoming...)
+	return incoming
+}
+
+// dedupeReport is the import dialog text for the merged duplicates (first 20 listed).
+func dedupeReport(merged []string) string {
+	const max = 20
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d duplicate pair(s) merged by (from, token) — balances are not counted twice:\n", len(merged))
+	for i, m := range merged {
+		if i == max {
+			fmt.Fprintf(&b, "… and %d more (see log)\n", len(merged)-max)
+			break
+		}
+		b.WriteString(m + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/ligun0805/bundle-rescue/internal/operators"
+)
+
+// Operator identity for the audit trail: every pair records who imported it, who confirmed
+// the risk gate and who ran it (JobRecord). OPERATOR_AUTH selects where the name comes from:
+//
+//	os        (default) the logged-in OS user, no prompt
+//	password  a login dialog against OPERATORS_FILE (operators.json in the profile
+//	          directory; accounts are managed with `bundlecli operators`)
+const (
+	authOS       = "os"
+	authPassword = "password"
+)
+
+// gOperator is the operator of this session ("" until logged in).
+var gOperator string
+
+// operatorAuth reads OPERATOR_AUTH.
+func operatorAuth() (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv("OPERATOR_AUTH"))); v {
+	case "", authOS:
+		return authOS, nil
+	case authPassword:
+		return authPassword, nil
+	default:
+		return "", fmt.Errorf("OPERATOR_AUTH=%q: expected os or password", v)
+	}
+}
+
+// operatorsFile is OPERATORS_FILE, else operators.json in the profile directory.
+func operatorsFile() string {
+	if v := strings.TrimSpace(os.Getenv("OPERATORS_FILE")); v != "" {
+		return v
+	}
+	return guiProfile.Path(operators.DefaultFile)
+}
+
+// requireOperator sets gOperator: the OS user, or after a login over w (which stays
+// blocked by the modal dialog until then). Cancel quits the app.
+func requireOperator(a fyne.App, w fyne.Window) {
+	mode, err := operatorAuth()
+	if err == nil && mode == authOS {
+		gOperator = defaultStr(operators.OSUser(), "unknown")
+		w.SetTitle(windowTitle("Bundle Rescue") + " — " + gOperator)
+		return
+	}
+	var store *operators.Store
+	if err == nil {
+		store, err = operators.Load(operatorsFile())
+	}
+	if err == nil && len(store.Names()) == 0 {
+		err = fmt.Errorf("OPERATOR_AUTH=password but %s has no operators; add one with: bundlecli operators add NAME", operatorsFile())
+	}
+	if err != nil {
+		d := dialog.NewCustom("Operator login", "Quit", widget.NewLabel(err.Error()), w)
+		d.SetOnClosed(a.Quit)
+		d.Show()
+		return
+	}
+	var show func(msg string)
+	show = func(msg string) {
+		name := widget.NewEntry()
+		pass := widget.NewPasswordEntry()
+		items := []*widget.FormItem{widget.NewFormItem("Operator", name), widget.NewFormItem("Password", pass)}
+		if msg != "" {
+			items = append([]*widget.FormItem{widget.NewFormItem("", widget.NewLabel(msg))}, items...)
+		}
+		d := dialog.NewForm("Operator login", "Log in", "Quit", items, func(ok bool) {
+			if !ok {
+				a.Quit()
+				return
+			}
+			who, err := store.Verify(name.Text, strings.TrimSpace(pass.Text))
+			if err != nil {
+				if !errors.Is(err, operators.ErrLogin) {
+					appendLogLine(a, "[operator] "+err.Error())
+				}
+				time.Sleep(time.Second) // slow down guessing
+				show(err.Error())
+				return
+			}
+			gOperator = who
+			w.SetTitle(windowTitle("Bundle Rescue") + " — " + who)
+			appendLogLine(a, "[operator] logged in: "+who)
+		}, w)
+		d.Resize(fyne.NewSize(420, 220))
+		d.Show()
+	}
+	show("")
+}
+
+// runApproval is what cleared a run for execution: By confirmed the risk gate (What says
+// how); pairs approved in TRIAGE_FILE add "triage approved" (the mark's note says by whom).
+type runApproval struct{ By, What string }
+
+func newRunApproval(gate string) runApproval {
+	var r runApproval
+	if gate != "" {
+		r.By, r.What = gOperator, gate
+	}
+	if strings.TrimSpace(os.Getenv("TRIAGE_FILE")) != "" {
+		r.What = strings.TrimPrefix(r.What+"; triage approved", "; ")
+	}
+	return r
+}
+
+// stampImport records the current operator as the importer of a newly queued pair.
+func stampImport(p *pairRow) {
+	if p.ImportedBy == "" {
+		p.ImportedBy = gOperator
+		p.ImportedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+}
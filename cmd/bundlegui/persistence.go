@@ -1,25 +1,44 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
+
+	"github.com/ligun0805/bundle-rescue/internal/queuefile"
 )
 
-const sessionFile = "pairs_session.json"
+// sessionFile is pairs_session.json, inside the profile directory with --profile.
+var sessionFile = queuefile.DefaultFile
+
+// sessionState is the session file: the queue plus the trash of undoable removals.
+// Older session files are a bare JSON array of pairs; loadQueueFromFile reads both.
+type sessionState struct {
+	Pairs []pairRow
+	Trash []trashOp `json:",omitempty"`
+}
 
 func saveQueueToFile() {
+	refreshProjection() // every queue mutation ends here
 	f, err := os.Create(sessionFile)
 	if err != nil { return }
 	defer f.Close()
-	json.NewEncoder(f).Encode(pairs)
+	json.NewEncoder(f).Encode(sessionState{Pairs: pairs, Trash: trash})
 }
 
 func loadQueueFromFile() {
-	f, err := os.Open(sessionFile)
+	data, err := os.ReadFile(sessionFile)
 	if err != nil { return }
-	defer f.Close()
-	var arr []pairRow
-	if err := json.NewDecoder(f).Decode(&arr); err == nil {
-		pairs = arr
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var arr []pairRow
+		if err := json.Unmarshal(data, &arr); err == nil {
+			pairs = arr
+		}
+		return
+	}
+	var st sessionState
+	if err := json.Unmarshal(data, &st); err == nil {
+		pairs, trash = st.Pairs, st.Trash
 	}
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math/big"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/ligun0805/bundle-rescue/internal/profile"
+)
+
+// guiProfile is the --profile (or PROFILE) the GUI was started with; nil = root .env.
+// The session file lives in the profile directory, so one client's queue never shows up
+// under another client's SAFE.
+var guiProfile *profile.Profile
+
+// windowTitle puts the profile name into the title bar.
+func windowTitle(base string) string {
+	if guiProfile == nil {
+		return base
+	}
+	return base + " — PROFILE " + guiProfile.Name
+}
+
+// profileBanner is the coloured strip at the top of the main window (nil without a profile).
+func profileBanner() fyne.CanvasObject {
+	if guiProfile == nil {
+		return nil
+	}
+	text := "PROFILE: " + guiProfile.Name
+	if c := strings.TrimSpace(os.Getenv("PROFILE_CHAIN_ID")); c != "" {
+		text += "   ·   chain " + c
+	}
+	bg := canvas.NewRectangle(color.NRGBA{R: 150, G: 90, B: 20, A: 255})
+	lbl := widget.NewLabelWithStyle(text, fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	return container.NewStack(bg, lbl)
+}
+
+// checkProfileChain refuses a run whose Chain ID field disagrees with PROFILE_CHAIN_ID.
+func checkProfileChain(chain string) error {
+	id, ok := new(big.Int).SetString(strings.TrimSpace(chain), 10)
+	if !ok {
+		return nil // the run itself reports a malformed Chain ID
+	}
+	if err := guiProfile.CheckChain(id); err != nil {
+		return fmt.Errorf("%w; fix the Chain ID field or start with another --profile", err)
+	}
+	return nil
+}
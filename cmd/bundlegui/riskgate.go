@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/riskgate"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+)
+
+// Confirmation gates before RESCUE (RISK_*, see internal/riskgate): the pairs of the run are
+// assessed together; medium risk asks once, high risk wants the confirmation phrase typed.
+
+// confirmRisk runs proceed right away when the gate is "none", otherwise after the operator
+// confirms in a dialog; approval says what was confirmed ("" = no gate) for the job store.
+// Pairs have no 7702 delegate in the GUI (classic bundles).
+func confirmRisk(w fyne.Window, only func(pairRow) bool, rpcURL, chain, relays, safe string, proceed func(approval string)) {
+	pol, err := riskgate.FromEnv(strings.TrimSpace(chain))
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	safeHex, err := deriveAddrFromPK(safe)
+	if err != nil {
+		proceed("") // runAll reports the bad SAFE key itself
+		return
+	}
+	public := false
+	for _, r := range strings.Split(relays, ",") {
+		if strings.TrimSpace(r) != "" && strings.EqualFold(rpcdial.Host(r), rpcdial.Host(rpcURL)) {
+			public = true
+		}
+	}
+	var list []riskgate.Assessment
+	for _, pr := range pairs {
+		if only != nil && !only(pr) {
+			continue
+		}
+		amt, ok := new(big.Int).SetString(strings.TrimSpace(pr.AmountWei), 10)
+		if !ok {
+			amt = nil
+		}
+		hook := "" // transfer_hook warning from the scan / Add Pair check
+		for _, w := range pr.Warnings {
+			if w.Code == warnings.TransferHook {
+				hook = w.Detail
+			}
+		}
+		list = append(list, pol.Assess(riskgate.Action{
+			Token: common.HexToAddress(pr.Token), Decimals: pr.Decimals, Amount: amt,
+			Recipient: common.HexToAddress(pr.To), Safe: common.HexToAddress(safeHex), PublicFallback: public,
+			TransferHook: hook,
+		}))
+	}
+	as := riskgate.Merge(list...)
+	gate := pol.GateFor(as)
+	if gate == riskgate.GateNone {
+		proceed("")
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Risk: %s\n", as.Level)
+	for _, f := range as.Findings {
+		fmt.Fprintf(&b, "\n • %s (%s): %s", f.Factor, f.Level, f.Detail)
+	}
+	msg := widget.NewLabel(b.String())
+	msg.Wrapping = fyne.TextWrapWord
+	if gate == riskgate.GateConfirm {
+		d := dialog.NewCustomConfirm("Confirm rescue", "Run", "Cancel", msg, func(ok bool) {
+			if ok {
+				proceed(fmt.Sprintf("%s risk confirmed", as.Level))
+			}
+		}, w)
+		d.Resize(fyne.NewSize(560, 300))
+		d.Show()
+		return
+	}
+	phrase := widget.NewEntry()
+	phrase.SetPlaceHolder(pol.Phrase)
+	items := []*widget.FormItem{
+		widget.NewFormItem("", msg),
+		widget.NewFormItem("Type", widget.NewLabel(pol.Phrase)),
+		widget.NewFormItem("Phrase", phrase),
+	}
+	d := dialog.NewForm("Confirm high-risk rescue", "Run", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if !pol.PhraseOK(phrase.Text) {
+			dialog.ShowInformation("Not confirmed", "The phrase did not match; nothing was sent.", w)
+			return
+		}
+		proceed(fmt.Sprintf("%s risk confirmed with the phrase", as.Level))
+	}, w)
+	d.Resize(fyne.NewSize(560, 360))
+	d.Show()
+}
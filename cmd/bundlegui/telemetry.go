@@ -1,16 +1,13 @@
 package main
 
-import "sync"
+import (
+	"sync"
 
-type TelemetryItem struct {
-	Time      string `json:"time"`
-	Action    string `json:"action"`
-	PairIndex int    `json:"pairIndex"`
-	Relay     string `json:"relay,omitempty"`
-	OK        bool   `json:"ok,omitempty"`
-	Error     string `json:"error,omitempty"`
-	Raw       string `json:"raw,omitempty"`
-}
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
+)
+
+// TelemetryItem is the versioned telemetry record (see internal/reportschema).
+type TelemetryItem = reportschema.TelemetryItem
 
 var (
 	telemetry []TelemetryItem
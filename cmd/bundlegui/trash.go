@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// trashOp is one destructive queue operation (row delete, REMOVE NON-TRANSFERABLE): the rows
+// it removed with their former positions, so it can be undone. Ops live in the "trash" part
+// of the session file and survive restarts.
+type trashOp struct {
+	ID     int64
+	Time   time.Time
+	Action string
+	Rows   []trashedRow
+}
+
+type trashedRow struct {
+	Index    int // position in the queue before the removal
+	Pair     pairRow
+	Scenario string `json:",omitempty"`
+}
+
+var trash []trashOp // oldest first
+
+// undoDepth is UNDO_DEPTH: how many destructive operations stay restorable (default 20).
+func undoDepth() int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("UNDO_DEPTH"))); err == nil && n > 0 {
+		return n
+	}
+	return 20
+}
+
+// removePairs removes the queue rows at idx (and their per-row UI state), moving them to the
+// trash as one undoable operation, and saves the queue.
+func removePairs(action string, idx []int) {
+	idx = append([]int(nil), idx...)
+	sort.Ints(idx)
+	op := trashOp{ID: time.Now().UnixNano(), Time: time.Now(), Action: action}
+	drop := make(map[int]bool, len(idx))
+	for _, i := range idx {
+		if i < 0 || i >= len(pairs) || drop[i] {
+			continue
+		}
+		drop[i] = true
+		op.Rows = append(op.Rows, trashedRow{Index: i, Pair: pairs[i], Scenario: at(pairScenario, i)})
+	}
+	if len(op.Rows) == 0 {
+		return
+	}
+	var keep []pairRow
+	var keepSc, keepSt, keepS, keepD []string
+	for i, pr := range pairs {
+		if drop[i] {
+			continue
+		}
+		keep = append(keep, pr)
+		keepSc = append(keepSc, at(pairScenario, i))
+		keepSt = append(keepSt, at(pairStatus, i))
+		keepS = append(keepS, at(pairCheckS, i))
+		keepD = append(keepD, at(pairCheckD, i))
+	}
+	pairs = keep
+	pairScenario, pairStatus, pairCheckS, pairCheckD = keepSc, keepSt, keepS, keepD
+	trash = append(trash, op)
+	if n := undoDepth(); len(trash) > n {
+		trash = append([]trashOp(nil), trash[len(trash)-n:]...)
+	}
+	saveQueueToFile()
+}
+
+// undoLast restores the most recent destructive operation; ok=false when the trash is empty.
+func undoLast() (trashOp, bool) {
+	if len(trash) == 0 {
+		return trashOp{}, false
+	}
+	return restoreOp(trash[len(trash)-1].ID)
+}
+
+// restoreOp puts the rows of trash op id back at their former positions (clamped to the
+// current queue) and drops the op from the trash.
+func restoreOp(id int64) (trashOp, bool) {
+	for k, op := range trash {
+		if op.ID != id {
+			continue
+		}
+		rows := append([]trashedRow(nil), op.Rows...)
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Index < rows[j].Index })
+		for _, r := range rows {
+			i := r.Index
+			if i > len(pairs) {
+				i = len(pairs)
+			}
+			pairs = insertAt(pairs, i, r.Pair)
+			pairScenario = insertAt(pad(pairScenario, i), i, r.Scenario)
+			pairStatus = insertAt(pad(pairStatus, i), i, "")
+			pairCheckS = insertAt(pad(pairCheckS, i), i, "")
+			pairCheckD = insertAt(pad(pairCheckD, i), i, "")
+		}
+		trash = append(trash[:k:k], trash[k+1:]...)
+		saveQueueToFile()
+		return op, true
+	}
+	return trashOp{}, false
+}
+
+// insertAt inserts v at position i of s (append when i is past the end).
+func insertAt[T any](s []T, i int, v T) []T {
+	if i >= len(s) {
+		return append(s, v)
+	}
+	var zero T
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+// pad extends a per-row UI slice (they may lag behind pairs) to at least n entries.
+func pad(s []string, n int) []string {
+	for len(s) < n {
+		s = append(s, "")
+	}
+	return s
+}
+
+// at is s[i], or "" past the end of a lagging per-row UI slice.
+func at(s []string, i int) string {
+	if i < len(s) {
+		return s[i]
+	}
+	return ""
+}
+
+// undoText is the UNDO button label: how many operations can be undone.
+func undoText() string {
+	if len(trash) == 0 {
+		return "UNDO"
+	}
+	return fmt.Sprintf("UNDO (%d)", len(trash))
+}
+
+// opSummary is a one-line description of a trash op.
+func opSummary(op trashOp) string {
+	s := fmt.Sprintf("%s  %s — %d row(s)", op.Time.Local().Format("2006-01-02 15:04:05"), op.Action, len(op.Rows))
+	if len(op.Rows) == 1 {
+		p := op.Rows[0].Pair
+		s += ": " + shortAddr(p.From) + " / " + shortAddr(p.Token)
+	}
+	return s
+}
+
+// openTrashWindow lists the restorable operations, newest first. onChange runs after a
+// restore or after the trash was emptied.
+func openTrashWindow(a fyne.App, onChange func()) {
+	w := a.NewWindow("Trash")
+	var list *widget.List
+	ops := func() []trashOp {
+		out := make([]trashOp, len(trash))
+		for i := range trash {
+			out[i] = trash[len(trash)-1-i]
+		}
+		return out
+	}
+	list = widget.NewList(
+		func() int { return len(trash) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil, widget.NewButton("Restore", nil), widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			cur := ops()
+			if id >= len(cur) {
+				return
+			}
+			op := cur[id]
+			c := obj.(*fyne.Container)
+			c.Objects[0].(*widget.Label).SetText(opSummary(op))
+			c.Objects[1].(*widget.Button).OnTapped = func() {
+				if _, ok := restoreOp(op.ID); ok {
+					appendLogLine(a, "[trash] restored: "+opSummary(op))
+					list.Refresh()
+					onChange()
+				}
+			}
+		},
+	)
+	empty := widget.NewButton("Empty trash", func() {
+		trash = nil
+		saveQueueToFile()
+		list.Refresh()
+		onChange()
+	})
+	note := widget.NewLabel(fmt.Sprintf("Last %d destructive operations (UNDO_DEPTH). Restored rows go back to their former positions.", undoDepth()))
+	w.SetContent(container.NewBorder(note, empty, nil, nil, list))
+	w.Resize(fyne.NewSize(720, 420))
+	w.Show()
+}
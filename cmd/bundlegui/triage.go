@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/triage"
+)
+
+// Triage annotations (TRIAGE_FILE, edited with `batchcli -mark`): when set, RESCUE runs only
+// the queue pairs marked approved; review/declined/unmarked pairs stay in the queue untouched.
+
+// triageFilter narrows only to approved pairs. Without TRIAGE_FILE it returns only as is;
+// note is the log line with what the sidecar holds back ("" when nothing).
+func triageFilter(only func(pairRow) bool) (filter func(pairRow) bool, note string, err error) {
+	path := strings.TrimSpace(os.Getenv("TRIAGE_FILE"))
+	if path == "" {
+		return only, "", nil
+	}
+	store, err := triage.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	approved := func(p pairRow) bool {
+		return store.Status(common.HexToAddress(p.From), common.HexToAddress(p.Token)) == triage.Approved
+	}
+	held := map[triage.Status]int{}
+	for _, pr := range pairs {
+		if only != nil && !only(pr) {
+			continue
+		}
+		if st := store.Status(common.HexToAddress(pr.From), common.HexToAddress(pr.Token)); st != triage.Approved {
+			held[st]++
+		}
+	}
+	if n := held[triage.Review] + held[triage.Declined] + held[triage.Unmarked]; n > 0 {
+		note = fmt.Sprintf("[triage] %s: %d pair(s) not approved are skipped (review=%d declined=%d unmarked=%d)",
+			path, n, held[triage.Review], held[triage.Declined], held[triage.Unmarked])
+	}
+	return func(p pairRow) bool { return (only == nil || only(p)) && approved(p) }, note, nil
+}
@@ -13,8 +13,9 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
 )
 
 // openAddPairWindow opens the form to add a row into the queue.
@@ -42,6 +43,8 @@ func openAddPairWindow(a fyne.App, rpc string, safePk string) {
 		if addr, err := deriveAddrFromPK(s); err == nil { toE.SetText(addr) }
 	}
 	decE := widget.NewEntry()
+	amountHint := widget.NewLabel("")
+	bindAmountPreview(amountTokE, decE, amountHint, func() int { return atoi(decE.Text, -1) })
 	status := widget.NewLabel("")
 	spinner := widget.NewProgressBarInfinite()
 	spinner.Hide()
@@ -82,10 +85,11 @@ func openAddPairWindow(a fyne.App, rpc string, safePk string) {
 		if dec < 0 {
 			if d, e := fetchTokenDecimals(ec, common.HexToAddress(token)); e == nil { dec = d; decE.SetText(fmt.Sprintf("%d", d)) } else { status.SetText("decimals: "+e.Error()); spinner.Hide(); return }
 		}
-		amountTok := strings.TrimSpace(strings.ReplaceAll(amountTokE.Text, ",", "."))
+		amountTok, err := normalizeAmount(amountTokE.Text)
+		if err != nil { status.SetText("amount: "+err.Error()); spinner.Hide(); return }
 		bal, err := fetchTokenBalance(ec, common.HexToAddress(token), common.HexToAddress(from)); if err != nil { status.SetText("balance: "+err.Error()); spinner.Hide(); return }
 		var w *big.Int
-		if amountTok == "" || strings.EqualFold(amountTok, "all") {
+		if amountTok == "" || amountTok == "all" {
 			w = new(big.Int).Set(bal)
 			amountTok = formatTokensFromWei(w, dec)
 			amountTokE.SetText(amountTok)
@@ -102,20 +106,32 @@ func openAddPairWindow(a fyne.App, rpc string, safePk string) {
 			status.SetText("Rejected: " + restr.Summary()); spinner.Hide(); return
 		}
 		if ok, reason, err := core.PreflightTransfer(ctx, ec, common.HexToAddress(token), common.HexToAddress(from), common.HexToAddress(to), w); !ok {
-			if err != nil && isRPCTimeout(err) {
+			if err != nil && rpcerrors.IsTimeout(err) {
 				status.SetText("Preflight: RPC timeout — saving anyway")
 			} else {
 				status.SetText("Rejected: token not transferable (" + reason + ")"); spinner.Hide(); return
 			}
 		}
-		pairs = append(pairs, pairRow{
+		// ERC-777 / ERC-1363: hooked tokens get a transfer_hook warning (risk gate) and must pass
+		// the strict simulation
+		var warns warnings.List
+		if hooks, err := core.DetectTransferHooks(ctx, ec, common.HexToAddress(token), common.HexToAddress(from), common.HexToAddress(to)); err == nil && hooks.Any() {
+			if _, err := core.SimulateTransferStrict(ctx, ec.Client(), common.HexToAddress(token), common.HexToAddress(from), common.HexToAddress(to), w); err != nil {
+				status.SetText("Rejected: " + hooks.Summary() + ": " + err.Error()); spinner.Hide(); return
+			}
+			warns.Add(warnings.TransferHook, hooks.Summary()+" (strict simulation OK)")
+		}
+		added, merged := mergeIntoQueue([]pairRow{{
 			Token: token, From: from, FromPK: fromPk, To: to,
 			AmountWei: w.String(), AmountTokens: amountTok, Decimals: dec,
 			BalanceWei: bal.String(), BalanceTokens: formatTokensFromWei(bal, dec),
-		})
-		statsAdded++
+			Warnings: warns,
+		}})
+		statsAdded += len(added)
 		saveQueueToFile()
-		if strings.Contains(strings.ToLower(status.Text), "preflight: rpc timeout") {
+		if len(merged) > 0 {
+			status.SetText("Already queued — merged with fresh balance ✔")
+		} else if strings.Contains(strings.ToLower(status.Text), "preflight: rpc timeout") {
 			status.SetText("Saved to queue ✔ (preflight skipped due to RPC timeout)")
 		} else {
 			status.SetText("Saved to queue ✔")
@@ -130,7 +146,7 @@ func openAddPairWindow(a fyne.App, rpc string, safePk string) {
 		widget.NewFormItem("From", fromE),
 		widget.NewFormItem("From PK", fromPkE),
 		widget.NewFormItem("To", toE),
-		widget.NewFormItem("Amount (tokens)", amountTokE),
+		widget.NewFormItem("Amount (tokens)", container.NewVBox(amountTokE, amountHint)),
 		widget.NewFormItem("Decimals", decE),
 		widget.NewFormItem("", container.NewHBox(saveBtn, cancelBtn)),
 	)
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/ligun0805/bundle-rescue/internal/calldata"
+)
+
+// showCalldataDialog decodes pasted calldata against the delegate/ERC-20/router ABIs
+// (same as `bundlecli decode-calldata`).
+func showCalldataDialog(w fyne.Window) {
+	in := widget.NewMultiLineEntry()
+	in.SetPlaceHolder("0x… (tx input / data)")
+	in.Wrapping = fyne.TextWrapBreak
+	out := widget.NewMultiLineEntry()
+	out.Wrapping = fyne.TextWrapWord
+	out.TextStyle = fyne.TextStyle{Monospace: true}
+	decode := func() {
+		data, err := calldata.ParseHex(in.Text)
+		if err != nil {
+			out.SetText(err.Error())
+			return
+		}
+		c, err := calldata.Decode(data)
+		switch {
+		case c == nil:
+			out.SetText(err.Error())
+		case err != nil:
+			out.SetText(c.String() + "\n" + err.Error())
+		default:
+			out.SetText(c.String())
+		}
+	}
+	in.OnChanged = func(string) { decode() }
+	inScroll := container.NewVScroll(in)
+	inScroll.SetMinSize(fyne.NewSize(700, 90))
+	outScroll := container.NewVScroll(out)
+	outScroll.SetMinSize(fyne.NewSize(700, 260))
+	d := dialog.NewCustom("Decode calldata", "Close", container.NewBorder(inScroll, nil, nil, nil, outScroll), w)
+	d.Resize(fyne.NewSize(760, 460))
+	d.Show()
+}
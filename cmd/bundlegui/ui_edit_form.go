@@ -12,6 +12,8 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/units"
 )
 
 // buildEditForm builds a small editor for a pairRow. It updates pr in-place.
@@ -21,6 +23,11 @@ func buildEditForm(pr *pairRow, onSave func()) fyne.CanvasObject {
 	toE    := widget.NewEntry();     toE.SetText(strings.TrimSpace(pr.To))
 	amtTok := widget.NewEntry();     amtTok.SetText(strings.TrimSpace(pr.AmountTokens))
 	decE   := widget.NewEntry();     decE.SetText(fmt.Sprintf("%d", pr.Decimals))
+	amtHint := widget.NewLabel("")
+	bindAmountPreview(amtTok, decE, amtHint, func() int {
+		if d, err := strconv.Atoi(strings.TrimSpace(decE.Text)); err == nil && d >= 0 && d <= units.MaxDecimals { return d }
+		return -1
+	})
 
 	saveBtn := widget.NewButtonWithIcon("Save", theme.ConfirmIcon(), func() {
 		token := strings.TrimSpace(tokenE.Text)
@@ -32,12 +39,13 @@ func buildEditForm(pr *pairRow, onSave func()) fyne.CanvasObject {
 			return
 		}
 		decimals := pr.Decimals
-		if d, err := strconv.Atoi(dec); err == nil && d >= 0 && d <= 36 {
+		if d, err := strconv.Atoi(dec); err == nil && d >= 0 && d <= units.MaxDecimals {
 			decimals = d
 		} else {
 			dialog.ShowInformation("Edit", "Bad decimals", viewWin); return
 		}
-		amountTokens := strings.TrimSpace(strings.ReplaceAll(amtTok.Text, ",", "."))
+		amountTokens, err := normalizeAmount(amtTok.Text)
+		if err != nil { dialog.ShowInformation("Edit", "Bad amount: "+err.Error(), viewWin); return }
 		var amountWei *big.Int
 		if amountTokens == "" {
 			amountWei = new(big.Int).SetInt64(0)
@@ -70,7 +78,7 @@ func buildEditForm(pr *pairRow, onSave func()) fyne.CanvasObject {
 		widget.NewFormItem("Token", tokenE),
 		widget.NewFormItem("From",  fromE),
 		widget.NewFormItem("To",    toE),
-		widget.NewFormItem("Amount (tokens)", amtTok),
+		widget.NewFormItem("Amount (tokens)", container.NewVBox(amtTok, amtHint)),
 		widget.NewFormItem("Decimals", decE),
 		widget.NewFormItem("", container.NewHBox(saveBtn, cancelBtn)),
 	)
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const historyAll = "All"
+
+var histWin fyne.Window
+
+// openHistoryWindow lists past pairs from the job store with date/campaign/status filters.
+func openHistoryWindow(a fyne.App) {
+	if histWin != nil {
+		histWin.Show()
+		histWin.RequestFocus()
+		return
+	}
+	histWin = a.NewWindow("History")
+	histWin.SetOnClosed(func() { histWin = nil })
+
+	var all, shown []JobRecord
+
+	dateEntry := widget.NewEntry()
+	dateEntry.SetPlaceHolder("Date (YYYY-MM-DD or YYYY-MM)")
+	campSel := widget.NewSelect([]string{historyAll}, nil)
+	campSel.SetSelected(historyAll)
+	statusSel := widget.NewSelect([]string{historyAll, "COMPLETED", "PENDING", "FAILED"}, nil)
+	statusSel.SetSelected(historyAll)
+	opSel := widget.NewSelect([]string{historyAll}, nil)
+	opSel.SetSelected(historyAll)
+	countLbl := widget.NewLabel("")
+
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.Truncation = fyne.TextTruncateEllipsis
+			return lbl
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < 0 || id >= len(shown) {
+				return
+			}
+			r := shown[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  [%s]  %-9s  %s by %s  from %s  token %s  %s",
+				r.Time, r.Campaign, r.Status, r.Mode, defaultStr(r.Operator, "?"), shortAddr(r.From), shortAddr(r.Token), r.Reason))
+		},
+	)
+
+	applyFilter := func() {
+		date := strings.TrimSpace(dateEntry.Text)
+		shown = shown[:0]
+		for _, r := range all {
+			if date != "" && !strings.HasPrefix(r.Time, date) {
+				continue
+			}
+			if campSel.Selected != "" && campSel.Selected != historyAll && r.Campaign != campSel.Selected {
+				continue
+			}
+			if statusSel.Selected != "" && statusSel.Selected != historyAll && r.Status != statusSel.Selected {
+				continue
+			}
+			if opSel.Selected != "" && opSel.Selected != historyAll && !involves(r, opSel.Selected) {
+				continue
+			}
+			shown = append(shown, r)
+		}
+		countLbl.SetText(fmt.Sprintf("%d / %d", len(shown), len(all)))
+		list.UnselectAll()
+		list.Refresh()
+	}
+	reload := func() {
+		all = loadJobs()
+		seen, seenOp := map[string]bool{}, map[string]bool{}
+		opts, ops := []string{}, []string{}
+		for _, r := range all {
+			if !seen[r.Campaign] {
+				seen[r.Campaign] = true
+				opts = append(opts, r.Campaign)
+			}
+			for _, o := range []string{r.Operator, r.ImportedBy, r.ApprovedBy} {
+				if o != "" && !seenOp[o] {
+					seenOp[o] = true
+					ops = append(ops, o)
+				}
+			}
+		}
+		sort.Strings(ops)
+		opSel.Options = append([]string{historyAll}, ops...)
+		if !seenOp[opSel.Selected] {
+			opSel.SetSelected(historyAll)
+		}
+		opSel.Refresh()
+		sort.Strings(opts)
+		campSel.Options = append([]string{historyAll}, opts...)
+		if !seen[campSel.Selected] {
+			campSel.SetSelected(historyAll)
+		}
+		campSel.Refresh()
+		applyFilter()
+	}
+
+	dateEntry.OnChanged = func(string) { applyFilter() }
+	campSel.OnChanged = func(string) { applyFilter() }
+	statusSel.OnChanged = func(string) { applyFilter() }
+	opSel.OnChanged = func(string) { applyFilter() }
+	list.OnSelected = func(id widget.ListItemID) {
+		if id >= 0 && id < len(shown) {
+			showJobDetails(histWin, shown[id])
+		}
+	}
+
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), reload)
+	winsBtn := widget.NewButton("Relay wins", func() { showRelayWins(histWin, all) })
+	filters := container.NewBorder(nil, nil, nil, container.NewHBox(countLbl, winsBtn, refreshBtn),
+		container.NewGridWithColumns(4, dateEntry, campSel, statusSel, opSel))
+	histWin.SetContent(container.NewBorder(filters, nil, nil, nil, list))
+	histWin.Resize(fyne.NewSize(1000, 600))
+	reload()
+	histWin.Show()
+}
+
+// showJobDetails opens the full decision trail and relay responses of one record.
+func showJobDetails(w fyne.Window, r JobRecord) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Run: %s\nTime: %s\nCampaign: %s\nMode: %s\nPair #%d\nToken: %s\nFrom: %s\nTo: %s\nStatus: %s\n",
+		r.RunID, r.Time, r.Campaign, r.Mode, r.PairIndex+1, r.Token, r.From, r.To, r.Status)
+	if r.Reason != "" {
+		fmt.Fprintf(&b, "Reason: %s\n", r.Reason)
+	}
+	if r.Status == "COMPLETED" && r.Mode == "run" {
+		fmt.Fprintf(&b, "Built by: %s via %s\n", defaultStr(r.Builder, "unknown builder"), defaultStr(strings.Join(r.WinRelays, ", "), "-"))
+	}
+	fmt.Fprintf(&b, "Imported by: %s\nApproved: %s\nExecuted by: %s\n",
+		defaultStr(r.ImportedBy, "-"), defaultStr(strings.TrimSpace(r.Approval+" "+byOperator(r.ApprovedBy)), "-"), defaultStr(r.Operator, "-"))
+	b.WriteString("\n--- Decision trail ---\n")
+	for _, l := range r.Log {
+		b.WriteString(l + "\n")
+	}
+	if len(r.Relays) > 0 {
+		b.WriteString("\n--- Relay responses ---\n")
+		for _, t := range r.Relays {
+			fmt.Fprintf(&b, "%s %s %s ok=%v %s\n%s\n", t.Time, t.Action, t.Relay, t.OK, t.Error, t.Raw)
+		}
+	}
+	txt := widget.NewMultiLineEntry()
+	txt.SetText(b.String())
+	txt.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(txt)
+	scroll.SetMinSize(fyne.NewSize(860, 480))
+	dialog.ShowCustom("Job details", "Close", scroll, w)
+}
+
+// involves reports whether op imported, approved or ran the record.
+func involves(r JobRecord, op string) bool {
+	return r.Operator == op || r.ImportedBy == op || r.ApprovedBy == op
+}
+
+func byOperator(op string) string {
+	if op == "" {
+		return ""
+	}
+	return "by " + op
+}
+
+// showRelayWins shows the win rate of every relay over the sending runs of the job store
+// (inclusions credited by winner attribution) and the RELAYS order it suggests.
+func showRelayWins(w fyne.Window, recs []JobRecord) {
+	rows := relayWinRates(recs)
+	if len(rows) == 0 {
+		dialog.ShowInformation("Relay wins", "No sending runs with relay records yet.", w)
+		return
+	}
+	tbl := widget.NewTable(
+		func() (int, int) { return len(rows) + 1, 4 },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			l := o.(*widget.Label)
+			if id.Row == 0 {
+				l.SetText([]string{"Relay", "Runs", "Wins", "Win rate"}[id.Col])
+				l.TextStyle = fyne.TextStyle{Bold: true}
+				return
+			}
+			r := rows[id.Row-1]
+			l.TextStyle = fyne.TextStyle{}
+			l.SetText([]string{r.Relay, fmt.Sprint(r.Runs), fmt.Sprint(r.Wins), fmt.Sprintf("%.0f%%", 100*r.Rate())}[id.Col])
+		},
+	)
+	tbl.SetColumnWidth(0, 280)
+	for i := 1; i < 4; i++ {
+		tbl.SetColumnWidth(i, 90)
+	}
+	order := make([]string, 0, len(rows))
+	for _, r := range rows {
+		order = append(order, r.Relay)
+	}
+	hint := widget.NewLabel("Suggested RELAYS order (best win rate first): " + strings.Join(order, ", "))
+	hint.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustom("Relay wins", "Close", container.NewBorder(nil, hint, nil, nil, tbl), w)
+	d.Resize(fyne.NewSize(640, 420))
+	d.Show()
+}
@@ -13,6 +13,9 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
 )
 
 // ensureLogWindow creates or returns the log window.
@@ -25,7 +28,8 @@ func ensureLogWindow(a fyne.App) fyne.Window {
 	exportBtn := widget.NewButtonWithIcon("Export Telemetry JSON", theme.DocumentSaveIcon(), func(){
 		saveTelemetryJSON(logWin)
 	})
-	top := container.NewBorder(nil, nil, nil, exportBtn, container.NewHBox(widget.NewLabel("Progress:"), logProg, logProgLbl))
+	decodeBtn := widget.NewButtonWithIcon("Decode calldata", theme.SearchIcon(), func(){ showCalldataDialog(logWin) })
+	top := container.NewBorder(nil, nil, nil, container.NewHBox(decodeBtn, exportBtn), container.NewHBox(widget.NewLabel("Progress:"), logProg, logProgLbl))
 	bg := canvas.NewLinearGradient(color.NRGBA{12,16,24,255}, color.NRGBA{20,28,40,255}, 90)
 	logBox = widget.NewMultiLineEntry()
 	logBox.Disable()
@@ -40,12 +44,12 @@ func ensureLogWindow(a fyne.App) fyne.Window {
 // appendLogLine adds a timestamped line to the log.
 func appendLogLine(a fyne.App, s string) {
 	w := ensureLogWindow(a)
-	logBox.SetText(logBox.Text + time.Now().Format("15:04:05 ") + s + "\n")
+	logBox.SetText(logBox.Text + time.Now().Format("15:04:05 ") + privacy.Line(s) + "\n")
 	if logScroll != nil { logScroll.ScrollToBottom() }
 	w.Canvas().Refresh(logBox)
 }
 
-// saveTelemetryJSON writes telemetry to a timestamped JSON file.
+// saveTelemetryJSON writes telemetry to a timestamped JSON file (schema/telemetry.schema.json).
 func saveTelemetryJSON(w fyne.Window) {
 	ts := time.Now().Format("20060102_150405")
 	exe, _ := os.Executable()
@@ -53,9 +57,14 @@ func saveTelemetryJSON(w fyne.Window) {
 	dir := filepath.Join(base, "log_data")
 	_ = os.MkdirAll(dir, 0o755)
 	path := filepath.Join(dir, ts+".json")
-	out := map[string]any{
-		"generatedAt": time.Now().UTC().Format(time.RFC3339),
-		"telemetry":   telemetry,
+	telMu.Lock()
+	items := append([]TelemetryItem(nil), telemetry...)
+	telMu.Unlock()
+	out := reportschema.TelemetryExport{
+		SchemaVersion: reportschema.Version,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Telemetry:     items,
+		Wallets:       walletReportJSON(),
 	}
 	f, err := os.Create(path)
 	if err != nil {
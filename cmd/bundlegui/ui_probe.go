@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	eip7702 "github.com/ligun0805/bundle-rescue/internal/eip7702"
+)
+
+// Settings probes behind the "Test" buttons next to RPC URL and Relays: each endpoint gets
+// connectivity / chain-ID / auth / simulation checks before a rescue depends on them.
+
+// probeRow is one line of the result table.
+type probeRow struct {
+	Endpoint, Check string
+	Status          string // PASS | FAIL | SKIP
+	Detail          string
+}
+
+func probePass(ep, check, detail string) probeRow { return probeRow{ep, check, "PASS", detail} }
+func probeFail(ep, check, detail string) probeRow { return probeRow{ep, check, "FAIL", detail} }
+func probeSkip(ep, check, detail string) probeRow { return probeRow{ep, check, "SKIP", detail} }
+
+// endpointLabel hides path/query: provider URLs carry API keys.
+func endpointLabel(raw string) string {
+	if u, err := url.Parse(strings.TrimSpace(raw)); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return strings.TrimSpace(raw)
+}
+
+// probeRPC checks the RPC URL plus HEAD_CHECK_RPCS: reachability/latency, chain ID vs the
+// Chain ID field, head freshness, eth_feeHistory and eth_call state overrides (7702 preflight).
+func probeRPC(rpcURL, chain string) []probeRow {
+	var rows []probeRow
+	eps := []string{rpcURL}
+	for _, s := range strings.Split(envSecret("HEAD_CHECK_RPCS"), ",") {
+		if strings.TrimSpace(s) != "" {
+			eps = append(eps, strings.TrimSpace(s))
+		}
+	}
+	for _, u := range eps {
+		ep := endpointLabel(u)
+		if strings.TrimSpace(u) == "" {
+			rows = append(rows, probeFail("(empty)", "connect", "RPC URL is empty"))
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		rc, err := rpc.DialContext(ctx, u)
+		if err != nil {
+			rows = append(rows, probeFail(ep, "connect", err.Error()))
+			cancel()
+			continue
+		}
+		ec := ethclient.NewClient(rc)
+		start := time.Now()
+		head, err := ec.HeaderByNumber(ctx, nil)
+		if err != nil {
+			rows = append(rows, probeFail(ep, "connect", err.Error()))
+			rc.Close()
+			cancel()
+			continue
+		}
+		rows = append(rows, probePass(ep, "connect", fmt.Sprintf("head %s in %s", head.Number, time.Since(start).Round(time.Millisecond))))
+
+		if id, err := ec.ChainID(ctx); err != nil {
+			rows = append(rows, probeFail(ep, "chain id", err.Error()))
+		} else if want := strings.TrimSpace(chain); want != "" && id.String() != want {
+			rows = append(rows, probeFail(ep, "chain id", fmt.Sprintf("RPC is chain %s, Chain ID field says %s", id, want)))
+		} else {
+			rows = append(rows, probePass(ep, "chain id", id.String()))
+		}
+
+		if age := time.Since(time.Unix(int64(head.Time), 0)); age > time.Minute {
+			rows = append(rows, probeFail(ep, "head fresh", fmt.Sprintf("latest block is %s old — provider lagging?", age.Round(time.Second))))
+		} else {
+			rows = append(rows, probePass(ep, "head fresh", fmt.Sprintf("%s old", age.Round(time.Second))))
+		}
+
+		var fh map[string]any
+		if err := rc.CallContext(ctx, &fh, "eth_feeHistory", "0x1", "pending", []int{50}); err != nil {
+			rows = append(rows, probeFail(ep, "feeHistory", err.Error()+" (tips/baseFee fall back to the latest header)"))
+		} else {
+			rows = append(rows, probePass(ep, "feeHistory", "ok"))
+		}
+
+		var out string
+		call := map[string]any{"to": "0x0000000000000000000000000000000000000001", "data": "0x"}
+		if err := rc.CallContext(ctx, &out, "eth_call", call, "latest", map[string]any{}); err != nil {
+			rows = append(rows, probeFail(ep, "state override", err.Error()+" (7702 preflight needs eth_call stateOverrides)"))
+		} else {
+			rows = append(rows, probePass(ep, "state override", "eth_call accepts overrides"))
+		}
+		rc.Close()
+		cancel()
+	}
+	return rows
+}
+
+// probeRelays runs the authenticated no-op call on every relay (same as bundlecli batch
+// startup) and, when the Safe PK is set, simulates a signed 0-value self-transfer from SAFE
+// at head+1 via eth_callBundle. Nothing is ever sent.
+func probeRelays(rpcURL, chain, relays, auth, safe string) []probeRow {
+	var rows []probeRow
+	list := []string{}
+	for _, r := range strings.Split(relays, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			list = append(list, r)
+		}
+	}
+	if len(list) == 0 {
+		return []probeRow{probeFail("(none)", "relays", "Relays field is empty")}
+	}
+	var signer *ecdsa.PrivateKey
+	if a := strings.TrimSpace(auth); a != "" {
+		k, err := crypto.HexToECDSA(strings.TrimPrefix(a, "0x"))
+		if err != nil {
+			rows = append(rows, probeFail("Auth PK", "parse", err.Error()+"; expected 0x + 64 hex"))
+		} else {
+			signer = k
+		}
+	}
+	headers := eip7702.ExtraHeaders{}
+	if v := os.Getenv("BLOXROUTE_RELAY"); v != "" {
+		h := map[string]string{}
+		if k := envSecret("BLOXROUTE_API_KEY"); k != "" {
+			h["X-API-KEY"], h["Authorization"] = k, k
+		}
+		if a := envSecret("BLOXROUTE_AUTH_HEADER"); a != "" {
+			h["Authorization"] = a
+		}
+		headers[v] = h
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	authOK := map[string]bool{}
+	for _, rc := range eip7702.ValidateRelays(ctx, list, headers, signer) {
+		if rc.OK {
+			authOK[rc.RelayURL] = true
+			rows = append(rows, probePass(endpointLabel(rc.RelayURL), "connect+auth", fmt.Sprintf("http %d", rc.HTTPStatus)))
+		} else {
+			rows = append(rows, probeFail(endpointLabel(rc.RelayURL), "connect+auth", rc.Problem+" — "+rc.Hint))
+		}
+	}
+
+	raw, blockHex, why := probeSimTx(ctx, rpcURL, chain, safe)
+	for _, r := range list {
+		ep := endpointLabel(r)
+		switch {
+		case !authOK[r]:
+			rows = append(rows, probeSkip(ep, "simulate", "auth failed"))
+		case raw == "":
+			rows = append(rows, probeSkip(ep, "simulate", why))
+		default:
+			sr := eip7702.SimulatePrivate(ctx, raw, blockHex, []string{r}, headers, signer)[0]
+			switch {
+			case sr.Err != nil:
+				rows = append(rows, probeFail(ep, "simulate", sr.Err.Error()))
+			case sr.OK:
+				rows = append(rows, probePass(ep, "simulate", "eth_callBundle at "+blockHex))
+			default:
+				rows = append(rows, probeFail(ep, "simulate", fmt.Sprintf("http %d: %s", sr.HTTPStatus, defaultStr(sr.Reason, sr.ResponseBody))))
+			}
+		}
+	}
+	return rows
+}
+
+// probeSimTx signs (never sends) a 0-value SAFE->SAFE transfer for the simulate probe.
+func probeSimTx(ctx context.Context, rpcURL, chain, safe string) (raw, blockHex, why string) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(safe), "0x"))
+	if err != nil {
+		return "", "", "Safe PK not set"
+	}
+	ec, err := newEthClientWithTimeout(rpcURL)
+	if err != nil {
+		return "", "", "RPC: " + err.Error()
+	}
+	defer ec.Close()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	head, err := ec.HeaderByNumber(ctx, nil)
+	if err != nil || head.BaseFee == nil {
+		return "", "", "RPC: no head/baseFee"
+	}
+	nonce, err := ec.PendingNonceAt(ctx, from)
+	if err != nil {
+		return "", "", "RPC: " + err.Error()
+	}
+	if _, err := ec.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &from}); err != nil {
+		return "", "", "RPC: " + err.Error()
+	}
+	tip := big.NewInt(1_000_000_000)
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID: mustBig(chain), Nonce: nonce, GasTipCap: tip, GasFeeCap: new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip),
+		Gas: 21000, To: &from, Value: big.NewInt(0),
+	})
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(mustBig(chain)), key)
+	if err != nil {
+		return "", "", "sign: " + err.Error()
+	}
+	b, _ := signed.MarshalBinary()
+	return fmt.Sprintf("0x%x", b), fmt.Sprintf("0x%x", new(big.Int).Add(head.Number, big.NewInt(1))), ""
+}
+
+// runProbe shows a progress dialog, runs probe in the background, then the result table.
+func runProbe(w fyne.Window, title string, probe func() []probeRow) {
+	prog := dialog.NewCustomWithoutButtons(title, widget.NewProgressBarInfinite(), w)
+	prog.Show()
+	go func() {
+		rows := probe()
+		prog.Hide()
+		showProbeResults(w, title, rows)
+	}()
+}
+
+func showProbeResults(w fyne.Window, title string, rows []probeRow) {
+	fails := 0
+	for _, r := range rows {
+		if r.Status == "FAIL" {
+			fails++
+		}
+	}
+	tbl := widget.NewTable(
+		func() (int, int) { return len(rows) + 1, 4 },
+		func() fyne.CanvasObject { l := widget.NewLabel(""); l.Truncation = fyne.TextTruncateEllipsis; return l },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			l := o.(*widget.Label)
+			if id.Row == 0 {
+				l.SetText([]string{"Endpoint", "Check", "Result", "Detail"}[id.Col])
+				l.TextStyle = fyne.TextStyle{Bold: true}
+				return
+			}
+			r := rows[id.Row-1]
+			l.TextStyle = fyne.TextStyle{}
+			l.SetText([]string{r.Endpoint, r.Check, r.Status, r.Detail}[id.Col])
+		},
+	)
+	tbl.SetColumnWidth(0, 220)
+	tbl.SetColumnWidth(1, 120)
+	tbl.SetColumnWidth(2, 60)
+	tbl.SetColumnWidth(3, 520)
+	tbl.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 {
+			r := rows[id.Row-1]
+			dialog.ShowInformation(r.Endpoint+" — "+r.Check, r.Status+"\n\n"+r.Detail, w)
+		}
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%d check(s), %d failed — click a row for the full detail", len(rows), fails))
+	d := dialog.NewCustom(title, "Close", container.NewBorder(summary, nil, nil, nil, tbl), w)
+	d.Resize(fyne.NewSize(960, 420))
+	d.Show()
+}
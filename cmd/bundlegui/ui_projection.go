@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Gas model for projections; mirrors bundlecore.Run (SAFE prefund tx + victim transfer).
+const (
+	projPrefundGas  = 21_000
+	projTransferGas = 65_000 // typical ERC-20 transfer; Run uses estimateGas (fallback 90k)
+	projBlockSecs   = 12.0
+	projPairSecs    = 4.0 // preflight/build/sim overhead per pair
+)
+
+// strategyPreset is a named set of Strategy entries.
+type strategyPreset struct {
+	Name                              string
+	Blocks, Tip, TipMul, BaseMul, Buf string
+}
+
+var strategyPresets = []strategyPreset{
+	{"Conservative", "4", "2", "1.10", "2", "10"},
+	{"Balanced", "6", "3", "1.25", "2", "5"},
+	{"Aggressive", "10", "6", "1.50", "3", "10"},
+}
+
+// projection is the estimated spend for the current queue under one strategy.
+type projection struct {
+	Pairs                       int
+	PairFirstETH, PairLastETH   float64 // included at first / last attempt
+	TotalFirstETH, TotalLastETH float64
+	SecsFirst, SecsLast         float64
+}
+
+// projectCost follows Run's fee escalation: tip_k = tip*tipMul^k, maxFee_k = base*baseMul + tip_k,
+// prefund_k = transferGas*maxFee_k*(100+buffer)% (buffer >= 10 as in Run). The prefund is
+// counted as spent: whatever is left on the compromised wallet is usually swept.
+func projectCost(pairs int, baseGwei float64, blocks int, tipGwei, tipMul, baseMul float64, bufferPct int64) projection {
+	if blocks < 1 {
+		blocks = 1
+	}
+	if bufferPct < 10 {
+		bufferPct = 10
+	}
+	at := func(k int) float64 {
+		tip := tipGwei * math.Pow(tipMul, float64(k))
+		maxFee := baseGwei*baseMul + tip
+		prefund := projTransferGas * maxFee * float64(100+bufferPct) / 100
+		safeFee := projPrefundGas * (baseGwei + tip)
+		return (prefund + safeFee) * 1e-9
+	}
+	p := projection{Pairs: pairs, PairFirstETH: at(0), PairLastETH: at(blocks - 1)}
+	p.TotalFirstETH = p.PairFirstETH * float64(pairs)
+	p.TotalLastETH = p.PairLastETH * float64(pairs)
+	p.SecsFirst = float64(pairs) * (projBlockSecs + projPairSecs)
+	p.SecsLast = float64(pairs) * (float64(blocks)*projBlockSecs + projPairSecs)
+	return p
+}
+
+// projBaseGwei is the last baseFee seen by UPDATE NETWORK (0 = unknown yet).
+var projBaseGwei float64
+
+// refreshProjection is set by newProjectionCard; call after the queue or network changes.
+var refreshProjection = func() {}
+
+// newProjectionCard builds the preset selector + live cost projection for the queue.
+func newProjectionCard(blocks, tip, tipMul, baseMul, buffer *widget.Entry) *widget.Card {
+	lbl := widget.NewLabel("")
+	lbl.Wrapping = fyne.TextWrapWord
+	pf := func(s string, d float64) float64 {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return v
+		}
+		return d
+	}
+	refreshProjection = func() {
+		if projBaseGwei <= 0 {
+			lbl.SetText(fmt.Sprintf("Queue: %d pair(s) · press UPDATE NETWORK for baseFee", len(pairs)))
+			return
+		}
+		p := projectCost(len(pairs), projBaseGwei, atoi(blocks.Text, 6),
+			pf(tip.Text, 3), pf(tipMul.Text, 1.25), pf(baseMul.Text, 2), atoi64(buffer.Text, 5))
+		lbl.SetText(fmt.Sprintf(
+			"Queue: %d pair(s) @ baseFee %.2f gwei\nPer pair: %.6f ETH (1st block) … %.6f ETH (last block)\nTotal: %.6f … %.6f ETH · time ≈ %s … %s",
+			p.Pairs, projBaseGwei, p.PairFirstETH, p.PairLastETH, p.TotalFirstETH, p.TotalLastETH,
+			fmtSecs(p.SecsFirst), fmtSecs(p.SecsLast)))
+	}
+	for _, e := range []*widget.Entry{blocks, tip, tipMul, baseMul, buffer} {
+		e.OnChanged = func(string) { refreshProjection() }
+	}
+	names := make([]string, 0, len(strategyPresets))
+	for _, ps := range strategyPresets {
+		names = append(names, ps.Name)
+	}
+	presetSel := widget.NewSelect(names, func(name string) {
+		for _, ps := range strategyPresets {
+			if ps.Name == name {
+				blocks.SetText(ps.Blocks)
+				tip.SetText(ps.Tip)
+				tipMul.SetText(ps.TipMul)
+				baseMul.SetText(ps.BaseMul)
+				buffer.SetText(ps.Buf)
+			}
+		}
+	})
+	presetSel.PlaceHolder = "Preset…"
+	refreshProjection()
+	return widget.NewCard("Projection", "", container.NewBorder(nil, nil, presetSel, nil, lbl))
+}
+
+func fmtSecs(s float64) string {
+	if s < 90 {
+		return fmt.Sprintf("%.0fs", s)
+	}
+	if s < 5400 {
+		return fmt.Sprintf("%.0fm", s/60)
+	}
+	return fmt.Sprintf("%.1fh", s/3600)
+}
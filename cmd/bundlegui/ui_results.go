@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+)
+
+// Results view of the last RESCUE run: balances read right before and right after each
+// pair's run, so a row that reports "included" but moved nothing stands out. Recipient is
+// the pair's To (usually the SAFE); SAFE ETH spent is what the SAFE paid for that pair
+// (prefund + fees, less anything that came back).
+
+// balanceSnap is one read of the balances a pair touches (nil = read failed).
+type balanceSnap struct {
+	VictimTok, ToTok, SafeETH *big.Int
+}
+
+// pairDiff is the before/after of one pair of the run.
+type pairDiff struct {
+	Index           int // into pairs
+	Token, From, To string
+	Decimals        int
+	Before, After   balanceSnap
+	Included        bool
+	Result          string
+}
+
+var (
+	runDiffsMu sync.Mutex
+	runDiffs   []pairDiff
+	runDiffsID string // RunID of runDiffs
+)
+
+var resultsWin fyne.Window
+
+// snapBalances reads the victim's and the recipient's token balance and the SAFE's ETH.
+func snapBalances(ec *ethclient.Client, token, from, to, safe common.Address) balanceSnap {
+	var s balanceSnap
+	s.VictimTok, _ = fetchTokenBalance(ec, token, from)
+	s.ToTok, _ = fetchTokenBalance(ec, token, to)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s.SafeETH, _ = ec.BalanceAt(ctx, safe, nil)
+	return s
+}
+
+// delta is after - before (nil when either read failed).
+func delta(before, after *big.Int) *big.Int {
+	if before == nil || after == nil {
+		return nil
+	}
+	return new(big.Int).Sub(after, before)
+}
+
+// Verdicts of a pair by its balances.
+const (
+	verdictMoved    = "MOVED"
+	verdictHollow   = "INCLUDED, NOTHING MOVED"
+	verdictNotMoved = "NOT MOVED"
+	verdictUnknown  = "UNKNOWN (balance read failed)"
+)
+
+// Verdict says what the balances show, independent of what the run reported: the victim's
+// token balance went down or it did not.
+func (d pairDiff) Verdict() string {
+	victim := delta(d.Before.VictimTok, d.After.VictimTok)
+	switch {
+	case victim == nil:
+		return verdictUnknown
+	case victim.Sign() < 0:
+		return verdictMoved
+	case d.Included:
+		return verdictHollow
+	}
+	return verdictNotMoved
+}
+
+// safeSpent is SAFE ETH before - after (nil when unknown).
+func (d pairDiff) safeSpent() *big.Int {
+	return delta(d.After.SafeETH, d.Before.SafeETH)
+}
+
+func (d pairDiff) tok(v *big.Int) string {
+	if v == nil {
+		return "?"
+	}
+	return privacy.Amount(units.Format(v, d.Decimals, 6))
+}
+
+func (d pairDiff) tokDelta(before, after *big.Int) string {
+	v := delta(before, after)
+	if v == nil {
+		return "?"
+	}
+	s := d.tok(v)
+	if v.Sign() > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// resetRunDiffs starts the results of a new run.
+func resetRunDiffs(runID string) {
+	runDiffsMu.Lock()
+	runDiffs, runDiffsID = nil, runID
+	runDiffsMu.Unlock()
+}
+
+func addRunDiff(d pairDiff) {
+	runDiffsMu.Lock()
+	runDiffs = append(runDiffs, d)
+	runDiffsMu.Unlock()
+}
+
+// resultsSummary counts the verdicts, e.g. "3 MOVED · 1 INCLUDED, NOTHING MOVED".
+func resultsSummary(ds []pairDiff) string {
+	cnt := map[string]int{}
+	var order []string
+	spent := new(big.Int)
+	for _, d := range ds {
+		v := d.Verdict()
+		if cnt[v] == 0 {
+			order = append(order, v)
+		}
+		cnt[v]++
+		if s := d.safeSpent(); s != nil {
+			spent.Add(spent, s)
+		}
+	}
+	parts := make([]string, 0, len(order))
+	for _, v := range order {
+		parts = append(parts, fmt.Sprintf("%d %s", cnt[v], v))
+	}
+	return fmt.Sprintf("%d pair(s): %s · SAFE spent %s", len(ds), strings.Join(parts, " · "), privacy.Amount(fmtETHWei(spent)))
+}
+
+var resultsCols = []string{"#", "From", "Token", "Victim before → after", "Recipient Δ", "SAFE ETH spent", "Run result", "Balances say"}
+
+// openResultsWindow shows the before/after table of the last RESCUE run.
+func openResultsWindow(a fyne.App) {
+	runDiffsMu.Lock()
+	ds := append([]pairDiff(nil), runDiffs...)
+	id := runDiffsID
+	runDiffsMu.Unlock()
+	if resultsWin == nil {
+		resultsWin = a.NewWindow("Results")
+		resultsWin.SetOnClosed(func() { resultsWin = nil })
+		resultsWin.Resize(fyne.NewSize(1200, 500))
+	}
+	if len(ds) == 0 {
+		resultsWin.SetContent(widget.NewLabel("No RESCUE run yet in this session."))
+		resultsWin.Show()
+		return
+	}
+	cell := func(d pairDiff, col int) string {
+		switch col {
+		case 0:
+			return fmt.Sprint(d.Index + 1)
+		case 1:
+			return shortAddr(d.From)
+		case 2:
+			return shortAddr(d.Token)
+		case 3:
+			return d.tok(d.Before.VictimTok) + " → " + d.tok(d.After.VictimTok)
+		case 4:
+			return d.tokDelta(d.Before.ToTok, d.After.ToTok)
+		case 5:
+			if s := d.safeSpent(); s != nil {
+				return privacy.Amount(fmtETHWei(s))
+			}
+			return "?"
+		case 6:
+			return d.Result
+		}
+		return d.Verdict()
+	}
+	tbl := widget.NewTable(
+		func() (int, int) { return len(ds) + 1, len(resultsCols) },
+		func() fyne.CanvasObject { l := widget.NewLabel(""); l.Truncation = fyne.TextTruncateEllipsis; return l },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			l := o.(*widget.Label)
+			if id.Row == 0 {
+				l.SetText(resultsCols[id.Col])
+				l.TextStyle = fyne.TextStyle{Bold: true}
+				l.Importance = widget.MediumImportance
+				return
+			}
+			d := ds[id.Row-1]
+			l.TextStyle = fyne.TextStyle{}
+			l.Importance = widget.MediumImportance
+			if id.Col == len(resultsCols)-1 {
+				switch d.Verdict() {
+				case verdictMoved:
+					l.Importance = widget.SuccessImportance
+				case verdictHollow:
+					l.Importance = widget.DangerImportance
+				default:
+					l.Importance = widget.WarningImportance
+				}
+			}
+			l.SetText(cell(d, id.Col))
+		},
+	)
+	for i, w := range []float32{40, 150, 150, 260, 140, 140, 180, 200} {
+		tbl.SetColumnWidth(i, w)
+	}
+	tbl.OnSelected = func(c widget.TableCellID) {
+		if c.Row == 0 {
+			return
+		}
+		d := ds[c.Row-1]
+		dialog.ShowInformation(fmt.Sprintf("Pair #%d", d.Index+1), fmt.Sprintf(
+			"From: %s\nToken: %s\nTo: %s\n\nVictim token: %s → %s\nRecipient token: %s → %s (%s)\nSAFE ETH: %s → %s\n\nRun result: %s\nBalances say: %s",
+			d.From, d.Token, d.To,
+			d.tok(d.Before.VictimTok), d.tok(d.After.VictimTok),
+			d.tok(d.Before.ToTok), d.tok(d.After.ToTok), d.tokDelta(d.Before.ToTok, d.After.ToTok),
+			ethOrUnknown(d.Before.SafeETH), ethOrUnknown(d.After.SafeETH),
+			d.Result, d.Verdict()), resultsWin)
+	}
+	head := widget.NewLabel(fmt.Sprintf("Run %s — %s", id, resultsSummary(ds)))
+	resultsWin.SetContent(container.NewBorder(head, nil, nil, nil, tbl))
+	resultsWin.Show()
+}
+
+func ethOrUnknown(v *big.Int) string {
+	if v == nil {
+		return "?"
+	}
+	return privacy.Amount(fmtETHWei(v))
+}
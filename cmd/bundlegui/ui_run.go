@@ -2,18 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/errhelp"
+	"github.com/ligun0805/bundle-rescue/internal/explorer"
+	"github.com/ligun0805/bundle-rescue/internal/relayrecord"
+	"github.com/ligun0805/bundle-rescue/internal/runlock"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
 )
 
 // runAll iterates over the queue and simulates/sends each pair.
-func runAll(a fyne.App, simOnly bool, rpc, chain, relays, auth, safe, blocksS, tipS, tipMulS, baseMulS, bufferS string) {
+// only (optional) limits the run to matching rows, e.g. one wallet; appr is what was
+// approved before the run, kept in every job record.
+func runAll(a fyne.App, simOnly bool, only func(pairRow) bool, appr runApproval, rpc, chain, relays, auth, safe, blocksS, tipS, tipMulS, baseMulS, bufferS string) {
 	defer func() {
 		if r := recover(); r != nil {
 			appendLogLine(a, fmt.Sprintf("[panic] %v", r))
@@ -21,30 +31,108 @@ func runAll(a fyne.App, simOnly bool, rpc, chain, relays, auth, safe, blocksS, t
 	}()
 	if len(pairs)==0 { appendLogLine(a, "no pairs"); return }
 	ec, err := newEthClientWithTimeout(rpc); if err!=nil { appendLogLine(a, fmt.Sprintf("dial err: %v", err)); return }
+	// one sending run per SAFE (bundlecli uses the same lock); simulation needs no lock
+	if !simOnly {
+		if sa, err := deriveAddrFromPK(safe); err == nil {
+			lock, err := runlock.Acquire(common.HexToAddress(sa), "bundlegui")
+			var held *runlock.HeldError
+			switch {
+			case errors.As(err, &held) && os.Getenv("ALLOW_CONCURRENT_SAFE") == "1":
+				appendLogLine(a, "[lock] WARNING: "+held.Error()+" — continuing (ALLOW_CONCURRENT_SAFE=1)")
+			case errors.As(err, &held):
+				appendLogLine(a, "[lock] "+held.Error()+"; wait for it to finish or set ALLOW_CONCURRENT_SAFE=1")
+				ensureLogWindow(a).Show()
+				return
+			case err != nil:
+				appendLogLine(a, "[lock] run lock unavailable: "+err.Error())
+			}
+			defer lock.Release()
+		}
+	}
 	runCtx, runCancel = context.WithCancel(context.Background())
 	ctx := runCtx
-	total := len(pairs)
+	total := 0
+	for _, pr := range pairs { if only == nil || only(pr) { total++ } }
 	ensureLogWindow(a).Show()
 	if logProg != nil { logProg.Min = 0; logProg.Max = float64(total); logProg.SetValue(0) }
 	if logProgLbl != nil { logProgLbl.SetText(fmt.Sprintf("0/%d", total)) }
+	runID := time.Now().Format("20060102_150405")
+	mode := map[bool]string{true:"simulate", false:"run"}[simOnly]
+	done := 0
+	man := guiManifest(runID, mode, only, rpc, chain, relays, auth, safe, blocksS, tipS, tipMulS, baseMulS, bufferS)
+	man.MarkBlock(ctx, ec)
+	stagetime.Reset()
+	// before/after balances per pair for the Results window (RESCUE only)
+	var safeAddr common.Address
+	if sa, err := deriveAddrFromPK(safe); err == nil { safeAddr = common.HexToAddress(sa) }
+	if !simOnly { resetRunDiffs(runID); defer openResultsWindow(a) }
+	// pre-run congestion advisory (bundlecli batch mode prints the same; it can also defer the start)
+	if n := atoi(os.Getenv("CONGESTION_BLOCKS"), core.DefaultCongestionBlocks); !simOnly && n > 0 {
+		if c, err := core.SampleCongestion(ctx, ec, n); err == nil {
+			appendLogLine(a, "[gas] "+c.Advisory())
+			appendLogLine(a, "[gas] "+c.Details())
+		}
+	}
+	// MEV-Share settings for "share:" relays (same MEVSHARE_* keys as bundlecli)
+	shareHints, err := core.ParseShareHints(os.Getenv("MEVSHARE_HINTS"))
+	if err != nil { appendLogLine(a, "MEVSHARE_HINTS: "+err.Error()+" — default hints used") }
+	share := core.ShareOptions{ MaxBlocks: atoi(os.Getenv("MEVSHARE_MAX_BLOCKS"), 0), Hints: shareHints, RefundTo: strings.TrimSpace(os.Getenv("MEVSHARE_REFUND_RECIPIENT")) }
+	for _, b := range strings.Split(os.Getenv("MEVSHARE_BUILDERS"), ",") { if b = strings.TrimSpace(b); b != "" { share.Builders = append(share.Builders, b) } }
+	// relay submission record of this run (SUBMISSION_LOG, same as bundlecli; "off" disables)
+	var submissions *relayrecord.Recorder
+	if root := defaultStr(strings.TrimSpace(os.Getenv("SUBMISSION_LOG")), guiProfile.Path("submissions")); !strings.EqualFold(root, "off") {
+		if submissions, err = relayrecord.Start(root, "gui_"+runID); err != nil { appendLogLine(a, "[submissions] recording unavailable: "+err.Error()) } else { man.Outputs = append(man.Outputs, submissions.Dir()) }
+	}
+	defer func() {
+		_ = submissions.Stop()
+		if submissions != nil { appendLogLine(a, fmt.Sprintf("[submissions] %d relay call(s) recorded in %s", submissions.Count(), submissions.Dir())) }
+		man.MarkBlock(context.Background(), ec)
+		man.Result = fmt.Sprintf("%d/%d pair(s) processed", done, total)
+		path := filepath.Join("manifests", "gui_"+runID+".json")
+		if err := man.Write(path); err != nil { appendLogLine(a, "manifest: "+err.Error()); return }
+		appendLogLine(a, fmt.Sprintf("manifest: %s (config %s)", path, man.ConfigHash))
+	}()
 	for i, pr := range pairs {
+		if only != nil && !only(pr) { continue }
 		select { case <-ctx.Done(): appendLogLine(a, "STOP pressed — cancelling"); return; default: }
-		appendLogLine(a, fmt.Sprintf("=== %s ALL: pair %d/%d ===", map[bool]string{true:"Simulate", false:"Run"}[simOnly], i+1, len(pairs)))
+		appendLogLine(a, fmt.Sprintf("=== %s ALL: pair %d/%d ===", map[bool]string{true:"Simulate", false:"Run"}[simOnly], done+1, total))
+		// job record for the History window (see jobstore.go)
+		job := JobRecord{ RunID: runID, Campaign: defaultStr(pr.Campaign, "manual"), Mode: mode, PairIndex: i, Token: pr.Token, From: pr.From, To: pr.To,
+			Operator: gOperator, ImportedBy: pr.ImportedBy, ApprovedBy: appr.By, Approval: appr.What }
 		p := core.Params{
 			RPC: rpc, ChainID: mustBig(chain), Relays: strings.Split(relays, ","), AuthPrivHex: auth,
 			Token: common.HexToAddress(pr.Token), From: common.HexToAddress(pr.From), To: common.HexToAddress(pr.To),
 			AmountWei: mustBig(pr.AmountWei), SafePKHex: safe, FromPKHex: pr.FromPK,
 			Blocks: atoi(blocksS, 6), TipGweiBase: atoi64(tipS, 3), TipMul: atof(tipMulS, 1.25), BaseMul: atoi64(baseMulS, 2), BufferPct: atoi64(bufferS, 5),
-			SimulateOnly: simOnly, SkipIfPaused: true,
-			Logf: func(f string, a2 ...any){ appendLogLine(a, fmt.Sprintf(f, a2...)) },
+			SimulateOnly: simOnly, SkipIfPaused: true, Share: share, AccessList: os.Getenv("ACCESS_LIST") == "1",
+			HeadCheckRPCs: strings.Split(envSecret("HEAD_CHECK_RPCS"), ","), HeadLagWarn: atoi(os.Getenv("HEAD_LAG_WARN"), core.DefaultHeadLagWarn),
+			Logf: func(f string, a2 ...any){
+				line := fmt.Sprintf(f, a2...)
+				appendLogLine(a, line)
+				job.Log = append(job.Log, time.Now().Format("15:04:05 ")+line)
+			},
 			OnSimResult: func(relay, raw string, ok bool, err string){
-				telAdd(TelemetryItem{ Time: time.Now().UTC().Format(time.RFC3339), Action:"eth_callBundle", PairIndex:i, Relay: relay, OK: ok, Error: err, Raw: raw })
+				it := TelemetryItem{ Time: time.Now().UTC().Format(time.RFC3339), Action:"eth_callBundle", PairIndex:i, Relay: relay, OK: ok, Error: err, Raw: raw }
+				telAdd(it)
+				job.Relays = append(job.Relays, it)
 				if simOnly { statsSimulated++ }
 			},
 		}
+		var before balanceSnap
+		if !simOnly { before = snapBalances(ec, p.Token, p.From, p.To, safeAddr) }
 		out, err := core.Run(ctx, ec, p)
+		if !simOnly { for _, r := range p.Relays { if strings.TrimSpace(r) != "" { job.SentTo = append(job.SentTo, core.RelayHost(r)) } } }
+		job.Builder, job.WinRelays = out.Builder, out.WinRelays
+		if !simOnly {
+			d := pairDiff{ Index: i, Token: pr.Token, From: pr.From, To: pr.To, Decimals: pr.Decimals, Before: before, After: snapBalances(ec, p.Token, p.From, p.To, safeAddr), Included: out.Included, Result: defaultStr(out.Reason, "-") }
+			if err != nil { d.Result = "error: " + err.Error() }
+			addRunDiff(d)
+		}
+		job.Time = time.Now().Format("2006-01-02 15:04:05")
 		if err != nil {
+			job.Status, job.Reason = "FAILED", err.Error()
 			appendLogLine(a, "error: "+err.Error())
+			for _, l := range errhelp.Explain(err.Error()) { appendLogLine(a, "  "+l) }
 			// mark FAILED
 			if i < len(pairs) { // defensive
 				// set status if present and refresh table
@@ -53,17 +141,45 @@ func runAll(a fyne.App, simOnly bool, rpc, chain, relays, auth, safe, blocksS, t
 			}
 		} else {
 			appendLogLine(a, "result: " + out.Reason)
+			if out.Err() != nil { for _, l := range errhelp.ExplainCode(string(out.Code), out.Reason) { appendLogLine(a, "  "+l) } }
+			job.Status, job.Reason = "PENDING", out.Reason
+			if out.Included { if u := explorer.Tx(chain, out.TxHash.Hex()); u != "" { appendLogLine(a, "tx: "+u); job.Reason += " " + u } }
 			if out.Included {
+				job.Status = "COMPLETED"
 				statsRescued++
 				if i < len(pairStatus) { pairStatus[i] = "COMPLETED" }
 			} else {
 				if i < len(pairStatus) { pairStatus[i] = "PENDING" }
 			}
 		}
+		jobAppend(job)
 		// refresh grid, if it exists
 		if pairsTable != nil { pairsTable.Refresh() }
-		if logProg != nil { logProg.SetValue(float64(i+1)) }
-		if logProgLbl != nil { logProgLbl.SetText(fmt.Sprintf("%d/%d", i+1, total)) }
+		done++
+		if logProg != nil { logProg.SetValue(float64(done)) }
+		if logProgLbl != nil { logProgLbl.SetText(fmt.Sprintf("%d/%d", done, total)) }
 	}
 	appendLogLine(a, "ALL: completed")
+	for _, l := range stagetime.Report() { appendLogLine(a, l) }
+}
+
+// guiManifest builds the run manifest for runAll. Keys become addresses, the RPC URL its host;
+// the input hash covers token,from,to,amount of the rows in this run (not the private keys).
+func guiManifest(runID, mode string, only func(pairRow) bool, rpc, chain, relays, auth, safe, blocksS, tipS, tipMulS, baseMulS, bufferS string) *runmanifest.Manifest {
+	safeAddr, _ := deriveAddrFromPK(safe)
+	authAddr, _ := deriveAddrFromPK(auth)
+	man := runmanifest.New("bundlegui", runID, map[string]string{
+		"mode": mode, "rpc": runmanifest.Endpoint(rpc), "safe": safeAddr, "authSigner": authAddr,
+		"blocks": blocksS, "tipGwei": tipS, "tipMul": tipMulS, "baseMul": baseMulS, "bufferPct": bufferS,
+	})
+	var in strings.Builder
+	for _, pr := range pairs {
+		if only != nil && !only(pr) { continue }
+		fmt.Fprintf(&in, "%s,%s,%s,%s\n", strings.ToLower(pr.Token), strings.ToLower(pr.From), strings.ToLower(pr.To), pr.AmountWei)
+	}
+	man.SetInput("queue", []byte(in.String()))
+	man.SetChainID(mustBig(chain))
+	man.SetRelays(strings.Split(relays, ","))
+	man.Outputs = []string{jobStoreFile}
+	return man
 }
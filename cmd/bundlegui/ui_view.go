@@ -11,6 +11,8 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
 )
 
 // openViewPairsWindow shows the pairs table with filter/sort controls.
@@ -112,9 +114,9 @@ func openViewPairsWindow(a fyne.App, rpc string) {
 			case colTo:
 				padAct.Hide(); padLbl.Show(); lbl.Alignment = fyne.TextAlignLeading; lbl.SetText(shortAddr(pr.To))
 			case colAmtTok:
-				padAct.Hide(); padLbl.Show(); lbl.Alignment = fyne.TextAlignTrailing; lbl.SetText(pr.AmountTokens)
+				padAct.Hide(); padLbl.Show(); lbl.Alignment = fyne.TextAlignTrailing; lbl.SetText(privacy.Amount(pr.AmountTokens))
 			case colAmtWei:
-				padAct.Hide(); padLbl.Show(); lbl.Alignment = fyne.TextAlignTrailing; lbl.SetText(pr.AmountWei)
+				padAct.Hide(); padLbl.Show(); lbl.Alignment = fyne.TextAlignTrailing; lbl.SetText(privacy.Amount(pr.AmountWei))
 			case colDec:
 				padAct.Hide(); padLbl.Show(); lbl.Alignment = fyne.TextAlignCenter;  lbl.SetText(fmt.Sprintf("%d", pr.Decimals))
 			case colActions:
@@ -131,8 +133,9 @@ func openViewPairsWindow(a fyne.App, rpc string) {
 				}
 				delBtn.OnTapped = func() {
 					dialog := widget.NewPopUp(container.NewPadded(widget.NewLabel("Removing row…")), viewWin.Canvas())
-					pairs = append(pairs[:row], pairs[row+1:]...)
-					saveQueueToFile()
+					removePairs("delete row", []int{row})
+					refreshUndo()
+					if pairsTable != nil { pairsTable.Refresh() }
 					rebuildViewIdx()
 					table.Refresh()
 					dialog.Hide()
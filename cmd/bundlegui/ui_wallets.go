@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ethereum/go-ethereum/common"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/privacy"
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
+	"github.com/ligun0805/bundle-rescue/internal/units"
+)
+
+// walletGroup is one compromised wallet with the queue rows of its tokens.
+type walletGroup struct {
+	From string
+	Idx  []int // indices into pairs
+}
+
+// walletValues caches ETH quotes per (from|token) row key; filled by "VALUE".
+var (
+	walletValuesMu sync.Mutex
+	walletValues   = map[string]*big.Int{}
+)
+
+var walletWin fyne.Window
+
+// groupByWallet clusters the queue by FROM (case-insensitive), biggest wallets first.
+func groupByWallet() []walletGroup {
+	idx := map[string]int{}
+	var out []walletGroup
+	for i, p := range pairs {
+		k := strings.ToLower(strings.TrimSpace(p.From))
+		j, ok := idx[k]
+		if !ok {
+			j = len(out)
+			idx[k] = j
+			out = append(out, walletGroup{From: p.From})
+		}
+		out[j].Idx = append(out[j].Idx, i)
+	}
+	sort.SliceStable(out, func(a, b int) bool { return len(out[a].Idx) > len(out[b].Idx) })
+	return out
+}
+
+// walletStatusSummary counts row statuses, e.g. "2 COMPLETED · 1 PENDING".
+func walletStatusSummary(g walletGroup) string {
+	cnt := map[string]int{}
+	for _, i := range g.Idx {
+		cnt[statusOf(i)]++
+	}
+	var parts []string
+	for _, s := range []string{"COMPLETED", "PENDING", "FAILED"} {
+		if cnt[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", cnt[s], s))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+func statusOf(i int) string {
+	if i < len(pairStatus) && pairStatus[i] != "" {
+		return pairStatus[i]
+	}
+	return "PENDING"
+}
+
+// walletTotalETH sums cached quotes; known=false when some row is not priced yet.
+func walletTotalETH(g walletGroup) (total *big.Int, known bool) {
+	walletValuesMu.Lock()
+	defer walletValuesMu.Unlock()
+	total, known = new(big.Int), true
+	for _, i := range g.Idx {
+		v, ok := walletValues[pairKey(pairs[i])]
+		if !ok {
+			known = false
+			continue
+		}
+		total.Add(total, v)
+	}
+	return total, known
+}
+
+// quoteWallet prices every token of the wallet in ETH via UniswapV2 (best-effort).
+func quoteWallet(rpc string, g walletGroup) error {
+	ec, err := newEthClientWithTimeout(rpc)
+	if err != nil {
+		return err
+	}
+	defer ec.Close()
+	for _, i := range g.Idx {
+		p := pairs[i]
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		v, err := core.QuoteTokenToETH(ctx, ec, common.HexToAddress(p.Token), mustBig(p.BalanceWei))
+		cancel()
+		if err != nil {
+			v = big.NewInt(0) // no pool / no quote: counts as worthless
+		}
+		walletValuesMu.Lock()
+		walletValues[pairKey(p)] = v
+		walletValuesMu.Unlock()
+	}
+	return nil
+}
+
+func fmtETHWei(v *big.Int) string {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(v), big.NewFloat(1e18)).Float64()
+	return fmt.Sprintf("%.6f ETH", f)
+}
+
+// openWalletsWindow shows the queue grouped by wallet with per-wallet actions:
+// rescue only this wallet's pairs, and price the wallet (sum of token quotes).
+func openWalletsWindow(a fyne.App, rpc func() string, rescueWallet func(from string)) {
+	if walletWin != nil {
+		walletWin.Show()
+		walletWin.RequestFocus()
+		return
+	}
+	walletWin = a.NewWindow("Wallets")
+	walletWin.SetOnClosed(func() { walletWin = nil })
+
+	acc := widget.NewAccordion()
+	countLbl := widget.NewLabel("")
+	var rebuild func()
+	rebuild = func() {
+		groups := groupByWallet()
+		acc.Items = nil
+		for _, g := range groups {
+			g := g
+			title := fmt.Sprintf("%s — %d token(s) — %s", shortAddr(g.From), len(g.Idx), walletStatusSummary(g))
+			if t, known := walletTotalETH(g); known {
+				title += " — " + privacy.Amount(fmtETHWei(t))
+			}
+			rows := container.NewVBox()
+			for _, i := range g.Idx {
+				p := pairs[i]
+				line := fmt.Sprintf("%s  %s  [%s]", p.Token, privacy.Amount(defaultStr(p.BalanceTokens, p.AmountTokens)), statusOf(i))
+				walletValuesMu.Lock()
+				if v, ok := walletValues[pairKey(p)]; ok {
+					line += "  ≈ " + privacy.Amount(fmtETHWei(v))
+				}
+				walletValuesMu.Unlock()
+				lbl := widget.NewLabel(line)
+				lbl.TextStyle = fyne.TextStyle{Monospace: true}
+				rows.Add(lbl)
+			}
+			rescueBtn := widget.NewButtonWithIcon("RESCUE WALLET", theme.ConfirmIcon(), func() {
+				dialog.ShowConfirm("Rescue wallet", fmt.Sprintf("Run %d pair(s) of %s?", len(g.Idx), g.From), func(ok bool) {
+					if ok {
+						rescueWallet(g.From)
+					}
+				}, walletWin)
+			})
+			valueBtn := widget.NewButtonWithIcon("VALUE", theme.ViewRefreshIcon(), func() {
+				go func() {
+					if err := quoteWallet(rpc(), g); err != nil {
+						dialog.ShowError(err, walletWin)
+						return
+					}
+					rebuild()
+				}()
+			})
+			rows.Add(container.NewHBox(rescueBtn, valueBtn))
+			acc.Append(widget.NewAccordionItem(title, rows))
+		}
+		countLbl.SetText(fmt.Sprintf("%d wallet(s), %d pair(s)", len(groups), len(pairs)))
+		acc.Refresh()
+	}
+	exportBtn := widget.NewButtonWithIcon("Export CSV", theme.DocumentSaveIcon(), func() {
+		path, err := exportWalletReport()
+		if err != nil {
+			dialog.ShowError(err, walletWin)
+			return
+		}
+		fyne.CurrentApp().SendNotification(&fyne.Notification{Title: "Saved", Content: path})
+	})
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { rebuild() })
+	rebuild()
+	top := container.NewBorder(nil, nil, countLbl, container.NewHBox(refreshBtn, exportBtn))
+	walletWin.SetContent(container.NewBorder(top, nil, nil, nil, container.NewVScroll(acc)))
+	walletWin.Resize(fyne.NewSize(980, 620))
+	walletWin.Show()
+}
+
+// exportWalletReport writes log_data/wallets_<ts>.csv: one row per token plus a TOTAL row per wallet.
+func exportWalletReport() (string, error) {
+	exe, _ := os.Executable()
+	dir := filepath.Join(filepath.Dir(exe), "log_data")
+	_ = os.MkdirAll(dir, 0o755)
+	path := filepath.Join(dir, "wallets_"+time.Now().Format("20060102_150405")+".csv")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	_ = w.Write([]string{"wallet", "token", "balanceTokens", "status", "valueETH", "balanceWei", "valueWei"})
+	for _, g := range groupByWallet() {
+		for _, i := range g.Idx {
+			p := pairs[i]
+			val, valWei := "", ""
+			walletValuesMu.Lock()
+			if v, ok := walletValues[pairKey(p)]; ok {
+				val, valWei = units.Format(v, 18, exportPrecision()), v.String()
+			}
+			walletValuesMu.Unlock()
+			_ = w.Write([]string{g.From, p.Token, exportBalance(p), statusOf(i), val, balanceWeiOf(p), valWei})
+		}
+		total, known := walletTotalETH(g)
+		tv, tvWei := "", ""
+		if known {
+			tv, tvWei = units.Format(total, 18, exportPrecision()), total.String()
+		}
+		_ = w.Write([]string{g.From, "TOTAL", fmt.Sprintf("%d token(s)", len(g.Idx)), walletStatusSummary(g), tv, "", tvWei})
+	}
+	return path, w.Error()
+}
+
+// walletReportJSON is the wallet → tokens view embedded in the telemetry export.
+func walletReportJSON() []reportschema.WalletReport {
+	var out []reportschema.WalletReport
+	for _, g := range groupByWallet() {
+		var toks []reportschema.WalletToken
+		for _, i := range g.Idx {
+			p := pairs[i]
+			toks = append(toks, reportschema.WalletToken{Token: p.Token, BalanceTokens: exportBalance(p), BalanceWei: balanceWeiOf(p), Status: statusOf(i), Warnings: p.Warnings})
+		}
+		w := reportschema.WalletReport{Wallet: g.From, Tokens: toks, Status: walletStatusSummary(g)}
+		if t, known := walletTotalETH(g); known {
+			w.ValueETH, w.ValueWei = units.Format(t, 18, exportPrecision()), t.String()
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// exportPrecision: fractional digits of amounts in exported reports (OUTPUT_PRECISION,
+// default all decimals). Raw wei columns are always written next to them.
+func exportPrecision() int { return units.ParsePrecision(os.Getenv("OUTPUT_PRECISION"), units.Full) }
+
+// balanceWeiOf is the raw balance of a row: the scanned balance, else the queued amount.
+func balanceWeiOf(p pairRow) string { return strings.TrimSpace(defaultStr(p.BalanceWei, p.AmountWei)) }
+
+// exportBalance formats balanceWeiOf with exportPrecision when the decimals are known.
+func exportBalance(p pairRow) string {
+	if wei := balanceWeiOf(p); wei != "" && p.Decimals >= 0 {
+		return units.FormatString(wei, p.Decimals, exportPrecision())
+	}
+	return defaultStr(p.BalanceTokens, p.AmountTokens)
+}
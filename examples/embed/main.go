@@ -0,0 +1,79 @@
+// Command embed shows pkg/rescue embedded in another Go tool: it triages one pair, prints
+// the plan and, with -send, races the bundle and verifies the tokens left FROM.
+//
+//	RPC_URL=... SAFE_PRIVATE_KEY=... AUTH_PRIVATE_KEY=... RELAYS=... \
+//	  go run ./examples/embed -token 0x... -from-key 0x... -to 0x... [-send]
+//
+// Without -send the bundle is only simulated (Config.DryRun).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ligun0805/bundle-rescue/pkg/rescue"
+)
+
+func main() {
+	token := flag.String("token", "", "token address")
+	fromKey := flag.String("from-key", "", "private key of the compromised wallet")
+	to := flag.String("to", "", "recipient (default: the SAFE)")
+	send := flag.Bool("send", false, "send the bundle (default: simulate only)")
+	flag.Parse()
+	if !common.IsHexAddress(*token) || *fromKey == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var relays []string
+	for _, r := range strings.Split(os.Getenv("RELAYS"), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			relays = append(relays, r)
+		}
+	}
+	c, err := rescue.New(ctx, rescue.Config{
+		RPC:        os.Getenv("RPC_URL"),
+		Relays:     relays,
+		AuthKeyHex: os.Getenv("AUTH_PRIVATE_KEY"),
+		SafeKeyHex: os.Getenv("SAFE_PRIVATE_KEY"),
+		DryRun:     !*send,
+		Logf:       func(f string, a ...any) { log.Printf(f, a...) },
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	pair := rescue.Pair{Token: common.HexToAddress(*token), FromKeyHex: *fromKey, To: c.Safe()}
+	if common.IsHexAddress(*to) {
+		pair.To = common.HexToAddress(*to)
+	}
+
+	rep, err := c.Rescue(ctx, []rescue.Pair{pair})
+	for _, a := range rep.Plan.Skipped {
+		fmt.Printf("skipped %s: %s (%s)\n", a.Pair.From.Hex(), a.Code, a.Reason)
+	}
+	if errors.Is(err, rescue.ErrNothingToDo) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !rep.Plan.Funded {
+		fmt.Printf("warning: SAFE holds %s wei, the worst case is %s wei\n", rep.Plan.SafeBalance, rep.Plan.MaxCostWei)
+	}
+	for _, v := range rep.Verifications {
+		e := v.Execution
+		fmt.Printf("%s %s: %s verified=%v (%s)\n", e.Step.Pair.From.Hex(), e.Step.Amount, e.Outcome, v.Verified, v.Note)
+	}
+}
@@ -0,0 +1,92 @@
+// Package bribe sizes coinbase bribes. The advisor proposes a bribe from what builders were
+// paid in recent blocks (bundlecore.ScanCoinbaseBribes) and from the value being recovered,
+// and guards it with a cap in percent of that value, so a bribe never eats the rescue. Each
+// bribed run is appended to an efficacy log (included or not); the advisor reads it back and
+// raises its proposal to the share of value that got bundles included before.
+package bribe
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Pricer values amount of token in wei of ETH. It is the pluggable part of the guard: the
+// CLI uses the UniswapV2 quote (bundlecore.QuoteTokenToETH); any other source fits.
+type Pricer func(ctx context.Context, token common.Address, amount *big.Int) (*big.Int, error)
+
+// Policy holds the sizing knobs, in percent of the recovered value.
+type Policy struct {
+	TargetPct float64 // proposal when there is no market sample (BRIBE_TARGET_PCT)
+	MaxPct    float64 // hard cap (BRIBE_MAX_PCT); 0 = no cap
+}
+
+// Advice is the advisor's output. Nil amounts are unknown.
+type Advice struct {
+	ValueWei    *big.Int // recovered value in ETH
+	MarketP50   *big.Int // median bribe of recent blocks
+	MarketP95   *big.Int
+	Samples     int
+	TunedWei    *big.Int // value × median share of past included runs (efficacy log)
+	ProposedWei *big.Int
+	CapWei      *big.Int // nil = no cap (value unknown or MaxPct 0)
+	Capped      bool     // the proposal was cut down to CapWei
+}
+
+// Advise builds the proposal: the market median (or TargetPct of the value without
+// samples), raised to the tuned share from the efficacy log, then cut to MaxPct of the value.
+func Advise(valueWei *big.Int, p50, p95 *big.Int, samples int, eff Efficacy, pol Policy) Advice {
+	a := Advice{ValueWei: valueWei, Samples: samples}
+	if samples > 0 {
+		a.MarketP50, a.MarketP95 = p50, p95
+	}
+	known := valueWei != nil && valueWei.Sign() > 0
+	switch {
+	case a.MarketP50 != nil && a.MarketP50.Sign() > 0:
+		a.ProposedWei = new(big.Int).Set(a.MarketP50)
+	case known:
+		a.ProposedWei = pct(valueWei, pol.TargetPct)
+	default:
+		a.ProposedWei = big.NewInt(0)
+	}
+	if known && eff.IncludedMedianPct > 0 {
+		a.TunedWei = pct(valueWei, eff.IncludedMedianPct)
+		if a.TunedWei.Cmp(a.ProposedWei) > 0 {
+			a.ProposedWei = new(big.Int).Set(a.TunedWei)
+		}
+	}
+	if known && pol.MaxPct > 0 {
+		a.CapWei = pct(valueWei, pol.MaxPct)
+		if a.ProposedWei.Cmp(a.CapWei) > 0 {
+			a.ProposedWei, a.Capped = new(big.Int).Set(a.CapWei), true
+		}
+	}
+	return a
+}
+
+// Clamp enforces the cap on a bribe the operator chose; ok=false when it was cut down.
+func (a Advice) Clamp(wei *big.Int) (*big.Int, bool) {
+	if wei == nil || a.CapWei == nil || wei.Cmp(a.CapWei) <= 0 {
+		return wei, true
+	}
+	return new(big.Int).Set(a.CapWei), false
+}
+
+// SharePct is wei in percent of the value (0 when the value is unknown).
+func SharePct(wei, valueWei *big.Int) float64 {
+	if wei == nil || valueWei == nil || valueWei.Sign() <= 0 {
+		return 0
+	}
+	f, _ := new(big.Rat).SetFrac(new(big.Int).Mul(wei, big.NewInt(100)), valueWei).Float64()
+	return f
+}
+
+// pct returns v × p / 100 (p with two decimals).
+func pct(v *big.Int, p float64) *big.Int {
+	if v == nil || p <= 0 {
+		return big.NewInt(0)
+	}
+	bp := big.NewInt(int64(p*100 + 0.5))
+	return new(big.Int).Div(new(big.Int).Mul(v, bp), big.NewInt(10_000))
+}
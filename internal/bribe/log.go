@@ -0,0 +1,117 @@
+package bribe
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// logHeader is the efficacy log format: one row per bribed run, appended.
+var logHeader = []string{"time", "token", "from", "valueWei", "bribeWei", "sharePct", "included", "reason"}
+
+// Record is one bribed run.
+type Record struct {
+	Time     time.Time
+	Token    common.Address
+	From     common.Address
+	ValueWei *big.Int // nil = unknown
+	BribeWei *big.Int
+	Included bool
+	Reason   string
+}
+
+// Append adds r to the efficacy log at path (created with a header when missing).
+func Append(path string, r Record) error {
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if errors.Is(statErr, fs.ErrNotExist) {
+		_ = w.Write(logHeader)
+	}
+	value, share := "", ""
+	if r.ValueWei != nil && r.ValueWei.Sign() > 0 {
+		value, share = r.ValueWei.String(), strconv.FormatFloat(SharePct(r.BribeWei, r.ValueWei), 'f', 2, 64)
+	}
+	_ = w.Write([]string{
+		r.Time.UTC().Format(time.RFC3339), r.Token.Hex(), r.From.Hex(),
+		value, r.BribeWei.String(), share, strconv.FormatBool(r.Included), r.Reason,
+	})
+	w.Flush()
+	if err := w.Error(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Efficacy summarizes the log.
+type Efficacy struct {
+	Runs              int
+	Included          int
+	IncludedMedianPct float64 // median bribe share of value over included runs with a known value
+	MissedMedianPct   float64 // the same over runs that were not included
+}
+
+// Summarize reads the efficacy log; a missing file is an empty summary.
+func Summarize(path string) (Efficacy, error) {
+	var e Efficacy
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return e, fmt.Errorf("bribe log %s: %w", path, err)
+	}
+	var hit, miss []float64
+	for i, row := range rows {
+		if len(row) < len(logHeader) || (i == 0 && row[0] == logHeader[0]) {
+			continue
+		}
+		inc, _ := strconv.ParseBool(strings.TrimSpace(row[6]))
+		e.Runs++
+		if inc {
+			e.Included++
+		}
+		share, err := strconv.ParseFloat(strings.TrimSpace(row[5]), 64)
+		if err != nil || share <= 0 {
+			continue
+		}
+		if inc {
+			hit = append(hit, share)
+		} else {
+			miss = append(miss, share)
+		}
+	}
+	e.IncludedMedianPct, e.MissedMedianPct = median(hit), median(miss)
+	return e, nil
+}
+
+func median(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	sort.Float64s(v)
+	if len(v)%2 == 1 {
+		return v[len(v)/2]
+	}
+	return (v[len(v)/2-1] + v[len(v)/2]) / 2
+}
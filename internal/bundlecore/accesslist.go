@@ -0,0 +1,96 @@
+package bundlecore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Access list of the transfer tx (Params.AccessList): eth_createAccessList returns the
+// addresses and storage slots the call touches; pre-declaring them (EIP-2930) turns the
+// cold SLOADs of storage-heavy tokens (proxies, fee/blacklist lookups) into warm ones. The
+// list is used only when the estimate with it is lower than without; an RPC without the
+// method is remembered and the plain tx is sent.
+
+// accessListUnsupported holds the RPC URLs that answered eth_createAccessList with
+// "method not found", so later runs in the same process do not ask again.
+var accessListUnsupported sync.Map
+
+// accessListResult is the answer of eth_createAccessList.
+type accessListResult struct {
+	AccessList types.AccessList `json:"accessList"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// createAccessList asks the RPC for the access list of the call from -> to(data). No fee
+// fields are sent; nodes that fill in a price (geth) need FROM to hold the gas, so a drained
+// FROM gets an "insufficient funds" error there and the plain tx.
+func createAccessList(ctx context.Context, ec *ethclient.Client, from, to common.Address, data []byte) (types.AccessList, error) {
+	callObj := map[string]interface{}{"from": from, "to": to, "data": hexutil.Encode(data)}
+	var res accessListResult
+	if err := ec.Client().CallContext(ctx, &res, "eth_createAccessList", callObj, "latest"); err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+	return res.AccessList, nil
+}
+
+// methodUnsupported reports an RPC error that means the endpoint does not have the method.
+func methodUnsupported(err error) bool {
+	var re rpc.Error
+	if errors.As(err, &re) && re.ErrorCode() == -32601 {
+		return true
+	}
+	low := strings.ToLower(err.Error())
+	return strings.Contains(low, "method not found") || strings.Contains(low, "does not exist") ||
+		strings.Contains(low, "not supported") || strings.Contains(low, "unsupported") || strings.Contains(low, "not available")
+}
+
+// transferAccessList returns the access list for the transfer tx and its gas limit, or
+// (nil, plainGas) when the RPC has no eth_createAccessList, the call fails or the list does
+// not save gas. quiet suppresses the log line (later attempts reuse what the first said).
+func transferAccessList(ctx context.Context, ec *ethclient.Client, p *Params, to common.Address, data []byte, plainGas uint64, quiet bool) (types.AccessList, uint64) {
+	if _, no := accessListUnsupported.Load(p.RPC); no {
+		return nil, plainGas
+	}
+	logf := p.logf
+	if quiet {
+		logf = func(string, ...any) {}
+	}
+	al, err := createAccessList(ctx, ec, p.From, to, data)
+	if err != nil {
+		if methodUnsupported(err) {
+			accessListUnsupported.Store(p.RPC, true)
+			p.logf("[access-list] eth_createAccessList not supported by RPC — plain tx")
+			return nil, plainGas
+		}
+		logf("[access-list] eth_createAccessList failed (%v) — plain tx", err)
+		return nil, plainGas
+	}
+	if len(al) == 0 {
+		logf("[access-list] empty list — plain tx")
+		return nil, plainGas
+	}
+	gas, err := ec.EstimateGas(ctx, ethereum.CallMsg{From: p.From, To: &to, Data: data, AccessList: al})
+	if err != nil || gas == 0 {
+		logf("[access-list] estimateGas with list failed (%v) — plain tx", err)
+		return nil, plainGas
+	}
+	if gas >= plainGas {
+		logf("[access-list] %d address(es), %d slot(s): gas %d -> %d, no saving — plain tx", len(al), al.StorageKeys(), plainGas, gas)
+		return nil, plainGas
+	}
+	logf("[access-list] %d address(es), %d slot(s): gas %d -> %d", len(al), al.StorageKeys(), plainGas, gas)
+	return al, gas
+}
@@ -0,0 +1,165 @@
+package bundlecore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// SafeERC20-style checks for routes that spend an allowance (approve, then a call whose
+// transferFrom pulls the tokens). allowance() is read before and after the approvals, the
+// approvals are eth_call'ed from the owner, and the whole sequence can be simulated as one
+// eth_callBundle, where the follow-up runs on the state the approvals leave behind.
+// Tokens that refuse to change a non-zero allowance (USDT-style) get approve(0) first.
+
+// Gas limits of the simulated bundle: the follow-up cannot be estimated before the approval
+// exists, and the simulation only has to run, not be priced.
+const (
+	approveSimGas  = 100_000
+	followUpSimGas = 600_000
+)
+
+// ApprovalPlan is what it takes for spender to pull Need of owner's token.
+type ApprovalPlan struct {
+	Token, Owner, Spender common.Address
+	Allowance             *big.Int // allowance() before
+	Need                  *big.Int
+	Calls                 [][]byte // approve calls to send first, in order (nil: the allowance suffices)
+	ZeroFirst             bool     // approve(0) comes first: the token refuses to change a non-zero allowance
+}
+
+// PlanApproval reads allowance(owner, spender) and, when it is below need, plans the
+// approve calls that set it to value (value >= need, e.g. what a whole plan spends).
+func PlanApproval(ctx context.Context, ec *ethclient.Client, token, owner, spender common.Address, need, value *big.Int) (ApprovalPlan, error) {
+	ap := ApprovalPlan{Token: token, Owner: owner, Spender: spender, Need: need}
+	cur, err := Allowance(ctx, ec, token, owner, spender)
+	if err != nil {
+		return ap, fmt.Errorf("allowance: %w", err)
+	}
+	ap.Allowance = cur
+	if cur.Cmp(need) >= 0 {
+		return ap, nil
+	}
+	approve := EncodeERC20Approve(spender, value)
+	err = callApprove(ctx, ec, token, owner, approve)
+	if err == nil {
+		ap.Calls = [][]byte{approve}
+		return ap, nil
+	}
+	if cur.Sign() == 0 {
+		return ap, fmt.Errorf("approve(%s): %w", value, err)
+	}
+	if zerr := callApprove(ctx, ec, token, owner, EncodeERC20Approve(spender, new(big.Int))); zerr != nil {
+		return ap, fmt.Errorf("approve(%s): %v; approve(0): %w", value, err, zerr)
+	}
+	ap.Calls = [][]byte{EncodeERC20Approve(spender, new(big.Int)), approve}
+	ap.ZeroFirst = true
+	return ap, nil
+}
+
+// callApprove eth_calls an approve from owner: a revert or a false return is an error
+// (SafeERC20 treats both as failure; no return data is accepted).
+func callApprove(ctx context.Context, ec *ethclient.Client, token, owner common.Address, data []byte) error {
+	ret, err := callWithRetry(ctx, ec, ethereum.CallMsg{From: owner, To: &token, Data: data})
+	if err != nil {
+		return errors.New(revertReason(err))
+	}
+	if len(ret) >= 32 && new(big.Int).SetBytes(ret[:32]).Sign() == 0 {
+		return errors.New("approve() returned false")
+	}
+	return nil
+}
+
+// CheckAllowance re-reads allowance() once the approvals are mined: some tokens accept
+// approve() without storing the value.
+func (ap ApprovalPlan) CheckAllowance(ctx context.Context, ec *ethclient.Client) error {
+	cur, err := Allowance(ctx, ec, ap.Token, ap.Owner, ap.Spender)
+	if err != nil {
+		return fmt.Errorf("allowance: %w", err)
+	}
+	if cur.Cmp(ap.Need) < 0 {
+		return fmt.Errorf("allowance is %s after approve, %s needed", cur, ap.Need)
+	}
+	return nil
+}
+
+// ErrNoSimRelay: SimulateApprovalRoute needs a relay that simulates bundles.
+var ErrNoSimRelay = errors.New("no relay simulates bundles")
+
+// SimulateApprovalRoute signs the approve calls of ap and the follow-up call (followTo,
+// followData: the call whose transferFrom spends the allowance) with consecutive nonces of
+// prv and simulates them as one bundle at head+1 on the relays. nil when a relay
+// ran the whole bundle without a revert.
+func SimulateApprovalRoute(ctx context.Context, ec *ethclient.Client, chainID *big.Int, prv *ecdsa.PrivateKey, relays []string, authPrv *ecdsa.PrivateKey, ap ApprovalPlan, followTo common.Address, followData []byte) error {
+	rs, err := NewRelays(relays, RelayConfig{AuthPrv: authPrv})
+	if err != nil {
+		return err
+	}
+	if len(rs) == 0 {
+		return ErrNoSimRelay
+	}
+	from := gethcrypto.PubkeyToAddress(prv.PublicKey)
+	nonce, err := ec.PendingNonceAt(ctx, from)
+	if err != nil {
+		return fmt.Errorf("nonce: %w", err)
+	}
+	tip, err := ec.SuggestGasTipCap(ctx)
+	if err != nil {
+		return fmt.Errorf("tip: %w", err)
+	}
+	base, head, err := latestBaseFee(ctx, ec)
+	if err != nil {
+		return err
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(base, big.NewInt(2)), tip)
+
+	var signed []*types.Transaction
+	var hexes []string
+	add := func(to common.Address, gas uint64, data []byte) error {
+		tx, err := signTx(buildDynamicTx(chainID, nonce+uint64(len(signed)), &to, big.NewInt(0), gas, tip, feeCap, data), chainID, prv)
+		if err != nil {
+			return err
+		}
+		signed, hexes = append(signed, tx), append(hexes, txAsHex(tx))
+		return nil
+	}
+	for _, c := range ap.Calls {
+		if err := add(ap.Token, approveSimGas, c); err != nil {
+			return err
+		}
+	}
+	if err := add(followTo, followUpSimGas, followData); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var fails []string
+	unsupported := 0
+	p := Params{OnSimResult: func(relay, raw string, ok bool, errStr string) {
+		if !ok {
+			mu.Lock()
+			fails = append(fails, relay+": "+errStr)
+			if errStr == simUnsupported {
+				unsupported++
+			}
+			mu.Unlock()
+		}
+	}}
+	if simulateBundle(ctx, &p, rs, Bundle{Txs: signed, Hexes: hexes, Block: new(big.Int).Add(head, big.NewInt(1))}) {
+		return nil
+	}
+	if unsupported == len(rs) {
+		return ErrNoSimRelay
+	}
+	return fmt.Errorf("bundle simulation failed (%s)", strings.Join(fails, "; "))
+}
@@ -0,0 +1,249 @@
+package bundlecore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Winner attribution: the same bundle goes to every relay, so inclusion alone does not say
+// whose builder landed it. The inclusion block's fee recipient and extraData are matched
+// against a registry of known builders, and the builder's relays among RELAYS get the win
+// (Result.Builder, Result.WinRelays). The registry is built in and extended by
+// BUILDER_REGISTRY (a JSON file, see LoadBuilderEnv):
+//
+//	{"builders": [{"name": "mybuilder", "extraData": ["mybuilder.xyz"], "feeRecipients": ["0x…"], "relays": ["mybuilder.xyz"]}]}
+
+// DefaultBuilderFile is the builder registry name (inside the profile directory with --profile).
+const DefaultBuilderFile = "builders.json"
+
+// Builder is one known block builder.
+type Builder struct {
+	Name string `json:"name"`
+	// ExtraData are case-insensitive substrings of the block's extraData.
+	ExtraData []string `json:"extraData,omitempty"`
+	// FeeRecipients are the builder's coinbase addresses.
+	FeeRecipients []string `json:"feeRecipients,omitempty"`
+	// Relays are case-insensitive substrings of the RELAYS entries that reach this builder.
+	Relays []string `json:"relays,omitempty"`
+}
+
+// BuilderRegistry is the builder registry file.
+type BuilderRegistry struct {
+	Builders []Builder `json:"builders"`
+}
+
+var (
+	buildersMu sync.RWMutex
+	builders   = builtinBuilders()
+)
+
+func builtinBuilders() []Builder {
+	return []Builder{
+		{Name: "beaverbuild", ExtraData: []string{"beaverbuild"}, FeeRecipients: []string{"0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5"}, Relays: []string{"beaverbuild"}},
+		{Name: "titan", ExtraData: []string{"titanbuilder", "titan (titan"}, FeeRecipients: []string{"0x4838B106FCe9647Bdf1E7877BF73cE8B0BAD5f97"}, Relays: []string{"titanbuilder"}},
+		{Name: "buildernet", ExtraData: []string{"buildernet"}, Relays: []string{"buildernet", "flashbots.net", "beaverbuild"}},
+		{Name: "flashbots", ExtraData: []string{"flashbots", "illuminate dmocratize dstribute"}, Relays: []string{"flashbots.net"}},
+		{Name: "rsync", ExtraData: []string{"rsync-builder"}, Relays: []string{"rsync-builder"}},
+		{Name: "bloxroute", ExtraData: []string{"bloxroute", "blxr"}, Relays: []string{"blxrbdn", "bloxroute"}},
+		{Name: "quasar", ExtraData: []string{"quasar"}, Relays: []string{"quasar"}},
+		{Name: "penguinbuild", ExtraData: []string{"penguinbuild"}, Relays: []string{"penguinbuild"}},
+		{Name: "jetbldr", ExtraData: []string{"jetbldr", "jetbuilder"}, Relays: []string{"jetbldr"}},
+	}
+}
+
+// RegisterBuilder adds b to the registry ahead of the built-in builders; a builder of the
+// same name is replaced.
+func RegisterBuilder(b Builder) {
+	buildersMu.Lock()
+	defer buildersMu.Unlock()
+	out := []Builder{b}
+	for _, old := range builders {
+		if !strings.EqualFold(old.Name, b.Name) {
+			out = append(out, old)
+		}
+	}
+	builders = out
+}
+
+// LoadBuilderRegistry reads path; a missing file is an empty registry.
+func LoadBuilderRegistry(path string) (BuilderRegistry, error) {
+	var r BuilderRegistry
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, fmt.Errorf("builder registry %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, fmt.Errorf("builder registry %s: %w", path, err)
+	}
+	for _, e := range r.Builders {
+		if strings.TrimSpace(e.Name) == "" {
+			return r, fmt.Errorf("builder registry %s: builder without a name", path)
+		}
+		for _, a := range e.FeeRecipients {
+			if !common.IsHexAddress(a) {
+				return r, fmt.Errorf("builder registry %s: %s: fee recipient %q is not an address", path, e.Name, a)
+			}
+		}
+	}
+	return r, nil
+}
+
+// BuilderFile is BUILDER_REGISTRY, else def.
+func BuilderFile(def string) string {
+	if v := strings.TrimSpace(os.Getenv("BUILDER_REGISTRY")); v != "" {
+		return v
+	}
+	return def
+}
+
+// LoadBuilderEnv registers the builders of BuilderFile(def) (a missing file is fine).
+// Call once at startup.
+func LoadBuilderEnv(def string) error {
+	path := BuilderFile(def)
+	if path == "" {
+		return nil
+	}
+	r, err := LoadBuilderRegistry(path)
+	if err != nil {
+		return fmt.Errorf("BUILDER_REGISTRY: %w", err)
+	}
+	for _, b := range r.Builders {
+		RegisterBuilder(b)
+	}
+	return nil
+}
+
+// Attribution is who built an inclusion block.
+type Attribution struct {
+	Block        *big.Int
+	FeeRecipient common.Address
+	ExtraData    string // printable part of the header's extraData
+	Builder      string // registry name; "" = unknown builder
+}
+
+// String is the log form: "titan (fee recipient 0x…, extraData "Titan (titanbuilder.xyz)")".
+func (a Attribution) String() string {
+	name := a.Builder
+	if name == "" {
+		name = "unknown builder"
+	}
+	return fmt.Sprintf("%s (fee recipient %s, extraData %q)", name, a.FeeRecipient.Hex(), a.ExtraData)
+}
+
+// AttributeBlock reads the header of block and matches it against the registry: the fee
+// recipient first (exact), then extraData.
+func AttributeBlock(ctx context.Context, ec *ethclient.Client, block *big.Int) (Attribution, error) {
+	h, err := ec.HeaderByNumber(ctx, block)
+	if err != nil {
+		return Attribution{}, err
+	}
+	if h == nil {
+		return Attribution{}, fmt.Errorf("block %s not found", block)
+	}
+	a := Attribution{Block: h.Number, FeeRecipient: h.Coinbase, ExtraData: printableExtra(h.Extra)}
+	a.Builder = matchBuilder(a.FeeRecipient, a.ExtraData)
+	return a, nil
+}
+
+func matchBuilder(feeRecipient common.Address, extra string) string {
+	buildersMu.RLock()
+	defer buildersMu.RUnlock()
+	for _, b := range builders {
+		for _, fr := range b.FeeRecipients {
+			if common.HexToAddress(fr) == feeRecipient {
+				return b.Name
+			}
+		}
+	}
+	low := strings.ToLower(extra)
+	for _, b := range builders {
+		for _, s := range b.ExtraData {
+			if s != "" && strings.Contains(low, strings.ToLower(s)) {
+				return b.Name
+			}
+		}
+	}
+	return ""
+}
+
+// printableExtra keeps the printable characters of extraData (builders write a name or URL).
+func printableExtra(extra []byte) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, string(extra)))
+}
+
+// WinRelays returns the relays among entries (RELAYS) that reach builder, by host: the
+// relays credited with the inclusion. Empty for an unknown builder.
+func WinRelays(builder string, entries []string) []string {
+	if builder == "" {
+		return nil
+	}
+	buildersMu.RLock()
+	var subs []string
+	for _, b := range builders {
+		if strings.EqualFold(b.Name, builder) {
+			subs = b.Relays
+			break
+		}
+	}
+	buildersMu.RUnlock()
+	var out []string
+	seen := map[string]bool{}
+	for _, e := range entries {
+		low := strings.ToLower(strings.TrimSpace(e))
+		for _, s := range subs {
+			if s != "" && strings.Contains(low, strings.ToLower(s)) {
+				if h := RelayHost(e); !seen[h] {
+					seen[h] = true
+					out = append(out, h)
+				}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// RelayHost is the host of a RELAYS entry (kind prefix dropped), the key relay statistics
+// are kept by: relay URLs often carry API keys.
+func RelayHost(entry string) string {
+	_, u := relayKindFor(strings.TrimSpace(entry))
+	return hostOf(u)
+}
+
+// attributeWin fills in the builder and the winning relays of an included res.
+func attributeWin(ctx context.Context, ec *ethclient.Client, p *Params, res *Result, block *big.Int) {
+	if p.LocalFork || block == nil {
+		return
+	}
+	a, err := AttributeBlock(ctx, ec, block)
+	if err != nil {
+		p.logf("[winner] block %s: %v", block, err)
+		return
+	}
+	res.Builder, res.WinRelays = a.Builder, WinRelays(a.Builder, p.Relays)
+	DefaultMetrics.builderWin(a.Builder)
+	if len(res.WinRelays) > 0 {
+		p.logf("[winner] block %s built by %s — via %s", block, a, strings.Join(res.WinRelays, ", "))
+	} else {
+		p.logf("[winner] block %s built by %s — none of RELAYS is known to reach it", block, a)
+	}
+}
@@ -0,0 +1,145 @@
+package bundlecore
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Cache lets embedding applications plug their own storage (Redis, disk, ...)
+// for CheckRestrictions / Preflight results. Values are opaque JSON blobs.
+// Keys do not include the chain: use one Cache per network.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, val []byte, ttl time.Duration)
+}
+
+// DefaultCacheTTL is used by the Cached* helpers when ttl <= 0.
+const DefaultCacheTTL = 5 * time.Minute
+
+// MemoryCache is the default in-process Cache with per-entry TTL.
+type MemoryCache struct {
+	mu sync.Mutex
+	m  map[string]memEntry
+}
+
+type memEntry struct {
+	val []byte
+	exp time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache { return &MemoryCache{m: map[string]memEntry{}} }
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.exp.IsZero() && time.Now().After(e.exp) {
+		delete(c.m, key)
+		return nil, false
+	}
+	return e.val, true
+}
+
+func (c *MemoryCache) Set(key string, val []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := memEntry{val: val}
+	if ttl > 0 {
+		e.exp = time.Now().Add(ttl)
+	}
+	c.m[key] = e
+}
+
+func cacheKey(kind string, parts ...string) string {
+	return kind + ":" + strings.ToLower(strings.Join(parts, ":"))
+}
+
+// preflightEntry is the cached shape of (ok, reason) preflight results.
+type preflightEntry struct {
+	OK     bool   `json:"ok"`
+	Reason string `json:"reason"`
+}
+
+// CachedCheckRestrictions is CheckRestrictions with an optional cache (nil = no caching).
+// Only successful results are cached.
+func CachedCheckRestrictions(ctx context.Context, c Cache, ttl time.Duration, ec *ethclient.Client, token, from, to common.Address) (TokenRestrictions, error) {
+	if c == nil {
+		return CheckRestrictions(ctx, ec, token, from, to)
+	}
+	key := cacheKey("restr", token.Hex(), from.Hex(), to.Hex())
+	if b, ok := c.Get(key); ok {
+		var tr TokenRestrictions
+		if json.Unmarshal(b, &tr) == nil {
+			return tr, nil
+		}
+	}
+	tr, err := CheckRestrictions(ctx, ec, token, from, to)
+	if err == nil {
+		if b, e := json.Marshal(tr); e == nil {
+			c.Set(key, b, ttlOrDefault(ttl))
+		}
+	}
+	return tr, err
+}
+
+// CachedPreflightTransfer is PreflightTransfer with an optional cache (nil = no caching).
+func CachedPreflightTransfer(ctx context.Context, c Cache, ttl time.Duration, ec *ethclient.Client, token, from, to common.Address, amount *big.Int) (bool, string, error) {
+	if c == nil {
+		return PreflightTransfer(ctx, ec, token, from, to, amount)
+	}
+	key := cacheKey("preflight", token.Hex(), from.Hex(), to.Hex(), amount.String())
+	return cachedPreflight(c, key, ttl, func() (bool, string, error) {
+		return PreflightTransfer(ctx, ec, token, from, to, amount)
+	})
+}
+
+// CachedPreflightTransfer7702 is PreflightTransfer7702 with an optional cache (nil = no caching).
+func CachedPreflightTransfer7702(ctx context.Context, c Cache, ttl time.Duration, ec *ethclient.Client, rc *rpc.Client, token, from, recipient common.Address, amount *big.Int) (bool, string, error) {
+	return CachedPreflightTransfer7702On(ctx, c, ttl, Mainnet, ec, rc, token, from, recipient, amount)
+}
+
+// CachedPreflightTransfer7702On is PreflightTransfer7702On with an optional cache.
+func CachedPreflightTransfer7702On(ctx context.Context, c Cache, ttl time.Duration, chain Chain, ec *ethclient.Client, rc *rpc.Client, token, from, recipient common.Address, amount *big.Int) (bool, string, error) {
+	if c == nil {
+		return PreflightTransfer7702On(ctx, chain, ec, rc, token, from, recipient, amount)
+	}
+	key := cacheKey("preflight7702", token.Hex(), from.Hex(), recipient.Hex(), amount.String())
+	return cachedPreflight(c, key, ttl, func() (bool, string, error) {
+		return PreflightTransfer7702On(ctx, chain, ec, rc, token, from, recipient, amount)
+	})
+}
+
+func cachedPreflight(c Cache, key string, ttl time.Duration, fn func() (bool, string, error)) (bool, string, error) {
+	if b, ok := c.Get(key); ok {
+		var e preflightEntry
+		if json.Unmarshal(b, &e) == nil {
+			return e.OK, e.Reason, nil
+		}
+	}
+	ok, reason, err := fn()
+	if err == nil {
+		if b, e := json.Marshal(preflightEntry{OK: ok, Reason: reason}); e == nil {
+			c.Set(key, b, ttlOrDefault(ttl))
+		}
+	}
+	return ok, reason, err
+}
+
+func ttlOrDefault(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return DefaultCacheTTL
+	}
+	return ttl
+}
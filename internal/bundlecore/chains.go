@@ -0,0 +1,90 @@
+package bundlecore
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Chain holds the per-network constants of the sell-route checks: the wrapped native token
+// at the tail of the [token, WETH] path and the V2-style factory/router (UniswapV2 ABI)
+// with the deepest liquidity on that network.
+type Chain struct {
+	ID        uint64
+	Name      string
+	WETH      common.Address // WETH, WBNB, WPOL...
+	V2Name    string
+	V2Factory common.Address
+	V2Router  common.Address
+}
+
+// Mainnet is Ethereum mainnet; the functions without a Chain argument use it.
+var Mainnet = Chain{
+	ID: 1, Name: "ethereum", WETH: mainnetWETH, V2Name: "UniswapV2",
+	V2Factory: common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f"),
+	V2Router:  KnownV2Routers[0].Address,
+}
+
+var knownChains = map[uint64]Chain{
+	1: Mainnet,
+	10: {ID: 10, Name: "optimism", WETH: common.HexToAddress("0x4200000000000000000000000000000000000006"), V2Name: "UniswapV2",
+		V2Factory: common.HexToAddress("0x0c3c1c532F1e39EdF36BE9Fe0bE1410313E074Bf"),
+		V2Router:  common.HexToAddress("0x4A7b5Da61326A6379179b40d00F57E5bbDC962c2")},
+	56: {ID: 56, Name: "bsc", WETH: common.HexToAddress("0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c"), V2Name: "PancakeSwapV2",
+		V2Factory: common.HexToAddress("0xcA143Ce32Fe78f1f7019d7d551a6402fC5350c73"),
+		V2Router:  common.HexToAddress("0x10ED43C718714eb63d5aA57B78B54704E256024E")},
+	137: {ID: 137, Name: "polygon", WETH: common.HexToAddress("0x0d500B1d8E8eF31E21C99d1Db9A6444d3ADf1270"), V2Name: "QuickSwap",
+		V2Factory: common.HexToAddress("0x5757371414417b8C6CAad45bAeF941aBc7d3Ab32"),
+		V2Router:  common.HexToAddress("0xa5E0829CaCEd8fFDD4De3c43696c57F7D7A678ff")},
+	8453: {ID: 8453, Name: "base", WETH: common.HexToAddress("0x4200000000000000000000000000000000000006"), V2Name: "UniswapV2",
+		V2Factory: common.HexToAddress("0x8909Dc15e40173Ff4699343b6eB8132c65e18eC6"),
+		V2Router:  common.HexToAddress("0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24")},
+	42161: {ID: 42161, Name: "arbitrum", WETH: common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1"), V2Name: "UniswapV2",
+		V2Factory: common.HexToAddress("0xf1D7CC64Fb4452F05c498126312eBE29f30Fbcf9"),
+		V2Router:  common.HexToAddress("0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24")},
+}
+
+// ChainByID returns the constants of a known network.
+func ChainByID(id *big.Int) (Chain, bool) {
+	if id == nil || !id.IsUint64() {
+		return Chain{}, false
+	}
+	c, ok := knownChains[id.Uint64()]
+	return c, ok
+}
+
+// ParseChain accepts a chain ID ("8453") or a known name ("base", case-insensitive).
+// Unknown numeric IDs are returned with ok=false and only the ID set.
+func ParseChain(s string) (c Chain, ok bool, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if id, perr := strconv.ParseUint(s, 10, 64); perr == nil {
+		if c, ok := knownChains[id]; ok {
+			return c, true, nil
+		}
+		return Chain{ID: id}, false, nil
+	}
+	for _, c := range knownChains {
+		if c.Name == s {
+			return c, true, nil
+		}
+	}
+	return Chain{}, false, fmt.Errorf("unknown chain %q (use a chain ID or one of: %s)", s, strings.Join(KnownChainNames(), ", "))
+}
+
+// KnownChainNames lists the networks with sell-route constants, by chain ID.
+func KnownChainNames() []string {
+	ids := make([]uint64, 0, len(knownChains))
+	for id := range knownChains {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = knownChains[id].Name
+	}
+	return names
+}
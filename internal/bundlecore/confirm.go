@@ -0,0 +1,82 @@
+package bundlecore
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+)
+
+// transferTopic = keccak256("Transfer(address,address,uint256)")
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// TransferConfirmation is what eth_getLogs says moved from victim to recipient in one block.
+type TransferConfirmation struct {
+	Amount *big.Int      // sum of matching Transfer values
+	TxHash []common.Hash // transactions that emitted them
+}
+
+// Found reports whether at least one matching Transfer was seen.
+func (c TransferConfirmation) Found() bool { return c.Amount != nil && c.Amount.Sign() > 0 }
+
+// ConfirmTransferLogs queries Transfer(from, to) logs of token in the given block, emitted by
+// tx (zero tx: by any transaction). Independent of receipts, so it still works when the RPC
+// is flaky on eth_getTransactionReceipt. A zero `to` matches any recipient (router sells,
+// where tokens land in the pool) and needs tx: any Transfer out of FROM, the attacker's
+// sweep included, would match otherwise.
+func ConfirmTransferLogs(ctx context.Context, ec *ethclient.Client, token, from, to common.Address, tx common.Hash, block *big.Int) (TransferConfirmation, error) {
+	if to == (common.Address{}) && tx == (common.Hash{}) {
+		return TransferConfirmation{}, errors.New("confirm transfer: any-recipient match needs the tx hash")
+	}
+	topics := [][]common.Hash{{transferTopic}, {common.BytesToHash(from.Bytes())}}
+	if to != (common.Address{}) {
+		topics = append(topics, []common.Hash{common.BytesToHash(to.Bytes())})
+	}
+	q := ethereum.FilterQuery{
+		FromBlock: block,
+		ToBlock:   block,
+		Addresses: []common.Address{token},
+		Topics:    topics,
+	}
+	logs, err := filterLogsWithRetry(ctx, ec, q)
+	if err != nil {
+		return TransferConfirmation{}, err
+	}
+	out := TransferConfirmation{Amount: new(big.Int)}
+	for _, lg := range logs {
+		if lg.Removed || len(lg.Data) < 32 || (tx != (common.Hash{}) && lg.TxHash != tx) {
+			continue
+		}
+		out.Amount.Add(out.Amount, new(big.Int).SetBytes(lg.Data[:32]))
+		out.TxHash = append(out.TxHash, lg.TxHash)
+	}
+	return out, nil
+}
+
+func filterLogsWithRetry(ctx context.Context, ec *ethclient.Client, q ethereum.FilterQuery) ([]types.Log, error) {
+	const maxAttempts = 3
+	backoff := 200 * time.Millisecond
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		logs, err := ec.FilterLogs(ctx, q)
+		if err == nil {
+			return logs, nil
+		}
+		lastErr = err
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			if rpcerrors.IsRateLimited(err) {
+				backoff *= 2
+			}
+		}
+	}
+	return nil, lastErr
+}
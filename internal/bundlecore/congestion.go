@@ -0,0 +1,217 @@
+package bundlecore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Congestion advisory: before a sending batch, the base fee of the last blocks (an hour on
+// mainnet by default) is compared with the next block's. The trend says whether gas is
+// rising, how full the recent blocks are says whether it keeps rising (above 50% full the
+// base fee goes up, up to 12.5% per block), txpool_status adds the pending pool where the
+// provider exposes it. A spike usually fades about as fast as it built up, so the wait
+// suggested is the spike's age so far.
+
+// DefaultCongestionBlocks is the window sampled by SampleCongestion (~1h at 12s blocks).
+const DefaultCongestionBlocks = 300
+
+// congestionChunk is the eth_feeHistory block count per call (providers cap it, often at 1024,
+// some lower).
+const congestionChunk = 100
+
+// Congestion is one sample of the fee market.
+type Congestion struct {
+	Blocks    int           // blocks sampled
+	BlockTime time.Duration // average over the window
+	BaseFee   *big.Int      // next block's base fee
+	Median    *big.Int      // median base fee of the window
+	RisePct   float64       // BaseFee vs Median, %: the expected cost change against the window
+	TrendPct  float64       // BaseFee vs the base fee ~5 minutes ago, %
+	Fullness  float64       // average gasUsed/gasLimit of the last 10 blocks
+	Pending   int           // txpool_status pending count; -1 when the provider does not expose it
+	SpikeAge  time.Duration // how long the base fee has been >10% above Median (0: it is not)
+}
+
+// SampleCongestion reads the base fees and block fullness of the last blocks.
+func SampleCongestion(ctx context.Context, ec *ethclient.Client, blocks int) (Congestion, error) {
+	c := Congestion{Pending: -1}
+	if blocks <= 0 {
+		blocks = DefaultCongestionBlocks
+	}
+	head, err := ec.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return c, err
+	}
+	if head.BaseFee == nil {
+		return c, errors.New("no baseFee (pre-1559?)")
+	}
+	if h := head.Number.Int64(); int64(blocks) > h {
+		blocks = int(h)
+	}
+	if blocks < 2 {
+		return c, fmt.Errorf("only %d block(s) on chain", blocks)
+	}
+
+	// oldest → newest; the last feeHistory call also returns the next block's base fee
+	var fees []*big.Int
+	var ratios []float64
+	var next *big.Int
+	for last := head.Number.Int64(); len(fees) < blocks; {
+		n := min(congestionChunk, blocks-len(fees))
+		fh, err := ec.FeeHistory(ctx, uint64(n), big.NewInt(last), nil)
+		if err != nil {
+			return c, fmt.Errorf("feeHistory: %w", err)
+		}
+		if len(fh.BaseFee) < 2 || len(fh.GasUsedRatio) == 0 {
+			return c, errors.New("feeHistory: short baseFee array")
+		}
+		if next == nil {
+			next = fh.BaseFee[len(fh.BaseFee)-1]
+		}
+		fees = append(append([]*big.Int{}, fh.BaseFee[:len(fh.BaseFee)-1]...), fees...)
+		ratios = append(append([]float64{}, fh.GasUsedRatio...), ratios...)
+		last = fh.OldestBlock.Int64() - 1
+		if last < 0 {
+			break
+		}
+	}
+	c.Blocks, c.BaseFee = len(fees), next
+
+	if old, err := ec.HeaderByNumber(ctx, big.NewInt(head.Number.Int64()-int64(len(fees))+1)); err == nil && len(fees) > 1 {
+		c.BlockTime = time.Duration(head.Time-old.Time) * time.Second / time.Duration(len(fees)-1)
+	}
+	if c.BlockTime <= 0 {
+		c.BlockTime = 12 * time.Second
+	}
+
+	sorted := append([]*big.Int{}, fees...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	c.Median = sorted[len(sorted)/2]
+	c.RisePct = pctChange(c.BaseFee, c.Median)
+
+	ago := int(5 * time.Minute / c.BlockTime)
+	ago = max(1, min(ago, len(fees)-1))
+	c.TrendPct = pctChange(c.BaseFee, fees[len(fees)-ago])
+
+	recent := ratios[max(0, len(ratios)-10):]
+	for _, r := range recent {
+		c.Fullness += r
+	}
+	c.Fullness /= float64(len(recent))
+
+	// spike: the newest blocks whose base fee is >10% above the median
+	limit := new(big.Int).Div(new(big.Int).Mul(c.Median, big.NewInt(110)), big.NewInt(100))
+	if c.BaseFee.Cmp(limit) > 0 {
+		n := 0
+		for i := len(fees) - 1; i >= 0 && fees[i].Cmp(limit) > 0; i-- {
+			n++
+		}
+		c.SpikeAge = time.Duration(n) * c.BlockTime
+	}
+
+	var pool struct {
+		Pending hexutil.Uint64 `json:"pending"`
+	}
+	if err := ec.Client().CallContext(ctx, &pool, "txpool_status"); err == nil {
+		c.Pending = int(pool.Pending)
+	}
+	return c, nil
+}
+
+func pctChange(now, then *big.Int) float64 {
+	if then == nil || then.Sign() == 0 {
+		return 0
+	}
+	n, _ := new(big.Float).SetInt(now).Float64()
+	t, _ := new(big.Float).SetInt(then).Float64()
+	return 100 * (n - t) / t
+}
+
+// Rising reports whether the base fee is going up: up over the last minutes, or blocks over
+// half full (the next base fee is higher).
+func (c Congestion) Rising() bool { return c.TrendPct >= 10 || c.Fullness > 0.6 }
+
+// Advisory is the one-line verdict, e.g. "gas rising sharply — expected cost +35% vs the
+// last 1h, consider waiting ~2h".
+func (c Congestion) Advisory() string {
+	window := roundWait(time.Duration(c.Blocks) * c.BlockTime)
+	switch {
+	case c.RisePct >= 25 && c.Rising():
+		return fmt.Sprintf("gas rising sharply — expected cost %+.0f%% vs the last %s, consider waiting ~%s", c.RisePct, window, roundWait(c.suggestedWait()))
+	case c.RisePct >= 25:
+		return fmt.Sprintf("gas elevated but easing — expected cost %+.0f%% vs the last %s, consider waiting ~%s", c.RisePct, window, roundWait(c.suggestedWait()))
+	case c.RisePct <= -10:
+		return fmt.Sprintf("gas below usual — expected cost %+.0f%% vs the last %s, a good time to start", c.RisePct, window)
+	case c.Rising():
+		return fmt.Sprintf("gas normal but rising — expected cost %+.0f%% vs the last %s", c.RisePct, window)
+	}
+	return fmt.Sprintf("gas normal — expected cost %+.0f%% vs the last %s", c.RisePct, window)
+}
+
+// suggestedWait is the spike's age so far (at least 15 minutes).
+func (c Congestion) suggestedWait() time.Duration {
+	return max(c.SpikeAge, 15*time.Minute)
+}
+
+// Details are the numbers behind Advisory.
+func (c Congestion) Details() string {
+	parts := []string{
+		fmt.Sprintf("baseFee next %s gwei", gweiString(c.BaseFee)),
+		fmt.Sprintf("median %s gwei", gweiString(c.Median)),
+		fmt.Sprintf("%+.0f%% in ~5m", c.TrendPct),
+		fmt.Sprintf("blocks %.0f%% full", 100*c.Fullness),
+	}
+	if c.Pending >= 0 {
+		parts = append(parts, fmt.Sprintf("%d pending tx(s)", c.Pending))
+	}
+	if c.SpikeAge > 0 {
+		parts = append(parts, "elevated for "+roundWait(c.SpikeAge))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Meets reports whether the sample is within the start thresholds: base fee at most maxGwei
+// and at most maxRisePct above the median (0 = that threshold is not set). why says which
+// one is not met.
+func (c Congestion) Meets(maxGwei, maxRisePct float64) (ok bool, why string) {
+	if maxGwei > 0 {
+		if f, _ := new(big.Float).Quo(new(big.Float).SetInt(c.BaseFee), big.NewFloat(1e9)).Float64(); f > maxGwei {
+			return false, fmt.Sprintf("baseFee %s gwei > %g gwei", gweiString(c.BaseFee), maxGwei)
+		}
+	}
+	if maxRisePct > 0 && c.RisePct > maxRisePct {
+		return false, fmt.Sprintf("baseFee %+.0f%% vs median > +%g%%", c.RisePct, maxRisePct)
+	}
+	return true, ""
+}
+
+func gweiString(wei *big.Int) string {
+	if wei == nil {
+		return "?"
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	if f < 10 {
+		return fmt.Sprintf("%.2f", f)
+	}
+	return fmt.Sprintf("%.0f", f)
+}
+
+// roundWait prints d as "40m", "2h", "1h30m".
+func roundWait(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", max(1, int(d.Round(5*time.Minute)/time.Minute)))
+	}
+	d = d.Round(30 * time.Minute)
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dh%dm", d/time.Hour, d%time.Hour/time.Minute)
+}
@@ -0,0 +1,197 @@
+package bundlecore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Post-rescue disposal: tokens that reached the SAFE are sold for ETH in tranches through
+// the UniswapV2 router (swapExactTokensForETHSupportingFeeOnTransferTokens, token -> WETH).
+// PlanDisposal only reads (quotes, transfer tax); SignSafeCall builds the SAFE's own
+// transactions, which are sent as normal public txs or to a private RPC.
+
+// SellTranche is one step of a disposal plan.
+type SellTranche struct {
+	Index     int
+	At        time.Time
+	Amount    *big.Int // tokens sold, in token units
+	QuoteWei  *big.Int // expected ETH at plan time (after transfer tax)
+	MinOutWei *big.Int // amountOutMin: QuoteWei minus slippage
+	ImpactBps int64    // price impact against the spot price of a small trade
+}
+
+// DisposalPlan is the sell schedule of one token held by Owner.
+type DisposalPlan struct {
+	Token, Owner, Router common.Address
+	Total                *big.Int
+	Tax                  TransferTax
+	TaxErr               error // tax not measured (no stateOverride support): quotes assume none
+	Tranches             []SellTranche
+}
+
+// QuoteWei is the expected ETH of all tranches.
+func (p DisposalPlan) QuoteWei() *big.Int {
+	sum := new(big.Int)
+	for _, t := range p.Tranches {
+		sum.Add(sum, t.QuoteWei)
+	}
+	return sum
+}
+
+// DisposalOptions shape the schedule: Tranches equal parts (the last takes the remainder),
+// the first at Start, then one every Interval.
+type DisposalOptions struct {
+	Tranches    int
+	Start       time.Time
+	Interval    time.Duration
+	SlippageBps int64
+}
+
+// PlanDisposal splits total into tranches and quotes each one at the current pool state.
+// Tranches are quoted independently: each assumes the pool has recovered from the
+// previous sell by the time it runs (the interval is there for arbitrage to do that).
+func PlanDisposal(ctx context.Context, ec *ethclient.Client, token, owner common.Address, total *big.Int, o DisposalOptions) (DisposalPlan, error) {
+	plan := DisposalPlan{Token: token, Owner: owner, Router: KnownV2Routers[0].Address}
+	if total == nil || total.Sign() <= 0 {
+		return plan, errors.New("nothing to sell")
+	}
+	plan.Total = new(big.Int).Set(total)
+	if o.Tranches < 1 {
+		o.Tranches = 1
+	}
+	if o.SlippageBps < 0 || o.SlippageBps >= 10_000 {
+		return plan, fmt.Errorf("slippage %d bps: expected 0..9999", o.SlippageBps)
+	}
+	part := new(big.Int).Div(total, big.NewInt(int64(o.Tranches)))
+	if part.Sign() == 0 {
+		return plan, fmt.Errorf("%s token units cannot be split into %d tranches", total, o.Tranches)
+	}
+	plan.Tax, plan.TaxErr = SimulateTransferTax(ctx, ec.Client(), token, owner, plan.Router, part)
+	for i := 0; i < o.Tranches; i++ {
+		amount := new(big.Int).Set(part)
+		if i == o.Tranches-1 {
+			amount.Sub(total, new(big.Int).Mul(part, big.NewInt(int64(o.Tranches-1))))
+		}
+		quote, impact, err := QuoteSell(ctx, ec, token, amount, plan.Tax)
+		if err != nil {
+			return plan, fmt.Errorf("tranche %d: %w", i+1, err)
+		}
+		plan.Tranches = append(plan.Tranches, SellTranche{
+			Index: i + 1, At: o.Start.Add(time.Duration(i) * o.Interval), Amount: amount,
+			QuoteWei: quote, MinOutWei: MinOut(quote, o.SlippageBps), ImpactBps: impact,
+		})
+	}
+	return plan, nil
+}
+
+// QuoteSell prices a router sell of amount (after tax) and its price impact in bps:
+// the shortfall against 1/1000 of the amount quoted and scaled up.
+func QuoteSell(ctx context.Context, ec *ethclient.Client, token common.Address, amount *big.Int, tax TransferTax) (quote *big.Int, impactBps int64, err error) {
+	sold := tax.ReceivedAfter(amount)
+	if quote, err = QuoteTokenToETH(ctx, ec, token, sold); err != nil {
+		return nil, 0, fmt.Errorf("no UniswapV2 quote: %w", err)
+	}
+	probe := new(big.Int).Div(sold, big.NewInt(1000))
+	if probe.Sign() == 0 {
+		return quote, 0, nil
+	}
+	pq, err := QuoteTokenToETH(ctx, ec, token, probe)
+	if err != nil || pq.Sign() == 0 {
+		return quote, 0, nil
+	}
+	spot := new(big.Int).Div(new(big.Int).Mul(pq, sold), probe)
+	if spot.Cmp(quote) <= 0 {
+		return quote, 0, nil
+	}
+	short := new(big.Int).Sub(spot, quote)
+	return quote, new(big.Int).Div(new(big.Int).Mul(short, big.NewInt(10_000)), spot).Int64(), nil
+}
+
+// MinOut is quote minus slippageBps.
+func MinOut(quote *big.Int, slippageBps int64) *big.Int {
+	v := new(big.Int).Mul(quote, big.NewInt(10_000-slippageBps))
+	return v.Div(v, big.NewInt(10_000))
+}
+
+// EncodeERC20Approve encodes approve(spender, amount).
+func EncodeERC20Approve(spender common.Address, amount *big.Int) []byte {
+	data := sel("approve(address,uint256)")
+	data = append(data, common.LeftPadBytes(spender.Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+}
+
+// SignSafeCall signs a plain EIP-1559 call from the key's own address: pending nonce,
+// estimated gas +20%, suggested tip and a fee cap of 2x base fee + tip.
+func SignSafeCall(ctx context.Context, ec *ethclient.Client, chainID *big.Int, prv *ecdsa.PrivateKey, to common.Address, data []byte) (*types.Transaction, error) {
+	from := crypto.PubkeyToAddress(prv.PublicKey)
+	gas, err := estimateGasWithRetry(ctx, ec, ethereum.CallMsg{From: from, To: &to, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("estimate gas: %w", err)
+	}
+	tip, err := ec.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tip: %w", err)
+	}
+	base, _, err := latestBaseFee(ctx, ec)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := ec.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(base, big.NewInt(2)), tip)
+	return signTx(buildDynamicTx(chainID, nonce, &to, big.NewInt(0), gas*12/10, tip, feeCap, data), chainID, prv)
+}
+
+// SendRawPrivate submits tx with eth_sendRawTransaction to a private RPC (e.g. Flashbots
+// Protect): it never enters the public mempool.
+func SendRawPrivate(ctx context.Context, url string, tx *types.Transaction) error {
+	rc, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	var hash common.Hash
+	return rc.CallContext(ctx, &hash, "eth_sendRawTransaction", txAsHex(tx))
+}
+
+// WaitReceipt polls for the receipt of hash until it exists or ctx ends.
+func WaitReceipt(ctx context.Context, ec *ethclient.Client, hash common.Hash) (*types.Receipt, error) {
+	for {
+		rcpt, err := ec.TransactionReceipt(ctx, hash)
+		if err == nil && rcpt != nil {
+			return rcpt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("tx %s not mined: %w", hash.Hex(), ctx.Err())
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+// wethWithdrawalTopic is WETH's Withdrawal(address,uint256) event.
+var wethWithdrawalTopic = crypto.Keccak256Hash([]byte("Withdrawal(address,uint256)"))
+
+// SwapOutputETH is the ETH a router sell paid out: the WETH the router unwrapped in rcpt.
+func SwapOutputETH(rcpt *types.Receipt) *big.Int {
+	sum := new(big.Int)
+	for _, l := range rcpt.Logs {
+		if l.Address == mainnetWETH && len(l.Topics) > 0 && l.Topics[0] == wethWithdrawalTopic && len(l.Data) >= 32 {
+			sum.Add(sum, new(big.Int).SetBytes(l.Data[:32]))
+		}
+	}
+	return sum
+}
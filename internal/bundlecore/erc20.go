@@ -13,6 +13,9 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	gethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
 )
 
 // Encode ERC-20 transfer calldata.
@@ -24,14 +27,6 @@ func EncodeERC20Transfer(to common.Address, amount *big.Int) []byte {
 }
 
 // --- small RPC helpers (retry + backoff) ---
-func isRateLimitError(err error) bool {
-	if err == nil {
-		return false
-	}
-	s := err.Error()
-	return strings.Contains(s, "Too Many Requests") || strings.Contains(s, "-32005")
-}
-
 // callWithRetry performs eth_call with small exponential backoff.
 func callWithRetry(ctx context.Context, ec *ethclient.Client, msg ethereum.CallMsg) ([]byte, error) {
 	const maxAttempts = 3
@@ -45,7 +40,7 @@ func callWithRetry(ctx context.Context, ec *ethclient.Client, msg ethereum.CallM
 		lastErr = err
 		if attempt < maxAttempts {
 			time.Sleep(backoff)
-			if isRateLimitError(err) {
+			if rpcerrors.IsRateLimited(err) {
 				backoff *= 2
 			}
 		}
@@ -65,7 +60,7 @@ func estimateGasWithRetry(ctx context.Context, ec *ethclient.Client, msg ethereu
 		lastErr = err
 		if attempt < maxAttempts {
 			time.Sleep(backoff)
-			if isRateLimitError(err) {
+			if rpcerrors.IsRateLimited(err) {
 				backoff *= 2
 			}
 		}
@@ -184,6 +179,24 @@ func (tr TokenRestrictions) Blocked() bool {
 	return false
 }
 
+// ReasonCode is the reason code of a blocked token ("" when it is not blocked), the most
+// specific restriction first.
+func (tr TokenRestrictions) ReasonCode() reasons.Code {
+	switch {
+	case !tr.Blocked():
+		return ""
+	case tr.Paused:
+		return reasons.Paused
+	case tr.BlacklistedFrom:
+		return reasons.BlacklistedFrom
+	case tr.BlacklistedTo:
+		return reasons.BlacklistedTo
+	case tr.TransferDisabled:
+		return reasons.TransferDisabled
+	}
+	return reasons.NotWhitelisted
+}
+
 func (tr TokenRestrictions) Summary() string {
 	parts := []string{}
 	if tr.Paused {
@@ -281,6 +294,13 @@ func CheckRestrictions(ctx context.Context, ec *ethclient.Client, token common.A
 				return true
 			}
 		}
+		// getters from the selector config (RESTRICTION_SELECTORS)
+		for _, s := range extraBlacklistSelectors(token) {
+			data := append(s, common.LeftPadBytes(addr.Bytes(), 32)...)
+			if ret, ok := call(data); ok && boolOf(ret) {
+				return true
+			}
+		}
 		return false
 	}
 	out.BlacklistedFrom = isBlacklisted(from)
@@ -6,13 +6,58 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
 )
 
+// rpcHTTP is used for raw JSON-RPC posts (feeHistory, relays) so they show up in rpcmetrics.
+var rpcHTTP = &http.Client{Transport: rpcmetrics.Transport(nil)}
+
+// postRPC sends one raw JSON-RPC request to the node and returns the response body.
+// ws:// / wss:// URLs go through a shared WebSocket client; its answer is re-encoded as a
+// JSON-RPC envelope, so callers decode both transports the same way.
+func postRPC(ctx context.Context, rpcURL string, r rpcReq) (io.ReadCloser, error) {
+	if rpcdial.IsWS(rpcURL) {
+		c, err := rpcdial.Shared(ctx, rpcURL)
+		if err != nil {
+			return nil, err
+		}
+		params, _ := r.Params.([]any)
+		var result json.RawMessage
+		env := map[string]any{"jsonrpc": "2.0", "id": r.ID}
+		if err := c.CallContext(ctx, &result, r.Method, params...); err != nil {
+			var rpcErr rpc.Error
+			if !errors.As(err, &rpcErr) {
+				return nil, err
+			}
+			env["error"] = map[string]any{"code": rpcErr.ErrorCode(), "message": rpcErr.Error()}
+		} else {
+			env["result"] = result
+		}
+		b, _ := json.Marshal(env)
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	body, _ := json.Marshal(r)
+	req, err := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := rpcHTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
 // Latest base fee and head number.
 func latestBaseFee(ctx context.Context, ec *ethclient.Client) (*big.Int, *big.Int, error) {
 	h, err := ec.HeaderByNumber(ctx, nil)
@@ -38,16 +83,13 @@ func nextBaseFeeViaFeeHistory(ctx context.Context, rpcURL string) (*big.Int, err
 			Message string `json:"message"`
 		} `json:"error,omitempty"`
 	}
-	body, _ := json.Marshal(rpcReq{Jsonrpc: "2.0", Method: "eth_feeHistory", Params: []any{"0x1", "pending", []int{50}}, ID: 1})
-	req, _ := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	body, err := postRPC(ctx, rpcURL, rpcReq{Jsonrpc: "2.0", Method: "eth_feeHistory", Params: []any{"0x1", "pending", []int{50}}, ID: 1})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 	var out feeHistResp
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
 		return nil, err
 	}
 	if out.Error != nil {
@@ -91,20 +133,17 @@ func FeeHistoryStats(ctx context.Context, rpcURL string, blocks int, percentiles
 		} `json:"error,omitempty"`
 	}
 
-	body, _ := json.Marshal(rpcReq{
+	body, err := postRPC(ctx, rpcURL, rpcReq{
 		Jsonrpc: "2.0", Method: "eth_feeHistory",
 		Params: []any{fmt.Sprintf("0x%x", blocks), "pending", percentiles}, ID: 1,
 	})
-	req, _ := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
 	var out feeHistResp
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
 		return nil, err
 	}
 	if out.Error != nil {
@@ -170,20 +209,17 @@ func TipFromFeeHistory(ctx context.Context, rpcURL string, blocks int, percentil
 			Message string `json:"message"`
 		} `json:"error,omitempty"`
 	}
-	body, _ := json.Marshal(rpcReq{
+	body, err := postRPC(ctx, rpcURL, rpcReq{
 		Jsonrpc: "2.0", Method: "eth_feeHistory",
 		Params: []any{fmt.Sprintf("0x%x", blocks), "pending", []int{percentile}}, ID: 1,
 	})
-	req, _ := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
 	var out feeHistResp
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
 		return nil, err
 	}
 	if out.Error != nil {
@@ -220,16 +256,13 @@ func suggestPriorityViaRPC(ctx context.Context, rpcURL string) *big.Int {
 			Message string `json:"message"`
 		} `json:"error,omitempty"`
 	}
-	body, _ := json.Marshal(rpcReq{Jsonrpc: "2.0", Method: "eth_maxPriorityFeePerGas", Params: []any{}, ID: 1})
-	req, _ := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	body, err := postRPC(ctx, rpcURL, rpcReq{Jsonrpc: "2.0", Method: "eth_maxPriorityFeePerGas", Params: []any{}, ID: 1})
 	if err != nil {
 		return nil
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 	var out respT
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
 		return nil
 	}
 	if out.Error != nil || out.Result == "" {
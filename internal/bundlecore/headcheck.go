@@ -0,0 +1,101 @@
+package bundlecore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHeadLagWarn is the lag (blocks) above which a stale primary RPC is reported.
+const DefaultHeadLagWarn = 2
+
+// HeadView is the primary RPC's head next to the freshest head seen on the secondary
+// endpoints (Params.HeadCheckRPCs). Overloaded free RPCs often serve a head a few blocks
+// behind: target blocks computed from it are already in the past.
+type HeadView struct {
+	Primary uint64
+	Best    uint64
+	Source  string // host that reported Best; "primary" when no secondary is ahead
+}
+
+// Lag is how many blocks the primary is behind the freshest source.
+func (h HeadView) Lag() uint64 {
+	if h.Best > h.Primary {
+		return h.Best - h.Primary
+	}
+	return 0
+}
+
+// CheckHead asks every secondary for eth_blockNumber (3s each, errors ignored) and
+// returns the freshest head next to primaryHead.
+func CheckHead(ctx context.Context, primaryHead uint64, secondaries []string) HeadView {
+	hv := HeadView{Primary: primaryHead, Best: primaryHead, Source: "primary"}
+	for _, u := range secondaries {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		n, err := blockNumberAt(ctx, u)
+		if err != nil || n <= hv.Best {
+			continue
+		}
+		hv.Best, hv.Source = n, hostOf(u)
+	}
+	return hv
+}
+
+// freshHead applies CheckHead inside Run: warns when the lag is above the threshold
+// and returns the head to use for target-block math.
+func (p *Params) freshHead(ctx context.Context, head uint64) uint64 {
+	if len(p.HeadCheckRPCs) == 0 || p.LocalFork {
+		return head
+	}
+	hv := CheckHead(ctx, head, p.HeadCheckRPCs)
+	lag := hv.Lag()
+	if lag == 0 {
+		return head
+	}
+	warn := p.HeadLagWarn
+	if warn <= 0 {
+		warn = DefaultHeadLagWarn
+	}
+	if lag > uint64(warn) {
+		p.logf("[warn] RPC head %d is %d block(s) behind %s (%d): stale provider? target blocks use the fresher head", hv.Primary, lag, hv.Source, hv.Best)
+	}
+	return hv.Best
+}
+
+func blockNumberAt(ctx context.Context, rpcURL string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	body, err := postRPC(ctx, rpcURL, rpcReq{Jsonrpc: "2.0", Method: "eth_blockNumber", Params: []any{}, ID: 1})
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	var out struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("eth_blockNumber: %w", err)
+	}
+	if out.Error != nil {
+		return 0, errors.New(out.Error.Message)
+	}
+	return strconv.ParseUint(strings.TrimPrefix(out.Result, "0x"), 16, 64)
+}
+
+// hostOf keeps only the host: secondary URLs usually carry API keys.
+func hostOf(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "secondary"
+}
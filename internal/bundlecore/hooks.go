@@ -0,0 +1,140 @@
+package bundlecore
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+)
+
+// Transfer hooks. ERC-777 tokens call tokensToSend / tokensReceived hooks registered in the
+// ERC-1820 registry, ERC-1363 tokens call back into contract recipients. A hook runs code
+// chosen by someone else in the middle of our transfer: it can behave differently under
+// eth_call than in the block, and it can reenter the 7702 delegate at FROM. Such tokens get
+// a risk note and a stricter simulation (SimulateTransferStrict).
+
+// ERC1820Registry is the ERC-1820 pseudo-introspection registry (same address on every chain).
+var ERC1820Registry = common.HexToAddress("0x1820a4B7618BdE71Dce8cdc73aAB6C95905faD24")
+
+var (
+	erc777TokenHash     = common.HexToHash("0xac7fbab5f54a3ca8194167523c6753bfeb96a445279294b6125b68cce2177054") // keccak256("ERC777Token")
+	erc777SenderHash    = common.HexToHash("0x29ddb589b1fb5fc7cf394961c1adf5f8c6454761adf795e67fe149f658abe895") // keccak256("ERC777TokensSender")
+	erc777RecipientHash = common.HexToHash("0xb281fc8c12954d22544db45de3159a39272895b169a852b314f9cc762e44c53b") // keccak256("ERC777TokensRecipient")
+	erc1363InterfaceID  = [4]byte{0xb0, 0x20, 0x2a, 0x11}
+)
+
+// TransferHooks is what DetectTransferHooks found for one token and pair.
+type TransferHooks struct {
+	ERC777        bool           // token registered ERC777Token in ERC-1820
+	ERC1363       bool           // supportsInterface(ERC-1363)
+	SenderHook    common.Address // ERC777TokensSender implementer of FROM (zero = none)
+	RecipientHook common.Address // ERC777TokensRecipient implementer of the recipient (zero = none)
+}
+
+// Any reports whether the token has transfer hooks at all.
+func (h TransferHooks) Any() bool { return h.ERC777 || h.ERC1363 }
+
+// Summary is a one-line description for logs, warnings and risk notes.
+func (h TransferHooks) Summary() string {
+	var parts []string
+	if h.ERC777 {
+		parts = append(parts, "ERC-777")
+	}
+	if h.ERC1363 {
+		parts = append(parts, "ERC-1363")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	s := strings.Join(parts, "+") + " transfer hooks"
+	if h.SenderHook != (common.Address{}) {
+		s += ", FROM has a tokensToSend hook " + h.SenderHook.Hex()
+	}
+	if h.RecipientHook != (common.Address{}) {
+		s += ", recipient has a tokensReceived hook " + h.RecipientHook.Hex()
+	}
+	return s
+}
+
+// DetectTransferHooks looks token up in ERC-1820 (ERC777Token) and via ERC-165
+// (ERC-1363). For ERC-777 it also reports the sender/recipient hooks registered for from
+// and to. A chain without the registry simply reports no ERC-777.
+func DetectTransferHooks(ctx context.Context, ec *ethclient.Client, token, from, to common.Address) (TransferHooks, error) {
+	var h TransferHooks
+	impl, err := interfaceImplementer(ctx, ec, token, erc777TokenHash)
+	if err != nil {
+		return h, err
+	}
+	h.ERC777 = impl != (common.Address{})
+	if h.ERC777 {
+		if h.SenderHook, err = interfaceImplementer(ctx, ec, from, erc777SenderHash); err != nil {
+			return h, err
+		}
+		if h.RecipientHook, err = interfaceImplementer(ctx, ec, to, erc777RecipientHash); err != nil {
+			return h, err
+		}
+	}
+	h.ERC1363 = supportsInterface(ctx, ec, token, erc1363InterfaceID)
+	return h, nil
+}
+
+// interfaceImplementer is ERC1820Registry.getInterfaceImplementer(addr, iface); zero when
+// nothing is registered or there is no registry on this chain.
+func interfaceImplementer(ctx context.Context, ec *ethclient.Client, addr common.Address, iface common.Hash) (common.Address, error) {
+	data := append(common.FromHex("0xaabbb8ca"), common.LeftPadBytes(addr.Bytes(), 32)...)
+	data = append(data, iface.Bytes()...)
+	res, err := callWithRetry(ctx, ec, ethereum.CallMsg{To: &ERC1820Registry, Data: data})
+	if err != nil {
+		if rpcerrors.IsRateLimited(err) || ctx.Err() != nil {
+			return common.Address{}, err
+		}
+		return common.Address{}, nil // registry missing or reverting: no hooks known
+	}
+	if len(res) < 32 {
+		return common.Address{}, nil
+	}
+	return common.BytesToAddress(res[:32]), nil
+}
+
+// supportsInterface follows ERC-165 detection: supportsInterface(0x01ffc9a7) must be true
+// and supportsInterface(0xffffffff) false before id is trusted.
+func supportsInterface(ctx context.Context, ec *ethclient.Client, token common.Address, id [4]byte) bool {
+	query := func(id [4]byte) bool {
+		data := append(common.FromHex("0x01ffc9a7"), common.RightPadBytes(id[:], 32)...)
+		// single call: a revert is the common "no", retrying it only adds latency to every pair
+		res, err := ec.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+		return err == nil && len(res) >= 32 && new(big.Int).SetBytes(res[:32]).Cmp(big.NewInt(1)) == 0
+	}
+	return query([4]byte{0x01, 0xff, 0xc9, 0xa7}) && !query([4]byte{0xff, 0xff, 0xff, 0xff}) && query(id)
+}
+
+// SimulateTransferStrict is the simulation used for hooked tokens: the transfer runs from
+// FROM with stateOverride code (as the delegate will run it) and both balance deltas are
+// checked. FROM must lose exactly amount — a hook that pulls more, or reenters and moves
+// other funds, fails here even when transfer() itself returned true. The recipient delta is
+// returned as TransferTax (fee-on-transfer stays allowed).
+func SimulateTransferStrict(ctx context.Context, rc *rpc.Client, token, from, to common.Address, amount *big.Int) (TransferTax, error) {
+	fromBefore, fromAfter, err := probeTransfer(ctx, rc, token, from, to, from, amount)
+	if err != nil {
+		return TransferTax{}, err
+	}
+	if debited := new(big.Int).Sub(fromBefore, fromAfter); debited.Cmp(amount) != 0 {
+		return TransferTax{}, fmt.Errorf("strict simulation: FROM debited %s, expected %s (a hook or a sender-side fee moved more)", debited, amount)
+	}
+	toBefore, toAfter, err := probeTransfer(ctx, rc, token, from, to, to, amount)
+	if err != nil {
+		return TransferTax{}, err
+	}
+	received := new(big.Int).Sub(toAfter, toBefore)
+	if received.Sign() < 0 || received.Cmp(amount) > 0 {
+		return TransferTax{}, fmt.Errorf("strict simulation: recipient balance changed by %s for a transfer of %s", received, amount)
+	}
+	return TransferTax{Sent: new(big.Int).Set(amount), Received: received}, nil
+}
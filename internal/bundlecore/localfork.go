@@ -0,0 +1,42 @@
+package bundlecore
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// SubmitLocalBundle plays a bundle on a dev node with automine (anvil/hardhat): txs are sent
+// one by one in bundle order, each waiting for its receipt, and the first failure stops the rest.
+// Not atomic like a relay bundle, which is fine for rehearsals. Returns the block of watch.
+func SubmitLocalBundle(ctx context.Context, rpcURL string, list types.Transactions, watch common.Hash) (*big.Int, error) {
+	ec, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	defer ec.Close()
+	var watchBlock *big.Int
+	for i, tx := range list {
+		if err := ec.SendTransaction(ctx, tx); err != nil {
+			return nil, fmt.Errorf("tx[%d] %s: %w", i, tx.Hash().Hex(), err)
+		}
+		rcpt, err := ec.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("tx[%d] %s: no receipt (automine off?): %w", i, tx.Hash().Hex(), err)
+		}
+		if tx.Hash() == watch {
+			watchBlock = rcpt.BlockNumber
+		}
+		if rcpt.Status != types.ReceiptStatusSuccessful && tx.Hash() != watch {
+			return watchBlock, fmt.Errorf("tx[%d] %s reverted", i, tx.Hash().Hex())
+		}
+	}
+	if watchBlock == nil {
+		return nil, fmt.Errorf("tx %s not in bundle", watch.Hex())
+	}
+	return watchBlock, nil
+}
@@ -0,0 +1,225 @@
+package bundlecore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+)
+
+// Metrics of Run / SweepETH in the Prometheus text format (ServeMetrics, METRICS_ADDR in
+// bundlecli and the GUI), for watching long batches: bundles sent and simulation results per
+// relay, run outcomes (and so the inclusion rate), attempt latency and the classes of RPC
+// errors. Counting is always on and costs a map update; nothing listens unless ServeMetrics
+// is called. Relays are labelled by host: relay URLs often carry API keys.
+
+// attemptBuckets are the upper bounds (seconds) of the attempt latency histogram: one
+// attempt is build + simulate + send + the wait for its target block.
+var attemptBuckets = []float64{1, 2, 5, 10, 15, 20, 30, 45, 60, 90, 120}
+
+// Metrics holds the counters of one process.
+type Metrics struct {
+	mu        sync.Mutex
+	sent      map[[2]string]int64 // relay host, result (ok | already_known | error)
+	sims      map[[2]string]int64 // relay host, result (ok | fail | unsupported)
+	runs      map[ReasonCode]int64
+	rpcErrors map[reasons.Code]int64
+	wins      map[string]int64 // builder of the inclusion block ("unknown")
+	buckets   []int64          // cumulative per attemptBuckets, +Inf last
+	latSum    float64
+	latCount  int64
+}
+
+// DefaultMetrics is what Run and SweepETH count on.
+var DefaultMetrics = &Metrics{}
+
+func (m *Metrics) init() {
+	if m.sent == nil {
+		m.sent, m.sims = map[[2]string]int64{}, map[[2]string]int64{}
+		m.runs, m.rpcErrors = map[ReasonCode]int64{}, map[reasons.Code]int64{}
+		m.wins = map[string]int64{}
+		m.buckets = make([]int64, len(attemptBuckets)+1)
+	}
+}
+
+func (m *Metrics) bundleSent(relay, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.sent[[2]string{RelayHost(relay), result}]++
+}
+
+func (m *Metrics) simResult(relay, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.sims[[2]string{RelayHost(relay), result}]++
+}
+
+// runDone counts the outcome of one Run / SweepETH and, when it failed on the RPC, the
+// error class (key or parameter errors are not RPC errors).
+func (m *Metrics) runDone(res Result, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if err != nil {
+		m.runs["error"]++
+		if isRPCFailure(err) {
+			m.rpcErrors[RPCErrorClass(err)]++
+		}
+		return
+	}
+	if res.Code != "" {
+		m.runs[res.Code]++
+	}
+}
+
+// rpcError counts an RPC error the run survived (a failed wait, estimate, ...); reverts and
+// other answers of a working RPC are not counted.
+func (m *Metrics) rpcError(err error) {
+	if err == nil || errors.Is(err, context.Canceled) || !isRPCFailure(err) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.rpcErrors[RPCErrorClass(err)]++
+}
+
+func (m *Metrics) builderWin(builder string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if builder == "" {
+		builder = "unknown"
+	}
+	m.wins[builder]++
+}
+
+func (m *Metrics) attempt(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	s := d.Seconds()
+	for i, le := range attemptBuckets {
+		if s <= le {
+			m.buckets[i]++
+		}
+	}
+	m.buckets[len(attemptBuckets)]++
+	m.latSum += s
+	m.latCount++
+}
+
+// isRPCFailure reports errors of the transport or the JSON-RPC server.
+func isRPCFailure(err error) bool { return rpcerrors.FromRPC(err) }
+
+// RPCErrorClass maps an RPC error to its reason code: RPC_TIMEOUT, RPC_UNAVAILABLE,
+// RPC_RATE_LIMITED or RPC_ERROR.
+func RPCErrorClass(err error) reasons.Code { return rpcerrors.Classify(err).RPCCode() }
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format (0.0.4).
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+
+	pairs := func(name, help string, c map[[2]string]int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		keys := make([][2]string, 0, len(c))
+		for k := range c {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i][0] != keys[j][0] {
+				return keys[i][0] < keys[j][0]
+			}
+			return keys[i][1] < keys[j][1]
+		})
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s{relay=%q,result=%q} %d\n", name, k[0], k[1], c[k])
+		}
+	}
+	pairs("bundlecore_bundles_sent_total", "Bundle submissions per relay and result.", m.sent)
+	pairs("bundlecore_simulations_total", "Bundle simulations per relay and result.", m.sims)
+
+	fmt.Fprintf(w, "# HELP bundlecore_runs_total Finished runs by outcome code (error = the run returned an error).\n# TYPE bundlecore_runs_total counter\n")
+	codes := make([]string, 0, len(m.runs))
+	var included, finished int64
+	for c, n := range m.runs {
+		codes = append(codes, string(c))
+		if c == ReasonSimulateOnly || c == ReasonSimulationFailed {
+			continue
+		}
+		finished += n
+		if c == ReasonIncluded {
+			included += n
+		}
+	}
+	sort.Strings(codes)
+	for _, c := range codes {
+		fmt.Fprintf(w, "bundlecore_runs_total{code=%q} %d\n", c, m.runs[ReasonCode(c)])
+	}
+	rate := 0.0
+	if finished > 0 {
+		rate = float64(included) / float64(finished)
+	}
+	fmt.Fprintf(w, "# HELP bundlecore_inclusion_ratio Included runs / finished runs, simulations excluded.\n# TYPE bundlecore_inclusion_ratio gauge\nbundlecore_inclusion_ratio %g\n", rate)
+
+	fmt.Fprintf(w, "# HELP bundlecore_builder_wins_total Included bundles by builder of the inclusion block.\n# TYPE bundlecore_builder_wins_total counter\n")
+	names := make([]string, 0, len(m.wins))
+	for b := range m.wins {
+		names = append(names, b)
+	}
+	sort.Strings(names)
+	for _, b := range names {
+		fmt.Fprintf(w, "bundlecore_builder_wins_total{builder=%q} %d\n", b, m.wins[b])
+	}
+
+	fmt.Fprintf(w, "# HELP bundlecore_attempt_duration_seconds Duration of one attempt: build, simulate, send, wait for the target block.\n# TYPE bundlecore_attempt_duration_seconds histogram\n")
+	for i, le := range attemptBuckets {
+		fmt.Fprintf(w, "bundlecore_attempt_duration_seconds_bucket{le=\"%g\"} %d\n", le, m.buckets[i])
+	}
+	fmt.Fprintf(w, "bundlecore_attempt_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.buckets[len(attemptBuckets)])
+	fmt.Fprintf(w, "bundlecore_attempt_duration_seconds_sum %g\nbundlecore_attempt_duration_seconds_count %d\n", m.latSum, m.latCount)
+
+	fmt.Fprintf(w, "# HELP bundlecore_rpc_errors_total RPC errors seen by runs, by class.\n# TYPE bundlecore_rpc_errors_total counter\n")
+	classes := make([]string, 0, len(m.rpcErrors))
+	for c := range m.rpcErrors {
+		classes = append(classes, string(c))
+	}
+	sort.Strings(classes)
+	for _, c := range classes {
+		fmt.Fprintf(w, "bundlecore_rpc_errors_total{class=%q} %d\n", c, m.rpcErrors[reasons.Code(c)])
+	}
+}
+
+// MetricsHandler serves DefaultMetrics.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		DefaultMetrics.WritePrometheus(w)
+	})
+}
+
+// ServeMetrics listens on addr (":9464", "127.0.0.1:9464") and serves /metrics in the
+// background; the returned server is for Close. The error is the listen error, if any.
+func ServeMetrics(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	return srv, nil
+}
@@ -16,13 +16,20 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	gethcrypto "github.com/ethereum/go-ethereum/crypto"
-	w3 "github.com/lmittmann/w3"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/flashbots"
+
+	"github.com/ligun0805/bundle-rescue/internal/relayseen"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
 )
 
-// Relay dialed via w3 + flashbots.
-type relayClient struct {
-	URL string
-	C   *w3.Client
+// dialRelay is flashbots.Dial over rpcmetrics.Transport: the calls are counted and end up
+// in the relay submission record (relayrecord) like the raw posts of rpcHTTP. The record is
+// taken before the X-Flashbots-Signature is added, so its headers do not carry it.
+func dialRelay(u string, authPrv *ecdsa.PrivateKey) (*rpc.Client, error) {
+	return rpc.DialOptions(context.Background(), u, rpc.WithHTTPClient(&http.Client{
+		Transport: rpcmetrics.Transport(flashbots.AuthTransport(authPrv)),
+	}))
 }
 
 // buildStandardPayload returns the classic/old-style bundle payload.
@@ -71,107 +78,11 @@ func buildStrategyPayload(p *Params, relayURL string, txHexes []string, targetBl
 	return payload
 }
 
+// sendMevBundle sends to a legacy matchmaker (mev: / mm:) with reasonable fallbacks.
+func sendMevBundle(ctx context.Context, p *Params, u string, headers map[string]string, authPriv *ecdsa.PrivateKey, txHexes []string, targetBlock *big.Int) (string, error) {
+	useStrategy := p.useStrategy()
 
-
-
-// classifyRelays splits relay URLs into classic (flashbots-compatible) and matchmakers (mev: / mm: / bloxroute etc.)
-func classifyRelays(relays []string, dial func(url string) *w3.Client) (classic []relayClient, matchmakers []string) {
-	for _, r := range relays {
-		u := strings.TrimSpace(r)
-		if u == "" {
-			continue
-		}
-		low := strings.ToLower(u)
-		switch {
-		case strings.HasPrefix(low, "mm:"):
-			matchmakers = append(matchmakers, strings.TrimPrefix(u, "mm:"))
-		case strings.Contains(low, "blxrbdn.com") || strings.Contains(low, "bloxroute"):
-			// bloXroute Cloud-API is not flashbots-RPC compatible — treat as matchmaker path
-			matchmakers = append(matchmakers, u)
-			continue
-		case strings.HasPrefix(low, "mev:"):
-			// explicit "mev:" prefix — treat as matchmaker and strip prefix
-			u2 := strings.TrimPrefix(u, "mev:")
-			matchmakers = append(matchmakers, u2)
-		case strings.HasPrefix(low, "classic:"):
-			u2 := strings.TrimPrefix(u, "classic:")
-			classic = append(classic, relayClient{URL: u2, C: dial(u2)})
-		case strings.Contains(low, "mev") || strings.Contains(low, "matchmaker"):
-			// backward compatibility: old heuristic
-			matchmakers = append(matchmakers, u)
-		default:
-			classic = append(classic, relayClient{URL: u, C: dial(u)})
-		}
-	}
-	return
-}
-
-// sendMevBundle handles flashbots-like and bloxroute APIs with reasonable fallbacks.
-func sendMevBundle(ctx context.Context, p *Params, url string, headers map[string]string, authPriv *ecdsa.PrivateKey, txHexes []string, targetBlock *big.Int) (string, error) {
-	u := strings.TrimPrefix(url, "mev:")
-	isBLXR := strings.Contains(strings.ToLower(u), "blxrbdn.com")
-
-	// Strategy switch (any of these knobs => strategy mode)
-	useStrategy := false
-	if p != nil {
-		useStrategy = (p.MinTimestamp > 0 ||
-			p.MaxTimestamp > 0 ||
-			p.ReplacementUUID != "" ||
-			len(p.Builders) > 0 ||
-			p.BeaverAllowBuilderNetRefunds != nil ||
-			strings.TrimSpace(p.BeaverRefundRecipientHex) != "")
-	}
-
-	// Helper to POST a JSON-RPC request with provided body.
-	postJSON := func(body []byte) (string, error) {
-		req, _ := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", "bundle-rescue/1.0")
-		for k, v := range headers {
-			req.Header.Set(k, v)
-		}
-		if authPriv != nil {
-			addr := gethcrypto.PubkeyToAddress(authPriv.PublicKey)
-			msgHash := accounts.TextHash(body)
-			sigBytes, err := gethcrypto.Sign(msgHash, authPriv)
-			if err != nil {
-				return "", err
-			}
-			req.Header.Set("X-Flashbots-Signature", addr.Hex()+":"+hexutil.Encode(sigBytes))
-		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return "", err
-		}
-		defer resp.Body.Close()
-		var out rpcResp
-		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-			return "", err
-		}
-		if out.Error != nil {
-			return "", errors.New(out.Error.Message)
-		}
-		return string(out.Result), nil
-	}
-
-	// BLXR path (Cloud API)
-	if isBLXR {
-		txsNo0x := make([]string, 0, len(txHexes))
-		for _, h := range txHexes {
-			txsNo0x = append(txsNo0x, strings.TrimPrefix(h, "0x"))
-		}
-		req := map[string]any{
-			"jsonrpc": "2.0",
-			"id":      1,
-			"method":  "blxr_submit_bundle",
-			"params": map[string]any{
-				"transaction":  txsNo0x,
-				"block_number": "0x" + targetBlock.Text(16),
-			},
-		}
-		body, _ := json.Marshal(req)
-		return postJSON(body)
-	}
+	postJSON := func(body []byte) (string, error) { return postRelayJSON(ctx, u, headers, authPriv, body) }
 
 	// STANDARD mode: strictly old behavior — mev_sendBundle with minimal payload
 	if !useStrategy {
@@ -200,6 +111,9 @@ func sendMevBundle(ctx context.Context, p *Params, url string, headers map[strin
 	if err == nil {
 		return res, nil
 	}
+	if relayseen.IsAlreadyKnown(err.Error()) {
+		return "", err // relay holds it already; mev_sendBundle would only repeat the answer
+	}
 	lowErr := strings.ToLower(err.Error())
 	if strings.Contains(lowErr, "method") ||
 		strings.Contains(lowErr, "not found") ||
@@ -216,11 +130,55 @@ func sendMevBundle(ctx context.Context, p *Params, url string, headers map[strin
 	return "", err
 }
 
+// postRelayJSON POSTs one JSON-RPC request to a relay (signed with authPriv when set) and
+// returns the raw result.
+func postRelayJSON(ctx context.Context, u string, headers map[string]string, authPriv *ecdsa.PrivateKey, body []byte) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bundle-rescue/1.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if authPriv != nil {
+		addr := gethcrypto.PubkeyToAddress(authPriv.PublicKey)
+		msgHash := accounts.TextHash(body)
+		sigBytes, err := gethcrypto.Sign(msgHash, authPriv)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("X-Flashbots-Signature", addr.Hex()+":"+hexutil.Encode(sigBytes))
+	}
+	resp, err := rpcHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out rpcResp
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if out.Error != nil {
+		return "", errors.New(out.Error.Message)
+	}
+	return string(out.Result), nil
+}
 
-func simulateMevBundle(ctx context.Context, p *Params, url string, headers map[string]string, authPriv *ecdsa.PrivateKey, txHexes []string, targetBlock *big.Int) (string, bool, error) {
-    u := strings.TrimPrefix(url, "mev:")
-    low := strings.ToLower(u)
+// useStrategy reports whether any strategy knob is set (legacy matchmakers then send
+// eth_sendBundle with the extended payload, see buildStrategyPayload).
+func (p *Params) useStrategy() bool {
+	if p == nil {
+		return false
+	}
+	return p.MinTimestamp > 0 ||
+		p.MaxTimestamp > 0 ||
+		p.ReplacementUUID != "" ||
+		len(p.Builders) > 0 ||
+		p.BeaverAllowBuilderNetRefunds != nil ||
+		strings.TrimSpace(p.BeaverRefundRecipientHex) != ""
+}
 
+// simulateMevBundle simulates on a legacy matchmaker; ok is false when it gave no simulation.
+func simulateMevBundle(ctx context.Context, p *Params, u string, headers map[string]string, authPriv *ecdsa.PrivateKey, txHexes []string, targetBlock *big.Int) (string, bool, error) {
     maybeLogBundleOnce(txHexes, targetBlock)
 
     // Use parent block as state base for simulation.
@@ -233,75 +191,7 @@ func simulateMevBundle(ctx context.Context, p *Params, url string, headers map[s
     }
 
     // Decide whether strategy knobs are enabled (used below after simOnce is declared).
-    useStrategy := false
-    if p != nil {
-        useStrategy = (p.MinTimestamp > 0 ||
-            p.MaxTimestamp > 0 ||
-            p.ReplacementUUID != "" ||
-            len(p.Builders) > 0 ||
-            p.BeaverAllowBuilderNetRefunds != nil ||
-            strings.TrimSpace(p.BeaverRefundRecipientHex) != "")
-    }
-	// ---- bloXroute Cloud-API ----
-    if strings.Contains(low, "blxrbdn.com") || strings.Contains(low, "bloxroute") {
-        txNo0x := make([]string, 0, len(txHexes))
-        for _, h := range txHexes {
-            txNo0x = append(txNo0x, strings.TrimPrefix(h, "0x"))
-        }
-        params := BlxrSimulateBundleParams{
-            Transaction:       txNo0x,
-            BlockNumber:       "0x" + targetBlock.Text(16),
-            BlockchainNetwork: "Mainnet",
-        }
-        body, _ := json.Marshal(map[string]any{
-            "jsonrpc": "2.0",
-            "id":      "1",
-            "method":  "blxr_simulate_bundle",
-            "params":  params,
-        })
-
-        req, _ := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
-        req.Header.Set("Content-Type", "application/json")
-        for k, v := range headers {
-            req.Header.Set(k, v)
-        }
-        // No X-Flashbots-Signature for BLXR; only Authorization is required.
-        resp, err := http.DefaultClient.Do(req)
-        if err != nil {
-            return "", false, err
-        }
-        defer resp.Body.Close()
-
-        raw, _ := io.ReadAll(resp.Body)
-        if resp.StatusCode != http.StatusOK {
-            return string(raw), false, fmt.Errorf("http %d", resp.StatusCode)
-        }
-        var parsed BlxrSimulateBundleResponse
-        if err := json.Unmarshal(raw, &parsed); err != nil {
-            return string(raw), false, err
-        }
-        if parsed.Error != nil {
-            // Gracefully degrade on plans/endpoints where BLXR simulation is not supported.
-            // The Cloud API returns e.g. "simulation not supported on matchmaker" for
-            // non-Ultra/Enterprise plans – treat this as "unsupported" so the caller can still send.
-            lowMsg := strings.ToLower(parsed.Error.Message)
-            if strings.Contains(lowMsg, "not supported") || strings.Contains(lowMsg, "simulation not supported") {
-                return string(raw), false, nil
-            }
-            return string(raw), false, errors.New(parsed.Error.Message)
-        }
-        ok := parsed.Result != nil
-        if ok {
-            for _, r := range parsed.Result.Results {
-                if r.Error != "" || r.Revert != "" {
-                    ok = false
-                    break
-                }
-            }
-        }
-        return string(raw), ok, nil
-    }
-
+    useStrategy := p.useStrategy()
     // ---- classic relays: try eth_callBundle first, then fallback to mev_simBundle ----
     simOnce := func(method string) (raw string, ok bool, err error) {
 
@@ -323,7 +213,7 @@ func simulateMevBundle(ctx context.Context, p *Params, url string, headers map[s
                 req.Header.Set("X-Flashbots-Signature", addr.Hex()+":"+hexutil.Encode(sigBytes))
             }
         }
-        resp, err := http.DefaultClient.Do(req)
+        resp, err := rpcHTTP.Do(req)
         if err != nil {
             return "", false, err
         }
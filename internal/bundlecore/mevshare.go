@@ -0,0 +1,193 @@
+package bundlecore
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// MEV-Share relays ("share:<url>" in Relays, e.g. share:https://relay.flashbots.net; the
+// mev-share relay kind): the bundle goes out as mev_sendBundle v0.1 — an inclusion block
+// range, privacy hints that let searchers backrun it, and a refundConfig that pays the
+// backrun refund to To (the rescue destination) instead of the signer of the first tx.
+// Simulation is mev_simBundle on the same body. Legacy mev_sendBundle/eth_sendBundle relays
+// are unaffected.
+
+const shareVersion = "v0.1"
+
+// ShareHints are the privacy hints a MEV-Share relay accepts.
+var ShareHints = []string{"calldata", "contract_address", "logs", "function_selector", "hash", "tx_hash", "default_logs", "special_logs"}
+
+// DefaultShareHints is what a rescue bundle reveals when ShareOptions.Hints is empty: enough
+// for searchers to find a backrun (e.g. after a router sell), but no calldata.
+var DefaultShareHints = []string{"hash", "contract_address", "function_selector", "logs"}
+
+// ShareOptions tunes the bundles sent to "share:" relays.
+type ShareOptions struct {
+	MaxBlocks int      // inclusion.maxBlock = target + MaxBlocks (0 = the target block only)
+	Hints     []string // privacy.hints (empty = DefaultShareHints)
+	Builders  []string // privacy.builders (empty = the relay's default)
+	RefundTo  string   // refundConfig address for backrun refunds (empty = To)
+}
+
+// ParseShareHints splits a comma list of privacy hints, rejecting unknown ones.
+func ParseShareHints(s string) ([]string, error) {
+	var out []string
+	for _, h := range strings.Split(s, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		known := false
+		for _, k := range ShareHints {
+			known = known || h == k
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown MEV-Share hint %q (known: %s)", h, strings.Join(ShareHints, ", "))
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// buildSharePayload returns the mev_sendBundle v0.1 bundle for txHexes at targetBlock.
+func buildSharePayload(p *Params, txHexes []string, targetBlock *big.Int) map[string]any {
+	body := make([]map[string]any, 0, len(txHexes))
+	for _, h := range txHexes {
+		body = append(body, map[string]any{"tx": h, "canRevert": false})
+	}
+	inclusion := map[string]any{"block": "0x" + targetBlock.Text(16)}
+	var opt ShareOptions
+	var to common.Address
+	if p != nil {
+		opt, to = p.Share, p.To
+	}
+	if opt.MaxBlocks > 0 {
+		inclusion["maxBlock"] = "0x" + new(big.Int).Add(targetBlock, big.NewInt(int64(opt.MaxBlocks))).Text(16)
+	}
+	hints := opt.Hints
+	if len(hints) == 0 {
+		hints = DefaultShareHints
+	}
+	privacy := map[string]any{"hints": hints}
+	if len(opt.Builders) > 0 {
+		privacy["builders"] = opt.Builders
+	}
+	payload := map[string]any{
+		"version":   shareVersion,
+		"inclusion": inclusion,
+		"body":      body,
+		"privacy":   privacy,
+	}
+	refundTo := to
+	if common.IsHexAddress(opt.RefundTo) {
+		refundTo = common.HexToAddress(opt.RefundTo)
+	}
+	if refundTo != (common.Address{}) {
+		payload["validity"] = map[string]any{
+			"refundConfig": []map[string]any{{"address": refundTo.Hex(), "percent": 100}},
+		}
+	}
+	return payload
+}
+
+// postShare sends one signed JSON-RPC call to a MEV-Share relay and returns its raw result.
+func postShare(ctx context.Context, url string, headers map[string]string, authPriv *ecdsa.PrivateKey, method string, params []any) (json.RawMessage, error) {
+	if authPriv == nil {
+		return nil, errors.New("MEV-Share needs FLASHBOTS_AUTH_PK (requests must be signed)")
+	}
+	body, _ := json.Marshal(rpcReq{Jsonrpc: "2.0", Method: method, Params: params, ID: 1})
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bundle-rescue/1.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	sig, err := gethcrypto.Sign(accounts.TextHash([]byte(gethcrypto.Keccak256Hash(body).Hex())), authPriv)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Flashbots-Signature", gethcrypto.PubkeyToAddress(authPriv.PublicKey).Hex()+":"+hexutil.Encode(sig))
+	resp, err := rpcHTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	var out rpcResp
+	if err := json.Unmarshal(raw, &out); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+		}
+		return nil, err
+	}
+	if out.Error != nil {
+		return nil, errors.New(out.Error.Message)
+	}
+	return out.Result, nil
+}
+
+// sendShareBundle submits the bundle to the MEV-Share endpoint url; the result is the bundle hash.
+func sendShareBundle(ctx context.Context, p *Params, url string, headers map[string]string, authPriv *ecdsa.PrivateKey, txHexes []string, targetBlock *big.Int) (string, error) {
+	res, err := postShare(ctx, url, headers, authPriv, "mev_sendBundle", []any{buildSharePayload(p, txHexes, targetBlock)})
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		BundleHash string `json:"bundleHash"`
+	}
+	if json.Unmarshal(res, &out) == nil && out.BundleHash != "" {
+		return out.BundleHash, nil
+	}
+	return string(res), nil
+}
+
+// simulateShareBundle runs mev_simBundle on the parent block of targetBlock. ok is false only
+// when the relay gave no simulation; a failing bundle returns ok with the error.
+func simulateShareBundle(ctx context.Context, p *Params, url string, headers map[string]string, authPriv *ecdsa.PrivateKey, txHexes []string, targetBlock *big.Int) (string, bool, error) {
+	parent := new(big.Int).Sub(targetBlock, big.NewInt(1))
+	if parent.Sign() < 0 {
+		parent = big.NewInt(0)
+	}
+	res, err := postShare(ctx, url, headers, authPriv, "mev_simBundle", []any{
+		buildSharePayload(p, txHexes, targetBlock),
+		map[string]any{"parentBlock": "0x" + parent.Text(16)},
+	})
+	if err != nil {
+		return "", false, err
+	}
+	var out struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(res, &out); err != nil {
+		return string(res), false, err
+	}
+	if !out.Success {
+		if out.Error == "" {
+			out.Error = "mev_simBundle: bundle failed"
+		}
+		return string(res), true, errors.New(out.Error)
+	}
+	return string(res), true, nil
+}
+
+// errText is err's message ("" for nil), for OnSimResult.
+func errText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
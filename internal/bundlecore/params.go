@@ -3,8 +3,11 @@ package bundlecore
 import (
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
 )
 
 type Params struct {
@@ -23,12 +26,23 @@ type Params struct {
 	BeaverAllowBuilderNetRefunds *bool
 	BeaverRefundRecipientHex     string
 
+	// MEV-Share ("share:" relays): inclusion range, privacy hints, refund recipient
+	Share ShareOptions
+
 	// Transfer details
 	Token     common.Address
 	From      common.Address
 	To        common.Address
 	AmountWei *big.Int
 
+	// Route for the victim tx: "transfer" (default), "router" (sell via a known V2 router
+	// the victim already approved, ETH goes to To) or "auto" (router only if transfer
+	// preflight fails). SellMinOutWei is the swap's amountOutMin; nil = the router's quote
+	// minus SellSlippageBps (0 = DefaultSellSlippageBps).
+	Route           string
+	SellMinOutWei   *big.Int
+	SellSlippageBps int64
+
 	// Keys
 	SafePKHex string
 	FromPKHex string
@@ -48,17 +62,48 @@ type Params struct {
 	TipWindow     int    // last N blocks for eth_feeHistory.reward
 	TipPercentile int    // 1..99 (usually 99)
 
+	// Competition handling: when a competing tx from the victim's nonce shows up
+	// mid-run, rebuild in replace mode with tip bumped by this percent (compounded once
+	// per new competitor nonce, up to CompeteMaxMul; 0 = DefaultCompeteMaxMul) and retry
+	// next block instead of stopping with "competing nonce". 0 = off.
+	CompeteBumpPct int64
+	CompeteMaxMul  float64
+
+	// AccessList attaches an EIP-2930 access list (eth_createAccessList) to the transfer tx
+	// when it lowers the gas estimate; RPCs without the method get the plain tx.
+	AccessList bool
+
 	// Optional coinbase bribe
 	BribeWei      *big.Int
 	BribeGasLimit uint64
 
 	// Per-relay extra headers
 	ExtraHeaders map[string]map[string]string
+
+	// Optional result cache for restriction checks (nil = always query)
+	Cache    Cache
+	CacheTTL time.Duration
+
+	// Head cross-check: secondary RPC endpoints asked for eth_blockNumber every attempt.
+	// When the primary RPC lags, target blocks are computed from the fresher head and a
+	// warning is logged once the lag exceeds HeadLagWarn blocks (0 = DefaultHeadLagWarn).
+	HeadCheckRPCs []string
+	HeadLagWarn   int
+
+	// LocalFork mines the bundle straight on a dev node (anvil/hardhat at RPC) instead of
+	// sending it to relays. Rehearsals only; Relays may be empty.
+	LocalFork bool
 }
 
 type Result struct {
-	Included bool
-	Reason   string
+	Included    bool
+	Code        ReasonCode   // what happened (see reason.go); branch on this, not on Reason
+	Reason      string       // the same for people, with details
+	Restriction reasons.Code // ReasonTokenPaused / ReasonTokenRestricted: which restriction (PAUSED, BLACKLISTED_FROM, ...)
+	Moved       *big.Int     // token amount confirmed via Transfer logs; nil if not observed
+	TxHash      common.Hash  // transfer tx of the included bundle; zero when not included
+	Builder     string       // builder of the inclusion block (builders.go); "" = unknown or not included
+	WinRelays   []string     // hosts of the RELAYS entries that reach Builder
 }
 
 func (p *Params) logf(format string, a ...any) {
@@ -74,7 +119,7 @@ func (p *Params) headerFor(u string) map[string]string {
 	if h, ok := p.ExtraHeaders[u]; ok {
 		return h
 	}
-	u2 := strings.TrimPrefix(u, "mev:")
+	u2 := strings.TrimPrefix(strings.TrimPrefix(u, "mev:"), "share:")
 	if h, ok := p.ExtraHeaders[u2]; ok {
 		return h
 	}
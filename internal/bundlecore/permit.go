@@ -0,0 +1,104 @@
+package bundlecore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EIP-2612 selectors.
+var (
+	selPermit          = [4]byte{0xd5, 0x05, 0xac, 0xcf} // permit(address,address,uint256,uint256,uint8,bytes32,bytes32)
+	selDaiPermit       = [4]byte{0x8f, 0xcb, 0xaf, 0x0c} // DAI: permit(address,address,uint256,uint256,bool,uint8,bytes32,bytes32)
+	selDomainSeparator = common.FromHex("0x3644e515")    // DOMAIN_SEPARATOR()
+	selNonces          = common.FromHex("0x7ecebe00")    // nonces(address)
+)
+
+// PermitSupport is what DetectPermit found out about EIP-2612 on a token.
+type PermitSupport struct {
+	Supported       bool
+	DomainSeparator common.Hash
+	Nonce           *big.Int // nonces(owner); nil when the getter is missing
+	Reason          string   // why the token is not permit-capable ("" when Supported)
+}
+
+// DetectPermit probes token for EIP-2612: DOMAIN_SEPARATOR() and nonces(owner) must answer,
+// and permit(...) must be in the dispatcher of the token code (or of its EIP-1967 / EIP-1167
+// implementation). Permit-capable tokens can move with a signed approval instead of a
+// transfer from FROM. Reverting getters mean "no"; only transport errors are returned.
+func DetectPermit(ctx context.Context, ec *ethclient.Client, token, owner common.Address) (PermitSupport, error) {
+	var ps PermitSupport
+	ds, err := permitCall(ctx, ec, token, selDomainSeparator)
+	if err != nil {
+		return ps, err
+	}
+	if len(ds) < 32 || common.BytesToHash(ds[:32]) == (common.Hash{}) {
+		ps.Reason = "no DOMAIN_SEPARATOR()"
+		return ps, nil
+	}
+	ps.DomainSeparator = common.BytesToHash(ds[:32])
+	n, err := permitCall(ctx, ec, token, append(append([]byte{}, selNonces...), common.LeftPadBytes(owner.Bytes(), 32)...))
+	if err != nil {
+		return ps, err
+	}
+	if len(n) < 32 {
+		ps.Reason = "no nonces(address)"
+		return ps, nil
+	}
+	ps.Nonce = new(big.Int).SetBytes(n[:32])
+
+	code, err := ec.CodeAt(ctx, token, nil)
+	if err != nil {
+		return ps, err
+	}
+	if impl := minimalProxyTarget(code); impl != (common.Address{}) {
+		if code, err = ec.CodeAt(ctx, impl, nil); err != nil {
+			return ps, err
+		}
+	} else if !hasSelector(code, selPermit) && !hasSelector(code, selDaiPermit) {
+		if impl, err := ProxyImplementation(ctx, ec, token); err == nil && impl != (common.Address{}) {
+			if code, err = ec.CodeAt(ctx, impl, nil); err != nil {
+				return ps, err
+			}
+		}
+	}
+	switch {
+	case hasSelector(code, selPermit):
+		ps.Supported = true
+	case hasSelector(code, selDaiPermit):
+		ps.Reason = "DAI-style permit (not EIP-2612)"
+	default:
+		ps.Reason = "no permit() in the contract code"
+	}
+	return ps, nil
+}
+
+// permitCall is an eth_call where a revert (JSON-RPC error) is an empty answer, not an error.
+func permitCall(ctx context.Context, ec *ethclient.Client, token common.Address, data []byte) ([]byte, error) {
+	res, err := ec.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	var rerr rpc.Error
+	if errors.As(err, &rerr) {
+		return nil, nil
+	}
+	return res, err
+}
+
+// hasSelector reports whether code pushes sel (PUSH4 sel), as a Solidity/Vyper dispatcher does.
+func hasSelector(code []byte, sel [4]byte) bool {
+	return bytes.Contains(code, []byte{0x63, sel[0], sel[1], sel[2], sel[3]})
+}
+
+// minimalProxyTarget returns the implementation of an EIP-1167 minimal proxy (zero otherwise).
+func minimalProxyTarget(code []byte) common.Address {
+	prefix := common.FromHex("0x363d3d373d3d3d363d73")
+	if len(code) >= len(prefix)+20 && bytes.HasPrefix(code, prefix) {
+		return common.BytesToAddress(code[len(prefix) : len(prefix)+20])
+	}
+	return common.Address{}
+}
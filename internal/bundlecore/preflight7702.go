@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 
@@ -15,6 +16,7 @@ import (
 
 const minimalNonEmptyCode = "0x00"
 
+// PreflightTransfer7702 runs PreflightTransfer7702On with the mainnet pair (Mainnet).
 func PreflightTransfer7702(
 	ctx context.Context,
 	ec *ethclient.Client,
@@ -23,6 +25,22 @@ func PreflightTransfer7702(
 	fromEOA common.Address,
 	recipient common.Address,
 	amount *big.Int,
+) (bool, string, error) {
+	return PreflightTransfer7702On(ctx, Mainnet, ec, rc, token, fromEOA, recipient, amount)
+}
+
+// PreflightTransfer7702On simulates the transfer from fromEOA with code (7702 context);
+// when the direct transfer fails, the sell route is tried: a transfer into the token/WETH
+// pair of chain's V2 factory.
+func PreflightTransfer7702On(
+	ctx context.Context,
+	chain Chain,
+	ec *ethclient.Client,
+	rc *rpc.Client,
+	token common.Address,
+	fromEOA common.Address,
+	recipient common.Address,
+	amount *big.Int,
 ) (bool, string, error) {
 	if amount == nil || amount.Sign() == 0 {
 		return false, "no balance", nil
@@ -39,8 +57,10 @@ func PreflightTransfer7702(
 		return true, "route=direct", nil
 	}
 
-	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
-	pair := getV2Pair(ctx, ec, token, weth)
+	if chain.V2Factory == (common.Address{}) {
+		return false, fmt.Sprintf("no v2 factory known for chain %d (router path not checked)", chain.ID), nil
+	}
+	pair := getV2Pair(ctx, ec, chain.V2Factory, token, chain.WETH)
 	if pair == (common.Address{}) {
 		return false, "no v2 pair for router path", nil
 	}
@@ -91,14 +111,13 @@ func simulateTransferWithOverride(
 	return false, "", nil
 }
 
-func getV2Pair(ctx context.Context, ec *ethclient.Client, token, weth common.Address) common.Address {
+func getV2Pair(ctx context.Context, ec *ethclient.Client, factory, token, weth common.Address) common.Address {
 	selector := []byte{0xe6, 0xa4, 0x39, 0x05}
 	data := make([]byte, 0, 4+32+32)
 	data = append(data, selector...)
 	data = append(data, common.LeftPadBytes(token.Bytes(), 32)...)
 	data = append(data, common.LeftPadBytes(weth.Bytes(), 32)...)
 
-	factory := common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
 	out, err := ec.CallContract(ctx, ethereum.CallMsg{To: &factory, Data: data}, nil)
 	if err != nil || len(out) < 32 {
 		return common.Address{}
@@ -0,0 +1,45 @@
+package bundlecore
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// EIP-1967 storage slots: keccak256("eip1967.proxy.implementation") - 1 and
+// keccak256("eip1967.proxy.beacon") - 1.
+var (
+	eip1967ImplSlot   = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	eip1967BeaconSlot = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+)
+
+// ProxyImplementation returns the implementation behind an EIP-1967 proxy: the address in
+// the implementation slot, else beacon.implementation() for a beacon proxy. Zero address
+// when token is not such a proxy (or the implementation has no code).
+func ProxyImplementation(ctx context.Context, ec *ethclient.Client, token common.Address) (common.Address, error) {
+	word, err := ec.StorageAt(ctx, token, eip1967ImplSlot, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	impl := common.BytesToAddress(word)
+	if impl == (common.Address{}) {
+		if word, err = ec.StorageAt(ctx, token, eip1967BeaconSlot, nil); err != nil {
+			return common.Address{}, err
+		}
+		beacon := common.BytesToAddress(word)
+		if beacon == (common.Address{}) {
+			return common.Address{}, nil
+		}
+		res, err := ec.CallContract(ctx, ethereum.CallMsg{To: &beacon, Data: common.FromHex("0x5c60da1b")}, nil) // implementation()
+		if err != nil || len(res) < 32 {
+			return common.Address{}, err
+		}
+		impl = common.BytesToAddress(res[:32])
+	}
+	if code, err := ec.CodeAt(ctx, impl, nil); err != nil || len(code) == 0 {
+		return common.Address{}, err
+	}
+	return impl, nil
+}
@@ -0,0 +1,75 @@
+package bundlecore
+
+import "errors"
+
+// Outcome of Run / SweepETH: Result.Code says what happened, Result.Reason is the text for
+// people (it may carry details and change wording). Callers branch on the code, or on
+// Result.Err with errors.Is against the sentinels below; errors returned next to the Result
+// are wrapped with %w, so errors.Is/As reach the RPC or key error underneath.
+
+// ReasonCode is the typed outcome of a run.
+type ReasonCode string
+
+const (
+	ReasonIncluded         ReasonCode = "included"
+	ReasonSimulateOnly     ReasonCode = "simulate_only"             // SimulateOnly: a bundle simulated fine, nothing was sent
+	ReasonSimulationFailed ReasonCode = "simulation_failed"         // SimulateOnly: no attempt simulated
+	ReasonCompetingNonce   ReasonCode = "competing_nonce"           // FROM's nonce moved: someone else spent from it
+	ReasonInsufficientSafe ReasonCode = "insufficient_safe_balance" // SAFE cannot pay the fees plus FROM's prefund
+	ReasonInsufficientFrom ReasonCode = "insufficient_from"         // SweepETH: FROM's ETH does not cover the gas
+	ReasonTokenPaused      ReasonCode = "token_paused"              // SkipIfPaused
+	ReasonTokenRestricted  ReasonCode = "token_restricted"          // Result.Restriction says which
+	ReasonNoRoute          ReasonCode = "no_route"                  // Params.Route router/auto: no router takes the tokens
+	ReasonRelayLimits      ReasonCode = "relay_limits"              // the bundle exceeds the limits of every relay
+	ReasonNotIncluded      ReasonCode = "not_included"              // attempts exhausted
+)
+
+// ReasonError is a Result that rescued nothing, as an error (Result.Err).
+type ReasonError struct {
+	Code   ReasonCode
+	Reason string
+}
+
+func (e *ReasonError) Error() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	return string(e.Code)
+}
+
+// Is matches any ReasonError of the same code, so errors.Is(res.Err(), ErrCompetingNonce)
+// holds whatever the text says.
+func (e *ReasonError) Is(target error) bool {
+	var t *ReasonError
+	return errors.As(target, &t) && t.Code == e.Code
+}
+
+// Sentinels of the failing codes, for errors.Is.
+var (
+	ErrSimulationFailed = &ReasonError{Code: ReasonSimulationFailed}
+	ErrCompetingNonce   = &ReasonError{Code: ReasonCompetingNonce}
+	ErrInsufficientSafe = &ReasonError{Code: ReasonInsufficientSafe}
+	ErrInsufficientFrom = &ReasonError{Code: ReasonInsufficientFrom}
+	ErrTokenPaused      = &ReasonError{Code: ReasonTokenPaused}
+	ErrTokenRestricted  = &ReasonError{Code: ReasonTokenRestricted}
+	ErrNoRoute          = &ReasonError{Code: ReasonNoRoute}
+	ErrRelayLimits      = &ReasonError{Code: ReasonRelayLimits}
+	ErrNotIncluded      = &ReasonError{Code: ReasonNotIncluded}
+)
+
+// ErrNoRelays: Run / SweepETH without RELAYS (and not on a local fork).
+var ErrNoRelays = errors.New("no relays or matchmakers configured")
+
+// failed is the Result of a run that stops with code.
+func failed(code ReasonCode, reason string) Result {
+	return Result{Code: code, Reason: reason}
+}
+
+// Err is nil when the bundle was included or (SimulateOnly) simulated fine, else a
+// *ReasonError of the Result's code.
+func (r Result) Err() error {
+	if r.Included || r.Code == ReasonSimulateOnly || (r.Code == "" && r.Reason == "") {
+		return nil
+	}
+	return &ReasonError{Code: r.Code, Reason: r.Reason}
+}
@@ -0,0 +1,157 @@
+package bundlecore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Relays: every RELAYS entry becomes a Relay of the kind the registry picks for it — by an
+// explicit "kind:" prefix (flashbots:, beaver:, titan:, bloxroute:, share:, mev:/mm:,
+// classic:) or, without one, by the URL. Run, SweepETH and SimulateApprovalRoute only talk
+// to the Relay interface; a new relay API is one more RelayKind (RegisterRelayKind), the run
+// loop does not change.
+
+// Bundle is what a relay simulates or sends.
+type Bundle struct {
+	Txs   []*types.Transaction // signed, in bundle order
+	Hexes []string             // their raw encoding (0x...)
+	Block *big.Int             // target block
+}
+
+// ErrRelayUnsupported is returned by the Relay methods a relay API does not offer (Cancel
+// on bloXroute, Simulate on a matchmaker without simulation, ...).
+var ErrRelayUnsupported = errors.New("not supported by relay")
+
+// Relay is one bundle endpoint.
+type Relay interface {
+	// URL names the relay in logs, OnSimResult, ExtraHeaders and the "bundle held by" line:
+	// the RELAYS entry, its kind prefix stripped (share: is kept, the same URL may also be
+	// listed as a plain relay).
+	URL() string
+	// Kind is the name of the RelayKind that built it.
+	Kind() string
+	// Limits is what the relay takes in one request; RequestSize the size of the send
+	// request for b, checked against Limits().MaxBytes.
+	Limits() RelayLimits
+	RequestSize(b Bundle) int
+	// Simulate runs b on the relay: the raw answer, and an error when the bundle (or the
+	// call) failed. ErrRelayUnsupported: the relay does not simulate.
+	Simulate(ctx context.Context, b Bundle) (raw string, err error)
+	// Send submits b; the result is the relay's answer (usually the bundle hash).
+	Send(ctx context.Context, b Bundle) (string, error)
+	// Status asks what became of a sent bundle (raw answer).
+	Status(ctx context.Context, bundleHash string, block *big.Int) (string, error)
+	// Cancel withdraws the bundles sent with replacementUUID (Params.ReplacementUUID).
+	Cancel(ctx context.Context, replacementUUID string) error
+}
+
+// RelayConfig is what a relay is built with.
+type RelayConfig struct {
+	Params  *Params           // the run's knobs (strategy fields, Share, ExtraHeaders)
+	AuthPrv *ecdsa.PrivateKey // FLASHBOTS_AUTH_PK; nil = requests are not signed
+}
+
+// headers are the ExtraHeaders of relay u.
+func (c RelayConfig) headers(u string) map[string]string {
+	if c.Params == nil {
+		return nil
+	}
+	return c.Params.headerFor(u)
+}
+
+// RelayKind is one relay implementation in the registry.
+type RelayKind struct {
+	Name string
+	// Prefixes select the kind explicitly ("titan:https://..."); they are stripped from
+	// the URL handed to New.
+	Prefixes []string
+	// Match selects the kind by the (lower-case) URL when no prefix did; nil = by prefix only.
+	Match func(lowURL string) bool
+	New   func(url string, cfg RelayConfig) (Relay, error)
+}
+
+var (
+	relayKindsMu sync.RWMutex
+	relayKinds   = builtinRelayKinds()
+)
+
+// defaultRelayKind takes the URLs no kind matched: a Flashbots-compatible eth_sendBundle relay.
+const defaultRelayKind = "classic"
+
+// RegisterRelayKind adds k to the registry ahead of the built-in kinds, so it may also take
+// over URLs they would match. A kind of the same name is replaced.
+func RegisterRelayKind(k RelayKind) {
+	relayKindsMu.Lock()
+	defer relayKindsMu.Unlock()
+	out := []RelayKind{k}
+	for _, old := range relayKinds {
+		if old.Name != k.Name {
+			out = append(out, old)
+		}
+	}
+	relayKinds = out
+}
+
+// RelayKinds lists the registered kinds by name, in lookup order.
+func RelayKinds() []string {
+	relayKindsMu.RLock()
+	defer relayKindsMu.RUnlock()
+	out := make([]string, 0, len(relayKinds))
+	for _, k := range relayKinds {
+		out = append(out, k.Name)
+	}
+	return out
+}
+
+// relayKindFor picks the kind of a RELAYS entry and returns it with the URL to build it
+// from (the prefix stripped).
+func relayKindFor(entry string) (RelayKind, string) {
+	relayKindsMu.RLock()
+	defer relayKindsMu.RUnlock()
+	low := strings.ToLower(entry)
+	for _, k := range relayKinds {
+		for _, pre := range k.Prefixes {
+			if strings.HasPrefix(low, pre) {
+				return k, strings.TrimSpace(entry[len(pre):])
+			}
+		}
+	}
+	var def RelayKind
+	for _, k := range relayKinds {
+		if k.Match != nil && k.Match(low) {
+			return k, entry
+		}
+		if k.Name == defaultRelayKind {
+			def = k
+		}
+	}
+	return def, entry
+}
+
+// NewRelays builds the relays of RELAYS entries (blank ones are skipped).
+func NewRelays(entries []string, cfg RelayConfig) ([]Relay, error) {
+	var out []Relay
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		k, u := relayKindFor(e)
+		if k.New == nil {
+			return nil, fmt.Errorf("relay %s: no relay kind for it", e)
+		}
+		r, err := k.New(u, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("relay %s (%s): %w", e, k.Name, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
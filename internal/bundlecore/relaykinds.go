@@ -0,0 +1,307 @@
+package bundlecore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/lmittmann/flashbots"
+	w3 "github.com/lmittmann/w3"
+)
+
+// builtinRelayKinds is the registry in lookup order: the URL heuristics of matchmakers and
+// bloXroute come before the builder hosts ("mev" in a flashbots.net URL means a matchmaker).
+func builtinRelayKinds() []RelayKind {
+	return []RelayKind{
+		{Name: "mev-share", Prefixes: []string{"share:"}, New: newShareRelay},
+		{Name: "bloxroute", Prefixes: []string{"bloxroute:", "blxr:"}, New: newBloxrouteRelay,
+			Match: func(u string) bool { return strings.Contains(u, "blxrbdn.com") || strings.Contains(u, "bloxroute") }},
+		{Name: "matchmaker", Prefixes: []string{"mev:", "mm:"}, New: newMatchmakerRelay,
+			Match: func(u string) bool { return strings.Contains(u, "mev") || strings.Contains(u, "matchmaker") }},
+		{Name: "flashbots", Prefixes: []string{"flashbots:"}, New: bundleRelayKind("flashbots", "flashbots_getBundleStatsV2", true),
+			Match: func(u string) bool { return strings.Contains(u, "flashbots.net") }},
+		{Name: "beaver", Prefixes: []string{"beaver:"}, New: bundleRelayKind("beaver", "", false),
+			Match: func(u string) bool { return strings.Contains(u, "beaverbuild.org") }},
+		{Name: "titan", Prefixes: []string{"titan:"}, New: bundleRelayKind("titan", "titan_getBundleStats", true),
+			Match: func(u string) bool { return strings.Contains(u, "titanbuilder.xyz") }},
+		{Name: defaultRelayKind, Prefixes: []string{"classic:"}, New: bundleRelayKind(defaultRelayKind, "", false)},
+	}
+}
+
+// ---- Flashbots-compatible builders (eth_callBundle / eth_sendBundle) ----
+
+// bundleRelay is a relay dialed via w3 + flashbots: Flashbots, Beaver, Titan and any
+// other eth_sendBundle endpoint.
+type bundleRelay struct {
+	url, kind    string
+	c            *w3.Client
+	rc           *rpc.Client
+	statusMethod string // "" = no bundle status API
+	cancels      bool   // eth_cancelBundle by replacementUuid
+}
+
+func bundleRelayKind(kind, statusMethod string, cancels bool) func(string, RelayConfig) (Relay, error) {
+	return func(u string, cfg RelayConfig) (Relay, error) {
+		rc, err := dialRelay(u, cfg.AuthPrv)
+		if err != nil {
+			return nil, err
+		}
+		return &bundleRelay{url: u, kind: kind, c: w3.NewClient(rc), rc: rc, statusMethod: statusMethod, cancels: cancels}, nil
+	}
+}
+
+func (r *bundleRelay) URL() string         { return r.url }
+func (r *bundleRelay) Kind() string        { return r.kind }
+func (r *bundleRelay) Limits() RelayLimits { return relayLimitsFor(r.url) }
+func (r *bundleRelay) RequestSize(b Bundle) int {
+	return requestSize("eth_sendBundle", buildStandardPayload(b.Hexes, b.Block))
+}
+
+func (r *bundleRelay) Simulate(ctx context.Context, b Bundle) (string, error) {
+	var resp *flashbots.CallBundleResponse
+	err := r.c.CallCtx(ctx,
+		flashbots.CallBundle(&flashbots.CallBundleRequest{
+			Transactions: b.Txs,
+			BlockNumber:  new(big.Int).Set(b.Block),
+		}).Returns(&resp),
+	)
+	raw := ""
+	if resp != nil {
+		bs, _ := json.Marshal(resp)
+		raw = string(bs)
+		for _, res := range resp.Results {
+			if res.Error != nil {
+				return raw, res.Error
+			}
+			if len(res.Revert) > 0 {
+				return raw, errors.New(res.Revert)
+			}
+		}
+	}
+	return raw, err
+}
+
+func (r *bundleRelay) Send(ctx context.Context, b Bundle) (string, error) {
+	var bundleHash common.Hash
+	err := r.c.CallCtx(ctx,
+		flashbots.SendBundle(&flashbots.SendBundleRequest{
+			Transactions: b.Txs,
+			BlockNumber:  new(big.Int).Set(b.Block),
+		}).Returns(&bundleHash),
+	)
+	if err != nil {
+		return "", err
+	}
+	return bundleHash.Hex(), nil
+}
+
+func (r *bundleRelay) Status(ctx context.Context, bundleHash string, block *big.Int) (string, error) {
+	if r.statusMethod == "" {
+		return "", ErrRelayUnsupported
+	}
+	arg := map[string]any{"bundleHash": bundleHash}
+	if r.statusMethod == "flashbots_getBundleStatsV2" && block != nil {
+		arg["blockNumber"] = "0x" + block.Text(16)
+	}
+	var raw json.RawMessage
+	if err := r.rc.CallContext(ctx, &raw, r.statusMethod, arg); err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (r *bundleRelay) Cancel(ctx context.Context, replacementUUID string) error {
+	if !r.cancels {
+		return ErrRelayUnsupported
+	}
+	if replacementUUID == "" {
+		return errors.New("cancel needs the bundle's replacementUuid")
+	}
+	var raw json.RawMessage
+	return r.rc.CallContext(ctx, &raw, "eth_cancelBundle", map[string]any{"replacementUuid": replacementUUID})
+}
+
+// ---- legacy matchmakers (mev: / mm:, mev_sendBundle) ----
+
+type matchmakerRelay struct {
+	url string
+	cfg RelayConfig
+}
+
+func newMatchmakerRelay(u string, cfg RelayConfig) (Relay, error) {
+	return &matchmakerRelay{url: u, cfg: cfg}, nil
+}
+
+func (r *matchmakerRelay) URL() string         { return r.url }
+func (r *matchmakerRelay) Kind() string        { return "matchmaker" }
+func (r *matchmakerRelay) Limits() RelayLimits { return relayLimitsFor(r.url) }
+func (r *matchmakerRelay) RequestSize(b Bundle) int {
+	return requestSize("mev_sendBundle", buildStrategyPayload(r.cfg.Params, r.url, b.Hexes, b.Block))
+}
+
+func (r *matchmakerRelay) Simulate(ctx context.Context, b Bundle) (string, error) {
+	raw, ok, err := simulateMevBundle(ctx, r.cfg.Params, r.url, r.cfg.headers(r.url), r.cfg.AuthPrv, b.Hexes, b.Block)
+	if !ok {
+		return "", ErrRelayUnsupported
+	}
+	return raw, err
+}
+
+func (r *matchmakerRelay) Send(ctx context.Context, b Bundle) (string, error) {
+	return sendMevBundle(ctx, r.cfg.Params, r.url, r.cfg.headers(r.url), r.cfg.AuthPrv, b.Hexes, b.Block)
+}
+
+func (r *matchmakerRelay) Status(context.Context, string, *big.Int) (string, error) {
+	return "", ErrRelayUnsupported
+}
+
+func (r *matchmakerRelay) Cancel(context.Context, string) error { return ErrRelayUnsupported }
+
+// ---- MEV-Share (share:, mev_sendBundle v0.1; see mevshare.go) ----
+
+type shareRelay struct {
+	endpoint string
+	cfg      RelayConfig
+}
+
+func newShareRelay(u string, cfg RelayConfig) (Relay, error) {
+	return &shareRelay{endpoint: u, cfg: cfg}, nil
+}
+
+func (r *shareRelay) URL() string         { return "share:" + r.endpoint }
+func (r *shareRelay) Kind() string        { return "mev-share" }
+func (r *shareRelay) Limits() RelayLimits { return relayLimitsFor(r.endpoint) }
+func (r *shareRelay) RequestSize(b Bundle) int {
+	return requestSize("mev_sendBundle", buildSharePayload(r.cfg.Params, b.Hexes, b.Block))
+}
+
+func (r *shareRelay) Simulate(ctx context.Context, b Bundle) (string, error) {
+	maybeLogBundleOnce(b.Hexes, b.Block)
+	raw, ok, err := simulateShareBundle(ctx, r.cfg.Params, r.endpoint, r.cfg.headers(r.URL()), r.cfg.AuthPrv, b.Hexes, b.Block)
+	if !ok && err == nil {
+		return "", ErrRelayUnsupported
+	}
+	return raw, err
+}
+
+func (r *shareRelay) Send(ctx context.Context, b Bundle) (string, error) {
+	return sendShareBundle(ctx, r.cfg.Params, r.endpoint, r.cfg.headers(r.URL()), r.cfg.AuthPrv, b.Hexes, b.Block)
+}
+
+func (r *shareRelay) Status(context.Context, string, *big.Int) (string, error) {
+	return "", ErrRelayUnsupported
+}
+
+func (r *shareRelay) Cancel(context.Context, string) error { return ErrRelayUnsupported }
+
+// ---- bloXroute Cloud API (blxr_submit_bundle / blxr_simulate_bundle) ----
+
+type bloxrouteRelay struct {
+	url string
+	cfg RelayConfig
+}
+
+func newBloxrouteRelay(u string, cfg RelayConfig) (Relay, error) {
+	return &bloxrouteRelay{url: u, cfg: cfg}, nil
+}
+
+func (r *bloxrouteRelay) URL() string         { return r.url }
+func (r *bloxrouteRelay) Kind() string        { return "bloxroute" }
+func (r *bloxrouteRelay) Limits() RelayLimits { return relayLimitsFor(r.url) }
+func (r *bloxrouteRelay) RequestSize(b Bundle) int {
+	body, _ := json.Marshal(blxrSubmitRequest(b))
+	return len(body)
+}
+
+// blxrTxs are the raw txs without "0x", as the Cloud API takes them.
+func blxrTxs(hexes []string) []string {
+	out := make([]string, 0, len(hexes))
+	for _, h := range hexes {
+		out = append(out, strings.TrimPrefix(h, "0x"))
+	}
+	return out
+}
+
+func blxrSubmitRequest(b Bundle) map[string]any {
+	return map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "blxr_submit_bundle",
+		"params": map[string]any{
+			"transaction":  blxrTxs(b.Hexes),
+			"block_number": "0x" + b.Block.Text(16),
+		},
+	}
+}
+
+func (r *bloxrouteRelay) Send(ctx context.Context, b Bundle) (string, error) {
+	body, _ := json.Marshal(blxrSubmitRequest(b))
+	return postRelayJSON(ctx, r.url, r.cfg.headers(r.url), r.cfg.AuthPrv, body)
+}
+
+func (r *bloxrouteRelay) Simulate(ctx context.Context, b Bundle) (string, error) {
+	maybeLogBundleOnce(b.Hexes, b.Block)
+	body, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      "1",
+		"method":  "blxr_simulate_bundle",
+		"params": BlxrSimulateBundleParams{
+			Transaction:       blxrTxs(b.Hexes),
+			BlockNumber:       "0x" + b.Block.Text(16),
+			BlockchainNetwork: "Mainnet",
+		},
+	})
+	req, _ := http.NewRequestWithContext(ctx, "POST", r.url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range r.cfg.headers(r.url) {
+		req.Header.Set(k, v)
+	}
+	// No X-Flashbots-Signature for BLXR; only Authorization is required.
+	resp, err := rpcHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return string(raw), fmt.Errorf("http %d", resp.StatusCode)
+	}
+	var parsed BlxrSimulateBundleResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return string(raw), err
+	}
+	if parsed.Error != nil {
+		// Plans without simulation (non-Ultra/Enterprise) answer e.g. "simulation not
+		// supported on matchmaker": unsupported, so the caller can still send.
+		if strings.Contains(strings.ToLower(parsed.Error.Message), "not supported") {
+			return string(raw), ErrRelayUnsupported
+		}
+		return string(raw), errors.New(parsed.Error.Message)
+	}
+	if parsed.Result == nil {
+		return string(raw), ErrRelayUnsupported
+	}
+	for _, res := range parsed.Result.Results {
+		if res.Error != "" {
+			return string(raw), errors.New(res.Error)
+		}
+		if res.Revert != "" {
+			return string(raw), errors.New(res.Revert)
+		}
+	}
+	return string(raw), nil
+}
+
+func (r *bloxrouteRelay) Status(context.Context, string, *big.Int) (string, error) {
+	return "", ErrRelayUnsupported
+}
+
+func (r *bloxrouteRelay) Cancel(context.Context, string) error { return ErrRelayUnsupported }
@@ -0,0 +1,86 @@
+package bundlecore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RelayLimits is what a relay accepts in one eth_sendBundle / mev_sendBundle request.
+// Zero fields are not checked.
+type RelayLimits struct {
+	MaxBytes int    // JSON-RPC request body
+	MaxTxs   int    // transactions in the bundle
+	MaxGas   uint64 // sum of the transactions' gas limits
+}
+
+// defaultRelayLimits applies to relays not listed in knownRelayLimits: no builder takes a
+// bundle above the block gas limit (30M is the lowest mainnet has had since London).
+var defaultRelayLimits = RelayLimits{MaxBytes: 1 << 20, MaxTxs: 100, MaxGas: 30_000_000}
+
+// knownRelayLimits are conservative per-relay limits (the relays' published caps, rounded
+// down), matched by a substring of the URL. A relay that answers an oversized bundle does so with an opaque error, and the attempt
+// (one block) is lost; checking here skips it instead.
+var knownRelayLimits = []struct {
+	host   string
+	limits RelayLimits
+}{
+	{"flashbots.net", RelayLimits{MaxBytes: 1 << 20, MaxTxs: 100, MaxGas: 30_000_000}},
+	{"beaverbuild.org", RelayLimits{MaxBytes: 1 << 20, MaxTxs: 100, MaxGas: 30_000_000}},
+	{"titanbuilder.xyz", RelayLimits{MaxBytes: 1 << 20, MaxTxs: 100, MaxGas: 30_000_000}},
+	{"blxrbdn.com", RelayLimits{MaxBytes: 512 << 10, MaxTxs: 50, MaxGas: 30_000_000}},
+	{"bloxroute", RelayLimits{MaxBytes: 512 << 10, MaxTxs: 50, MaxGas: 30_000_000}},
+}
+
+// relayLimitsFor returns the limits of relay URL u.
+func relayLimitsFor(u string) RelayLimits {
+	low := strings.ToLower(u)
+	for _, k := range knownRelayLimits {
+		if strings.Contains(low, k.host) {
+			return k.limits
+		}
+	}
+	return defaultRelayLimits
+}
+
+// check returns why the bundle does not fit l ("" when it does).
+func (l RelayLimits) check(size, txs int, gas uint64) string {
+	var over []string
+	if l.MaxBytes > 0 && size > l.MaxBytes {
+		over = append(over, fmt.Sprintf("%d bytes > %d", size, l.MaxBytes))
+	}
+	if l.MaxTxs > 0 && txs > l.MaxTxs {
+		over = append(over, fmt.Sprintf("%d txs > %d", txs, l.MaxTxs))
+	}
+	if l.MaxGas > 0 && gas > l.MaxGas {
+		over = append(over, fmt.Sprintf("gas %d > %d", gas, l.MaxGas))
+	}
+	return strings.Join(over, ", ")
+}
+
+// requestSize is the size of the JSON-RPC request carrying payload.
+func requestSize(method string, payload map[string]any) int {
+	b, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": []any{payload}})
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// fitRelays drops the relays whose limits the bundle exceeds, logging each one with the
+// reason. The remaining relays are returned; empty means nobody would take it.
+func fitRelays(p *Params, relays []Relay, b Bundle) []Relay {
+	var gas uint64
+	for _, tx := range b.Txs {
+		gas += tx.Gas()
+	}
+	ok := relays[:0:0]
+	for _, r := range relays {
+		if why := r.Limits().check(r.RequestSize(b), len(b.Txs), gas); why != "" {
+			p.logf("[limits %s] skip: bundle over the relay's limits (%s)", r.URL(), why)
+			continue
+		}
+		ok = append(ok, r)
+	}
+	return ok
+}
@@ -0,0 +1,309 @@
+package bundlecore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Selector config (RESTRICTION_SELECTORS, a JSON file): blacklist getters beyond the built-in
+// list, usually found by DiscoverBlacklistGetters (`bundlecli discover-selectors`) and checked
+// by CheckRestrictions next to isBlacklisted(address) & co.
+//
+//	{"blacklist": [{"selector": "0x1a2b3c4d", "token": "0x…", "source": "discover-selectors", "found": "…"}]}
+//
+// An entry without "token" applies to every token.
+
+// DefaultSelectorFile is the selector config name (inside the profile directory with --profile).
+const DefaultSelectorFile = "restriction_selectors.json"
+
+// ExtraSelector is one getter f(address) returns (bool) that is true for a blacklisted address.
+type ExtraSelector struct {
+	Selector string `json:"selector"`
+	Token    string `json:"token,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Found    string `json:"found,omitempty"`
+}
+
+// SelectorConfig is the selector config file.
+type SelectorConfig struct {
+	Blacklist []ExtraSelector `json:"blacklist"`
+}
+
+var (
+	extraSelMu     sync.RWMutex
+	extraBlacklist []ExtraSelector
+)
+
+// LoadSelectorConfig reads path; a missing file is an empty config.
+func LoadSelectorConfig(path string) (SelectorConfig, error) {
+	var c SelectorConfig
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("selector config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("selector config %s: %w", path, err)
+	}
+	for _, e := range c.Blacklist {
+		if len(common.FromHex(e.Selector)) != 4 {
+			return c, fmt.Errorf("selector config %s: %q is not a 4-byte selector", path, e.Selector)
+		}
+		if e.Token != "" && !common.IsHexAddress(e.Token) {
+			return c, fmt.Errorf("selector config %s: token %q is not an address", path, e.Token)
+		}
+	}
+	return c, nil
+}
+
+// Save writes c to path (entries sorted by token, then selector).
+func (c SelectorConfig) Save(path string) error {
+	sort.Slice(c.Blacklist, func(i, j int) bool {
+		a, b := c.Blacklist[i], c.Blacklist[j]
+		if !strings.EqualFold(a.Token, b.Token) {
+			return strings.ToLower(a.Token) < strings.ToLower(b.Token)
+		}
+		return strings.ToLower(a.Selector) < strings.ToLower(b.Selector)
+	})
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Add records a getter of token unless the config already has it; false when it was known.
+func (c *SelectorConfig) Add(token common.Address, selector [4]byte, source string) bool {
+	s := hexutil.Encode(selector[:])
+	for _, e := range c.Blacklist {
+		if strings.EqualFold(e.Selector, s) && (e.Token == "" || common.HexToAddress(e.Token) == token) {
+			return false
+		}
+	}
+	c.Blacklist = append(c.Blacklist, ExtraSelector{Selector: s, Token: token.Hex(), Source: source, Found: time.Now().UTC().Format(time.RFC3339)})
+	return true
+}
+
+// SetSelectorConfig makes CheckRestrictions use c's extra getters.
+func SetSelectorConfig(c SelectorConfig) {
+	extraSelMu.Lock()
+	defer extraSelMu.Unlock()
+	extraBlacklist = append([]ExtraSelector(nil), c.Blacklist...)
+}
+
+// SelectorFile is RESTRICTION_SELECTORS, else def.
+func SelectorFile(def string) string {
+	if v := strings.TrimSpace(os.Getenv("RESTRICTION_SELECTORS")); v != "" {
+		return v
+	}
+	return def
+}
+
+// LoadSelectorEnv loads SelectorFile(def) for CheckRestrictions (a missing file is fine).
+// Call once at startup.
+func LoadSelectorEnv(def string) error {
+	path := SelectorFile(def)
+	if path == "" {
+		return nil
+	}
+	c, err := LoadSelectorConfig(path)
+	if err != nil {
+		return fmt.Errorf("RESTRICTION_SELECTORS: %w", err)
+	}
+	SetSelectorConfig(c)
+	return nil
+}
+
+// extraBlacklistSelectors are the configured getters that apply to token.
+func extraBlacklistSelectors(token common.Address) [][]byte {
+	extraSelMu.RLock()
+	defer extraSelMu.RUnlock()
+	var out [][]byte
+	for _, e := range extraBlacklist {
+		if e.Token == "" || common.HexToAddress(e.Token) == token {
+			out = append(out, common.FromHex(e.Selector))
+		}
+	}
+	return out
+}
+
+// Discovery. The dispatcher of a Solidity/Vyper contract compares the call's selector with
+// every public function's: DUP1 PUSH4 <selector> EQ PUSH2 <dest> JUMPI. Each such selector
+// is called as f(address) for the known-blacklisted addresses and for fresh random ones; a
+// getter answers one ABI bool word, false for every random address and true for at least
+// one blacklisted address. Selectors of the built-in lists and of standard ERC-20 views are
+// skipped (balanceOf of a 1-wei holder would look the same).
+
+// DiscoveredGetter is a blacklist getter candidate that passed the probes.
+type DiscoveredGetter struct {
+	Selector [4]byte
+	Hits     []common.Address // blacklisted addresses it reports true for
+}
+
+// DiscoveryReport is what DiscoverBlacklistGetters looked at.
+type DiscoveryReport struct {
+	CodeFrom  common.Address // contract whose code was scanned (the proxy's implementation)
+	Functions int            // dispatcher selectors found in the code
+	Probed    int            // selectors probed (not built-in / standard)
+	Getters   []DiscoveredGetter
+}
+
+// randomProbeAddrs is how many fresh addresses must read false.
+const randomProbeAddrs = 2
+
+// knownNonBlacklistSelectors are views f(address) that must not be taken for a blacklist getter.
+var knownNonBlacklistSelectors = []string{
+	"balanceOf(address)", "nonces(address)", "isWhitelisted(address)", "whitelisted(address)",
+	"isExcludedFromFee(address)", "isExcludedFromFees(address)", "_isExcludedFromFee(address)",
+	"isExcludedFromReward(address)", "isExcludedFromMaxTx(address)", "isOwner(address)",
+	"isMinter(address)", "isPauser(address)", "isAdmin(address)", "automatedMarketMakerPairs(address)",
+}
+
+// DiscoverBlacklistGetters scans token's code (its EIP-1967 / EIP-1167 implementation for a
+// proxy) for public functions and probes them against blacklisted. The calls go to token
+// itself, so proxies answer with their own storage.
+func DiscoverBlacklistGetters(ctx context.Context, ec *ethclient.Client, token common.Address, blacklisted []common.Address) (DiscoveryReport, error) {
+	var rep DiscoveryReport
+	if len(blacklisted) == 0 {
+		return rep, errors.New("no known-blacklisted addresses to probe with")
+	}
+	code, from, err := implementationCode(ctx, ec, token)
+	if err != nil {
+		return rep, err
+	}
+	rep.CodeFrom = from
+	if len(code) == 0 {
+		return rep, fmt.Errorf("%s has no code", token.Hex())
+	}
+
+	skip := map[[4]byte]bool{}
+	for _, list := range [][]string{blacklistAddrViewSigsStr, knownNonBlacklistSelectors} {
+		for _, s := range list {
+			skip[[4]byte(sel(s))] = true
+		}
+	}
+	for _, e := range extraBlacklistSelectors(token) {
+		skip[[4]byte(e)] = true
+	}
+
+	random := make([]common.Address, randomProbeAddrs)
+	for i := range random {
+		if _, err := rand.Read(random[i][:]); err != nil {
+			return rep, err
+		}
+	}
+	selectors := dispatcherSelectors(code)
+	rep.Functions = len(selectors)
+	for _, s := range selectors {
+		if skip[s] {
+			continue
+		}
+		rep.Probed++
+		if g, ok := probeBlacklistGetter(ctx, ec, token, s, blacklisted, random); ok {
+			rep.Getters = append(rep.Getters, g)
+		}
+	}
+	return rep, nil
+}
+
+// probeBlacklistGetter calls s(addr) for the random then the blacklisted addresses.
+func probeBlacklistGetter(ctx context.Context, ec *ethclient.Client, token common.Address, s [4]byte, blacklisted, random []common.Address) (DiscoveredGetter, bool) {
+	g := DiscoveredGetter{Selector: s}
+	ask := func(a common.Address) (v bool, ok bool) {
+		data := append(append([]byte{}, s[:]...), common.LeftPadBytes(a.Bytes(), 32)...)
+		res, err := callWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: data})
+		if err != nil || len(res) != 32 || !isBoolWord(res) {
+			return false, false
+		}
+		return res[31] == 1, true
+	}
+	for _, a := range random {
+		if v, ok := ask(a); !ok || v {
+			return g, false
+		}
+	}
+	for _, a := range blacklisted {
+		if v, ok := ask(a); !ok {
+			return g, false
+		} else if v {
+			g.Hits = append(g.Hits, a)
+		}
+	}
+	return g, len(g.Hits) > 0
+}
+
+// isBoolWord reports whether w is an ABI-encoded bool (31 zero bytes, then 0 or 1).
+func isBoolWord(w []byte) bool {
+	for _, b := range w[:31] {
+		if b != 0 {
+			return false
+		}
+	}
+	return w[31] <= 1
+}
+
+// dispatcherSelectors lists the selectors compared by the dispatcher (PUSH4 x EQ), in code
+// order (via-IR dispatchers put a DUP2 in between). The scan walks opcodes, so PUSH data is
+// never read as an instruction.
+func dispatcherSelectors(code []byte) [][4]byte {
+	var out [][4]byte
+	seen := map[[4]byte]bool{}
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		if op < 0x60 || op > 0x7f { // not PUSH1..PUSH32
+			continue
+		}
+		n := int(op-0x60) + 1
+		if op == 0x63 && pc+5 < len(code) && (code[pc+5] == 0x14 || pc+6 < len(code) && code[pc+5] == 0x81 && code[pc+6] == 0x14) { // PUSH4 x [DUP2] EQ
+			var s [4]byte
+			copy(s[:], code[pc+1:pc+5])
+			if !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+		pc += n
+	}
+	return out
+}
+
+// implementationCode returns the code that runs for token: an EIP-1167 target or EIP-1967
+// implementation when token is a proxy, else token's own.
+func implementationCode(ctx context.Context, ec *ethclient.Client, token common.Address) ([]byte, common.Address, error) {
+	code, err := ec.CodeAt(ctx, token, nil)
+	if err != nil {
+		return nil, token, err
+	}
+	impl := minimalProxyTarget(code)
+	if impl == (common.Address{}) {
+		impl, _ = ProxyImplementation(ctx, ec, token)
+	}
+	if impl == (common.Address{}) {
+		return code, token, nil
+	}
+	implCode, err := ec.CodeAt(ctx, impl, nil)
+	if err != nil {
+		return nil, impl, err
+	}
+	return implCode, impl, nil
+}
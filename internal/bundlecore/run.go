@@ -2,10 +2,11 @@ package bundlecore
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,47 +17,65 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	gethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/lmittmann/flashbots"
-	w3 "github.com/lmittmann/w3"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/relayseen"
+	"github.com/ligun0805/bundle-rescue/internal/rpcdial"
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
 )
 
+// DefaultCompeteMaxMul caps the compounded CompeteBumpPct tip multiplier when
+// Params.CompeteMaxMul is not set.
+const DefaultCompeteMaxMul = 4.0
+
 // Run builds bundle (optional bribe + prefund + cancel + transfer) and races relays for inclusion.
 func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
+	res, err := run(ctx, ec, p)
+	DefaultMetrics.runDone(res, err)
+	return res, err
+}
+
+func run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 	if p.AmountWei == nil || p.AmountWei.Sign() <= 0 {
 		return Result{}, errors.New("AmountWei must be > 0")
 	}
 	if p.ChainID == nil {
 		chainID, err := ec.ChainID(ctx)
 		if err != nil {
-			return Result{}, err
+			return Result{}, fmt.Errorf("chain id: %w", err)
 		}
 		p.ChainID = chainID
 	}
 
 	safePrv, err := hexToECDSAPriv(p.SafePKHex)
 	if err != nil {
-		return Result{}, err
+		return Result{}, fmt.Errorf("SAFE key: %w", err)
 	}
 	fromPrv, err := hexToECDSAPriv(p.FromPKHex)
 	if err != nil {
-		return Result{}, err
+		return Result{}, fmt.Errorf("FROM key: %w", err)
 	}
 	authPrv, err := hexToECDSAPriv(p.AuthPrivHex)
 	if err != nil {
-		return Result{}, err
+		return Result{}, fmt.Errorf("auth key: %w", err)
 	}
 	safeAddr := gethcrypto.PubkeyToAddress(safePrv.PublicKey)
 
 	if p.SkipIfPaused {
 		if known, paused, _ := CheckPaused(ctx, ec, p.Token); known && paused {
 			p.logf("[pre-check] token is paused => skip")
-			return Result{Included: false, Reason: "token paused"}, nil
+			res := failed(ReasonTokenPaused, "token paused")
+			res.Restriction = reasons.Paused
+			return res, nil
 		}
 	}
 
-	classic, matchmakers := classifyRelays(p.Relays, func(u string) *w3.Client { return flashbots.MustDial(u, authPrv) })
-	if len(classic) == 0 && len(matchmakers) == 0 {
-		return Result{}, errors.New("no relays or matchmakers configured")
+	relays, err := NewRelays(p.Relays, RelayConfig{Params: &p, AuthPrv: authPrv})
+	if err != nil {
+		return Result{}, err
+	}
+	if len(relays) == 0 && !p.LocalFork {
+		return Result{}, ErrNoRelays
 	}
 	if p.Blocks <= 0 {
 		p.Blocks = 6
@@ -73,17 +92,39 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 	if p.BufferPct < 0 {
 		p.BufferPct = 0
 	}
-	if restr, err := CheckRestrictions(ctx, ec, p.Token, p.From, p.To); err == nil && restr.Blocked() {
+	restrStart := time.Now()
+	restr, err := CachedCheckRestrictions(ctx, p.Cache, p.CacheTTL, ec, p.Token, p.From, p.To)
+	stagetime.Since(stagetime.Restrictions, restrStart)
+	if err == nil && restr.Blocked() {
 		p.logf("[pre-check] token restricted => %s", restr.Summary())
-		return Result{Included: false, Reason: "token restricted: " + restr.Summary()}, nil
+		res := failed(ReasonTokenRestricted, "token restricted: "+restr.Summary())
+		res.Restriction = restr.ReasonCode()
+		return res, nil
 	}
 
+	routeStart := time.Now()
+	sellRouter, err := pickSellRouter(ctx, ec, &p)
+	stagetime.Since(stagetime.Preflight, routeStart)
+	if err != nil {
+		p.logf("[route] %v", err)
+		return failed(ReasonNoRoute, err.Error()), nil
+	}
+	sellChain, _ := ChainByID(p.ChainID) // WETH at the tail of the sell path
+
 	startFromNonce, err := ec.PendingNonceAt(ctx, p.From)
 	if err != nil {
-		return Result{}, err
+		return Result{}, fmt.Errorf("FROM nonce: %w", err)
 	}
 
+	competeMul := 1.0       // compounded tip multiplier while competing (CompeteBumpPct)
+	competitorSeen := false // set when the previous attempt lost the nonce race
+	competeNonce := uint64(0) // FROM nonce of the last competitor bumped for (0 = none yet)
+	maxCompeteMul := p.CompeteMaxMul
+	if maxCompeteMul < 1 {
+		maxCompeteMul = DefaultCompeteMaxMul
+	}
 	for attempt := 0; attempt < p.Blocks; attempt++ {
+		buildStart := time.Now()
 		var baseFee *big.Int
 		var headNum *big.Int
 		if bf, err := nextBaseFeeViaFeeHistory(ctx, p.RPC); err == nil {
@@ -97,10 +138,14 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 			var err2 error
 			baseFee, headNum, err2 = latestBaseFee(ctx, ec)
 			if err2 != nil {
-				return Result{}, err2
+				return Result{}, fmt.Errorf("base fee: %w", err2)
 			}
 		}
+		headNum = new(big.Int).SetUint64(p.freshHead(ctx, headNum.Uint64()))
 		targetBlock := new(big.Int).Add(headNum, big.NewInt(1+int64(attempt)))
+		if p.LocalFork {
+			targetBlock = new(big.Int).Add(headNum, big.NewInt(1)) // mined on demand, never ahead
+		}
 
 		latestNonce, _ := ec.NonceAt(ctx, p.From, nil)
 		pendingNonce, _ := ec.PendingNonceAt(ctx, p.From)
@@ -111,50 +156,22 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 		}
 		if pendingNonce > fromNonce && !replaceMode {
 			p.logf("[abort] competing nonce detected (start=%d now=%d)", fromNonce, pendingNonce)
-			return Result{Included: false, Reason: "competing nonce"}, nil
+			return failed(ReasonCompetingNonce, "competing nonce"), nil
+		}
+		// bump once per competitor: a pending one stays visible every block until it is
+		// mined or replaced, so only a FROM nonce not bumped for yet counts
+		if seen := max(latestNonce, pendingNonce); p.CompeteBumpPct > 0 && (replaceMode || competitorSeen) && seen > competeNonce {
+			competeNonce = seen
+			competeMul = math.Min(competeMul*(1+float64(p.CompeteBumpPct)/100), maxCompeteMul)
+			p.logf("[compete] competitor on from nonce (latest=%d pending=%d) — replace=%v, tip x%.2f (cap x%.2f)",
+				latestNonce, pendingNonce, replaceMode, competeMul, maxCompeteMul)
 		}
+		competitorSeen = false
 
-		var tip *big.Int
-		if strings.ToLower(p.TipMode) == "feehist" {
-			t, err := TipFromFeeHistory(ctx, p.RPC, p.TipWindow, p.TipPercentile)
-			if err == nil && t != nil && t.Sign() > 0 {
-				if p.TipMul > 0 && p.TipMul != 1 {
-					mult := math.Pow(p.TipMul, float64(attempt))
-					fv := new(big.Float).Mul(new(big.Float).SetInt(t), big.NewFloat(mult))
-					fv.Int(t)
-				}
-				tip = t
-			} else {
-				// fallback — old fixed logic with escalation
-				suggest := suggestPriorityViaRPC(ctx, p.RPC)
-				baseTipGwei := float64(p.TipGweiBase)
-				if suggest != nil {
-					g := new(big.Int).Div(suggest, big.NewInt(1_000_000_000)).Int64()
-					if float64(g) > baseTipGwei {
-						baseTipGwei = float64(g)
-					}
-				}
-				tipGweiScaled := int64(math.Round(baseTipGwei * math.Pow(p.TipMul, float64(attempt))))
-				if tipGweiScaled < 1 {
-					tipGweiScaled = p.TipGweiBase
-				}
-				tip = gweiToWei(tipGweiScaled)
-			}
-		} else {
-			// old fixed logic with escalation
-			suggest := suggestPriorityViaRPC(ctx, p.RPC)
-			baseTipGwei := float64(p.TipGweiBase)
-			if suggest != nil {
-				g := new(big.Int).Div(suggest, big.NewInt(1_000_000_000)).Int64()
-				if float64(g) > baseTipGwei {
-					baseTipGwei = float64(g)
-				}
-			}
-			tipGweiScaled := int64(math.Round(baseTipGwei * math.Pow(p.TipMul, float64(attempt))))
-			if tipGweiScaled < 1 {
-				tipGweiScaled = p.TipGweiBase
-			}
-			tip = gweiToWei(tipGweiScaled)
+		tip := attemptTip(ctx, &p, attempt)
+		if competeMul > 1 {
+			fv := new(big.Float).Mul(new(big.Float).SetInt(tip), big.NewFloat(competeMul))
+			tip, _ = fv.Int(nil)
 		}
 		maxFee := addBig(mulBig(baseFee, p.BaseMul), tip)
 
@@ -175,11 +192,29 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 		}
 
 		calldata := EncodeERC20Transfer(p.To, new(big.Int).Set(p.AmountWei))
+		to2 := p.Token
 		gasTransfer := uint64(90_000)
-		if est, err := ec.EstimateGas(ctx, ethereum.CallMsg{From: p.From, To: &p.Token, Data: calldata}); err == nil && est > 0 {
+		if sellRouter != nil {
+			minOut, err := sellMinOut(ctx, ec, &p, *sellRouter)
+			if err != nil {
+				p.logf("[route] %v", err)
+				return failed(ReasonNoRoute, err.Error()), nil
+			}
+			p.logf("[route] amountOutMin=%s wei", minOut)
+			deadline := time.Now().Add(time.Duration(p.Blocks+2) * 12 * time.Second).Unix()
+			calldata = EncodeSwapExactTokensForETHOn(sellChain, p.Token, new(big.Int).Set(p.AmountWei), minOut, p.To, deadline)
+			to2 = *sellRouter
+			gasTransfer = 250_000
+		}
+		if est, err := ec.EstimateGas(ctx, ethereum.CallMsg{From: p.From, To: &to2, Data: calldata}); err == nil && est > 0 {
 			gasTransfer = est
 		} else {
 			p.logf("[warn] estimateGas for transfer failed (%v) — fallback gas=%d", err, gasTransfer)
+			DefaultMetrics.rpcError(err)
+		}
+		var accessList types.AccessList
+		if p.AccessList {
+			accessList, gasTransfer = transferAccessList(ctx, ec, &p, to2, calldata, gasTransfer, attempt > 0)
 		}
 		cancelGas := uint64(0)
 		if replaceMode {
@@ -212,7 +247,7 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 		if safeBal.Cmp(needTotal) < 0 {
 			p.logf("[abort] SAFE balance insufficient for fee+prefund at attempt %d/%d: need >= %s ETH, have %s ETH",
 				attempt+1, p.Blocks, fmtETH(needTotal), fmtETH(safeBal))
-			return Result{Included: false, Reason: "insufficient SAFE balance for fee+prefund"}, nil
+			return failed(ReasonInsufficientSafe, "insufficient SAFE balance for fee+prefund"), nil
 		}
 
 		// 0) optional bribe tx (contract creation with {0x41,0xff})
@@ -226,7 +261,7 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 			tx0 := buildDynamicTx(p.ChainID, safeNonce, nil, new(big.Int).Set(p.BribeWei), gasBribe, tip, maxFee, bribeInit)
 			sb, err := signTx(tx0, p.ChainID, safePrv)
 			if err != nil {
-				return Result{}, err
+				return Result{}, fmt.Errorf("sign bribe tx: %w", err)
 			}
 			signedBribe = sb
 			safeNonce++
@@ -237,19 +272,18 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 		tx1 := buildDynamicTx(p.ChainID, safeNonce, &to1, prefundWei, 21_000, tip, maxFee, nil)
 		signed1, err := signTx(tx1, p.ChainID, safePrv)
 		if err != nil {
-			return Result{}, err
+			return Result{}, fmt.Errorf("sign prefund tx: %w", err)
 		}
 
-		// 2) main transfer
-		to2 := p.Token
+		// 2) main transfer (or router sell)
 		nonce2 := fromNonce
 		if replaceMode {
 			nonce2 = fromNonce + 1
 		}
-		tx2 := buildDynamicTx(p.ChainID, nonce2, &to2, big.NewInt(0), gasTransfer, tip, maxFee, calldata)
+		tx2 := buildDynamicTxAL(p.ChainID, nonce2, &to2, big.NewInt(0), gasTransfer, tip, maxFee, calldata, accessList)
 		signed2, err := signTx(tx2, p.ChainID, fromPrv)
 		if err != nil {
-			return Result{}, err
+			return Result{}, fmt.Errorf("sign transfer tx: %w", err)
 		}
 
 		// optional cancel (nonce=fromNonce) if replace mode
@@ -259,7 +293,7 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 			cancelTx := buildDynamicTx(p.ChainID, fromNonce, &toSelf, big.NewInt(0), 21_000, tip, maxFee, nil)
 			sc, err := signTx(cancelTx, p.ChainID, fromPrv)
 			if err != nil {
-				return Result{}, err
+				return Result{}, fmt.Errorf("sign cancel tx: %w", err)
 			}
 			signedCancel = sc
 		}
@@ -308,225 +342,268 @@ func Run(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
 		}
 		
 		logBundleSummary(&p, signedList, targetBlock)
+		stagetime.Since(stagetime.BuildSign, buildStart)
 
-		// === PREFLIGHT SIMULATION (always log) ===
-		{
-			var simOK atomic.Bool
-			var wgSim sync.WaitGroup
-			// classic
-			for _, rc := range classic {
-				rc := rc
-				wgSim.Add(1)
-				go func() {
-					defer wgSim.Done()
-					var resp *flashbots.CallBundleResponse
-					err2 := rc.C.Call(
-						flashbots.CallBundle(&flashbots.CallBundleRequest{
-							Transactions: signedList,
-							BlockNumber:  new(big.Int).Set(targetBlock),
-						}).Returns(&resp),
-					)
-					ok := (err2 == nil)
-					raw := ""
-					errStr := ""
-					if resp != nil {
-						b, _ := json.Marshal(resp)
-						raw = string(b)
-						for _, r := range resp.Results {
-							if r.Error != nil || len(r.Revert) > 0 {
-								ok = false
-								if r.Error != nil {
-									errStr = r.Error.Error()
-								} else {
-									errStr = r.Revert
-								}
-								break
-							}
-						}
-					}
-					if !ok && err2 != nil {
-						errStr = err2.Error()
-					}
-					if p.OnSimResult != nil {
-						p.OnSimResult(rc.URL, raw, ok, errStr)
-					}
-					if ok {
-						simOK.Store(true)
-					}
-				}()
-			}
-			// matchmakers
-			for _, u := range matchmakers {
-				u := u
-				wgSim.Add(1)
-				go func() {
-					defer wgSim.Done()
-					raw, ok, err := simulateMevBundle(ctx, &p, u, p.headerFor(u), authPrv, txHexes, targetBlock)
-					if p.OnSimResult != nil {
-						if ok {
-							p.OnSimResult(u, raw, err == nil, "")
-						} else {
-							p.OnSimResult(u, "", false, "simulation not supported on matchmaker")
-						}
-					}
-					if ok && err == nil {
-						simOK.Store(true)
-					}
-				}()
-			}
-			wgSim.Wait()
+		// relays whose size/tx/gas limits the bundle exceeds would only burn the attempt
+		bundle := Bundle{Txs: signedList, Hexes: txHexes, Block: targetBlock}
+		relays := fitRelays(&p, relays, bundle)
+		if len(relays) == 0 && !p.LocalFork {
+			return failed(ReasonRelayLimits, "bundle exceeds the limits of every relay"), nil
 		}
 
-		if p.SimulateOnly {
-			var simOK atomic.Bool
-			var wgSim sync.WaitGroup
-			for _, rc := range classic {
-				rc := rc
-				wgSim.Add(1)
-				go func() {
-					defer wgSim.Done()
-					var resp *flashbots.CallBundleResponse
-					err2 := rc.C.Call(
-						flashbots.CallBundle(&flashbots.CallBundleRequest{
-							Transactions: signedList,
-							BlockNumber:  new(big.Int).Set(targetBlock),
-						}).Returns(&resp),
-					)
-					ok := (err2 == nil)
-					raw := ""
-					errStr := ""
-					if resp != nil {
-						b, _ := json.Marshal(resp)
-						raw = string(b)
-						for _, r := range resp.Results {
-							if r.Error != nil || len(r.Revert) > 0 {
-								ok = false
-								if r.Error != nil {
-									errStr = r.Error.Error()
-								} else {
-									errStr = r.Revert
-								}
-								break
-							}
-						}
-					}
-					if !ok && err2 != nil {
-						errStr = err2.Error()
-					}
-					if p.OnSimResult != nil {
-						p.OnSimResult(rc.URL, raw, ok, errStr)
-					}
-					if ok {
-						simOK.Store(true)
-					}
-				}()
-			}
-			for _, u := range matchmakers {
-				if p.OnSimResult != nil {
-					if raw, ok, err := simulateMevBundle(ctx, &p, u, p.headerFor(u), authPrv, txHexes, targetBlock); ok {
-						p.OnSimResult(u, raw, err == nil, "")
-					} else {
-						p.OnSimResult(u, "", false, "simulation not supported on matchmaker")
-					}
-				}
-			}
-			wgSim.Wait()
+		// === PREFLIGHT SIMULATION (always log) ===
+		simStart := time.Now()
+		simOK := simulateBundle(ctx, &p, relays, bundle)
+		stagetime.Since(stagetime.Simulate, simStart)
 
-			if !simOK.Load() {
+		if p.SimulateOnly {
+			DefaultMetrics.attempt(time.Since(buildStart))
+			if !simOK {
 				p.logf("[attempt %d/%d] block=%s gas=%d(+%d) tip=%s gwei (~%s ETH/gas) feeCap=%s gwei (~%s ETH/gas) prefund=%s ETH nonce(safe=%d, from=%d)%s",
 					attempt+1, p.Blocks, targetBlock.String(),
 					gasTransfer, cancelGas, fmtGwei(tip), fmtETH(tip), fmtGwei(maxFee), fmtETH(maxFee), fmtETH(prefundWei),
 					safeNonce, fromNonce, map[bool]string{true: " (+replace)", false: ""}[replaceMode])
 				curFromNonce2, _ := ec.NonceAt(ctx, p.From, nil)
 				if curFromNonce2 > startFromNonce {
-					return Result{Included: false, Reason: "competing nonce"}, nil
+					return failed(ReasonCompetingNonce, "competing nonce"), nil
 				}
 				continue
 			}
-			return Result{Included: false, Reason: "simulate only"}, nil
+			return failed(ReasonSimulateOnly, "simulate only"), nil
 		}
 
 		// === SEND TO RELAYS ===
-		var wgSend sync.WaitGroup
-		for _, rc := range classic {
-			rc := rc
-			wgSend.Add(1)
-			go func() {
-				defer wgSend.Done()
-				var bundleHash common.Hash
-				err3 := rc.C.Call(
-					flashbots.SendBundle(&flashbots.SendBundleRequest{
-						Transactions: signedList,
-						BlockNumber:  new(big.Int).Set(targetBlock),
-					}).Returns(&bundleHash),
-				)
-				if err3 != nil {
-					p.logf("[send %s] err: %v", rc.URL, err3)
-					return
-				}
-				p.logf("[send %s] bundle submitted: %s", rc.URL, bundleHash.Hex())
-			}()
-		}
-		for _, u := range matchmakers {
-			u := u
-			wgSend.Add(1)
-			go func() {
-				defer wgSend.Done()
-				res, err3 := sendMevBundle(ctx, &p, u, p.headerFor(u), authPrv, txHexes, targetBlock)
-				if err3 != nil {
-					p.logf("[mev_sendBundle %s] err: %v", u, err3)
-					return
-				}
-				p.logf("[mev_sendBundle %s] ok: %s", u, res)
-			}()
+		// "already known" is a soft success: the relay holds this bundle (a retried or shared submission).
+		sendStart := time.Now()
+		if p.LocalFork {
+			if blk, err := SubmitLocalBundle(ctx, p.RPC, signedList, transferTxHash); err != nil {
+				p.logf("[local] %v", err)
+			} else {
+				targetBlock, bundle.Block = blk, blk
+				p.logf("[local] bundle mined on dev node, transfer in block %s", targetBlock.String())
+			}
 		}
-		wgSend.Wait()
+		sendBundle(ctx, &p, relays, bundle)
+		stagetime.Since(stagetime.RelaySend, sendStart)
 
 		waitCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
 		defer cancel()
-		incl, reason, err := waitInclusionOrCompete(waitCtx, ec, p.From, startFromNonce, transferTxHash, targetBlock)
+		logTo := p.To
+		if sellRouter != nil {
+			// tokens land in the pool on a sell (ETH reaches To via the router): any recipient,
+			// but only logs of transferTxHash count
+			logTo = common.Address{}
+		}
+		waitStart := time.Now()
+		res, err := waitInclusionOrCompete(waitCtx, ec, p.Token, p.From, logTo, startFromNonce, transferTxHash, targetBlock)
+		stagetime.Since(stagetime.InclusionWait, waitStart)
+		DefaultMetrics.attempt(time.Since(buildStart))
 		if err != nil {
 			p.logf("[attempt %d/%d] wait err: %v", attempt+1, p.Blocks, err)
+			DefaultMetrics.rpcError(err)
 		}
-		if incl {
-			return Result{Included: true, Reason: reason}, nil
+		if res.Included {
+			if res.Moved != nil {
+				p.logf("[confirm] Transfer logs in block %s: %s wei moved %s -> %s", targetBlock.String(), res.Moved.String(), p.From.Hex(), logTo.Hex())
+			}
+			res.TxHash = transferTxHash
+			attributeWin(ctx, ec, &p, &res, targetBlock)
+			return res, nil
 		}
-		if reason == "competing nonce" {
-			return Result{Included: false, Reason: reason}, nil
+		if res.Code == ReasonCompetingNonce {
+			if p.CompeteBumpPct > 0 {
+				p.logf("[attempt %d/%d] competing nonce — rebuilding with +%d%% tip for next block", attempt+1, p.Blocks, p.CompeteBumpPct)
+				competitorSeen = true
+				// the competitor is mined: from here on only a newer FROM nonce is a new race
+				if n, err := ec.NonceAt(ctx, p.From, nil); err == nil && n > startFromNonce {
+					startFromNonce = n
+				}
+				continue
+			}
+			return res, nil
 		}
 	}
 
-	return Result{Included: false, Reason: "exhausted attempts"}, nil
+	if p.SimulateOnly {
+		return failed(ReasonSimulationFailed, "simulation failed"), nil
+	}
+	return failed(ReasonNotIncluded, "exhausted attempts"), nil
 }
 
-// waitInclusionOrCompete waits for target block and checks inclusion/nonce race.
-func waitInclusionOrCompete(ctx context.Context, ec *ethclient.Client, from common.Address, startNonce uint64, ourTx2 common.Hash, targetBlock *big.Int) (bool, string, error) {
-	for {
-		select {
-		case <-ctx.Done():
-			return false, "timeout waiting block", ctx.Err()
-		default:
-			h, err := ec.HeaderByNumber(ctx, nil)
-			if err == nil && h != nil && h.Number != nil && h.Number.Cmp(targetBlock) >= 0 {
-				goto CHECK
+// attemptTip picks the priority fee of an attempt: eth_feeHistory percentile (TipMode
+// "feehist") or TIP_GWEI / eth_maxPriorityFeePerGas, escalated by TipMul per attempt.
+func attemptTip(ctx context.Context, p *Params, attempt int) *big.Int {
+	var tip *big.Int
+	if strings.ToLower(p.TipMode) == "feehist" {
+		t, err := TipFromFeeHistory(ctx, p.RPC, p.TipWindow, p.TipPercentile)
+		if err == nil && t != nil && t.Sign() > 0 {
+			if p.TipMul > 0 && p.TipMul != 1 {
+				mult := math.Pow(p.TipMul, float64(attempt))
+				fv := new(big.Float).Mul(new(big.Float).SetInt(t), big.NewFloat(mult))
+				fv.Int(t)
 			}
-			time.Sleep(300 * time.Millisecond)
+			tip = t
+		} else {
+			// fallback — old fixed logic with escalation
+			suggest := suggestPriorityViaRPC(ctx, p.RPC)
+			baseTipGwei := float64(p.TipGweiBase)
+			if suggest != nil {
+				g := new(big.Int).Div(suggest, big.NewInt(1_000_000_000)).Int64()
+				if float64(g) > baseTipGwei {
+					baseTipGwei = float64(g)
+				}
+			}
+			tipGweiScaled := int64(math.Round(baseTipGwei * math.Pow(p.TipMul, float64(attempt))))
+			if tipGweiScaled < 1 {
+				tipGweiScaled = p.TipGweiBase
+			}
+			tip = gweiToWei(tipGweiScaled)
 		}
-	}
-CHECK:
-	latestNonce, err := ec.NonceAt(ctx, from, nil)
-	if err == nil && latestNonce > startNonce {
-		rcpt, err2 := ec.TransactionReceipt(ctx, ourTx2)
-		if err2 == nil && rcpt != nil && rcpt.BlockNumber != nil && rcpt.BlockNumber.Cmp(targetBlock) == 0 && rcpt.Status == types.ReceiptStatusSuccessful {
-			return true, "included", nil
+	} else {
+		// old fixed logic with escalation
+		suggest := suggestPriorityViaRPC(ctx, p.RPC)
+		baseTipGwei := float64(p.TipGweiBase)
+		if suggest != nil {
+			g := new(big.Int).Div(suggest, big.NewInt(1_000_000_000)).Int64()
+			if float64(g) > baseTipGwei {
+				baseTipGwei = float64(g)
+			}
+		}
+		tipGweiScaled := int64(math.Round(baseTipGwei * math.Pow(p.TipMul, float64(attempt))))
+		if tipGweiScaled < 1 {
+			tipGweiScaled = p.TipGweiBase
 		}
-		return false, "competing nonce", nil
+		tip = gweiToWei(tipGweiScaled)
+	}
+	return tip
+}
+
+// simUnsupported is the OnSimResult error of a relay that does not simulate.
+const simUnsupported = "simulation not supported by relay"
+
+// simulateBundle simulates b on every relay that offers simulation, reporting each answer
+// via OnSimResult; true when at least one passed.
+func simulateBundle(ctx context.Context, p *Params, relays []Relay, b Bundle) bool {
+	var simOK atomic.Bool
+	var wgSim sync.WaitGroup
+	for _, r := range relays {
+		r := r
+		wgSim.Add(1)
+		go func() {
+			defer wgSim.Done()
+			raw, err := r.Simulate(ctx, b)
+			switch {
+			case errors.Is(err, ErrRelayUnsupported):
+				DefaultMetrics.simResult(r.URL(), "unsupported")
+			case err != nil:
+				DefaultMetrics.simResult(r.URL(), "fail")
+			default:
+				DefaultMetrics.simResult(r.URL(), "ok")
+			}
+			if p.OnSimResult != nil {
+				if errors.Is(err, ErrRelayUnsupported) {
+					p.OnSimResult(r.URL(), "", false, simUnsupported)
+				} else {
+					p.OnSimResult(r.URL(), raw, err == nil, errText(err))
+				}
+			}
+			if err == nil {
+				simOK.Store(true)
+			}
+		}()
+	}
+	wgSim.Wait()
+	return simOK.Load()
+}
+
+// sendBundle submits b to every relay once. Every attempt targets its own block with a
+// rebuilt bundle, so there is nothing to deduplicate across attempts; an "already known"
+// answer still counts as held.
+func sendBundle(ctx context.Context, p *Params, relays []Relay, b Bundle) {
+	var (
+		wgSend sync.WaitGroup
+		mu     sync.Mutex
+		held   []string
+	)
+	for _, r := range relays {
+		r, u := r, r.URL()
+		wgSend.Add(1)
+		go func() {
+			defer wgSend.Done()
+			res, err := r.Send(ctx, b)
+			if err != nil && relayseen.IsAlreadyKnown(err.Error()) {
+				DefaultMetrics.bundleSent(u, "already_known")
+				p.logf("[send %s] already known (ok)", u)
+			} else if err != nil {
+				DefaultMetrics.bundleSent(u, "error")
+				p.logf("[send %s] err: %v", u, err)
+				return
+			} else {
+				DefaultMetrics.bundleSent(u, "ok")
+				p.logf("[send %s] bundle submitted: %s", u, res)
+			}
+			mu.Lock()
+			held = append(held, u)
+			mu.Unlock()
+		}()
+	}
+	wgSend.Wait()
+	if len(held) > 0 {
+		sort.Strings(held)
+		p.logf("[send] bundle held by %d relay(s): %s", len(held), strings.Join(held, ", "))
+	}
+}
+
+// waitInclusionOrCompete waits for target block and checks inclusion/nonce race.
+// Inclusion is confirmed by receipt OR by a Transfer(from->to) log of ourTx2 in the target block;
+// the moved amount comes from the logs (nil when no log was seen).
+func waitInclusionOrCompete(ctx context.Context, ec *ethclient.Client, token, from, to common.Address, startNonce uint64, ourTx2 common.Hash, targetBlock *big.Int) (Result, error) {
+	if err := waitHead(ctx, ec, targetBlock); err != nil {
+		return failed(ReasonNotIncluded, "timeout waiting block"), err
+	}
+	var moved *big.Int
+	// only our transfer counts: a Transfer out of FROM by anyone else (the attacker's sweep)
+	// in the same block is not our inclusion
+	conf, cerr := ConfirmTransferLogs(ctx, ec, token, from, to, ourTx2, targetBlock)
+	if cerr == nil && conf.Found() {
+		moved = conf.Amount
 	}
 	rcpt, err := ec.TransactionReceipt(ctx, ourTx2)
 	if err == nil && rcpt != nil && rcpt.BlockNumber != nil && rcpt.BlockNumber.Cmp(targetBlock) == 0 && rcpt.Status == types.ReceiptStatusSuccessful {
-		return true, "included", nil
+		return Result{Included: true, Code: ReasonIncluded, Reason: "included", Moved: moved}, nil
+	}
+	if moved != nil {
+		return Result{Included: true, Code: ReasonIncluded, Reason: "included (Transfer log)", Moved: moved}, nil
+	}
+	latestNonce, err := ec.NonceAt(ctx, from, nil)
+	if err == nil && latestNonce > startNonce {
+		return failed(ReasonCompetingNonce, "competing nonce"), nil
+	}
+	return failed(ReasonNotIncluded, "not included"), nil
+}
+
+// waitHead blocks until the chain head reaches target. Over WebSocket it waits on the
+// client's shared newHeads subscription (rpcdial.WatchHeads: one subscription for every
+// attempt and pair); over HTTP it polls.
+func waitHead(ctx context.Context, ec *ethclient.Client, target *big.Int) error {
+	if w, err := rpcdial.WatchHeads(ctx, ec); err == nil {
+		defer w.Release()
+		return w.WaitFor(ctx, target)
+	}
+	reached := func() bool {
+		h, err := ec.HeaderByNumber(ctx, nil)
+		return err == nil && h != nil && h.Number != nil && h.Number.Cmp(target) >= 0
+	}
+	tick := time.NewTicker(300 * time.Millisecond)
+	defer tick.Stop()
+	for !reached() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
 	}
-	return false, "not included", nil
+	return nil
 }
 
 // logBundleSummary prints a compact bundle description once per attempt.
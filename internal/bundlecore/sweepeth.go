@@ -0,0 +1,199 @@
+package bundlecore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/ligun0805/bundle-rescue/internal/stagetime"
+)
+
+// SweepETH drains the native ETH of p.From to p.To with a classic bundle (no 7702, no SAFE
+// prefund): FROM pays its own gas and sends balance − gas × maxFee. When a pending tx of
+// FROM holds the next nonce, a 0-value self-transfer at that nonce is bundled first to
+// cancel it (its gas is taken from the swept value too). Relays are raced block by block
+// like Run; the token fields, SafePKHex, route and bribe settings are ignored.
+// Result.Moved is the swept value of the included attempt.
+func SweepETH(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
+	res, err := sweepETH(ctx, ec, p)
+	DefaultMetrics.runDone(res, err)
+	return res, err
+}
+
+func sweepETH(ctx context.Context, ec *ethclient.Client, p Params) (Result, error) {
+	if p.ChainID == nil {
+		chainID, err := ec.ChainID(ctx)
+		if err != nil {
+			return Result{}, fmt.Errorf("chain id: %w", err)
+		}
+		p.ChainID = chainID
+	}
+	fromPrv, err := hexToECDSAPriv(p.FromPKHex)
+	if err != nil {
+		return Result{}, fmt.Errorf("FROM key: %w", err)
+	}
+	authPrv, err := hexToECDSAPriv(p.AuthPrivHex)
+	if err != nil {
+		return Result{}, fmt.Errorf("auth key: %w", err)
+	}
+	if gethcrypto.PubkeyToAddress(fromPrv.PublicKey) != p.From {
+		return Result{}, errors.New("FromPKHex does not match From")
+	}
+	relays, err := NewRelays(p.Relays, RelayConfig{Params: &p, AuthPrv: authPrv})
+	if err != nil {
+		return Result{}, err
+	}
+	if len(relays) == 0 && !p.LocalFork {
+		return Result{}, ErrNoRelays
+	}
+	if p.Blocks <= 0 {
+		p.Blocks = 6
+	}
+	if p.TipGweiBase <= 0 {
+		p.TipGweiBase = 3
+	}
+	if p.TipMul <= 0 {
+		p.TipMul = 1.2
+	}
+	if p.BaseMul <= 0 {
+		p.BaseMul = 2
+	}
+
+	startFromNonce, err := ec.NonceAt(ctx, p.From, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("FROM nonce: %w", err)
+	}
+	// Sweep gas: 21k to an EOA; a contract SAFE (multisig) may need more in its receive().
+	gasSweep := uint64(21_000)
+	if est, err := ec.EstimateGas(ctx, ethereum.CallMsg{From: p.From, To: &p.To, Value: big.NewInt(1)}); err == nil && est > gasSweep {
+		gasSweep = est + est/5
+		p.logf("[sweep] recipient is a contract: gas=%d (estimate %d + 20%%)", gasSweep, est)
+	}
+
+	for attempt := 0; attempt < p.Blocks; attempt++ {
+		buildStart := time.Now()
+		baseFee, headNum, err := latestBaseFee(ctx, ec)
+		if bf, ferr := nextBaseFeeViaFeeHistory(ctx, p.RPC); ferr == nil {
+			baseFee = bf
+		} else if err != nil {
+			return Result{}, fmt.Errorf("base fee: %w", err)
+		}
+		headNum = new(big.Int).SetUint64(p.freshHead(ctx, headNum.Uint64()))
+		targetBlock := new(big.Int).Add(headNum, big.NewInt(1+int64(attempt)))
+		if p.LocalFork {
+			targetBlock = new(big.Int).Add(headNum, big.NewInt(1))
+		}
+
+		latestNonce, _ := ec.NonceAt(ctx, p.From, nil)
+		pendingNonce, _ := ec.PendingNonceAt(ctx, p.From)
+		if latestNonce > startFromNonce {
+			p.logf("[abort] FROM nonce moved on chain (start=%d now=%d)", startFromNonce, latestNonce)
+			return failed(ReasonCompetingNonce, "competing nonce"), nil
+		}
+		replaceMode := pendingNonce > latestNonce
+
+		tip := attemptTip(ctx, &p, attempt)
+		maxFee := addBig(mulBig(baseFee, p.BaseMul), tip)
+		cancelGas := uint64(0)
+		if replaceMode {
+			cancelGas = 21_000
+		}
+		bal, err := ec.BalanceAt(ctx, p.From, nil)
+		if err != nil {
+			return Result{}, fmt.Errorf("FROM balance: %w", err)
+		}
+		gasCost := new(big.Int).Mul(new(big.Int).SetUint64(gasSweep+cancelGas), maxFee)
+		value := new(big.Int).Sub(bal, gasCost)
+		if value.Sign() <= 0 {
+			p.logf("[abort] FROM balance %s ETH does not cover gas %s ETH at attempt %d/%d", fmtETH(bal), fmtETH(gasCost), attempt+1, p.Blocks)
+			return failed(ReasonInsufficientFrom, "ETH balance below gas cost"), nil
+		}
+
+		signedList := make([]*types.Transaction, 0, 2)
+		nonce := latestNonce
+		if replaceMode {
+			self := p.From
+			sc, err := signTx(buildDynamicTx(p.ChainID, nonce, &self, big.NewInt(0), 21_000, tip, maxFee, nil), p.ChainID, fromPrv)
+			if err != nil {
+				return Result{}, fmt.Errorf("sign cancel tx: %w", err)
+			}
+			signedList = append(signedList, sc)
+			nonce++
+		}
+		to := p.To
+		sweep, err := signTx(buildDynamicTx(p.ChainID, nonce, &to, value, gasSweep, tip, maxFee, nil), p.ChainID, fromPrv)
+		if err != nil {
+			return Result{}, fmt.Errorf("sign sweep tx: %w", err)
+		}
+		signedList = append(signedList, sweep)
+		txHexes := make([]string, 0, len(signedList))
+		for _, t := range signedList {
+			txHexes = append(txHexes, txAsHex(t))
+		}
+
+		p.logf("[attempt %d/%d] block=%s sweep=%s ETH gas=%d(+%d) tip=%s gwei feeCap=%s gwei nonce=%d%s",
+			attempt+1, p.Blocks, targetBlock.String(), fmtETH(value), gasSweep, cancelGas, fmtGwei(tip), fmtGwei(maxFee),
+			nonce, map[bool]string{true: " (+cancel)", false: ""}[replaceMode])
+		logBundleSummary(&p, signedList, targetBlock)
+		stagetime.Since(stagetime.BuildSign, buildStart)
+
+		bundle := Bundle{Txs: signedList, Hexes: txHexes, Block: targetBlock}
+		relays := fitRelays(&p, relays, bundle)
+		if len(relays) == 0 && !p.LocalFork {
+			return failed(ReasonRelayLimits, "bundle exceeds the limits of every relay"), nil
+		}
+		simStart := time.Now()
+		simOK := simulateBundle(ctx, &p, relays, bundle)
+		stagetime.Since(stagetime.Simulate, simStart)
+		if p.SimulateOnly {
+			DefaultMetrics.attempt(time.Since(buildStart))
+			if !simOK {
+				continue
+			}
+			res := failed(ReasonSimulateOnly, "simulate only")
+			res.Moved = value
+			return res, nil
+		}
+
+		sendStart := time.Now()
+		if p.LocalFork {
+			if blk, err := SubmitLocalBundle(ctx, p.RPC, signedList, sweep.Hash()); err != nil {
+				p.logf("[local] %v", err)
+			} else {
+				targetBlock, bundle.Block = blk, blk
+			}
+		}
+		sendBundle(ctx, &p, relays, bundle)
+		stagetime.Since(stagetime.RelaySend, sendStart)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
+		waitStart := time.Now()
+		err = waitHead(waitCtx, ec, targetBlock)
+		stagetime.Since(stagetime.InclusionWait, waitStart)
+		cancel()
+		DefaultMetrics.attempt(time.Since(buildStart))
+		if err != nil {
+			p.logf("[attempt %d/%d] wait err: %v", attempt+1, p.Blocks, err)
+			DefaultMetrics.rpcError(err)
+			continue
+		}
+		rcpt, err := ec.TransactionReceipt(ctx, sweep.Hash())
+		if err == nil && rcpt != nil && rcpt.Status == types.ReceiptStatusSuccessful {
+			p.logf("[confirm] %s ETH swept %s -> %s in block %s", fmtETH(value), p.From.Hex(), p.To.Hex(), rcpt.BlockNumber.String())
+			res := Result{Included: true, Code: ReasonIncluded, Reason: "included", Moved: value, TxHash: sweep.Hash()}
+			attributeWin(ctx, ec, &p, &res, rcpt.BlockNumber)
+			return res, nil
+		}
+	}
+	if p.SimulateOnly {
+		return failed(ReasonSimulationFailed, "simulation failed"), nil
+	}
+	return failed(ReasonNotIncluded, "exhausted attempts"), nil
+}
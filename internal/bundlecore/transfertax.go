@@ -0,0 +1,146 @@
+package bundlecore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// taxProbeCode runs at FROM (stateOverride code, as in the 7702 preflight) and is called
+// with calldata token‖to‖amount‖watch (4 raw words):
+//
+//	before = token.balanceOf(watch); ok = token.transfer(to, amount); after = token.balanceOf(watch)
+//	return before, ok, transfer return word, transfer return size, after
+//
+// The token sees msg.sender == FROM, so the transfer is the one the bundle will make.
+var taxProbeCode = func() []byte {
+	var b []byte
+	push1 := func(v byte) { b = append(b, 0x60, v) }
+	push2 := func(v uint16) { b = append(b, 0x61, byte(v>>8), byte(v)) }
+	sel := func(s uint32) { // mem[0:4] = selector
+		b = append(b, 0x63, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+		push1(0xe0)
+		b = append(b, 0x1b) // SHL
+		push1(0)
+		b = append(b, 0x52) // MSTORE
+	}
+	word := func(arg, at byte) { // mem[at:at+32] = calldata word arg
+		push1(32 * arg)
+		b = append(b, 0x35) // CALLDATALOAD
+		push1(at)
+		b = append(b, 0x52)
+	}
+	balanceOf := func(ret uint16) {
+		sel(0x70a08231)
+		word(3, 0x04)
+		push1(0x20) // retSize
+		push2(ret)
+		push1(0x24) // argsSize
+		push1(0)    // argsOffset
+		push1(0)
+		b = append(b, 0x35, 0x5a, 0xfa, 0x50) // CALLDATALOAD(token) GAS STATICCALL POP
+	}
+	balanceOf(0x100)
+	sel(0xa9059cbb)
+	word(1, 0x04)
+	word(2, 0x24)
+	push1(0x20)
+	push2(0x140)
+	push1(0x44)
+	push1(0)
+	push1(0) // value
+	push1(0)
+	b = append(b, 0x35, 0x5a, 0xf1) // CALLDATALOAD(token) GAS CALL
+	push2(0x120)
+	b = append(b, 0x52, 0x3d) // MSTORE(ok) RETURNDATASIZE
+	push2(0x160)
+	b = append(b, 0x52)
+	balanceOf(0x180)
+	push1(0xa0)
+	push2(0x100)
+	b = append(b, 0xf3) // RETURN
+	return b
+}()
+
+// TransferTax is the simulated outcome of transfer(to, Sent) from FROM.
+type TransferTax struct {
+	Sent     *big.Int
+	Received *big.Int // balance delta of the recipient
+}
+
+// Bps is the share of Sent that did not arrive, in basis points (0 when nothing was lost).
+func (t TransferTax) Bps() int64 {
+	if t.Sent == nil || t.Sent.Sign() == 0 || t.Received == nil || t.Received.Cmp(t.Sent) >= 0 {
+		return 0
+	}
+	lost := new(big.Int).Sub(t.Sent, t.Received)
+	return new(big.Int).Div(new(big.Int).Mul(lost, big.NewInt(10_000)), t.Sent).Int64()
+}
+
+// Pct formats Bps as a percentage with 2 decimals ("2.50").
+func (t TransferTax) Pct() string {
+	bps := t.Bps()
+	return fmt.Sprintf("%d.%02d", bps/100, bps%100)
+}
+
+// ReceivedAfter scales amount by what arrives (amount × Received / Sent), e.g. the tokens a
+// router swap will actually sell.
+func (t TransferTax) ReceivedAfter(amount *big.Int) *big.Int {
+	if t.Bps() == 0 {
+		return new(big.Int).Set(amount)
+	}
+	return new(big.Int).Div(new(big.Int).Mul(amount, t.Received), t.Sent)
+}
+
+// SimulateTransferTax measures the fee-on-transfer of token by running transfer(to, amount)
+// from `from` inside one eth_call (stateOverride code at `from`) and comparing the recipient's
+// balance before and after. Needs an RPC with eth_call stateOverrides; a failing transfer is
+// an error (the regular preflight explains why).
+func SimulateTransferTax(ctx context.Context, rc *rpc.Client, token, from, to common.Address, amount *big.Int) (TransferTax, error) {
+	before, after, err := probeTransfer(ctx, rc, token, from, to, to, amount)
+	if err != nil {
+		return TransferTax{}, err
+	}
+	received := new(big.Int).Sub(after, before)
+	if received.Sign() < 0 {
+		received.SetInt64(0)
+	}
+	return TransferTax{Sent: new(big.Int).Set(amount), Received: received}, nil
+}
+
+// probeTransfer runs transfer(to, amount) from `from` with taxProbeCode and returns the
+// token balance of watch before and after it.
+func probeTransfer(ctx context.Context, rc *rpc.Client, token, from, to, watch common.Address, amount *big.Int) (before, after *big.Int, err error) {
+	if rc == nil {
+		return nil, nil, errors.New("no RPC client for stateOverrides")
+	}
+	if amount == nil || amount.Sign() == 0 {
+		return nil, nil, errors.New("zero amount")
+	}
+	data := append(common.LeftPadBytes(token.Bytes(), 32), common.LeftPadBytes(to.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(watch.Bytes(), 32)...)
+	callObj := map[string]interface{}{"from": from, "to": from, "data": hexutil.Encode(data)}
+	override := map[string]map[string]string{
+		strings.ToLower(from.Hex()): {"code": hexutil.Encode(taxProbeCode)},
+	}
+	var res hexutil.Bytes
+	if err := rc.CallContext(ctx, &res, "eth_call", callObj, "latest", override); err != nil {
+		return nil, nil, err
+	}
+	if len(res) != 5*32 {
+		return nil, nil, fmt.Errorf("tax probe: unexpected %d-byte result", len(res))
+	}
+	w := func(i int) *big.Int { return new(big.Int).SetBytes(res[32*i : 32*(i+1)]) }
+	ok, ret, retSize := w(1), w(2), w(3)
+	if ok.Sign() == 0 || (retSize.Sign() != 0 && ret.Sign() == 0) {
+		return nil, nil, errors.New("tax probe: transfer failed")
+	}
+	return w(0), w(4), nil
+}
@@ -11,15 +11,21 @@ import (
 
 // Build EIP-1559 transaction.
 func buildDynamicTx(chain *big.Int, nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, tip, feeCap *big.Int, data []byte) *types.Transaction {
+	return buildDynamicTxAL(chain, nonce, to, value, gasLimit, tip, feeCap, data, nil)
+}
+
+// Build EIP-1559 transaction with an EIP-2930 access list (nil = none).
+func buildDynamicTxAL(chain *big.Int, nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, tip, feeCap *big.Int, data []byte, al types.AccessList) *types.Transaction {
 	df := &types.DynamicFeeTx{
-		ChainID:   chain,
-		Nonce:     nonce,
-		Gas:       gasLimit,
-		GasTipCap: new(big.Int).Set(tip),
-		GasFeeCap: new(big.Int).Set(feeCap),
-		To:        to,
-		Value:     new(big.Int).Set(value),
-		Data:      data,
+		ChainID:    chain,
+		Nonce:      nonce,
+		Gas:        gasLimit,
+		GasTipCap:  new(big.Int).Set(tip),
+		GasFeeCap:  new(big.Int).Set(feeCap),
+		To:         to,
+		Value:      new(big.Int).Set(value),
+		Data:       data,
+		AccessList: al,
 	}
 	return types.NewTx(df)
 }
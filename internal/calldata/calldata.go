@@ -0,0 +1,161 @@
+// Package calldata decodes transaction input against the ABIs this tool produces: the rescue
+// delegate (sweepERC20/sweepETH/sweepToken/sellToETH_V2), ERC-20 and the UniswapV2 router.
+// Used by `bundlecli decode-calldata` and the GUI decoder to review exported transactions,
+// and to explain the "duplicated calldata head" warning of eip7702.BuildSetCodeTx: bytes
+// after the ABI-encoded arguments are reported and, when they start with a selector again,
+// decoded as a second call.
+package calldata
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// knownABI is every function the tool encodes or calls, by contract kind.
+const knownABI = `[
+  {"type":"function","name":"sweepERC20","inputs":[{"name":"tokens","type":"address[]"},{"name":"to","type":"address"}],"outputs":[]},
+  {"type":"function","name":"sweepETH","inputs":[{"name":"to","type":"address"}],"outputs":[]},
+  {"type":"function","name":"sweepToken","inputs":[{"name":"token","type":"address"},{"name":"recipient","type":"address"}],"outputs":[]},
+  {"type":"function","name":"sellToETH_V2","inputs":[{"name":"tokenIn","type":"address"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinETH","type":"uint256"},{"name":"recipient","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+
+  {"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"type":"bool"}]},
+  {"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"type":"bool"}]},
+  {"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"type":"bool"}]},
+  {"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"type":"uint256"}]},
+  {"type":"function","name":"allowance","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"type":"uint256"}]},
+  {"type":"function","name":"decimals","inputs":[],"outputs":[{"type":"uint8"}]},
+  {"type":"function","name":"symbol","inputs":[],"outputs":[{"type":"string"}]},
+
+  {"type":"function","name":"swapExactTokensForETHSupportingFeeOnTransferTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[]},
+  {"type":"function","name":"swapExactTokensForETH","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[{"type":"uint256[]"}]},
+  {"type":"function","name":"getAmountsOut","inputs":[{"name":"amountIn","type":"uint256"},{"name":"path","type":"address[]"}],"outputs":[{"type":"uint256[]"}]},
+  {"type":"function","name":"getPair","inputs":[{"name":"tokenA","type":"address"},{"name":"tokenB","type":"address"}],"outputs":[{"type":"address"}]}
+]`
+
+var parsed = func() abi.ABI {
+	a, err := abi.JSON(strings.NewReader(knownABI))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}()
+
+// Arg is one decoded argument.
+type Arg struct {
+	Name, Type, Value string
+}
+
+// Call is decoded calldata.
+type Call struct {
+	Selector  string // 0x + 4 bytes
+	Signature string // "" = unknown selector
+	Args      []Arg
+	Words     []string // unknown selector: the raw 32-byte words after it
+	Trailing  []byte   // bytes after the ABI-encoded arguments
+	Next      *Call    // Trailing decoded again when it starts with a known selector
+	DupHead   bool     // Trailing starts with the same selector (BuildSetCodeTx warning)
+}
+
+// ParseHex accepts calldata with or without 0x and surrounding whitespace.
+func ParseHex(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("calldata: %w", err)
+	}
+	return b, nil
+}
+
+// Decode decodes data against the known ABIs.
+func Decode(data []byte) (*Call, error) {
+	if len(data) < 4 {
+		return nil, errors.New("calldata shorter than a 4-byte selector")
+	}
+	c := &Call{Selector: hexutil.Encode(data[:4])}
+	m, err := parsed.MethodById(data[:4])
+	if err != nil {
+		for rest := data[4:]; len(rest) > 0; {
+			n := min(32, len(rest))
+			c.Words = append(c.Words, hexutil.Encode(rest[:n]))
+			rest = rest[n:]
+		}
+		return c, nil
+	}
+	c.Signature = m.Sig
+	vals, err := m.Inputs.Unpack(data[4:])
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", m.Sig, err)
+	}
+	for i, in := range m.Inputs {
+		c.Args = append(c.Args, Arg{Name: in.Name, Type: in.Type.String(), Value: format(vals[i])})
+	}
+	// Re-encoding gives the length the arguments occupy; anything after it is trailing.
+	if enc, err := m.Inputs.Pack(vals...); err == nil && len(data[4:]) > len(enc) {
+		c.Trailing = data[4+len(enc):]
+		c.DupHead = len(c.Trailing) >= 4 && string(c.Trailing[:4]) == string(data[:4])
+		if next, err := Decode(c.Trailing); err == nil && next.Signature != "" {
+			c.Next = next
+		}
+	}
+	return c, nil
+}
+
+// String renders the call for a terminal or a dialog, one argument per line.
+func (c *Call) String() string {
+	var b strings.Builder
+	c.write(&b, "")
+	return b.String()
+}
+
+func (c *Call) write(b *strings.Builder, indent string) {
+	if c.Signature == "" {
+		fmt.Fprintf(b, "%sselector %s: unknown function (not in the delegate/ERC-20/router ABIs)\n", indent, c.Selector)
+		for i, w := range c.Words {
+			fmt.Fprintf(b, "%s  [%d] %s\n", indent, i, w)
+		}
+		return
+	}
+	fmt.Fprintf(b, "%s%s  (selector %s)\n", indent, c.Signature, c.Selector)
+	for _, a := range c.Args {
+		fmt.Fprintf(b, "%s  %s %s = %s\n", indent, a.Type, a.Name, a.Value)
+	}
+	if len(c.Trailing) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s  trailing %d byte(s) after the arguments (ignored by the contract)\n", indent, len(c.Trailing))
+	if c.DupHead {
+		fmt.Fprintf(b, "%s  duplicated selector: calldata was encoded twice\n", indent)
+	}
+	if c.Next != nil {
+		c.Next.write(b, indent+"  ")
+	} else {
+		fmt.Fprintf(b, "%s  %s\n", indent, hexutil.Encode(c.Trailing))
+	}
+}
+
+func format(v any) string {
+	switch x := v.(type) {
+	case common.Address:
+		return x.Hex()
+	case []common.Address:
+		s := make([]string, len(x))
+		for i, a := range x {
+			s[i] = a.Hex()
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	case *big.Int:
+		return x.String()
+	case []byte:
+		return hexutil.Encode(x)
+	}
+	return fmt.Sprint(v)
+}
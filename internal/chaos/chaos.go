@@ -0,0 +1,146 @@
+// Package chaos injects RPC timeouts, 429s, relay 5xx answers and nonce races into the
+// HTTP transport (see rpcmetrics.Transport), so retry/backoff, competing-nonce handling
+// and resume can be exercised before a real run. The injector only exists in dev builds
+// (go build -tags chaos); release builds refuse --chaos.
+//
+// Spec: comma-separated key=rate pairs, rates in 0..1:
+//
+//	timeout=0.05,429=0.1,relay5xx=0.2,nonce=0.05,seed=42,delay=2s
+//
+// "1" or "on" enables the defaults above.
+package chaos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/features"
+)
+
+func init() {
+	detail := "fault injector (--chaos): dev builds only, go build -tags chaos"
+	if Built {
+		detail = "fault injector (--chaos)"
+	}
+	features.Register(features.Feature{Name: "chaos", Available: Built, Detail: detail})
+}
+
+// Config holds injection rates (probability per request).
+type Config struct {
+	Timeout   float64       // provider request hangs for Delay, then fails as a timeout
+	RateLimit float64       // provider answers 429 / -32005
+	Relay5xx  float64       // relay answers 502
+	Nonce     float64       // pending nonce reported +1 (a competing tx appeared)
+	Delay     time.Duration // how long an injected timeout hangs
+	Seed      int64         // 0 = time-based
+}
+
+// Defaults are used for "--chaos 1".
+var Defaults = Config{Timeout: 0.05, RateLimit: 0.1, Relay5xx: 0.2, Nonce: 0.05, Delay: 2 * time.Second}
+
+// Parse reads a spec (see package doc).
+func Parse(spec string) (Config, error) {
+	spec = strings.TrimSpace(spec)
+	switch strings.ToLower(spec) {
+	case "1", "on", "true", "default":
+		return Defaults, nil
+	}
+	c := Config{Delay: Defaults.Delay}
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("chaos: %q is not key=value", kv)
+		}
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		switch k {
+		case "delay":
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("chaos: delay: %w", err)
+			}
+			c.Delay = d
+			continue
+		case "seed":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return Config{}, fmt.Errorf("chaos: seed: %w", err)
+			}
+			c.Seed = n
+			continue
+		}
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("chaos: %s: rate must be 0..1, got %q", k, v)
+		}
+		switch k {
+		case "timeout":
+			c.Timeout = rate
+		case "429", "ratelimit":
+			c.RateLimit = rate
+		case "relay5xx", "5xx":
+			c.Relay5xx = rate
+		case "nonce":
+			c.Nonce = rate
+		default:
+			return Config{}, fmt.Errorf("chaos: unknown key %q", k)
+		}
+	}
+	return c, nil
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("timeout=%.2f 429=%.2f relay5xx=%.2f nonce=%.2f delay=%s seed=%d",
+		c.Timeout, c.RateLimit, c.Relay5xx, c.Nonce, c.Delay, c.Seed)
+}
+
+var (
+	mu       sync.Mutex
+	active   *Config
+	injected = map[string]int{}
+)
+
+// Enable turns injection on for the process. Fails in release builds.
+func Enable(spec string) (Config, error) {
+	if !Built {
+		return Config{}, fmt.Errorf("chaos mode is only in dev builds (go build -tags chaos)")
+	}
+	c, err := Parse(spec)
+	if err != nil {
+		return Config{}, err
+	}
+	mu.Lock()
+	active = &c
+	mu.Unlock()
+	seed(c.Seed)
+	return c, nil
+}
+
+func current() *Config {
+	mu.Lock()
+	defer mu.Unlock()
+	return active
+}
+
+func count(kind string) {
+	mu.Lock()
+	injected[kind]++
+	mu.Unlock()
+}
+
+// Summary is one line with injected fault counts ("" when chaos is off).
+func Summary() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		return ""
+	}
+	return fmt.Sprintf("[chaos] injected: timeout=%d 429=%d relay5xx=%d nonce=%d",
+		injected["timeout"], injected["429"], injected["relay5xx"], injected["nonce"])
+}
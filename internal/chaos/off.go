@@ -0,0 +1,13 @@
+//go:build !chaos
+
+package chaos
+
+import "net/http"
+
+// Built reports whether the fault injector is compiled in.
+const Built = false
+
+// Wrap returns base unchanged in release builds.
+func Wrap(base http.RoundTripper) http.RoundTripper { return base }
+
+func seed(int64) {}
@@ -0,0 +1,144 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Built reports whether the fault injector is compiled in.
+const Built = true
+
+var (
+	rngMu sync.Mutex
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func seed(s int64) {
+	if s == 0 {
+		return
+	}
+	rngMu.Lock()
+	rng = rand.New(rand.NewSource(s))
+	rngMu.Unlock()
+}
+
+func roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.Float64() < rate
+}
+
+var relayMethods = map[string]bool{
+	"eth_sendBundle": true, "eth_callBundle": true, "mev_sendBundle": true, "mev_simBundle": true,
+	"eth_sendPrivateTransaction": true, "eth_sendPrivateRawTransaction": true, "blxr_submit_bundle": true,
+}
+
+type transport struct{ base http.RoundTripper }
+
+// Wrap puts the injector in front of base. Rates are read per request, so clients built
+// before Enable (package-level http.Clients) are covered too.
+func Wrap(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type rpcMsg struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := current()
+	if c == nil || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var m rpcMsg
+	_ = json.Unmarshal(body, &m) // batches stay rpcMsg{} and only get provider faults
+	if relayMethods[m.Method] {
+		if roll(c.Relay5xx) {
+			count("relay5xx")
+			return fake(req, http.StatusBadGateway, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"chaos: bad gateway"}}`), nil
+		}
+		return t.base.RoundTrip(req)
+	}
+	if roll(c.Timeout) {
+		count("timeout")
+		select {
+		case <-time.After(c.Delay):
+		case <-req.Context().Done():
+		}
+		return nil, fmt.Errorf("chaos: %s: i/o timeout", m.Method)
+	}
+	if roll(c.RateLimit) {
+		count("429")
+		return fake(req, http.StatusTooManyRequests, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"chaos: Too Many Requests"}}`), nil
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || m.Method != "eth_getTransactionCount" || len(m.Params) < 2 || !strings.Contains(string(m.Params[1]), "pending") || !roll(c.Nonce) {
+		return resp, err
+	}
+	return bumpNonce(resp), nil
+}
+
+// bumpNonce answers pending nonce + 1, as if a competing tx from the victim hit the mempool.
+func bumpNonce(resp *http.Response) *http.Response {
+	raw, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(raw))
+	if err != nil {
+		return resp
+	}
+	var out map[string]any
+	if json.Unmarshal(raw, &out) != nil {
+		return resp
+	}
+	s, ok := out["result"].(string)
+	n, ok2 := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok || !ok2 {
+		return resp
+	}
+	out["result"] = fmt.Sprintf("0x%x", n.Add(n, big.NewInt(1)))
+	b, _ := json.Marshal(out)
+	count("nonce")
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Header.Del("Content-Length")
+	return resp
+}
+
+func fake(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// Compressed inputs: leak dumps usually arrive as .csv.gz or .zip. They are recognised by
+// their magic bytes (not the file name) and decompressed on the fly, so a multi-gigabyte
+// dump never has to be extracted to disk first. A gzip stream (also a concatenated one) is
+// read as it arrives; of a zip archive the first list entry (.csv, .tsv, .txt, .json, or
+// the only file) is read.
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// listExts are the zip entries taken as the list, in the order they are looked for.
+var listExts = []string{".csv", ".tsv", ".txt", ".json", ".jsonl", ".ndjson"}
+
+// Decompress returns the content of r: gunzipped or unzipped when r holds a gzip stream or
+// a zip archive, else r itself. name is the name of the content when the container records
+// one (the zip entry, gzip's original file name), else "". A zip read from a plain reader
+// is buffered in memory (zip needs random access); OpenInput avoids that for files.
+func Decompress(r io.Reader) (content io.Reader, name string, err error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(zipMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("gzip: %w", err)
+		}
+		return zr, zr.Name, nil
+	case bytes.HasPrefix(head, zipMagic):
+		b, err := io.ReadAll(br)
+		if err != nil {
+			return nil, "", err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return nil, "", fmt.Errorf("zip: %w", err)
+		}
+		f, err := listEntry(zr.File)
+		if err != nil {
+			return nil, "", fmt.Errorf("zip: %w", err)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+		return rc, f.Name, nil
+	}
+	return br, "", nil
+}
+
+// OpenInput opens an input list for reading, decompressed when it is gzip or zip (see
+// Decompress); "-" means stdin. A zip file is read entry-wise, without loading the archive.
+func OpenInput(p string) (io.ReadCloser, error) {
+	r, _, err := OpenInputCounted(p, nil)
+	return r, err
+}
+
+// OpenInputCounted is OpenInput that also adds to read the bytes taken from the file
+// itself (compressed bytes for .gz/.zip, so read/size is how far the input has been
+// read) and returns the file size (0 for stdin). read may be nil.
+func OpenInputCounted(p string, read *atomic.Int64) (io.ReadCloser, int64, error) {
+	if strings.TrimSpace(p) == "-" {
+		r, _, err := Decompress(countingReader{os.Stdin, read})
+		if err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(r), 0, nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	head := make([]byte, len(zipMagic))
+	n, _ := io.ReadFull(f, head)
+	if bytes.HasPrefix(head[:n], zipMagic) {
+		var zr *zip.Reader
+		if zr, err = zip.NewReader(countingReaderAt{f, read}, st.Size()); err == nil {
+			var e *zip.File
+			if e, err = listEntry(zr.File); err == nil {
+				var rc io.ReadCloser
+				if rc, err = e.Open(); err == nil {
+					return readCloser{rc, f}, st.Size(), nil
+				}
+			}
+		}
+		f.Close()
+		return nil, 0, fmt.Errorf("zip %s: %w", p, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	r, _, err := Decompress(countingReader{f, read})
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("%s: %w", p, err)
+	}
+	return readCloser{r, f}, st.Size(), nil
+}
+
+// ListName is the name a list goes by once decompressed: inner (the name the container
+// recorded) when set, else name without its .gz/.gzip/.zip suffix.
+func ListName(name, inner string) string {
+	if inner != "" {
+		return path.Base(inner)
+	}
+	for _, ext := range []string{".gz", ".gzip", ".zip"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// listEntry picks the list inside a zip archive: the first entry with a list extension,
+// else the only file; directories and macOS resource forks are ignored.
+func listEntry(files []*zip.File) (*zip.File, error) {
+	var regular []*zip.File
+	for _, f := range files {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(path.Base(f.Name), "._") {
+			continue
+		}
+		regular = append(regular, f)
+	}
+	for _, ext := range listExts {
+		for _, f := range regular {
+			if strings.EqualFold(path.Ext(f.Name), ext) {
+				return f, nil
+			}
+		}
+	}
+	if len(regular) == 1 {
+		return regular[0], nil
+	}
+	if len(regular) == 0 {
+		return nil, errors.New("the archive has no files")
+	}
+	return nil, fmt.Errorf("no .csv/.txt/.json entry among %d files", len(regular))
+}
+
+// countingReader adds the bytes read from r to n (when set).
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(b []byte) (int, error) {
+	k, err := c.r.Read(b)
+	if c.n != nil {
+		c.n.Add(int64(k))
+	}
+	return k, err
+}
+
+// countingReaderAt is countingReader for zip's random access.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n *atomic.Int64
+}
+
+func (c countingReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	k, err := c.r.ReadAt(b, off)
+	if c.n != nil {
+		c.n.Add(int64(k))
+	}
+	return k, err
+}
+
+// readCloser reads from r and closes c (the file under a decompressor).
+type readCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+func (rc readCloser) Close() error { return rc.c.Close() }
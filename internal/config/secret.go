@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ResolveSecret expands a secret reference used in config fields:
+//
+//	env:NAME          value of environment variable NAME
+//	file:/path        contents of the file (e.g. /run/secrets/safe.pk), trimmed
+//
+// Anything else is returned unchanged, so plain values keep working.
+func ResolveSecret(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	switch {
+	case strings.HasPrefix(v, "env:"):
+		name := strings.TrimSpace(strings.TrimPrefix(v, "env:"))
+		s, ok := os.LookupEnv(name)
+		if !ok || strings.TrimSpace(s) == "" {
+			return "", fmt.Errorf("secret %s: environment variable %s is not set", v, name)
+		}
+		return strings.TrimSpace(s), nil
+	case strings.HasPrefix(v, "file:"):
+		path := strings.TrimSpace(strings.TrimPrefix(v, "file:"))
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("secret file:%s: %w", path, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return v, nil
+}
+
+// ReadInput reads a whole input file; "-" means stdin, so lists can be piped in
+// without touching the disk. gzip and zip inputs are decompressed (see OpenInput).
+func ReadInput(path string) ([]byte, error) {
+	r, err := OpenInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,100 @@
+package eip7702
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DelegateFunctions are the delegate entry points the tools can call, by name, with the
+// route that needs each one. Deployed delegates differ: one has sweepToken, another only
+// sweepERC20, a third adds sellToETH_V2.
+var DelegateFunctions = []struct {
+	Name, Signature, Route string
+}{
+	{"sweepERC20", "sweepERC20(address[],address)", "sweep (bundlecli single mode)"},
+	{"sweepETH", "sweepETH(address)", "-"},
+	{"sweepToken", "sweepToken(address,address)", "transfer"},
+	{"sellToETH_V2", "sellToETH_V2(address,uint256,uint256,address,uint256)", "sell-v2"},
+}
+
+// Capabilities is the function set of one delegate, read from its bytecode.
+type Capabilities struct {
+	Delegate common.Address
+	Impl     common.Address  // EIP-1167 target when the delegate is a minimal proxy
+	Has      map[string]bool // function name -> selector found in the dispatcher
+	Opaque   bool            // no known selector at all: the code dispatches some other way, nothing is ruled out
+}
+
+// Supports reports whether the delegate can execute fn (always true when Opaque).
+func (c Capabilities) Supports(fn string) bool { return c.Opaque || c.Has[fn] }
+
+// Matrix is a one-line capability summary: "sweepERC20=yes sweepETH=no ...".
+func (c Capabilities) Matrix() string {
+	if c.Opaque {
+		return "unknown (no known selector in the bytecode; every route is attempted)"
+	}
+	parts := make([]string, 0, len(DelegateFunctions))
+	for _, f := range DelegateFunctions {
+		v := "no"
+		if c.Has[f.Name] {
+			v = "yes"
+		}
+		parts = append(parts, f.Name+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ProbeDelegate reads the code of delegate and looks for the selector of every
+// DelegateFunctions entry in its dispatcher. An EIP-1167 minimal proxy is followed to its
+// implementation. A delegate without code is an error.
+func ProbeDelegate(ctx context.Context, ec *ethclient.Client, delegate common.Address) (Capabilities, error) {
+	caps := Capabilities{Delegate: delegate, Has: map[string]bool{}}
+	code, err := ec.CodeAt(ctx, delegate, nil)
+	if err != nil {
+		return caps, fmt.Errorf("delegate %s getCode: %w", delegate.Hex(), err)
+	}
+	if len(code) == 0 {
+		return caps, fmt.Errorf("delegate %s has no code", delegate.Hex())
+	}
+	if impl := minimalProxyTarget(code); impl != (common.Address{}) {
+		caps.Impl = impl
+		if code, err = ec.CodeAt(ctx, impl, nil); err != nil {
+			return caps, fmt.Errorf("delegate %s implementation %s getCode: %w", delegate.Hex(), impl.Hex(), err)
+		}
+	}
+	found := false
+	for _, f := range DelegateFunctions {
+		var sel [4]byte
+		copy(sel[:], crypto.Keccak256([]byte(f.Signature))[:4])
+		if pushesSelector(code, sel) {
+			caps.Has[f.Name] = true
+			found = true
+		}
+	}
+	caps.Opaque = !found
+	return caps, nil
+}
+
+// pushesSelector reports whether code pushes sel as a dispatcher does: PUSH4 sel, or PUSH3
+// of the last three bytes when the selector starts with 0x00 (solc drops the leading zero).
+func pushesSelector(code []byte, sel [4]byte) bool {
+	if bytes.Contains(code, []byte{0x63, sel[0], sel[1], sel[2], sel[3]}) {
+		return true
+	}
+	return sel[0] == 0 && bytes.Contains(code, []byte{0x62, sel[1], sel[2], sel[3]})
+}
+
+// minimalProxyTarget returns the implementation of an EIP-1167 minimal proxy (zero otherwise).
+func minimalProxyTarget(code []byte) common.Address {
+	prefix := common.FromHex("0x363d3d373d3d3d363d73")
+	if len(code) >= len(prefix)+20 && bytes.HasPrefix(code, prefix) {
+		return common.BytesToAddress(code[len(prefix) : len(prefix)+20])
+	}
+	return common.Address{}
+}
@@ -0,0 +1,81 @@
+package eip7702
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DelegatePolicy picks the delegate contract for a pair: per-row override, then
+// per-token mapping, then the default. Every choice must be on the allowlist,
+// which always contains the default.
+type DelegatePolicy struct {
+	Default common.Address
+	ByToken map[common.Address]common.Address
+	Allow   map[common.Address]bool
+}
+
+// NewDelegatePolicy builds a policy from env-style strings:
+//
+//	def     = DELEGATE_ADDRESS
+//	allow   = "0xD1,0xD2"               (DELEGATE_ALLOWLIST)
+//	byToken = "0xTok1=0xD1,0xTok2=0xD2" (DELEGATE_BY_TOKEN)
+//
+// Token mappings pointing outside the allowlist are rejected up front.
+func NewDelegatePolicy(def, allow, byToken string) (*DelegatePolicy, error) {
+	if !common.IsHexAddress(strings.TrimSpace(def)) {
+		return nil, fmt.Errorf("bad DELEGATE_ADDRESS %q", def)
+	}
+	p := &DelegatePolicy{
+		Default: common.HexToAddress(strings.TrimSpace(def)),
+		ByToken: map[common.Address]common.Address{},
+		Allow:   map[common.Address]bool{},
+	}
+	p.Allow[p.Default] = true
+	for _, s := range strings.Split(allow, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("bad DELEGATE_ALLOWLIST entry %q", s)
+		}
+		p.Allow[common.HexToAddress(s)] = true
+	}
+	for _, kv := range strings.Split(byToken, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		tok, del, ok := strings.Cut(kv, "=")
+		tok, del = strings.TrimSpace(tok), strings.TrimSpace(del)
+		if !ok || !common.IsHexAddress(tok) || !common.IsHexAddress(del) {
+			return nil, fmt.Errorf("bad DELEGATE_BY_TOKEN entry %q (want token=delegate)", kv)
+		}
+		d := common.HexToAddress(del)
+		if !p.Allow[d] {
+			return nil, fmt.Errorf("DELEGATE_BY_TOKEN: %s is not in DELEGATE_ALLOWLIST", d.Hex())
+		}
+		p.ByToken[common.HexToAddress(tok)] = d
+	}
+	return p, nil
+}
+
+// Resolve returns the delegate for token; override is an optional per-row address.
+func (p *DelegatePolicy) Resolve(token common.Address, override string) (common.Address, string, error) {
+	if o := strings.TrimSpace(override); o != "" {
+		if !common.IsHexAddress(o) {
+			return common.Address{}, "", fmt.Errorf("bad delegate override %q", o)
+		}
+		d := common.HexToAddress(o)
+		if !p.Allow[d] {
+			return common.Address{}, "", fmt.Errorf("delegate %s is not in DELEGATE_ALLOWLIST", d.Hex())
+		}
+		return d, "row", nil
+	}
+	if d, ok := p.ByToken[token]; ok {
+		return d, "token", nil
+	}
+	return p.Default, "default", nil
+}
@@ -21,6 +21,9 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	u256 "github.com/holiman/uint256"
+
+	"github.com/ligun0805/bundle-rescue/internal/relayseen"
+	"github.com/ligun0805/bundle-rescue/internal/rpcmetrics"
 )
 
 // ABI of a minimal delegate with `sweepERC20(address[] tokens, address to)` and `sweepETH(address to)`.
@@ -39,6 +42,8 @@ type RelayResult struct {
 	ResponseBody  string
 	HTTPStatus    int
 	RequestMethod string
+	AlreadyKnown  bool // relay answered "already known": counted as accepted
+	Skipped       bool // not sent: this relay already has the tx (see SendPrivate's sent)
 }
 
 // ExtraHeaders maps relay URL -> {Header:Value}. Useful for BLXR API keys etc.
@@ -109,7 +114,7 @@ func BuildSetCodeTx(p BuildParams) (*types.Transaction, error) {
     if len(p.Calldata) > 4 {
         head := p.Calldata[:4]
         if idx := bytes.Index(p.Calldata[4:], head); idx >= 0 {
-            fmt.Println("[warn] duplicated calldata head detected; length=", len(p.Calldata))
+            fmt.Println("[warn] duplicated calldata head detected; length=", len(p.Calldata), "(inspect: bundlecli decode-calldata 0x...)")
         }
     }	
 	txdata := &types.SetCodeTx{
@@ -170,9 +175,17 @@ func EstimateGas(ctx context.Context, ec *ethclient.Client, fromSponsor common.A
 // 1) eth_sendPrivateTransaction { "tx": "0x..." }
 // 2) eth_sendPrivateRawTransaction "0x..."
 // 3) eth_sendRawTransaction "0x..." (beaver treats as private)
-func SendPrivate(ctx context.Context, rawTxHex string, relays []string, headers ExtraHeaders, authSigner *ecdsa.PrivateKey) []RelayResult {
+//
+// sent is the caller's per-run ledger of relays that already accepted (or knew) a raw tx,
+// keyed by tx hash; entries expire after its TTL. nil sends to every relay.
+func SendPrivate(ctx context.Context, rawTxHex string, relays []string, headers ExtraHeaders, authSigner *ecdsa.PrivateKey, sent *relayseen.Ledger) []RelayResult {
 	results := make([]RelayResult, 0, len(relays)*3)
+	txKey := crypto.Keccak256Hash(common.FromHex(rawTxHex)).Hex()
 	for _, url := range relays {
+		if !sent.ShouldSend(url, txKey) {
+			results = append(results, RelayResult{RelayURL: url, Accepted: true, Skipped: true, ResponseBody: "skipped: relay already has this tx"})
+			continue
+		}
 		// Per-relay method preference
 		methods := []string{"eth_sendPrivateTransaction", "eth_sendPrivateRawTransaction", "eth_sendRawTransaction"}
 		if strings.Contains(url, "blxrbdn.com") {
@@ -213,13 +226,20 @@ func SendPrivate(ctx context.Context, rawTxHex string, relays []string, headers
 			if !ok && code == 405 {
 				// Some endpoints reject unknown method with 405; continue to next method.
 			}
+			// "already known" means the relay has the tx: soft success, not a failure to retry.
+			known := relayseen.IsAlreadyKnown(body) || (err != nil && relayseen.IsAlreadyKnown(err.Error()))
+			if known {
+				ok = true
+			}
 			results = append(results, RelayResult{
 				RelayURL:      url,
 				Accepted:      ok,
 				ResponseBody:  body,
 				HTTPStatus:    code,
 				RequestMethod: m,
+				AlreadyKnown:  known,
 			})
+			sent.Mark(url, txKey, ok, known)
 			if ok {
 				break // stop trying other methods for this relay
 			}
@@ -246,7 +266,7 @@ func doHTTP(ctx context.Context, url string, body []byte, headers map[string]str
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	httpClient := &http.Client{Timeout: 8 * time.Second}
+	httpClient := &http.Client{Timeout: 8 * time.Second, Transport: rpcmetrics.Transport(nil)}
 	res, err := httpClient.Do(req)
 	if err != nil {
 		return 0, "", err
@@ -279,6 +299,7 @@ type RescueRequest struct {
 	ExtraHeaders ExtraHeaders
 	AuthSignerPriv *ecdsa.PrivateKey
 	EnableSimulation bool
+	Sent             *relayseen.Ledger // optional per-run resend ledger (see SendPrivate)
 }
 
 type RescueResponse struct {
@@ -355,7 +376,7 @@ func ExecuteRescue(ctx context.Context, ec *ethclient.Client, req RescueRequest)
 		}
 	}
 	
-	attempts := SendPrivate(ctx, rawHex, req.RelayURLs, req.ExtraHeaders, req.AuthSignerPriv)
+	attempts := SendPrivate(ctx, rawHex, req.RelayURLs, req.ExtraHeaders, req.AuthSignerPriv, req.Sent)
 	return &RescueResponse{
 		TxHash:        signed.Hash(),
 		RawTxHex:      rawHex,
@@ -375,6 +396,26 @@ func pickFlashbotsRelay(relays []string) string {
 	return "https://relay.flashbots.net"
 }
 
+// SimResult is the eth_callBundle verdict of one relay for a single raw tx.
+type SimResult struct {
+	RelayURL     string
+	OK           bool
+	Reason       string
+	ResponseBody string
+	HTTPStatus   int
+	Err          error
+}
+
+// SimulatePrivate runs eth_callBundle for rawTxHex at blockHex on every relay, never sending anything.
+func SimulatePrivate(ctx context.Context, rawTxHex string, blockHex string, relays []string, headers ExtraHeaders, authSigner *ecdsa.PrivateKey) []SimResult {
+	out := make([]SimResult, 0, len(relays))
+	for _, url := range relays {
+		ok, reason, body, code, err := simulateFlashbotsCallBundle(ctx, url, headers, authSigner, rawTxHex, blockHex)
+		out = append(out, SimResult{RelayURL: url, OK: ok && err == nil, Reason: reason, ResponseBody: body, HTTPStatus: code, Err: err})
+	}
+	return out
+}
+
 // simulateFlashbotsCallBundle performs eth_callBundle for a single raw tx.
 // Returns (ok, reason, body, status, err).
 func simulateFlashbotsCallBundle(ctx context.Context, relay string, headers ExtraHeaders, authSigner *ecdsa.PrivateKey, rawTxHex string, blockHex string) (bool, string, string, int, error) {
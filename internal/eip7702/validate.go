@@ -0,0 +1,90 @@
+package eip7702
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"strings"
+)
+
+// RelayCheck is the outcome of an authenticated no-op call against one relay.
+type RelayCheck struct {
+	RelayURL     string
+	OK           bool
+	HTTPStatus   int
+	Problem      string // short diagnosis when !OK
+	Hint         string // what to fix in .env
+	ResponseBody string
+}
+
+// ValidateRelays sends an empty signed eth_callBundle to every relay before any real work.
+// Relays answer an empty bundle with a JSON-RPC error, which is fine: the point is to see
+// whether the request got past authentication (401/403, "signature", "unauthorized"...).
+// bloXroute endpoints without an Authorization header are rejected without a network call.
+func ValidateRelays(ctx context.Context, relays []string, headers ExtraHeaders, authSigner *ecdsa.PrivateKey) []RelayCheck {
+	out := make([]RelayCheck, 0, len(relays))
+	for _, url := range relays {
+		out = append(out, validateRelay(ctx, url, headers, authSigner))
+	}
+	return out
+}
+
+func validateRelay(ctx context.Context, url string, headers ExtraHeaders, authSigner *ecdsa.PrivateKey) RelayCheck {
+	rc := RelayCheck{RelayURL: url}
+	hdr := map[string]string{"Content-Type": "application/json"}
+	if headers != nil {
+		for k, v := range headers[url] {
+			hdr[k] = v
+		}
+	}
+	if strings.Contains(url, "blxrbdn.com") && strings.TrimSpace(hdr["Authorization"]) == "" {
+		rc.Problem = "missing Authorization header"
+		rc.Hint = "set BLOXROUTE_AUTH_HEADER (or BLOXROUTE_API_KEY) for " + url
+		return rc
+	}
+	needsSig := strings.Contains(url, "flashbots.net") || strings.Contains(url, "payload.de") || strings.Contains(url, "buildernet")
+	if needsSig && authSigner == nil {
+		rc.Problem = "relay requires X-Flashbots-Signature"
+		rc.Hint = "set FLASHBOTS_AUTH_PK to a valid 32-byte hex key"
+		return rc
+	}
+
+	reqBody := map[string]any{"jsonrpc": "2.0", "id": 1, "method": "eth_callBundle",
+		"params": []any{map[string]any{"txs": []string{}, "blockNumber": "latest", "stateBlockNumber": "latest"}}}
+	b, _ := json.Marshal(reqBody)
+	if authSigner != nil {
+		if sig := makeFlashbotsHeader(authSigner, b); sig != "" {
+			hdr["X-Flashbots-Signature"] = sig
+			hdr["x-auction-signature"] = sig
+		}
+	}
+	code, body, err := doHTTP(ctx, url, b, hdr)
+	rc.HTTPStatus, rc.ResponseBody = code, body
+	if err != nil {
+		rc.Problem = "unreachable: " + err.Error()
+		rc.Hint = "check the relay URL in RELAYS / BLOXROUTE_RELAY and network access"
+		return rc
+	}
+	low := strings.ToLower(body)
+	switch {
+	case code == 401 || code == 403 ||
+		strings.Contains(low, "unauthorized") || strings.Contains(low, "forbidden") ||
+		strings.Contains(low, "signature") || strings.Contains(low, "not authorized") ||
+		strings.Contains(low, "invalid api key") || strings.Contains(low, "authorization"):
+		rc.Problem = "authentication rejected"
+		if strings.Contains(url, "blxrbdn.com") {
+			rc.Hint = "BLOXROUTE_AUTH_HEADER / BLOXROUTE_API_KEY is invalid or lacks bundle permissions"
+		} else {
+			rc.Hint = "FLASHBOTS_AUTH_PK signature was rejected; check the key format (0x + 64 hex)"
+		}
+	case code >= 500:
+		rc.Problem = "relay error"
+		rc.Hint = "relay is failing right now; remove it from RELAYS or retry later"
+	case code == 404 || code == 405:
+		rc.Problem = "endpoint does not accept bundle calls"
+		rc.Hint = "check the relay URL (path) in RELAYS"
+	default:
+		rc.OK = true
+	}
+	return rc
+}
@@ -0,0 +1,116 @@
+package erc4337
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Bundler is an ERC-4337 bundler JSON-RPC endpoint (eth_sendUserOperation and friends).
+type Bundler struct {
+	rc *rpc.Client
+}
+
+// DialBundler connects to a bundler endpoint.
+func DialBundler(ctx context.Context, url string) (*Bundler, error) {
+	rc, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("bundler: %w", err)
+	}
+	return &Bundler{rc: rc}, nil
+}
+
+// Close closes the connection.
+func (b *Bundler) Close() { b.rc.Close() }
+
+// Supports reports whether the bundler serves entryPoint (eth_supportedEntryPoints).
+func (b *Bundler) Supports(ctx context.Context, entryPoint common.Address) (bool, error) {
+	var eps []common.Address
+	if err := b.rc.CallContext(ctx, &eps, "eth_supportedEntryPoints"); err != nil {
+		return false, fmt.Errorf("eth_supportedEntryPoints: %w", err)
+	}
+	for _, ep := range eps {
+		if ep == entryPoint {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Estimate fills op's gas limits from eth_estimateUserOperationGas (op is sent with the
+// dummy signature when it has none).
+func (b *Bundler) Estimate(ctx context.Context, op *UserOp, entryPoint common.Address, version string) error {
+	est := *op
+	if len(est.Signature) == 0 {
+		est.Signature = DummySignature
+	}
+	var res struct {
+		CallGasLimit         *hexutil.Big `json:"callGasLimit"`
+		VerificationGasLimit *hexutil.Big `json:"verificationGasLimit"`
+		PreVerificationGas   *hexutil.Big `json:"preVerificationGas"`
+	}
+	if err := b.rc.CallContext(ctx, &res, "eth_estimateUserOperationGas", est.rpcObject(version), entryPoint); err != nil {
+		return fmt.Errorf("eth_estimateUserOperationGas: %w", err)
+	}
+	if res.CallGasLimit == nil || res.VerificationGasLimit == nil || res.PreVerificationGas == nil {
+		return errors.New("eth_estimateUserOperationGas: incomplete result")
+	}
+	op.CallGasLimit = res.CallGasLimit.ToInt()
+	op.VerificationGasLimit = res.VerificationGasLimit.ToInt()
+	op.PreVerificationGas = res.PreVerificationGas.ToInt()
+	return nil
+}
+
+// Send submits the signed op and returns its userOpHash.
+func (b *Bundler) Send(ctx context.Context, op *UserOp, entryPoint common.Address, version string) (common.Hash, error) {
+	var h common.Hash
+	if err := b.rc.CallContext(ctx, &h, "eth_sendUserOperation", op.rpcObject(version), entryPoint); err != nil {
+		return h, fmt.Errorf("eth_sendUserOperation: %w", err)
+	}
+	return h, nil
+}
+
+// Receipt is the part of eth_getUserOperationReceipt the pipeline reports.
+type Receipt struct {
+	Success       bool         `json:"success"`
+	Reason        string       `json:"reason"`
+	ActualGasCost *hexutil.Big `json:"actualGasCost"`
+	Receipt       struct {
+		TransactionHash common.Hash  `json:"transactionHash"`
+		BlockNumber     *hexutil.Big `json:"blockNumber"`
+	} `json:"receipt"`
+}
+
+// Wait polls eth_getUserOperationReceipt until the op is included or ctx ends.
+func (b *Bundler) Wait(ctx context.Context, userOpHash common.Hash, poll time.Duration) (*Receipt, error) {
+	t := time.NewTicker(poll)
+	defer t.Stop()
+	for {
+		var r *Receipt
+		if err := b.rc.CallContext(ctx, &r, "eth_getUserOperationReceipt", userOpHash); err != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("eth_getUserOperationReceipt: %w", err)
+		}
+		if r != nil {
+			return r, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("userop %s not included: %w", userOpHash.Hex(), ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// Cost is the receipt's actualGasCost in wei (0 when missing).
+func (r *Receipt) Cost() *big.Int {
+	if r == nil || r.ActualGasCost == nil {
+		return new(big.Int)
+	}
+	return r.ActualGasCost.ToInt()
+}
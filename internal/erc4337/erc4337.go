@@ -0,0 +1,265 @@
+// Package erc4337 handles victims that are ERC-4337 smart accounts rather than EOAs: the
+// leaked key is the account's owner, so neither a 7702 authorization nor a raw transfer
+// from the key's address moves the assets. Instead the owner signs a UserOperation whose
+// callData is the account's execute(dest, value, func) wrapping transfer(SAFE, balance),
+// and a bundler submits it through the EntryPoint.
+//
+// Supported are SimpleAccount-style accounts (SimpleAccount, LightAccount, Biconomy and
+// most forks): entryPoint() returns EntryPoint v0.6 or v0.7, execute(address,uint256,bytes)
+// runs calls, and the signature is the owner's EIP-191 signature of the userOpHash.
+package erc4337
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// EntryPoint versions.
+const (
+	V06 = "v0.6"
+	V07 = "v0.7"
+)
+
+// EntryPoints are the canonical EntryPoint deployments (same address on every chain).
+var EntryPoints = map[common.Address]string{
+	common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"): V06,
+	common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032"): V07,
+}
+
+var (
+	selEntryPoint = common.FromHex("0xb0d691fe") // entryPoint()
+	selOwner      = common.FromHex("0x8da5cb5b") // owner()
+	selExecute    = common.FromHex("0xb61d27f6") // execute(address,uint256,bytes)
+	selGetNonce   = common.FromHex("0x35567e1a") // getNonce(address,uint192)
+	selBalanceOf  = common.FromHex("0x70a08231") // balanceOf(address): EntryPoint deposit
+	selDepositTo  = common.FromHex("0xb760faf9") // depositTo(address)
+	selTransfer   = common.FromHex("0xa9059cbb") // transfer(address,uint256)
+)
+
+// ErrNotAccount is returned by Detect when the address is not a supported smart account.
+var ErrNotAccount = errors.New("not an ERC-4337 account")
+
+// Account is a detected smart account.
+type Account struct {
+	Address    common.Address
+	EntryPoint common.Address
+	Version    string         // V06 | V07
+	Owner      common.Address // owner(); zero when the account does not expose it
+}
+
+// Detect checks that addr is a deployed smart account bound to a known EntryPoint. A 7702
+// delegated EOA (code 0xef0100…) is not one: its own key still signs for it.
+func Detect(ctx context.Context, ec *ethclient.Client, addr common.Address) (Account, error) {
+	a := Account{Address: addr}
+	code, err := ec.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return a, err
+	}
+	switch {
+	case len(code) == 0:
+		return a, fmt.Errorf("%w: no code at %s", ErrNotAccount, addr.Hex())
+	case len(code) == 23 && code[0] == 0xef && code[1] == 0x01 && code[2] == 0x00:
+		return a, fmt.Errorf("%w: %s is a 7702-delegated EOA", ErrNotAccount, addr.Hex())
+	}
+	out, err := ec.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: selEntryPoint}, nil)
+	if err != nil || len(out) < 32 {
+		return a, fmt.Errorf("%w: %s has no entryPoint()", ErrNotAccount, addr.Hex())
+	}
+	a.EntryPoint = common.BytesToAddress(out[:32])
+	v, ok := EntryPoints[a.EntryPoint]
+	if !ok {
+		return a, fmt.Errorf("%w: unknown EntryPoint %s", ErrNotAccount, a.EntryPoint.Hex())
+	}
+	a.Version = v
+	if out, err := ec.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: selOwner}, nil); err == nil && len(out) >= 32 {
+		a.Owner = common.BytesToAddress(out[:32])
+	}
+	return a, nil
+}
+
+// CheckOwner reports whether key can sign for the account: owner() must be key when the
+// account exposes it (accounts without owner() are left to the bundler's validation).
+func (a Account) CheckOwner(key common.Address) error {
+	if a.Owner != (common.Address{}) && a.Owner != key {
+		return fmt.Errorf("key %s is not the owner of %s (owner %s)", key.Hex(), a.Address.Hex(), a.Owner.Hex())
+	}
+	return nil
+}
+
+// Nonce is the account's EntryPoint nonce (key 0).
+func (a Account) Nonce(ctx context.Context, ec *ethclient.Client) (*big.Int, error) {
+	data := append(append([]byte{}, selGetNonce...), common.LeftPadBytes(a.Address.Bytes(), 32)...)
+	data = append(data, make([]byte, 32)...)
+	out, err := ec.CallContract(ctx, ethereum.CallMsg{To: &a.EntryPoint, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getNonce: %w", err)
+	}
+	if len(out) < 32 {
+		return nil, errors.New("getNonce: short return")
+	}
+	return new(big.Int).SetBytes(out[:32]), nil
+}
+
+// Funds is what pays the UserOperation's gas: the account's EntryPoint deposit plus its
+// native balance (the account tops up the deposit during validation).
+func (a Account) Funds(ctx context.Context, ec *ethclient.Client) (*big.Int, error) {
+	bal, err := ec.BalanceAt(ctx, a.Address, nil)
+	if err != nil {
+		return nil, err
+	}
+	data := append(append([]byte{}, selBalanceOf...), common.LeftPadBytes(a.Address.Bytes(), 32)...)
+	out, err := ec.CallContract(ctx, ethereum.CallMsg{To: &a.EntryPoint, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("EntryPoint balanceOf: %w", err)
+	}
+	if len(out) >= 32 {
+		bal.Add(bal, new(big.Int).SetBytes(out[:32]))
+	}
+	return bal, nil
+}
+
+// DepositToCalldata is EntryPoint.depositTo(account): a third party (SAFE) prefunding the
+// account's gas.
+func (a Account) DepositToCalldata() []byte {
+	return append(append([]byte{}, selDepositTo...), common.LeftPadBytes(a.Address.Bytes(), 32)...)
+}
+
+// TransferCallData is execute(token, 0, transfer(to, amount)).
+func TransferCallData(token, to common.Address, amount *big.Int) []byte {
+	inner := append(append([]byte{}, selTransfer...), common.LeftPadBytes(to.Bytes(), 32)...)
+	inner = append(inner, common.LeftPadBytes(amount.Bytes(), 32)...)
+	return ExecuteCallData(token, new(big.Int), inner)
+}
+
+// ExecuteCallData is execute(dest, value, func).
+func ExecuteCallData(dest common.Address, value *big.Int, fn []byte) []byte {
+	data := append(append([]byte{}, selExecute...), common.LeftPadBytes(dest.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(value.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(96).Bytes(), 32)...) // offset of func
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(fn))).Bytes(), 32)...)
+	data = append(data, fn...)
+	if pad := len(fn) % 32; pad != 0 {
+		data = append(data, make([]byte, 32-pad)...)
+	}
+	return data
+}
+
+// SimulateExecute runs callData on the account as the EntryPoint would call it (eth_call
+// from the EntryPoint): ok=false with the revert when the account or the token refuses.
+func (a Account) SimulateExecute(ctx context.Context, ec *ethclient.Client, callData []byte) (ok bool, why string, err error) {
+	_, err = ec.CallContract(ctx, ethereum.CallMsg{From: a.EntryPoint, To: &a.Address, Data: callData}, nil)
+	if err == nil {
+		return true, "", nil
+	}
+	var de interface{ ErrorData() interface{} }
+	if errors.As(err, &de) {
+		return false, "userop execute reverted: " + err.Error(), nil
+	}
+	return false, "", err
+}
+
+// UserOp is a UserOperation without paymaster or initCode (the account already exists and
+// pays its own gas).
+type UserOp struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	Signature            []byte
+}
+
+// DummySignature is a well-formed ECDSA signature for gas estimation: validation runs the
+// recovery without reverting, it just fails to match the owner.
+var DummySignature = common.FromHex("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+// Prefund is the most the EntryPoint charges the account: every gas limit at MaxFeePerGas.
+func (op *UserOp) Prefund() *big.Int {
+	gas := new(big.Int).Add(op.CallGasLimit, op.VerificationGasLimit)
+	gas.Add(gas, op.PreVerificationGas)
+	return gas.Mul(gas, op.MaxFeePerGas)
+}
+
+// Hash is the userOpHash the owner signs: keccak(abi.encode(keccak(pack(op)), entryPoint, chainId)).
+func (op *UserOp) Hash(entryPoint common.Address, version string, chainID *big.Int) common.Hash {
+	word := func(x *big.Int) []byte { return common.LeftPadBytes(x.Bytes(), 32) }
+	empty := crypto.Keccak256(nil) // initCode, paymasterAndData
+	packed := append([]byte{}, common.LeftPadBytes(op.Sender.Bytes(), 32)...)
+	packed = append(packed, word(op.Nonce)...)
+	packed = append(packed, empty...)
+	packed = append(packed, crypto.Keccak256(op.CallData)...)
+	if version == V07 {
+		// accountGasLimits = verificationGasLimit<<128 | callGasLimit; gasFees = maxPriorityFee<<128 | maxFee
+		packed = append(packed, pack128(op.VerificationGasLimit, op.CallGasLimit)...)
+		packed = append(packed, word(op.PreVerificationGas)...)
+		packed = append(packed, pack128(op.MaxPriorityFeePerGas, op.MaxFeePerGas)...)
+	} else {
+		packed = append(packed, word(op.CallGasLimit)...)
+		packed = append(packed, word(op.VerificationGasLimit)...)
+		packed = append(packed, word(op.PreVerificationGas)...)
+		packed = append(packed, word(op.MaxFeePerGas)...)
+		packed = append(packed, word(op.MaxPriorityFeePerGas)...)
+	}
+	packed = append(packed, empty...)
+	enc := append(crypto.Keccak256(packed), common.LeftPadBytes(entryPoint.Bytes(), 32)...)
+	enc = append(enc, word(chainID)...)
+	return crypto.Keccak256Hash(enc)
+}
+
+func pack128(hi, lo *big.Int) []byte {
+	out := make([]byte, 32)
+	hi.FillBytes(out[:16])
+	lo.FillBytes(out[16:])
+	return out
+}
+
+// Sign sets the owner's signature: EIP-191 personal_sign of the userOpHash, v = 27/28.
+func (op *UserOp) Sign(owner *ecdsa.PrivateKey, entryPoint common.Address, version string, chainID *big.Int) error {
+	h := op.Hash(entryPoint, version, chainID)
+	sig, err := crypto.Sign(accounts.TextHash(h.Bytes()), owner)
+	if err != nil {
+		return err
+	}
+	sig[64] += 27
+	op.Signature = sig
+	return nil
+}
+
+// rpcObject is the UserOperation in the bundler RPC layout of version.
+func (op *UserOp) rpcObject(version string) map[string]any {
+	q := func(x *big.Int) string {
+		if x == nil {
+			return "0x0"
+		}
+		return hexutil.EncodeBig(x)
+	}
+	m := map[string]any{
+		"sender":               op.Sender,
+		"nonce":                q(op.Nonce),
+		"callData":             hexutil.Bytes(op.CallData),
+		"callGasLimit":         q(op.CallGasLimit),
+		"verificationGasLimit": q(op.VerificationGasLimit),
+		"preVerificationGas":   q(op.PreVerificationGas),
+		"maxFeePerGas":         q(op.MaxFeePerGas),
+		"maxPriorityFeePerGas": q(op.MaxPriorityFeePerGas),
+		"signature":            hexutil.Bytes(op.Signature),
+	}
+	if version == V06 {
+		m["initCode"] = "0x"
+		m["paymasterAndData"] = "0x"
+	}
+	return m
+}
@@ -0,0 +1,150 @@
+// Package errhelp is the central error catalog: it maps the failure reasons the tools print
+// (batchcli reason codes, bundlecore Result reasons, relay and RPC errors) to a short,
+// actionable explanation and the env vars / flags that change the outcome. batchcli,
+// bundlecli and the GUI append these hints to their output, so operators do not have to
+// look up what "rpc_rate_limited" or "no relay accepted" means.
+package errhelp
+
+import (
+	"strings"
+)
+
+// Entry is one catalog record.
+type Entry struct {
+	Code  string   // stable catalog code (the BAD verdicts' own codes are in internal/reasons)
+	Match []string // lowercase substrings of the message that select this entry
+	Text  string   // what happened and what to do, one or two sentences
+	Knobs []string // env vars / flags to look at
+	Doc   string   // README section, "" = none
+}
+
+// Catalog is checked in order: the first entry with a matching substring wins, so the
+// specific messages come before the generic ones.
+var Catalog = []Entry{
+	{
+		Code:  "run_locked",
+		Match: []string{"is in use by", "run lock"},
+		Text:  "Another GUI/CLI run is using the same SAFE; parallel runs collide on SAFE nonces. Wait for it to finish.",
+		Knobs: []string{"ALLOW_CONCURRENT_SAFE=1 / --allow-concurrent (only if you are sure)", "RUN_LOCK_DIR"},
+		Doc:   "SAFE run lock",
+	},
+	{
+		Code:  "insufficient_safe_balance",
+		Match: []string{"insufficient safe balance"},
+		Text:  "SAFE cannot pay the bundle gas plus FROM's prefund at the current fees. Top up the SAFE address or lower the fee settings.",
+		Knobs: []string{"SAFE_PRIVATE_KEY (fund its address)", "TIP_GWEI", "BASEFEE_MUL", "BUFFER_PCT"},
+	},
+	{
+		Code:  "insufficient_eth_simulation",
+		Match: []string{"insufficient eth for simulation", "insufficient funds for gas"},
+		Text:  "The simulated sender has too little ETH for the gas of the bundle. Fund SAFE, or lower the fees used for the simulation.",
+		Knobs: []string{"SAFE_PRIVATE_KEY (fund its address)", "TIP_GWEI", "BASEFEE_MUL"},
+	},
+	{
+		Code:  "no_relay_accepted",
+		Match: []string{"no relay accepted", "no relays"},
+		Text:  "Every relay rejected or ignored the submission. Check the http= and body= of each relay line above: wrong URL, missing auth, or a bundle the relay refuses.",
+		Knobs: []string{"RELAYS", "FLASHBOTS_AUTH_PK", "BLOXROUTE_AUTH_HEADER / BLOXROUTE_API_KEY", "BUILDERS"},
+	},
+	{
+		Code:  "simulation_unsupported",
+		Match: []string{"simulation not supported by relay"},
+		Text:  "This relay does not offer eth_callBundle. Add a relay that simulates (e.g. relay.flashbots.net) to RELAYS, or run without simulation.",
+		Knobs: []string{"RELAYS"},
+	},
+	{
+		Code:  "not_included",
+		Match: []string{"exhausted attempts", "not included"},
+		Text:  "The bundle was sent but no builder included it within the block window. Raise the tip, try for more blocks, or add builders.",
+		Knobs: []string{"BLOCKS", "TIP_GWEI", "TIP_MUL", "BUILDERS", "RELAYS"},
+	},
+	{
+		Code:  "competing_nonce",
+		Match: []string{"competing nonce"},
+		Text:  "FROM's nonce changed during the run: a sweeper bot or another tool sent a transaction from FROM. Re-run; a more aggressive tip helps to win the next block.",
+		Knobs: []string{"COMPETE_BUMP_PCT", "TIP_GWEI", "TIP_MUL"},
+	},
+	{
+		Code:  "rpc_rate_limited",
+		Match: []string{"rpc_rate_limited", "[rate_limit]", "too many requests", "-32005", "http 429"},
+		Text:  "The RPC provider is throttling requests. Slow the scan down or spread it over more endpoints.",
+		Knobs: []string{"-rpc-delay-ms / BATCH_RPC_DELAY_MS", "-workers / BATCH_WORKERS", "BATCH_RPC_MAX_CONCURRENCY", "RPC_URL (comma-separated failover list)"},
+		Doc:   "RPC failover (batchcli)",
+	},
+	{
+		Code:  "rpc_timeout",
+		Match: []string{"rpc_timeout", "context deadline exceeded", "i/o timeout"},
+		Text:  "RPC calls ran out of time. The endpoint is slow or overloaded: give the calls more time or let batchcli adapt the timeouts.",
+		Knobs: []string{"-pair-timeout-ms / BATCH_PAIR_TIMEOUT_MS", "-preflight-attempt-timeout-ms", "-adaptive-timeout / BATCH_ADAPTIVE_TIMEOUT", "RPC_URL"},
+		Doc:   "Adaptive timeouts (batchcli)",
+	},
+	{
+		Code:  "rpc_unavailable",
+		Match: []string{"rpc_unavailable", "rpc unreachable", "connection refused", "no such host", "network/dns error", "dial tcp"},
+		Text:  "The RPC endpoint cannot be reached. Check the URL, the API key in it, and the network; a second endpoint gives failover.",
+		Knobs: []string{"RPC_URL / -rpc"},
+	},
+	{
+		Code:  "blocked",
+		Match: []string{"blocked:", "token restricted", "token paused"},
+		Text:  "The token itself refuses the transfer for FROM (blacklist, pause, trading limits). Fees or relays will not help; check the token contract on the explorer.",
+		Knobs: []string{"-pair-logs (batchcli: see which check failed)"},
+	},
+}
+
+// Lookup returns the first catalog entry matching msg.
+func Lookup(msg string) (Entry, bool) {
+	m := strings.ToLower(msg)
+	if strings.TrimSpace(m) == "" {
+		return Entry{}, false
+	}
+	for _, e := range Catalog {
+		for _, s := range e.Match {
+			if strings.Contains(m, s) {
+				return e, true
+			}
+		}
+	}
+	return Entry{}, false
+}
+
+// ByCode returns the entry with the given code.
+func ByCode(code string) (Entry, bool) {
+	for _, e := range Catalog {
+		if e.Code == code {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// ExplainCode explains a failure that carries a code (bundlecore Result.Code, the run outcome
+// codes share the catalog's names where both exist): the entry of that code, else the one
+// msg matches as in Explain.
+func ExplainCode(code, msg string) []string {
+	if e, ok := ByCode(code); ok && code != "" {
+		return e.Lines()
+	}
+	return Explain(msg)
+}
+
+// Lines renders e as output lines: the explanation, then the knobs and the README section.
+func (e Entry) Lines() []string {
+	out := []string{"hint: " + e.Text}
+	if len(e.Knobs) > 0 {
+		out = append(out, "      settings: "+strings.Join(e.Knobs, ", "))
+	}
+	if e.Doc != "" {
+		out = append(out, `      see README "`+e.Doc+`"`)
+	}
+	return out
+}
+
+// Explain is Lookup(msg).Lines(), or nil when the catalog has nothing for msg.
+func Explain(msg string) []string {
+	e, ok := Lookup(msg)
+	if !ok {
+		return nil
+	}
+	return e.Lines()
+}
@@ -0,0 +1,45 @@
+// Package exitcode lists the process exit codes shared by batchcli and bundlecli,
+// so scripts and CI can tell outcomes apart without parsing output.
+package exitcode
+
+import "errors"
+
+const (
+	OK          = 0 // every pair succeeded
+	Failure     = 1 // unexpected error
+	Partial     = 3 // run finished but some pairs failed / were rejected
+	Config      = 4 // bad flags/env/input files (nothing was attempted)
+	RPC         = 5 // RPC endpoint unreachable
+	Budget      = 6 // SAFE balance / spend budget exceeded
+	Locked      = 7 // another run holds the SAFE (run lock), nothing was sent
+	Interrupted = 8 // stopped by SIGINT/SIGTERM; batchcli wrote a resume checkpoint
+)
+
+// Error tags err with the exit code the process should end with.
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap returns err tagged with code (nil stays nil).
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the code carried by err: OK for nil, Failure when untagged.
+func Of(err error) int {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Failure
+}
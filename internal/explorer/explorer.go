@@ -0,0 +1,138 @@
+// Package explorer builds block-explorer links (transaction, address, token) for a chain ID.
+// Well-known public explorers are built in; self-hosted or custom ones are configured with
+// EXPLORER_URLS ("31337=http://localhost:4000,1=https://eth.blockscout.com": Etherscan/
+// Blockscout-style paths under a base URL) or EXPLORER_CONFIG (a JSON file with full URL
+// templates). Unknown chains yield "" so callers simply print nothing.
+package explorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Templates are URL templates with {hash}, {address} and {token} placeholders.
+type Templates struct {
+	Tx      string `json:"tx"`
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+// FromBase returns the Etherscan/Blockscout path layout under base.
+func FromBase(base string) Templates {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	return Templates{Tx: base + "/tx/{hash}", Address: base + "/address/{address}", Token: base + "/token/{token}"}
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Templates{
+		"1":        FromBase("https://etherscan.io"),
+		"11155111": FromBase("https://sepolia.etherscan.io"),
+		"17000":    FromBase("https://holesky.etherscan.io"),
+		"560048":   FromBase("https://hoodi.etherscan.io"),
+		"10":       FromBase("https://optimistic.etherscan.io"),
+		"56":       FromBase("https://bscscan.com"),
+		"100":      FromBase("https://gnosisscan.io"),
+		"137":      FromBase("https://polygonscan.com"),
+		"8453":     FromBase("https://basescan.org"),
+		"42161":    FromBase("https://arbiscan.io"),
+		"43114":    FromBase("https://snowtrace.io"),
+		"59144":    FromBase("https://lineascan.build"),
+		"534352":   FromBase("https://scrollscan.com"),
+	}
+)
+
+// Set registers (or replaces) the templates for a chain.
+func Set(chainID string, t Templates) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[strings.TrimSpace(chainID)] = t
+}
+
+// Lookup returns the templates for a chain.
+func Lookup(chainID string) (Templates, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	t, ok := registry[strings.TrimSpace(chainID)]
+	return t, ok
+}
+
+// LoadEnv applies EXPLORER_URLS and then EXPLORER_CONFIG (which wins). Call once at startup.
+func LoadEnv() error {
+	for _, kv := range strings.Split(os.Getenv("EXPLORER_URLS"), ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		id, base, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(id) == "" || strings.TrimSpace(base) == "" {
+			return fmt.Errorf("EXPLORER_URLS: %q is not chainId=baseURL", kv)
+		}
+		Set(id, FromBase(base))
+	}
+	path := strings.TrimSpace(os.Getenv("EXPLORER_CONFIG"))
+	if path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("EXPLORER_CONFIG: %w", err)
+	}
+	return LoadJSON(b)
+}
+
+// LoadJSON registers explorers from {"<chainId>": {"base": "..."} | {"tx": "...", "address": "...", "token": "..."}}.
+// Missing templates fall back to the base layout (or the built-in explorer).
+func LoadJSON(b []byte) error {
+	var m map[string]struct {
+		Base string `json:"base"`
+		Templates
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("explorer config: %w", err)
+	}
+	for id, e := range m {
+		t, _ := Lookup(id)
+		if e.Base != "" {
+			t = FromBase(e.Base)
+		}
+		if e.Tx != "" {
+			t.Tx = e.Tx
+		}
+		if e.Address != "" {
+			t.Address = e.Address
+		}
+		if e.Token != "" {
+			t.Token = e.Token
+		}
+		Set(id, t)
+	}
+	return nil
+}
+
+// Tx is the transaction link, "" when the chain has no explorer.
+func Tx(chainID, hash string) string {
+	t, _ := Lookup(chainID)
+	return fill(t.Tx, "{hash}", hash)
+}
+
+// Address is the account link.
+func Address(chainID, addr string) string {
+	t, _ := Lookup(chainID)
+	return fill(t.Address, "{address}", addr)
+}
+
+// Token is the token page link.
+func Token(chainID, token string) string {
+	t, _ := Lookup(chainID)
+	return fill(t.Token, "{token}", token)
+}
+
+func fill(tmpl, key, v string) string {
+	if tmpl == "" || strings.TrimSpace(v) == "" {
+		return ""
+	}
+	return strings.ReplaceAll(tmpl, key, strings.TrimSpace(v))
+}
@@ -0,0 +1,6 @@
+//go:build cgo
+
+package features
+
+// CGO reports whether the binary was built with cgo.
+const CGO = true
@@ -0,0 +1,29 @@
+package features
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ligun0805/bundle-rescue/internal/exitcode"
+)
+
+// Command is the `version [--features]` subcommand of tool; it returns the exit code.
+func Command(tool string, args []string) int {
+	fs := flag.NewFlagSet(tool+" version", flag.ContinueOnError)
+	withFeatures := fs.Bool("features", false, "also list the optional subsystems and whether this build has them")
+	if err := fs.Parse(args); err != nil {
+		return exitcode.Config
+	}
+	fmt.Println(VersionLine(tool))
+	if *withFeatures {
+		for _, l := range Lines() {
+			fmt.Println(l)
+		}
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "%s version: unexpected argument %q\n", tool, fs.Arg(0))
+		return exitcode.Config
+	}
+	return exitcode.OK
+}
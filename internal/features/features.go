@@ -0,0 +1,89 @@
+// Package features lists the optional subsystems compiled into a binary. The CLIs build
+// without cgo for any GOOS/GOARCH (CGO_ENABLED=0 GOOS=linux GOARCH=arm64 go build
+// ./cmd/batchcli); subsystems that need cgo or build tags register themselves here as
+// available or not, so `version --features` says what a given binary can do instead of
+// a run failing halfway.
+package features
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+)
+
+// Feature is one optional subsystem.
+type Feature struct {
+	Name      string
+	Available bool
+	Detail    string // what it provides, or how to get it when unavailable
+}
+
+var (
+	mu  sync.Mutex
+	all = map[string]Feature{}
+)
+
+// Register records f (the last registration of a name wins). Packages call it from init.
+func Register(f Feature) {
+	mu.Lock()
+	all[f.Name] = f
+	mu.Unlock()
+}
+
+// List is every registered feature, by name.
+func List() []Feature {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]Feature, 0, len(all))
+	for _, f := range all {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Available reports whether the named feature is registered and available.
+func Available(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return all[name].Available
+}
+
+// VersionLine is "tool <version> (<go version> <os>/<arch>, cgo on|off)".
+func VersionLine(tool string) string {
+	c := "off"
+	if CGO {
+		c = "on"
+	}
+	return fmt.Sprintf("%s %s (%s %s/%s, cgo %s)", tool, runmanifest.BinaryVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH, c)
+}
+
+// Lines renders List, one "  [+] name  detail" / "  [-] name  detail" line per feature.
+func Lines() []string {
+	fs := List()
+	width := 0
+	for _, f := range fs {
+		width = max(width, len(f.Name))
+	}
+	out := make([]string, 0, len(fs))
+	for _, f := range fs {
+		mark := "-"
+		if f.Available {
+			mark = "+"
+		}
+		out = append(out, fmt.Sprintf("  [%s] %-*s  %s", mark, width, f.Name, f.Detail))
+	}
+	return out
+}
+
+func init() {
+	// go-ethereum signs and recovers with libsecp256k1 under cgo, with a pure-Go port otherwise.
+	if CGO {
+		Register(Feature{Name: "secp256k1", Available: true, Detail: "libsecp256k1 (cgo)"})
+	} else {
+		Register(Feature{Name: "secp256k1", Available: true, Detail: "pure Go (decred); same results, slower signing"})
+	}
+}
@@ -0,0 +1,143 @@
+// Package keyref replaces raw private keys in shared CSVs with HMAC fingerprints
+// and maps them back to keys at execution time.
+//
+// A fingerprint is "kfp:" + hex(HMAC-SHA256(secret, key))[:32]. Without the secret
+// it cannot be linked to a key; with the secret and the key file it is resolved back.
+package keyref
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Prefix marks a fingerprint cell in CSVs.
+const Prefix = "kfp:"
+
+// normalize returns the key as lower-case hex without 0x, or "" if it is not a private key.
+func normalize(s string) string {
+	h := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X"))
+	if len(h) != 64 {
+		return ""
+	}
+	if _, err := hex.DecodeString(h); err != nil {
+		return ""
+	}
+	return h
+}
+
+// IsPrivateKey reports whether s looks like a 32-byte hex private key.
+func IsPrivateKey(s string) bool { return normalize(s) != "" }
+
+// IsFingerprint reports whether s is a fingerprint produced by Fingerprint.
+func IsFingerprint(s string) bool { return strings.HasPrefix(strings.TrimSpace(s), Prefix) }
+
+// Fingerprint returns the HMAC fingerprint of privHex under secret.
+func Fingerprint(secret []byte, privHex string) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("empty fingerprint secret")
+	}
+	h := normalize(privHex)
+	if h == "" {
+		return "", errors.New("not a private key")
+	}
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(h))
+	return Prefix + hex.EncodeToString(m.Sum(nil))[:32], nil
+}
+
+// Address derives the EOA address of privHex.
+func Address(privHex string) (common.Address, error) {
+	k, err := crypto.HexToECDSA(normalize(privHex))
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(k.PublicKey), nil
+}
+
+// Index maps fingerprints back to private keys.
+type Index map[string]string
+
+// LoadIndex reads every private-key-looking token from path (one per line, or any
+// comma/semicolon/space separated CSV such as the original input) and indexes it.
+func LoadIndex(path string, secret []byte) (Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx := Index{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.FieldsFunc(sc.Text(), func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t'
+		})
+		for _, c := range fields {
+			if !IsPrivateKey(c) {
+				continue
+			}
+			fp, err := Fingerprint(secret, c)
+			if err != nil {
+				return nil, err
+			}
+			idx[fp] = "0x" + normalize(c)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Resolve returns the private key for a fingerprint cell; raw keys pass through unchanged.
+func (idx Index) Resolve(cell string) (string, error) {
+	cell = strings.TrimSpace(cell)
+	if !IsFingerprint(cell) {
+		return cell, nil
+	}
+	if k, ok := idx[cell]; ok {
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown key fingerprint %s (missing in key file or wrong secret)", cell)
+}
+
+// RedactRow replaces private keys in row with fingerprints. When the row does not
+// already carry the derived address next to the key, it is inserted right after it,
+// so "token,privateKey" becomes "token,kfp:...,from".
+func RedactRow(secret []byte, row []string) ([]string, error) {
+	out := make([]string, 0, len(row)+1)
+	for i, c := range row {
+		if !IsPrivateKey(c) {
+			out = append(out, c)
+			continue
+		}
+		fp, err := Fingerprint(secret, c)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := Address(c)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fp)
+		hasAddr := false
+		for _, other := range row {
+			if strings.EqualFold(strings.TrimSpace(other), addr.Hex()) {
+				hasAddr = true
+				break
+			}
+		}
+		if !hasAddr && (i+1 >= len(row) || !common.IsHexAddress(strings.TrimSpace(row[i+1]))) {
+			out = append(out, addr.Hex())
+		}
+	}
+	return out, nil
+}
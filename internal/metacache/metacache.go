@@ -0,0 +1,147 @@
+// Package metacache persists the static metadata of tokens (decimals, symbol, proxy
+// implementation, EIP-2612 support) across batchcli runs (-cache metadata.db). Repeated scans
+// of the same token set with -recheck-balances-only read these from the cache and spend RPC
+// calls only on balances and preflights. The file is JSON, rewritten atomically on Save.
+package metacache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is the static metadata of one token on one chain.
+type Entry struct {
+	ChainID  string `json:"chainId"`
+	Address  string `json:"address"`
+	Decimals int    `json:"decimals"` // only stored when decimals() answered
+	Symbol   string `json:"symbol,omitempty"`
+	Proxy    string `json:"proxy,omitempty"`  // EIP-1967 implementation the getters were read from
+	Permit   string `json:"permit,omitempty"` // yes | no | "" (not probed)
+	Updated  string `json:"updated"`
+}
+
+// Cache is the loaded file. Safe for concurrent use.
+type Cache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*Entry // key: chainId:address (lower-case)
+	hits    int
+	stored  int
+}
+
+type file struct {
+	Updated string   `json:"updated"`
+	Tokens  []*Entry `json:"tokens"`
+}
+
+// Load reads path; a missing file is an empty cache.
+func Load(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: map[string]*Entry{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("metadata cache %s: %w", path, err)
+	}
+	for _, e := range f.Tokens {
+		c.entries[key(e.ChainID, e.Address)] = e
+	}
+	return c, nil
+}
+
+func key(chainID, addr string) string {
+	return strings.ToLower(strings.TrimSpace(chainID)) + ":" + strings.ToLower(strings.TrimSpace(addr))
+}
+
+// Lookup returns a copy of the cached entry and counts the hit.
+func (c *Cache) Lookup(chainID, addr string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key(chainID, addr)]
+	if !ok {
+		return Entry{}, false
+	}
+	c.hits++
+	return *e, true
+}
+
+// Put stores e, replacing the decimals/symbol/proxy of an existing entry. Permit is kept
+// when e does not carry one (it is probed later in the pair check, see SetPermit).
+func (c *Cache) Put(e Entry) {
+	if strings.TrimSpace(e.Address) == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := key(e.ChainID, e.Address)
+	if old := c.entries[k]; old != nil && e.Permit == "" {
+		e.Permit = old.Permit
+	}
+	e.Address = strings.ToLower(e.Address)
+	e.Updated = time.Now().UTC().Format(time.RFC3339)
+	c.entries[k] = &e
+	c.stored++
+}
+
+// SetPermit records the EIP-2612 probe result of a cached token (no-op if not cached).
+func (c *Cache) SetPermit(chainID, addr, permit string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[key(chainID, addr)]; e != nil && permit != "" {
+		e.Permit = permit
+	}
+}
+
+// Stats returns the number of cached tokens, lookups answered and entries stored since Load.
+func (c *Cache) Stats() (total, hits, stored int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.hits, c.stored
+}
+
+// Save writes the cache back (temp file + rename, so a crash never truncates it).
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	out := make([]*Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		cp := *e
+		out = append(out, &cp)
+	}
+	c.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ChainID != out[j].ChainID {
+			return out[i].ChainID < out[j].ChainID
+		}
+		return out[i].Address < out[j].Address
+	})
+	b, err := json.MarshalIndent(file{Updated: time.Now().UTC().Format(time.RFC3339), Tokens: out}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".metacache_*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
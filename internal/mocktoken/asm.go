@@ -0,0 +1,127 @@
+package mocktoken
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// opcode is an EVM opcode; only the ones the mock needs (avoids pulling core/vm into the build).
+type opcode byte
+
+const (
+	opADD          opcode = 0x01
+	opMUL          opcode = 0x02
+	opSUB          opcode = 0x03
+	opDIV          opcode = 0x04
+	opLT           opcode = 0x10
+	opEQ           opcode = 0x14
+	opSHR          opcode = 0x1c
+	opKECCAK256    opcode = 0x20
+	opCALLER       opcode = 0x33
+	opCALLDATALOAD opcode = 0x35
+	opMSTORE       opcode = 0x52
+	opSLOAD        opcode = 0x54
+	opSSTORE       opcode = 0x55
+	opJUMPI        opcode = 0x57
+	opJUMPDEST     opcode = 0x5b
+	opPUSH1        opcode = 0x60
+	opPUSH2        opcode = 0x61
+	opDUP1         opcode = 0x80
+	opDUP2         opcode = 0x81
+	opDUP3         opcode = 0x82
+	opSWAP1        opcode = 0x90
+	opLOG3         opcode = 0xa3
+	opRETURN       opcode = 0xf3
+	opREVERT       opcode = 0xfd
+)
+
+// asm is a tiny EVM assembler: opcodes, minimal pushes and opPUSH2 label references
+// resolved in bytes().
+type asm struct {
+	code   []byte
+	labels map[string]int
+	refs   map[int]string // offset of a opPUSH2 operand -> label
+}
+
+func newAsm() *asm { return &asm{labels: map[string]int{}, refs: map[int]string{}} }
+
+func (a *asm) op(ops ...opcode) *asm {
+	for _, o := range ops {
+		a.code = append(a.code, byte(o))
+	}
+	return a
+}
+
+func (a *asm) push(v uint64) *asm { return a.pushBytes(new(big.Int).SetUint64(v).Bytes()) }
+
+func (a *asm) pushBytes(b []byte) *asm {
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	if len(b) > 32 {
+		panic("push > 32 bytes")
+	}
+	a.code = append(a.code, byte(opPUSH1)+byte(len(b)-1))
+	a.code = append(a.code, b...)
+	return a
+}
+
+func (a *asm) pushLabel(l string) *asm {
+	a.code = append(a.code, byte(opPUSH2))
+	a.refs[len(a.code)] = l
+	a.code = append(a.code, 0, 0)
+	return a
+}
+
+func (a *asm) label(l string) *asm {
+	a.labels[l] = len(a.code)
+	return a.op(opJUMPDEST)
+}
+
+// arg pushes the i-th 32-byte calldata argument.
+func (a *asm) arg(i int) *asm { return a.push(uint64(4 + 32*i)).op(opCALLDATALOAD) }
+
+// mapSlot: [key] -> [keccak(key . slot)]
+func (a *asm) mapSlot(slot uint64) *asm {
+	a.push(0).op(opMSTORE)
+	a.push(slot).push(0x20).op(opMSTORE)
+	return a.push(0x40).push(0).op(opKECCAK256)
+}
+
+// nestedSlot: [outer, key] -> [keccak(key . outer)]
+func (a *asm) nestedSlot() *asm {
+	a.push(0).op(opMSTORE)
+	a.push(0x20).op(opMSTORE)
+	return a.push(0x40).push(0).op(opKECCAK256)
+}
+
+// retWord returns the top of the stack as one ABI word.
+func (a *asm) retWord() *asm {
+	a.push(0).op(opMSTORE)
+	return a.push(0x20).push(0).op(opRETURN)
+}
+
+// retString returns s ABI-encoded as a dynamic string (s <= 32 bytes).
+func (a *asm) retString(s string) *asm {
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	word := make([]byte, 32)
+	copy(word, s)
+	a.push(0x20).push(0).op(opMSTORE)
+	a.push(uint64(len(s))).push(0x20).op(opMSTORE)
+	a.pushBytes(word).push(0x40).op(opMSTORE)
+	return a.push(0x60).push(0).op(opRETURN)
+}
+
+func (a *asm) bytes() []byte {
+	for off, l := range a.refs {
+		dst, ok := a.labels[l]
+		if !ok {
+			panic(fmt.Sprintf("mocktoken: undefined label %q", l))
+		}
+		binary.BigEndian.PutUint16(a.code[off:], uint16(dst))
+	}
+	return a.code
+}
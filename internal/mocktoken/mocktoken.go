@@ -0,0 +1,162 @@
+// Package mocktoken builds a minimal ERC-20 runtime with switchable "hostile" behaviours
+// (pause, blacklist, fee-on-transfer) and installs it on a local dev node (anvil/hardhat)
+// for rehearsals. It is NOT a production token: no transferFrom, no events besides Transfer.
+//
+// Storage layout (Solidity-style mappings, keccak256(key . slot)):
+//
+//	0  balances[address]
+//	1  allowances[owner][spender]
+//	2  paused (bool)
+//	3  fee in basis points, burned from every transfer
+//	4  blacklist[address]
+//	5  totalSupply
+package mocktoken
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	slotBalances   = 0
+	slotAllowances = 1
+	slotPaused     = 2
+	slotFeeBps     = 3
+	slotBlacklist  = 4
+	slotSupply     = 5
+)
+
+// Config describes one mock token.
+type Config struct {
+	Name      string
+	Symbol    string
+	Decimals  uint8
+	Paused    bool
+	FeeBps    uint16           // 0..10000, burned on transfer
+	Blacklist []common.Address // transfer reverts when from or to is listed
+	// HideViews drops paused()/isBlacklisted(address), so restrictions are only
+	// visible through a reverting transfer (what preflight has to catch).
+	HideViews bool
+}
+
+// Runtime returns the deployed bytecode for cfg.
+func Runtime(cfg Config) []byte {
+	a := newAsm()
+	type entry struct{ sig, label string }
+	entries := []entry{
+		{"balanceOf(address)", "balanceOf"},
+		{"transfer(address,uint256)", "transfer"},
+		{"decimals()", "decimals"},
+		{"symbol()", "symbol"},
+		{"name()", "name"},
+		{"totalSupply()", "totalSupply"},
+		{"allowance(address,address)", "allowance"},
+		{"approve(address,uint256)", "approve"},
+	}
+	if !cfg.HideViews {
+		entries = append(entries, entry{"paused()", "paused"}, entry{"isBlacklisted(address)", "isBlacklisted"})
+	}
+
+	// dispatcher: selector = calldata[0:4]
+	a.push(0).op(opCALLDATALOAD).push(0xe0).op(opSHR)
+	for _, e := range entries {
+		a.op(opDUP1).pushBytes(crypto.Keccak256([]byte(e.sig))[:4]).op(opEQ).pushLabel(e.label).op(opJUMPI)
+	}
+	a.label("revert").push(0).op(opDUP1, opREVERT)
+
+	a.label("balanceOf").arg(0).mapSlot(slotBalances).op(opSLOAD).retWord()
+	a.label("decimals").push(uint64(cfg.Decimals)).retWord()
+	a.label("totalSupply").push(slotSupply).op(opSLOAD).retWord()
+	a.label("paused").push(slotPaused).op(opSLOAD).retWord()
+	a.label("isBlacklisted").arg(0).mapSlot(slotBlacklist).op(opSLOAD).retWord()
+	a.label("symbol").retString(cfg.Symbol)
+	a.label("name").retString(cfg.Name)
+
+	// allowance(owner, spender) = keccak(spender . keccak(owner . 1))
+	a.label("allowance").arg(0).mapSlot(slotAllowances).arg(1).nestedSlot().op(opSLOAD).retWord()
+	// approve(spender, amount): allowances[caller][spender] = amount
+	a.label("approve").op(opCALLER).mapSlot(slotAllowances).arg(0).nestedSlot()
+	a.arg(1).op(opSWAP1, opSSTORE).push(1).retWord()
+
+	// transfer(to, amount)
+	a.label("transfer")
+	a.push(slotPaused).op(opSLOAD).pushLabel("revert").op(opJUMPI)
+	a.op(opCALLER).mapSlot(slotBlacklist).op(opSLOAD).pushLabel("revert").op(opJUMPI)
+	a.arg(0).mapSlot(slotBlacklist).op(opSLOAD).pushLabel("revert").op(opJUMPI)
+	a.arg(1)                                                                        // [amt]
+	a.op(opCALLER).mapSlot(slotBalances)                                            // [amt, fromSlot]
+	a.op(opDUP1, opSLOAD)                                                           // [amt, fromSlot, fromBal]
+	a.op(opDUP3, opDUP2, opLT).pushLabel("revert").op(opJUMPI)                      // fromBal < amt
+	a.op(opDUP3, opSWAP1, opSUB)                                                    // [amt, fromSlot, fromBal-amt]
+	a.op(opSWAP1, opSSTORE)                                                         // [amt]
+	a.op(opDUP1).push(slotFeeBps).op(opSLOAD, opMUL).push(10000).op(opSWAP1, opDIV) // [amt, fee]
+	a.op(opSWAP1, opSUB)                                                            // [net]
+	a.arg(0).mapSlot(slotBalances)                                                  // [net, toSlot]
+	a.op(opDUP2, opDUP2, opSLOAD, opADD)                                            // [net, toSlot, toBal+net]
+	a.op(opSWAP1, opSSTORE)                                                         // [net]
+	a.push(0).op(opMSTORE)
+	a.arg(0).op(opCALLER).pushBytes(crypto.Keccak256([]byte("Transfer(address,address,uint256)")))
+	a.push(0x20).push(0).op(opLOG3)
+	a.push(1).retWord()
+
+	return a.bytes()
+}
+
+// Storage returns the initial storage for cfg with the given balances.
+func Storage(cfg Config, balances map[common.Address]*big.Int) map[common.Hash]common.Hash {
+	out := map[common.Hash]common.Hash{}
+	supply := new(big.Int)
+	for who, v := range balances {
+		out[mappingSlot(who, slotBalances)] = common.BigToHash(v)
+		supply.Add(supply, v)
+	}
+	out[common.BigToHash(big.NewInt(slotSupply))] = common.BigToHash(supply)
+	if cfg.Paused {
+		out[common.BigToHash(big.NewInt(slotPaused))] = common.BigToHash(big.NewInt(1))
+	}
+	if cfg.FeeBps > 0 {
+		out[common.BigToHash(big.NewInt(slotFeeBps))] = common.BigToHash(big.NewInt(int64(cfg.FeeBps)))
+	}
+	for _, b := range cfg.Blacklist {
+		out[mappingSlot(b, slotBlacklist)] = common.BigToHash(big.NewInt(1))
+	}
+	return out
+}
+
+// Install puts the mock at addr on a dev node via anvil_setCode / anvil_setStorageAt.
+// Public RPCs reject these methods, so a rehearsal can never touch a real chain.
+func Install(ctx context.Context, rc *rpc.Client, addr common.Address, cfg Config, balances map[common.Address]*big.Int) error {
+	if cfg.FeeBps > 10000 {
+		return fmt.Errorf("fee %d bps > 10000", cfg.FeeBps)
+	}
+	if err := rc.CallContext(ctx, nil, "anvil_setCode", addr, hexutil.Bytes(Runtime(cfg))); err != nil {
+		return fmt.Errorf("anvil_setCode: %w", err)
+	}
+	for slot, val := range Storage(cfg, balances) {
+		if err := rc.CallContext(ctx, nil, "anvil_setStorageAt", addr, slot, val); err != nil {
+			return fmt.Errorf("anvil_setStorageAt: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetBalance sets the ETH balance of who on the dev node.
+func SetBalance(ctx context.Context, rc *rpc.Client, who common.Address, wei *big.Int) error {
+	return rc.CallContext(ctx, nil, "anvil_setBalance", who, (*hexutil.Big)(wei))
+}
+
+// Address is a deterministic token address per rehearsal name (same name = same address).
+func Address(name string) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte("bundle-rescue/rehearse/" + strings.ToLower(name)))[12:])
+}
+
+func mappingSlot(key common.Address, slot int64) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(key.Bytes(), 32), common.LeftPadBytes(big.NewInt(slot).Bytes(), 32))
+}
@@ -0,0 +1,270 @@
+package mocktoken
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	token = Address("test")
+	alice = common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	bob   = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	carol = common.HexToAddress("0x00000000000000000000000000000000000ca401")
+
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// chain is a bare EVM state with the mock installed the way Install does it.
+type chain struct {
+	t  *testing.T
+	st *state.StateDB
+}
+
+func deploy(t *testing.T, cfg Config, balances map[common.Address]*big.Int) *chain {
+	t.Helper()
+	st, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetCode(token, Runtime(cfg))
+	for slot, val := range Storage(cfg, balances) {
+		st.SetState(token, slot, val)
+	}
+	return &chain{t: t, st: st}
+}
+
+// call runs sig(args...) from caller; a revert is returned as vm.ErrExecutionReverted.
+func (c *chain) call(from common.Address, sig string, args ...any) ([]byte, error) {
+	c.t.Helper()
+	input := crypto.Keccak256([]byte(sig))[:4]
+	for _, a := range args {
+		switch v := a.(type) {
+		case common.Address:
+			input = append(input, common.LeftPadBytes(v.Bytes(), 32)...)
+		case int64:
+			input = append(input, common.LeftPadBytes(big.NewInt(v).Bytes(), 32)...)
+		default:
+			c.t.Fatalf("unsupported argument %T", a)
+		}
+	}
+	ret, _, err := runtime.Call(token, input, &runtime.Config{State: c.st, Origin: from})
+	return ret, err
+}
+
+// word calls a view returning one uint256.
+func (c *chain) word(sig string, args ...any) int64 {
+	c.t.Helper()
+	ret, err := c.call(alice, sig, args...)
+	if err != nil {
+		c.t.Fatalf("%s: %v", sig, err)
+	}
+	if len(ret) != 32 {
+		c.t.Fatalf("%s: %d bytes returned, want 32", sig, len(ret))
+	}
+	return new(big.Int).SetBytes(ret).Int64()
+}
+
+func (c *chain) balance(who common.Address) int64 { return c.word("balanceOf(address)", who) }
+
+// transfer runs transfer(to, amount) from and returns the Transfer logs it emitted.
+func (c *chain) transfer(from, to common.Address, amount int64) ([]*types.Log, error) {
+	c.t.Helper()
+	before := len(c.st.Logs())
+	ret, err := c.call(from, "transfer(address,uint256)", to, amount)
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).SetBytes(ret).Int64() != 1 || len(ret) != 32 {
+		c.t.Fatalf("transfer returned %x, want true", ret)
+	}
+	return c.st.Logs()[before:], nil
+}
+
+func funded(amounts ...int64) map[common.Address]*big.Int {
+	return map[common.Address]*big.Int{alice: big.NewInt(amounts[0]), bob: big.NewInt(amounts[1])}
+}
+
+func TestViews(t *testing.T) {
+	cfg := Config{Name: "Mock Token", Symbol: "MOCK", Decimals: 6, Blacklist: []common.Address{carol}}
+	c := deploy(t, cfg, funded(1000, 5))
+	if got := c.balance(alice); got != 1000 {
+		t.Errorf("balanceOf(alice) = %d, want 1000", got)
+	}
+	if got := c.balance(carol); got != 0 {
+		t.Errorf("balanceOf(carol) = %d, want 0", got)
+	}
+	if got := c.word("decimals()"); got != 6 {
+		t.Errorf("decimals() = %d, want 6", got)
+	}
+	if got := c.word("totalSupply()"); got != 1005 {
+		t.Errorf("totalSupply() = %d, want 1005", got)
+	}
+	if got := c.word("paused()"); got != 0 {
+		t.Errorf("paused() = %d, want 0", got)
+	}
+	if got := c.word("isBlacklisted(address)", carol); got != 1 {
+		t.Errorf("isBlacklisted(carol) = %d, want 1", got)
+	}
+	if got := c.word("isBlacklisted(address)", bob); got != 0 {
+		t.Errorf("isBlacklisted(bob) = %d, want 0", got)
+	}
+	for sig, want := range map[string]string{"name()": "Mock Token", "symbol()": "MOCK"} {
+		ret, err := c.call(alice, sig)
+		if err != nil {
+			t.Fatalf("%s: %v", sig, err)
+		}
+		if len(ret) != 96 || new(big.Int).SetBytes(ret[:32]).Int64() != 32 {
+			t.Fatalf("%s: not an ABI string: %x", sig, ret)
+		}
+		n := new(big.Int).SetBytes(ret[32:64]).Int64()
+		if got := string(ret[64 : 64+n]); got != want {
+			t.Errorf("%s = %q, want %q", sig, got, want)
+		}
+	}
+	if _, err := c.call(alice, "transferFrom(address,address,uint256)", alice, bob, int64(1)); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("unknown selector: err = %v, want a revert", err)
+	}
+}
+
+func TestHideViews(t *testing.T) {
+	c := deploy(t, Config{Paused: true, HideViews: true}, funded(1000, 0))
+	for _, sig := range []string{"paused()", "isBlacklisted(address)"} {
+		if _, err := c.call(alice, sig, alice); !errors.Is(err, vm.ErrExecutionReverted) {
+			t.Errorf("%s with HideViews: err = %v, want a revert", sig, err)
+		}
+	}
+	if _, err := c.transfer(alice, bob, 1); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("hidden pause: transfer err = %v, want a revert", err)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	c := deploy(t, Config{}, funded(1000, 5))
+	logs, err := c.transfer(alice, bob, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := c.balance(alice), c.balance(bob); a != 750 || b != 255 {
+		t.Errorf("balances after transfer = %d/%d, want 750/255", a, b)
+	}
+	checkTransferLog(t, logs, alice, bob, 250)
+
+	// the whole balance, then one unit too many
+	if _, err := c.transfer(alice, carol, 750); err != nil {
+		t.Fatalf("transfer of the whole balance: %v", err)
+	}
+	if _, err := c.transfer(alice, carol, 1); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("transfer above the balance: err = %v, want a revert", err)
+	}
+	if a, cb := c.balance(alice), c.balance(carol); a != 0 || cb != 750 {
+		t.Errorf("balances after the revert = %d/%d, want 0/750", a, cb)
+	}
+}
+
+func TestTransferFee(t *testing.T) {
+	c := deploy(t, Config{FeeBps: 500}, funded(1000, 0))
+	logs, err := c.transfer(alice, bob, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5% of 1000 is burned: FROM loses the full amount, TO and the log get the net
+	if a, b := c.balance(alice), c.balance(bob); a != 0 || b != 950 {
+		t.Errorf("balances after a 5%% fee transfer = %d/%d, want 0/950", a, b)
+	}
+	checkTransferLog(t, logs, alice, bob, 950)
+
+	// the fee rounds down
+	c = deploy(t, Config{FeeBps: 500}, funded(19, 0))
+	if _, err := c.transfer(alice, bob, 19); err != nil {
+		t.Fatal(err)
+	}
+	if b := c.balance(bob); b != 19 {
+		t.Errorf("19 units at 5%%: TO got %d, want 19 (fee 0.95 rounds to 0)", b)
+	}
+}
+
+func TestPaused(t *testing.T) {
+	c := deploy(t, Config{Paused: true}, funded(1000, 0))
+	if got := c.word("paused()"); got != 1 {
+		t.Errorf("paused() = %d, want 1", got)
+	}
+	logs := len(c.st.Logs())
+	if _, err := c.transfer(alice, bob, 1); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("paused transfer: err = %v, want a revert", err)
+	}
+	if a := c.balance(alice); a != 1000 {
+		t.Errorf("balance after the paused transfer = %d, want 1000", a)
+	}
+	if n := len(c.st.Logs()); n != logs {
+		t.Errorf("a reverted transfer left %d log(s)", n-logs)
+	}
+}
+
+func TestBlacklist(t *testing.T) {
+	c := deploy(t, Config{Blacklist: []common.Address{carol}}, map[common.Address]*big.Int{
+		alice: big.NewInt(1000), carol: big.NewInt(1000),
+	})
+	if _, err := c.transfer(carol, alice, 1); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("transfer from a listed address: err = %v, want a revert", err)
+	}
+	if _, err := c.transfer(alice, carol, 1); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("transfer to a listed address: err = %v, want a revert", err)
+	}
+	if _, err := c.transfer(alice, bob, 1); err != nil {
+		t.Errorf("transfer between unlisted addresses: %v", err)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	c := deploy(t, Config{}, funded(1000, 0))
+	ret, err := c.call(alice, "approve(address,uint256)", bob, int64(77))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret) != 32 || new(big.Int).SetBytes(ret).Int64() != 1 {
+		t.Errorf("approve returned %x, want true", ret)
+	}
+	if got := c.word("allowance(address,address)", alice, bob); got != 77 {
+		t.Errorf("allowance(alice, bob) = %d, want 77", got)
+	}
+	if got := c.word("allowance(address,address)", bob, alice); got != 0 {
+		t.Errorf("allowance(bob, alice) = %d, want 0", got)
+	}
+	// Solidity layout: allowances[owner][spender] = keccak(spender . keccak(owner . 1))
+	outer := mappingSlot(alice, slotAllowances)
+	slot := crypto.Keccak256Hash(common.LeftPadBytes(bob.Bytes(), 32), outer.Bytes())
+	if got := c.st.GetState(token, slot).Big().Int64(); got != 77 {
+		t.Errorf("allowance storage slot = %d, want 77", got)
+	}
+}
+
+func checkTransferLog(t *testing.T, logs []*types.Log, from, to common.Address, amount int64) {
+	t.Helper()
+	if len(logs) != 1 {
+		t.Fatalf("%d logs, want 1 Transfer", len(logs))
+	}
+	l := logs[0]
+	if l.Address != token {
+		t.Errorf("log address = %s, want the token", l.Address.Hex())
+	}
+	want := []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}
+	if len(l.Topics) != 3 {
+		t.Fatalf("log topics = %v, want Transfer(from, to)", l.Topics)
+	}
+	for i := range want {
+		if l.Topics[i] != want[i] {
+			t.Errorf("log topic %d = %s, want %s", i, l.Topics[i].Hex(), want[i].Hex())
+		}
+	}
+	if len(l.Data) != 32 || new(big.Int).SetBytes(l.Data).Int64() != amount {
+		t.Errorf("log data = %x, want %d", l.Data, amount)
+	}
+}
@@ -0,0 +1,189 @@
+// Package operators holds the local operator accounts of a shared rescue workstation: a
+// name and a salted scrypt hash of its password per operator, in one JSON file
+// (operators.json). The GUI logs operators in against it (OPERATOR_AUTH=password) and
+// records who imported, approved and executed each pair in its job store.
+package operators
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// DefaultFile is the accounts file name (inside the profile directory with --profile).
+const DefaultFile = "operators.json"
+
+// MinPassword is the shortest password Add accepts.
+const MinPassword = 8
+
+const (
+	scryptN = 1 << 15 // ~50 ms per login attempt
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+	saltLen = 16
+)
+
+// ErrLogin is returned by Verify for an unknown name or a wrong password (not told apart).
+var ErrLogin = errors.New("unknown operator or wrong password")
+
+// Account is one operator. Salt and Hash are hex.
+type Account struct {
+	Name    string    `json:"name"`
+	Salt    string    `json:"salt"`
+	Hash    string    `json:"hash"`
+	Created time.Time `json:"created"`
+}
+
+// Store is the accounts file.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	accounts map[string]Account // lower-case name -> account
+}
+
+// Load reads path; a missing file is an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, accounts: map[string]Account{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("operators %s: %w", path, err)
+	}
+	var file struct {
+		Operators []Account `json:"operators"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("operators %s: %w", path, err)
+	}
+	for _, a := range file.Operators {
+		s.accounts[strings.ToLower(a.Name)] = a
+	}
+	return s, nil
+}
+
+// Path is the file the store was loaded from.
+func (s *Store) Path() string { return s.path }
+
+// Names lists the operators, sorted.
+func (s *Store) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0, len(s.accounts))
+	for _, a := range s.accounts {
+		out = append(out, a.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Add creates an operator; an existing name is an error (Remove it first).
+func (s *Store) Add(name, password string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t\r\n,;") {
+		return fmt.Errorf("operator name %q: no spaces, commas or semicolons", name)
+	}
+	if len(password) < MinPassword {
+		return fmt.Errorf("password: at least %d characters", MinPassword)
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.accounts[strings.ToLower(name)]; dup {
+		return fmt.Errorf("operator %q already exists", name)
+	}
+	s.accounts[strings.ToLower(name)] = Account{Name: name, Salt: hex.EncodeToString(salt), Hash: hex.EncodeToString(key), Created: time.Now().UTC()}
+	return nil
+}
+
+// Remove deletes an operator.
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := strings.ToLower(strings.TrimSpace(name))
+	if _, ok := s.accounts[k]; !ok {
+		return fmt.Errorf("no operator %q", name)
+	}
+	delete(s.accounts, k)
+	return nil
+}
+
+// Verify checks a login and returns the operator name as stored.
+func (s *Store) Verify(name, password string) (string, error) {
+	s.mu.Lock()
+	a, ok := s.accounts[strings.ToLower(strings.TrimSpace(name))]
+	s.mu.Unlock()
+	if !ok {
+		return "", ErrLogin
+	}
+	salt, err1 := hex.DecodeString(a.Salt)
+	want, err2 := hex.DecodeString(a.Hash)
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("operator %q: corrupted entry in %s", a.Name, s.path)
+	}
+	key, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, len(want))
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare(key, want) != 1 {
+		return "", ErrLogin
+	}
+	return a.Name, nil
+}
+
+// Save writes the store atomically, readable by the owner only.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	list := make([]Account, 0, len(s.accounts))
+	for _, a := range s.accounts {
+		list = append(list, a)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	b, err := json.MarshalIndent(struct {
+		Operators []Account `json:"operators"`
+	}{list}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// OSUser is the identity of the logged-in OS account (DOMAIN\user on Windows), "" when
+// it cannot be determined.
+func OSUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, k := range []string{"USER", "USERNAME"} {
+		if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
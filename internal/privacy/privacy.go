@@ -0,0 +1,100 @@
+// Package privacy is the "privacy display" switch: when on, balances and amounts shown in
+// the GUI and on the console are replaced by their order of magnitude ("~1K–10K"), so a run
+// can be demoed or done on a shared screen. Only display paths call it: CSV/JSON reports,
+// manifests and job history keep full precision.
+package privacy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+var on atomic.Bool
+
+// Enable switches masking on or off for the process.
+func Enable(v bool) { on.Store(v) }
+
+// Enabled reports whether amounts are masked.
+func Enabled() bool { return on.Load() }
+
+// Amount masks a displayed amount when privacy display is on, otherwise returns s unchanged.
+// A trailing unit is kept: "12.5 ETH" -> "~10–100 ETH".
+func Amount(s string) string {
+	if !Enabled() {
+		return s
+	}
+	num, unit, _ := strings.Cut(strings.TrimSpace(s), " ")
+	b := Bucket(num)
+	if unit != "" {
+		b += " " + unit
+	}
+	return b
+}
+
+// Bucket returns the decimal order of magnitude of a non-negative decimal string:
+// "0", "~0.01–0.1", "~1–10", "~1K–10K", ... and "<0.001" below that. Unparseable input
+// becomes "•••", so nothing leaks through.
+func Bucket(num string) string {
+	num = strings.ReplaceAll(strings.TrimSpace(num), ",", "")
+	num = strings.TrimPrefix(num, "-")
+	intPart, frac, _ := strings.Cut(num, ".")
+	if intPart == "" && frac == "" || strings.Trim(intPart+frac, "0123456789") != "" {
+		return "•••"
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	var e int
+	switch {
+	case intPart != "":
+		e = len(intPart) - 1
+	case strings.Trim(frac, "0") == "":
+		return "0"
+	default:
+		e = -(len(frac) - len(strings.TrimLeft(frac, "0")) + 1)
+	}
+	if e < -3 {
+		return "<0.001"
+	}
+	return "~" + pow10(e) + "–" + pow10(e+1)
+}
+
+func pow10(e int) string {
+	switch {
+	case e < 0:
+		return "0." + strings.Repeat("0", -e-1) + "1"
+	case e < 3:
+		return "1" + strings.Repeat("0", e)
+	case e < 6:
+		return "1" + strings.Repeat("0", e-3) + "K"
+	case e < 9:
+		return "1" + strings.Repeat("0", e-6) + "M"
+	case e < 12:
+		return "1" + strings.Repeat("0", e-9) + "B"
+	case e < 15:
+		return "1" + strings.Repeat("0", e-12) + "T"
+	}
+	return fmt.Sprintf("1e%d", e)
+}
+
+var (
+	reUnit  = regexp.MustCompile(`\b\d[\d,]*(?:\.\d+)?(\s*(?:ETH|wei|tokens))\b`)
+	reField = regexp.MustCompile(`(?i)\b((?:balance|amount)\w*\s*[=:]\s*)(\d[\d,]*(?:\.\d+)?)`)
+)
+
+// Line masks amounts inside a free-form log line: numbers followed by ETH/wei/tokens and
+// values of balance=/amount...= fields. Gas prices (gwei), blocks and nonces stay visible.
+func Line(s string) string {
+	if !Enabled() {
+		return s
+	}
+	// Units first: a masked "~0.01–0.1 ETH" must not be matched again as "0.1 ETH".
+	s = reUnit.ReplaceAllStringFunc(s, func(m string) string {
+		p := reUnit.FindStringSubmatch(m)
+		return Bucket(strings.TrimSuffix(m, p[1])) + p[1]
+	})
+	return reField.ReplaceAllStringFunc(s, func(m string) string {
+		p := reField.FindStringSubmatch(m)
+		return p[1] + Bucket(p[2])
+	})
+}
@@ -0,0 +1,170 @@
+// Package profile selects a named environment (--profile clientA-mainnet) instead of the
+// single .env in the working directory. A profile is a directory under PROFILES_DIR
+// (default "profiles") with its own .env, optional .env.local and optional batchcli.toml.
+// With a profile active the root .env is NOT read, so a setting missing from the profile
+// cannot silently come from another client's file.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Profile is the active profile; nil means the plain .env / .env.local setup.
+type Profile struct {
+	Name string
+	Dir  string
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Extract finds --profile NAME / --profile=NAME (one or two dashes) in args and returns the
+// name and args without it, so subcommand flag sets never see the flag. Falls back to the
+// PROFILE environment variable.
+func Extract(args []string) (name string, rest []string) {
+	name = strings.TrimSpace(os.Getenv("PROFILE"))
+	rest = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		flagName := strings.TrimLeft(a, "-")
+		if flagName == a {
+			rest = append(rest, a)
+			continue
+		}
+		if v, ok := strings.CutPrefix(flagName, "profile="); ok {
+			name = strings.TrimSpace(v)
+			continue
+		}
+		if flagName == "profile" && i+1 < len(args) {
+			name = strings.TrimSpace(args[i+1])
+			i++
+			continue
+		}
+		rest = append(rest, a)
+	}
+	return name, rest
+}
+
+// Load reads the environment of profile name: <dir>/.env (without overriding variables
+// already set in the shell) and <dir>/.env.local (overriding), like the root files.
+// An empty name loads the root .env / .env.local and returns nil.
+func Load(name string) (*Profile, error) {
+	if name == "" {
+		_ = godotenv.Load()
+		_ = godotenv.Overload(".env.local")
+		return nil, nil
+	}
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("profile %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	root := strings.TrimSpace(os.Getenv("PROFILES_DIR"))
+	if root == "" {
+		root = "profiles"
+	}
+	p := &Profile{Name: name, Dir: filepath.Join(root, name)}
+	env := filepath.Join(p.Dir, ".env")
+	if _, err := os.Stat(env); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("profile %q: %s not found (profiles: %s)", name, env, strings.Join(List(), ", "))
+		}
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	if err := godotenv.Load(env); err != nil {
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	if local := filepath.Join(p.Dir, ".env.local"); fileExists(local) {
+		if err := godotenv.Overload(local); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	os.Setenv("PROFILE", name) // child processes and later lookups see the same profile
+	return p, nil
+}
+
+// List returns the profile names under PROFILES_DIR (directories with a .env).
+func List() []string {
+	root := strings.TrimSpace(os.Getenv("PROFILES_DIR"))
+	if root == "" {
+		root = "profiles"
+	}
+	entries, _ := os.ReadDir(root)
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && fileExists(filepath.Join(root, e.Name(), ".env")) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
+
+// Path returns name inside the profile directory, or name itself without a profile:
+// per-profile state files (GUI session, batchcli.toml) stay apart.
+func (p *Profile) Path(name string) string {
+	if p == nil {
+		return name
+	}
+	return filepath.Join(p.Dir, name)
+}
+
+// File returns name inside the profile directory when that file exists, "" otherwise
+// (and always without a profile).
+func (p *Profile) File(name string) string {
+	if p == nil || !fileExists(filepath.Join(p.Dir, name)) {
+		return ""
+	}
+	return filepath.Join(p.Dir, name)
+}
+
+// Label is the profile name for banners and titles ("" without a profile).
+func (p *Profile) Label() string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
+
+// Banner is the line CLIs print at startup.
+func (p *Profile) Banner() string {
+	if p == nil {
+		return ""
+	}
+	s := "=== PROFILE: " + p.Name + " (" + p.Dir + ")"
+	if c := expectedChain(); c != "" {
+		s += " chain " + c
+	}
+	return s + " ==="
+}
+
+// CheckChain compares the chain the RPC reports with PROFILE_CHAIN_ID of the profile:
+// a mainnet profile pointed at a testnet RPC (or the reverse) is refused.
+func (p *Profile) CheckChain(chainID *big.Int) error {
+	want := expectedChain()
+	if p == nil || want == "" || chainID == nil {
+		return nil
+	}
+	if chainID.String() != want {
+		return fmt.Errorf("profile %q expects chain %s (PROFILE_CHAIN_ID) but the RPC is on chain %s", p.Name, want, chainID)
+	}
+	return nil
+}
+
+func expectedChain() string { return strings.TrimSpace(os.Getenv("PROFILE_CHAIN_ID")) }
+
+func fileExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir()
+}
@@ -0,0 +1,128 @@
+// Package queuefile is the pair queue of bundlegui's session file (pairs_session.json). The
+// GUI keeps its queue in it between runs; batchcli -queue-out merges its OK pairs into the
+// same file, so a scanned list is ready to send without a re-import and a second round of
+// checks.
+package queuefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
+)
+
+// DefaultFile is the GUI session file name (inside the profile directory with --profile).
+const DefaultFile = "pairs_session.json"
+
+// Pair is one queued rescue: FROM's tokens go to To (the SAFE). Amounts are decimal strings,
+// wei and token units; Decimals is -1 when unknown.
+type Pair struct {
+	Token, From, FromPK, To   string
+	AmountWei, AmountTokens   string
+	Decimals                  int
+	BalanceWei, BalanceTokens string
+	Campaign                  string        `json:",omitempty"` // import file name; empty = manual
+	Sources                   []string      `json:",omitempty"` // every import that contributed this (from, token)
+	Warnings                  warnings.List `json:",omitempty"` // soft problems from the scan (batchcli columns) or import
+	ImportedBy, ImportedAt    string        `json:",omitempty"` // operator who queued the pair (bundlegui operator.go)
+}
+
+// Key identifies a pair: (from, token), lower-case.
+func Key(p Pair) string {
+	return strings.ToLower(strings.TrimSpace(p.From)) + "|" + strings.ToLower(strings.TrimSpace(p.Token))
+}
+
+// File is the session file as seen from outside the GUI: the queue, and the GUI's undo
+// trash kept as is.
+type File struct {
+	Pairs []Pair
+	Trash json.RawMessage `json:",omitempty"`
+}
+
+// Read loads path; a missing file is an empty queue. The older bare-array layout is accepted.
+func Read(path string) (File, error) {
+	var f File
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return f, err
+	}
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return f, nil
+	case data[0] == '[':
+		err = json.Unmarshal(data, &f.Pairs)
+	default:
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return f, fmt.Errorf("queue %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Write saves f in the GUI's layout, replacing path atomically.
+func (f File) Write(path string) error {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(f); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Merge adds the pairs not queued yet and refreshes the balances and amounts of the ones that
+// are (the incoming read is newer), as a GUI import does. Pairs are never queued twice.
+func (f *File) Merge(in []Pair) (added, updated int) {
+	idx := make(map[string]int, len(f.Pairs))
+	for i, p := range f.Pairs {
+		idx[Key(p)] = i
+	}
+	for _, p := range in {
+		k := Key(p)
+		j, dup := idx[k]
+		if !dup {
+			f.Pairs = append(f.Pairs, p)
+			idx[k] = len(f.Pairs) - 1
+			added++
+			continue
+		}
+		cur := &f.Pairs[j]
+		cur.BalanceWei, cur.BalanceTokens = p.BalanceWei, p.BalanceTokens
+		cur.AmountWei, cur.AmountTokens = p.AmountWei, p.AmountTokens
+		if cur.Decimals < 0 {
+			cur.Decimals = p.Decimals
+		}
+		if cur.FromPK == "" {
+			cur.FromPK = p.FromPK
+		}
+		cur.Warnings = p.Warnings
+		for _, s := range p.Sources {
+			if !contains(cur.Sources, s) {
+				cur.Sources = append(cur.Sources, s)
+			}
+		}
+		updated++
+	}
+	return added, updated
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+// Package reasons is the reason taxonomy the tools share: every BAD verdict of batchcli (and
+// the token checks bundlecore runs for bundlecli and the GUI) carries one Code next to its
+// human text, so outputs from different commands and runs can be counted by the same key.
+//
+// Codes are stable upper-case identifiers. The text is for people and may change; anything
+// that aggregates, filters or retries goes by the code. Classify derives the code from the
+// text for reasons that were built before a code existed (older output files, helpers that
+// only return a string); Parse also accepts the lower-case codes of schemaVersion 1 files.
+package reasons
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Code is one canonical reason.
+type Code string
+
+const (
+	// input
+	MalformedRow Code = "MALFORMED_ROW"
+	InvalidToken Code = "INVALID_TOKEN"
+	InvalidKey   Code = "INVALID_KEY"
+	WrongChain   Code = "WRONG_CHAIN"   // chain column that cannot be used
+	SmartAccount Code = "SMART_ACCOUNT" // account column that is not an account of the key
+
+	// balance
+	NoBalance Code = "NO_BALANCE"
+
+	// token
+	DeadToken        Code = "DEAD_TOKEN"
+	Paused           Code = "PAUSED"
+	TransferDisabled Code = "TRANSFER_DISABLED"
+	BlacklistedFrom  Code = "BLACKLISTED_FROM"
+	BlacklistedTo    Code = "BLACKLISTED_TO"
+	NotWhitelisted   Code = "NOT_WHITELISTED"
+	Restricted       Code = "RESTRICTED" // blocked by a restriction not listed above
+	NoLiquidity      Code = "NO_LIQUIDITY"
+	TransferHook     Code = "TRANSFER_HOOK"
+	Reverted         Code = "REVERTED"
+	NotTransferable  Code = "NOT_TRANSFERABLE"
+
+	// rpc
+	RPCTimeout     Code = "RPC_TIMEOUT"
+	RPCUnavailable Code = "RPC_UNAVAILABLE"
+	RPCRateLimited Code = "RPC_RATE_LIMITED"
+	RPCError       Code = "RPC_ERROR"
+
+	Other Code = "OTHER"
+)
+
+// All lists the codes in documentation order.
+var All = []Code{
+	MalformedRow, InvalidToken, InvalidKey, WrongChain, SmartAccount,
+	NoBalance,
+	DeadToken, Paused, TransferDisabled, BlacklistedFrom, BlacklistedTo, NotWhitelisted, Restricted,
+	NoLiquidity, TransferHook, Reverted, NotTransferable,
+	RPCTimeout, RPCUnavailable, RPCRateLimited, RPCError,
+	Other,
+}
+
+// Class groups a code by what is at fault: input, balance, token, rpc or other.
+func (c Code) Class() string {
+	switch c {
+	case "":
+		return ""
+	case MalformedRow, InvalidToken, InvalidKey, WrongChain, SmartAccount:
+		return "input"
+	case NoBalance:
+		return "balance"
+	case DeadToken, Paused, TransferDisabled, BlacklistedFrom, BlacklistedTo, NotWhitelisted, Restricted,
+		NoLiquidity, TransferHook, Reverted, NotTransferable:
+		return "token"
+	case RPCTimeout, RPCUnavailable, RPCRateLimited, RPCError:
+		return "rpc"
+	}
+	return "other"
+}
+
+// Transient reports codes that say nothing about the pair: another provider, or the same
+// one later, may well pass it.
+func (c Code) Transient() bool { return c.Class() == "rpc" }
+
+// legacy are the lower-case codes written before this taxonomy (schemaVersion 1).
+var legacy = map[string]Code{
+	"malformed_row": MalformedRow, "invalid_token": InvalidToken, "invalid_key": InvalidKey,
+	"dead_token": DeadToken, "no_balance": NoBalance, "blocked": Restricted,
+	"rpc_timeout": RPCTimeout, "rpc_unavailable": RPCUnavailable, "rpc_rate_limited": RPCRateLimited, "rpc_error": RPCError,
+	"transfer_hook": TransferHook, "reverted": Reverted, "not_transferable": NotTransferable, "other": Other,
+}
+
+// Parse reads a stored code: a current one, or a legacy lower-case one. Unknown text is Other,
+// "" stays "".
+func Parse(s string) Code {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	if c, ok := legacy[s]; ok {
+		return c
+	}
+	c := Code(strings.ToUpper(s))
+	for _, k := range All {
+		if k == c {
+			return c
+		}
+	}
+	return Other
+}
+
+// Classify derives the code of a reason text.
+func Classify(reason string) Code {
+	r := strings.ToLower(strings.TrimSpace(reason))
+	switch {
+	case r == "":
+		return ""
+	case strings.HasPrefix(r, "not enough columns"):
+		return MalformedRow
+	case strings.HasPrefix(r, "chain column"):
+		return WrongChain
+	case r == "invalid token address":
+		return InvalidToken
+	case r == "invalid private key":
+		return InvalidKey
+	case strings.HasPrefix(r, "smart account"):
+		return SmartAccount
+	case strings.HasPrefix(r, "dead token"):
+		return DeadToken
+	case r == "no token balance", r == "no balance":
+		return NoBalance
+	case strings.HasPrefix(r, "blocked:"):
+		return Restriction(strings.TrimPrefix(r, "blocked:"))
+	case strings.HasPrefix(r, "rpc_timeout"):
+		return RPCTimeout
+	case strings.HasPrefix(r, "rpc_unavailable"):
+		return RPCUnavailable
+	case strings.HasPrefix(r, "rpc_rate_limited"):
+		return RPCRateLimited
+	case strings.HasPrefix(r, "rpc_error"), strings.HasPrefix(r, "preflight error"):
+		return RPCError
+	case strings.HasPrefix(r, "transfer hook"):
+		return TransferHook
+	case strings.Contains(r, "no v2 pair"), strings.Contains(r, "no liquidity"), strings.Contains(r, "insufficient_liquidity"):
+		return NoLiquidity
+	case strings.HasPrefix(r, "blocked"):
+		return Restricted
+	case strings.Contains(r, "revert"):
+		return Reverted
+	case strings.HasPrefix(r, "not transferable"), strings.Contains(r, "7702"):
+		return NotTransferable
+	}
+	return Other
+}
+
+// Restriction is the code of a restriction summary (bundlecore TokenRestrictions.Summary:
+// "paused from:blacklisted ..."), the most specific one first.
+func Restriction(summary string) Code {
+	s := strings.ToLower(summary)
+	switch {
+	case strings.Contains(s, "paused"):
+		return Paused
+	case strings.Contains(s, "from:blacklisted"):
+		return BlacklistedFrom
+	case strings.Contains(s, "to:blacklisted"):
+		return BlacklistedTo
+	case strings.Contains(s, "transferdisabled"):
+		return TransferDisabled
+	case strings.Contains(s, "whitelist"):
+		return NotWhitelisted
+	}
+	return Restricted
+}
+
+// Tally counts verdicts by code.
+type Tally map[Code]int
+
+// Add counts one reason ("" is not counted).
+func (t Tally) Add(c Code) {
+	if c != "" {
+		t[c]++
+	}
+}
+
+// Sorted returns the codes most frequent first (ties by code).
+func (t Tally) Sorted() []Code {
+	out := make([]Code, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if t[out[i]] != t[out[j]] {
+			return t[out[i]] > t[out[j]]
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// String renders "NO_BALANCE=12 RPC_TIMEOUT=3", most frequent first.
+func (t Tally) String() string {
+	parts := make([]string, 0, len(t))
+	for _, c := range t.Sorted() {
+		parts = append(parts, string(c)+"="+strconv.Itoa(t[c]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Map is the tally keyed by plain strings (JSON documents).
+func (t Tally) Map() map[string]int {
+	if len(t) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(t))
+	for c, n := range t {
+		out[string(c)] = n
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package relayrecord
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Export writes the run directory dir as a zip for a relay or builder operator: the
+// records, every body they reference and SHA256SUMS over all of it. A body whose content no
+// longer matches the hash in its name fails the export (the record would not prove anything).
+func Export(dir string, w io.Writer) ([]Record, error) {
+	recs, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{RecordsFile}
+	seen := map[string]bool{}
+	for _, r := range recs {
+		for _, b := range []string{r.RequestBody, r.ResponseBody} {
+			if b != "" && !seen[b] {
+				seen[b] = true
+				files = append(files, b)
+			}
+		}
+	}
+	sort.Strings(files[1:])
+
+	zw := zip.NewWriter(w)
+	var sums strings.Builder
+	for _, name := range files {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		h := hex.EncodeToString(sum[:])
+		if name != RecordsFile && strings.TrimSuffix(filepath.Base(name), ".json") != h {
+			return nil, fmt.Errorf("%s: content does not match its hash (%s)", name, h)
+		}
+		fw, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(b); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", h, name)
+	}
+	fw, err := zw.Create("SHA256SUMS")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(fw, sums.String()); err != nil {
+		return nil, err
+	}
+	return recs, zw.Close()
+}
+
+// Runs lists the recorded runs under root, oldest first (none when root does not exist).
+func Runs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(root, e.Name(), RecordsFile)); e.IsDir() && err == nil {
+			out = append(out, e.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Summary renders one line per relay: calls, sends, failures and the time span.
+func Summary(recs []Record) []string {
+	type agg struct {
+		calls, sends, failed int
+		first, last          string
+	}
+	by := map[string]*agg{}
+	var relays []string
+	for _, r := range recs {
+		a := by[r.Relay]
+		if a == nil {
+			a = &agg{first: r.SentAt}
+			by[r.Relay] = a
+			relays = append(relays, r.Relay)
+		}
+		a.calls++
+		if strings.Contains(r.Method, "send") || r.Method == "blxr_submit_bundle" {
+			a.sends++
+		}
+		if r.Error != "" || r.Status >= 400 {
+			a.failed++
+		}
+		if r.SentAt < a.first {
+			a.first = r.SentAt
+		}
+		if r.SentAt > a.last {
+			a.last = r.SentAt
+		}
+	}
+	sort.Strings(relays)
+	out := make([]string, 0, len(relays))
+	for _, u := range relays {
+		a := by[u]
+		out = append(out, fmt.Sprintf("%s: %d call(s), %d submission(s), %d without a 2xx answer, %s .. %s", u, a.calls, a.sends, a.failed, a.first, a.last))
+	}
+	return out
+}
@@ -0,0 +1,295 @@
+// Package relayrecord keeps a dispute record of relay traffic. Every bundle / private-tx
+// call to a relay (eth_sendBundle, mev_sendBundle, their simulations, bloXroute, private
+// transactions) is recorded with the time it left and the time the answer came back, the
+// HTTP status, the headers (values redacted) and the SHA-256 of the exact request and
+// response bodies. The bodies themselves are stored next to the record, named by their
+// hash, so "the relay never received it" can be answered with what was sent, when, and
+// what the relay said.
+//
+// Layout of a run (see Start):
+//
+//	<root>/<runID>/records.ndjson        one Record per call, in the order they finished
+//	<root>/<runID>/bodies/<sha256>.json  request and response bodies
+//
+// The recorder sits in the HTTP transport (see rpcmetrics.Transport) under the chaos
+// injector, so only real network exchanges are recorded. Bodies hold signed transactions,
+// never private keys; authorization headers keep their name only.
+package relayrecord
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one relay call.
+type Record struct {
+	Seq             int               `json:"seq"`
+	SentAt          string            `json:"sentAt"` // RFC3339Nano, UTC
+	ReceivedAt      string            `json:"receivedAt,omitempty"`
+	LatencyMs       int64             `json:"latencyMs"`
+	Relay           string            `json:"relay"` // scheme://host/path (the query may hold keys and is dropped)
+	Method          string            `json:"method"`
+	TargetBlock     string            `json:"targetBlock,omitempty"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
+	RequestSHA256   string            `json:"requestSha256"`
+	RequestBody     string            `json:"requestBody"` // relative to the run directory
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseSHA256  string            `json:"responseSha256,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+	Error           string            `json:"error,omitempty"` // transport error: no answer was received
+}
+
+// RecordsFile is the record file of a run directory.
+const RecordsFile = "records.ndjson"
+
+// Methods are the JSON-RPC methods that are recorded.
+var Methods = map[string]bool{
+	"eth_sendBundle": true, "eth_callBundle": true, "mev_sendBundle": true, "mev_simBundle": true,
+	"eth_sendPrivateTransaction": true, "eth_sendPrivateRawTransaction": true,
+	"blxr_submit_bundle": true, "blxr_simulate_bundle": true,
+}
+
+// keptHeaders are recorded with their value; any other header is recorded as redacted.
+var keptHeaders = map[string]bool{
+	"Content-Type": true, "Content-Length": true, "Accept": true, "User-Agent": true,
+	"Date": true, "Server": true, "X-Request-Id": true, "Cf-Ray": true,
+}
+
+// Recorder appends the records of one run.
+type Recorder struct {
+	dir string
+	mu  sync.Mutex
+	f   *os.File
+	seq int
+}
+
+var (
+	curMu sync.RWMutex
+	cur   *Recorder
+)
+
+func current() *Recorder {
+	curMu.RLock()
+	defer curMu.RUnlock()
+	return cur
+}
+
+// Start opens <root>/<runID> and records all relay calls into it until Stop.
+func Start(root, runID string) (*Recorder, error) {
+	dir := filepath.Join(root, runID)
+	if err := os.MkdirAll(filepath.Join(dir, "bodies"), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, RecordsFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{dir: dir, f: f}
+	curMu.Lock()
+	cur = r
+	curMu.Unlock()
+	return r, nil
+}
+
+// Dir is the run directory.
+func (r *Recorder) Dir() string {
+	if r == nil {
+		return ""
+	}
+	return r.dir
+}
+
+// Count is the number of calls recorded so far.
+func (r *Recorder) Count() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seq
+}
+
+// Stop ends the recording.
+func (r *Recorder) Stop() error {
+	if r == nil {
+		return nil
+	}
+	curMu.Lock()
+	if cur == r {
+		cur = nil
+	}
+	curMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// storeBody writes b as bodies/<sha256>.json (once) and returns its hash and relative path.
+func (r *Recorder) storeBody(b []byte) (string, string) {
+	sum := sha256.Sum256(b)
+	h := hex.EncodeToString(sum[:])
+	rel := filepath.ToSlash(filepath.Join("bodies", h+".json"))
+	path := filepath.Join(r.dir, rel)
+	if _, err := os.Stat(path); err != nil {
+		_ = os.WriteFile(path, b, 0o644)
+	}
+	return h, rel
+}
+
+func (r *Recorder) write(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	rec.Seq = r.seq
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	_, _ = r.f.Write(append(b, '\n')) // unbuffered: a record survives os.Exit
+}
+
+type transport struct{ base http.RoundTripper }
+
+// Wrap records the relay calls going through base (nil = http.DefaultTransport) while a
+// Recorder is started; other requests pass through untouched.
+func Wrap(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := current()
+	if r == nil || req.Body == nil || req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	method, target := callOf(body)
+	if !Methods[method] {
+		return t.base.RoundTrip(req)
+	}
+
+	rec := Record{Relay: relayURL(req.URL), Method: method, TargetBlock: target, RequestHeaders: redact(req.Header)}
+	rec.RequestSHA256, rec.RequestBody = r.storeBody(body)
+	sent := time.Now()
+	rec.SentAt = sent.UTC().Format(time.RFC3339Nano)
+	resp, err := t.base.RoundTrip(req)
+	received := time.Now()
+	rec.LatencyMs = received.Sub(sent).Milliseconds()
+	if err != nil {
+		rec.Error = err.Error()
+		r.write(rec)
+		return nil, err
+	}
+	rec.ReceivedAt = received.UTC().Format(time.RFC3339Nano)
+	rec.Status, rec.ResponseHeaders = resp.StatusCode, redact(resp.Header)
+	raw, rerr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(raw))
+	rec.ResponseSHA256, rec.ResponseBody = r.storeBody(raw)
+	if rerr != nil {
+		rec.Error = "reading the answer: " + rerr.Error()
+	}
+	r.write(rec)
+	return resp, rerr
+}
+
+// callOf returns the method of a single JSON-RPC request and the block it targets
+// (blockNumber / inclusion.block / block_number of the first param), "" when not present.
+func callOf(body []byte) (method, target string) {
+	var m struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if json.Unmarshal(body, &m) != nil {
+		return "", ""
+	}
+	var p struct {
+		BlockNumber  string `json:"blockNumber"`
+		BlockNumber2 string `json:"block_number"`
+		Inclusion    struct {
+			Block string `json:"block"`
+		} `json:"inclusion"`
+	}
+	if len(m.Params) > 0 && json.Unmarshal(m.Params[0], &p) == nil {
+		target = p.BlockNumber
+		if target == "" {
+			target = p.BlockNumber2
+		}
+		if target == "" {
+			target = p.Inclusion.Block
+		}
+	}
+	return m.Method, target
+}
+
+// relayURL drops the query and credentials (API keys travel there).
+func relayURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// redact keeps the values of keptHeaders; X-Flashbots-Signature keeps its signer address,
+// everything else (authorization, API keys, cookies) only its name.
+func redact(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		ck := http.CanonicalHeaderKey(k)
+		val := strings.Join(v, ", ")
+		switch {
+		case keptHeaders[ck]:
+			out[ck] = val
+		case ck == "X-Flashbots-Signature":
+			addr, _, _ := strings.Cut(val, ":")
+			out[ck] = addr + ":<redacted>"
+		default:
+			out[ck] = "<redacted>"
+		}
+	}
+	return out
+}
+
+// Load reads the records of a run directory.
+func Load(dir string) ([]Record, error) {
+	b, err := os.ReadFile(filepath.Join(dir, RecordsFile))
+	if err != nil {
+		return nil, err
+	}
+	var out []Record
+	for i, line := range bytes.Split(b, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", RecordsFile, i+1, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
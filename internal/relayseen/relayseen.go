@@ -0,0 +1,163 @@
+// Package relayseen tracks which relays already saw a payload, so "already known"
+// answers count as delivered and identical payloads are not resubmitted in vain.
+package relayseen
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// knownMarkers are lowercase fragments relays use for duplicate submissions. Only whole
+// phrases: a bare "duplicate" also matches unrelated rejections (a duplicate nonce, duplicate
+// signers), which must not count as delivered.
+var knownMarkers = []string{
+	"already known",
+	"known transaction",
+	"already imported",
+	"bundle already",
+	"already exists",
+	"duplicate bundle",
+	"duplicate transaction",
+	"duplicate tx",
+}
+
+// IsAlreadyKnown reports whether a relay response/error text means "I already have this".
+func IsAlreadyKnown(s string) bool {
+	s = strings.ToLower(s)
+	for _, m := range knownMarkers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ledger remembers (relay, payload key) submissions. The key should include the target
+// block for bundles (relays drop bundles after their block) and the tx hash for private txs.
+//
+// Accepted payloads are not resent under the same key until the entry expires after TTL.
+// "Already known" ones are retried only after a backoff that doubles with every duplicate
+// answer (Base, 2*Base, ... up to Max), in case the relay silently dropped it.
+//
+// A Ledger belongs to one run: relays forget payloads, so a long-lived one would hold back
+// a resend that is needed.
+type Ledger struct {
+	Base time.Duration // default 2s
+	Max  time.Duration // default 30s
+	TTL  time.Duration // default 2m
+
+	mu sync.Mutex
+	m  map[string]*entry
+}
+
+type entry struct {
+	last     time.Time
+	accepted bool
+	known    int
+}
+
+// NewLedger returns an empty Ledger with default backoff.
+func NewLedger() *Ledger { return &Ledger{m: map[string]*entry{}} }
+
+func (l *Ledger) key(relay, payload string) string { return relay + "|" + payload }
+
+// ShouldSend reports whether payload is worth (re)submitting to relay now.
+func (l *Ledger) ShouldSend(relay, payload string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	k := l.key(relay, payload)
+	e := l.m[k]
+	if e == nil {
+		return true
+	}
+	if time.Since(e.last) >= l.ttl() {
+		delete(l.m, k)
+		return true
+	}
+	if e.accepted {
+		return false
+	}
+	return time.Since(e.last) >= l.backoff(e.known)
+}
+
+// Mark records a submission outcome; alreadyKnown=true for duplicate answers.
+func (l *Ledger) Mark(relay, payload string, accepted, alreadyKnown bool) {
+	if l == nil || (!accepted && !alreadyKnown) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.m == nil {
+		l.m = map[string]*entry{}
+	}
+	l.prune()
+	k := l.key(relay, payload)
+	e := l.m[k]
+	if e == nil {
+		e = &entry{}
+		l.m[k] = e
+	}
+	e.last = time.Now()
+	if alreadyKnown {
+		e.known++
+	} else {
+		e.accepted = true
+	}
+}
+
+// Relays returns the relays that saw payload (accepted or already known).
+func (l *Ledger) Relays(payload string) []string {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []string
+	suffix := "|" + payload
+	ttl := l.ttl()
+	for k, e := range l.m {
+		if strings.HasSuffix(k, suffix) && time.Since(e.last) < ttl {
+			out = append(out, strings.TrimSuffix(k, suffix))
+		}
+	}
+	return out
+}
+
+// prune drops expired entries; l.mu is held.
+func (l *Ledger) prune() {
+	ttl := l.ttl()
+	for k, e := range l.m {
+		if time.Since(e.last) >= ttl {
+			delete(l.m, k)
+		}
+	}
+}
+
+func (l *Ledger) ttl() time.Duration {
+	if l.TTL <= 0 {
+		return 2 * time.Minute
+	}
+	return l.TTL
+}
+
+func (l *Ledger) backoff(n int) time.Duration {
+	base, max := l.Base, l.Max
+	if base <= 0 {
+		base = 2 * time.Second
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	d := base
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
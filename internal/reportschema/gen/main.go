@@ -0,0 +1,84 @@
+// Command gen writes schema/*.schema.json from the reportschema types, or with -check
+// verifies them: the committed files must be up to date, and a change that breaks readers
+// (field removed, retyped, no longer required) must come with a reportschema.Version bump.
+//
+//	go run ./internal/reportschema/gen          # regenerate
+//	go run ./internal/reportschema/gen -check   # CI gate, exit 1 on drift or a breaking change
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+)
+
+// documents are the versioned top-level documents by schema file name.
+var documents = map[string]any{
+	"telemetry":   reportschema.TelemetryExport{},
+	"pair_report": reportschema.PairReport{},
+	"run_summary": runmanifest.Manifest{},
+}
+
+func main() {
+	dir := flag.String("dir", "schema", "Directory of the committed *.schema.json files")
+	check := flag.Bool("check", false, "Verify instead of writing: up to date and backward compatible")
+	flag.Parse()
+
+	names := make([]string, 0, len(documents))
+	for n := range documents {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	failed := false
+	for _, name := range names {
+		path := filepath.Join(*dir, name+".schema.json")
+		cur, err := json.MarshalIndent(reportschema.Schema(name, documents[name]), "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, name+":", err)
+			os.Exit(1)
+		}
+		cur = append(cur, '\n')
+		if !*check {
+			if err := os.MkdirAll(*dir, 0o755); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(path, cur, 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Println("wrote", path)
+			continue
+		}
+		old, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, name+":", err)
+			failed = true
+			continue
+		}
+		if bytes.Equal(old, cur) {
+			continue
+		}
+		failed = true
+		fmt.Fprintf(os.Stderr, "%s: out of date, run go run ./internal/reportschema/gen\n", path)
+		var o, c map[string]any
+		if json.Unmarshal(old, &o) != nil || json.Unmarshal(cur, &c) != nil {
+			continue
+		}
+		if v, _ := o["x-schemaVersion"].(float64); int(v) == reportschema.Version {
+			for _, d := range reportschema.Incompatible(o, c) {
+				fmt.Fprintf(os.Stderr, "%s: breaking change without a Version bump: %s\n", path, d)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,144 @@
+package reportschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema derives a JSON Schema (draft 2020-12) from a struct's json tags. Fields without
+// omitempty are required; pointers, slices and maps follow their element type.
+func Schema(title string, v any) map[string]any {
+	s := typeSchema(reflect.TypeOf(v))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = title
+	s["x-schemaVersion"] = Version
+	return s
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = typeSchema(f.Type)
+			if !strings.Contains(","+opts+",", ",omitempty,") {
+				required = append(required, name)
+			}
+		}
+		s := map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			sort.Strings(required)
+			s["required"] = required
+		}
+		return s
+	}
+	return map[string]any{}
+}
+
+// Incompatible lists the changes from old to cur that break readers of old: a property
+// removed or retyped, or a required property becoming optional. Schemas are compared as
+// decoded JSON (map[string]any), so old may be read back from a committed file.
+func Incompatible(old, cur map[string]any) []string {
+	var out []string
+	diffSchema("", old, cur, &out)
+	return out
+}
+
+func diffSchema(path string, old, cur map[string]any, out *[]string) {
+	at := path
+	if at == "" {
+		at = "(root)"
+	}
+	if ot, ct := old["type"], cur["type"]; ot != ct {
+		*out = append(*out, fmt.Sprintf("%s: type %v -> %v", at, ot, ct))
+		return
+	}
+	if oi, ok := old["items"].(map[string]any); ok {
+		ci, _ := cur["items"].(map[string]any)
+		diffSchema(path+"[]", oi, ci, out)
+	}
+	if oa, ok := old["additionalProperties"].(map[string]any); ok {
+		ca, _ := cur["additionalProperties"].(map[string]any)
+		diffSchema(path+"{}", oa, ca, out)
+	}
+	oprops, _ := old["properties"].(map[string]any)
+	cprops, _ := cur["properties"].(map[string]any)
+	names := make([]string, 0, len(oprops))
+	for name := range oprops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := name
+		if path != "" {
+			p = path + "." + name
+		}
+		cp, ok := cprops[name].(map[string]any)
+		if !ok {
+			*out = append(*out, p+": removed")
+			continue
+		}
+		op, _ := oprops[name].(map[string]any)
+		diffSchema(p, op, cp, out)
+	}
+	creq := map[string]bool{}
+	for _, r := range asStrings(cur["required"]) {
+		creq[r] = true
+	}
+	for _, r := range asStrings(old["required"]) {
+		if !creq[r] && cprops[r] != nil {
+			p := r
+			if path != "" {
+				p = path + "." + r
+			}
+			*out = append(*out, p+": no longer required")
+		}
+	}
+}
+
+// asStrings accepts []string (generated) and []any (decoded from JSON).
+func asStrings(v any) []string {
+	switch x := v.(type) {
+	case []string:
+		return x
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, e := range x {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
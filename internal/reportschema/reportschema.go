@@ -0,0 +1,81 @@
+// Package reportschema defines the JSON documents the tools write for downstream tooling
+// (dashboards, log shippers): GUI telemetry exports, batchcli NDJSON pair reports and run
+// summaries (runmanifest.Manifest, which takes Version from here). Every top-level document
+// carries schemaVersion.
+//
+// Evolution rules (checked by `go run ./internal/reportschema/gen -check` against the
+// committed schema/*.schema.json):
+//   - new fields are added as optional (omitempty) — readers of the old schema ignore them;
+//   - existing fields are never renamed, removed or retyped;
+//   - a breaking change bumps Version and regenerates the schemas.
+package reportschema
+
+import "github.com/ligun0805/bundle-rescue/internal/warnings"
+
+// Version is the schemaVersion written into every document of this package.
+// 2: reasonCode holds the upper-case codes of internal/reasons (1: lower-case batchcli codes).
+const Version = 2
+
+// TelemetryItem is one relay/RPC event of the GUI (eth_callBundle result, send verdict).
+type TelemetryItem struct {
+	Time      string `json:"time"` // RFC3339 UTC
+	Action    string `json:"action"`
+	PairIndex int    `json:"pairIndex"`
+	Relay     string `json:"relay,omitempty"`
+	OK        bool   `json:"ok,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Raw       string `json:"raw,omitempty"`
+}
+
+// TelemetryExport is the GUI "Save telemetry" file (log_data/<ts>.json).
+type TelemetryExport struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	GeneratedAt   string          `json:"generatedAt"` // RFC3339 UTC
+	Telemetry     []TelemetryItem `json:"telemetry"`
+	Wallets       []WalletReport  `json:"wallets"`
+}
+
+// WalletReport is one compromised wallet with its tokens in a TelemetryExport.
+type WalletReport struct {
+	Wallet   string        `json:"wallet"`
+	Tokens   []WalletToken `json:"tokens"`
+	Status   string        `json:"status"`
+	ValueETH string        `json:"valueETH,omitempty"` // set when every token has a quote
+	ValueWei string        `json:"valueWei,omitempty"`
+}
+
+// WalletToken is one token row of a WalletReport.
+type WalletToken struct {
+	Token         string             `json:"token"`
+	BalanceTokens string             `json:"balanceTokens"`
+	BalanceWei    string             `json:"balanceWei"`
+	Status        string             `json:"status"`
+	Warnings      []warnings.Warning `json:"warnings,omitempty"`
+}
+
+// PairReport is one NDJSON line of batchcli -format json.
+type PairReport struct {
+	SchemaVersion  int                `json:"schemaVersion"`
+	Line           int                `json:"line"`
+	Verdict        string             `json:"verdict"` // ok | bad | spam
+	Token          string             `json:"token"`
+	PrivateKey     string             `json:"privateKey,omitempty"`
+	From           string             `json:"from,omitempty"`
+	Symbol         string             `json:"symbol,omitempty"`
+	Decimals       *int               `json:"decimals,omitempty"`
+	BalanceWei     string             `json:"balanceWei,omitempty"`
+	BalanceTokens  string             `json:"balanceTokens,omitempty"`
+	USDValue       string             `json:"usdValue,omitempty"`
+	TransferTaxPct string             `json:"transferTaxPct,omitempty"` // simulated fee-on-transfer, "2.50"
+	Permit         string             `json:"permit,omitempty"`         // EIP-2612 permit support: yes | no
+	Route          string             `json:"route,omitempty"`          // rescue route the preflight passed: transfer | sell
+	GasEstimate    uint64             `json:"gasEstimate,omitempty"`    // sponsor gas of the route (-gas-estimate)
+	GasCostWei     string             `json:"gasCostWei,omitempty"`     // gasEstimate x (base fee + tip)
+	ValueWei       string             `json:"valueWei,omitempty"`       // balance quoted in the native coin
+	GasOverValue   string             `json:"gasOverValue,omitempty"`   // yes | no
+	ReasonCode     string             `json:"reasonCode,omitempty"`     // internal/reasons code: NO_BALANCE, PAUSED, RPC_TIMEOUT, ...
+	Reason         string             `json:"reason,omitempty"`
+	SpamReasons    []string           `json:"spamReasons,omitempty"`
+	Warnings       []warnings.Warning `json:"warnings,omitempty"`
+	TimingsMs      map[string]int64   `json:"timingsMs,omitempty"`
+}
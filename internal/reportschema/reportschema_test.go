@@ -0,0 +1,84 @@
+package reportschema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
+	"github.com/ligun0805/bundle-rescue/internal/runmanifest"
+)
+
+// documents mirrors gen's list: the versioned top-level documents by schema file name.
+var documents = map[string]any{
+	"telemetry":   reportschema.TelemetryExport{},
+	"pair_report": reportschema.PairReport{},
+	"run_summary": runmanifest.Manifest{},
+}
+
+// TestCommittedSchemas regenerates every schema and compares it with schema/*.schema.json:
+// a breaking change needs a Version bump, any other drift a regenerated file.
+func TestCommittedSchemas(t *testing.T) {
+	for name, doc := range documents {
+		path := filepath.Join("..", "..", "schema", name+".schema.json")
+		committed, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cur, err := json.MarshalIndent(reportschema.Schema(name, doc), "", "  ")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var o, c map[string]any
+		if err := json.Unmarshal(committed, &o); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if err := json.Unmarshal(cur, &c); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if v, _ := o["x-schemaVersion"].(float64); int(v) == reportschema.Version {
+			for _, d := range reportschema.Incompatible(o, c) {
+				t.Errorf("%s: breaking change without a Version bump: %s", path, d)
+			}
+		}
+		if !bytes.Equal(committed, append(cur, '\n')) {
+			t.Errorf("%s: out of date, run go run ./internal/reportschema/gen", path)
+		}
+	}
+}
+
+// TestIncompatible checks that the compatibility diff reports what breaks readers and lets
+// additions through.
+func TestIncompatible(t *testing.T) {
+	old := reportschema.Schema("pair_report", reportschema.PairReport{})
+	cur := reportschema.Schema("pair_report", reportschema.PairReport{})
+	if d := reportschema.Incompatible(old, cur); len(d) != 0 {
+		t.Fatalf("identical schemas: %v", d)
+	}
+	props, _ := cur["properties"].(map[string]any)
+	if len(props) == 0 {
+		t.Fatal("pair_report schema has no properties")
+	}
+	var removed string
+	for k := range props {
+		removed = k
+		break
+	}
+	trimmed := make(map[string]any, len(props))
+	for k, v := range props {
+		if k != removed {
+			trimmed[k] = v
+		}
+	}
+	cur["properties"] = trimmed
+	if d := reportschema.Incompatible(old, cur); len(d) == 0 {
+		t.Errorf("removing %q was not reported", removed)
+	}
+	trimmed[removed] = props[removed]
+	trimmed["addedLater"] = map[string]any{"type": "string"}
+	if d := reportschema.Incompatible(old, cur); len(d) != 0 {
+		t.Errorf("an added property was reported: %v", d)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !sqlite || !cgo
+
+package resultsdb
+
+// Built reports whether the SQLite driver is compiled in (-tags sqlite with cgo: without
+// cgo go-sqlite3 compiles to a stub that fails at the first query).
+const Built = false
+
+const driverName = ""
@@ -0,0 +1,10 @@
+//go:build sqlite && cgo
+
+package resultsdb
+
+import _ "github.com/mattn/go-sqlite3" // registers "sqlite3"; needs cgo
+
+// Built reports whether the SQLite driver is compiled in.
+const Built = true
+
+const driverName = "sqlite3"
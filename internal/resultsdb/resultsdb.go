@@ -0,0 +1,260 @@
+// Package resultsdb keeps batch classifications in a SQLite database, next to (not instead
+// of) the OK/BAD files: every run, every check of a (chain, from, token) pair, and the
+// reasons/warnings behind it. Repeated runs can then report verdicts that changed since the
+// last classification and skip pairs whose balance did not move (see batchcli -db).
+//
+// Private keys are never stored. The SQLite driver needs cgo and is only compiled into
+// cgo builds with -tags sqlite; other builds refuse Open (see Built) and list the feature
+// as unavailable (version --features).
+package resultsdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/features"
+	"github.com/ligun0805/bundle-rescue/internal/warnings"
+)
+
+func init() {
+	if Built {
+		features.Register(features.Feature{Name: "sqlite", Available: true, Detail: "results database (batchcli -db)"})
+	} else {
+		features.Register(features.Feature{Name: "sqlite", Available: false, Detail: "results database (batchcli -db): rebuild with CGO_ENABLED=1 go build -tags sqlite"})
+	}
+}
+
+// Reason kinds in the reasons table.
+const (
+	KindBad     = "bad"
+	KindSpam    = "spam"
+	KindWarning = "warning"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	tool        TEXT NOT NULL,
+	chain_id    TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT,
+	input_hash  TEXT,
+	config_hash TEXT,
+	result      TEXT
+);
+CREATE TABLE IF NOT EXISTS pairs (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	chain_id       TEXT NOT NULL,
+	from_addr      TEXT NOT NULL,
+	token          TEXT NOT NULL,
+	first_run      INTEGER NOT NULL REFERENCES runs(id),
+	last_check     INTEGER,
+	UNIQUE (chain_id, from_addr, token)
+);
+CREATE TABLE IF NOT EXISTS checks (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id         INTEGER NOT NULL REFERENCES runs(id),
+	pair_id        INTEGER NOT NULL REFERENCES pairs(id),
+	line           INTEGER,
+	verdict        TEXT NOT NULL,
+	reason_code    TEXT,
+	symbol         TEXT,
+	decimals       INTEGER,
+	balance_wei    TEXT,
+	balance_tokens TEXT,
+	total_ms       INTEGER,
+	reused         INTEGER NOT NULL DEFAULT 0,
+	checked_at     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS checks_pair ON checks (pair_id, id);
+CREATE TABLE IF NOT EXISTS reasons (
+	check_id INTEGER NOT NULL REFERENCES checks(id),
+	kind     TEXT NOT NULL,
+	code     TEXT,
+	detail   TEXT
+);
+CREATE INDEX IF NOT EXISTS reasons_check ON reasons (check_id);
+`
+
+// Check is one classification of a pair. Addresses are stored checksummed as given.
+type Check struct {
+	ChainID, From, Token string
+	Line                 int
+	Verdict              string // ok | bad | spam
+	ReasonCode, Reason   string
+	Symbol               string
+	Decimals             int
+	BalanceWei           string
+	BalanceTokens        string
+	Warnings             warnings.List
+	SpamReasons          []string
+	TotalMs              int64
+	Reused               bool // verdict copied from the previous check (balance unchanged)
+	CheckedAt            time.Time
+}
+
+// DB is an open results database. Methods are safe for concurrent use.
+type DB struct {
+	db    *sql.DB
+	runID int64
+}
+
+// Open creates or opens the database at path.
+func Open(path string) (*DB, error) {
+	if !Built {
+		return nil, errors.New("results database: this build has no SQLite driver (rebuild with CGO_ENABLED=1 go build -tags sqlite)")
+	}
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // one writer; SQLite serializes anyway
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "PRAGMA foreign_keys=ON", schema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("results database %s: %w", path, err)
+		}
+	}
+	return &DB{db: db}, nil
+}
+
+// Close closes the database. Safe on nil.
+func (d *DB) Close() error {
+	if d == nil {
+		return nil
+	}
+	return d.db.Close()
+}
+
+// BeginRun records a new run; later checks belong to it.
+func (d *DB) BeginRun(tool, chainID, inputHash, configHash string) error {
+	res, err := d.db.Exec(`INSERT INTO runs (tool, chain_id, started_at, input_hash, config_hash) VALUES (?, ?, ?, ?, ?)`,
+		tool, chainID, now(), inputHash, configHash)
+	if err != nil {
+		return err
+	}
+	d.runID, err = res.LastInsertId()
+	return err
+}
+
+// FinishRun stores the run's end time and result line.
+func (d *DB) FinishRun(result string) error {
+	_, err := d.db.Exec(`UPDATE runs SET finished_at = ?, result = ? WHERE id = ?`, now(), result, d.runID)
+	return err
+}
+
+// Last returns the latest check of a pair (ok=false if it was never classified).
+func (d *DB) Last(chainID, from, token string) (Check, bool, error) {
+	c := Check{ChainID: chainID, From: from, Token: token}
+	var checkID int64
+	var reasonCode, symbol, balWei, balTok sql.NullString
+	var decimals, line, totalMs sql.NullInt64
+	var reused int
+	var checkedAt string
+	err := d.db.QueryRow(`SELECT c.id, c.line, c.verdict, c.reason_code, c.symbol, c.decimals, c.balance_wei, c.balance_tokens, c.total_ms, c.reused, c.checked_at
+		FROM pairs p JOIN checks c ON c.id = p.last_check
+		WHERE p.chain_id = ? AND p.from_addr = ? AND p.token = ?`, chainID, key(from), key(token)).
+		Scan(&checkID, &line, &c.Verdict, &reasonCode, &symbol, &decimals, &balWei, &balTok, &totalMs, &reused, &checkedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, err
+	}
+	c.Line, c.ReasonCode, c.Symbol, c.Decimals = int(line.Int64), reasonCode.String, symbol.String, int(decimals.Int64)
+	c.BalanceWei, c.BalanceTokens, c.TotalMs, c.Reused = balWei.String, balTok.String, totalMs.Int64, reused != 0
+	c.CheckedAt, _ = time.Parse(time.RFC3339, checkedAt)
+
+	rows, err := d.db.Query(`SELECT kind, code, detail FROM reasons WHERE check_id = ? ORDER BY rowid`, checkID)
+	if err != nil {
+		return c, false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, code, detail sql.NullString
+		if err := rows.Scan(&kind, &code, &detail); err != nil {
+			return c, false, err
+		}
+		switch kind.String {
+		case KindBad:
+			c.Reason = detail.String
+		case KindSpam:
+			c.SpamReasons = append(c.SpamReasons, detail.String)
+		case KindWarning:
+			c.Warnings = append(c.Warnings, warnings.Warning{Code: warnings.Code(code.String), Detail: detail.String})
+		}
+	}
+	return c, true, rows.Err()
+}
+
+// Record stores a check and returns the pair's previous verdict ("" = first classification).
+func (d *DB) Record(c Check) (string, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	from, token := key(c.From), key(c.Token)
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO pairs (chain_id, from_addr, token, first_run) VALUES (?, ?, ?, ?)`,
+		c.ChainID, from, token, d.runID); err != nil {
+		return "", err
+	}
+	var pairID int64
+	var prevCheck sql.NullInt64
+	if err := tx.QueryRow(`SELECT id, last_check FROM pairs WHERE chain_id = ? AND from_addr = ? AND token = ?`,
+		c.ChainID, from, token).Scan(&pairID, &prevCheck); err != nil {
+		return "", err
+	}
+	prev := ""
+	if prevCheck.Valid {
+		if err := tx.QueryRow(`SELECT verdict FROM checks WHERE id = ?`, prevCheck.Int64).Scan(&prev); err != nil {
+			return "", err
+		}
+	}
+	at := c.CheckedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	res, err := tx.Exec(`INSERT INTO checks (run_id, pair_id, line, verdict, reason_code, symbol, decimals, balance_wei, balance_tokens, total_ms, reused, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.runID, pairID, c.Line, c.Verdict, c.ReasonCode, c.Symbol, c.Decimals, c.BalanceWei, c.BalanceTokens, c.TotalMs, c.Reused, at.UTC().Format(time.RFC3339))
+	if err != nil {
+		return "", err
+	}
+	checkID, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+	addReason := func(kind, code, detail string) error {
+		_, err := tx.Exec(`INSERT INTO reasons (check_id, kind, code, detail) VALUES (?, ?, ?, ?)`, checkID, kind, code, detail)
+		return err
+	}
+	if c.Reason != "" {
+		if err := addReason(KindBad, c.ReasonCode, c.Reason); err != nil {
+			return "", err
+		}
+	}
+	for _, r := range c.SpamReasons {
+		if err := addReason(KindSpam, "", r); err != nil {
+			return "", err
+		}
+	}
+	for _, w := range c.Warnings {
+		if err := addReason(KindWarning, string(w.Code), w.Detail); err != nil {
+			return "", err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE pairs SET last_check = ? WHERE id = ?`, checkID, pairID); err != nil {
+		return "", err
+	}
+	return prev, tx.Commit()
+}
+
+// key normalizes an address for lookups.
+func key(addr string) string { return strings.ToLower(strings.TrimSpace(addr)) }
+
+func now() string { return time.Now().UTC().Format(time.RFC3339) }
@@ -0,0 +1,430 @@
+// Package riskgate decides how much human confirmation a rescue action needs before it is
+// sent. Each risk factor of the action (new token, high value, recipient other than the SAFE,
+// unknown delegate, public-mempool fallback) has a level; the highest level picks the gate:
+// none (run unattended), confirm (one y/N) or phrase (type a confirmation phrase).
+// Factor levels, gates, the phrase and thresholds come from RISK_* env vars, so each team
+// can enforce its own operating procedure.
+package riskgate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ligun0805/bundle-rescue/internal/tokencatalog"
+)
+
+// Level is the risk level of a factor or of a whole action.
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	High
+)
+
+func (l Level) String() string {
+	switch l {
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	}
+	return "low"
+}
+
+// ParseLevel reads low | medium | high.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return Low, nil
+	case "medium", "med":
+		return Medium, nil
+	case "high":
+		return High, nil
+	}
+	return Low, fmt.Errorf("bad risk level %q (low, medium or high)", s)
+}
+
+// Factor is one reason an action is risky.
+type Factor string
+
+const (
+	NewToken         Factor = "new_token"          // token not in RISK_KNOWN_TOKENS / the catalog
+	HighValue        Factor = "high_value"         // amount at or above RISK_HIGH_VALUE
+	RecipientNotSafe Factor = "recipient_not_safe" // funds go somewhere other than the SAFE
+	UnknownDelegate  Factor = "unknown_delegate"   // 7702 delegate not in RISK_KNOWN_DELEGATES
+	PublicFallback   Factor = "public_fallback"    // tx may reach the public mempool
+	TransferHook     Factor = "transfer_hook"      // ERC-777 / ERC-1363 token: the transfer runs third-party hooks
+)
+
+var factors = []Factor{NewToken, HighValue, RecipientNotSafe, UnknownDelegate, PublicFallback, TransferHook}
+
+// Gate is what a human has to do before the action runs.
+type Gate string
+
+const (
+	GateNone    Gate = "none"    // run unattended
+	GateConfirm Gate = "confirm" // one y/N confirmation
+	GatePhrase  Gate = "phrase"  // type Policy.Phrase
+)
+
+// DefaultPhrase is typed to confirm a phrase-gated action unless RISK_PHRASE says otherwise.
+const DefaultPhrase = "I ACCEPT THE RISK"
+
+// ErrDeclined is returned by Confirm when the operator did not confirm.
+var ErrDeclined = errors.New("not confirmed")
+
+// Policy is one deployment's confirmation rules.
+type Policy struct {
+	Levels map[Factor]Level
+	Gates  map[Level]Gate
+	Phrase string
+	// HighValue thresholds in token units, keyed by lower-case address, upper-case symbol
+	// or "*" for any token. Empty = the high-value check is off.
+	HighValue map[string]*big.Float
+	// KnownTokens is nil when neither RISK_KNOWN_TOKENS nor RISK_TOKEN_CATALOG is set:
+	// the new-token check is off then.
+	KnownTokens map[common.Address]bool
+	// KnownDelegates is nil when RISK_KNOWN_DELEGATES is unset; callers fill it with their
+	// delegate allowlist.
+	KnownDelegates map[common.Address]bool
+	// Unattended is the highest level that may run without a human (batch mode, CI).
+	Unattended Level
+}
+
+// Default is the policy without RISK_* variables: new token, high value and transfer hooks are medium,
+// the other factors high; medium asks once, high wants the phrase; only low runs unattended.
+func Default() *Policy {
+	return &Policy{
+		Levels: map[Factor]Level{
+			NewToken: Medium, HighValue: Medium,
+			RecipientNotSafe: High, UnknownDelegate: High, PublicFallback: High,
+			TransferHook: Medium,
+		},
+		Gates:      map[Level]Gate{Low: GateNone, Medium: GateConfirm, High: GatePhrase},
+		Phrase:     DefaultPhrase,
+		HighValue:  map[string]*big.Float{},
+		Unattended: Low,
+	}
+}
+
+// FromEnv applies RISK_* variables on top of Default. chainID selects catalog entries.
+//
+//	RISK_LEVELS          = "new_token=low,high_value=high"
+//	RISK_GATES           = "medium=phrase"
+//	RISK_PHRASE          = "RESCUE NOW"
+//	RISK_HIGH_VALUE      = "*=10000,USDT=50000,0xToken=5"  (token units)
+//	RISK_KNOWN_TOKENS    = "0xT1,0xT2"
+//	RISK_TOKEN_CATALOG   = token_catalog.json  (tokens with last verdict "ok" are known)
+//	RISK_KNOWN_DELEGATES = "0xD1,0xD2"
+//	RISK_UNATTENDED_MAX  = low | medium | high
+func FromEnv(chainID string) (*Policy, error) {
+	p := Default()
+	kvs, err := pairs("RISK_LEVELS")
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		f, l := Factor(strings.ToLower(kv[0])), kv[1]
+		if !knownFactor(f) {
+			return nil, fmt.Errorf("RISK_LEVELS: unknown factor %q", kv[0])
+		}
+		lvl, err := ParseLevel(l)
+		if err != nil {
+			return nil, fmt.Errorf("RISK_LEVELS: %w", err)
+		}
+		p.Levels[f] = lvl
+	}
+	kvs, err = pairs("RISK_GATES")
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		lvl, err := ParseLevel(kv[0])
+		if err != nil {
+			return nil, fmt.Errorf("RISK_GATES: %w", err)
+		}
+		g := Gate(strings.ToLower(kv[1]))
+		if g != GateNone && g != GateConfirm && g != GatePhrase {
+			return nil, fmt.Errorf("RISK_GATES: bad gate %q (none, confirm or phrase)", kv[1])
+		}
+		p.Gates[lvl] = g
+	}
+	if v := strings.TrimSpace(os.Getenv("RISK_PHRASE")); v != "" {
+		p.Phrase = v
+	}
+	kvs, err = pairs("RISK_HIGH_VALUE")
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		v, ok := new(big.Float).SetString(kv[1])
+		if !ok || v.Sign() <= 0 {
+			return nil, fmt.Errorf("RISK_HIGH_VALUE: bad amount %q", kv[1])
+		}
+		p.HighValue[valueKey(kv[0])] = v
+	}
+	if v := strings.TrimSpace(os.Getenv("RISK_KNOWN_TOKENS")); v != "" {
+		set, err := addrSet(v)
+		if err != nil {
+			return nil, fmt.Errorf("RISK_KNOWN_TOKENS: %w", err)
+		}
+		p.KnownTokens = set
+	}
+	if path := strings.TrimSpace(os.Getenv("RISK_TOKEN_CATALOG")); path != "" {
+		c, err := tokencatalog.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("RISK_TOKEN_CATALOG: %w", err)
+		}
+		if p.KnownTokens == nil {
+			p.KnownTokens = map[common.Address]bool{}
+		}
+		for _, e := range c.Entries() {
+			if e.ChainID == chainID && e.LastVerdict == "ok" && common.IsHexAddress(e.Address) {
+				p.KnownTokens[common.HexToAddress(e.Address)] = true
+			}
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("RISK_KNOWN_DELEGATES")); v != "" {
+		set, err := addrSet(v)
+		if err != nil {
+			return nil, fmt.Errorf("RISK_KNOWN_DELEGATES: %w", err)
+		}
+		p.KnownDelegates = set
+	}
+	if v := strings.TrimSpace(os.Getenv("RISK_UNATTENDED_MAX")); v != "" {
+		lvl, err := ParseLevel(v)
+		if err != nil {
+			return nil, fmt.Errorf("RISK_UNATTENDED_MAX: %w", err)
+		}
+		p.Unattended = lvl
+	}
+	return p, nil
+}
+
+// TrustDelegates marks addrs as known delegates unless RISK_KNOWN_DELEGATES set its own list.
+func (p *Policy) TrustDelegates(addrs map[common.Address]bool) {
+	if p.KnownDelegates != nil {
+		return
+	}
+	p.KnownDelegates = map[common.Address]bool{}
+	for a, ok := range addrs {
+		if ok {
+			p.KnownDelegates[a] = true
+		}
+	}
+}
+
+// Action is what is about to be sent. Zero Delegate = no 7702 delegation (classic bundle);
+// zero Token = native ETH (never a "new token"; RISK_HIGH_VALUE key ETH via Symbol).
+type Action struct {
+	Token          common.Address
+	Symbol         string
+	Decimals       int
+	Amount         *big.Int // wei; nil = unknown
+	Recipient      common.Address
+	Safe           common.Address
+	Delegate       common.Address
+	PublicFallback bool
+	TransferHook   string // core.TransferHooks summary when the token has hooks ("" = none)
+}
+
+// Finding is one factor that applies to an action.
+type Finding struct {
+	Factor Factor
+	Level  Level
+	Detail string
+}
+
+// Assessment is the outcome of Assess: the highest level and what raised it.
+type Assessment struct {
+	Level    Level
+	Findings []Finding
+}
+
+// Assess lists the factors of a that apply under p.
+func (p *Policy) Assess(a Action) Assessment {
+	var as Assessment
+	add := func(f Factor, detail string) {
+		as.Findings = append(as.Findings, Finding{Factor: f, Level: p.Levels[f], Detail: detail})
+	}
+	if p.KnownTokens != nil && a.Token != (common.Address{}) && !p.KnownTokens[a.Token] {
+		add(NewToken, "token "+a.Token.Hex()+" was not rescued before")
+	}
+	if limit := p.highValueLimit(a); limit != nil && a.Amount != nil {
+		amt := new(big.Float).Quo(new(big.Float).SetInt(a.Amount), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(a.Decimals)), nil)))
+		if amt.Cmp(limit) >= 0 {
+			add(HighValue, fmt.Sprintf("amount %s %s ≥ %s", amt.Text('f', 4), symbolOr(a), limit.Text('f', -1)))
+		}
+	}
+	if a.Recipient != a.Safe {
+		add(RecipientNotSafe, "recipient "+a.Recipient.Hex()+" is not the SAFE "+a.Safe.Hex())
+	}
+	if a.Delegate != (common.Address{}) && !p.KnownDelegates[a.Delegate] {
+		add(UnknownDelegate, "delegate "+a.Delegate.Hex()+" is not a known delegate")
+	}
+	if a.PublicFallback {
+		add(PublicFallback, "the tx may be broadcast to the public mempool")
+	}
+	if a.TransferHook != "" {
+		add(TransferHook, a.TransferHook+": hooks may behave differently on-chain than in simulation")
+	}
+	for _, f := range as.Findings {
+		if f.Level > as.Level {
+			as.Level = f.Level
+		}
+	}
+	return as
+}
+
+// Merge combines assessments of several actions confirmed together.
+func Merge(list ...Assessment) Assessment {
+	var out Assessment
+	seen := map[string]bool{}
+	for _, as := range list {
+		if as.Level > out.Level {
+			out.Level = as.Level
+		}
+		for _, f := range as.Findings {
+			if k := string(f.Factor) + "|" + f.Detail; !seen[k] {
+				seen[k] = true
+				out.Findings = append(out.Findings, f)
+			}
+		}
+	}
+	sort.SliceStable(out.Findings, func(i, j int) bool { return out.Findings[i].Level > out.Findings[j].Level })
+	return out
+}
+
+// Summary is "high: recipient_not_safe, new_token" (or "low" without findings).
+func (as Assessment) Summary() string {
+	if len(as.Findings) == 0 {
+		return as.Level.String()
+	}
+	names := make([]string, 0, len(as.Findings))
+	for _, f := range as.Findings {
+		names = append(names, string(f.Factor))
+	}
+	return as.Level.String() + ": " + strings.Join(names, ", ")
+}
+
+// GateFor returns the gate of an assessment.
+func (p *Policy) GateFor(as Assessment) Gate {
+	if g, ok := p.Gates[as.Level]; ok {
+		return g
+	}
+	return GatePhrase
+}
+
+// UnattendedOK reports whether as may run with nobody to confirm it.
+func (p *Policy) UnattendedOK(as Assessment) bool {
+	return as.Level <= p.Unattended || p.GateFor(as) == GateNone
+}
+
+// Confirm asks on the console according to the gate; nil means go ahead.
+func (p *Policy) Confirm(r *bufio.Reader, w io.Writer, as Assessment) error {
+	g := p.GateFor(as)
+	if g == GateNone {
+		return nil
+	}
+	fmt.Fprintf(w, "  [risk] %s\n", as.Level)
+	for _, f := range as.Findings {
+		fmt.Fprintf(w, "   • %-18s %-6s %s\n", f.Factor, f.Level, f.Detail)
+	}
+	if g == GateConfirm {
+		fmt.Fprint(w, "  Продолжить? [y/N]: ")
+		ans, _ := r.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(ans)) {
+		case "y", "yes", "д", "да":
+			return nil
+		}
+		return ErrDeclined
+	}
+	fmt.Fprintf(w, "  Для продолжения введите фразу %q: ", p.Phrase)
+	ans, _ := r.ReadString('\n')
+	if !p.PhraseOK(ans) {
+		return ErrDeclined
+	}
+	return nil
+}
+
+// PhraseOK compares typed text with the phrase (surrounding spaces ignored, case kept).
+func (p *Policy) PhraseOK(typed string) bool {
+	return strings.TrimSpace(typed) == p.Phrase
+}
+
+func (p *Policy) highValueLimit(a Action) *big.Float {
+	if v, ok := p.HighValue[valueKey(a.Token.Hex())]; ok {
+		return v
+	}
+	if a.Symbol != "" {
+		if v, ok := p.HighValue[valueKey(a.Symbol)]; ok {
+			return v
+		}
+	}
+	return p.HighValue["*"]
+}
+
+func symbolOr(a Action) string {
+	if a.Symbol != "" {
+		return a.Symbol
+	}
+	return "tokens"
+}
+
+// valueKey normalizes a RISK_HIGH_VALUE key: addresses lower-case, symbols upper-case.
+func valueKey(k string) string {
+	k = strings.TrimSpace(k)
+	if common.IsHexAddress(k) {
+		return strings.ToLower(common.HexToAddress(k).Hex())
+	}
+	return strings.ToUpper(k)
+}
+
+func knownFactor(f Factor) bool {
+	for _, k := range factors {
+		if k == f {
+			return true
+		}
+	}
+	return false
+}
+
+// pairs reads env var name as "k=v,k2=v2".
+func pairs(name string) ([][2]string, error) {
+	var out [][2]string
+	for _, kv := range strings.Split(os.Getenv(name), ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("%s: bad entry %q (want key=value)", name, strings.TrimSpace(kv))
+		}
+		out = append(out, [2]string{k, v})
+	}
+	return out, nil
+}
+
+func addrSet(csv string) (map[common.Address]bool, error) {
+	set := map[common.Address]bool{}
+	for _, s := range strings.Split(csv, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("bad address %q", s)
+		}
+		set[common.HexToAddress(s)] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,146 @@
+package rpcdial
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+const (
+	headStale = 3 * time.Second  // no head for this long: a waiter asks the node (resubscribe in progress)
+	headIdle  = 30 * time.Second // a subscription nobody waits on is dropped after this
+)
+
+// HeadWatcher is one newHeads subscription of a client shared by every waiter: pairs run
+// one after the other or side by side wait on the same notifications instead of each
+// subscribing (and polling) on its own.
+type HeadWatcher struct {
+	ec  *ethclient.Client
+	sub event.Subscription
+
+	mu   sync.Mutex
+	head *types.Header
+	seen time.Time     // last head notification or poll
+	wake chan struct{} // closed when the head advances
+
+	refs int         // guarded by watchersMu
+	idle *time.Timer // guarded by watchersMu
+}
+
+var (
+	watchersMu sync.Mutex
+	watchers   = map[*ethclient.Client]*HeadWatcher{}
+)
+
+// WatchHeads returns the shared watcher of ec, subscribing on first use; the caller
+// Releases it. Over HTTP it fails like SubscribeHeads and the caller polls.
+func WatchHeads(ctx context.Context, ec *ethclient.Client) (*HeadWatcher, error) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	if w := watchers[ec]; w != nil {
+		w.refs++
+		if w.idle != nil {
+			w.idle.Stop()
+			w.idle = nil
+		}
+		return w, nil
+	}
+	ch := make(chan *types.Header, 16)
+	sub, err := SubscribeHeads(ctx, ec, ch)
+	if err != nil {
+		return nil, err
+	}
+	w := &HeadWatcher{ec: ec, sub: sub, wake: make(chan struct{}), refs: 1}
+	watchers[ec] = w
+	go w.loop(ch)
+	return w, nil
+}
+
+// Release drops the caller's reference. The subscription outlives the last one by headIdle,
+// so the next attempt or pair reuses it.
+func (w *HeadWatcher) Release() {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	if w.refs--; w.refs > 0 {
+		return
+	}
+	w.idle = time.AfterFunc(headIdle, func() {
+		watchersMu.Lock()
+		defer watchersMu.Unlock()
+		if w.refs == 0 && watchers[w.ec] == w {
+			delete(watchers, w.ec)
+			w.sub.Unsubscribe()
+		}
+	})
+}
+
+func (w *HeadWatcher) loop(ch <-chan *types.Header) {
+	for {
+		select {
+		case h := <-ch:
+			w.observe(h)
+		case <-w.sub.Err(): // closed by Unsubscribe
+			return
+		}
+	}
+}
+
+// observe records a head (nil: a failed poll) and wakes the waiters when it advanced.
+func (w *HeadWatcher) observe(h *types.Header) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seen = time.Now()
+	if h == nil || h.Number == nil || (w.head != nil && h.Number.Cmp(w.head.Number) <= 0) {
+		return
+	}
+	w.head = h
+	close(w.wake)
+	w.wake = make(chan struct{})
+}
+
+// Latest is the newest head seen (nil before the first one).
+func (w *HeadWatcher) Latest() *types.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.head
+}
+
+// WaitFor blocks until the head reaches target. Heads come from the subscription; only when
+// none arrived for headStale (first call, reconnect) one of the waiters asks the node.
+func (w *HeadWatcher) WaitFor(ctx context.Context, target *big.Int) error {
+	for {
+		w.mu.Lock()
+		if w.head != nil && w.head.Number.Cmp(target) >= 0 {
+			w.mu.Unlock()
+			return nil
+		}
+		wake := w.wake
+		wait := headStale - time.Since(w.seen)
+		if wait <= 0 {
+			w.seen = time.Now() // this waiter polls, the others wait for it
+		}
+		w.mu.Unlock()
+		if wait <= 0 {
+			h, err := w.ec.HeaderByNumber(ctx, nil)
+			if err != nil {
+				h = nil
+			}
+			w.observe(h)
+			continue
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-wake:
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
@@ -0,0 +1,118 @@
+// Package rpcdial dials JSON-RPC endpoints over HTTP(S) or WebSocket (ws://, wss://).
+// WebSocket keeps one connection for all calls (faster turnaround than HTTP requests) and
+// allows newHeads subscriptions. Reconnects: the first dial is retried with backoff, geth's
+// rpc.Client redials a dropped connection on the next call, and SubscribeHeads re-subscribes.
+package rpcdial
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	dialAttempts   = 3
+	dialBackoff    = 500 * time.Millisecond
+	resubscribeMax = 30 * time.Second // longest wait between re-subscribe attempts
+)
+
+// IsWS reports whether raw is a ws:// or wss:// URL.
+func IsWS(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss")
+}
+
+// Dial connects to raw. HTTP(S) uses httpClient (nil = default); WebSocket dials are
+// retried dialAttempts times, since a refused handshake is often a brief provider hiccup.
+func Dial(ctx context.Context, raw string, httpClient *http.Client) (*rpc.Client, error) {
+	raw = strings.TrimSpace(raw)
+	if !IsWS(raw) {
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		return rpc.DialHTTPWithClient(raw, httpClient)
+	}
+	backoff := dialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= dialAttempts; attempt++ {
+		c, err := rpc.DialOptions(ctx, raw)
+		if err == nil {
+			return c, nil
+		}
+		lastErr = err
+		if attempt == dialAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return nil, fmt.Errorf("websocket %s: %w", Host(raw), lastErr)
+}
+
+// DialEth is Dial wrapped in an ethclient.
+func DialEth(ctx context.Context, raw string, httpClient *http.Client) (*ethclient.Client, error) {
+	c, err := Dial(ctx, raw, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(c), nil
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*rpc.Client{}
+)
+
+// Shared returns a process-wide WebSocket client for raw, dialed on first use. It serves
+// code that posts raw JSON-RPC over HTTP and needs the same call over a ws:// URL.
+func Shared(ctx context.Context, raw string) (*rpc.Client, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if c, ok := shared[raw]; ok {
+		return c, nil
+	}
+	c, err := Dial(ctx, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	shared[raw] = c
+	return c, nil
+}
+
+// SubscribeHeads follows newHeads and re-subscribes (with backoff) after the connection
+// drops. Over HTTP it fails right away with rpc.ErrNotificationsUnsupported: callers poll.
+func SubscribeHeads(ctx context.Context, ec *ethclient.Client, ch chan<- *types.Header) (event.Subscription, error) {
+	first, err := ec.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		return nil, err
+	}
+	return event.ResubscribeErr(resubscribeMax, func(ctx context.Context, _ error) (event.Subscription, error) {
+		if first != nil {
+			s := first
+			first = nil
+			return s, nil
+		}
+		return ec.SubscribeNewHead(ctx, ch)
+	}), nil
+}
+
+// Host keeps only the host of an endpoint URL (URLs carry API keys).
+func Host(raw string) string {
+	if u, err := url.Parse(strings.TrimSpace(raw)); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "endpoint"
+}
@@ -0,0 +1,243 @@
+// Package rpcerrors classifies the errors of JSON-RPC calls: provider throttling, timeouts,
+// an unreachable endpoint, a revert of the called contract (with its reason), an invalid
+// opcode, a call to an address without code. bundlecli, batchcli, the GUI and bundlecore all
+// classify through it, so a rate limit is a rate limit in every output and every retry loop.
+//
+// Typed errors (net.Error, rpc.Error codes, rpc.HTTPError status codes, context errors) are
+// checked first; provider messages that only exist as text fall back to substring matching.
+package rpcerrors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+)
+
+// Kind is what went wrong.
+type Kind int
+
+const (
+	None          Kind = iota // no error
+	RateLimited               // provider throttled the request (HTTP 429, -32005)
+	Timeout                   // deadline exceeded, i/o or gateway timeout
+	Canceled                  // the caller's context was cancelled
+	Unavailable               // connection refused/reset, EOF, bad gateway, unknown host
+	Reverted                  // the call reverted; Class.Reason has the revert reason
+	InvalidOpcode             // the EVM hit INVALID (0xfe)
+	NotContract               // no bytecode at the called address (ClassifyCall only)
+	Unsupported               // ABI / return type mismatch (ClassifyCall only)
+	Other                     // any other RPC error
+)
+
+var kindNames = [...]string{"none", "rate_limited", "timeout", "canceled", "unavailable", "reverted", "invalid_opcode", "not_contract", "unsupported", "other"}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "other"
+	}
+	return kindNames[k]
+}
+
+// Transient reports kinds worth retrying: another try, later or on another provider, may pass.
+func (k Kind) Transient() bool { return k == RateLimited || k == Timeout || k == Unavailable }
+
+// Class is the classification of one error.
+type Class struct {
+	Kind   Kind
+	Reason string // revert reason (Reverted; "" when the node gave none)
+	Err    error
+}
+
+// Classify classifies err without further calls. nil is Kind None.
+func Classify(err error) Class {
+	if err == nil {
+		return Class{Kind: None}
+	}
+	c := Class{Kind: Other, Err: err}
+	var he rpc.HTTPError
+	var re rpc.Error
+	var ne net.Error
+	s := strings.ToLower(err.Error())
+	switch {
+	case errors.As(err, &he) && he.StatusCode == http.StatusTooManyRequests,
+		errors.As(err, &re) && re.ErrorCode() == -32005,
+		strings.Contains(s, "too many requests"), strings.Contains(s, "-32005"):
+		c.Kind = RateLimited
+	case strings.Contains(s, "execution reverted"):
+		c.Kind, c.Reason = Reverted, revertReason(err, s)
+	case strings.Contains(s, "invalid opcode"):
+		c.Kind = InvalidOpcode
+	case errors.Is(err, context.Canceled):
+		c.Kind = Canceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne) && ne.Timeout(),
+		errors.As(err, &he) && he.StatusCode == http.StatusGatewayTimeout,
+		strings.Contains(s, "deadline exceeded"), strings.Contains(s, "timeout"), strings.Contains(s, "timed out"):
+		c.Kind = Timeout
+	case strings.Contains(s, "rate limit"):
+		c.Kind = RateLimited
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &he) && (he.StatusCode == http.StatusBadGateway || he.StatusCode == http.StatusServiceUnavailable),
+		strings.Contains(s, "connection refused"), strings.Contains(s, "connection reset"), strings.Contains(s, "broken pipe"),
+		strings.Contains(s, "eof"), strings.Contains(s, "no such host"), strings.Contains(s, "bad gateway"),
+		strings.Contains(s, "service unavailable"):
+		c.Kind = Unavailable
+	case strings.Contains(s, "context canceled"):
+		c.Kind = Canceled
+	}
+	return c
+}
+
+// CodeReader is the part of ethclient.Client ClassifyCall needs.
+type CodeReader interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+}
+
+// ClassifyCall classifies the error of an eth_call to target. An error Classify leaves as
+// Other is narrowed down: no code at target is NotContract, an ABI/decoding complaint is
+// Unsupported.
+func ClassifyCall(ctx context.Context, ec CodeReader, target common.Address, err error) Class {
+	c := Classify(err)
+	if c.Kind != Other {
+		return c
+	}
+	if code, e := ec.CodeAt(ctx, target, nil); e == nil && len(code) == 0 {
+		c.Kind = NotContract
+		return c
+	}
+	if s := strings.ToLower(err.Error()); strings.Contains(s, "unsupported") || strings.Contains(s, "abi") {
+		c.Kind = Unsupported
+	}
+	return c
+}
+
+// IsTransient reports errors worth retrying (Kind.Transient).
+func IsTransient(err error) bool { return Classify(err).Kind.Transient() }
+
+// IsRateLimited reports provider throttling.
+func IsRateLimited(err error) bool { return Classify(err).Kind == RateLimited }
+
+// IsTimeout reports a timeout or a cancelled context.
+func IsTimeout(err error) bool {
+	k := Classify(err).Kind
+	return k == Timeout || k == Canceled
+}
+
+// FromRPC reports errors of the transport or the JSON-RPC server, as opposed to errors of
+// the caller (bad input, a cancelled context).
+func FromRPC(err error) bool {
+	var ne net.Error
+	var re rpc.Error
+	var he rpc.HTTPError
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ne) || errors.As(err, &re) || errors.As(err, &he) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Code is the reason code of the class (reasons package).
+func (c Class) Code() reasons.Code {
+	switch c.Kind {
+	case None:
+		return ""
+	case RateLimited:
+		return reasons.RPCRateLimited
+	case Timeout, Canceled:
+		return reasons.RPCTimeout
+	case Unavailable:
+		return reasons.RPCUnavailable
+	case Reverted, InvalidOpcode:
+		return reasons.Reverted
+	case NotContract:
+		return reasons.DeadToken
+	}
+	return reasons.RPCError
+}
+
+// RPCCode is the rpc-class reason code: RPC_TIMEOUT, RPC_UNAVAILABLE, RPC_RATE_LIMITED, or
+// RPC_ERROR for everything else ("" for None).
+func (c Class) RPCCode() reasons.Code {
+	if code := c.Code(); code == "" || code.Class() == "rpc" {
+		return code
+	}
+	return reasons.RPCError
+}
+
+// Tag is the lower-case prefix of batchcli reason texts: rpc_timeout, rpc_unavailable,
+// rpc_rate_limited or rpc_error.
+func (c Class) Tag() string {
+	if c.Kind == None {
+		return ""
+	}
+	return strings.ToLower(string(c.RPCCode()))
+}
+
+// Describe is a short user-facing line: "[REVERT] paused", "[RATE_LIMIT] provider throttled
+// the request", "[RPC] <error>".
+func (c Class) Describe() string {
+	switch c.Kind {
+	case None:
+		return ""
+	case RateLimited:
+		return "[RATE_LIMIT] provider throttled the request"
+	case Reverted:
+		if c.Reason != "" {
+			return "[REVERT] " + c.Reason
+		}
+		return "[REVERT] execution reverted"
+	case InvalidOpcode:
+		return "[INVALID] invalid opcode during execution"
+	case NotContract:
+		return "[NOT_CONTRACT] no bytecode at address"
+	case Unsupported:
+		return "[UNSUPPORTED] ABI/return type mismatch"
+	}
+	return "[RPC] " + c.Err.Error()
+}
+
+// revertReason is the text after "execution reverted", or the decoded Error(string) of the
+// revert data when the node only sent that.
+func revertReason(err error, lower string) string {
+	msg := err.Error()
+	if i := strings.Index(lower, "execution reverted"); i >= 0 {
+		rest := strings.TrimSpace(msg[i+len("execution reverted"):])
+		if rest = strings.TrimSpace(strings.TrimPrefix(rest, ":")); rest != "" {
+			return rest
+		}
+	}
+	var de rpc.DataError
+	if errors.As(err, &de) {
+		if h, ok := de.ErrorData().(string); ok {
+			if r, ok := errorString(common.FromHex(h)); ok {
+				return r
+			}
+		}
+	}
+	return ""
+}
+
+// errorString decodes Error(string) revert data (selector 0x08c379a0). The data comes from
+// the called contract, so the offset and length words are bounded by the payload before any
+// slicing: a hostile token must not be able to panic the caller.
+func errorString(data []byte) (string, bool) {
+	if len(data) < 4+64 || common.Bytes2Hex(data[:4]) != "08c379a0" {
+		return "", false
+	}
+	payload := data[4:]
+	off := new(big.Int).SetBytes(payload[:32])
+	if !off.IsUint64() || off.Uint64() > uint64(len(payload)-32) {
+		return "", false
+	}
+	start := off.Uint64() + 32
+	n := new(big.Int).SetBytes(payload[start-32 : start])
+	if !n.IsUint64() || n.Uint64() > uint64(len(payload))-start {
+		return "", false
+	}
+	return string(payload[start : start+n.Uint64()]), true
+}
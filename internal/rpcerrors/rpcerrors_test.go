@@ -0,0 +1,68 @@
+package rpcerrors
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// revertData builds Error(string) revert data from raw offset/length words and a tail.
+func revertData(off, length *big.Int, tail []byte) []byte {
+	data := common.FromHex("0x08c379a0")
+	data = append(data, common.LeftPadBytes(off.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(length.Bytes(), 32)...)
+	return append(data, tail...)
+}
+
+func word(n uint64) *big.Int { return new(big.Int).SetUint64(n) }
+
+func TestErrorString(t *testing.T) {
+	paused := common.RightPadBytes([]byte("paused"), 32)
+	cases := []struct {
+		name string
+		data []byte
+		want string
+		ok   bool
+	}{
+		{"valid", revertData(word(32), word(6), paused), "paused", true},
+		{"empty reason", revertData(word(32), word(0), nil), "", true},
+		{"unpadded tail", revertData(word(32), word(6), []byte("paused")), "paused", true},
+		{"offset past the first word", append(revertData(word(64), word(0), nil), append(common.LeftPadBytes([]byte{4}, 32), common.RightPadBytes([]byte("gone"), 32)...)...), "gone", true},
+		{"nil", nil, "", false},
+		{"selector only", common.FromHex("0x08c379a0"), "", false},
+		{"wrong selector", append(common.FromHex("0x4e487b71"), revertData(word(32), word(6), paused)[4:]...), "", false},
+		{"truncated", revertData(word(32), word(10), []byte("short")), "", false},
+		{"length wraps uint64", revertData(word(32), new(big.Int).SetUint64(^uint64(0)), paused), "", false},
+		{"length near 2^64", revertData(word(32), word(^uint64(0)-66), paused), "", false},
+		{"length 2^255", revertData(word(32), new(big.Int).Lsh(big.NewInt(1), 255), paused), "", false},
+		{"offset past the data", revertData(word(1024), word(6), paused), "", false},
+		{"offset wraps uint64", revertData(new(big.Int).SetUint64(^uint64(0)), word(6), paused), "", false},
+		{"offset 2^200", revertData(new(big.Int).Lsh(big.NewInt(1), 200), word(6), paused), "", false},
+	}
+	for _, c := range cases {
+		got, ok := errorString(c.data)
+		if ok != c.ok || got != c.want {
+			t.Errorf("%s: errorString = %q, %v; want %q, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+// dataErr is an rpc.DataError carrying revert data, as the node returns it.
+type dataErr struct{ data string }
+
+func (e dataErr) Error() string          { return "execution reverted" }
+func (e dataErr) ErrorData() interface{} { return e.data }
+
+func TestClassifyHostileRevertData(t *testing.T) {
+	hostile := revertData(word(32), new(big.Int).SetUint64(^uint64(0)), []byte(strings.Repeat("x", 32)))
+	c := Classify(dataErr{common.Bytes2Hex(hostile)})
+	if c.Kind != Reverted || c.Reason != "" {
+		t.Errorf("Classify(hostile) = %v %q, want reverted without a reason", c.Kind, c.Reason)
+	}
+	c = Classify(dataErr{"0x" + common.Bytes2Hex(revertData(word(32), word(6), []byte("paused")))})
+	if c.Kind != Reverted || c.Reason != "paused" {
+		t.Errorf("Classify(valid) = %v %q, want reverted: paused", c.Kind, c.Reason)
+	}
+}
@@ -0,0 +1,65 @@
+package rpcmetrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is how many recent provider round trips Latency looks at.
+const latencyWindow = 64
+
+// latencies is a ring of recent provider round-trip times (relay calls excluded).
+type latencies struct {
+	mu   sync.Mutex
+	buf  [latencyWindow]time.Duration
+	n    int
+	next int
+}
+
+var recent latencies
+
+func (l *latencies) observe(d time.Duration) {
+	l.mu.Lock()
+	l.buf[l.next] = d
+	l.next = (l.next + 1) % latencyWindow
+	if l.n < latencyWindow {
+		l.n++
+	}
+	l.mu.Unlock()
+}
+
+// Latency returns the q-quantile (0..1) of the last round trips to the RPC provider and how
+// many samples it is based on (0 = nothing measured yet). Failed calls count with the time
+// they took, so timeouts push the estimate up.
+func Latency(q float64) (time.Duration, int) {
+	recent.mu.Lock()
+	s := append([]time.Duration(nil), recent.buf[:recent.n]...)
+	recent.mu.Unlock()
+	if len(s) == 0 {
+		return 0, 0
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	i := int(q * float64(len(s)-1))
+	if i < 0 {
+		i = 0
+	}
+	return s[i], len(s)
+}
+
+// ProviderCalls is the number of non-relay calls counted on Default so far.
+func ProviderCalls() int64 {
+	var n int64
+	for m, c := range Default.Snapshot() {
+		if !relayMethods[m] {
+			n += c
+		}
+	}
+	return n
+}
+
+func resetLatency() {
+	recent.mu.Lock()
+	recent.n, recent.next = 0, 0
+	recent.mu.Unlock()
+}
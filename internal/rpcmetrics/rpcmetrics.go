@@ -0,0 +1,237 @@
+// Package rpcmetrics counts JSON-RPC calls per method for the whole process and turns the
+// counts into an approximate provider bill ("this run cost ~N compute units"), so teams can
+// size RPC plans before large scans. Counting happens in an http.RoundTripper, so retries
+// and batch requests are billed the way providers bill them: per method call.
+package rpcmetrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/chaos"
+	"github.com/ligun0805/bundle-rescue/internal/relayrecord"
+)
+
+// Counter holds call counts per method.
+type Counter struct {
+	mu    sync.Mutex
+	calls map[string]int64
+}
+
+// Default is the process-wide counter used by Transport and Add.
+var Default = &Counter{}
+
+// Add counts one call of method on the Default counter (for clients we cannot wrap, e.g. relays).
+func Add(method string) { Default.Add(method) }
+
+// Add counts one call of method.
+func (c *Counter) Add(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = map[string]int64{}
+	}
+	c.calls[method]++
+}
+
+// Snapshot returns a copy of the counts.
+func (c *Counter) Snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int64, len(c.calls))
+	for k, v := range c.calls {
+		out[k] = v
+	}
+	return out
+}
+
+// Reset clears the counts (scheduled runs report per pass). On Default it also drops
+// the latency window.
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	c.calls = nil
+	c.mu.Unlock()
+	if c == Default {
+		resetLatency()
+	}
+}
+
+type countingTransport struct {
+	base http.RoundTripper
+	c    *Counter
+}
+
+// Transport wraps base (nil = http.DefaultTransport) and counts every JSON-RPC method
+// in request bodies, single or batch, on the Default counter. The chaos injector (dev builds)
+// sits under the counter, so injected failures and their retries show up in the report;
+// the relay submission record (relayrecord) sits under the injector and sees only real
+// exchanges.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &countingTransport{base: chaos.Wrap(relayrecord.Wrap(base)), c: Default}
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := false
+	if req.Body != nil && req.Method == http.MethodPost {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range methodsOf(body) {
+			t.c.Add(m)
+			provider = provider || !relayMethods[m]
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if provider && t.c == Default {
+		recent.observe(time.Since(start))
+	}
+	return resp, err
+}
+
+func methodsOf(body []byte) []string {
+	type msg struct {
+		Method string `json:"method"`
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []msg
+		if json.Unmarshal(body, &batch) != nil {
+			return nil
+		}
+		out := make([]string, 0, len(batch))
+		for _, m := range batch {
+			if m.Method != "" {
+				out = append(out, m.Method)
+			}
+		}
+		return out
+	}
+	var m msg
+	if json.Unmarshal(body, &m) != nil || m.Method == "" {
+		return nil
+	}
+	return []string{m.Method}
+}
+
+// relayMethods go to builders/relays, not to the RPC provider: counted, never billed.
+var relayMethods = map[string]bool{
+	"eth_sendBundle": true, "eth_callBundle": true, "mev_sendBundle": true, "mev_simBundle": true,
+	"eth_sendPrivateTransaction": true, "eth_sendPrivateRawTransaction": true, "blxr_submit_bundle": true,
+}
+
+// pricing is a per-method unit table plus the price of anything not listed.
+// Numbers follow the providers' public price sheets (approximate, they change).
+type pricing struct {
+	unit    string
+	def     int64
+	methods map[string]int64
+}
+
+var pricings = map[string]pricing{
+	"alchemy": {"Alchemy CU", 26, map[string]int64{
+		"eth_blockNumber": 10, "eth_chainId": 0, "eth_call": 26, "eth_estimateGas": 20, "eth_feeHistory": 10,
+		"eth_gasPrice": 20, "eth_getBalance": 19, "eth_getBlockByNumber": 16, "eth_getCode": 26, "eth_getLogs": 75,
+		"eth_getStorageAt": 17, "eth_getTransactionCount": 26, "eth_getTransactionReceipt": 15,
+		"eth_maxPriorityFeePerGas": 10, "eth_sendRawTransaction": 250,
+	}},
+	"infura": {"Infura credits", 80, map[string]int64{
+		"eth_chainId": 5, "eth_estimateGas": 300, "eth_getLogs": 255,
+	}},
+	"quicknode": {"QuickNode credits", 20, map[string]int64{
+		"eth_getLogs": 60,
+	}},
+	"flat": {"requests", 1, nil},
+}
+
+// Provider picks the pricing model: RPC_PRICING (alchemy|infura|quicknode|flat) or a guess from the URL.
+func Provider(rpcURL string) string {
+	if p := strings.ToLower(strings.TrimSpace(os.Getenv("RPC_PRICING"))); p != "" {
+		if _, ok := pricings[p]; ok {
+			return p
+		}
+	}
+	u := strings.ToLower(rpcURL)
+	switch {
+	case strings.Contains(u, "alchemy.com"):
+		return "alchemy"
+	case strings.Contains(u, "infura.io"):
+		return "infura"
+	case strings.Contains(u, "quiknode.pro"), strings.Contains(u, "quicknode"):
+		return "quicknode"
+	}
+	return "flat"
+}
+
+// Cost returns the estimated provider units for counts under provider's pricing.
+func Cost(provider string, counts map[string]int64) (units int64, unit string) {
+	pr, ok := pricings[provider]
+	if !ok {
+		pr = pricings["flat"]
+	}
+	for m, n := range counts {
+		if relayMethods[m] {
+			continue
+		}
+		price, ok := pr.methods[m]
+		if !ok {
+			price = pr.def
+		}
+		units += price * n
+	}
+	return units, pr.unit
+}
+
+// Report renders the Default counts as printable lines: per-method calls, relay calls
+// and the cost estimate for rpcURL's provider. Empty when nothing was counted.
+func Report(rpcURL string) []string {
+	counts := Default.Snapshot()
+	if len(counts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(counts))
+	for m := range counts {
+		names = append(names, m)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	var rpcParts, relayParts []string
+	var total int64
+	for _, m := range names {
+		part := fmt.Sprintf("%s=%d", m, counts[m])
+		if relayMethods[m] {
+			relayParts = append(relayParts, part)
+			continue
+		}
+		total += counts[m]
+		rpcParts = append(rpcParts, part)
+	}
+	lines := []string{fmt.Sprintf("[rpc] %d call(s): %s", total, strings.Join(rpcParts, " "))}
+	if len(relayParts) > 0 {
+		lines = append(lines, "[rpc] relays: "+strings.Join(relayParts, " "))
+	}
+	provider := Provider(rpcURL)
+	units, unit := Cost(provider, counts)
+	lines = append(lines, fmt.Sprintf("[rpc] this run cost ~%d %s (pricing=%s; set RPC_PRICING to override)", units, unit, provider))
+	return lines
+}
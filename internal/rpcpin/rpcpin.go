@@ -0,0 +1,130 @@
+// Package rpcpin pins JSON-RPC reads to one historical block (batchcli -at-block): an
+// http.RoundTripper rewrites the block argument of state reads from "latest"/"pending" (or an
+// omitted argument) to the pinned number and answers eth_blockNumber with it. Every read of
+// a scan — balances, restrictions, preflights, fee data — then sees the same state, which
+// makes triage snapshots reproducible. Needs an archive node for blocks older than ~128.
+package rpcpin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// blockArg is the position of the block argument per method.
+var blockArg = map[string]int{
+	"eth_call":                1,
+	"eth_estimateGas":         1,
+	"eth_createAccessList":    1,
+	"eth_simulateV1":          1,
+	"debug_traceCall":         1,
+	"eth_getBalance":          1,
+	"eth_getCode":             1,
+	"eth_getTransactionCount": 1,
+	"eth_getStorageAt":        2,
+	"eth_getProof":            2,
+	"eth_getBlockByNumber":    0,
+	"eth_feeHistory":          1,
+}
+
+// movingTags are the block tags that follow the chain tip.
+var movingTags = map[string]bool{"latest": true, "pending": true, "safe": true, "finalized": true}
+
+type request struct {
+	JSONRPC string            `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type pinTransport struct {
+	base  http.RoundTripper
+	block uint64
+	hex   json.RawMessage // "0x..." quoted
+}
+
+// Transport wraps base (nil = http.DefaultTransport) so that reads run at block; block 0
+// returns base unchanged.
+func Transport(base http.RoundTripper, block uint64) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if block == 0 {
+		return base
+	}
+	return &pinTransport{base: base, block: block, hex: json.RawMessage(strconv.Quote(fmt.Sprintf("0x%x", block)))}
+}
+
+func (t *pinTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(body)
+	var out []byte
+	if len(body) > 0 && body[0] == '[' {
+		var batch []request
+		if json.Unmarshal(body, &batch) == nil {
+			for i := range batch {
+				t.pin(&batch[i])
+			}
+			out, _ = json.Marshal(batch)
+		}
+	} else {
+		var r request
+		if json.Unmarshal(body, &r) == nil {
+			if r.Method == "eth_blockNumber" {
+				return t.blockNumber(req, r.ID), nil
+			}
+			t.pin(&r)
+			out, _ = json.Marshal(r)
+		}
+	}
+	if out == nil {
+		out = body // not JSON-RPC we understand: pass through
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(out))
+	req.ContentLength = int64(len(out))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(out)), nil }
+	return t.base.RoundTrip(req)
+}
+
+// pin replaces a moving block tag (or the omitted block argument) of r with the pinned block.
+func (t *pinTransport) pin(r *request) {
+	i, ok := blockArg[r.Method]
+	if !ok {
+		return
+	}
+	switch {
+	case i == len(r.Params):
+		r.Params = append(r.Params, t.hex)
+	case i < len(r.Params):
+		var tag string
+		if json.Unmarshal(r.Params[i], &tag) == nil && movingTags[tag] {
+			r.Params[i] = t.hex
+		}
+	}
+}
+
+// blockNumber answers eth_blockNumber locally: the head of a pinned scan is the pinned block.
+func (t *pinTransport) blockNumber(req *http.Request, id json.RawMessage) *http.Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	b := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, id, t.hex))
+	return &http.Response{
+		Status: "200 OK", StatusCode: http.StatusOK, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,249 @@
+// Package rpcpool spreads JSON-RPC traffic over several equivalent endpoints with failover.
+// The Pool is an http.RoundTripper: every client dialed with it (ethclient, the raw rpc
+// client used for stateOverrides) shares the same endpoint choice and health scores.
+// Requests go to the current endpoint; a 429, a 5xx or a transport error/timeout lowers its
+// score, puts it on a short cooldown and rotates to the healthiest other endpoint, and the
+// request is retried there once.
+package rpcpool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	scoreAlpha   = 0.2 // EWMA weight of the newest outcome
+	baseCooldown = 15 * time.Second
+	maxCooldown  = 5 * time.Minute
+)
+
+type endpoint struct {
+	raw       string
+	u         *url.URL
+	score     float64 // EWMA of successes, 1 = healthy
+	streak    int     // consecutive failures
+	downUntil time.Time
+	calls     int64
+	fails     int64
+}
+
+// Pool is a failover set of RPC endpoints.
+type Pool struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	eps     []*endpoint
+	cur     int
+	rotated int
+}
+
+// Split parses a comma-separated endpoint list.
+func Split(list string) []string {
+	var out []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// New builds a pool over urls (in priority order); base nil = http.DefaultTransport.
+func New(urls []string, base http.RoundTripper) (*Pool, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	p := &Pool{base: base}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("rpc endpoint %q: need an http(s) URL", Host(raw))
+		}
+		p.eps = append(p.eps, &endpoint{raw: raw, u: u, score: 1})
+	}
+	if len(p.eps) == 0 {
+		return nil, errors.New("no rpc endpoint")
+	}
+	return p, nil
+}
+
+// URL is the address clients are dialed with; the pool rewrites it per request.
+func (p *Pool) URL() string { return p.eps[0].raw }
+
+// Len is the number of endpoints.
+func (p *Pool) Len() int { return len(p.eps) }
+
+// Endpoints returns the configured URLs.
+func (p *Pool) Endpoints() []string {
+	out := make([]string, len(p.eps))
+	for i, e := range p.eps {
+		out[i] = e.raw
+	}
+	return out
+}
+
+// MarkDown takes an endpoint out of rotation for a cooldown (e.g. failed startup check).
+func (p *Pool) MarkDown(raw string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, e := range p.eps {
+		if e.raw == raw {
+			p.failLocked(i)
+		}
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	tries := min(2, len(p.eps))
+	var lastResp *http.Response
+	var lastErr error
+	for t := 0; t < tries; t++ {
+		i, e := p.pick()
+		r := req.Clone(req.Context())
+		r.URL = cloneURL(e.u)
+		r.Host = ""
+		if e.u.User != nil {
+			pw, _ := e.u.User.Password()
+			r.SetBasicAuth(e.u.User.Username(), pw)
+		}
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+		resp, err := p.base.RoundTrip(r)
+		if !failed(resp, err) {
+			p.record(i, true)
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return resp, err // caller gave up: not the endpoint's fault, don't retry
+		}
+		p.record(i, false)
+		if lastResp != nil {
+			lastResp.Body.Close()
+		}
+		lastResp, lastErr = resp, err
+	}
+	return lastResp, lastErr
+}
+
+// failed: transport errors, 429 and 5xx count against the endpoint.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func (p *Pool) pick() (int, *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := time.Now(); now.Before(p.eps[p.cur].downUntil) {
+		p.cur = p.bestLocked(now)
+	}
+	return p.cur, p.eps[p.cur]
+}
+
+// bestLocked prefers endpoints out of cooldown, then the higher score, then config order;
+// when all are cooling down, the one that recovers first.
+func (p *Pool) bestLocked(now time.Time) int {
+	best := -1
+	for i, e := range p.eps {
+		if now.Before(e.downUntil) {
+			continue
+		}
+		if best < 0 || e.score > p.eps[best].score {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	best = 0
+	for i, e := range p.eps {
+		if e.downUntil.Before(p.eps[best].downUntil) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (p *Pool) record(i int, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.eps[i]
+	e.calls++
+	if ok {
+		e.score = e.score*(1-scoreAlpha) + scoreAlpha
+		e.streak = 0
+		return
+	}
+	p.failLocked(i)
+}
+
+func (p *Pool) failLocked(i int) {
+	e := p.eps[i]
+	e.fails++
+	e.score *= 1 - scoreAlpha
+	e.streak++
+	cd := baseCooldown << min(e.streak-1, 5)
+	if cd > maxCooldown {
+		cd = maxCooldown
+	}
+	e.downUntil = time.Now().Add(cd)
+	if i == p.cur && len(p.eps) > 1 {
+		if next := p.bestLocked(time.Now()); next != p.cur {
+			p.cur = next
+			p.rotated++
+		}
+	}
+}
+
+// Report prints one health line per endpoint (hosts only: URLs carry API keys). Safe on nil.
+func (p *Pool) Report() []string {
+	if p == nil || len(p.eps) < 2 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lines := []string{fmt.Sprintf("[rpc-pool] %d endpoint(s), %d rotation(s)", len(p.eps), p.rotated)}
+	for i, e := range p.eps {
+		mark := " "
+		if i == p.cur {
+			mark = "*"
+		}
+		lines = append(lines, fmt.Sprintf("[rpc-pool] %s %-32s calls=%d fails=%d health=%.2f", mark, Host(e.raw), e.calls, e.fails, e.score))
+	}
+	return lines
+}
+
+// Host keeps only the host of an endpoint URL.
+func Host(raw string) string {
+	if u, err := url.Parse(strings.TrimSpace(raw)); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "endpoint"
+}
+
+func cloneURL(u *url.URL) *url.URL {
+	c := *u
+	c.User = nil
+	return &c
+}
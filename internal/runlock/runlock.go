@@ -0,0 +1,106 @@
+// Package runlock is an advisory per-sponsor lock: only one sending run (GUI, bundlecli)
+// may use a SAFE key at a time, otherwise both sign with the same nonces and one of them
+// loses every bundle. The lock is a small JSON file named after the sponsor address in a
+// directory shared by all tools (RUN_LOCK_DIR, default <tmp>/bundle-rescue-locks). A lock
+// whose process is gone (same host) is taken over automatically.
+package runlock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Info describes the holder of a lock.
+type Info struct {
+	Sponsor string `json:"sponsor"`
+	Tool    string `json:"tool"`
+	PID     int    `json:"pid"`
+	Host    string `json:"host"`
+	Started string `json:"started"`
+}
+
+// HeldError is returned by Acquire when another live run holds the sponsor.
+type HeldError struct{ Holder Info }
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("SAFE %s is in use by %s (pid %d on %s, since %s): concurrent runs collide on SAFE nonces",
+		e.Holder.Sponsor, e.Holder.Tool, e.Holder.PID, e.Holder.Host, e.Holder.Started)
+}
+
+// Lock is a held lock; Release it when the run ends.
+type Lock struct {
+	path string
+	info Info
+}
+
+// Dir is where lock files live.
+func Dir() string {
+	if d := strings.TrimSpace(os.Getenv("RUN_LOCK_DIR")); d != "" {
+		return d
+	}
+	return filepath.Join(os.TempDir(), "bundle-rescue-locks")
+}
+
+// Acquire takes the lock for sponsor. A *HeldError means another live run has it.
+func Acquire(sponsor common.Address, tool string) (*Lock, error) {
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	l := &Lock{
+		path: filepath.Join(dir, strings.ToLower(sponsor.Hex())+".lock"),
+		info: Info{Sponsor: sponsor.Hex(), Tool: tool, PID: os.Getpid(), Host: host, Started: time.Now().Format(time.RFC3339)},
+	}
+	b, _ := json.MarshalIndent(l.info, "", "  ")
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, werr := f.Write(append(b, '\n'))
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				_ = os.Remove(l.path)
+				return nil, werr
+			}
+			return l, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		holder, rerr := read(l.path)
+		if rerr == nil && !(holder.Host == host && !alive(holder.PID)) {
+			return nil, &HeldError{Holder: holder}
+		}
+		// stale (process gone) or unreadable: take it over
+		_ = os.Remove(l.path)
+	}
+	return nil, fmt.Errorf("run lock %s: could not be created", l.path)
+}
+
+// Release removes the lock file if it is still ours. Safe on nil.
+func (l *Lock) Release() {
+	if l == nil {
+		return
+	}
+	if cur, err := read(l.path); err == nil && cur.PID == l.info.PID && cur.Host == l.info.Host {
+		_ = os.Remove(l.path)
+	}
+}
+
+func read(path string) (Info, error) {
+	var in Info
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return in, err
+	}
+	return in, json.Unmarshal(b, &in)
+}
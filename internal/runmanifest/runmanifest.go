@@ -0,0 +1,210 @@
+// Package runmanifest records what produced a run: binary version, a hash of the effective
+// config, a hash of the input, chain ID, the block range the run saw and the relay set.
+// A manifest is written next to the run's outputs, so any OK/BAD row or relay verdict can be
+// traced back to the exact inputs. Secrets never enter the manifest: callers pass derived
+// values (addresses, fingerprints) instead of private keys, and only hashes are stored.
+package runmanifest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ligun0805/bundle-rescue/internal/reportschema"
+)
+
+// Version is set at build time: go build -ldflags "-X github.com/ligun0805/bundle-rescue/internal/runmanifest.Version=v1.2.3".
+// Empty means "take the VCS revision from the build info".
+var Version = ""
+
+// Manifest is the JSON document written per run.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"` // reportschema.Version
+	Tool          string            `json:"tool"`
+	Version       string            `json:"version"`
+	GoVersion     string            `json:"goVersion"`
+	RunID         string            `json:"runId,omitempty"`
+	StartedAt     string            `json:"startedAt"`
+	FinishedAt    string            `json:"finishedAt,omitempty"`
+	ConfigHash    string            `json:"configHash"`
+	Config        map[string]string `json:"config"` // the hashed settings (no secrets)
+	InputPath     string            `json:"inputPath,omitempty"`
+	InputHash     string            `json:"inputHash,omitempty"`
+	ChainID       string            `json:"chainId,omitempty"`
+	BlockFrom     uint64            `json:"blockFrom,omitempty"`
+	BlockTo       uint64            `json:"blockTo,omitempty"`
+	Relays        []string          `json:"relays,omitempty"`
+	Outputs       []string          `json:"outputs,omitempty"`
+	Result        string            `json:"result,omitempty"`
+	ReasonCodes   map[string]int    `json:"reasonCodes,omitempty"` // BAD verdicts by reason code (internal/reasons)
+}
+
+// New starts a manifest for tool with the given (non-secret) settings.
+func New(tool, runID string, config map[string]string) *Manifest {
+	if config == nil {
+		config = map[string]string{}
+	}
+	return &Manifest{
+		SchemaVersion: reportschema.Version,
+		Tool:          tool,
+		Version:       BinaryVersion(),
+		GoVersion:     runtime.Version(),
+		RunID:         runID,
+		StartedAt:     time.Now().UTC().Format(time.RFC3339),
+		ConfigHash:    HashConfig(config),
+		Config:        config,
+	}
+}
+
+// BinaryVersion is Version, or "<vcs.revision>[+dirty]" from the build info, or "devel".
+func BinaryVersion() string {
+	if Version != "" {
+		return Version
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	rev, dirty := "", false
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if rev == "" {
+		if v := bi.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+		return "devel"
+	}
+	if dirty {
+		rev += "+dirty"
+	}
+	return rev
+}
+
+// HashConfig is sha256 over the sorted "key=value" lines, so map order doesn't matter.
+func HashConfig(config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, config[k])
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// HashBytes is the input hash ("sha256:<hex>").
+func HashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// InputHasher computes HashBytes of an input that is streamed instead of held in memory:
+// write the bytes to it as they are read, then take Sum.
+type InputHasher struct{ h hash.Hash }
+
+func NewInputHasher() *InputHasher { return &InputHasher{h: sha256.New()} }
+
+func (ih *InputHasher) Write(b []byte) (int, error) { return ih.h.Write(b) }
+
+// Sum is the hash of the bytes written so far, in the HashBytes format.
+func (ih *InputHasher) Sum() string { return "sha256:" + hex.EncodeToString(ih.h.Sum(nil)) }
+
+// SetInput records the input path and the hash of the exact bytes the run read.
+func (m *Manifest) SetInput(path string, data []byte) {
+	m.SetInputHash(path, HashBytes(data))
+}
+
+// SetInputHash is SetInput for a streamed input whose hash was computed while reading.
+func (m *Manifest) SetInputHash(path, hash string) {
+	m.InputPath = path
+	m.InputHash = hash
+}
+
+// SetRelays records the relay set (trimmed, empty entries dropped).
+func (m *Manifest) SetRelays(relays []string) {
+	m.Relays = nil
+	for _, r := range relays {
+		if r = strings.TrimSpace(r); r != "" {
+			m.Relays = append(m.Relays, r)
+		}
+	}
+}
+
+// SetChainID records the chain ID (nil is ignored).
+func (m *Manifest) SetChainID(id *big.Int) {
+	if id != nil {
+		m.ChainID = id.String()
+	}
+}
+
+// HeadReader is the one ethclient method used for the block range.
+type HeadReader interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// MarkBlock widens [BlockFrom, BlockTo] with the current head. Call it at start and end.
+// RPC errors are ignored: the range is informational.
+func (m *Manifest) MarkBlock(ctx context.Context, ec HeadReader) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	n, err := ec.BlockNumber(ctx)
+	if err != nil {
+		return
+	}
+	if m.BlockFrom == 0 || n < m.BlockFrom {
+		m.BlockFrom = n
+	}
+	if n > m.BlockTo {
+		m.BlockTo = n
+	}
+}
+
+// Write stamps FinishedAt and writes the manifest as indented JSON to path.
+func (m *Manifest) Write(path string) error {
+	m.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Endpoint keeps scheme://host of an RPC URL: provider URLs often carry the API key in the
+// path or query, and that must not end up in a manifest.
+func Endpoint(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// PathFor returns "<out without extension>.manifest.json", e.g. ok_pairs.csv -> ok_pairs.manifest.json.
+func PathFor(out string) string {
+	return strings.TrimSuffix(out, filepath.Ext(out)) + ".manifest.json"
+}
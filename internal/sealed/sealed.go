@@ -0,0 +1,97 @@
+// Package sealed is the encrypted container for input files with private keys
+// (batchcli -input, "batchcli seal"). The plaintext only ever exists in memory.
+//
+// Layout: "BRSEAL1\n" | logN | r | p | salt[16] | nonce[12] | AES-256-GCM ciphertext.
+// The key is scrypt(passphrase, salt, 2^logN, r, p); the header is the GCM additional
+// data, so tampering with the parameters fails authentication like any other edit.
+package sealed
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Magic starts every sealed file.
+const Magic = "BRSEAL1\n"
+
+// MinPassphrase is the shortest passphrase Seal accepts.
+const MinPassphrase = 8
+
+const (
+	logN      = 17 // scrypt N = 131072: ~0.3s and 128 MiB per attempt
+	scryptR   = 8
+	scryptP   = 1
+	saltLen   = 16
+	nonceLen  = 12
+	headerLen = len(Magic) + 3 + saltLen + nonceLen
+)
+
+// ErrPassphrase is returned by Open for a wrong passphrase (or a modified file:
+// GCM cannot tell the two apart).
+var ErrPassphrase = errors.New("sealed input: wrong passphrase or corrupted file")
+
+// IsSealed reports whether data starts like a sealed container.
+func IsSealed(data []byte) bool { return bytes.HasPrefix(data, []byte(Magic)) }
+
+// Seal encrypts plaintext under passphrase.
+func Seal(plaintext []byte, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphrase {
+		return nil, fmt.Errorf("passphrase too short (min %d characters)", MinPassphrase)
+	}
+	hdr := make([]byte, headerLen)
+	copy(hdr, Magic)
+	hdr[len(Magic)], hdr[len(Magic)+1], hdr[len(Magic)+2] = logN, scryptR, scryptP
+	if _, err := rand.Read(hdr[len(Magic)+3:]); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(hdr, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := hdr[headerLen-nonceLen:]
+	return aead.Seal(hdr, nonce, plaintext, hdr), nil
+}
+
+// Open decrypts a container produced by Seal.
+func Open(data []byte, passphrase string) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, errors.New("sealed input: not a sealed file")
+	}
+	if len(data) < headerLen+16 {
+		return nil, errors.New("sealed input: truncated file")
+	}
+	hdr := data[:headerLen]
+	aead, err := newAEAD(hdr, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := aead.Open(nil, hdr[headerLen-nonceLen:], data[headerLen:], hdr)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return pt, nil
+}
+
+// newAEAD derives the AES-GCM key from the parameters and salt stored in hdr.
+func newAEAD(hdr []byte, passphrase string) (cipher.AEAD, error) {
+	n, r, p := hdr[len(Magic)], hdr[len(Magic)+1], hdr[len(Magic)+2]
+	if n < 10 || n > 22 || r == 0 || r > 32 || p == 0 || p > 16 {
+		return nil, fmt.Errorf("sealed input: unsupported scrypt parameters logN=%d r=%d p=%d", n, r, p)
+	}
+	salt := hdr[len(Magic)+3 : len(Magic)+3+saltLen]
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<n, int(r), int(p), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
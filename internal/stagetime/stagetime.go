@@ -0,0 +1,151 @@
+// Package stagetime adds up where a run's time goes, per pipeline stage (metadata reads,
+// restriction checks, preflights, building and signing, relay simulation and submission,
+// waiting for inclusion), and says at the end whether RPC reads or relay round trips
+// dominate: what to optimize first in a given operator's environment. Parallel workers
+// time their stages concurrently, so the sums can exceed the run's wall time.
+package stagetime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stages, in pipeline order.
+const (
+	Metadata      = "metadata"
+	Restrictions  = "restrictions"
+	Preflight     = "preflight"
+	BuildSign     = "build/sign"
+	Simulate      = "simulate"
+	RelaySend     = "relay send"
+	InclusionWait = "inclusion wait"
+)
+
+var order = []string{Metadata, Restrictions, Preflight, BuildSign, Simulate, RelaySend, InclusionWait}
+
+// kind groups the stages by what bounds them: the RPC provider, the relays, or the chain
+// (block time, nothing to optimize).
+var kind = map[string]string{
+	Metadata: "rpc", Restrictions: "rpc", Preflight: "rpc", BuildSign: "rpc",
+	Simulate: "relay", RelaySend: "relay",
+	InclusionWait: "chain",
+}
+
+type stat struct {
+	total time.Duration
+	n     int
+}
+
+// Timer holds the totals per stage.
+type Timer struct {
+	mu    sync.Mutex
+	stats map[string]*stat
+}
+
+// Default is the process-wide timer used by Add and Since.
+var Default = &Timer{}
+
+// Add records d spent in stage on Default.
+func Add(stage string, d time.Duration) { Default.Add(stage, d) }
+
+// Since records the time since start in stage on Default: defer stagetime.Since(stage, time.Now()).
+func Since(stage string, start time.Time) { Default.Add(stage, time.Since(start)) }
+
+// Reset clears Default (scheduled runs report per pass).
+func Reset() { Default.Reset() }
+
+// Report renders Default (see Timer.Report).
+func Report() []string { return Default.Report() }
+
+// Add records d spent in stage.
+func (t *Timer) Add(stage string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stats == nil {
+		t.stats = map[string]*stat{}
+	}
+	s := t.stats[stage]
+	if s == nil {
+		s = &stat{}
+		t.stats[stage] = s
+	}
+	s.total += d
+	s.n++
+}
+
+// Reset clears the totals.
+func (t *Timer) Reset() {
+	t.mu.Lock()
+	t.stats = nil
+	t.mu.Unlock()
+}
+
+// Report is one line with every timed stage (total, share, count, average) and one verdict
+// line; nil when nothing was timed.
+func (t *Timer) Report() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.stats) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(t.stats))
+	var all time.Duration
+	for name, s := range t.stats {
+		names = append(names, name)
+		all += s.total
+	}
+	rank := func(name string) int {
+		for i, o := range order {
+			if o == name {
+				return i
+			}
+		}
+		return len(order)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ri, rj := rank(names[i]), rank(names[j]); ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	byKind := map[string]time.Duration{}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		s := t.stats[name]
+		byKind[kind[name]] += s.total
+		parts = append(parts, fmt.Sprintf("%s %s (%s, %d×, avg %s)", name, round(s.total), pct(s.total, all), s.n, round(s.total/time.Duration(s.n))))
+	}
+	lines := []string{"[stages] " + strings.Join(parts, " · ")}
+
+	rpc, relay, chain := byKind["rpc"], byKind["relay"], byKind["chain"]
+	verdict := "RPC reads dominate: a faster or closer RPC (or more workers, caching) pays off first"
+	if relay > rpc {
+		verdict = "relay round trips dominate: closer or fewer relays pay off first"
+	}
+	line := fmt.Sprintf("[stages] RPC %s (%s), relays %s (%s)", round(rpc), pct(rpc, all), round(relay), pct(relay, all))
+	if chain > 0 {
+		line += fmt.Sprintf(", waiting for blocks %s (%s)", round(chain), pct(chain, all))
+	}
+	if rpc+relay > 0 {
+		line += ": " + verdict
+	}
+	return append(lines, line)
+}
+
+func pct(d, all time.Duration) string {
+	if all <= 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(d)/float64(all))
+}
+
+// round keeps durations readable: ms below 10s, 0.1s above.
+func round(d time.Duration) time.Duration {
+	if d < 10*time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
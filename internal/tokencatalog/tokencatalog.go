@@ -0,0 +1,224 @@
+// Package tokencatalog keeps a cumulative, per-team record of every token the scanners have
+// met: symbol, decimals, risk score, verification status, first/last seen and the last
+// verdict. It lives in one JSON file that every scan updates, so a repeated engagement starts
+// from what earlier runs already learned. Export renders it as JSON or CSV for browsing.
+package tokencatalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verified values.
+const (
+	VerifiedYes     = "yes"
+	VerifiedNo      = "no"
+	VerifiedUnknown = ""
+)
+
+// Entry is what the catalog knows about one token on one chain.
+type Entry struct {
+	ChainID     string   `json:"chainId"`
+	Address     string   `json:"address"`
+	Symbol      string   `json:"symbol,omitempty"`
+	Decimals    *int     `json:"decimals,omitempty"`  // nil = never read successfully
+	RiskScore   *int     `json:"riskScore,omitempty"` // spam/risk score of the last scan that computed one
+	RiskReasons []string `json:"riskReasons,omitempty"`
+	Verified    string   `json:"verified,omitempty"` // yes | no | "" (unknown)
+	FirstSeen   string   `json:"firstSeen"`
+	LastSeen    string   `json:"lastSeen"`
+	Scans       int      `json:"scans"`                 // pairs checked with this token, all runs
+	LastVerdict string   `json:"lastVerdict,omitempty"` // ok | bad | spam
+	LastReason  string   `json:"lastReason,omitempty"`
+}
+
+// Observation is one pair's worth of facts about a token. Zero fields mean "not learned
+// this time" and never overwrite what the catalog already has.
+type Observation struct {
+	ChainID     string
+	Address     string
+	Symbol      string
+	Decimals    *int
+	RiskScore   *int
+	RiskReasons []string
+	Verified    string
+	Verdict     string
+	Reason      string
+}
+
+// Catalog is the loaded file. Safe for concurrent Observe calls.
+type Catalog struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*Entry // key: chainId:address (lower-case)
+	added   int
+}
+
+type file struct {
+	Updated string   `json:"updated"`
+	Tokens  []*Entry `json:"tokens"`
+}
+
+// Load reads path; a missing file is an empty catalog.
+func Load(path string) (*Catalog, error) {
+	c := &Catalog{path: path, entries: map[string]*Entry{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("token catalog %s: %w", path, err)
+	}
+	for _, e := range f.Tokens {
+		c.entries[key(e.ChainID, e.Address)] = e
+	}
+	return c, nil
+}
+
+func key(chainID, addr string) string {
+	return strings.ToLower(strings.TrimSpace(chainID)) + ":" + strings.ToLower(strings.TrimSpace(addr))
+}
+
+// Observe merges o into the catalog.
+func (c *Catalog) Observe(o Observation) {
+	if strings.TrimSpace(o.Address) == "" {
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := key(o.ChainID, o.Address)
+	e := c.entries[k]
+	if e == nil {
+		e = &Entry{ChainID: o.ChainID, Address: strings.ToLower(o.Address), FirstSeen: now}
+		c.entries[k] = e
+		c.added++
+	}
+	e.LastSeen = now
+	e.Scans++
+	if o.Symbol != "" {
+		e.Symbol = o.Symbol
+	}
+	if o.Decimals != nil {
+		d := *o.Decimals
+		e.Decimals = &d
+	}
+	if o.RiskScore != nil {
+		s := *o.RiskScore
+		e.RiskScore, e.RiskReasons = &s, o.RiskReasons
+	}
+	if o.Verified != VerifiedUnknown {
+		e.Verified = o.Verified
+	}
+	if o.Verdict != "" {
+		e.LastVerdict, e.LastReason = o.Verdict, o.Reason
+	}
+}
+
+// Len returns the number of tokens and how many were added since Load.
+func (c *Catalog) Len() (total, added int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.added
+}
+
+// Lookup returns a copy of the entry for addr on chainID (ok=false if never seen).
+func (c *Catalog) Lookup(chainID, addr string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key(chainID, addr)]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Entries returns the tokens sorted by chain, then address.
+func (c *Catalog) Entries() []*Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		cp := *e
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ChainID != out[j].ChainID {
+			return out[i].ChainID < out[j].ChainID
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+// Save writes the catalog back (temp file + rename, so a crash never truncates it).
+func (c *Catalog) Save() error {
+	b, err := json.MarshalIndent(file{Updated: time.Now().UTC().Format(time.RFC3339), Tokens: c.Entries()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".token_catalog_*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Export writes the catalog as CSV or JSON (an array of entries), by format "csv" or "json".
+func (c *Catalog) Export(w io.Writer, format string) error {
+	entries := c.Entries()
+	switch strings.ToLower(format) {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"chainId", "address", "symbol", "decimals", "riskScore", "riskReasons", "verified", "firstSeen", "lastSeen", "scans", "lastVerdict", "lastReason"})
+		for _, e := range entries {
+			_ = cw.Write([]string{e.ChainID, e.Address, e.Symbol, intOrEmpty(e.Decimals), intOrEmpty(e.RiskScore), strings.Join(e.RiskReasons, "; "),
+				e.Verified, e.FirstSeen, e.LastSeen, strconv.Itoa(e.Scans), e.LastVerdict, e.LastReason})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown export format %q (csv or json)", format)
+}
+
+// FormatOf picks the export format from a file name: .json -> json, anything else csv.
+func FormatOf(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return "json"
+	}
+	return "csv"
+}
+
+func intOrEmpty(p *int) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.Itoa(*p)
+}
@@ -0,0 +1,203 @@
+// Package triage is the analyst annotation layer on top of scan results: each (from, token)
+// pair can be marked approved (may be executed), review (needs a closer look) or declined
+// (the client said no). Marks live in a sidecar CSV next to the pair lists, so they survive
+// re-scans and can be edited by hand; executors run only approved pairs when a sidecar is
+// configured. A "*" token marks every token of the wallet; an exact pair mark wins over it.
+package triage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Status is a triage mark. Values are part of the sidecar format: never rename.
+type Status string
+
+const (
+	Approved Status = "approved" // may be executed
+	Review   Status = "review"   // needs review before execution
+	Declined Status = "declined" // client declined: never execute
+	Unmarked Status = ""         // no mark (executors skip it)
+)
+
+// AnyToken is the token column value that marks all tokens of a wallet.
+const AnyToken = "*"
+
+// ParseStatus accepts the status names and the long forms used in docs ("needs-review",
+// "client-declined", "approved-for-execution"); "clear"/"none" = Unmarked.
+func ParseStatus(s string) (Status, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "approved", "approve", "approved-for-execution":
+		return Approved, nil
+	case "review", "needs-review":
+		return Review, nil
+	case "declined", "decline", "client-declined":
+		return Declined, nil
+	case "clear", "none", "unmarked":
+		return Unmarked, nil
+	}
+	return "", fmt.Errorf("triage status %q: expected approved, review, declined or clear", s)
+}
+
+// Entry is one mark.
+type Entry struct {
+	From    common.Address
+	Token   string // checksummed address or AnyToken
+	Status  Status
+	Note    string
+	Updated time.Time
+}
+
+// Store is a loaded sidecar file.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+var header = []string{"from", "token", "status", "note", "updated"}
+
+// Load reads path; a missing file is an empty store (created on Save).
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, entries: map[string]Entry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("triage %s: %w", path, err)
+	}
+	for i, row := range rows {
+		if len(row) == 0 || (i == 0 && strings.EqualFold(strings.TrimSpace(row[0]), "from")) {
+			continue
+		}
+		if len(row) < 3 {
+			return nil, fmt.Errorf("triage %s line %d: expected from,token,status[,note,updated]", path, i+1)
+		}
+		st, err := ParseStatus(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("triage %s line %d: %w", path, i+1, err)
+		}
+		from, token := strings.TrimSpace(row[0]), strings.TrimSpace(row[1])
+		if !common.IsHexAddress(from) || (token != AnyToken && !common.IsHexAddress(token)) {
+			return nil, fmt.Errorf("triage %s line %d: bad from/token", path, i+1)
+		}
+		e := Entry{From: common.HexToAddress(from), Token: normToken(token), Status: st}
+		if len(row) > 3 {
+			e.Note = row[3]
+		}
+		if len(row) > 4 {
+			e.Updated, _ = time.Parse(time.RFC3339, strings.TrimSpace(row[4]))
+		}
+		if st != Unmarked {
+			s.entries[key(e.From, e.Token)] = e
+		}
+	}
+	return s, nil
+}
+
+// Path is the sidecar file of the store.
+func (s *Store) Path() string { return s.path }
+
+// Status is the mark of (from, token): the exact pair, else the wallet-wide mark.
+func (s *Store) Status(from, token common.Address) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key(from, token.Hex())]; ok {
+		return e.Status
+	}
+	return s.entries[key(from, AnyToken)].Status
+}
+
+// Set marks (from, token); token may be AnyToken. Unmarked removes the mark.
+func (s *Store) Set(from common.Address, token string, st Status, note string) error {
+	token = strings.TrimSpace(token)
+	if token != AnyToken && !common.IsHexAddress(token) {
+		return fmt.Errorf("bad token %q", token)
+	}
+	token = normToken(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st == Unmarked {
+		delete(s.entries, key(from, token))
+		return nil
+	}
+	s.entries[key(from, token)] = Entry{From: from, Token: token, Status: st, Note: note, Updated: time.Now().UTC()}
+	return nil
+}
+
+// Entries returns the marks sorted by wallet, then token.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From != out[j].From {
+			return out[i].From.Hex() < out[j].From.Hex()
+		}
+		return out[i].Token < out[j].Token
+	})
+	return out
+}
+
+// Counts returns the number of marks per status.
+func (s *Store) Counts() map[Status]int {
+	out := map[Status]int{}
+	for _, e := range s.Entries() {
+		out[e.Status]++
+	}
+	return out
+}
+
+// Save writes the store back to its file (temp file + rename).
+func (s *Store) Save() error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(header)
+	for _, e := range s.Entries() {
+		upd := ""
+		if !e.Updated.IsZero() {
+			upd = e.Updated.Format(time.RFC3339)
+		}
+		_ = w.Write([]string{e.From.Hex(), e.Token, string(e.Status), e.Note, upd})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func normToken(t string) string {
+	if t == AnyToken {
+		return t
+	}
+	return common.HexToAddress(t).Hex()
+}
+
+func key(from common.Address, token string) string {
+	return strings.ToLower(from.Hex() + "|" + token)
+}
@@ -0,0 +1,134 @@
+// Package units formats raw on-chain amounts (wei / smallest token unit) as decimal token
+// amounts with a configurable number of fractional digits. Every tool writes raw wei next to
+// the formatted value in its reports, so downstream tooling never has to parse the rounded
+// one.
+package units
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Full keeps every fractional digit.
+const Full = -1
+
+// Format renders wei as a decimal amount with at most precision fractional digits
+// (truncated, trailing zeros trimmed). precision < 0 (Full) keeps all decimals.
+// nil is "0"; decimals <= 0 returns the raw integer.
+func Format(wei *big.Int, decimals, precision int) string {
+	if wei == nil {
+		return "0"
+	}
+	if decimals <= 0 {
+		return wei.String()
+	}
+	s := new(big.Int).Abs(wei).String()
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	intPart, frac := s[:len(s)-decimals], s[len(s)-decimals:]
+	if precision >= 0 && precision < len(frac) {
+		frac = frac[:precision]
+	}
+	frac = strings.TrimRight(frac, "0")
+	out := intPart
+	if frac != "" {
+		out += "." + frac
+	}
+	if wei.Sign() < 0 && out != "0" {
+		out = "-" + out
+	}
+	return out
+}
+
+// FormatString is Format for a decimal wei string; unparseable input is returned as is.
+func FormatString(wei string, decimals, precision int) string {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(wei), 10)
+	if !ok {
+		return wei
+	}
+	return Format(v, decimals, precision)
+}
+
+// WeiString is the raw column value: "" for nil so an unknown balance stays distinguishable from 0.
+func WeiString(wei *big.Int) string {
+	if wei == nil {
+		return ""
+	}
+	return wei.String()
+}
+
+// ParsePrecision reads a precision setting: "", "full", "all" or a negative number mean Full.
+func ParsePrecision(s string, def int) int {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return def
+	case "full", "all":
+		return Full
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return def
+	}
+	if n < 0 {
+		return Full
+	}
+	return n
+}
+
+// MaxDecimals is the largest decimals() an ERC-20 can report (it is a uint8).
+const MaxDecimals = 255
+
+// Parse converts a decimal token amount ("1.5", "42") to the smallest unit of a
+// token with the given decimals (decimals < 0 means 18). Trailing fractional zeros are not
+// precision ("5.0" is fine for a 0-decimals token); any other digit past decimals is an error.
+func Parse(amount string, decimals int) (*big.Int, error) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return nil, fmt.Errorf("empty amount")
+	}
+	if decimals < 0 {
+		decimals = 18
+	}
+	if decimals > MaxDecimals {
+		return nil, fmt.Errorf("decimals %d out of range (max %d)", decimals, MaxDecimals)
+	}
+	intPart, fracPart, _ := strings.Cut(amount, ".")
+	if intPart == "" && fracPart == "" {
+		return nil, fmt.Errorf("bad amount %q", amount)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		if strings.Trim(part, "0123456789") != "" {
+			return nil, fmt.Errorf("bad amount %q", amount)
+		}
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > decimals {
+		return nil, fmt.Errorf("too many fractional digits for %d decimals", decimals)
+	}
+	clean := strings.TrimLeft(intPart+fracPart+strings.Repeat("0", decimals-len(fracPart)), "0")
+	if clean == "" {
+		return big.NewInt(0), nil
+	}
+	v, _ := new(big.Int).SetString(clean, 10)
+	return v, nil
+}
+
+// DecodeDecimals reads the return data of decimals(): an ABI uint8 in a 32-byte word. Empty
+// data (a token without the getter) is the 18 default; a value that does not fit uint8 is an
+// error rather than a wrapped or overflowing number that would poison every amount.
+func DecodeDecimals(res []byte) (int, error) {
+	if len(res) == 0 {
+		return 18, nil
+	}
+	if len(res) > 32 {
+		res = res[:32]
+	}
+	v := new(big.Int).SetBytes(res)
+	if !v.IsInt64() || v.Int64() > MaxDecimals {
+		return 0, fmt.Errorf("decimals() returned %s, not a uint8", v)
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,147 @@
+package units
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func bigOf(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad test number %q", s)
+	}
+	return v
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		wei       string
+		decimals  int
+		precision int
+		want      string
+	}{
+		{"42", 0, Full, "42"},
+		{"42", 0, 2, "42"},
+		{"1500000", 6, Full, "1.5"},
+		{"1", 6, Full, "0.000001"},
+		{"1234567", 6, 2, "1.23"}, // truncated, not rounded
+		{"1000000000000000000", 18, Full, "1"},
+		{"1", 18, Full, "0.000000000000000001"},
+		{"1", 18, 4, "0"},
+		{"-2500000000000000000", 18, Full, "-2.5"},
+		{"1000000000000000000000000", 24, Full, "1"},
+		{"123", 24, Full, "0.000000000000000000000123"},
+		{"1" + strings.Repeat("0", 255), 255, Full, "1"},
+		{"5", 255, Full, "0." + strings.Repeat("0", 254) + "5"},
+		{"7", 256, Full, "0." + strings.Repeat("0", 255) + "7"},
+	}
+	for _, c := range cases {
+		if got := Format(bigOf(t, c.wei), c.decimals, c.precision); got != c.want {
+			t.Errorf("Format(%s, %d, %d) = %q, want %q", c.wei, c.decimals, c.precision, got, c.want)
+		}
+	}
+	if got := Format(nil, 18, Full); got != "0" {
+		t.Errorf("Format(nil) = %q, want 0", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		amount   string
+		decimals int
+		want     string
+	}{
+		{"42", 0, "42"},
+		{"5.0", 0, "5"}, // trailing fractional zeros are not precision
+		{"1.5", 6, "1500000"},
+		{".5", 6, "500000"},
+		{"1.", 6, "1000000"},
+		{"0.000001", 6, "1"},
+		{"1", 18, "1000000000000000000"},
+		{"1.5", -1, "1500000000000000000"}, // negative decimals mean 18
+		{"0.000000000000000000000123", 24, "123"},
+		{"1", 255, "1" + strings.Repeat("0", 255)},
+		{"000", 6, "0"},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.amount, c.decimals)
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", c.amount, c.decimals, err)
+			continue
+		}
+		if got.String() != c.want {
+			t.Errorf("Parse(%q, %d) = %s, want %s", c.amount, c.decimals, got, c.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	cases := []struct {
+		amount   string
+		decimals int
+	}{
+		{"0.5", 0},                    // a 0-decimals token has no fraction
+		{"1.0000001", 6},              // one digit too many
+		{"0.0000000000000000001", 18}, // 19 fractional digits
+		{"1." + strings.Repeat("1", 25), 24},
+		{"1", 256}, // decimals() is a uint8
+		{"", 18},
+		{".", 18},
+		{"1e18", 18},
+		{"-1", 18},
+		{"1.2.3", 18},
+	}
+	for _, c := range cases {
+		if v, err := Parse(c.amount, c.decimals); err == nil {
+			t.Errorf("Parse(%q, %d) = %s, want an error", c.amount, c.decimals, v)
+		}
+	}
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	values := []string{"0", "1", "9", "10", "1500000", "123456789012345678901234567890", "1" + strings.Repeat("0", 80)}
+	for _, decimals := range []int{0, 6, 18, 24, 255} {
+		for _, s := range values {
+			x := bigOf(t, s)
+			back, err := Parse(Format(x, decimals, Full), decimals)
+			if err != nil {
+				t.Errorf("decimals %d, %s: %v", decimals, s, err)
+				continue
+			}
+			if back.Cmp(x) != 0 {
+				t.Errorf("decimals %d: Parse(Format(%s)) = %s", decimals, s, back)
+			}
+		}
+	}
+}
+
+func TestDecodeDecimals(t *testing.T) {
+	word := func(n int64) []byte { return common.LeftPadBytes(big.NewInt(n).Bytes(), 32) }
+	cases := []struct {
+		res  []byte
+		want int
+	}{
+		{nil, 18}, // no getter
+		{word(0), 0},
+		{word(6), 6},
+		{word(18), 18},
+		{word(24), 24},
+		{word(255), 255},
+		{append(word(8), word(99)...), 8}, // only the first word counts
+	}
+	for _, c := range cases {
+		got, err := DecodeDecimals(c.res)
+		if err != nil || got != c.want {
+			t.Errorf("DecodeDecimals(%x) = %d, %v; want %d", c.res, got, err, c.want)
+		}
+	}
+	for _, bad := range [][]byte{word(256), word(1 << 40), common.LeftPadBytes(new(big.Int).Lsh(big.NewInt(1), 200).Bytes(), 32)} {
+		if got, err := DecodeDecimals(bad); err == nil {
+			t.Errorf("DecodeDecimals(%x) = %d, want an error", bad, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package warnings models soft problems found while checking a pair (decimals fallback,
+// missing symbol, ...) separately from failure reasons. Each warning has a stable code for
+// tooling and a human detail; CSVs carry them as two columns, "warnings" (codes) and
+// "warningDetails" (details, same order).
+package warnings
+
+import "strings"
+
+// Code is a machine-readable warning type. Codes are part of the CSV/JSON output: never rename.
+type Code string
+
+const (
+	DecimalsFallback Code = "decimals_fallback" // decimals() failed, 18 assumed
+	SymbolMissing    Code = "symbol_missing"    // symbol() failed or empty
+	BalanceUnknown   Code = "balance_unknown"   // balanceOf() failed, 1-wei preflight used instead
+	TransferTax      Code = "transfer_tax"      // fee-on-transfer: SAFE receives less than sent
+	Proxy            Code = "proxy"             // EIP-1967 proxy: getters read from the implementation
+	TransferHook     Code = "transfer_hook"     // ERC-777 / ERC-1363: transfer calls into third-party hooks
+	ChecksSkipped    Code = "checks_skipped"    // batchcli -checks turned stages off: the pair is not fully verified
+)
+
+// Warning is one soft problem.
+type Warning struct {
+	Code   Code   `json:"code"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// List is an ordered set of warnings (one per code).
+type List []Warning
+
+// Add appends a warning; a repeated code keeps the first detail.
+func (l *List) Add(code Code, detail string) {
+	if l.Has(code) {
+		return
+	}
+	*l = append(*l, Warning{Code: code, Detail: detail})
+}
+
+// Has reports whether code is in the list.
+func (l List) Has(code Code) bool {
+	for _, w := range l {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Codes is the "warnings" CSV column: codes joined by ";".
+func (l List) Codes() string {
+	parts := make([]string, len(l))
+	for i, w := range l {
+		parts[i] = string(w.Code)
+	}
+	return strings.Join(parts, ";")
+}
+
+// Details is the "warningDetails" CSV column: details joined by " | ", same order as Codes.
+func (l List) Details() string {
+	parts := make([]string, len(l))
+	for i, w := range l {
+		parts[i] = w.Detail
+	}
+	return strings.Join(parts, " | ")
+}
+
+// String is for humans: "code: detail; code: detail".
+func (l List) String() string {
+	parts := make([]string, len(l))
+	for i, w := range l {
+		parts[i] = string(w.Code)
+		if w.Detail != "" {
+			parts[i] += ": " + w.Detail
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Parse rebuilds a List from the two CSV columns (details may be empty).
+func Parse(codes, details string) List {
+	var l List
+	ds := strings.Split(details, " | ")
+	for i, c := range strings.Split(codes, ";") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		d := ""
+		if i < len(ds) {
+			d = strings.TrimSpace(ds[i])
+		}
+		l.Add(Code(c), d)
+	}
+	return l
+}
@@ -0,0 +1,25 @@
+// Package rescue is bundle-rescue as a library: the pipeline the CLIs and the GUI run,
+// for incident-response tooling written in Go that wants to embed it instead of shelling
+// out to bundlecli.
+//
+// The pipeline has four steps, each usable on its own:
+//
+//	c, err := rescue.New(ctx, rescue.Config{RPC: rpcURL, Relays: relays, AuthKeyHex: auth, SafeKeyHex: safe})
+//	as := c.Triage(ctx, pairs)         // can each pair be moved? balance, restrictions, transfer preflight
+//	plan, err := c.Plan(ctx, as)       // what to send, gas, worst-case SAFE cost
+//	ex := c.Execute(ctx, plan)         // one Flashbots-style bundle race per pair (bundlecore.Run)
+//	vs := c.Verify(ctx, ex)            // did the tokens actually leave FROM?
+//
+// or all of them with Client.Rescue. Reason and outcome codes are the same as in the CLI
+// outputs (README: "Reason codes", "Run outcome codes").
+//
+// # Stability
+//
+// The exported API of this package follows semantic versioning with the module's release
+// tags (vMAJOR.MINOR.PATCH); Version is the API version of this tree. Within a major version
+// fields and functions are only added, codes are only added, and no signature changes.
+// Everything under internal/ may change in any release.
+package rescue
+
+// Version is the semantic version of the pkg/rescue API.
+const Version = "1.0.0"
@@ -0,0 +1,135 @@
+package rescue
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// Outcome is the run outcome code of a step (README "Run outcome codes").
+type Outcome string
+
+const (
+	OutcomeIncluded         Outcome = Outcome(core.ReasonIncluded)
+	OutcomeSimulated        Outcome = Outcome(core.ReasonSimulateOnly) // DryRun: the bundle simulated fine
+	OutcomeSimulationFailed Outcome = Outcome(core.ReasonSimulationFailed)
+	OutcomeCompetingNonce   Outcome = Outcome(core.ReasonCompetingNonce)
+	OutcomeInsufficientSafe Outcome = Outcome(core.ReasonInsufficientSafe)
+	OutcomeTokenPaused      Outcome = Outcome(core.ReasonTokenPaused)
+	OutcomeTokenRestricted  Outcome = Outcome(core.ReasonTokenRestricted)
+	OutcomeNoRoute          Outcome = Outcome(core.ReasonNoRoute)
+	OutcomeRelayLimits      Outcome = Outcome(core.ReasonRelayLimits)
+	OutcomeNotIncluded      Outcome = Outcome(core.ReasonNotIncluded)
+	OutcomeError            Outcome = "error" // Err says what failed (key, RPC, ...)
+	OutcomeCancelled        Outcome = "cancelled"
+)
+
+// Execution is what became of one step.
+type Execution struct {
+	Step      Step
+	Outcome   Outcome
+	Reason    string
+	Included  bool
+	TxHash    common.Hash // the transfer tx when included
+	Moved     *big.Int    // tokens moved per the Transfer logs; nil = not observed
+	Builder   string      // builder of the inclusion block ("" = unknown)
+	WinRelays []string    // relays credited with the inclusion
+	Err       error
+}
+
+// Execute races each step's bundle (SAFE prefund + FROM transfer) on the relays, one step
+// after the other. With DryRun the bundles are only simulated. A cancelled ctx marks the
+// remaining steps OutcomeCancelled.
+func (c *Client) Execute(ctx context.Context, plan Plan) []Execution {
+	out := make([]Execution, 0, len(plan.Steps))
+	for _, st := range plan.Steps {
+		ex := Execution{Step: st}
+		if ctx.Err() != nil {
+			ex.Outcome, ex.Err = OutcomeCancelled, ctx.Err()
+			out = append(out, ex)
+			continue
+		}
+		p := c.params()
+		p.Token, p.From, p.To = st.Pair.Token, st.Pair.From, st.Pair.To
+		p.FromPKHex, p.AmountWei = st.Pair.FromKeyHex, new(big.Int).Set(st.Amount)
+		res, err := core.Run(ctx, c.ec, p)
+		if err != nil {
+			ex.Outcome, ex.Reason, ex.Err = OutcomeError, err.Error(), err
+		} else {
+			ex.Outcome, ex.Reason, ex.Err = Outcome(res.Code), res.Reason, res.Err()
+			ex.Included, ex.TxHash, ex.Moved = res.Included, res.TxHash, res.Moved
+			ex.Builder, ex.WinRelays = res.Builder, res.WinRelays
+		}
+		c.logf("[execute] %s %s: %s", st.Pair.From.Hex(), st.Pair.Token.Hex(), ex.Outcome)
+		out = append(out, ex)
+	}
+	return out
+}
+
+// params are the bundlecore parameters of the client's config, CLI defaults filled in.
+func (c *Client) params() core.Params {
+	cfg := c.cfg
+	p := core.Params{
+		RPC: cfg.RPC, ChainID: c.chainID, Relays: cfg.Relays, AuthPrivHex: cfg.AuthKeyHex, SafePKHex: cfg.SafeKeyHex,
+		Blocks: cfg.Blocks, TipGweiBase: cfg.TipGwei, TipMul: cfg.TipMul, BaseMul: cfg.BaseMul, BufferPct: cfg.BufferPct,
+		Route: cfg.Route, AccessList: cfg.AccessList, SimulateOnly: cfg.DryRun, SkipIfPaused: true,
+		Logf: cfg.Logf,
+	}
+	if p.Blocks <= 0 {
+		p.Blocks = 6
+	}
+	if p.TipGweiBase <= 0 {
+		p.TipGweiBase = 3
+	}
+	if p.TipMul <= 0 {
+		p.TipMul = 1.25
+	}
+	if p.BaseMul <= 0 {
+		p.BaseMul = 2
+	}
+	if p.BufferPct <= 0 {
+		p.BufferPct = 5
+	}
+	return p
+}
+
+// Verification is the check of one execution against the chain.
+type Verification struct {
+	Execution   Execution
+	FromBalance *big.Int // FROM's token balance now; nil = not read
+	Verified    bool     // included, and the tokens left FROM
+	Note        string
+}
+
+// Verify reads FROM's token balance after each execution: an included bundle is verified
+// when its Transfer logs show a move or the balance dropped below the one seen at triage
+// (Step.PreBalance; a partial Pair.Amount leaves the rest behind), so a bundle that landed
+// but moved nothing (a fee-on-transfer or hook token, a changed balance) is not counted as
+// rescued.
+func (c *Client) Verify(ctx context.Context, ex []Execution) []Verification {
+	out := make([]Verification, 0, len(ex))
+	for _, e := range ex {
+		v := Verification{Execution: e}
+		bal, err := tokenBalance(ctx, c.ec, e.Step.Pair.Token, e.Step.Pair.From)
+		if err == nil {
+			v.FromBalance = bal
+		}
+		switch {
+		case !e.Included:
+			v.Note = "not included"
+		case e.Moved != nil && e.Moved.Sign() > 0:
+			v.Verified, v.Note = true, "Transfer logs: "+e.Moved.String()+" moved"
+		case bal != nil && e.Step.PreBalance != nil && bal.Cmp(e.Step.PreBalance) < 0:
+			v.Verified, v.Note = true, "FROM balance dropped"
+		case err != nil:
+			v.Note = "balance: " + err.Error()
+		default:
+			v.Note = "included, but FROM still holds the tokens"
+		}
+		c.logf("[verify] %s %s: %s", e.Step.Pair.From.Hex(), e.Step.Pair.Token.Hex(), v.Note)
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package rescue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+)
+
+// Gas of the SAFE -> FROM prefund, and the transfer fallback when estimateGas fails (as Run).
+const (
+	prefundGas          = 21_000
+	fallbackTransferGas = 90_000
+)
+
+// Step is one pair Plan sends.
+type Step struct {
+	Pair       Pair
+	Amount     *big.Int // tokens to move (the balance at triage time, or Pair.Amount)
+	PreBalance *big.Int // FROM's token balance at triage; Verify checks it went down
+	Gas        uint64   // transfer gas estimate
+	MaxCost    *big.Int // worst case for the SAFE: every attempt's last-block fees, buffer included
+}
+
+// Plan is what Execute will send.
+type Plan struct {
+	Steps       []Step
+	Skipped     []Assessment // triage rejected them
+	BaseFee     *big.Int     // of the latest block
+	MaxCostWei  *big.Int     // sum of the steps' MaxCost
+	SafeBalance *big.Int
+	Funded      bool // SafeBalance covers MaxCostWei
+}
+
+// ErrNothingToDo: no pair passed triage.
+var ErrNothingToDo = errors.New("no pair passed triage")
+
+// Plan turns the assessments that passed into steps and prices them at the current base
+// fee. An unfunded plan is returned with Funded false; Execute still runs it (the worst
+// case rarely happens), the caller decides.
+func (c *Client) Plan(ctx context.Context, as []Assessment) (Plan, error) {
+	var pl Plan
+	for _, a := range as {
+		if !a.OK {
+			pl.Skipped = append(pl.Skipped, a)
+		}
+	}
+	head, err := c.ec.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return pl, fmt.Errorf("base fee: %w", err)
+	}
+	pl.BaseFee = big.NewInt(0)
+	if head.BaseFee != nil {
+		pl.BaseFee = new(big.Int).Set(head.BaseFee)
+	}
+	if pl.SafeBalance, err = c.ec.BalanceAt(ctx, c.safe, nil); err != nil {
+		return pl, fmt.Errorf("SAFE balance: %w", err)
+	}
+	feeCap := c.worstFeeCap(pl.BaseFee)
+	pl.MaxCostWei = new(big.Int)
+	for _, a := range as {
+		if !a.OK {
+			continue
+		}
+		st := Step{Pair: a.Pair, Amount: a.Pair.amount(a.Balance), PreBalance: a.Balance, Gas: fallbackTransferGas}
+		data := core.EncodeERC20Transfer(st.Pair.To, st.Amount)
+		if g, err := core.EstimateTransferGas(ctx, c.ec, st.Pair.From, st.Pair.Token, data); err == nil && g > 0 {
+			st.Gas = g
+		}
+		st.MaxCost = new(big.Int).Mul(new(big.Int).SetUint64(st.Gas+prefundGas), feeCap)
+		st.MaxCost.Mul(st.MaxCost, big.NewInt(100+c.bufferPct()))
+		st.MaxCost.Div(st.MaxCost, big.NewInt(100))
+		pl.MaxCostWei.Add(pl.MaxCostWei, st.MaxCost)
+		pl.Steps = append(pl.Steps, st)
+	}
+	pl.Funded = pl.SafeBalance.Cmp(pl.MaxCostWei) >= 0
+	c.logf("[plan] %d step(s), %d skipped, worst case %s wei, SAFE has %s wei", len(pl.Steps), len(pl.Skipped), pl.MaxCostWei, pl.SafeBalance)
+	if len(pl.Steps) == 0 {
+		return pl, ErrNothingToDo
+	}
+	return pl, nil
+}
+
+// worstFeeCap is the maxFee of the last attempt: baseFee*BaseMul + TipGwei*TipMul^(Blocks-1).
+func (c *Client) worstFeeCap(baseFee *big.Int) *big.Int {
+	p := c.params()
+	tipGwei := float64(p.TipGweiBase) * math.Pow(p.TipMul, float64(p.Blocks-1))
+	tip, _ := new(big.Float).Mul(big.NewFloat(tipGwei), big.NewFloat(1e9)).Int(nil)
+	return new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(p.BaseMul)), tip)
+}
+
+func (c *Client) bufferPct() int64 {
+	if b := c.params().BufferPct; b > 10 {
+		return b
+	}
+	return 10 // Run's prefund buffer is at least 10%
+}
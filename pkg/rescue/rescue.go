@@ -0,0 +1,151 @@
+package rescue
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Config is what a Client works with. Zero strategy fields take the CLI defaults.
+type Config struct {
+	RPC        string
+	ChainID    *big.Int // nil = asked from the RPC
+	Relays     []string // RELAYS entries ("titan:https://...", "share:https://...")
+	AuthKeyHex string   // Flashbots auth key (request signing)
+	SafeKeyHex string   // SAFE: pays the gas of every rescue
+
+	Blocks    int     // attempts, one per block (default 6)
+	TipGwei   int64   // first priority fee (default 3)
+	TipMul    float64 // tip escalation per attempt (default 1.25)
+	BaseMul   int64   // maxFee = baseFee*BaseMul + tip (default 2)
+	BufferPct int64   // prefund buffer (default 5)
+
+	Route      string // "transfer" (default), "router" or "auto": sell through an approved router
+	AccessList bool   // EIP-2930 access list on the transfer when it saves gas
+	DryRun     bool   // Execute simulates the bundles, nothing is sent
+
+	Logf func(format string, a ...any) // progress lines; nil = silent
+}
+
+// Pair is one token position to move out of a compromised wallet.
+type Pair struct {
+	Token      common.Address
+	From       common.Address // zero = derived from FromKeyHex
+	To         common.Address // recipient, usually the SAFE
+	FromKeyHex string         // key of the compromised wallet
+	Amount     *big.Int       // nil or 0 = the whole balance
+}
+
+// Client runs the pipeline against one RPC.
+type Client struct {
+	cfg     Config
+	ec      *ethclient.Client
+	own     bool // ec was dialed by New
+	chainID *big.Int
+	safe    common.Address
+}
+
+// New dials cfg.RPC; Close releases the connection.
+func New(ctx context.Context, cfg Config) (*Client, error) {
+	ec, err := ethclient.DialContext(ctx, cfg.RPC)
+	if err != nil {
+		return nil, fmt.Errorf("dial RPC: %w", err)
+	}
+	c, err := NewWithClient(ctx, ec, cfg)
+	if err != nil {
+		ec.Close()
+		return nil, err
+	}
+	c.own = true
+	return c, nil
+}
+
+// NewWithClient uses an existing connection (cfg.RPC must still name it: the fee history
+// and stateOverride calls dial it separately).
+func NewWithClient(ctx context.Context, ec *ethclient.Client, cfg Config) (*Client, error) {
+	if strings.TrimSpace(cfg.SafeKeyHex) == "" {
+		return nil, errors.New("SafeKeyHex is empty")
+	}
+	safeKey, err := parseKey(cfg.SafeKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("SAFE key: %w", err)
+	}
+	c := &Client{cfg: cfg, ec: ec, chainID: cfg.ChainID, safe: crypto.PubkeyToAddress(safeKey.PublicKey)}
+	if c.chainID == nil {
+		cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if c.chainID, err = ec.ChainID(cctx); err != nil {
+			return nil, fmt.Errorf("chain id: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection New dialed (a NewWithClient connection stays open).
+func (c *Client) Close() {
+	if c.own {
+		c.ec.Close()
+	}
+}
+
+// ChainID is the chain the client works on.
+func (c *Client) ChainID() *big.Int { return new(big.Int).Set(c.chainID) }
+
+// Safe is the SAFE address (from SafeKeyHex).
+func (c *Client) Safe() common.Address { return c.safe }
+
+// Report is the outcome of Client.Rescue.
+type Report struct {
+	Assessments   []Assessment
+	Plan          Plan
+	Executions    []Execution
+	Verifications []Verification
+}
+
+// Rescue runs the whole pipeline on pairs. The error is the planning error; per-pair
+// failures are in the report.
+func (c *Client) Rescue(ctx context.Context, pairs []Pair) (Report, error) {
+	var r Report
+	r.Assessments = c.Triage(ctx, pairs)
+	plan, err := c.Plan(ctx, r.Assessments)
+	r.Plan = plan
+	if err != nil {
+		return r, err
+	}
+	r.Executions = c.Execute(ctx, plan)
+	r.Verifications = c.Verify(ctx, r.Executions)
+	return r, nil
+}
+
+func (c *Client) logf(format string, a ...any) {
+	if c.cfg.Logf != nil {
+		c.cfg.Logf(format, a...)
+	}
+}
+
+func parseKey(h string) (*ecdsa.PrivateKey, error) {
+	return crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(h), "0x"))
+}
+
+// resolve fills in From from the key and checks they match.
+func (p Pair) resolve() (Pair, error) {
+	k, err := parseKey(p.FromKeyHex)
+	if err != nil {
+		return p, errors.New("invalid private key")
+	}
+	addr := crypto.PubkeyToAddress(k.PublicKey)
+	if p.From == (common.Address{}) {
+		p.From = addr
+	} else if p.From != addr {
+		return p, errors.New("invalid private key: it is not the key of From")
+	}
+	return p, nil
+}
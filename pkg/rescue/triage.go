@@ -0,0 +1,112 @@
+package rescue
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	core "github.com/ligun0805/bundle-rescue/internal/bundlecore"
+	"github.com/ligun0805/bundle-rescue/internal/reasons"
+	"github.com/ligun0805/bundle-rescue/internal/rpcerrors"
+)
+
+// Reason codes of a pair Triage rejects (the shared taxonomy of the CLI outputs; README
+// "Reason codes" lists them all).
+const (
+	CodeInvalidKey     = string(reasons.InvalidKey)
+	CodeNoBalance      = string(reasons.NoBalance)
+	CodePaused         = string(reasons.Paused)
+	CodeBlacklisted    = string(reasons.BlacklistedFrom)
+	CodeNotWhitelisted = string(reasons.NotWhitelisted)
+	CodeRestricted     = string(reasons.Restricted)
+	CodeReverted       = string(reasons.Reverted)
+	CodeRPCTimeout     = string(reasons.RPCTimeout)
+	CodeRPCError       = string(reasons.RPCError)
+)
+
+// Assessment is the triage verdict of one pair.
+type Assessment struct {
+	Pair    Pair     // From filled in
+	Balance *big.Int // FROM's token balance; nil = not read
+	OK      bool     // the pair can go to Plan
+	Code    string   // reason code when !OK
+	Reason  string   // the same for people
+}
+
+// Triage checks every pair: the key, FROM's token balance, the token's pause / blacklist /
+// whitelist state and an eth_call of the transfer from FROM.
+func (c *Client) Triage(ctx context.Context, pairs []Pair) []Assessment {
+	out := make([]Assessment, 0, len(pairs))
+	for _, p := range pairs {
+		a := c.assess(ctx, p)
+		if a.OK {
+			c.logf("[triage] %s %s: ok, balance %s", a.Pair.From.Hex(), a.Pair.Token.Hex(), a.Balance)
+		} else {
+			c.logf("[triage] %s %s: %s (%s)", a.Pair.From.Hex(), a.Pair.Token.Hex(), a.Code, a.Reason)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func (c *Client) assess(ctx context.Context, p Pair) Assessment {
+	a := Assessment{Pair: p}
+	rp, err := p.resolve()
+	if err != nil {
+		a.Code, a.Reason = CodeInvalidKey, err.Error()
+		return a
+	}
+	a.Pair = rp
+	bal, err := tokenBalance(ctx, c.ec, rp.Token, rp.From)
+	if err != nil {
+		a.Code, a.Reason = string(rpcerrors.Classify(err).Code()), "balance: "+err.Error()
+		return a
+	}
+	a.Balance = bal
+	if bal.Sign() == 0 {
+		a.Code, a.Reason = CodeNoBalance, "no token balance"
+		return a
+	}
+	if r, err := core.CheckRestrictions(ctx, c.ec, rp.Token, rp.From, rp.To); err == nil && r.Blocked() {
+		a.Code, a.Reason = string(r.ReasonCode()), "blocked: "+r.Summary()
+		return a
+	}
+	ok, why, err := core.PreflightTransfer(ctx, c.ec, rp.Token, rp.From, rp.To, rp.amount(bal))
+	switch {
+	case err != nil:
+		a.Code, a.Reason = string(rpcerrors.Classify(err).Code()), "preflight error: "+err.Error()
+	case !ok:
+		a.Code, a.Reason = string(reasons.Classify(why)), why
+		if a.Code == string(reasons.Other) {
+			a.Code = CodeReverted
+		}
+	default:
+		a.OK = true
+	}
+	return a
+}
+
+// amount is what to move: Amount capped at balance, the whole balance without one.
+func (p Pair) amount(balance *big.Int) *big.Int {
+	if p.Amount == nil || p.Amount.Sign() <= 0 || (balance != nil && p.Amount.Cmp(balance) > 0) {
+		return new(big.Int).Set(balance)
+	}
+	return new(big.Int).Set(p.Amount)
+}
+
+func tokenBalance(ctx context.Context, ec ethereumCaller, token, owner common.Address) (*big.Int, error) {
+	data := append(common.FromHex("0x70a08231"), common.LeftPadBytes(owner.Bytes(), 32)...)
+	res, err := ec.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) < 32 {
+		return big.NewInt(0), nil
+	}
+	return new(big.Int).SetBytes(res[len(res)-32:]), nil
+}
+
+type ethereumCaller interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error)
+}